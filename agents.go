@@ -0,0 +1,151 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+func parseAgent(card *html.Node) string {
+	nameNode := findFirst(card, isAgentNameNode)
+	if nameNode != nil {
+		if name := normaliseAgentName(textContent(nameNode)); name != "" {
+			return name
+		}
+	}
+
+	logoNode := findFirst(card, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "img" && hasClass(n, "AgentLogo")
+	})
+	if logoNode != nil {
+		alt, _ := getAttr(logoNode, "alt")
+		return normaliseAgentName(alt)
+	}
+
+	return ""
+}
+
+func isAgentNameNode(n *html.Node) bool {
+	return n.Type == html.ElementNode && hasClass(n, "AgentName")
+}
+
+// normaliseAgentName reduces raw agent text like "  Foxtons  -  Clapham ",
+// "Foxtons, Clapham" or "Foxtons (Clapham)" to the agent's name ("Foxtons")
+// so that branches are counted together.
+func normaliseAgentName(raw string) string {
+	name := strings.Join(strings.Fields(raw), " ")
+	if i := strings.Index(name, " - "); i >= 0 {
+		name = name[:i]
+	}
+	if i := strings.Index(name, ","); i > 0 {
+		name = name[:i]
+	}
+	if i := strings.Index(name, " ("); i > 0 && strings.HasSuffix(name, ")") {
+		name = name[:i]
+	}
+
+	return strings.TrimSpace(name)
+}
+
+// defaultAgentMargin is how far an agent's median can be from the area
+// median, as a fraction of it, before the agent is flagged.
+const defaultAgentMargin = 0.15
+
+type agentStats struct {
+	name        string
+	count       int
+	meanPrice   float64
+	medianPrice float64
+
+	// share is the percentage of all listings that are the agent's.
+	share float64
+	// deviation is the percentage the agent's median is above (or below)
+	// the median of all listings.
+	deviation float64
+	flagged   bool
+}
+
+// calculateAgentStats summarises the prices of the topN agents with the
+// most listings. Agents whose median deviates from the area median by more
+// than margin are flagged.
+func calculateAgentStats(listings []listing, topN int, margin float64) []agentStats {
+	var agentNames []string
+	agentPrices := make(map[string][]uint64)
+	for i := range listings {
+		name := listings[i].Agent
+		if name == "" {
+			continue
+		}
+
+		if _, ok := agentPrices[name]; !ok {
+			agentNames = append(agentNames, name)
+		}
+		agentPrices[name] = append(agentPrices[name], listings[i].Price)
+	}
+
+	areaMedian := calculateMedian(listingPrices(listings))
+	stats := make([]agentStats, len(agentNames))
+	for i, name := range agentNames {
+		prices := agentPrices[name]
+		median := calculateMedian(prices)
+		deviation := percentChange(areaMedian, median)
+		stats[i] = agentStats{
+			name:        name,
+			count:       len(prices),
+			meanPrice:   calculateMean(prices),
+			medianPrice: median,
+			share:       float64(len(prices)) / float64(len(listings)) * 100,
+			deviation:   deviation,
+			flagged:     areaMedian > 0 && math.Abs(deviation) > margin*100,
+		}
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].count != stats[j].count {
+			return stats[i].count > stats[j].count
+		}
+		return stats[i].name < stats[j].name
+	})
+
+	if len(stats) > topN {
+		stats = stats[:topN]
+	}
+
+	return stats
+}
+
+func (s agentStats) String() string {
+	str := fmt.Sprintf("%s: count = %d (%.1f%%), mean price = %.0f, median price = %.0f (%+.1f%% vs area)",
+		s.name, s.count, s.share, s.meanPrice, s.medianPrice, s.deviation)
+	if s.flagged {
+		str += " *"
+	}
+
+	return str
+}
+
+func (s agentStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name      string  `json:"name"`
+		Count     int     `json:"count"`
+		Share     float64 `json:"share"`
+		Mean      float64 `json:"mean"`
+		Median    float64 `json:"median"`
+		Deviation float64 `json:"deviation"`
+		Flagged   bool    `json:"flagged"`
+	}{s.name, s.count, s.share, s.meanPrice, s.medianPrice, s.deviation, s.flagged})
+}
+
+func anyFlaggedAgent(agents []agentStats) bool {
+	for _, a := range agents {
+		if a.flagged {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,257 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNormaliseAgentName(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"Foxtons", "Foxtons"},
+		{"  Foxtons  -  Clapham ", "Foxtons"},
+		{"Foxtons, Brixton", "Foxtons"},
+		{"Foxtons (Brixton)", "Foxtons"},
+		{"Foxtons\n\t- Brixton", "Foxtons"},
+		{"Winkworth - Brixton (Sales)", "Winkworth"},
+		{"Haart (Sales) - Streatham", "Haart"},
+		{"(Unbranded)", "(Unbranded)"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normaliseAgentName(tt.raw); got != tt.want {
+			t.Errorf("normaliseAgentName(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		card string
+		want string
+	}{
+		{
+			name: "name node",
+			card: `<div data-testid="search-result"><p class="css-1 AgentName-x">Foxtons - Brixton</p></div>`,
+			want: "Foxtons",
+		},
+		{
+			name: "logo alt text",
+			card: `<div data-testid="search-result"><img class="AgentLogo" alt="Savills, Clapham"></div>`,
+			want: "Savills",
+		},
+		{
+			name: "empty name falls back to logo",
+			card: `<div data-testid="search-result"><p class="AgentName"> </p><img class="AgentLogo" alt="Haart (Streatham)"></div>`,
+			want: "Haart",
+		},
+		{
+			name: "no agent",
+			card: `<div data-testid="search-result"><p>£400,000</p></div>`,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseAgent(mustParseCard(t, tt.card)); got != tt.want {
+				t.Errorf("parseAgent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateAgentStatsCountsBranchesTogether(t *testing.T) {
+	var listings []listing
+	for _, raw := range []string{"Foxtons - Brixton", "Foxtons, Clapham", "Foxtons (Streatham)", "Savills"} {
+		listings = append(listings, listing{Price: 400000, Agent: normaliseAgentName(raw)})
+	}
+
+	stats := calculateAgentStats(listings, 5, defaultAgentMargin)
+	if len(stats) != 2 {
+		t.Fatalf("got %d agents, want 2: %v", len(stats), stats)
+	}
+	if stats[0].name != "Foxtons" || stats[0].count != 3 {
+		t.Errorf("top agent = %s with %d listings, want Foxtons with 3", stats[0].name, stats[0].count)
+	}
+	if math.Abs(stats[0].share-75) > 1e-9 {
+		t.Errorf("Foxtons share = %v, want 75", stats[0].share)
+	}
+}
+
+// agentListings splits reportListings between three agents. The area
+// median is £465,000.
+func agentListings() []listing {
+	listings := reportListings()
+	for i := range listings {
+		switch {
+		case i < 3:
+			listings[i].Agent = "Foxtons"
+		case i < 6:
+			listings[i].Agent = "Savills"
+		default:
+			listings[i].Agent = "Winkworth"
+		}
+	}
+
+	return listings
+}
+
+func TestCalculateAgentStats(t *testing.T) {
+	want := []struct {
+		name      string
+		count     int
+		median    float64
+		share     float64
+		deviation float64
+		flagged   bool
+	}{
+		{"Foxtons", 3, 320000, 37.5, 100 * (320000.0 - 465000) / 465000, true},
+		{"Savills", 3, 480000, 37.5, 100 * (480000.0 - 465000) / 465000, false},
+		{"Winkworth", 2, 725000, 25, 100 * (725000.0 - 465000) / 465000, true},
+	}
+
+	stats := calculateAgentStats(agentListings(), 5, defaultAgentMargin)
+	if len(stats) != len(want) {
+		t.Fatalf("got %d agents, want %d", len(stats), len(want))
+	}
+	for i, w := range want {
+		s := stats[i]
+		if s.name != w.name || s.count != w.count || s.medianPrice != w.median || !closeTo(s.share, w.share) ||
+			!closeTo(s.deviation, w.deviation) || s.flagged != w.flagged {
+			t.Errorf("agent %d: got %+v, want %+v", i, s, w)
+		}
+	}
+}
+
+func TestCalculateAgentStatsLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		topN    int
+		margin  float64
+		agents  []string
+		flagged int
+	}{
+		{"top two", 2, defaultAgentMargin, []string{"Foxtons", "Savills"}, 1},
+		{"wide margin", 5, 0.6, []string{"Foxtons", "Savills", "Winkworth"}, 0},
+		{"narrow margin", 5, 0.01, []string{"Foxtons", "Savills", "Winkworth"}, 3},
+		{"none", 0, defaultAgentMargin, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := calculateAgentStats(agentListings(), tt.topN, tt.margin)
+			var names []string
+			flagged := 0
+			for _, s := range stats {
+				names = append(names, s.name)
+				if s.flagged {
+					flagged++
+				}
+			}
+			if !equalStrings(names, tt.agents) || flagged != tt.flagged {
+				t.Errorf("got %v with %d flagged, want %v with %d", names, flagged, tt.agents, tt.flagged)
+			}
+			if got := anyFlaggedAgent(stats); got != (tt.flagged > 0) {
+				t.Errorf("anyFlaggedAgent() = %v", got)
+			}
+		})
+	}
+}
+
+func TestCalculateAgentStatsWithoutAgents(t *testing.T) {
+	if stats := calculateAgentStats(reportListings(), 5, defaultAgentMargin); len(stats) != 0 {
+		t.Errorf("got %v, want no agents", stats)
+	}
+}
+
+func TestAgentStatsString(t *testing.T) {
+	stats := calculateAgentStats(agentListings(), 2, defaultAgentMargin)
+
+	want := []string{
+		"Foxtons: count = 3 (37.5%), mean price = 320000, median price = 320000 (-31.2% vs area) *",
+		"Savills: count = 3 (37.5%), mean price = 480000, median price = 480000 (+3.2% vs area)",
+	}
+	for i, s := range stats {
+		if got := s.String(); got != want[i] {
+			t.Errorf("got %q, want %q", got, want[i])
+		}
+	}
+}
+
+func TestAgentStatsJSON(t *testing.T) {
+	s := agentStats{name: "Savills", count: 3, meanPrice: 480000, medianPrice: 480000, share: 37.5, deviation: 3.2}
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"name":"Savills","count":3,"share":37.5,"mean":480000,"median":480000,"deviation":3.2,"flagged":false}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestReportAgents(t *testing.T) {
+	tests := []struct {
+		name     string
+		margin   float64
+		want     []string
+		footnote bool
+	}{
+		{
+			name:     "flagged",
+			margin:   defaultAgentMargin,
+			footnote: true,
+			want: []string{
+				"## Top agents\n",
+				"| Foxtons \\* | 3 | 37.5% | £320,000 | £320,000 | -31.2% |\n",
+				"| Savills | 3 | 37.5% | £480,000 | £480,000 | +3.2% |\n",
+				"\\* Median is more than 15% from the area median.\n",
+			},
+		},
+		{
+			name:   "none flagged",
+			margin: 0.6,
+			want:   []string{"| Foxtons | 3 | 37.5% |"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := reportArgs()
+			args.TopAgents = 3
+			args.AgentMargin = tt.margin
+			listings := agentListings()
+
+			r := buildReport(listings, listingPrices(listings), args)
+			if len(r.Agents) != 3 {
+				t.Fatalf("got %d agents in the report, want 3", len(r.Agents))
+			}
+
+			md := renderMarkdown(r)
+			for _, want := range tt.want {
+				if !strings.Contains(md, want) {
+					t.Errorf("markdown has no %q:\n%s", want, md)
+				}
+			}
+			if got := strings.Contains(md, "Median is more than"); got != tt.footnote {
+				t.Errorf("got footnote %v, want %v", got, tt.footnote)
+			}
+		})
+	}
+}
+
+func TestReportWithoutAgents(t *testing.T) {
+	listings := agentListings()
+	r := buildReport(listings, listingPrices(listings), reportArgs())
+	if r.Agents != nil || strings.Contains(renderMarkdown(r), "Top agents") {
+		t.Errorf("got agents %v without --top-agents", r.Agents)
+	}
+}
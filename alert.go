@@ -0,0 +1,124 @@
+package zoopla
+
+import (
+	"math"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultAlertThreshold = 0.10
+	defaultBaselineRuns   = 4
+
+	// exitCodeAnomaly is the exit code when a run's stats deviate too far
+	// from its baseline.
+	exitCodeAnomaly = 3
+)
+
+// exitCodeError is an error that should end the program with a particular
+// exit code rather than the usual one.
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Cause() error {
+	return e.err
+}
+
+// baseline is the trailing average of recent runs' stats.
+type baseline struct {
+	runs   int
+	mean   float64
+	median float64
+}
+
+// trailingBaseline averages the stats of the last n runs with query, or
+// returns false if there are none, in which case the baseline has no runs.
+func trailingBaseline(runs []runEntry, query runQuery, n int) (baseline, bool) {
+	var b baseline
+	for i := len(runs) - 1; i >= 0 && b.runs < n; i-- {
+		if !sameQuery(runs[i].Query, query) || len(runs[i].Prices) == 0 {
+			continue
+		}
+
+		s := calculateRunStats(runs[i].Prices)
+		b.mean += s.Mean
+		b.median += s.Median
+		b.runs++
+	}
+
+	if b.runs == 0 {
+		return b, false
+	}
+
+	b.mean /= float64(b.runs)
+	b.median /= float64(b.runs)
+	return b, true
+}
+
+// deviation is the relative difference of v from the baseline value base.
+func deviation(base, v float64) float64 {
+	if base == 0 {
+		return 0
+	}
+
+	return (v - base) / base
+}
+
+// checkBaseline returns an exitCodeError if the median or mean of s deviate
+// from b by more than threshold, as a fraction.
+func checkBaseline(s priceStats, b baseline, threshold float64) error {
+	for _, c := range []struct {
+		name      string
+		base, cur float64
+	}{
+		{"median", b.median, s.median},
+		{"mean", b.mean, s.mean},
+	} {
+		if d := deviation(c.base, c.cur); math.Abs(d) > threshold {
+			return &exitCodeError{
+				code: exitCodeAnomaly,
+				err: errors.Errorf("%s %s is %+.1f%% from the baseline %s of the last %d runs, more than the %.0f%% alert threshold",
+					c.name, formatMeanPrice(c.cur), 100*d, formatMeanPrice(c.base), b.runs, 100*threshold),
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadBaseline averages the runs in the --baseline history file with the
+// same query. It's loaded before the run writes any output because with
+// --append that can be the same file, and the run mustn't count towards its
+// own baseline. A missing file is an empty history, so that the first run
+// can start it.
+func loadBaseline(args *cliArgs) (baseline, error) {
+	in, err := loadOutputFile(args.Baseline)
+	if os.IsNotExist(errors.Cause(err)) {
+		return baseline{}, nil
+	}
+	if err != nil {
+		return baseline{}, err
+	}
+	if in.Kind != outputKindHistory {
+		return baseline{}, errors.Errorf("%s holds %s, --baseline needs an --append history file", args.Baseline, in.Kind)
+	}
+
+	b, _ := trailingBaseline(in.Runs, newRunQuery(args), args.BaselineRuns)
+	return b, nil
+}
+
+// compareBaseline checks s against the baseline that loadBaseline found.
+func compareBaseline(args *cliArgs, s priceStats, b baseline) error {
+	if b.runs == 0 {
+		return errors.Errorf("no runs in %s have the same query to compare with", args.Baseline)
+	}
+
+	return checkBaseline(s, b, args.AlertThreshold)
+}
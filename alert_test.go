@@ -0,0 +1,172 @@
+package zoopla
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ryanc414/zoopla-analyzer/internal/testserver"
+)
+
+func TestTrailingBaseline(t *testing.T) {
+	sw2 := runQuery{Postcode: "SW2", Radius: 1}
+	runs := []runEntry{
+		{Query: sw2, Prices: []uint64{100000}},
+		{Query: sw2, Prices: []uint64{400000, 500000}},
+		{Query: runQuery{Postcode: "SE1"}, Prices: []uint64{900000}},
+		{Query: sw2},
+		{Query: sw2, Prices: []uint64{500000}},
+	}
+
+	tests := []struct {
+		name   string
+		query  runQuery
+		n      int
+		want   baseline
+		wantOK bool
+	}{
+		{"last run", sw2, 1, baseline{runs: 1, mean: 500000, median: 500000}, true},
+		{"skips other queries and empty runs", sw2, 2, baseline{runs: 2, mean: 475000, median: 475000}, true},
+		{"fewer runs than asked", sw2, 10, baseline{runs: 3, mean: 350000, median: 350000}, true},
+		{"no matching runs", runQuery{Postcode: "N1"}, 4, baseline{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := trailingBaseline(runs, tt.query, tt.n)
+			if ok != tt.wantOK || got.runs != tt.want.runs || !closeTo(got.mean, tt.want.mean) || !closeTo(got.median, tt.want.median) {
+				t.Errorf("got %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDeviation(t *testing.T) {
+	tests := []struct {
+		base, v, want float64
+	}{
+		{400000, 440000, 0.1},
+		{400000, 360000, -0.1},
+		{400000, 400000, 0},
+		{0, 400000, 0},
+	}
+
+	for _, tt := range tests {
+		if got := deviation(tt.base, tt.v); !closeTo(got, tt.want) {
+			t.Errorf("deviation(%v, %v) = %v, want %v", tt.base, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestCheckBaseline(t *testing.T) {
+	b := baseline{runs: 4, mean: 500000, median: 400000}
+
+	tests := []struct {
+		name    string
+		s       priceStats
+		wantErr string
+	}{
+		{"within", priceStats{mean: 540000, median: 360000}, ""},
+		{"median", priceStats{mean: 500000, median: 460000}, "median £460,000 is +15.0% from the baseline £400,000 of the last 4 runs, more than the 10% alert threshold"},
+		{"mean", priceStats{mean: 440000, median: 400000}, "mean £440,000 is -12.0% from the baseline £500,000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBaseline(tt.s, b, 0.1)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var exitErr *exitCodeError
+			if !errors.As(err, &exitErr) || exitErr.code != exitCodeAnomaly {
+				t.Fatalf("got %v, want an anomaly exit code", err)
+			}
+			if !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Errorf("got %q, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadBaseline(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		runs     int
+		wantErr  string
+	}{
+		{"history", trendFixture("history.json"), 4, ""},
+		{"missing", filepath.Join(t.TempDir(), "missing.json"), 0, ""},
+		{"not history", diffFixture("old.json"), 0, "--baseline needs an --append history file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := &cliArgs{Postcode: "SW2", Radius: 1, Baseline: tt.filename, BaselineRuns: defaultBaselineRuns}
+			b, err := loadBaseline(args)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b.runs != tt.runs {
+				t.Errorf("got %d runs, want %d", b.runs, tt.runs)
+			}
+		})
+	}
+}
+
+// TestBaselineExcludesAppendedRun checks that a run appending to its own
+// baseline history isn't compared with itself, which would pull the
+// baseline towards the run and hide the anomaly.
+func TestBaselineExcludesAppendedRun(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+
+	history := filepath.Join(t.TempDir(), "history.json")
+	fetch := func(now time.Time, price uint64) error {
+		setNow(t, now)
+		srv.SetPage(1, searchPage(price))
+		args := testSearchArgs(t, "--postcode", "sw2", "--base-url", srv.SearchURL(), "--delay", "0",
+			"--outputfilename", history, "--append", "--baseline", history, "--alert-threshold", "0.05", "--quiet")
+		return runFetch(context.Background(), args)
+	}
+
+	// The first run has nothing to compare with but still starts the
+	// history.
+	err := fetch(time.Date(2021, time.May, 1, 9, 0, 0, 0, time.UTC), 400000)
+	if err == nil || !strings.Contains(err.Error(), "have the same query to compare with") {
+		t.Fatalf("got %v, want an error about no runs to compare with", err)
+	}
+
+	// Averaged with itself the second run would be under 5% from the
+	// baseline, but it's 10% from the first.
+	err = fetch(time.Date(2021, time.May, 2, 9, 0, 0, 0, time.UTC), 440000)
+	var exitErr *exitCodeError
+	if !errors.As(err, &exitErr) || exitErr.code != exitCodeAnomaly {
+		t.Fatalf("got %v, want an anomaly", err)
+	}
+	if !strings.Contains(err.Error(), "+10.0% from the baseline £400,000 of the last 1 runs") {
+		t.Errorf("got %q", err)
+	}
+
+	runs, err := readRuns(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 {
+		t.Errorf("got %d runs in the history, want the anomalous run appended too", len(runs))
+	}
+}
@@ -1,17 +1,20 @@
-package main
+package zoopla
 
 import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
 	"math"
-	"net/http"
 	"net/url"
+	"os"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode"
 
 	"github.com/alexflint/go-arg"
 	"github.com/pkg/errors"
@@ -21,86 +24,1145 @@ import (
 const (
 	baseURL               = "https://www.zoopla.co.uk/for-sale/property"
 	defaultOutputFilename = "prices.json"
+	defaultTopAgents      = 5
 )
 
-func main() {
-	if err := run(context.Background()); err != nil {
-		log.Fatal(err)
+// Main runs the zoopla-analyzer command line tool with the program's
+// arguments and logs any error, or prints the usage for invalid arguments.
+// It returns the code to exit with, which is non-zero if the run failed or
+// raised an alert.
+func Main(ctx context.Context) int {
+	err := run(ctx)
+	if err == nil || errors.Is(err, errHelp) {
+		return 0
 	}
+
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		usageErr.write(os.Stderr)
+		return exitCodeUsage
+	}
+
+	log.Print(logPainter.paint(colorRed, err.Error()))
+	var exitErr *exitCodeError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
+	}
+
+	return 1
 }
 
 type cliArgs struct {
-	Postcode       string `arg:"required"`
-	PriceMin       *uint64
-	PriceMax       *uint64
-	BedsMin        *uint32
-	BedsMax        *uint32
-	Radius         uint32
-	OutputFilename string
+	Postcode               string
+	PriceMin               *uint64
+	PriceMax               *uint64
+	BedsMin                *uint32
+	BedsMax                *uint32
+	Radius                 uint32
+	OutputFilename         string
+	TopAgents              int           `arg:"--top-agents"`
+	Delay                  time.Duration `arg:"--delay"`
+	Retries                int           `arg:"--retries"`
+	FetchDetails           bool          `arg:"--fetch-details"`
+	DetailWorkers          int           `arg:"--detail-workers"`
+	Parser                 string        `arg:"--parser"`
+	SaveHTML               string        `arg:"--save-html"`
+	Diagnostics            string        `arg:"--diagnostics"`
+	IncludeCategory        []string      `arg:"--include-category,separate"`
+	DedupeFuzzy            bool          `arg:"--dedupe-fuzzy"`
+	IncludeSharedOwnership bool          `arg:"--include-shared-ownership"`
+	GrossingUp             bool          `arg:"--grossing-up"`
+	IncludeAuctionsInStats bool          `arg:"--include-auctions-in-stats"`
+	MaxStationDistance     *float64      `arg:"--max-station-distance"`
+	RecordFixture          string        `arg:"--record-fixture"`
+	BaseURL                string        `arg:"--base-url"`
+	Format                 string        `arg:"--format"`
+	Pretty                 bool          `arg:"--pretty"`
+	SortPrices             bool          `arg:"--sort-prices"`
+	OutputMode             string        `arg:"--output-mode"`
+	Output                 string        `arg:"--output"`
+	Append                 bool          `arg:"--append"`
+	StatsFile              string        `arg:"--stats-file"`
+	Compress               bool          `arg:"--compress"`
+	Combined               bool          `arg:"--combined"`
+	WithMetadata           bool          `arg:"--with-metadata"`
+	Histogram              bool          `arg:"--histogram"`
+	BucketSize             uint64        `arg:"--bucket-size"`
+	ReportMD               string        `arg:"--report-md"`
+	ReportHTML             string        `arg:"--report-html"`
+	Chart                  string        `arg:"--chart"`
+	PushGateway            string        `arg:"--push-gateway"`
+	PushJob                string        `arg:"--push-job"`
+	PushRequired           bool          `arg:"--push-required"`
+	Webhook                string        `arg:"--webhook"`
+	WebhookIncludePrices   bool          `arg:"--webhook-include-prices"`
+	WebhookSecret          string        `arg:"--webhook-secret,env:WEBHOOK_SECRET"`
+	WebhookSignatureHeader string        `arg:"--webhook-signature-header"`
+	WebhookRequired        bool          `arg:"--webhook-required"`
+	SlackWebhook           string        `arg:"--slack-webhook,env:SLACK_WEBHOOK"`
+	EmailTo                string        `arg:"--email-to"`
+	EmailFrom              string        `arg:"--email-from"`
+	SMTPHost               string        `arg:"--smtp-host,env:SMTP_HOST"`
+	SMTPUser               string        `arg:"--smtp-user,env:SMTP_USER"`
+	SMTPPass               string        `arg:"--smtp-pass,env:SMTP_PASS"`
+	SMTPTLS                string        `arg:"--smtp-tls"`
+	InfluxURL              string        `arg:"--influx-url"`
+	InfluxToken            string        `arg:"--influx-token,env:INFLUX_TOKEN"`
+	InfluxOrg              string        `arg:"--influx-org"`
+	InfluxBucket           string        `arg:"--influx-bucket"`
+	InfluxListings         bool          `arg:"--influx-listings"`
+	SheetsID               string        `arg:"--sheets-id"`
+	SheetsCredentials      string        `arg:"--sheets-credentials,env:GOOGLE_APPLICATION_CREDENTIALS"`
+	SheetsTab              string        `arg:"--sheets-tab"`
+	SheetsRequired         bool          `arg:"--sheets-required"`
+	UploadURL              string        `arg:"--upload-url"`
+	UploadEndpoint         string        `arg:"--upload-endpoint"`
+	UploadRegion           string        `arg:"--upload-region"`
+	UploadKeyTemplate      string        `arg:"--upload-key-template"`
+	NoHeader               bool          `arg:"--no-header"`
+	Retain                 uint          `arg:"--retain"`
+	ByBeds                 bool          `arg:"--by-beds"`
+	Quiet                  bool          `arg:"--quiet"`
+	NoColor                bool          `arg:"--no-color"`
+	URLsFile               string        `arg:"--urls-file"`
+	Columns                string        `arg:"--columns"`
+	Manifest               string        `arg:"--manifest"`
+	SQLDialect             string        `arg:"--sql-dialect"`
+	Percentiles            string        `arg:"--percentiles"`
+	TrimOutliers           string        `arg:"--trim-outliers"`
+	TrimmedMean            float64       `arg:"--trimmed-mean"`
+	Winsorize              float64       `arg:"--winsorize"`
+	MinGroupSize           int           `arg:"--min-group-size"`
+	ASCIIHist              bool          `arg:"--ascii-hist"`
+	CILevel                float64       `arg:"--ci-level"`
+	BootstrapResamples     int           `arg:"--bootstrap-resamples"`
+	Seed                   *int64        `arg:"--seed"`
+	MinSample              int           `arg:"--min-sample"`
+	MaxCV                  float64       `arg:"--max-cv"`
+	Bands                  string        `arg:"--bands"`
+	Rank                   string        `arg:"--rank"`
+	Budget                 *uint64       `arg:"--budget"`
+	StampDuty              string        `arg:"--stamp-duty"`
+	MortgageRate           *float64      `arg:"--mortgage-rate"`
+	Deposit                float64       `arg:"--deposit"`
+	Term                   uint          `arg:"--term"`
+	Income                 *uint64       `arg:"--income"`
+	LTI                    float64       `arg:"--lti"`
+	OnlyAffordable         bool          `arg:"--only-affordable"`
+	Baseline               string        `arg:"--baseline"`
+	AlertThreshold         float64       `arg:"--alert-threshold"`
+	BaselineRuns           int           `arg:"--baseline-runs"`
+	Regression             bool          `arg:"--regression"`
+	RegressionTypes        bool          `arg:"--regression-types"`
+	SortByValue            bool          `arg:"--sort-by-value"`
+	ByType                 bool          `arg:"--by-type"`
+	AgentMargin            float64       `arg:"--agent-margin"`
+	LandRegistry           bool          `arg:"--land-registry"`
+	LandRegistryMonths     int           `arg:"--land-registry-months"`
+	KDE                    bool          `arg:"--kde"`
+	KDEBandwidth           float64       `arg:"--kde-bandwidth"`
+	OutputSet              string        `arg:"--output-set"`
+	PostcodeFile           string        `arg:"--postcode-file"`
+	Yield                  bool          `arg:"--yield"`
+	RentBaseURL            string        `arg:"--rent-base-url"`
+	StaleDays              int           `arg:"--stale-days"`
+	Stalest                int           `arg:"--stalest"`
+
+	// Mode is set for the rental search made for --yield rather than by a
+	// flag.
+	Mode searchMode `arg:"-"`
 }
 
+// run dispatches to the subcommand named by the first argument. Without
+// one, the arguments are the fetch subcommand's, as they were before there
+// were subcommands.
 func run(ctx context.Context) error {
-	args := parseArgs()
-	prices, err := getAllPrices(&args)
+	if len(os.Args) > 1 {
+		if os.Args[1] == "help" {
+			return runHelp()
+		}
+		if cmd := lookupSubcommand(os.Args[1]); cmd != nil {
+			return cmd.run(ctx, os.Args[2:])
+		}
+	}
+
+	args, err := parseArgs()
 	if err != nil {
 		return err
 	}
+	return runFetch(ctx, args)
+}
+
+// runFetch searches for the postcodes in args, separately unless they're
+// combined.
+func runFetch(ctx context.Context, args cliArgs) error {
+	postcodes := splitPostcodes(args.Postcode)
+	if len(postcodes) > 1 && !args.Combined {
+		return runPerPostcode(ctx, &args, postcodes)
+	}
+
+	_, _, err := runSearch(ctx, &args, postcodes, timeNow())
+	return err
+}
+
+// runSearch fetches the listings for each of postcodes into a single output
+// and logs their stats. It returns the number of prices written and, if any
+// prices were left to calculate them from, the price stats. Output filenames
+// are expanded for a run starting at now.
+func runSearch(ctx context.Context, args *cliArgs, postcodes []string, now time.Time) (_ int, _ *priceStats, err error) {
+	templates := args
+	args, err = expandOutputFilenames(args, now)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var base baseline
+	if args.Baseline != "" {
+		if base, err = loadBaseline(args); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	f := newFetcher(args)
+	diag := newDiagnostics(args)
+
+	var nd *ndjsonWriter
+	var onPage func([]listing) error
+	if outputFormat(args) == formatNDJSON {
+		nd, err = newNDJSONWriter(args)
+		if err != nil {
+			return 0, nil, err
+		}
+		// A failed close can lose the lines still buffered by gzip or the
+		// OS, so it fails the run unless something else already has.
+		defer func() {
+			if closeErr := nd.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		onPage = nd.writePage
+	}
+
+	listings, err := getQueryListings(ctx, f, diag, args, postcodes, onPage)
+	if diagErr := diag.write(); diagErr != nil {
+		log.Print(diagErr)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	counts := outputCounts{Parsed: len(listings)}
+	listings = filterCategories(listings, args.IncludeCategory)
+
+	listings, comingSoon := splitComingSoon(listings)
+	assignPostcodes(comingSoon)
+	assignIdentity(comingSoon)
+	comingSoon = dedupeByID(comingSoon)
+	counts.ComingSoon = len(comingSoon)
+	if len(comingSoon) > 0 {
+		log.Printf("left %d coming soon listings out of the prices", len(comingSoon))
+	}
+
+	if args.FetchDetails {
+		fetchAllDetails(ctx, f, listings, args.DetailWorkers)
+	}
+
+	assignPostcodes(listings)
+	assignIdentity(listings)
+	if args.StampDuty != "" {
+		assignStampDuty(listings, args.StampDuty)
+	}
+
+	var regression *priceRegression
+	if args.Regression {
+		r, err := fitPriceRegression(listings, args.RegressionTypes)
+		if err != nil {
+			log.Print("skipped regression: ", err)
+		} else {
+			regression = r
+			log.Print("regression: ", r)
+			assignValueScores(listings, r)
+			if args.SortByValue {
+				sortByValue(listings)
+			}
+		}
+	}
+
+	var affordable *budgetCoverage
+	if args.Income != nil {
+		a := newAffordability(args)
+		log.Print("affordability: ", a.derivation())
+		c := calculateBudgetCoverage(listings, uint64(a.maxPrice), a.cost)
+		affordable = &c
+		log.Print("affordable: ", c)
+		if args.OnlyAffordable {
+			listings = filterAffordable(listings, a)
+			log.Printf("kept %d affordable listings", len(listings))
+		}
+	}
+
+	if args.MaxStationDistance != nil {
+		listings = filterStationDistance(listings, *args.MaxStationDistance)
+	}
+
+	rawListings := listings
+	listings = dedupeByID(listings)
+	if args.DedupeFuzzy {
+		listings = dedupeFuzzy(listings)
+	}
+	counts.Deduped = len(rawListings) - len(listings)
+
+	prices := listingPrices(listings)
 
 	log.Printf("got %d prices", len(prices))
 	if len(prices) == 0 {
-		return nil
+		return 0, nil, writeManifest(args, now, f, diag, counts)
 	}
 
-	if err := writePrices(prices, args.OutputFilename); err != nil {
-		return err
+	if args.IncludeSharedOwnership {
+		flagMissingShares(listings)
 	}
-	log.Print("wrote price data to ", args.OutputFilename)
 
-	stats := calculatePriceStats(prices)
-	log.Print("price stats: ", stats)
+	if nd != nil {
+		err = nd.writeSummary(calculatePriceStats(prices))
+	} else {
+		var meta *outputMetadata
+		if args.WithMetadata {
+			meta = newOutputMetadata(args, diag, counts)
+			if args.Histogram {
+				meta.Histogram = calculateHistogram(prices, args.BucketSize)
+			}
+		}
+		outListings := listings
+		if args.OutputSet == outputSetRaw {
+			outListings = rawListings
+		}
+		if args.OutputMode == outputModeListings && !args.Append {
+			// Coming soon listings have no price, but are still listed for
+			// anyone tracking upcoming supply.
+			outListings = append(outListings[:len(outListings):len(outListings)], comingSoon...)
+		}
+		err = writePrices(outListings, args, meta)
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+	if args.OutputFilename == stdoutFilename {
+		log.Print("wrote price data to stdout")
+	} else {
+		log.Print("wrote price data to ", args.OutputFilename)
+	}
+
+	if args.URLsFile != "" {
+		if err := writeFileAtomic(args.URLsFile, renderURLs(listings)); err != nil {
+			return 0, nil, err
+		}
+		log.Print("wrote listing URLs to ", args.URLsFile)
+	}
 
-	return nil
+	if args.Output != "" {
+		if err := saveToStore(ctx, args.Output, args, listings); err != nil {
+			return 0, nil, err
+		}
+		log.Print("saved run to ", args.Output)
+	}
+
+	statsListings := listings
+	if !args.IncludeAuctionsInStats {
+		var auctions []listing
+		statsListings, auctions = splitAuctions(listings)
+		if len(auctions) > 0 {
+			log.Print("auction stats: ", calculateAuctionStats(auctions))
+		}
+	}
+
+	statsPrices := listingPrices(statsListings)
+	if args.GrossingUp {
+		log.Print("raw price stats: ", calculatePriceStats(statsPrices))
+		statsPrices = grossedUpPrices(statsListings)
+	}
+
+	if args.TrimOutliers != "" {
+		statsPrices = trimStatsPrices(statsPrices, args.TrimOutliers)
+	}
+
+	var sold *soldComparison
+	if args.LandRegistry {
+		if sold = runSoldComparison(ctx, f, args, postcodes, statsPrices); sold != nil {
+			log.Print("sold prices: ", *sold)
+		}
+	}
+
+	var yield *yieldEstimate
+	if args.Yield {
+		if yield = runYieldEstimate(ctx, f, args, postcodes, statsListings); yield != nil {
+			log.Print("rental yield: ", *yield)
+			for _, g := range yield.groups {
+				log.Print("rental yield by beds ", g)
+			}
+		}
+	}
+
+	// The raw prices are only kept when de-duplication removed some, to
+	// show how much the duplicates moved the stats.
+	var rawPrices []uint64
+	if counts.Deduped > 0 {
+		rawPrices = rawStatsPrices(rawListings, args)
+	}
+
+	var stats *priceStats
+	if len(statsPrices) > 0 {
+		opts := newStatsOptions(args)
+		s := calculatePriceStatsWith(statsPrices, opts)
+		stats = &s
+		log.Print("price stats: ", s)
+		var rawStats *priceStats
+		if len(rawPrices) > 0 {
+			r := calculatePriceStatsWith(rawPrices, statsOptions{percentiles: opts.percentiles})
+			rawStats = &r
+			log.Printf("price stats including %d duplicates: %v", counts.Deduped, r)
+		}
+		log.Print("box plot: ", *s.box)
+		if s.lognormal != nil {
+			if s.lognormal.Excluded > 0 {
+				log.Printf("warning: left %d zero prices out of the log-normal fit", s.lognormal.Excluded)
+			}
+			log.Print("log-normal fit: ", *s.lognormal)
+		}
+		if s.shape != nil {
+			log.Print("distribution shape: ", *s.shape)
+			log.Print("normality: ", *s.normality)
+		} else {
+			log.Printf("skipped skewness and kurtosis, which need at least %d varying prices", minShapeSample)
+		}
+		for _, w := range sampleWarnings(s, *opts.thresholds) {
+			log.Print("warning: ", w)
+		}
+		if s.intervals != nil {
+			log.Print("confidence intervals: ", s.intervals.describe(s))
+		} else {
+			log.Printf("skipped confidence intervals, which need at least %d prices", minBootstrapSample)
+		}
+		logPriceExtremes(statsListings)
+		if args.Rank != "" {
+			logPercentileRanks(statsPrices, args.Rank)
+		}
+
+		var bedGroups []groupStats
+		if groups := orderedGroupStats(statsListings, bedsKey, args.MinGroupSize); !onlyUnknownGroup(groups) {
+			bedGroups = groups
+			for _, g := range groups {
+				log.Print("beds ", g)
+			}
+		}
+
+		typeGroups := reliableGroups(orderedGroupStats(statsListings, propertyTypeKey, args.MinGroupSize))
+		for _, g := range typeGroups {
+			log.Print("type ", g)
+		}
+		flatsHouses := compareFlatsAndHouses(statsListings, args.MinGroupSize)
+		if flatsHouses != nil {
+			log.Print("flats vs houses: ", *flatsHouses)
+		}
+		newBuilds := compareNewBuilds(statsListings, args.MinGroupSize)
+		if newBuilds != nil {
+			log.Print("new builds vs resales: ", *newBuilds)
+		}
+
+		var agents []agentStats
+		if args.TopAgents > 0 {
+			agents = calculateAgentStats(statsListings, args.TopAgents, args.AgentMargin)
+			for _, a := range agents {
+				log.Print("agent: ", a)
+			}
+			if anyFlaggedAgent(agents) {
+				log.Printf("* median is more than %s from the area median", fractionPercent(args.AgentMargin))
+			}
+		}
+
+		softness := calculateMarketSoftness(statsListings)
+		log.Print("market softness: ", softness)
+
+		onMarket := calculateTimeOnMarket(statsListings, timeNow(), args.StaleDays, args.Stalest)
+		log.Print("time on market: ", onMarket)
+		for _, sl := range onMarket.stalest {
+			log.Print("stale listing: ", sl)
+		}
+
+		perSqft := calculatePricePerSqftStats(statsListings)
+		log.Print("price per sq ft: ", perSqft)
+		for _, g := range perSqft.byType {
+			log.Print("price per sq ft by type ", g)
+		}
+		if perSqft.lowCoverage() {
+			log.Printf("warning: only %.0f%% of listings have a floor area, so price per sq ft may be biased towards new builds", 100*perSqft.coverage())
+		}
+
+		perBed := calculatePricePerBedStats(statsListings)
+		log.Print("price per bedroom: ", perBed)
+		for _, g := range perBed.byType {
+			log.Print("price per bedroom by type ", g)
+		}
+
+		var bands []bandCount
+		if args.Bands != "" {
+			bands = calculateBandCounts(statsPrices, bandEdges(args))
+			strs := make([]string, len(bands))
+			for i, b := range bands {
+				strs[i] = b.String()
+			}
+			log.Print("price bands: ", strings.Join(strs, ", "))
+		}
+
+		var budget *budgetCoverage
+		if args.Budget != nil {
+			c := calculateBudgetCoverage(statsListings, *args.Budget, listingPrice)
+			budget = &c
+			log.Print("budget: ", c)
+			for _, g := range c.byBeds {
+				log.Print("budget by beds ", g)
+			}
+		}
+
+		var duty *stampDutyStats
+		if args.StampDuty != "" {
+			d := calculateStampDutyStats(statsListings, args.StampDuty)
+			duty = &d
+			log.Print("stamp duty: ", d)
+		}
+
+		var mortgage *mortgageStats
+		if args.MortgageRate != nil {
+			m := calculateMortgageStats(statsListings, newMortgageTerms(args))
+			mortgage = &m
+			log.Print("mortgage: ", m)
+		}
+
+		var kde *kernelDensity
+		if args.KDE {
+			if kde = calculateKDE(statsPrices, args.KDEBandwidth); kde != nil {
+				log.Print("kernel density: ", *kde)
+			} else {
+				log.Print("skipped kernel density, which needs varying prices or --kde-bandwidth")
+			}
+		}
+
+		var histogram []histogramBucket
+		if args.Histogram {
+			histogram = calculateHistogram(statsPrices, args.BucketSize)
+			for _, b := range histogram {
+				log.Print("histogram ", b)
+			}
+		}
+
+		if args.StatsFile != "" {
+			out := statsOutput{
+				Stats:     s,
+				Histogram: histogram,
+				Beds:      bedGroups,
+				Types:     typeGroups,
+				Bands:     bands,
+				Budget:    budget,
+				StampDuty: duty,
+				Mortgage:  mortgage,
+
+				Affordability: affordable,
+				Regression:    regression,
+				FlatsVsHouses: flatsHouses,
+				NewBuilds:     newBuilds,
+				Agents:        agents,
+				Softness:      &softness,
+				OnMarket:      &onMarket,
+				Sold:          sold,
+				Yield:         yield,
+				KDE:           kde,
+				RawStats:      rawStats,
+				Duplicates:    counts.Deduped,
+			}
+			if perBed.all.Count > 0 {
+				out.PricePerBed = &perBed
+			}
+			if perSqft.all.Count > 0 {
+				out.PricePerSqft = &perSqft
+			}
+			if err := writeStatsFile(args.StatsFile, args, out); err != nil {
+				return 0, nil, err
+			}
+			log.Print("wrote stats to ", args.StatsFile)
+		}
+	}
+
+	if args.PushGateway != "" {
+		if err := pushMetrics(ctx, args, statsPrices); err != nil {
+			if args.PushRequired {
+				return 0, nil, err
+			}
+			log.Print(err)
+		} else {
+			log.Print("pushed metrics to ", args.PushGateway)
+		}
+	}
+
+	if args.InfluxURL != "" {
+		if err := writeInflux(ctx, args, statsListings); err != nil {
+			log.Print("warning: ", err)
+		} else {
+			log.Print("wrote points to InfluxDB")
+		}
+	}
+
+	if args.SheetsID != "" {
+		if err := sendToSheets(ctx, args, listings); err != nil {
+			if args.SheetsRequired {
+				return 0, nil, err
+			}
+			log.Print("warning: ", err)
+		} else {
+			log.Print("exported listings to Google Sheets")
+		}
+	}
+
+	if args.Webhook != "" {
+		meta := newOutputMetadata(args, diag, counts)
+		if err := sendWebhook(ctx, args, meta, stats, listingPrices(listings)); err != nil {
+			if args.WebhookRequired {
+				return 0, nil, err
+			}
+			log.Print("warning: ", err)
+		} else {
+			log.Print("sent results to webhook")
+		}
+	}
+
+	if args.Chart != "" && len(statsPrices) > 0 {
+		chart, err := renderChart(statsPrices, args)
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := writeFileAtomic(args.Chart, chart); err != nil {
+			return 0, nil, err
+		}
+		log.Print("wrote chart to ", args.Chart)
+	}
+
+	if args.ReportMD != "" || args.ReportHTML != "" || args.SlackWebhook != "" || args.EmailTo != "" {
+		report := buildReport(listings, statsPrices, args)
+		if sold != nil {
+			report.Sold = sold.String()
+		}
+		if yield != nil {
+			report.Yield = yield.String()
+			report.YieldGroups = reportYieldGroups(yield.groups)
+		}
+		if err := writeReports(report, args); err != nil {
+			return 0, nil, err
+		}
+
+		if args.SlackWebhook != "" {
+			if err := sendSlack(ctx, args, report); err != nil {
+				log.Print("warning: ", err)
+			} else {
+				log.Print("posted summary to Slack")
+			}
+		}
+
+		if args.EmailTo != "" {
+			if err := sendEmail(args, report, listings); err != nil {
+				log.Print("warning: ", err)
+			} else {
+				log.Print("emailed summary to ", args.EmailTo)
+			}
+		}
+	}
+
+	if args.UploadURL != "" {
+		if err := uploadArtifacts(ctx, args); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	for _, f := range outputFilenames(templates) {
+		if err := pruneOutputs(*f, args, args.Retain); err != nil {
+			log.Print("warning: ", err)
+		}
+	}
+
+	if args.FetchDetails {
+		log.Print("reduction stats: ", calculateReductionStats(listings))
+		for _, s := range calculateEPCStats(listings) {
+			log.Print("EPC band ", s)
+		}
+	}
+
+	log.Print("station stats: ", calculateStationStats(listings))
+	log.Print("time to reduction: ", calculateTimeToReductionStats(listings))
+
+	for _, g := range calculateGroupStats(listings, outcodeKey) {
+		log.Print("outcode ", g)
+	}
+
+	if err := writeManifest(args, now, f, diag, counts); err != nil {
+		return 0, nil, err
+	}
+
+	if !args.Quiet && len(statsPrices) > 0 {
+		rows := summaryRows(statsListings, args.ByBeds, args.ByType, args.MinGroupSize, func(ls []listing) []uint64 {
+			if args.GrossingUp {
+				return grossedUpPrices(ls)
+			}
+			return listingPrices(ls)
+		})
+		if len(rawPrices) > 0 {
+			rows = append(rows, newSummaryRow("raw", rawPrices))
+		}
+		fmt.Fprint(os.Stderr, "\n", renderSummaryTable(rows, logPainter))
+		if perSqft := calculatePricePerSqftStats(statsListings); perSqft.all.Count > 0 {
+			fmt.Fprint(os.Stderr, renderSqftSummary(perSqft))
+		}
+		if onMarket := calculateTimeOnMarket(statsListings, timeNow(), args.StaleDays, 0); onMarket.dated > 0 {
+			fmt.Fprint(os.Stderr, renderOnMarketSummary(onMarket))
+		}
+		if counts.ComingSoon > 0 {
+			fmt.Fprintf(os.Stderr, "coming soon: %d listings without a price\n", counts.ComingSoon)
+		}
+	}
+
+	if args.ASCIIHist && len(statsPrices) > 0 {
+		printASCIIHistogram(statsPrices, args)
+	}
+
+	if args.Baseline != "" && stats != nil {
+		if err := compareBaseline(args, *stats, base); err != nil {
+			return len(prices), stats, err
+		}
+		log.Print("stats are within the alert threshold of the baseline")
+	}
+
+	return len(prices), stats, nil
 }
 
-func parseArgs() cliArgs {
-	cli := cliArgs{OutputFilename: defaultOutputFilename}
-	arg.MustParse(&cli)
-	return cli
+// getQueryListings gets every postcode's listings in turn. When there's more
+// than one postcode each listing is tagged with the one it was found under.
+func getQueryListings(ctx context.Context, f *fetcher, diag *diagnostics, args *cliArgs, postcodes []string, onPage func([]listing) error) ([]listing, error) {
+	var listings []listing
+	for _, pc := range postcodes {
+		q := *args
+		q.Postcode = pc
+
+		pcListings, err := getAllListings(ctx, f, diag, &q, func(page []listing) error {
+			if len(postcodes) > 1 {
+				for i := range page {
+					page[i].SearchPostcode = pc
+				}
+			}
+			if onPage != nil {
+				return onPage(page)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "while getting listings for %s", pc)
+		}
+
+		listings = append(listings, pcListings...)
+	}
+
+	return listings, nil
 }
 
-func getAllPrices(args *cliArgs) ([]uint64, error) {
-	var allPrices []uint64
+func parseArgs() (cliArgs, error) {
+	return parseSearchArgs(func(cli *cliArgs) (*arg.Parser, error) {
+		return parseCommandLine(programName, cli, os.Args[1:])
+	})
+}
+
+// parseSearchArgs fills in the defaults, parses the arguments with parse and
+// validates them, so that subcommands running searches share the flags. An
+// error from parse, such as errHelp, is returned without validating, and
+// invalid arguments are a usageError.
+func parseSearchArgs(parse func(cli *cliArgs) (*arg.Parser, error)) (cliArgs, error) {
+	cli := cliArgs{
+		BaseURL:        baseURL,
+		OutputFilename: defaultOutputFilename,
+		TopAgents:      defaultTopAgents,
+		Delay:          defaultDelay,
+		Retries:        defaultRetries,
+		DetailWorkers:  defaultDetailWorkers,
+		MinGroupSize:   defaultMinGroupSize,
+
+		CILevel:            defaultCILevel,
+		BootstrapResamples: defaultBootstrapResamples,
+		MinSample:          defaultMinSample,
+		MaxCV:              defaultMaxCV,
+		Term:               defaultMortgageTerm,
+		LTI:                defaultLTI,
+		AlertThreshold:     defaultAlertThreshold,
+		AgentMargin:        defaultAgentMargin,
+		LandRegistryMonths: defaultLandRegistryMonths,
+		StaleDays:          defaultStaleDays,
+		Stalest:            defaultStalestTop,
+		RentBaseURL:        rentBaseURL,
+		OutputSet:          outputSetDeduped,
+		BaselineRuns:       defaultBaselineRuns,
+	}
+	p, err := parse(&cli)
+	if err != nil {
+		return cli, err
+	}
+	setupLogColor(cli.NoColor)
+	if cli.Parser != "" && cli.Parser != streamingParserName && lookupParser(cli.Parser) == nil {
+		return cli, newUsageError(p, fmt.Sprintf("unknown parser %q, must be one of %s", cli.Parser, strings.Join(parserNames(), ", ")))
+	}
+
+	if cli.RecordFixture != "" && cli.Parser == streamingParserName {
+		return cli, newUsageError(p, "--record-fixture needs a DOM parser and cannot be used with --parser streaming")
+	}
+
+	if cli.PostcodeFile != "" {
+		fromFile, err := readPostcodeFile(cli.PostcodeFile)
+		if err != nil {
+			return cli, newUsageError(p, err.Error())
+		}
+		cli.Postcode = strings.Join(append(splitPostcodes(cli.Postcode), fromFile...), ",")
+	}
+
+	postcodes := splitPostcodes(cli.Postcode)
+	if len(postcodes) == 0 {
+		return cli, newUsageError(p, "no postcode given, use --postcode or --postcode-file")
+	}
+	if pc := duplicatePostcode(postcodes); pc != "" {
+		return cli, newUsageError(p, fmt.Sprintf("postcode %q given more than once", pc))
+	}
+	if len(postcodes) > 1 && !cli.Combined && cli.OutputFilename == stdoutFilename {
+		return cli, newUsageError(p, "writing to stdout with several postcodes needs --combined")
+	}
+
+	if cli.KDEBandwidth < 0 {
+		return cli, newUsageError(p, "--kde-bandwidth cannot be negative")
+	}
+	if cli.KDEBandwidth > 0 && !cli.KDE {
+		return cli, newUsageError(p, "--kde-bandwidth needs --kde")
+	}
+
+	if cli.BucketSize > 0 && !cli.Histogram && !cli.ASCIIHist {
+		return cli, newUsageError(p, "--bucket-size needs --histogram or --ascii-hist")
+	}
+
+	if cli.SMTPTLS == "" {
+		cli.SMTPTLS = smtpTLSStartTLS
+	} else if !containsString(smtpTLSModes, cli.SMTPTLS) {
+		return cli, newUsageError(p, fmt.Sprintf("unknown SMTP TLS mode %q, must be one of %s", cli.SMTPTLS, strings.Join(smtpTLSModes, ", ")))
+	}
+	if cli.EmailTo != "" && cli.SMTPHost == "" {
+		return cli, newUsageError(p, "--email-to needs --smtp-host")
+	}
+
+	if cli.InfluxURL != "" && cli.InfluxBucket == "" {
+		return cli, newUsageError(p, "--influx-url needs --influx-bucket")
+	}
+
+	if cli.SheetsID != "" && cli.SheetsCredentials == "" {
+		return cli, newUsageError(p, "--sheets-id needs --sheets-credentials")
+	}
+
+	if cli.OutputMode == "" {
+		cli.OutputMode = outputModePrices
+	} else if !containsString(outputModes, cli.OutputMode) {
+		return cli, newUsageError(p, fmt.Sprintf("unknown output mode %q, must be one of %s", cli.OutputMode, strings.Join(outputModes, ", ")))
+	}
+
+	if !containsString(outputSets, cli.OutputSet) {
+		return cli, newUsageError(p, fmt.Sprintf("unknown output set %q, must be one of %s", cli.OutputSet, strings.Join(outputSets, ", ")))
+	}
+
+	if cli.Append && (cli.OutputFilename == stdoutFilename || cli.OutputMode != outputModePrices || outputFormat(&cli) != formatJSON) {
+		return cli, newUsageError(p, "--append requires JSON price output to a file")
+	}
+
+	if cli.TrimOutliers != "" {
+		if _, err := parseOutlierRule(cli.TrimOutliers); err != nil {
+			return cli, newUsageError(p, err.Error())
+		}
+	}
+
+	if cli.MinGroupSize < 0 {
+		return cli, newUsageError(p, "--min-group-size must not be negative")
+	}
+
+	if cli.MortgageRate != nil && *cli.MortgageRate < 0 {
+		return cli, newUsageError(p, "--mortgage-rate must not be negative")
+	}
+
+	if cli.Deposit < 0 {
+		return cli, newUsageError(p, "--deposit must not be negative")
+	}
+
+	if cli.MortgageRate != nil && cli.Term == 0 {
+		return cli, newUsageError(p, "--term must be at least a year")
+	}
+
+	if (cli.RegressionTypes || cli.SortByValue) && !cli.Regression {
+		return cli, newUsageError(p, "--regression-types and --sort-by-value need --regression")
+	}
+
+	if cli.SortByValue && cli.SortPrices {
+		return cli, newUsageError(p, "--sort-by-value and --sort-prices cannot be used together")
+	}
+
+	if cli.LandRegistryMonths <= 0 {
+		return cli, newUsageError(p, "--land-registry-months must be positive")
+	}
+
+	if cli.StaleDays <= 0 {
+		return cli, newUsageError(p, "--stale-days must be positive")
+	}
+
+	if cli.Stalest < 0 {
+		return cli, newUsageError(p, "--stalest cannot be negative")
+	}
+
+	if cli.AgentMargin < 0 {
+		return cli, newUsageError(p, "--agent-margin cannot be negative")
+	}
+
+	if cli.AlertThreshold <= 0 {
+		return cli, newUsageError(p, "--alert-threshold must be positive")
+	}
+
+	if cli.BaselineRuns <= 0 {
+		return cli, newUsageError(p, "--baseline-runs must be positive")
+	}
+
+	if cli.LTI <= 0 {
+		return cli, newUsageError(p, "--lti must be positive")
+	}
+
+	if cli.OnlyAffordable && cli.Income == nil {
+		return cli, newUsageError(p, "--only-affordable needs --income")
+	}
+
+	if cli.StampDuty != "" && !validBuyerType(cli.StampDuty) {
+		return cli, newUsageError(p, fmt.Sprintf("unknown buyer type %q for --stamp-duty, must be one of %s", cli.StampDuty, buyerTypeList()))
+	}
+
+	if cli.Rank != "" {
+		if _, err := parseRankPrices(cli.Rank); err != nil {
+			return cli, newUsageError(p, err.Error())
+		}
+	}
+
+	if cli.Bands != "" {
+		if _, err := parseBandEdges(cli.Bands); err != nil {
+			return cli, newUsageError(p, err.Error())
+		}
+	}
+
+	if cli.MinSample < 0 {
+		return cli, newUsageError(p, "--min-sample must not be negative")
+	}
+
+	if cli.MaxCV < 0 {
+		return cli, newUsageError(p, "--max-cv must not be negative")
+	}
+
+	if cli.CILevel <= 0 || cli.CILevel >= 1 {
+		return cli, newUsageError(p, "--ci-level must be between 0 and 1")
+	}
+
+	if cli.BootstrapResamples <= 0 {
+		return cli, newUsageError(p, "--bootstrap-resamples must be positive")
+	}
+
+	if err := validateMeanFraction("--trimmed-mean", cli.TrimmedMean); err != nil {
+		return cli, newUsageError(p, err.Error())
+	}
+
+	if err := validateMeanFraction("--winsorize", cli.Winsorize); err != nil {
+		return cli, newUsageError(p, err.Error())
+	}
+
+	if _, err := parsePercentiles(cli.Percentiles); err != nil {
+		return cli, newUsageError(p, err.Error())
+	}
+
+	if _, err := selectColumns(splitColumns(cli.Columns)); err != nil {
+		return cli, newUsageError(p, err.Error())
+	}
+
+	for _, f := range outputFilenames(&cli) {
+		if err := validateFilenameTemplate(*f); err != nil {
+			return cli, newUsageError(p, err.Error())
+		}
+	}
+
+	if cli.Format != "" && !containsString(outputFormats, cli.Format) {
+		return cli, newUsageError(p, fmt.Sprintf("unknown format %q, must be one of %s", cli.Format, strings.Join(outputFormats, ", ")))
+	}
+
+	if cli.SQLDialect == "" {
+		cli.SQLDialect = defaultSQLDialect
+	} else if sqlDialects[cli.SQLDialect] == nil {
+		return cli, newUsageError(p, fmt.Sprintf("unknown SQL dialect %q, must be postgres or sqlite", cli.SQLDialect))
+	}
+
+	if outputFormat(&cli) == formatParquet && isCompressed(&cli) {
+		return cli, newUsageError(p, "Parquet output is already compressed, drop --compress")
+	}
+
+	for _, c := range cli.IncludeCategory {
+		if !isCategory(c) {
+			return cli, newUsageError(p, fmt.Sprintf("unknown category %q, must be one of %s", c, strings.Join(categories, ", ")))
+		}
+	}
+
+	return cli, nil
+}
+
+type listing struct {
+	ID       string `json:"id,omitempty"`
+	Price    uint64 `json:"price"`
+	Status   string `json:"status"`
+	Agent    string `json:"agent,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Category string `json:"category"`
+	// SearchPostcode is the postcode searched under, set only when a run
+	// covers more than one.
+	SearchPostcode string `json:"search_postcode,omitempty"`
+	// PropertyType is derived from the title, such as "flat" or
+	// "semi_detached".
+	PropertyType string `json:"property_type,omitempty"`
+	Address      string `json:"address,omitempty"`
+	// Location is only known when the page embeds structured data.
+	Location *coordinates `json:"location,omitempty"`
+
+	// Outcode and Sector are derived from the postcode at the end of the
+	// address, such as "SW2" and "SW2 1".
+	Outcode string `json:"outcode,omitempty"`
+	Sector  string `json:"sector,omitempty"`
+
+	// Qualifier is the normalised price qualifier, such as "guide" or
+	// "offers_over", and is empty for a plain asking price.
+	Qualifier string `json:"qualifier,omitempty"`
+	IsAuction bool   `json:"is_auction"`
+	IsReduced bool   `json:"is_reduced"`
+	// IsNewBuild is set by a "new home" badge on the card.
+	IsNewBuild bool `json:"is_new_build"`
+
+	ListedOn  *time.Time `json:"listed_on,omitempty"`
+	ReducedOn *time.Time `json:"reduced_on,omitempty"`
+
+	Stations   []station `json:"stations,omitempty"`
+	Beds       *uint32   `json:"beds,omitempty"`
+	Baths      *uint32   `json:"baths,omitempty"`
+	Receptions *uint32   `json:"receptions,omitempty"`
+
+	// FloorArea is in square feet. FloorAreaSource records whether it came
+	// from the card or the detail page.
+	FloorArea       *uint32 `json:"floor_area_sqft,omitempty"`
+	FloorAreaSource string  `json:"floor_area_source,omitempty"`
+
+	// StampDuty and TotalCost are only set with --stamp-duty.
+	StampDuty *uint64 `json:"stamp_duty,omitempty"`
+	TotalCost *uint64 `json:"total_cost,omitempty"`
+
+	// ValueScore is only set with --regression, and is how far the price
+	// is below the one fitted for the listing's bedrooms, as a percentage.
+	ValueScore *float64 `json:"value_score,omitempty"`
+
+	// Fields below are only populated when detail pages are fetched.
+	HasFloorplan bool         `json:"has_floorplan"`
+	Tenure       string       `json:"tenure,omitempty"`
+	EPCBand      string       `json:"epc_band,omitempty"`
+	Description  string       `json:"description,omitempty"`
+	PriceHistory []pricePoint `json:"price_history,omitempty"`
+
+	// SharePercent is the share on offer for shared-ownership listings,
+	// whose price only covers that share. It is nil when no share is shown.
+	SharedOwnership bool     `json:"shared_ownership"`
+	SharePercent    *float64 `json:"share_percent,omitempty"`
+
+	Flags []string `json:"flags,omitempty"`
+}
+
+func listingPrices(listings []listing) []uint64 {
+	prices := make([]uint64, len(listings))
+	for i := range listings {
+		prices[i] = listings[i].Price
+	}
+
+	return prices
+}
+
+// getAllListings fetches every page of results. If onPage is set it's called
+// with each page's listings as soon as they're parsed.
+func getAllListings(ctx context.Context, f *fetcher, diag *diagnostics, args *cliArgs, onPage func([]listing) error) ([]listing, error) {
+	var allListings []listing
 	for pageNum := uint32(1); ; pageNum++ {
-		prices, err := getPricesPage(args, pageNum)
+		listings, err := getListingsPage(ctx, f, diag, args, pageNum)
 		if err != nil {
 			return nil, errors.Wrapf(err, "while getting page %d", pageNum)
 		}
 
-		if len(prices) == 0 {
-			return allPrices, nil
+		if len(listings) == 0 {
+			return allListings, nil
 		}
 
-		allPrices = append(allPrices, prices...)
+		if onPage != nil {
+			if err := onPage(listings); err != nil {
+				return nil, err
+			}
+		}
+
+		allListings = append(allListings, listings...)
 	}
 }
 
-func getPricesPage(args *cliArgs, pageNum uint32) ([]uint64, error) {
+func getListingsPage(ctx context.Context, f *fetcher, diag *diagnostics, args *cliArgs, pageNum uint32) ([]listing, error) {
 	pageUrl, err := getPageUrl(args, pageNum)
 	if err != nil {
 		return nil, errors.Wrap(err, "while getting page URL")
 	}
 	log.Print("pageUrl = ", pageUrl)
 
-	pageHTML, err := getPageHTML(pageUrl)
+	if args.Parser == streamingParserName {
+		var listings []listing
+		var cardErrs []cardError
+		err := f.fetch(ctx, pageUrl, func(body io.Reader) error {
+			var err error
+			listings, cardErrs, err = parseStreaming(body, args.Mode)
+			return err
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "while streaming page contents")
+		}
+
+		diag.report(pageNum, cardErrs)
+		resolveListingURLs(listings, pageUrl)
+		return listings, nil
+	}
+
+	pageHTML, err := f.getHTML(ctx, pageUrl)
 	if err != nil {
 		return nil, errors.Wrap(err, "while getting page contents")
 	}
+	diag.savePage(pageNum, pageHTML)
+
+	if args.RecordFixture != "" {
+		if err := recordFixture(args.RecordFixture, args.Postcode, pageNum, pageHTML, args.Parser, args.Mode); err != nil {
+			log.Print(err)
+		}
+	}
 
-	return parseHTML(pageHTML), nil
+	listings, cardErrs := parseHTML(pageHTML, args.Parser, args.Mode)
+	diag.report(pageNum, cardErrs)
+	resolveListingURLs(listings, pageUrl)
+
+	return listings, nil
 }
 
 func getPageUrl(args *cliArgs, pageNum uint32) (*url.URL, error) {
-	u, err := url.Parse(baseURL)
+	u, err := url.Parse(args.BaseURL)
 	if err != nil {
 		return nil, err
 	}
@@ -127,40 +1189,14 @@ func getPageUrl(args *cliArgs, pageNum uint32) (*url.URL, error) {
 	q.Set("radius", strconv.FormatUint(uint64(args.Radius), 10))
 	q.Set("pn", strconv.FormatUint(uint64(pageNum), 10))
 	q.Set("is_retirement_home", "false")
-	q.Set("is_shared_ownership", "false")
+	if !args.IncludeSharedOwnership {
+		q.Set("is_shared_ownership", "false")
+	}
 	u.RawQuery = q.Encode()
 
 	return u, nil
 }
 
-func getPageHTML(pageUrl *url.URL) (*html.Node, error) {
-	rsp, err := http.Get(pageUrl.String())
-	if err != nil {
-		return nil, errors.Wrapf(err, "while making HTTP request to %s", pageUrl)
-	}
-
-	if rsp.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("unexpected status %s", rsp.Status)
-	}
-
-	doc, err := html.Parse(rsp.Body)
-	if err != nil {
-		return nil, errors.Wrap(err, "while parsing as HTML")
-	}
-
-	return doc, nil
-}
-
-func parseHTML(root *html.Node) []uint64 {
-	listings := findListingsContainer(root)
-	if listings == nil {
-		log.Print("no listings container in response")
-		return nil
-	}
-
-	return getPricesFromListings(listings)
-}
-
 func findListingsContainer(root *html.Node) *html.Node {
 	var parseHTMLNode func(n *html.Node) *html.Node
 	parseHTMLNode = func(n *html.Node) *html.Node {
@@ -186,86 +1222,317 @@ func findListingsContainer(root *html.Node) *html.Node {
 	return parseHTMLNode(root)
 }
 
-func getPricesFromListings(listings *html.Node) []uint64 {
-	var prices []uint64
+func getListingsFromContainer(container *html.Node, mode searchMode) ([]listing, []cardError) {
+	var listings []listing
+	var cardErrs []cardError
+	for i, card := range findAll(container, isListingCard) {
+		l, err := parseListingCard(card, mode)
+		if err != nil {
+			cardErrs = append(cardErrs, cardError{index: i, node: card, err: err})
+			continue
+		}
+		listings = append(listings, l)
+	}
 
-	var parseHTMLNode func(n *html.Node)
-	parseHTMLNode = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "div" {
-			for i := range n.Attr {
-				if n.Attr[i].Key != "class" {
-					continue
-				}
+	return listings, cardErrs
+}
 
-				if strings.Contains(n.Attr[i].Val, "PriceContainer") {
-					price, err := parsePriceNode(n)
-					if err != nil {
-						log.Print(err)
-						continue
-					}
-					prices = append(prices, price)
-				}
-			}
-		}
+func isListingCard(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.Data != "div" {
+		return false
+	}
 
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			parseHTMLNode(c)
-		}
+	testID, _ := getAttr(n, "data-testid")
+	return testID == "search-result"
+}
+
+func parseListingCard(card *html.Node, mode searchMode) (listing, error) {
+	// Search the whole card when the price container is missing, since the
+	// price is still usually the first amount on the card.
+	priceNode := findFirst(card, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "div" && hasClass(n, "PriceContainer")
+	})
+	if priceNode == nil {
+		priceNode = card
 	}
 
-	parseHTMLNode(listings)
+	text := joinedText(card)
+	status := parseCardStatus(card)
 
-	return prices
+	price, err := parsePriceNode(priceNode, mode)
+	if status == statusComingSoon {
+		// Any figure on a coming-soon card is a placeholder.
+		price = 0
+	} else if err != nil {
+		return listing{}, err
+	}
+
+	l := listing{
+		Price:     price,
+		Status:    status,
+		Agent:     parseAgent(card),
+		URL:       parseListingURL(card),
+		Title:     parseListingTitle(card),
+		Qualifier: parseQualifier(card),
+		Address:   parseListingAddress(card),
+		Stations:  parseStations(card),
+	}
+	l.Category = classifyListing(l.Title, "")
+	parseCardText(text, &l)
+
+	return l, nil
 }
 
-func parsePriceNode(node *html.Node) (uint64, error) {
-	for c := node.FirstChild; c != nil; c = c.NextSibling {
-		if c.Type == html.ElementNode && c.Data == "p" {
-			for i := range c.Attr {
-				if c.Attr[i].Key != "class" {
-					continue
-				}
+// parseCardText fills in the listing fields found by pattern matching over
+// the whole text of a card.
+func parseCardText(text string, l *listing) {
+	parseCardFloorArea(text, l)
+	parseRooms(text, l)
+	parseCardDates(text, l)
+	l.SharePercent = parseSharePercent(text)
+	l.IsNewBuild = newBuildRegexp.MatchString(text)
+	if auctionRegexp.MatchString(text) {
+		l.IsAuction = true
+		l.Qualifier = qualifierGuide
+	}
+	l.SharedOwnership = l.SharePercent != nil || sharedOwnershipRegexp.MatchString(text)
+}
 
-				if strings.Contains(c.Attr[i].Val, "Text") && !strings.Contains(c.Attr[i].Val, "PriceTitleText") {
-					if c.FirstChild == nil {
-						return 0, errors.New("no price in Text node")
-					}
-					return parsePrice(c.FirstChild.Data)
-				}
-			}
+func isAddressNode(n *html.Node) bool {
+	return n.Type == html.ElementNode &&
+		(n.Data == "address" || hasTestID(n, "listing-address") || hasClass(n, "Address"))
+}
+
+func parseListingAddress(card *html.Node) string {
+	n := findFirst(card, isAddressNode)
+	if n == nil {
+		return ""
+	}
+
+	return strings.Join(strings.Fields(textContent(n)), " ")
+}
+
+func parseListingURL(card *html.Node) string {
+	link := findFirst(card, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.Data != "a" {
+			return false
+		}
+		href, _ := getAttr(n, "href")
+		return strings.Contains(href, "/details/")
+	})
+	if link == nil {
+		return ""
+	}
+
+	href, _ := getAttr(link, "href")
+	return href
+}
+
+// resolveListingURLs makes the listing links found on a page absolute. The
+// parsers leave them as they appear in the page.
+func resolveListingURLs(listings []listing, pageUrl *url.URL) {
+	for i := range listings {
+		if listings[i].URL == "" {
+			continue
 		}
+
+		ref, err := url.Parse(listings[i].URL)
+		if err != nil {
+			continue
+		}
+		listings[i].URL = pageUrl.ResolveReference(ref).String()
 	}
+}
 
-	return 0, errors.New("cannot find price data to parse")
+func parsePriceNode(node *html.Node, mode searchMode) (uint64, error) {
+	return findPriceInSegments(priceSegments(node), mode)
 }
 
 func parsePrice(raw string) (uint64, error) {
-	// raw will be a string like "£435,000"
-	raw = strings.TrimSpace(raw)
+	// raw will be a string like "£435,000", possibly with entities or odd
+	// whitespace depending on the template that served the page.
+	raw = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, normalisePriceText(raw))
 	raw = strings.Replace(raw, ",", "", -1)
 	raw = strings.Replace(raw, "£", "", 1)
 	return strconv.ParseUint(raw, 10, 64)
 }
 
-func writePrices(prices []uint64, filename string) error {
-	priceData, err := json.Marshal(prices)
-	if err != nil {
-		return errors.Wrap(err, "while marshalling price data")
-	}
-
-	return ioutil.WriteFile(filename, priceData, 0644)
-}
-
 type priceStats struct {
+	count  int
+	min    uint64
+	max    uint64
 	mean   float64
+	median float64
 	stddev float64
+	stderr float64
+	// cv is the coefficient of variation, stddev over mean.
+	cv          float64
+	percentiles []percentile
+	box         *boxPlot
+
+	trimmedMean    *robustMean
+	winsorizedMean *robustMean
+
+	// intervals is only set when bootstrapping was asked for and there were
+	// enough prices.
+	intervals *bootstrapCIs
+
+	// flags warn about the sample, such as it being small.
+	flags []string
+
+	lognormal *logNormalFit
+	shape     *distributionShape
+	normality *normalityTest
+}
+
+// percentile is the price below which p percent of prices fall.
+type percentile struct {
+	p     float64
+	value float64
+}
+
+func (p percentile) name() string {
+	return "p" + strconv.FormatFloat(p.p, 'f', -1, 64)
 }
 
 func calculatePriceStats(prices []uint64) priceStats {
+	return calculatePriceStatsWith(prices, statsOptions{percentiles: defaultPercentiles})
+}
+
+// calculatePriceStatsWith calculates the stats of prices along with the
+// optional ones chosen by opts. Percentiles are given between 0 and 100.
+func calculatePriceStatsWith(prices []uint64, opts statsOptions) priceStats {
 	mean := calculateMean(prices)
 	stddev := calculateStddev(prices, mean)
 
-	return priceStats{mean: mean, stddev: stddev}
+	s := priceStats{count: len(prices), mean: mean, stddev: stddev}
+	s.stderr = calculateStandardError(stddev, len(prices))
+	s.cv = coefficientOfVariation(stddev, mean)
+	if opts.thresholds != nil {
+		s.flags = opts.thresholds.flags(s)
+	}
+	for i, p := range prices {
+		if i == 0 || p < s.min {
+			s.min = p
+		}
+		if p > s.max {
+			s.max = p
+		}
+	}
+
+	if len(prices) == 0 {
+		return s
+	}
+
+	sorted := make([]uint64, len(prices))
+	copy(sorted, prices)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	box := calculateBoxPlot(sorted)
+	s.box = &box
+	s.median = box.Q2
+	s.lognormal = fitLogNormal(sorted)
+	s.shape = calculateShape(sorted, mean)
+	s.normality = testNormality(sorted, mean)
+
+	if opts.bootstrap != nil {
+		s.intervals = calculateBootstrapCIs(sorted, *opts.bootstrap)
+	}
+
+	if opts.trimFraction > 0 {
+		m := calculateTrimmedMean(sorted, opts.trimFraction)
+		s.trimmedMean = &m
+	}
+	if opts.winsorizeFraction > 0 {
+		m := calculateWinsorizedMean(sorted, opts.winsorizeFraction)
+		s.winsorizedMean = &m
+	}
+
+	for _, p := range opts.percentiles {
+		s.percentiles = append(s.percentiles, percentile{p: p, value: quantile(sorted, p/100)})
+	}
+
+	return s
+}
+
+func (s priceStats) MarshalJSON() ([]byte, error) {
+	flags := s.flags
+	if flags == nil {
+		flags = []string{}
+	}
+
+	var meanCI, medianCI *confidenceInterval
+	if s.intervals != nil {
+		meanCI, medianCI = &s.intervals.mean, &s.intervals.median
+	}
+
+	var percentiles map[string]float64
+	if len(s.percentiles) > 0 {
+		percentiles = make(map[string]float64, len(s.percentiles))
+		for _, p := range s.percentiles {
+			percentiles[p.name()] = p.value
+		}
+	}
+
+	return json.Marshal(struct {
+		Count          int                 `json:"count"`
+		Min            uint64              `json:"min"`
+		Max            uint64              `json:"max"`
+		Range          uint64              `json:"range"`
+		Mean           float64             `json:"mean"`
+		TrimmedMean    *robustMean         `json:"trimmed_mean,omitempty"`
+		WinsorizedMean *robustMean         `json:"winsorized_mean,omitempty"`
+		Median         float64             `json:"median"`
+		Stddev         float64             `json:"stddev"`
+		StdErr         float64             `json:"stderr"`
+		CV             float64             `json:"cv"`
+		Flags          []string            `json:"flags"`
+		Percentiles    map[string]float64  `json:"percentiles,omitempty"`
+		BoxPlot        *boxPlot            `json:"boxplot,omitempty"`
+		MeanCI         *confidenceInterval `json:"mean_ci,omitempty"`
+		MedianCI       *confidenceInterval `json:"median_ci,omitempty"`
+		LogNormal      *logNormalFit       `json:"lognormal,omitempty"`
+		Shape          *distributionShape  `json:"shape,omitempty"`
+		Normality      *normalityTest      `json:"normality,omitempty"`
+	}{s.count, s.min, s.max, s.max - s.min, s.mean, s.trimmedMean, s.winsorizedMean, s.median, s.stddev, s.stderr, s.cv, flags, percentiles, s.box, meanCI, medianCI, s.lognormal, s.shape, s.normality})
+}
+
+// logPriceExtremes logs where the cheapest and dearest of listings are, as
+// far as their addresses and URLs are known.
+func logPriceExtremes(listings []listing) {
+	if len(listings) == 0 {
+		return
+	}
+
+	cheapest, dearest := &listings[0], &listings[0]
+	for i := range listings {
+		if listings[i].Price < cheapest.Price {
+			cheapest = &listings[i]
+		}
+		if listings[i].Price > dearest.Price {
+			dearest = &listings[i]
+		}
+	}
+
+	log.Print("cheapest listing: ", describeExtreme(cheapest))
+	log.Print("dearest listing: ", describeExtreme(dearest))
+}
+
+func describeExtreme(l *listing) string {
+	parts := []string{formatPrice(l.Price)}
+	if l.Address != "" {
+		parts = append(parts, l.Address)
+	}
+	if l.URL != "" {
+		parts = append(parts, l.URL)
+	}
+
+	return strings.Join(parts, ", ")
 }
 
 func calculateMean(prices []uint64) float64 {
@@ -277,6 +1544,19 @@ func calculateMean(prices []uint64) float64 {
 	return sum / float64(len(prices))
 }
 
+func calculateMedian(prices []uint64) float64 {
+	sorted := make([]uint64, len(prices))
+	copy(sorted, prices)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return float64(sorted[mid])
+	}
+
+	return (float64(sorted[mid-1]) + float64(sorted[mid])) / 2
+}
+
 func calculateStddev(prices []uint64, mean float64) float64 {
 	if len(prices) == 1 {
 		return 0.0
@@ -293,5 +1573,17 @@ func calculateStddev(prices []uint64, mean float64) float64 {
 }
 
 func (s priceStats) String() string {
-	return fmt.Sprintf("mean = %.0f, stddev = %.0f", s.mean, s.stddev)
+	str := fmt.Sprintf("n = %d, min = %d, max = %d, range = %d, mean = %.0f (stderr %.0f), median = %.0f, stddev = %.0f",
+		s.count, s.min, s.max, s.max-s.min, s.mean, s.stderr, s.median, s.stddev)
+	if s.trimmedMean != nil {
+		str += fmt.Sprintf(", %s trimmed mean = %.0f", fractionPercent(s.trimmedMean.Fraction), s.trimmedMean.Value)
+	}
+	if s.winsorizedMean != nil {
+		str += fmt.Sprintf(", %s winsorized mean = %.0f", fractionPercent(s.winsorizedMean.Fraction), s.winsorizedMean.Value)
+	}
+	for _, p := range s.percentiles {
+		str += fmt.Sprintf(", %s = %.0f", p.name(), p.value)
+	}
+
+	return str
 }
@@ -0,0 +1,254 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/alexflint/go-arg"
+	"github.com/pkg/errors"
+)
+
+// TestMain keeps the run's progress logging out of the test output unless
+// it was asked for with -v.
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if !testing.Verbose() {
+		log.SetOutput(ioutil.Discard)
+	}
+
+	os.Exit(m.Run())
+}
+
+// testSearchArgs parses argv as the fetch subcommand's, with its defaults
+// and validation.
+func testSearchArgs(t *testing.T, argv ...string) cliArgs {
+	t.Helper()
+
+	args, err := parseSearchArgs(func(cli *cliArgs) (*arg.Parser, error) {
+		return parseSubcommand("fetch", cli, argv)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return args
+}
+
+// captureOutput runs f with stdout, stderr and the log redirected, returning
+// what was written to each stream.
+func captureOutput(t *testing.T, f func()) (stdout, stderr string) {
+	t.Helper()
+
+	read := func(r *os.File, out *string, done chan<- struct{}) {
+		b, _ := ioutil.ReadAll(r)
+		*out = string(b)
+		close(done)
+	}
+
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outDone, errDone := make(chan struct{}), make(chan struct{})
+	go read(outR, &stdout, outDone)
+	go read(errR, &stderr, errDone)
+
+	savedOut, savedErr, savedLog := os.Stdout, os.Stderr, log.Writer()
+	os.Stdout, os.Stderr = outW, errW
+	log.SetOutput(errW)
+	defer func() {
+		os.Stdout, os.Stderr = savedOut, savedErr
+		log.SetOutput(savedLog)
+	}()
+
+	f()
+
+	outW.Close()
+	errW.Close()
+	<-outDone
+	<-errDone
+
+	return stdout, stderr
+}
+
+func TestPriceStatsRange(t *testing.T) {
+	tests := []struct {
+		name                string
+		prices              []uint64
+		min, max, wantRange uint64
+	}{
+		{name: "single", prices: []uint64{400000}, min: 400000, max: 400000},
+		{name: "unsorted", prices: []uint64{450000, 300000, 900000, 500000}, min: 300000, max: 900000, wantRange: 600000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := calculatePriceStats(tt.prices)
+			if s.min != tt.min || s.max != tt.max {
+				t.Errorf("got min %d, max %d, want %d, %d", s.min, s.max, tt.min, tt.max)
+			}
+
+			data, err := json.Marshal(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got struct {
+				Min, Max, Range uint64
+			}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Min != tt.min || got.Max != tt.max || got.Range != tt.wantRange {
+				t.Errorf("got %s", data)
+			}
+
+			want := fmt.Sprintf("min = %d, max = %d, range = %d, ", tt.min, tt.max, tt.wantRange)
+			if str := s.String(); !strings.Contains(str, want) {
+				t.Errorf("%q has no %q", str, want)
+			}
+		})
+	}
+}
+
+func TestDescribeExtreme(t *testing.T) {
+	tests := []struct {
+		name string
+		l    listing
+		want string
+	}{
+		{"price only", listing{Price: 400000}, "£400,000"},
+		{"address", listing{Price: 400000, Address: "Acre Lane"}, "£400,000, Acre Lane"},
+		{"url", listing{Price: 400000, URL: "https://example.com/1"}, "£400,000, https://example.com/1"},
+		{"both", listing{Price: 400000, Address: "Acre Lane", URL: "https://example.com/1"}, "£400,000, Acre Lane, https://example.com/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeExtreme(&tt.l); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogPriceExtremes(t *testing.T) {
+	listings := []listing{
+		{Price: 450000, Address: "Middle"},
+		{Price: 300000, Address: "Cheap"},
+		{Price: 300000, Address: "Also cheap"},
+		{Price: 900000, Address: "Dear"},
+	}
+
+	_, stderr := captureOutput(t, func() { logPriceExtremes(listings) })
+	// Ties go to the first listing.
+	for _, want := range []string{"cheapest listing: £300,000, Cheap\n", "dearest listing: £900,000, Dear\n"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("log %q has no %q", stderr, want)
+		}
+	}
+
+	if _, stderr := captureOutput(t, func() { logPriceExtremes(nil) }); stderr != "" {
+		t.Errorf("got %q for no listings", stderr)
+	}
+}
+
+func TestMainExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"success", []string{"trend", trendFixture("history.json")}, 0},
+		{"failure", []string{"trend", trendFixture("missing.json")}, 1},
+		{"help", []string{"trend", "--help"}, 0},
+		{"fetch help", []string{"--help"}, 0},
+		{"invalid flags", []string{"--postcode", "sw2", "--kde-bandwidth", "1"}, exitCodeUsage},
+		{"unknown flag", []string{"--postcode", "sw2", "--bogus"}, exitCodeUsage},
+		{"invalid subcommand flags", []string{"trend", trendFixture("history.json"), "--moves", "-1"}, exitCodeUsage},
+	}
+
+	savedArgs := os.Args
+	defer func() { os.Args = savedArgs }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Args = append([]string{"zoopla-analyzer"}, tt.args...)
+			var code int
+			_, stderr := captureOutput(t, func() { code = Main(context.Background()) })
+			if code != tt.want {
+				t.Errorf("got exit code %d, want %d: %s", code, tt.want, stderr)
+			}
+		})
+	}
+}
+
+func TestParseSearchArgsInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		argv    []string
+		wantErr string
+	}{
+		{"no postcode", nil, "no postcode given, use --postcode or --postcode-file"},
+		{"repeated postcode", []string{"--postcode", "sw2,SW2"}, `postcode "SW2" given more than once`},
+		{"missing postcode file", []string{"--postcode-file", "missing.txt"}, "while reading postcode file"},
+		{"flag needing another", []string{"--postcode", "sw2", "--kde-bandwidth", "1"}, "--kde-bandwidth needs --kde"},
+		{"unknown format", []string{"--postcode", "sw2", "--format", "xml"}, `unknown format "xml"`},
+		{"bad value", []string{"--postcode", "sw2", "--radius", "far"}, "error processing --radius"},
+		{"unknown flag", []string{"--postcode", "sw2", "--bogus"}, "unknown argument --bogus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseSearchArgs(func(cli *cliArgs) (*arg.Parser, error) {
+				return parseCommandLine(programName, cli, tt.argv)
+			})
+
+			var usageErr *usageError
+			if !errors.As(err, &usageErr) || usageErr.p == nil {
+				t.Fatalf("got %v, want a usage error", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %q, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMainUsage(t *testing.T) {
+	savedArgs := os.Args
+	defer func() { os.Args = savedArgs }()
+	os.Args = []string{"zoopla-analyzer", "--postcode", "sw2", "--kde-bandwidth", "1"}
+
+	var code int
+	stdout, stderr := captureOutput(t, func() { code = Main(context.Background()) })
+	if code != exitCodeUsage || stdout != "" {
+		t.Errorf("got exit code %d, stdout %q", code, stdout)
+	}
+	if !strings.HasPrefix(stderr, "Usage: zoopla-analyzer [--postcode POSTCODE]") || !strings.HasSuffix(stderr, "\nerror: --kde-bandwidth needs --kde\n") {
+		t.Errorf("got stderr %q", stderr)
+	}
+}
+
+func TestParseCommandLineHelp(t *testing.T) {
+	var cli cliArgs
+	var err error
+	stdout, _ := captureOutput(t, func() { _, err = parseCommandLine(programName, &cli, []string{"--help"}) })
+	if !errors.Is(err, errHelp) {
+		t.Errorf("got %v, want errHelp", err)
+	}
+	if !strings.HasPrefix(stdout, "Usage: zoopla-analyzer [--postcode POSTCODE]") || !strings.Contains(stdout, "--help, -h") {
+		t.Errorf("got help %q", stdout)
+	}
+}
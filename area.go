@@ -0,0 +1,225 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	areaSourceCard   = "card"
+	areaSourceDetail = "detail"
+
+	sqftPerSqm = 10.7639
+
+	// maxAreaDisagreement is the relative difference between card and detail
+	// page areas above which the conflict is logged.
+	maxAreaDisagreement = 0.1
+)
+
+var floorAreaRegexp = regexp.MustCompile(`(?i)([\d,]+(?:\.\d+)?)\s*(sq\.?\s*ft|sq\.?\s*feet|square\s+feet|sq\.?\s*m|square\s+met(?:re|er)s?|m²)`)
+
+// parseFloorArea finds the first floor area in text and returns it in square
+// feet, converting from square metres where necessary.
+func parseFloorArea(text string) *uint32 {
+	m := floorAreaRegexp.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+
+	area, err := strconv.ParseFloat(strings.Replace(m[1], ",", "", -1), 64)
+	if err != nil || area <= 0 {
+		return nil
+	}
+
+	unit := strings.ToLower(m[2])
+	if !strings.Contains(unit, "f") {
+		area *= sqftPerSqm
+	}
+
+	sqft := uint32(math.Round(area))
+	return &sqft
+}
+
+func parseCardFloorArea(text string, l *listing) {
+	if area := parseFloorArea(text); area != nil {
+		l.FloorArea = area
+		l.FloorAreaSource = areaSourceCard
+	}
+}
+
+// applyDetailFloorArea prefers the detail page area over any card value,
+// logging when the two disagree significantly.
+func applyDetailFloorArea(l *listing, detailArea *uint32) {
+	if detailArea == nil {
+		return
+	}
+
+	if l.FloorArea != nil {
+		card, detail := float64(*l.FloorArea), float64(*detailArea)
+		if math.Abs(card-detail)/detail > maxAreaDisagreement {
+			log.Printf(
+				"card area %.0f sq ft and detail area %.0f sq ft disagree for %s",
+				card,
+				detail,
+				l.URL,
+			)
+		}
+	}
+
+	l.FloorArea = detailArea
+	l.FloorAreaSource = areaSourceDetail
+}
+
+func parseHasFloorplan(root *html.Node) bool {
+	return findFirst(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return false
+		}
+
+		if id, _ := getAttr(n, "data-testid"); strings.Contains(strings.ToLower(id), "floorplan") {
+			return true
+		}
+
+		if n.Data == "img" {
+			alt, _ := getAttr(n, "alt")
+			return strings.Contains(strings.ToLower(alt), "floor plan") ||
+				strings.Contains(strings.ToLower(alt), "floorplan")
+		}
+
+		return false
+	}) != nil
+}
+
+// minAreaCoverage is the share of listings with a floor area below which
+// the price per sq ft is flagged, since agents mostly give areas for new
+// builds and the subset is unlikely to be typical.
+const minAreaCoverage = 0.3
+
+// pricePerSqft returns a listing's price divided by its floor area, if it
+// has one.
+func pricePerSqft(l *listing) (float64, bool) {
+	if l.FloorArea == nil || *l.FloorArea == 0 {
+		return 0, false
+	}
+
+	return float64(l.Price) / float64(*l.FloorArea), true
+}
+
+type pricePerSqftGroup struct {
+	Key    string  `json:"key"`
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	P25    float64 `json:"p25"`
+	Median float64 `json:"median"`
+	Mean   float64 `json:"mean"`
+	P75    float64 `json:"p75"`
+	Max    float64 `json:"max"`
+}
+
+type pricePerSqftStats struct {
+	total  int
+	all    pricePerSqftGroup
+	byType []pricePerSqftGroup
+}
+
+func calculatePricePerSqftStats(listings []listing) pricePerSqftStats {
+	stats := pricePerSqftStats{total: len(listings)}
+
+	var values []float64
+	typeValues := make(map[string][]float64)
+	for i := range listings {
+		v, ok := pricePerSqft(&listings[i])
+		if !ok {
+			continue
+		}
+
+		values = append(values, v)
+		k := propertyTypeKey(&listings[i])
+		typeValues[k] = append(typeValues[k], v)
+	}
+
+	stats.all = newPricePerSqftGroup("all", values)
+
+	for k, vs := range typeValues {
+		stats.byType = append(stats.byType, newPricePerSqftGroup(k, vs))
+	}
+	sort.Slice(stats.byType, func(i, j int) bool {
+		return lessGroupKey(stats.byType[i].Key, stats.byType[j].Key)
+	})
+
+	return stats
+}
+
+func newPricePerSqftGroup(key string, values []float64) pricePerSqftGroup {
+	g := pricePerSqftGroup{Key: key, Count: len(values)}
+	if len(values) == 0 {
+		return g
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	g.Min, g.Max = sorted[0], sorted[len(sorted)-1]
+	g.P25 = floatQuantile(sorted, 0.25)
+	g.Median = floatQuantile(sorted, 0.5)
+	g.P75 = floatQuantile(sorted, 0.75)
+	g.Mean = meanFloat(sorted)
+
+	return g
+}
+
+// coverage is the share of listings with a floor area.
+func (s pricePerSqftStats) coverage() float64 {
+	if s.total == 0 {
+		return 0
+	}
+
+	return float64(s.all.Count) / float64(s.total)
+}
+
+func (s pricePerSqftStats) lowCoverage() bool {
+	return s.all.Count > 0 && s.coverage() < minAreaCoverage
+}
+
+func (s pricePerSqftStats) String() string {
+	if s.all.Count == 0 {
+		return fmt.Sprintf("no area data for any of %d listings", s.total)
+	}
+
+	return fmt.Sprintf("%s, area data for %d of %d listings (%.0f%%)", s.all, s.all.Count, s.total, 100*s.coverage())
+}
+
+func (g pricePerSqftGroup) String() string {
+	return fmt.Sprintf(
+		"%s: count = %d, min = %.0f, p25 = %.0f, median = %.0f, mean = %.0f, p75 = %.0f, max = %.0f per sq ft",
+		g.Key, g.Count, g.Min, g.P25, g.Median, g.Mean, g.P75, g.Max,
+	)
+}
+
+func (s pricePerSqftStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Count       int                 `json:"count"`
+		Total       int                 `json:"total"`
+		Coverage    float64             `json:"coverage"`
+		LowCoverage bool                `json:"low_coverage"`
+		Min         float64             `json:"min"`
+		P25         float64             `json:"p25"`
+		Median      float64             `json:"median"`
+		Mean        float64             `json:"mean"`
+		P75         float64             `json:"p75"`
+		Max         float64             `json:"max"`
+		ByType      []pricePerSqftGroup `json:"by_type"`
+	}{
+		s.all.Count, s.total, s.coverage(), s.lowCoverage(),
+		s.all.Min, s.all.P25, s.all.Median, s.all.Mean, s.all.P75, s.all.Max, s.byType,
+	})
+}
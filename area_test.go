@@ -0,0 +1,188 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseFloorArea(t *testing.T) {
+	tests := []struct {
+		text string
+		want *uint32
+	}{
+		{"812 sq ft", uint32Ptr(812)},
+		{"Approx 1,250 sq. ft", uint32Ptr(1250)},
+		{"900 square feet", uint32Ptr(900)},
+		{"75 sq m", uint32Ptr(807)},
+		{"75.5 square metres", uint32Ptr(813)},
+		{"60m²", uint32Ptr(646)},
+		{"2 bed flat, 640 sq ft, 59 sq m", uint32Ptr(640)},
+		{"0 sq ft", nil},
+		{"3 bedrooms", nil},
+	}
+
+	for _, tt := range tests {
+		got := parseFloorArea(tt.text)
+		if derefUint32(got) != derefUint32(tt.want) {
+			t.Errorf("parseFloorArea(%q) = %v, want %v", tt.text, derefUint32(got), derefUint32(tt.want))
+		}
+	}
+}
+
+func TestApplyDetailFloorArea(t *testing.T) {
+	tests := []struct {
+		name       string
+		card       *uint32
+		detail     *uint32
+		want       *uint32
+		wantSource string
+	}{
+		{"detail only", nil, uint32Ptr(800), uint32Ptr(800), areaSourceDetail},
+		{"detail replaces card", uint32Ptr(650), uint32Ptr(800), uint32Ptr(800), areaSourceDetail},
+		{"no detail keeps card", uint32Ptr(650), nil, uint32Ptr(650), areaSourceCard},
+		{"neither", nil, nil, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := listing{FloorArea: tt.card}
+			if tt.card != nil {
+				l.FloorAreaSource = areaSourceCard
+			}
+
+			applyDetailFloorArea(&l, tt.detail)
+			if derefUint32(l.FloorArea) != derefUint32(tt.want) || l.FloorAreaSource != tt.wantSource {
+				t.Errorf("got %v from %q, want %v from %q", derefUint32(l.FloorArea), l.FloorAreaSource, derefUint32(tt.want), tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestParseHasFloorplan(t *testing.T) {
+	tests := []struct {
+		page string
+		want bool
+	}{
+		{`<div data-testid="floorplan-tab"></div>`, true},
+		{`<button data-testid="gallery-FloorPlan">Plans</button>`, true},
+		{`<img src="/a.png" alt="Floor plan">`, true},
+		{`<img src="/a.png" alt="Ground floor floorplan">`, true},
+		{`<img src="/a.png" alt="Kitchen"><p>No floor plan available</p>`, false},
+	}
+
+	for _, tt := range tests {
+		if got := parseHasFloorplan(mustParseHTML(t, tt.page)); got != tt.want {
+			t.Errorf("parseHasFloorplan(%s) = %v, want %v", tt.page, got, tt.want)
+		}
+	}
+}
+
+func TestParseCardFloorArea(t *testing.T) {
+	var l listing
+	parseCardFloorArea("2 beds 1 bath 700 sq ft", &l)
+	if derefUint32(l.FloorArea) != uint32(700) || l.FloorAreaSource != areaSourceCard {
+		t.Errorf("got %v from %q, want 700 from the card", derefUint32(l.FloorArea), l.FloorAreaSource)
+	}
+}
+
+func TestPricePerSqft(t *testing.T) {
+	tests := []struct {
+		name   string
+		l      listing
+		want   float64
+		wantOK bool
+	}{
+		{"area", listing{Price: 400000, FloorArea: uint32Ptr(800)}, 500, true},
+		{"no area", listing{Price: 400000}, 0, false},
+		{"zero area", listing{Price: 400000, FloorArea: uint32Ptr(0)}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pricePerSqft(&tt.l)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("got %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// sqftListings has three of five listings with a usable floor area.
+func sqftListings() []listing {
+	return []listing{
+		{Price: 400000, PropertyType: "flat", FloorArea: uint32Ptr(800)},
+		{Price: 300000, PropertyType: "flat", FloorArea: uint32Ptr(500)},
+		{Price: 700000, PropertyType: "terraced", FloorArea: uint32Ptr(1000)},
+		{Price: 500000, PropertyType: "flat"},
+		{Price: 800000, PropertyType: "terraced", FloorArea: uint32Ptr(0)},
+	}
+}
+
+func TestCalculatePricePerSqftStats(t *testing.T) {
+	s := calculatePricePerSqftStats(sqftListings())
+
+	want := pricePerSqftGroup{Key: "all", Count: 3, Min: 500, P25: 550, Median: 600, Mean: 600, P75: 650, Max: 700}
+	if s.total != 5 || s.all != want {
+		t.Errorf("got %d listings, %+v, want 5, %+v", s.total, s.all, want)
+	}
+
+	wantByType := []pricePerSqftGroup{
+		{Key: "flat", Count: 2, Min: 500, P25: 525, Median: 550, Mean: 550, P75: 575, Max: 600},
+		{Key: "terraced", Count: 1, Min: 700, P25: 700, Median: 700, Mean: 700, P75: 700, Max: 700},
+	}
+	if len(s.byType) != len(wantByType) {
+		t.Fatalf("got %+v, want %+v", s.byType, wantByType)
+	}
+	for i, w := range wantByType {
+		if s.byType[i] != w {
+			t.Errorf("type %d: got %+v, want %+v", i, s.byType[i], w)
+		}
+	}
+
+	wantString := "all: count = 3, min = 500, p25 = 550, median = 600, mean = 600, p75 = 650, max = 700 per sq ft, area data for 3 of 5 listings (60%)"
+	if got := s.String(); got != wantString {
+		t.Errorf("got %q, want %q", got, wantString)
+	}
+}
+
+func TestPricePerSqftCoverage(t *testing.T) {
+	tests := []struct {
+		name     string
+		listings []listing
+		want     float64
+		wantLow  bool
+		wantText string
+	}{
+		{"none", nil, 0, false, "no area data for any of 0 listings"},
+		{"no areas", make([]listing, 4), 0, false, "no area data for any of 4 listings"},
+		{"good", sqftListings(), 0.6, false, ""},
+		{"at threshold", append(sqftListings(), make([]listing, 5)...), 0.3, false, ""},
+		{"low", append(sqftListings(), make([]listing, 6)...), 3.0 / 11, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := calculatePricePerSqftStats(tt.listings)
+			if !closeTo(s.coverage(), tt.want) || s.lowCoverage() != tt.wantLow {
+				t.Errorf("got coverage %v, low %v, want %v, %v", s.coverage(), s.lowCoverage(), tt.want, tt.wantLow)
+			}
+			if tt.wantText != "" && s.String() != tt.wantText {
+				t.Errorf("got %q, want %q", s.String(), tt.wantText)
+			}
+		})
+	}
+}
+
+func TestPricePerSqftStatsJSON(t *testing.T) {
+	data, err := json.Marshal(calculatePricePerSqftStats(sqftListings()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"count":3,"total":5,"coverage":0.6,"low_coverage":false,"min":500,"p25":550,"median":600,"mean":600,"p75":650,"max":700,"by_type":[` +
+		`{"key":"flat","count":2,"min":500,"p25":525,"median":550,"mean":550,"p75":575,"max":600},` +
+		`{"key":"terraced","count":1,"min":700,"p25":700,"median":700,"mean":700,"p75":700,"max":700}]}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,112 @@
+package zoopla
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultTerminalWidth = 80
+	minBarWidth          = 10
+)
+
+// terminalWidth is the width given by $COLUMNS, or a default if it's unset
+// or invalid.
+func terminalWidth(lookupEnv func(string) (string, bool)) int {
+	if v, ok := lookupEnv("COLUMNS"); ok {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+
+	return defaultTerminalWidth
+}
+
+// renderASCIIHistogram draws buckets as horizontal bars scaled to fit width
+// columns, marking the buckets holding the mean and median.
+func renderASCIIHistogram(buckets []histogramBucket, mean, median float64, width int) string {
+	if len(buckets) == 0 {
+		return ""
+	}
+
+	labels := make([]string, len(buckets))
+	labelWidth, countWidth, maxCount := 0, 0, 0
+	for i, b := range buckets {
+		labels[i] = compactPrice(b.Min) + "–" + compactPrice(b.Max)
+		if n := len([]rune(labels[i])); n > labelWidth {
+			labelWidth = n
+		}
+		if n := len(strconv.Itoa(b.Count)); n > countWidth {
+			countWidth = n
+		}
+		if b.Count > maxCount {
+			maxCount = b.Count
+		}
+	}
+
+	const markerWidth = len("  <- mean, median")
+	barWidth := width - labelWidth - countWidth - markerWidth - 2
+	if barWidth < minBarWidth {
+		barWidth = minBarWidth
+	}
+
+	var sb strings.Builder
+	for i, b := range buckets {
+		bar := 0
+		if maxCount > 0 {
+			bar = b.Count * barWidth / maxCount
+		}
+		if bar == 0 && b.Count > 0 {
+			bar = 1
+		}
+
+		pad := strings.Repeat(" ", labelWidth-len([]rune(labels[i])))
+		line := fmt.Sprintf("%s%s %*d %s", labels[i], pad, countWidth, b.Count, strings.Repeat("#", bar))
+
+		var marks []string
+		last := i == len(buckets)-1
+		if inBucket(b, mean, last) {
+			marks = append(marks, "mean")
+		}
+		if inBucket(b, median, last) {
+			marks = append(marks, "median")
+		}
+		if len(marks) > 0 {
+			line += strings.Repeat(" ", barWidth-bar) + "  <- " + strings.Join(marks, ", ")
+		}
+
+		sb.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+
+	return sb.String()
+}
+
+// inBucket reports whether v falls in b, which covers [Min, Max) or, for
+// the last bucket, [Min, Max].
+func inBucket(b histogramBucket, v float64, last bool) bool {
+	if v < float64(b.Min) {
+		return false
+	}
+
+	return v < float64(b.Max) || (last && v <= float64(b.Max))
+}
+
+// compactPrice formats a price like £425k or £1.25m.
+func compactPrice(price uint64) string {
+	switch {
+	case price >= 1000000:
+		return "£" + strconv.FormatFloat(float64(price)/1000000, 'f', -1, 64) + "m"
+	case price >= 1000:
+		return "£" + strconv.FormatFloat(float64(price)/1000, 'f', -1, 64) + "k"
+	default:
+		return "£" + strconv.FormatUint(price, 10)
+	}
+}
+
+func printASCIIHistogram(prices []uint64, args *cliArgs) {
+	buckets := calculateHistogram(prices, args.BucketSize)
+	hist := renderASCIIHistogram(buckets, calculateMean(prices), calculateMedian(prices), terminalWidth(os.LookupEnv))
+	fmt.Fprint(os.Stderr, "\n", hist)
+}
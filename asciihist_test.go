@@ -0,0 +1,150 @@
+package zoopla
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTerminalWidth(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want int
+	}{
+		{"unset", nil, defaultTerminalWidth},
+		{"set", map[string]string{"COLUMNS": "120"}, 120},
+		{"invalid", map[string]string{"COLUMNS": "wide"}, defaultTerminalWidth},
+		{"zero", map[string]string{"COLUMNS": "0"}, defaultTerminalWidth},
+		{"negative", map[string]string{"COLUMNS": "-5"}, defaultTerminalWidth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			}
+			if got := terminalWidth(lookup); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderASCIIHistogram(t *testing.T) {
+	buckets := []histogramBucket{
+		{Min: 100000, Max: 200000, Count: 4},
+		{Min: 200000, Max: 300000, Count: 2},
+		{Min: 300000, Max: 400000, Count: 1},
+	}
+
+	tests := []struct {
+		name         string
+		mean, median float64
+		width        int
+		want         []string
+	}{
+		{
+			name: "narrow",
+			mean: 230000, median: 200000, width: 40,
+			want: []string{
+				"£100k–£200k 4 ##########",
+				"£200k–£300k 2 #####       <- mean, median",
+				"£300k–£400k 1 ##",
+			},
+		},
+		{
+			name: "separate marks",
+			mean: 190000, median: 400000, width: 51,
+			want: []string{
+				"£100k–£200k 4 ####################  <- mean",
+				"£200k–£300k 2 ##########",
+				"£300k–£400k 1 #####                 <- median",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderASCIIHistogram(buckets, tt.mean, tt.median, tt.width)
+			if want := strings.Join(tt.want, "\n") + "\n"; got != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestRenderASCIIHistogramSmallCounts(t *testing.T) {
+	buckets := []histogramBucket{
+		{Min: 0, Max: 500, Count: 1000},
+		{Min: 500, Max: 1000, Count: 1},
+		{Min: 1000, Max: 1500, Count: 0},
+	}
+
+	lines := strings.Split(strings.TrimSuffix(renderASCIIHistogram(buckets, -1, -1, 80), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+	// A bucket with any prices gets at least one mark, while an empty one
+	// gets none.
+	if !strings.HasSuffix(lines[1], " 1 #") {
+		t.Errorf("got %q, want a single mark", lines[1])
+	}
+	if strings.Contains(lines[2], "#") {
+		t.Errorf("got %q, want no marks", lines[2])
+	}
+	// Counts are right-aligned.
+	if !strings.HasPrefix(lines[1], "£500–£1k     1 ") {
+		t.Errorf("got %q, want the count right-aligned", lines[1])
+	}
+}
+
+func TestRenderASCIIHistogramEmpty(t *testing.T) {
+	if got := renderASCIIHistogram(nil, 0, 0, 80); got != "" {
+		t.Errorf("got %q, want nothing", got)
+	}
+}
+
+func TestInBucket(t *testing.T) {
+	b := histogramBucket{Min: 100, Max: 200}
+
+	tests := []struct {
+		v    float64
+		last bool
+		want bool
+	}{
+		{99, false, false},
+		{100, false, true},
+		{199.5, false, true},
+		{200, false, false},
+		{200, true, true},
+		{201, true, false},
+	}
+
+	for _, tt := range tests {
+		if got := inBucket(b, tt.v, tt.last); got != tt.want {
+			t.Errorf("inBucket(%v, last %v) = %v, want %v", tt.v, tt.last, got, tt.want)
+		}
+	}
+}
+
+func TestCompactPrice(t *testing.T) {
+	tests := []struct {
+		price uint64
+		want  string
+	}{
+		{0, "£0"},
+		{999, "£999"},
+		{1000, "£1k"},
+		{425000, "£425k"},
+		{427500, "£427.5k"},
+		{1000000, "£1m"},
+		{1250000, "£1.25m"},
+	}
+
+	for _, tt := range tests {
+		if got := compactPrice(tt.price); got != tt.want {
+			t.Errorf("compactPrice(%d) = %q, want %q", tt.price, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package zoopla
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// writeFileAtomic writes data to a temporary file alongside filename, syncs it
+// and then renames it into place, so a crash never leaves a half-written file
+// and the previous contents survive any failure before the rename.
+func writeFileAtomic(filename string, data []byte) error {
+	return writeFileAtomicFunc(filename, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeFileAtomicFunc is like writeFileAtomic but streams the contents from
+// write rather than taking them in memory.
+func writeFileAtomicFunc(filename string, write func(io.Writer) error) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp-*")
+	if err != nil {
+		return errors.Wrap(err, "while creating temporary file")
+	}
+	defer os.Remove(tmp.Name())
+
+	bw := bufio.NewWriter(tmp)
+	if err := write(bw); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "while writing %s", tmp.Name())
+	}
+	if err := bw.Flush(); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "while writing %s", tmp.Name())
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "while syncing %s", tmp.Name())
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "while setting permissions on %s", tmp.Name())
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "while closing %s", tmp.Name())
+	}
+
+	return errors.Wrapf(os.Rename(tmp.Name(), filename), "while renaming into %s", filename)
+}
@@ -0,0 +1,83 @@
+package zoopla
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "prices.json")
+
+	for _, data := range []string{`[400000]`, `[450000,500000]`} {
+		if err := writeFileAtomic(filename, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := ioutil.ReadFile(filename); err != nil || string(got) != data {
+			t.Errorf("read back %q, %v, want %q", got, err, data)
+		}
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0644 {
+		t.Errorf("file has permissions %v, want 0644", perm)
+	}
+	assertOnlyFiles(t, dir, "prices.json")
+}
+
+func TestWriteFileAtomicKeepsOldContentsOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "prices.json")
+	if err := ioutil.WriteFile(filename, []byte(`[400000]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writeFileAtomicFunc(filename, func(w io.Writer) error {
+		if _, err := io.WriteString(w, `[450000,`); err != nil {
+			return err
+		}
+		return errors.New("killed part way")
+	})
+	if err == nil {
+		t.Fatal("write succeeded, want the writer's error")
+	}
+
+	if got, err := ioutil.ReadFile(filename); err != nil || string(got) != `[400000]` {
+		t.Errorf("file holds %q, %v, want the previous contents", got, err)
+	}
+	assertOnlyFiles(t, dir, "prices.json")
+}
+
+func TestWriteFileAtomicMissingDir(t *testing.T) {
+	if err := writeFileAtomic(filepath.Join(t.TempDir(), "missing", "prices.json"), []byte(`[]`)); err == nil {
+		t.Error("wrote into a directory that doesn't exist")
+	}
+}
+
+// assertOnlyFiles fails unless dir holds exactly the named files, such as
+// when a temporary file was left behind.
+func assertOnlyFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	if !reflect.DeepEqual(got, names) {
+		t.Errorf("%s holds %v, want %v", dir, got, names)
+	}
+}
@@ -0,0 +1,113 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// parseBandEdges parses the comma-separated --bands argument, whose edges
+// must be strictly increasing.
+func parseBandEdges(arg string) ([]uint64, error) {
+	var edges []uint64
+	for _, field := range strings.Split(arg, ",") {
+		edge, err := strconv.ParseUint(strings.TrimSpace(field), 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid band edge %q, must be a price in pounds", field)
+		}
+		if len(edges) > 0 && edge <= edges[len(edges)-1] {
+			return nil, errors.Errorf("band edges must be strictly increasing, but %d follows %d", edge, edges[len(edges)-1])
+		}
+		edges = append(edges, edge)
+	}
+
+	return edges, nil
+}
+
+// bandIndex returns which of the bands divided by edges price falls in.
+// Bands include their lower edge but not their upper one, so there's one
+// more band than edges.
+func bandIndex(edges []uint64, price uint64) int {
+	for i, upper := range edges {
+		if price < upper {
+			return i
+		}
+	}
+
+	return len(edges)
+}
+
+type bandCount struct {
+	// min and max are nil for the open-ended first and last bands.
+	min     *uint64
+	max     *uint64
+	count   int
+	percent float64
+}
+
+// calculateBandCounts counts how many of prices fall in each band divided
+// by edges.
+func calculateBandCounts(prices []uint64, edges []uint64) []bandCount {
+	bands := make([]bandCount, len(edges)+1)
+	for i := range bands {
+		if i > 0 {
+			bands[i].min = &edges[i-1]
+		}
+		if i < len(edges) {
+			bands[i].max = &edges[i]
+		}
+	}
+
+	for _, p := range prices {
+		bands[bandIndex(edges, p)].count++
+	}
+
+	if len(prices) > 0 {
+		for i := range bands {
+			bands[i].percent = 100 * float64(bands[i].count) / float64(len(prices))
+		}
+	}
+
+	return bands
+}
+
+func (b bandCount) label() string {
+	switch {
+	case b.min == nil && b.max == nil:
+		return "all"
+	case b.min == nil:
+		return "under " + compactPrice(*b.max)
+	case b.max == nil:
+		return compactPrice(*b.min) + " and over"
+	default:
+		return compactPrice(*b.min) + "–" + compactPrice(*b.max)
+	}
+}
+
+func (b bandCount) String() string {
+	return fmt.Sprintf("%s: %d (%.0f%%)", b.label(), b.count, b.percent)
+}
+
+func (b bandCount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Label   string  `json:"label"`
+		Min     *uint64 `json:"min"`
+		Max     *uint64 `json:"max"`
+		Count   int     `json:"count"`
+		Percent float64 `json:"percent"`
+	}{b.label(), b.min, b.max, b.count, b.percent})
+}
+
+// bandEdges returns the edges given with --bands, which parseArgs has
+// already validated.
+func bandEdges(args *cliArgs) []uint64 {
+	edges, err := parseBandEdges(args.Bands)
+	if err != nil {
+		return nil
+	}
+
+	return edges
+}
@@ -0,0 +1,136 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBandEdges(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    []uint64
+		wantErr string
+	}{
+		{arg: "500000", want: []uint64{500000}},
+		{arg: "300000, 500000,750000", want: []uint64{300000, 500000, 750000}},
+		{arg: "0,1", want: []uint64{0, 1}},
+		{arg: "500000,300000", wantErr: "band edges must be strictly increasing, but 300000 follows 500000"},
+		{arg: "500000,500000", wantErr: "strictly increasing"},
+		{arg: "500k", wantErr: `invalid band edge "500k", must be a price in pounds`},
+		{arg: "-1", wantErr: "invalid band edge"},
+		{arg: "", wantErr: "invalid band edge"},
+		{arg: "1,,2", wantErr: "invalid band edge"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := parseBandEdges(tt.arg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got %v, %v, want an error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBandIndex(t *testing.T) {
+	edges := []uint64{300000, 500000}
+
+	tests := []struct {
+		price uint64
+		want  int
+	}{
+		{0, 0},
+		{299999, 0},
+		{300000, 1},
+		{499999, 1},
+		{500000, 2},
+		{9000000, 2},
+	}
+
+	for _, tt := range tests {
+		if got := bandIndex(edges, tt.price); got != tt.want {
+			t.Errorf("bandIndex(%d) = %d, want %d", tt.price, got, tt.want)
+		}
+	}
+	if got := bandIndex(nil, 100); got != 0 {
+		t.Errorf("bandIndex without edges = %d, want 0", got)
+	}
+}
+
+func TestCalculateBandCounts(t *testing.T) {
+	prices := []uint64{250000, 300000, 350000, 450000, 500000, 1000000, 200000, 600000}
+
+	got := calculateBandCounts(prices, []uint64{300000, 500000})
+	want := []string{"under £300k: 2 (25%)", "£300k–£500k: 3 (38%)", "£500k and over: 3 (38%)"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d bands, want %d", len(got), len(want))
+	}
+	total := 0
+	for i, b := range got {
+		if b.String() != want[i] {
+			t.Errorf("band %d: got %q, want %q", i, b.String(), want[i])
+		}
+		total += b.count
+	}
+	if total != len(prices) {
+		t.Errorf("bands count %d prices, want %d", total, len(prices))
+	}
+}
+
+func TestCalculateBandCountsEdgeCases(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []uint64
+		edges  []uint64
+		want   []string
+	}{
+		{"no prices", nil, []uint64{500000}, []string{"under £500k: 0 (0%)", "£500k and over: 0 (0%)"}},
+		{"no edges", []uint64{1, 2}, nil, []string{"all: 2 (100%)"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, b := range calculateBandCounts(tt.prices, tt.edges) {
+				got = append(got, b.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBandCountJSON(t *testing.T) {
+	bands := calculateBandCounts([]uint64{100, 600000}, []uint64{500000})
+	data, err := json.Marshal(bands)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `[{"label":"under £500k","min":null,"max":500000,"count":1,"percent":50},` +
+		`{"label":"£500k and over","min":500000,"max":null,"count":1,"percent":50}]`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestBandEdges(t *testing.T) {
+	if got := bandEdges(&cliArgs{Bands: "100,200"}); !reflect.DeepEqual(got, []uint64{100, 200}) {
+		t.Errorf("got %v, want [100 200]", got)
+	}
+	if got := bandEdges(&cliArgs{}); got != nil {
+		t.Errorf("got %v without --bands, want none", got)
+	}
+}
@@ -0,0 +1,127 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+)
+
+const (
+	defaultCILevel            = 0.95
+	defaultBootstrapResamples = 1000
+
+	// minBootstrapSample is the fewest prices worth resampling.
+	minBootstrapSample = 5
+)
+
+type bootstrapOptions struct {
+	level     float64
+	resamples int
+	seed      int64
+}
+
+// newBootstrapOptions uses the --seed given, or else a seed from the clock.
+func newBootstrapOptions(args *cliArgs) *bootstrapOptions {
+	seed := timeNow().UnixNano()
+	if args.Seed != nil {
+		seed = *args.Seed
+	}
+
+	return &bootstrapOptions{level: args.CILevel, resamples: args.BootstrapResamples, seed: seed}
+}
+
+type confidenceInterval struct {
+	level float64
+	low   float64
+	high  float64
+}
+
+func (ci confidenceInterval) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Level float64 `json:"level"`
+		Low   float64 `json:"low"`
+		High  float64 `json:"high"`
+	}{ci.level, ci.low, ci.high})
+}
+
+// bootstrapCIs are percentile bootstrap confidence intervals for the mean
+// and median of some prices.
+type bootstrapCIs struct {
+	mean   confidenceInterval
+	median confidenceInterval
+}
+
+// calculateBootstrapCIs resamples sorted prices with replacement and takes
+// the central level of the resampled means and medians. It returns nil when
+// there are too few prices for resampling to mean much.
+func calculateBootstrapCIs(sorted []uint64, opts bootstrapOptions) *bootstrapCIs {
+	if len(sorted) < minBootstrapSample || opts.resamples <= 0 {
+		return nil
+	}
+
+	rng := rand.New(rand.NewSource(opts.seed))
+	means := make([]float64, opts.resamples)
+	medians := make([]float64, opts.resamples)
+	sample := make([]uint64, len(sorted))
+	for i := range means {
+		for j := range sample {
+			sample[j] = sorted[rng.Intn(len(sorted))]
+		}
+		means[i] = calculateMean(sample)
+		sort.Slice(sample, func(a, b int) bool { return sample[a] < sample[b] })
+		medians[i] = quantile(sample, 0.5)
+	}
+
+	return &bootstrapCIs{
+		mean:   percentileInterval(means, opts.level),
+		median: percentileInterval(medians, opts.level),
+	}
+}
+
+func percentileInterval(values []float64, level float64) confidenceInterval {
+	sort.Float64s(values)
+	tail := (1 - level) / 2
+
+	return confidenceInterval{
+		level: level,
+		low:   floatQuantile(values, tail),
+		high:  floatQuantile(values, 1-tail),
+	}
+}
+
+// floatQuantile is quantile for sorted floats.
+func floatQuantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// describeEstimate formats an estimate with its interval, like
+// "mean £512k (95% CI £489k–£536k)".
+func describeEstimate(name string, v float64, ci confidenceInterval) string {
+	return fmt.Sprintf("%s %s (%s CI %s–%s)", name, roundedPrice(v), fractionPercent(ci.level), roundedPrice(ci.low), roundedPrice(ci.high))
+}
+
+func (b bootstrapCIs) describe(s priceStats) string {
+	return describeEstimate("mean", s.mean, b.mean) + ", " + describeEstimate("median", s.median, b.median)
+}
+
+// roundedPrice formats a price to three significant figures or so, like
+// £512k or £1.25m.
+func roundedPrice(v float64) string {
+	switch {
+	case v >= 1000000:
+		return "£" + strconv.FormatFloat(math.Round(v/10000)/100, 'f', -1, 64) + "m"
+	case v >= 1000:
+		return "£" + strconv.FormatFloat(math.Round(v/1000), 'f', -1, 64) + "k"
+	default:
+		return "£" + strconv.FormatFloat(math.Round(v), 'f', -1, 64)
+	}
+}
@@ -0,0 +1,168 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewBootstrapOptions(t *testing.T) {
+	now := time.Date(2021, time.May, 10, 9, 0, 0, 0, time.UTC)
+	setNow(t, now)
+
+	seed := int64(42)
+	tests := []struct {
+		name string
+		args cliArgs
+		want bootstrapOptions
+	}{
+		{"seeded", cliArgs{CILevel: 0.9, BootstrapResamples: 500, Seed: &seed}, bootstrapOptions{level: 0.9, resamples: 500, seed: 42}},
+		{"clock", cliArgs{CILevel: 0.95, BootstrapResamples: 1000}, bootstrapOptions{level: 0.95, resamples: 1000, seed: now.UnixNano()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newBootstrapOptions(&tt.args); *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateBootstrapCIs(t *testing.T) {
+	sorted := make([]uint64, 50)
+	for i := range sorted {
+		sorted[i] = uint64(300000 + 10000*i)
+	}
+	mean := calculateMean(sorted)
+	median := quantile(sorted, 0.5)
+	opts := bootstrapOptions{level: 0.95, resamples: 2000, seed: 1}
+
+	got := calculateBootstrapCIs(sorted, opts)
+	if got == nil {
+		t.Fatal("got no intervals")
+	}
+	for _, c := range []struct {
+		name     string
+		estimate float64
+		ci       confidenceInterval
+	}{{"mean", mean, got.mean}, {"median", median, got.median}} {
+		if c.ci.level != 0.95 {
+			t.Errorf("%s: got level %v", c.name, c.ci.level)
+		}
+		if !(c.ci.low < c.estimate && c.estimate < c.ci.high) {
+			t.Errorf("%s: interval %+v doesn't contain the estimate %v", c.name, c.ci, c.estimate)
+		}
+		if c.ci.low < float64(sorted[0]) || c.ci.high > float64(sorted[len(sorted)-1]) {
+			t.Errorf("%s: interval %+v is outside the prices", c.name, c.ci)
+		}
+	}
+
+	// The same seed gives the same intervals, while a narrower level gives
+	// a narrower interval.
+	if again := calculateBootstrapCIs(sorted, opts); *again != *got {
+		t.Errorf("got %+v then %+v with the same seed", *got, *again)
+	}
+	narrow := calculateBootstrapCIs(sorted, bootstrapOptions{level: 0.5, resamples: 2000, seed: 1})
+	if narrow.mean.high-narrow.mean.low >= got.mean.high-got.mean.low {
+		t.Errorf("got 50%% interval %+v no narrower than the 95%% %+v", narrow.mean, got.mean)
+	}
+}
+
+func TestCalculateBootstrapCIsTooFew(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []uint64
+		opts   bootstrapOptions
+	}{
+		{"too few prices", []uint64{1, 2, 3, 4}, bootstrapOptions{level: 0.95, resamples: 100}},
+		{"no resamples", []uint64{1, 2, 3, 4, 5}, bootstrapOptions{level: 0.95}},
+	}
+
+	for _, tt := range tests {
+		if got := calculateBootstrapCIs(tt.sorted, tt.opts); got != nil {
+			t.Errorf("%s: got %+v, want nil", tt.name, *got)
+		}
+	}
+}
+
+func TestCalculateBootstrapCIsIdenticalPrices(t *testing.T) {
+	got := calculateBootstrapCIs([]uint64{500, 500, 500, 500, 500}, bootstrapOptions{level: 0.95, resamples: 100})
+	want := confidenceInterval{level: 0.95, low: 500, high: 500}
+	if got.mean != want || got.median != want {
+		t.Errorf("got %+v, want both %+v", *got, want)
+	}
+}
+
+func TestFloatQuantile(t *testing.T) {
+	sorted := []float64{10, 20, 30, 40, 50}
+
+	tests := []struct {
+		q, want float64
+	}{
+		{0, 10},
+		{0.25, 20},
+		{0.5, 30},
+		{0.1, 14},
+		{1, 50},
+	}
+
+	for _, tt := range tests {
+		if got := floatQuantile(sorted, tt.q); !closeTo(got, tt.want) {
+			t.Errorf("floatQuantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestRoundedPrice(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want string
+	}{
+		{499.6, "£500"},
+		{512345, "£512k"},
+		{999499, "£999k"},
+		{1000000, "£1m"},
+		{1254999, "£1.25m"},
+		{1255000, "£1.26m"},
+	}
+
+	for _, tt := range tests {
+		if got := roundedPrice(tt.v); got != tt.want {
+			t.Errorf("roundedPrice(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeBootstrapCIs(t *testing.T) {
+	s := priceStats{mean: 512000, median: 480000}
+	b := bootstrapCIs{
+		mean:   confidenceInterval{level: 0.95, low: 489000, high: 536000},
+		median: confidenceInterval{level: 0.95, low: 450000, high: 1250000},
+	}
+
+	want := "mean £512k (95% CI £489k–£536k), median £480k (95% CI £450k–£1.25m)"
+	if got := b.describe(s); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	data, err := json.Marshal(b.mean)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"level":0.95,"low":489000,"high":536000}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestPriceStatsBootstrap(t *testing.T) {
+	prices := []uint64{500000, 300000, 400000, 600000, 450000, 350000}
+
+	s := calculatePriceStatsWith(prices, statsOptions{bootstrap: &bootstrapOptions{level: 0.9, resamples: 200, seed: 1}})
+	if s.intervals == nil || s.intervals.mean.level != 0.9 {
+		t.Errorf("got intervals %+v", s.intervals)
+	}
+	if s := calculatePriceStatsWith(prices, statsOptions{}); s.intervals != nil {
+		t.Errorf("got intervals %+v without asking", *s.intervals)
+	}
+}
@@ -0,0 +1,52 @@
+package zoopla
+
+import "fmt"
+
+// whiskerIQRs is how many IQRs beyond the quartiles the whiskers of a box
+// plot reach. Prices beyond them are outliers.
+const whiskerIQRs = 1.5
+
+type boxPlot struct {
+	Min          uint64   `json:"min"`
+	Q1           float64  `json:"q1"`
+	Q2           float64  `json:"q2"`
+	Q3           float64  `json:"q3"`
+	Max          uint64   `json:"max"`
+	IQR          float64  `json:"iqr"`
+	LowerWhisker float64  `json:"lower_whisker"`
+	UpperWhisker float64  `json:"upper_whisker"`
+	Outliers     []uint64 `json:"outliers"`
+}
+
+// calculateBoxPlot calculates the box plot of sorted, which mustn't be
+// empty. Outliers are in ascending order.
+func calculateBoxPlot(sorted []uint64) boxPlot {
+	b := boxPlot{
+		Min:      sorted[0],
+		Q1:       quantile(sorted, 0.25),
+		Q2:       quantile(sorted, 0.5),
+		Q3:       quantile(sorted, 0.75),
+		Max:      sorted[len(sorted)-1],
+		Outliers: []uint64{},
+	}
+	b.IQR = b.Q3 - b.Q1
+	b.LowerWhisker = b.Q1 - whiskerIQRs*b.IQR
+	b.UpperWhisker = b.Q3 + whiskerIQRs*b.IQR
+
+	for _, p := range sorted {
+		if b.isOutlier(p) {
+			b.Outliers = append(b.Outliers, p)
+		}
+	}
+
+	return b
+}
+
+func (b boxPlot) isOutlier(price uint64) bool {
+	return float64(price) < b.LowerWhisker || float64(price) > b.UpperWhisker
+}
+
+func (b boxPlot) String() string {
+	return fmt.Sprintf("min = %d, q1 = %.0f, median = %.0f, q3 = %.0f, max = %d, iqr = %.0f, outliers = %d",
+		b.Min, b.Q1, b.Q2, b.Q3, b.Max, b.IQR, len(b.Outliers))
+}
@@ -0,0 +1,69 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCalculateBoxPlot(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []uint64
+		want   boxPlot
+	}{
+		{
+			name:   "single",
+			sorted: []uint64{400},
+			want:   boxPlot{Min: 400, Q1: 400, Q2: 400, Q3: 400, Max: 400, LowerWhisker: 400, UpperWhisker: 400, Outliers: []uint64{}},
+		},
+		{
+			name:   "no outliers",
+			sorted: []uint64{100, 200, 300, 400, 500},
+			want:   boxPlot{Min: 100, Q1: 200, Q2: 300, Q3: 400, Max: 500, IQR: 200, LowerWhisker: -100, UpperWhisker: 700, Outliers: []uint64{}},
+		},
+		{
+			name:   "high outlier",
+			sorted: []uint64{100, 200, 300, 400, 5000},
+			want:   boxPlot{Min: 100, Q1: 200, Q2: 300, Q3: 400, Max: 5000, IQR: 200, LowerWhisker: -100, UpperWhisker: 700, Outliers: []uint64{5000}},
+		},
+		{
+			name:   "outliers both sides",
+			sorted: []uint64{1, 1000, 1000, 1000, 1000, 1000, 1000, 1000, 1000, 99999},
+			want:   boxPlot{Min: 1, Q1: 1000, Q2: 1000, Q3: 1000, Max: 99999, LowerWhisker: 1000, UpperWhisker: 1000, Outliers: []uint64{1, 99999}},
+		},
+		{
+			name:   "on the whisker",
+			sorted: []uint64{100, 200, 300, 400, 700},
+			want:   boxPlot{Min: 100, Q1: 200, Q2: 300, Q3: 400, Max: 700, IQR: 200, LowerWhisker: -100, UpperWhisker: 700, Outliers: []uint64{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateBoxPlot(tt.sorted); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoxPlotJSON(t *testing.T) {
+	data, err := json.Marshal(calculateBoxPlot([]uint64{100, 200, 300}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Outliers are an empty array rather than null when there are none.
+	if !strings.Contains(string(data), `"outliers":[]`) {
+		t.Errorf("got %s", data)
+	}
+}
+
+func TestBoxPlotString(t *testing.T) {
+	want := "min = 100, q1 = 200, median = 300, q3 = 400, max = 5000, iqr = 200, outliers = 1"
+	if got := calculateBoxPlot([]uint64{100, 200, 300, 400, 5000}).String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
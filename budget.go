@@ -0,0 +1,95 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// budgetCoverage is how much of the market a budget can afford: the
+// listings at or under it.
+type budgetCoverage struct {
+	budget uint64
+	all    budgetGroup
+	byBeds []budgetGroup
+}
+
+type budgetGroup struct {
+	Key        string  `json:"key"`
+	Affordable int     `json:"affordable"`
+	Total      int     `json:"total"`
+	Percent    float64 `json:"percent"`
+}
+
+func (g *budgetGroup) add(cost, budget uint64) {
+	g.Total++
+	if cost <= budget {
+		g.Affordable++
+	}
+}
+
+func (g *budgetGroup) finish() {
+	if g.Total > 0 {
+		g.Percent = 100 * float64(g.Affordable) / float64(g.Total)
+	}
+}
+
+// calculateBudgetCoverage finds how many of listings cost no more than
+// budget, overall and by bedroom count when any bedroom counts are known.
+func calculateBudgetCoverage(listings []listing, budget uint64, cost func(*listing) uint64) budgetCoverage {
+	c := budgetCoverage{budget: budget, all: budgetGroup{Key: "all"}}
+
+	groups := make(map[string]*budgetGroup)
+	for i := range listings {
+		l := &listings[i]
+		c.all.add(cost(l), budget)
+
+		k := bedsKey(l)
+		g, ok := groups[k]
+		if !ok {
+			g = &budgetGroup{Key: k}
+			groups[k] = g
+		}
+		g.add(cost(l), budget)
+	}
+	c.all.finish()
+
+	if _, ok := groups[unknownGroup]; ok && len(groups) == 1 {
+		return c
+	}
+
+	for _, g := range groups {
+		g.finish()
+		c.byBeds = append(c.byBeds, *g)
+	}
+	sort.Slice(c.byBeds, func(i, j int) bool { return lessGroupKey(c.byBeds[i].Key, c.byBeds[j].Key) })
+
+	return c
+}
+
+func (c budgetCoverage) String() string {
+	if c.all.Total == 0 {
+		return fmt.Sprintf("no listings to compare with a budget of %s", formatPrice(c.budget))
+	}
+
+	return fmt.Sprintf("%d of %d listings (%.0f%%) are at or under %s",
+		c.all.Affordable, c.all.Total, c.all.Percent, formatPrice(c.budget))
+}
+
+func (g budgetGroup) String() string {
+	return fmt.Sprintf("%s: %d of %d (%.0f%%)", g.Key, g.Affordable, g.Total, g.Percent)
+}
+
+func (c budgetCoverage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Budget     uint64        `json:"budget"`
+		Affordable int           `json:"affordable"`
+		Total      int           `json:"total"`
+		Percent    float64       `json:"percent"`
+		ByBeds     []budgetGroup `json:"by_beds,omitempty"`
+	}{c.budget, c.all.Affordable, c.all.Total, c.all.Percent, c.byBeds})
+}
+
+func listingPrice(l *listing) uint64 {
+	return l.Price
+}
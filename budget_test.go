@@ -0,0 +1,124 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCalculateBudgetCoverage(t *testing.T) {
+	tests := []struct {
+		name   string
+		budget uint64
+		all    budgetGroup
+		byBeds []string
+	}{
+		{
+			name:   "between listings",
+			budget: 480000,
+			all:    budgetGroup{Key: "all", Affordable: 5, Total: 8, Percent: 62.5},
+			byBeds: []string{"1 bed: 3 of 3 (100%)", "2 bed: 2 of 3 (67%)", "3 bed: 0 of 2 (0%)"},
+		},
+		{
+			name:   "under everything",
+			budget: 100000,
+			all:    budgetGroup{Key: "all", Total: 8},
+			byBeds: []string{"1 bed: 0 of 3 (0%)", "2 bed: 0 of 3 (0%)", "3 bed: 0 of 2 (0%)"},
+		},
+		{
+			name:   "over everything",
+			budget: 750000,
+			all:    budgetGroup{Key: "all", Affordable: 8, Total: 8, Percent: 100},
+			byBeds: []string{"1 bed: 3 of 3 (100%)", "2 bed: 3 of 3 (100%)", "3 bed: 2 of 2 (100%)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := calculateBudgetCoverage(reportListings(), tt.budget, listingPrice)
+			if c.all != tt.all {
+				t.Errorf("got %+v, want %+v", c.all, tt.all)
+			}
+
+			var byBeds []string
+			for _, g := range c.byBeds {
+				byBeds = append(byBeds, g.String())
+			}
+			if !reflect.DeepEqual(byBeds, tt.byBeds) {
+				t.Errorf("got %q by beds, want %q", byBeds, tt.byBeds)
+			}
+		})
+	}
+}
+
+func TestCalculateBudgetCoverageUnknownBeds(t *testing.T) {
+	listings := []listing{{ID: "1", Price: 100}, {ID: "2", Price: 300}}
+
+	c := calculateBudgetCoverage(listings, 200, listingPrice)
+	if c.byBeds != nil {
+		t.Errorf("got %+v by beds when no bedroom counts are known", c.byBeds)
+	}
+	if c.all.Affordable != 1 || c.all.Total != 2 {
+		t.Errorf("got %+v", c.all)
+	}
+
+	listings[0].Beds = uint32Ptr(1)
+	c = calculateBudgetCoverage(listings, 200, listingPrice)
+	if len(c.byBeds) != 2 || c.byBeds[0].Key != "1 bed" || c.byBeds[1].Key != unknownGroup {
+		t.Errorf("got %+v by beds", c.byBeds)
+	}
+}
+
+func TestBudgetCoverageString(t *testing.T) {
+	tests := []struct {
+		name     string
+		listings []listing
+		want     string
+	}{
+		{"listings", reportListings(), "5 of 8 listings (62%) are at or under £480,000"},
+		{"none", nil, "no listings to compare with a budget of £480,000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateBudgetCoverage(tt.listings, 480000, listingPrice).String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBudgetCoverageJSON(t *testing.T) {
+	listings := []listing{{ID: "1", Price: 100}, {ID: "2", Price: 300}}
+
+	data, err := json.Marshal(calculateBudgetCoverage(listings, 200, listingPrice))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"budget":200,"affordable":1,"total":2,"percent":50}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	listings[0].Beds = uint32Ptr(2)
+	data, err = json.Marshal(calculateBudgetCoverage(listings, 200, listingPrice))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"budget":200,"affordable":1,"total":2,"percent":50,"by_beds":[` +
+		`{"key":"2 bed","affordable":1,"total":1,"percent":100},` +
+		`{"key":"unknown","affordable":0,"total":1,"percent":0}]}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestCalculateBudgetCoverageTotalCost(t *testing.T) {
+	listings := reportListings()
+	assignStampDuty(listings, buyerHomeMover)
+
+	// Stamp duty pushes the £480,000 listing over budget.
+	c := calculateBudgetCoverage(listings, 480000, affordability{}.cost)
+	if c.all.Affordable != 4 || c.all.Total != 8 {
+		t.Errorf("got %+v, want 4 of 8 affordable", c.all)
+	}
+}
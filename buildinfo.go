@@ -0,0 +1,23 @@
+//go:build go1.18
+// +build go1.18
+
+package zoopla
+
+import "runtime/debug"
+
+// vcsRevision returns the commit the binary was built from, if the build
+// recorded it.
+func vcsRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			return s.Value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,9 @@
+//go:build !go1.18
+// +build !go1.18
+
+package zoopla
+
+// vcsRevision returns "" as builds before Go 1.18 don't record the commit.
+func vcsRevision() string {
+	return ""
+}
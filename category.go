@@ -0,0 +1,100 @@
+package zoopla
+
+import (
+	"log"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	categoryResidential = "residential"
+	categoryLand        = "land"
+	categoryParking     = "parking"
+	categoryCommercial  = "commercial"
+)
+
+var categories = []string{categoryResidential, categoryLand, categoryParking, categoryCommercial}
+
+// categoryRules are checked in order against the lower-cased card title. The
+// residential rule comes first so "house with garage" is not parking.
+var categoryRules = []struct {
+	category string
+	pattern  *regexp.Regexp
+}{
+	{categoryResidential, regexp.MustCompile(`\b(?:\d+ bed|studio|flat|apartment|house|maisonette|bungalow|cottage|terrace|detached|duplex|penthouse|town house|mobile home|houseboat)\b`)},
+	{categoryLand, regexp.MustCompile(`\b(?:land|plot|building plot|woodland|farm land)\b`)},
+	{categoryParking, regexp.MustCompile(`\b(?:parking|garage|car port|mooring)\b`)},
+	{categoryCommercial, regexp.MustCompile(`\b(?:commercial|office|retail|shop|warehouse|industrial|restaurant|pub|hotel|leisure|business|investment)\b`)},
+}
+
+func isCategory(s string) bool {
+	for _, c := range categories {
+		if s == c {
+			return true
+		}
+	}
+
+	return false
+}
+
+// classifyListing assigns a category from embedded category data when
+// present, falling back to the title phrase. Anything unrecognised is
+// treated as residential.
+func classifyListing(title, category string) string {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if isCategory(category) {
+		return category
+	}
+
+	title = strings.ToLower(title)
+	for _, rule := range categoryRules {
+		if rule.pattern.MatchString(title) {
+			return rule.category
+		}
+	}
+
+	return categoryResidential
+}
+
+func isTitleNode(n *html.Node) bool {
+	return n.Type == html.ElementNode && (n.Data == "h2" || hasTestID(n, "listing-title"))
+}
+
+func parseListingTitle(card *html.Node) string {
+	title := findFirst(card, isTitleNode)
+	if title == nil {
+		return ""
+	}
+
+	return strings.Join(strings.Fields(textContent(title)), " ")
+}
+
+// filterCategories drops listings outside the residential category unless
+// their category was explicitly included, logging the number excluded per
+// category.
+func filterCategories(listings []listing, include []string) []listing {
+	included := map[string]bool{categoryResidential: true}
+	for _, c := range include {
+		included[c] = true
+	}
+
+	excluded := make(map[string]int)
+	kept := listings[:0]
+	for i := range listings {
+		if included[listings[i].Category] {
+			kept = append(kept, listings[i])
+			continue
+		}
+		excluded[listings[i].Category]++
+	}
+
+	for _, c := range categories {
+		if excluded[c] > 0 {
+			log.Printf("excluded %d %s listings", excluded[c], c)
+		}
+	}
+
+	return kept
+}
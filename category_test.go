@@ -0,0 +1,103 @@
+package zoopla
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyListing(t *testing.T) {
+	tests := []struct {
+		title    string
+		category string
+		want     string
+	}{
+		{title: "2 bed flat for sale", want: categoryResidential},
+		{title: "Studio for sale", want: categoryResidential},
+		{title: "3 bed house with garage", want: categoryResidential},
+		{title: "Land for sale", want: categoryLand},
+		{title: "Building plot for sale", want: categoryLand},
+		{title: "Garage for sale", want: categoryParking},
+		{title: "Parking/garage for sale", want: categoryParking},
+		{title: "Shop for sale", want: categoryCommercial},
+		{title: "Office to let", want: categoryCommercial},
+		{title: "Property for sale", want: categoryResidential},
+		{title: "", want: categoryResidential},
+		{title: "2 bed flat for sale", category: "Commercial", want: categoryCommercial},
+		{title: "Land for sale", category: " residential ", want: categoryResidential},
+		{title: "Land for sale", category: "retirement", want: categoryLand},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.title+"/"+tt.category, func(t *testing.T) {
+			if got := classifyListing(tt.title, tt.category); got != tt.want {
+				t.Errorf("classifyListing(%q, %q) = %q, want %q", tt.title, tt.category, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListingTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		card string
+		want string
+	}{
+		{
+			name: "h2",
+			card: `<div data-testid="search-result"><h2>  2 bed
+				flat for sale </h2></div>`,
+			want: "2 bed flat for sale",
+		},
+		{
+			name: "test ID",
+			card: `<div data-testid="search-result"><p data-testid="listing-title">Garage for sale</p></div>`,
+			want: "Garage for sale",
+		},
+		{
+			name: "missing",
+			card: `<div data-testid="search-result"><p>£450,000</p></div>`,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseListingTitle(mustParseCard(t, tt.card)); got != tt.want {
+				t.Errorf("parseListingTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterCategories(t *testing.T) {
+	listings := []listing{
+		{ID: "1", Category: categoryResidential},
+		{ID: "2", Category: categoryLand},
+		{ID: "3", Category: categoryParking},
+		{ID: "4", Category: categoryResidential},
+		{ID: "5", Category: categoryCommercial},
+	}
+
+	tests := []struct {
+		name    string
+		include []string
+		want    []string
+	}{
+		{name: "residential only", want: []string{"1", "4"}},
+		{name: "with land", include: []string{categoryLand}, want: []string{"1", "2", "4"}},
+		{name: "all", include: []string{categoryLand, categoryParking, categoryCommercial}, want: []string{"1", "2", "3", "4", "5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := append([]listing(nil), listings...)
+			var got []string
+			for _, l := range filterCategories(in, tt.include) {
+				got = append(got, l.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filterCategories() kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
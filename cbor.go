@@ -0,0 +1,77 @@
+package zoopla
+
+import (
+	"os"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/pkg/errors"
+)
+
+// cborDecMode decodes CBOR maps with string keys, so that a decoded document
+// can be re-encoded as JSON.
+var cborDecMode = func() cbor.DecMode {
+	mode, err := cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]interface{}(nil))}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}()
+
+// cborEncMode writes times as RFC 3339 strings, as JSON does.
+var cborEncMode = func() cbor.EncMode {
+	mode, err := cbor.EncOptions{Time: cbor.TimeRFC3339Nano}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}()
+
+// marshalCBOR encodes v as CBOR. Field names come from the json struct tags,
+// so the structure is the same as the JSON output.
+func marshalCBOR(v interface{}) ([]byte, error) {
+	data, err := cborEncMode.Marshal(v)
+	return data, errors.Wrap(err, "while marshalling CBOR")
+}
+
+// cborToJSON converts a CBOR document to JSON.
+func cborToJSON(data []byte, pretty bool) ([]byte, error) {
+	var v interface{}
+	if err := cborDecMode.Unmarshal(data, &v); err != nil {
+		return nil, errors.Wrap(err, "while decoding CBOR")
+	}
+
+	out, err := marshalJSON(v, pretty)
+	return out, errors.Wrap(err, "while converting CBOR to JSON")
+}
+
+type decodeArgs struct {
+	Input  string `arg:"positional,required"`
+	Output string `arg:"positional"`
+}
+
+// runDecode converts a CBOR output file to indented JSON for inspection,
+// written to stdout unless an output file is given.
+func runDecode(argv []string) error {
+	var args decodeArgs
+	if _, err := parseSubcommand("decode", &args, argv); err != nil {
+		return err
+	}
+
+	data, err := readFileMaybeGzip(args.Input)
+	if err != nil {
+		return errors.Wrapf(err, "while reading %s", args.Input)
+	}
+
+	out, err := cborToJSON(data, true)
+	if err != nil {
+		return errors.Wrapf(err, "while decoding %s", args.Input)
+	}
+
+	if args.Output == "" || args.Output == stdoutFilename {
+		_, err = os.Stdout.Write(append(out, '\n'))
+		return errors.Wrap(err, "while writing JSON to stdout")
+	}
+
+	return writeFileAtomic(args.Output, out)
+}
@@ -0,0 +1,302 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func cborTestMetadata() *outputMetadata {
+	return &outputMetadata{
+		GeneratedAt:  "2021-05-10T09:00:00Z",
+		Query:        runQuery{Postcode: "SW2", BedsMin: uint32Ptr(2), Radius: 1},
+		ToolVersion:  "test",
+		PagesFetched: 3,
+		Counts:       outputCounts{Parsed: 50, SkippedPOA: 2, Deduped: 48},
+		Histogram:    []histogramBucket{{Min: 400000, Max: 450000, Count: 30}, {Min: 450000, Max: 500000, Count: 18}},
+	}
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	listings := []listing{fullListing(), {ID: "58500001", Price: 400000, Status: statusForSale, Category: categoryResidential}}
+
+	tests := []struct {
+		name string
+		args cliArgs
+		meta *outputMetadata
+		kind string
+	}{
+		{name: "listings", args: cliArgs{OutputMode: outputModeListings}, kind: outputKindListings},
+		{name: "listings with metadata", args: cliArgs{OutputMode: outputModeListings, OutputSet: outputSetDeduped}, meta: cborTestMetadata(), kind: outputKindListings},
+		{name: "prices", kind: outputKindPrices},
+		{name: "prices with metadata", meta: cborTestMetadata(), kind: outputKindPrices},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			args.Format = formatCBOR
+			data, err := marshalOutput(listings, &args, tt.meta)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadOutput(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Kind != tt.kind {
+				t.Fatalf("got kind %s, want %s", got.Kind, tt.kind)
+			}
+			if !reflect.DeepEqual(got.Metadata, tt.meta) {
+				t.Errorf("got metadata %+v, want %+v", got.Metadata, tt.meta)
+			}
+			if !equalPrices(got.Prices, []uint64{450000, 400000}) {
+				t.Errorf("got prices %v", got.Prices)
+			}
+			if tt.kind == outputKindListings && !reflect.DeepEqual(got.Listings, listings) {
+				t.Errorf("got listings %+v, want %+v", got.Listings, listings)
+			}
+		})
+	}
+}
+
+func TestCBORMatchesJSON(t *testing.T) {
+	listings := []listing{fullListing()}
+
+	for _, mode := range []string{outputModeListings, outputModePrices} {
+		t.Run(mode, func(t *testing.T) {
+			jsonArgs := cliArgs{OutputMode: mode, Format: formatJSON}
+			cborArgs := cliArgs{OutputMode: mode, Format: formatCBOR}
+
+			want, err := marshalOutput(listings, &jsonArgs, cborTestMetadata())
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := marshalOutput(listings, &cborArgs, cborTestMetadata())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := cborToJSON(data, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Keys come back in sorted order, so compare the decoded
+			// documents.
+			var gotDoc, wantDoc interface{}
+			if err := json.Unmarshal(got, &gotDoc); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal(want, &wantDoc); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(gotDoc, wantDoc) {
+				t.Errorf("CBOR decodes to\n%s\nwant the JSON output\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestCBORPricesAlwaysEnveloped(t *testing.T) {
+	args := cliArgs{Format: formatCBOR}
+	data, err := marshalOutput([]listing{{Price: 400000}}, &args, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := cborToJSON(data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"prices":[400000],"schema_version":` + strconv.Itoa(pricesSchemaVersion) + `}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestCBORToJSONInvalid(t *testing.T) {
+	for _, data := range [][]byte{nil, []byte("not cbor"), {0xbf}} {
+		if _, err := cborToJSON(data, false); err == nil || !strings.Contains(err.Error(), "while decoding CBOR") {
+			t.Errorf("cborToJSON(%q) got error %v, want a decoding error", data, err)
+		}
+	}
+}
+
+func TestMarshalCBORTimes(t *testing.T) {
+	data, err := marshalCBOR(map[string]time.Time{"at": time.Date(2021, time.May, 10, 9, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := cborToJSON(data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"at":"2021-05-10T09:00:00Z"}`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestRunDecode(t *testing.T) {
+	dir := t.TempDir()
+	args := cliArgs{OutputMode: outputModeListings, Format: formatCBOR}
+	data, err := marshalOutput([]listing{fullListing()}, &args, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := filepath.Join(dir, "listings.cbor")
+	if err := ioutil.WriteFile(input, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	want, err := cborToJSON(data, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("file", func(t *testing.T) {
+		output := filepath.Join(dir, "listings.json")
+		if err := runDecode([]string{input, output}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := ioutil.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("got\n%s\nwant\n%s", got, want)
+		}
+		if _, err := loadOutputFile(output); err != nil {
+			t.Errorf("decoded file doesn't load: %v", err)
+		}
+	})
+
+	t.Run("stdout", func(t *testing.T) {
+		var err error
+		got, _ := captureOutput(t, func() { err = runDecode([]string{input}) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want)+"\n" {
+			t.Errorf("got\n%s\nwant\n%s", got, want)
+		}
+	})
+
+	t.Run("not CBOR", func(t *testing.T) {
+		notCBOR := filepath.Join(dir, "prices.json")
+		if err := ioutil.WriteFile(notCBOR, []byte("[1, 2"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := runDecode([]string{notCBOR}); err == nil || !strings.Contains(err.Error(), "while decoding "+notCBOR) {
+			t.Errorf("got error %v, want a decoding error", err)
+		}
+	})
+}
+
+// benchmarkListings are as many listings as a large multi-area search
+// returns.
+func benchmarkListings() []listing {
+	listings := make([]listing, 2000)
+	for i := range listings {
+		listings[i] = fullListing()
+		listings[i].ID = strconv.Itoa(58500000 + i)
+		listings[i].Price = uint64(300000 + 250*i)
+	}
+
+	return listings
+}
+
+// The marshal benchmarks compare the size and speed of the JSON and CBOR
+// outputs, reporting each output's size in bytes.
+func BenchmarkMarshalListingsJSON(b *testing.B) {
+	benchmarkMarshal(b, formatJSON, outputModeListings)
+}
+
+func BenchmarkMarshalListingsCBOR(b *testing.B) {
+	benchmarkMarshal(b, formatCBOR, outputModeListings)
+}
+
+func BenchmarkMarshalPricesJSON(b *testing.B) {
+	benchmarkMarshal(b, formatJSON, outputModePrices)
+}
+
+func BenchmarkMarshalPricesCBOR(b *testing.B) {
+	benchmarkMarshal(b, formatCBOR, outputModePrices)
+}
+
+func benchmarkMarshal(b *testing.B, format, mode string) {
+	listings := benchmarkListings()
+	args := cliArgs{Format: format, OutputMode: mode}
+	meta := cborTestMetadata()
+
+	var size int
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		data, err := marshalOutput(listings, &args, meta)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(data)
+	}
+	b.ReportMetric(float64(size), "output-bytes")
+}
+
+func BenchmarkLoadListingsJSON(b *testing.B) {
+	benchmarkLoad(b, formatJSON)
+}
+
+func BenchmarkLoadListingsCBOR(b *testing.B) {
+	benchmarkLoad(b, formatCBOR)
+}
+
+func benchmarkLoad(b *testing.B, format string) {
+	args := cliArgs{Format: format, OutputMode: outputModeListings}
+	data, err := marshalOutput(benchmarkListings(), &args, cborTestMetadata())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := loadOutput(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkStatsBlock calculates and marshals the stats block written with
+// --stats-file, which is JSON whatever the output format, for comparison with
+// the listings output benchmarks.
+func BenchmarkStatsBlock(b *testing.B) {
+	listings := benchmarkListings()
+	prices := listingPrices(listings)
+	args := reportArgs()
+	saved := log.Writer()
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(saved)
+
+	var size int
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out := statsOutput{Stats: calculatePriceStatsWith(prices, newStatsOptions(args))}
+		logListingStats(&out, listings, args)
+		data, err := marshalJSON(out, false)
+		if err != nil {
+			b.Fatal(err)
+		}
+		size = len(data)
+	}
+	b.ReportMetric(float64(size), "output-bytes")
+}
@@ -0,0 +1,158 @@
+package zoopla
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+)
+
+const (
+	chartWidth  = 8 * vg.Inch
+	chartHeight = 4 * vg.Inch
+)
+
+var (
+	meanLineColour   = color.RGBA{R: 0xd6, G: 0x27, B: 0x28, A: 0xff}
+	medianLineColour = color.RGBA{R: 0x2c, G: 0xa0, B: 0x2c, A: 0xff}
+	kdeLineColour    = color.RGBA{R: 0xff, G: 0x7f, B: 0x0e, A: 0xff}
+)
+
+// renderChart draws a PNG histogram of prices using the same buckets as
+// --histogram, with lines marking the mean and median and, with --kde, the
+// density curve scaled to listings per bucket.
+func renderChart(prices []uint64, args *cliArgs) ([]byte, error) {
+	if len(prices) == 0 {
+		return nil, errors.New("no prices to chart")
+	}
+
+	buckets := calculateHistogram(prices, args.BucketSize)
+	hist := &plotter.Histogram{FillColor: color.RGBA{R: 0x4a, G: 0x7e, B: 0xbb, A: 0xff}}
+	hist.LineStyle.Width = vg.Length(0.5)
+
+	maxCount := 0
+	for _, b := range buckets {
+		lo, hi := float64(b.Min), float64(b.Max)
+		if hi <= lo {
+			// Identical prices give a zero-width bucket which can't be
+			// drawn, so give it some width either side.
+			pad := math.Max(lo*0.01, 1)
+			lo, hi = lo-pad, hi+pad
+		}
+		hist.Bins = append(hist.Bins, plotter.HistogramBin{Min: lo, Max: hi, Weight: float64(b.Count)})
+		if b.Count > maxCount {
+			maxCount = b.Count
+		}
+	}
+	hist.Width = hist.Bins[0].Max - hist.Bins[0].Min
+
+	p := plot.New()
+	p.Title.Text = chartTitle(args)
+	p.X.Label.Text = "Price"
+	p.Y.Label.Text = "Listings"
+	p.X.Tick.Marker = poundTicks{}
+	p.Add(hist)
+
+	if args.KDE {
+		if kde := calculateKDE(prices, args.KDEBandwidth); kde != nil {
+			scale := float64(len(prices)) * hist.Width
+			xys := make(plotter.XYs, len(kde.Points))
+			for i, pt := range kde.Points {
+				xys[i] = plotter.XY{X: pt.Price, Y: pt.Density * scale}
+			}
+			line, err := plotter.NewLine(xys)
+			if err != nil {
+				return nil, errors.Wrap(err, "while drawing density curve")
+			}
+			line.Color = kdeLineColour
+			line.Width = vg.Points(1.5)
+			p.Add(line)
+			p.Legend.Add("density", line)
+		}
+	}
+
+	mean := calculateMean(prices)
+	median := calculateMedian(prices)
+	for _, m := range []struct {
+		name   string
+		value  float64
+		colour color.Color
+	}{
+		{"mean", mean, meanLineColour},
+		{"median", median, medianLineColour},
+	} {
+		line, err := plotter.NewLine(plotter.XYs{{X: m.value, Y: 0}, {X: m.value, Y: float64(maxCount)}})
+		if err != nil {
+			return nil, errors.Wrapf(err, "while drawing %s line", m.name)
+		}
+		line.Color = m.colour
+		line.Width = vg.Points(1.5)
+		line.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+		p.Add(line)
+		p.Legend.Add(fmt.Sprintf("%s %s", m.name, formatMeanPrice(m.value)), line)
+	}
+	p.Legend.Top = true
+
+	w, err := p.WriterTo(chartWidth, chartHeight, "png")
+	if err != nil {
+		return nil, errors.Wrap(err, "while rendering chart")
+	}
+
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		return nil, errors.Wrap(err, "while encoding chart")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func chartTitle(args *cliArgs) string {
+	if filters := filterSummary(args); filters != "" {
+		return "Prices in " + args.Postcode + ", " + filters
+	}
+
+	return "Prices in " + args.Postcode
+}
+
+// filterSummary describes the search filters in words, or is empty if there
+// are none.
+func filterSummary(args *cliArgs) string {
+	var parts []string
+	if args.PriceMin != nil {
+		parts = append(parts, "from "+formatPrice(*args.PriceMin))
+	}
+	if args.PriceMax != nil {
+		parts = append(parts, "up to "+formatPrice(*args.PriceMax))
+	}
+	if args.BedsMin != nil {
+		parts = append(parts, fmt.Sprintf("%d+ beds", *args.BedsMin))
+	}
+	if args.BedsMax != nil {
+		parts = append(parts, fmt.Sprintf("at most %d beds", *args.BedsMax))
+	}
+	if args.Radius > 0 {
+		parts = append(parts, fmt.Sprintf("within %d miles", args.Radius))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// poundTicks labels the default tick positions as prices.
+type poundTicks struct{}
+
+func (poundTicks) Ticks(min, max float64) []plot.Tick {
+	ticks := plot.DefaultTicks{}.Ticks(min, max)
+	for i := range ticks {
+		if ticks[i].Label != "" {
+			ticks[i].Label = formatMeanPrice(math.Max(ticks[i].Value, 0))
+		}
+	}
+
+	return ticks
+}
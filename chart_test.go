@@ -0,0 +1,80 @@
+package zoopla
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestRenderChart(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []uint64
+		args   cliArgs
+	}{
+		{"prices", []uint64{300000, 350000, 400000, 450000, 800000}, cliArgs{Postcode: "SW2"}},
+		{"single price", []uint64{450000}, cliArgs{Postcode: "SW2"}},
+		{"identical prices", []uint64{450000, 450000, 450000}, cliArgs{Postcode: "SW2"}},
+		{"bucket size", []uint64{300000, 350000, 400000}, cliArgs{Postcode: "SW2", BucketSize: 25000}},
+		{"density", []uint64{300000, 350000, 400000, 450000, 800000}, cliArgs{Postcode: "SW2", KDE: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := renderChart(tt.prices, &tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := png.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("chart isn't a PNG: %v", err)
+			}
+			// gonum renders at 96 dpi by default.
+			if wantW, wantH := int(chartWidth.Dots(96)), int(chartHeight.Dots(96)); cfg.Width != wantW || cfg.Height != wantH {
+				t.Errorf("chart is %dx%d, want %dx%d", cfg.Width, cfg.Height, wantW, wantH)
+			}
+		})
+	}
+}
+
+func TestRenderChartNoPrices(t *testing.T) {
+	if _, err := renderChart(nil, &cliArgs{}); err == nil {
+		t.Error("renderChart(nil) succeeded, want an error")
+	}
+}
+
+func TestChartTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		args cliArgs
+		want string
+	}{
+		{"no filters", cliArgs{Postcode: "SW2"}, "Prices in SW2"},
+		{"price range", cliArgs{Postcode: "SW2", PriceMin: uint64Ptr(300000), PriceMax: uint64Ptr(500000)}, "Prices in SW2, from £300,000, up to £500,000"},
+		{"beds", cliArgs{Postcode: "SW2", BedsMin: uint32Ptr(2), BedsMax: uint32Ptr(3)}, "Prices in SW2, 2+ beds, at most 3 beds"},
+		{"radius", cliArgs{Postcode: "SW2", Radius: 1}, "Prices in SW2, within 1 miles"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chartTitle(&tt.args); got != tt.want {
+				t.Errorf("chartTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPoundTicks(t *testing.T) {
+	for _, tick := range (poundTicks{}).Ticks(-50000, 1000000) {
+		if tick.Label == "" {
+			continue
+		}
+		// Ticks below zero are labelled as zero rather than a negative
+		// price.
+		if !strings.HasPrefix(tick.Label, "£") {
+			t.Errorf("tick label %q isn't a price", tick.Label)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+// Package zoopla searches Zoopla for property listings and calculates
+// statistics on their prices. The zoopla-analyzer command in
+// cmd/zoopla-analyzer is a command line tool built on it.
+package zoopla
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Listing is a property listing found by a search. Fields are left empty
+// when the card doesn't show them.
+type Listing struct {
+	ID       string `json:"id,omitempty"`
+	Price    uint64 `json:"price"`
+	Status   string `json:"status"`
+	Agent    string `json:"agent,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Category string `json:"category"`
+	// SearchPostcode is the postcode searched under, set only when a query
+	// covers more than one.
+	SearchPostcode string `json:"search_postcode,omitempty"`
+	// PropertyType is derived from the title, such as "flat" or
+	// "semi_detached".
+	PropertyType string `json:"property_type,omitempty"`
+	Address      string `json:"address,omitempty"`
+	// Location is only known when the page embeds structured data.
+	Location *Coordinates `json:"location,omitempty"`
+
+	// Outcode and Sector are derived from the postcode at the end of the
+	// address, such as "SW2" and "SW2 1".
+	Outcode string `json:"outcode,omitempty"`
+	Sector  string `json:"sector,omitempty"`
+
+	// Qualifier is the normalised price qualifier, such as "guide" or
+	// "offers_over", and is empty for a plain asking price.
+	Qualifier  string `json:"qualifier,omitempty"`
+	IsAuction  bool   `json:"is_auction"`
+	IsReduced  bool   `json:"is_reduced"`
+	IsNewBuild bool   `json:"is_new_build"`
+
+	ListedOn  *time.Time `json:"listed_on,omitempty"`
+	ReducedOn *time.Time `json:"reduced_on,omitempty"`
+
+	Stations   []Station `json:"stations,omitempty"`
+	Beds       *uint32   `json:"beds,omitempty"`
+	Baths      *uint32   `json:"baths,omitempty"`
+	Receptions *uint32   `json:"receptions,omitempty"`
+
+	// FloorArea is in square feet. FloorAreaSource records whether it came
+	// from the card or the detail page.
+	FloorArea       *uint32 `json:"floor_area_sqft,omitempty"`
+	FloorAreaSource string  `json:"floor_area_source,omitempty"`
+
+	// Fields below are only populated with Query.FetchDetails.
+	HasFloorplan bool         `json:"has_floorplan"`
+	Tenure       string       `json:"tenure,omitempty"`
+	EPCBand      string       `json:"epc_band,omitempty"`
+	Description  string       `json:"description,omitempty"`
+	PriceHistory []PricePoint `json:"price_history,omitempty"`
+
+	// SharePercent is the share on offer for shared-ownership listings,
+	// whose price only covers that share.
+	SharedOwnership bool     `json:"shared_ownership"`
+	SharePercent    *float64 `json:"share_percent,omitempty"`
+}
+
+// Coordinates are a listing's latitude and longitude.
+type Coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// Station is a station listed near a property.
+type Station struct {
+	Name          string  `json:"name"`
+	DistanceMiles float64 `json:"distance_miles"`
+}
+
+// PricePoint is an entry in a listing's price history. Date is nil when
+// the page doesn't give one.
+type PricePoint struct {
+	Date  *time.Time `json:"date,omitempty"`
+	Price uint64     `json:"price"`
+}
+
+// Client searches Zoopla. The zero value is ready to use, with the same
+// defaults as the command line tool. A Client keeps its requests spaced by
+// Delay, including between concurrent searches, so it should be shared.
+type Client struct {
+	// HTTPClient makes the requests, or http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// BaseURL is the search page the postcode is appended to. It defaults
+	// to Zoopla's for-sale search.
+	BaseURL string
+	// UserAgent is sent with each request if set.
+	UserAgent string
+	// Delay is the least time between requests. It defaults to one second;
+	// set it negative for no delay.
+	Delay time.Duration
+	// Retries is how many times a failed request is retried, defaulting to
+	// two; set it negative for none.
+	Retries int
+
+	once sync.Once
+	f    *fetcher
+}
+
+// Query is what to search for, mirroring the command line tool's search
+// flags. Nil bounds are unbounded.
+type Query struct {
+	// Postcode is one postcode or several separated by commas, each
+	// searched in turn.
+	Postcode string
+	PriceMin *uint64
+	PriceMax *uint64
+	BedsMin  *uint32
+	BedsMax  *uint32
+	// Radius is in miles.
+	Radius                 uint32
+	IncludeSharedOwnership bool
+	// IncludeCategory adds listing categories other than residential:
+	// "land", "parking" or "commercial".
+	IncludeCategory []string
+	// Parser forces a page parser by name rather than choosing one for
+	// each page.
+	Parser string
+	// FetchDetails fetches each listing's page for details missing from
+	// the search results, using DetailWorkers at a time.
+	FetchDetails  bool
+	DetailWorkers int
+	DedupeFuzzy   bool
+}
+
+// PriceStats summarises a set of prices. Percentiles are keyed by name,
+// such as "p25".
+type PriceStats struct {
+	Count       int                `json:"count"`
+	Min         uint64             `json:"min"`
+	Max         uint64             `json:"max"`
+	Mean        float64            `json:"mean"`
+	Median      float64            `json:"median"`
+	Stddev      float64            `json:"stddev"`
+	StdErr      float64            `json:"stderr"`
+	Percentiles map[string]float64 `json:"percentiles,omitempty"`
+}
+
+func (c *Client) fetcher() *fetcher {
+	c.once.Do(func() {
+		c.f = &fetcher{client: c.HTTPClient, delay: c.Delay, retries: c.Retries, backoff: defaultBackoff, userAgent: c.UserAgent}
+		if c.f.client == nil {
+			c.f.client = http.DefaultClient
+		}
+		switch {
+		case c.f.delay == 0:
+			c.f.delay = defaultDelay
+		case c.f.delay < 0:
+			c.f.delay = 0
+		}
+		switch {
+		case c.f.retries == 0:
+			c.f.retries = defaultRetries
+		case c.f.retries < 0:
+			c.f.retries = 0
+		}
+	})
+
+	return c.f
+}
+
+func (q Query) cliArgs(base string) cliArgs {
+	args := cliArgs{
+		Postcode:               q.Postcode,
+		PriceMin:               q.PriceMin,
+		PriceMax:               q.PriceMax,
+		BedsMin:                q.BedsMin,
+		BedsMax:                q.BedsMax,
+		Radius:                 q.Radius,
+		IncludeSharedOwnership: q.IncludeSharedOwnership,
+		IncludeCategory:        q.IncludeCategory,
+		Parser:                 q.Parser,
+		FetchDetails:           q.FetchDetails,
+		DetailWorkers:          q.DetailWorkers,
+		DedupeFuzzy:            q.DedupeFuzzy,
+		BaseURL:                base,
+	}
+	if args.BaseURL == "" {
+		args.BaseURL = baseURL
+	}
+	if args.DetailWorkers <= 0 {
+		args.DetailWorkers = defaultDetailWorkers
+	}
+
+	return args
+}
+
+// SearchPrices returns the priced listings matching q, de-duplicated the
+// same way as the command line tool's output. Coming soon listings are left
+// out.
+func (c *Client) SearchPrices(ctx context.Context, q Query) ([]Listing, error) {
+	args := q.cliArgs(c.BaseURL)
+	postcodes := splitPostcodes(args.Postcode)
+	if len(postcodes) == 0 {
+		return nil, errors.New("no postcode given")
+	}
+	if args.Parser != "" && args.Parser != streamingParserName && lookupParser(args.Parser) == nil {
+		return nil, errors.Errorf("unknown parser %q", args.Parser)
+	}
+
+	f := c.fetcher()
+	listings, err := getQueryListings(ctx, f, newDiagnostics(&args), &args, postcodes, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	listings = filterCategories(listings, args.IncludeCategory)
+	listings, _ = splitComingSoon(listings)
+	if args.FetchDetails {
+		fetchAllDetails(ctx, f, listings, args.DetailWorkers)
+	}
+	assignPostcodes(listings)
+	assignIdentity(listings)
+
+	listings = dedupeByID(listings)
+	if args.DedupeFuzzy {
+		listings = dedupeFuzzy(listings)
+	}
+
+	found := make([]Listing, len(listings))
+	for i := range listings {
+		found[i] = newListing(&listings[i])
+	}
+
+	return found, nil
+}
+
+func newListing(l *listing) Listing {
+	out := Listing{
+		ID:              l.ID,
+		Price:           l.Price,
+		Status:          l.Status,
+		Agent:           l.Agent,
+		URL:             l.URL,
+		Title:           l.Title,
+		Category:        l.Category,
+		SearchPostcode:  l.SearchPostcode,
+		PropertyType:    l.PropertyType,
+		Address:         l.Address,
+		Outcode:         l.Outcode,
+		Sector:          l.Sector,
+		Qualifier:       l.Qualifier,
+		IsAuction:       l.IsAuction,
+		IsReduced:       l.IsReduced,
+		IsNewBuild:      l.IsNewBuild,
+		ListedOn:        l.ListedOn,
+		ReducedOn:       l.ReducedOn,
+		Beds:            l.Beds,
+		Baths:           l.Baths,
+		Receptions:      l.Receptions,
+		FloorArea:       l.FloorArea,
+		FloorAreaSource: l.FloorAreaSource,
+		HasFloorplan:    l.HasFloorplan,
+		Tenure:          l.Tenure,
+		EPCBand:         l.EPCBand,
+		Description:     l.Description,
+		SharedOwnership: l.SharedOwnership,
+		SharePercent:    l.SharePercent,
+	}
+	if l.Location != nil {
+		out.Location = &Coordinates{Latitude: l.Location.Latitude, Longitude: l.Location.Longitude}
+	}
+	for _, s := range l.Stations {
+		out.Stations = append(out.Stations, Station{Name: s.Name, DistanceMiles: s.DistanceMiles})
+	}
+	for _, p := range l.PriceHistory {
+		out.PriceHistory = append(out.PriceHistory, PricePoint{Date: p.Date, Price: p.Price})
+	}
+
+	return out
+}
+
+// Stats calculates the stats of prices, such as those of the listings
+// returned by SearchPrices. They're all zero for no prices.
+func (c *Client) Stats(prices []uint64) PriceStats {
+	if len(prices) == 0 {
+		return PriceStats{}
+	}
+
+	s := calculatePriceStats(prices)
+	ps := PriceStats{
+		Count:  s.count,
+		Min:    s.min,
+		Max:    s.max,
+		Mean:   s.mean,
+		Median: s.median,
+		Stddev: s.stddev,
+		StdErr: s.stderr,
+	}
+	if len(s.percentiles) > 0 {
+		ps.Percentiles = make(map[string]float64, len(s.percentiles))
+		for _, p := range s.percentiles {
+			ps.Percentiles[p.name()] = p.value
+		}
+	}
+
+	return ps
+}
+
+// Prices returns the listings' prices, in the same order.
+func Prices(listings []Listing) []uint64 {
+	prices := make([]uint64, len(listings))
+	for i := range listings {
+		prices[i] = listings[i].Price
+	}
+
+	return prices
+}
@@ -0,0 +1,205 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryanc414/zoopla-analyzer/internal/testserver"
+)
+
+// clientPage has a listing repeated, one coming soon and a plot of land.
+const clientPage = `<html><body><div class="ListingsContainer">` +
+	`<div data-testid="search-result"><h2>2 bed flat for sale</h2><div class="PriceContainer"><p>£450,000</p></div><a href="/for-sale/details/1/">Details</a></div>` +
+	`<div data-testid="search-result"><h2>3 bed terraced house for sale</h2><div class="PriceContainer"><p>£700,000</p></div><a href="/for-sale/details/2/">Details</a></div>` +
+	`<div data-testid="search-result"><h2>2 bed flat for sale</h2><div class="PriceContainer"><p>£450,000</p></div><a href="/for-sale/details/1/">Details</a></div>` +
+	`<div data-testid="search-result"><h2>1 bed flat for sale</h2><p class="Badge">Coming soon</p><a href="/for-sale/details/3/">Details</a></div>` +
+	`<div data-testid="search-result"><h2>Plot of land for sale</h2><div class="PriceContainer"><p>£90,000</p></div><a href="/for-sale/details/4/">Details</a></div>` +
+	`</div></body></html>`
+
+func testClient(srv *testserver.Server) *Client {
+	return &Client{HTTPClient: srv.Client(), BaseURL: srv.SearchURL(), Delay: -1}
+}
+
+func TestClientSearchPrices(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, clientPage)
+
+	tests := []struct {
+		name       string
+		query      Query
+		wantPrices []uint64
+	}{
+		{"residential", Query{Postcode: "sw2"}, []uint64{450000, 700000}},
+		{"land included", Query{Postcode: "sw2", IncludeCategory: []string{categoryLand}}, []uint64{450000, 700000, 90000}},
+		{"streaming parser", Query{Postcode: "sw2", Parser: streamingParserName}, []uint64{450000, 700000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listings, err := testClient(srv).SearchPrices(context.Background(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Prices(listings); !equalPrices(got, tt.wantPrices) {
+				t.Errorf("got prices %v, want %v", got, tt.wantPrices)
+			}
+		})
+	}
+}
+
+func TestClientSearchPricesFields(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, clientPage)
+
+	listings, err := testClient(srv).SearchPrices(context.Background(), Query{Postcode: "sw2,sw9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The test server gives both postcodes the same listings, which are
+	// kept once each.
+	if len(listings) != 2 {
+		t.Fatalf("got %d listings, want 2", len(listings))
+	}
+	l := listings[0]
+	if l.ID != "1" || l.Price != 450000 || l.Title != "2 bed flat for sale" || l.PropertyType != "flat" ||
+		derefUint32(l.Beds) != uint32(2) || l.Category != categoryResidential || l.SearchPostcode != "sw2" {
+		t.Errorf("got %+v", l)
+	}
+	if !strings.HasSuffix(l.URL, "/for-sale/details/1/") {
+		t.Errorf("got URL %q", l.URL)
+	}
+}
+
+func TestClientSearchPricesErrors(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		query   Query
+		wantErr string
+	}{
+		{"no postcode", Query{}, "no postcode given"},
+		{"blank postcodes", Query{Postcode: " , "}, "no postcode given"},
+		{"unknown parser", Query{Postcode: "sw2", Parser: "regex"}, `unknown parser "regex"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := testClient(srv).SearchPrices(context.Background(), tt.query)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("got %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("made %d requests for invalid queries", n)
+	}
+}
+
+func TestClientSearchPricesCancelled(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, clientPage)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := testClient(srv).SearchPrices(ctx, Query{Postcode: "sw2"}); err == nil {
+		t.Error("got no error from a cancelled search")
+	}
+}
+
+func TestClientFetcherDefaults(t *testing.T) {
+	tests := []struct {
+		name        string
+		c           *Client
+		wantDelay   time.Duration
+		wantRetries int
+	}{
+		{"zero value", &Client{}, defaultDelay, defaultRetries},
+		{"set", &Client{Delay: time.Minute, Retries: 5}, time.Minute, 5},
+		{"none", &Client{Delay: -1, Retries: -1}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.c.fetcher()
+			if f.delay != tt.wantDelay || f.retries != tt.wantRetries || f.client == nil {
+				t.Errorf("got delay %v, %d retries, client %v", f.delay, f.retries, f.client)
+			}
+			if tt.c.fetcher() != f {
+				t.Error("got a new fetcher on the second call, which wouldn't keep to the delay")
+			}
+		})
+	}
+}
+
+func TestQueryCliArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		q           Query
+		base        string
+		wantBase    string
+		wantWorkers int
+	}{
+		{"defaults", Query{Postcode: "sw2"}, "", baseURL, defaultDetailWorkers},
+		{"set", Query{Postcode: "sw2", DetailWorkers: 8}, "http://localhost/for-sale", "http://localhost/for-sale", 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.q.cliArgs(tt.base)
+			if args.BaseURL != tt.wantBase || args.DetailWorkers != tt.wantWorkers || args.Postcode != tt.q.Postcode {
+				t.Errorf("got %+v", args)
+			}
+		})
+	}
+}
+
+func TestNewListing(t *testing.T) {
+	l := fullListing()
+	got, err := json.Marshal(newListing(&l))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the fields set by command line flags are left out.
+	l.StampDuty, l.TotalCost, l.ValueScore, l.Flags = nil, nil, nil, nil
+	want, err := json.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	if nl := newListing(&listing{Price: 1}); nl.Location != nil || nl.Stations != nil || nl.PriceHistory != nil {
+		t.Errorf("got %+v, want nil location, stations and history", nl)
+	}
+}
+
+func TestClientStats(t *testing.T) {
+	var c Client
+	s := c.Stats([]uint64{400000, 500000, 600000})
+
+	if s.Count != 3 || s.Min != 400000 || s.Max != 600000 || s.Mean != 500000 || s.Median != 500000 {
+		t.Errorf("got %+v", s)
+	}
+	if !closeTo(s.Stddev, 100000) {
+		t.Errorf("got stddev %v", s.Stddev)
+	}
+	if len(s.Percentiles) == 0 {
+		t.Error("got no percentiles")
+	}
+
+	if empty := c.Stats(nil); !reflect.DeepEqual(empty, PriceStats{}) {
+		t.Errorf("got %+v for no prices", empty)
+	}
+}
@@ -0,0 +1,14 @@
+// Command zoopla-analyzer searches Zoopla for property listings and reports
+// statistics on their prices.
+package main
+
+import (
+	"context"
+	"os"
+
+	zoopla "github.com/ryanc414/zoopla-analyzer"
+)
+
+func main() {
+	os.Exit(zoopla.Main(context.Background()))
+}
@@ -0,0 +1,92 @@
+package zoopla
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+)
+
+// ANSI SGR colour codes. Each is the same length so that tabwriter columns
+// whose cells are all painted stay aligned.
+const (
+	colorDefault = "39"
+	colorRed     = "31"
+	colorGreen   = "32"
+	colorYellow  = "33"
+)
+
+// painter wraps text in ANSI colour codes when true and leaves it alone
+// otherwise.
+type painter bool
+
+func (p painter) paint(color, s string) string {
+	if !p {
+		return s
+	}
+
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
+
+// paintPrice colours price green below p25 and red above p75.
+func (p painter) paintPrice(price, p25, p75 float64, s string) string {
+	switch {
+	case price < p25:
+		return p.paint(colorGreen, s)
+	case price > p75:
+		return p.paint(colorRed, s)
+	default:
+		return p.paint(colorDefault, s)
+	}
+}
+
+// shouldColor reports whether to colour output to a terminal, honouring
+// --no-color and the NO_COLOR convention (https://no-color.org).
+func shouldColor(noColor bool, lookupEnv func(string) (string, bool), tty bool) bool {
+	if noColor || !tty {
+		return false
+	}
+	if v, ok := lookupEnv("NO_COLOR"); ok && v != "" {
+		return false
+	}
+
+	return true
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func newPainter(f *os.File, noColor bool) painter {
+	return painter(shouldColor(noColor, os.LookupEnv, isTerminal(f)))
+}
+
+// logPainter colours the log output on stderr.
+var logPainter painter
+
+// setupLogColor turns on colouring of the log when stderr is a terminal.
+func setupLogColor(noColor bool) {
+	logPainter = newPainter(os.Stderr, noColor)
+	if logPainter {
+		log.SetOutput(warningWriter{os.Stderr})
+	}
+}
+
+// warningWriter colours log lines that carry a warning yellow.
+type warningWriter struct {
+	w io.Writer
+}
+
+func (w warningWriter) Write(p []byte) (int, error) {
+	if !bytes.Contains(p, []byte("warning: ")) {
+		return w.w.Write(p)
+	}
+
+	line := bytes.TrimSuffix(p, []byte("\n"))
+	if _, err := io.WriteString(w.w, logPainter.paint(colorYellow, string(line))+"\n"); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
@@ -0,0 +1,121 @@
+package zoopla
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPaint(t *testing.T) {
+	tests := []struct {
+		name string
+		p    painter
+		want string
+	}{
+		{"off", false, "text"},
+		{"on", true, "\x1b[31mtext\x1b[0m"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.paint(colorRed, "text"); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaintPrice(t *testing.T) {
+	tests := []struct {
+		name  string
+		price float64
+		color string
+	}{
+		{"below p25", 99, colorGreen},
+		{"at p25", 100, colorDefault},
+		{"between", 150, colorDefault},
+		{"at p75", 200, colorDefault},
+		{"above p75", 201, colorRed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := "\x1b[" + tt.color + "m£x\x1b[0m"
+			if got := painter(true).paintPrice(tt.price, 100, 200, "£x"); got != want {
+				t.Errorf("got %q, want %q", got, want)
+			}
+			if got := painter(false).paintPrice(tt.price, 100, 200, "£x"); got != "£x" {
+				t.Errorf("got %q without colour", got)
+			}
+		})
+	}
+}
+
+func TestShouldColor(t *testing.T) {
+	tests := []struct {
+		name    string
+		noColor bool
+		env     map[string]string
+		tty     bool
+		want    bool
+	}{
+		{name: "terminal", tty: true, want: true},
+		{name: "not a terminal", tty: false},
+		{name: "no color flag", noColor: true, tty: true},
+		{name: "NO_COLOR set", env: map[string]string{"NO_COLOR": "1"}, tty: true},
+		{name: "NO_COLOR empty", env: map[string]string{"NO_COLOR": ""}, tty: true, want: true},
+		{name: "other env", env: map[string]string{"TERM": "xterm"}, tty: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			}
+			if got := shouldColor(tt.noColor, lookup, tt.tty); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestColorCodesSameLength(t *testing.T) {
+	for _, c := range []string{colorRed, colorGreen, colorYellow} {
+		if len(c) != len(colorDefault) {
+			t.Errorf("colour %q is not as long as the default %q, which would misalign tables", c, colorDefault)
+		}
+	}
+}
+
+func TestWarningWriter(t *testing.T) {
+	saved := logPainter
+	defer func() { logPainter = saved }()
+
+	tests := []struct {
+		name    string
+		painter painter
+		line    string
+		want    string
+	}{
+		{"plain line", true, "got 3 prices\n", "got 3 prices\n"},
+		{"warning", true, "2021/03/01 warning: no prices\n", "\x1b[33m2021/03/01 warning: no prices\x1b[0m\n"},
+		{"warning without colour", false, "warning: no prices\n", "warning: no prices\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logPainter = tt.painter
+			var buf bytes.Buffer
+			n, err := warningWriter{&buf}.Write([]byte(tt.line))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n != len(tt.line) {
+				t.Errorf("wrote %d bytes, want %d", n, len(tt.line))
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
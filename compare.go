@@ -0,0 +1,292 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/pkg/errors"
+)
+
+// areaSummary is one postcode's column in a comparison.
+type areaSummary struct {
+	postcode string
+	count    int
+	mean     float64
+	median   float64
+	p25      float64
+	p75      float64
+	// pricePerBed is the median, and nil if no listing's bedrooms were
+	// known.
+	pricePerBed  *float64
+	reducedShare float64
+}
+
+type areaComparison struct {
+	areas []areaSummary
+}
+
+// runCompare searches each --postcode with the same filters and compares
+// them side by side.
+func runCompare(ctx context.Context, argv []string) error {
+	args, err := parseSearchArgs(func(cli *cliArgs) (*arg.Parser, error) {
+		return parseSubcommand("compare", cli, joinRepeatedFlag(argv, "--postcode"))
+	})
+	if err != nil {
+		return err
+	}
+	postcodes := splitPostcodes(args.Postcode)
+	if len(postcodes) < 2 {
+		return errors.New("compare needs at least two postcodes")
+	}
+
+	f := newFetcher(&args)
+	diag := newDiagnostics(&args)
+	listings, err := getQueryListings(ctx, f, diag, &args, postcodes, nil)
+	if diagErr := diag.write(); diagErr != nil {
+		log.Print(diagErr)
+	}
+	if err != nil {
+		return err
+	}
+
+	listings = filterCategories(listings, args.IncludeCategory)
+	listings, _ = splitComingSoon(listings)
+	if args.FetchDetails {
+		fetchAllDetails(ctx, f, listings, args.DetailWorkers)
+	}
+	assignPostcodes(listings)
+	assignIdentity(listings)
+	if !args.IncludeAuctionsInStats {
+		listings, _ = splitAuctions(listings)
+	}
+
+	c := compareAreas(postcodes, listings)
+	fmt.Print(renderComparison(c, newPainter(os.Stdout, args.NoColor)))
+
+	if args.StatsFile != "" {
+		data, err := marshalJSON(struct {
+			Timestamp string         `json:"timestamp"`
+			Query     runQuery       `json:"query"`
+			Areas     areaComparison `json:"areas"`
+		}{timeNow().UTC().Format(time.RFC3339), newRunQuery(&args), c}, args.Pretty)
+		if err != nil {
+			return errors.Wrap(err, "while marshalling comparison")
+		}
+		if err := writeFileAtomic(args.StatsFile, data); err != nil {
+			return err
+		}
+		log.Print("wrote comparison to ", args.StatsFile)
+	}
+
+	return nil
+}
+
+// joinRepeatedFlag merges each "--flag value" or "--flag=value" in argv
+// into a single comma-separated flag, in place of the first.
+func joinRepeatedFlag(argv []string, flag string) []string {
+	var values, rest []string
+	first := -1
+	for i := 0; i < len(argv); i++ {
+		a := argv[i]
+		switch {
+		case a == flag && i+1 < len(argv):
+			values = append(values, argv[i+1])
+			i++
+		case strings.HasPrefix(a, flag+"="):
+			values = append(values, strings.TrimPrefix(a, flag+"="))
+		default:
+			rest = append(rest, a)
+			continue
+		}
+		if first < 0 {
+			first = len(rest)
+		}
+	}
+
+	if first < 0 {
+		return argv
+	}
+
+	joined := append([]string{}, rest[:first]...)
+	joined = append(joined, flag, strings.Join(values, ","))
+	return append(joined, rest[first:]...)
+}
+
+// compareAreas summarises the listings found under each postcode, each
+// de-duplicated on its own so that a listing in both areas counts for both.
+func compareAreas(postcodes []string, listings []listing) areaComparison {
+	var c areaComparison
+	for _, pc := range postcodes {
+		var area []listing
+		for i := range listings {
+			if listings[i].SearchPostcode == pc {
+				area = append(area, listings[i])
+			}
+		}
+		c.areas = append(c.areas, summariseArea(pc, dedupeByID(area)))
+	}
+
+	return c
+}
+
+func summariseArea(pc string, listings []listing) areaSummary {
+	s := areaSummary{postcode: pc, count: len(listings)}
+	if len(listings) == 0 {
+		return s
+	}
+
+	prices := listingPrices(listings)
+	sorted := make([]uint64, len(prices))
+	copy(sorted, prices)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	s.mean = calculateMean(sorted)
+	s.median = calculateMedian(sorted)
+	s.p25 = quantile(sorted, 0.25)
+	s.p75 = quantile(sorted, 0.75)
+	if perBed := calculatePricePerBedStats(listings); perBed.all.Count > 0 {
+		s.pricePerBed = &perBed.all.Median
+	}
+	s.reducedShare = calculateMarketSoftness(listings).reducedShare()
+
+	return s
+}
+
+type comparisonMetric struct {
+	name  string
+	value func(a areaSummary) (float64, bool)
+	// price metrics are formatted as prices, with rises red and falls
+	// green; the rest are counts or percentages.
+	price   bool
+	percent bool
+}
+
+var comparisonMetrics = []comparisonMetric{
+	{name: "count", value: func(a areaSummary) (float64, bool) { return float64(a.count), true }},
+	{name: "median", price: true, value: func(a areaSummary) (float64, bool) { return a.median, a.count > 0 }},
+	{name: "mean", price: true, value: func(a areaSummary) (float64, bool) { return a.mean, a.count > 0 }},
+	{name: "p25", price: true, value: func(a areaSummary) (float64, bool) { return a.p25, a.count > 0 }},
+	{name: "p75", price: true, value: func(a areaSummary) (float64, bool) { return a.p75, a.count > 0 }},
+	{name: "price per bed", price: true, value: func(a areaSummary) (float64, bool) {
+		if a.pricePerBed == nil {
+			return 0, false
+		}
+		return *a.pricePerBed, true
+	}},
+	{name: "reduced", percent: true, value: func(a areaSummary) (float64, bool) { return a.reducedShare, a.count > 0 }},
+}
+
+func (m comparisonMetric) format(v float64) string {
+	switch {
+	case m.price:
+		return formatMeanPrice(v)
+	case m.percent:
+		return fmt.Sprintf("%.1f%%", v)
+	default:
+		return fmt.Sprintf("%.0f", v)
+	}
+}
+
+// formatDelta shows the change from base to v, with prices also as a
+// percentage and percentages in points.
+func (m comparisonMetric) formatDelta(base, v float64, p painter) string {
+	d := v - base
+	var s string
+	switch {
+	case m.price:
+		sign := "+"
+		if d < 0 {
+			sign = "-"
+		}
+		s = fmt.Sprintf("%s%s (%+.1f%%)", sign, formatMeanPrice(math.Abs(d)), percentChange(base, v))
+	case m.percent:
+		s = fmt.Sprintf("%+.1fpp", d)
+	default:
+		s = fmt.Sprintf("%+.0f", d)
+	}
+
+	switch {
+	case d == 0:
+		return p.paint(colorDefault, s)
+	case !m.price:
+		return p.paint(colorYellow, s)
+	case d > 0:
+		return p.paint(colorRed, s)
+	default:
+		return p.paint(colorGreen, s)
+	}
+}
+
+// renderComparison lays the areas out in columns, each after the first
+// followed by its difference from the first.
+func renderComparison(c areaComparison, p painter) string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+
+	header := []string{""}
+	for i, a := range c.areas {
+		header = append(header, a.postcode)
+		if i > 0 {
+			// Painted like the deltas below, to keep the column aligned.
+			header = append(header, p.paint(colorDefault, a.postcode+" vs "+c.areas[0].postcode))
+		}
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t")+"\t")
+
+	for _, m := range comparisonMetrics {
+		row := []string{m.name}
+		base, baseOK := m.value(c.areas[0])
+		for i, a := range c.areas {
+			v, ok := m.value(a)
+			cell := "-"
+			if ok {
+				cell = m.format(v)
+			}
+			row = append(row, cell)
+
+			if i == 0 {
+				continue
+			}
+			delta := p.paint(colorDefault, "-")
+			if ok && baseOK {
+				delta = m.formatDelta(base, v, p)
+			}
+			row = append(row, delta)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t")+"\t")
+	}
+
+	tw.Flush()
+	return b.String()
+}
+
+func (a areaSummary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Count        int      `json:"count"`
+		Mean         float64  `json:"mean"`
+		Median       float64  `json:"median"`
+		P25          float64  `json:"p25"`
+		P75          float64  `json:"p75"`
+		PricePerBed  *float64 `json:"price_per_bed,omitempty"`
+		ReducedShare float64  `json:"reduced_share"`
+	}{a.count, a.mean, a.median, a.p25, a.p75, a.pricePerBed, a.reducedShare})
+}
+
+// MarshalJSON keys the areas by postcode.
+func (c areaComparison) MarshalJSON() ([]byte, error) {
+	areas := make(map[string]areaSummary, len(c.areas))
+	for _, a := range c.areas {
+		areas[a.postcode] = a
+	}
+
+	return json.Marshal(areas)
+}
@@ -0,0 +1,209 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ryanc414/zoopla-analyzer/internal/testserver"
+)
+
+func TestJoinRepeatedFlag(t *testing.T) {
+	tests := []struct {
+		name string
+		argv []string
+		want []string
+	}{
+		{"none", []string{"--quiet"}, []string{"--quiet"}},
+		{"once", []string{"--postcode", "sw2", "--quiet"}, []string{"--postcode", "sw2", "--quiet"}},
+		{
+			name: "repeated",
+			argv: []string{"--quiet", "--postcode", "sw2", "--radius", "1", "--postcode=sw9", "--postcode", "se1"},
+			want: []string{"--quiet", "--postcode", "sw2,sw9,se1", "--radius", "1"},
+		},
+		{"already joined", []string{"--postcode", "sw2,sw9"}, []string{"--postcode", "sw2,sw9"}},
+		// A trailing flag without a value is left for the parser to reject.
+		{"missing value", []string{"--quiet", "--postcode"}, []string{"--quiet", "--postcode"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := joinRepeatedFlag(tt.argv, "--postcode"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// comparisonListings has a listing found under both postcodes, and one
+// repeated under SW9.
+func comparisonListings() []listing {
+	return []listing{
+		{ID: "a", SearchPostcode: "SW2", Price: 400000, Beds: uint32Ptr(2), IsReduced: true},
+		{ID: "b", SearchPostcode: "SW2", Price: 500000, Beds: uint32Ptr(2)},
+		{ID: "c", SearchPostcode: "SW2", Price: 600000},
+		{ID: "c", SearchPostcode: "SW9", Price: 600000},
+		{ID: "d", SearchPostcode: "SW9", Price: 300000},
+		{ID: "d", SearchPostcode: "SW9", Price: 300000},
+	}
+}
+
+func TestCompareAreas(t *testing.T) {
+	c := compareAreas([]string{"SW2", "SW9", "SE1"}, comparisonListings())
+
+	perBed := 225000.0
+	want := []areaSummary{
+		{postcode: "SW2", count: 3, mean: 500000, median: 500000, p25: 450000, p75: 550000, pricePerBed: &perBed, reducedShare: 100.0 / 3},
+		{postcode: "SW9", count: 2, mean: 450000, median: 450000, p25: 375000, p75: 525000},
+		{postcode: "SE1"},
+	}
+	if len(c.areas) != len(want) {
+		t.Fatalf("got %d areas, want %d", len(c.areas), len(want))
+	}
+	for i, w := range want {
+		a := c.areas[i]
+		if a.postcode != w.postcode || a.count != w.count || a.mean != w.mean || a.median != w.median ||
+			a.p25 != w.p25 || a.p75 != w.p75 || !closeTo(a.reducedShare, w.reducedShare) {
+			t.Errorf("area %d: got %+v, want %+v", i, a, w)
+		}
+		if (a.pricePerBed == nil) != (w.pricePerBed == nil) || (a.pricePerBed != nil && *a.pricePerBed != *w.pricePerBed) {
+			t.Errorf("area %d: got price per bed %v, want %v", i, derefFloat64(a.pricePerBed), derefFloat64(w.pricePerBed))
+		}
+	}
+}
+
+func TestComparisonMetricFormatDelta(t *testing.T) {
+	count, median, reduced := comparisonMetrics[0], comparisonMetrics[1], comparisonMetrics[6]
+
+	tests := []struct {
+		name    string
+		m       comparisonMetric
+		base, v float64
+		want    string
+		color   string
+	}{
+		{"price rise", median, 400000, 500000, "+£100,000 (+25.0%)", colorRed},
+		{"price fall", median, 500000, 400000, "-£100,000 (-20.0%)", colorGreen},
+		{"price unchanged", median, 500000, 500000, "+£0 (+0.0%)", colorDefault},
+		{"count", count, 3, 5, "+2", colorYellow},
+		{"count fall", count, 5, 3, "-2", colorYellow},
+		{"percent", reduced, 25, 12.5, "-12.5pp", colorYellow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.formatDelta(tt.base, tt.v, painter(false)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if got, want := tt.m.formatDelta(tt.base, tt.v, painter(true)), painter(true).paint(tt.color, tt.want); got != want {
+				t.Errorf("painted %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestComparisonMetricFormat(t *testing.T) {
+	tests := []struct {
+		m    comparisonMetric
+		v    float64
+		want string
+	}{
+		{comparisonMetrics[0], 3, "3"},
+		{comparisonMetrics[1], 450000.4, "£450,000"},
+		{comparisonMetrics[6], 100.0 / 3, "33.3%"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.m.format(tt.v); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.m.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderComparison(t *testing.T) {
+	c := compareAreas([]string{"SW2", "SW9", "SE1"}, comparisonListings())
+	got := renderComparison(c, painter(false))
+
+	want := []string{
+		"               SW2       SW9       SW9 vs SW2         SE1  SE1 vs SW2  ",
+		"count          3         2         -1                 0    -3          ",
+		"median         £500,000  £450,000  -£50,000 (-10.0%)  -    -           ",
+		"price per bed  £225,000  -         -                  -    -           ",
+		"reduced        33.3%     0.0%      -33.3pp            -    -           ",
+	}
+	lines := strings.Split(got, "\n")
+	for _, w := range want {
+		found := false
+		for _, l := range lines {
+			if l == w {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("comparison has no line %q:\n%s", w, got)
+		}
+	}
+	if len(lines) != len(comparisonMetrics)+2 {
+		t.Errorf("got %d lines, want a header and a row per metric:\n%s", len(lines), got)
+	}
+}
+
+func TestAreaComparisonJSON(t *testing.T) {
+	c := compareAreas([]string{"SW2", "SW9"}, comparisonListings())
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"SW2":{"count":3,"mean":500000,"median":500000,"p25":450000,"p75":550000,"price_per_bed":225000,"reduced_share":33.333333333333336},` +
+		`"SW9":{"count":2,"mean":450000,"median":450000,"p25":375000,"p75":525000,"reduced_share":0}}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestRunCompare(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, searchPage(400000, 500000))
+
+	statsFile := filepath.Join(t.TempDir(), "compare.json")
+	argv := []string{"--postcode", "sw2", "--postcode", "sw9", "--base-url", srv.SearchURL(), "--delay", "0",
+		"--stats-file", statsFile, "--no-color", "--quiet"}
+
+	var err error
+	stdout, _ := captureOutput(t, func() { err = runCompare(context.Background(), argv) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The test server gives both postcodes the same listings.
+	if !strings.Contains(stdout, "sw9 vs sw2") || !strings.Contains(stdout, "+£0 (+0.0%)") {
+		t.Errorf("got comparison:\n%s", stdout)
+	}
+
+	data, err := ioutil.ReadFile(statsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out struct {
+		Areas map[string]struct{ Count int } `json:"areas"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Areas) != 2 || out.Areas["sw2"].Count != 2 || out.Areas["sw9"].Count != 2 {
+		t.Errorf("got comparison %s", data)
+	}
+}
+
+func TestRunCompareNeedsTwoPostcodes(t *testing.T) {
+	err := runCompare(context.Background(), []string{"--postcode", "sw2", "--quiet"})
+	if err == nil || err.Error() != "compare needs at least two postcodes" {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package zoopla
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const gzipExt = ".gz"
+
+func isCompressed(args *cliArgs) bool {
+	return args.Compress || strings.HasSuffix(strings.ToLower(args.OutputFilename), gzipExt)
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, errors.Wrap(err, "while compressing")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "while compressing")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// readFileMaybeGzip reads a file, decompressing it if it starts with the gzip
+// magic number, so readers don't need to know how it was written.
+func readFileMaybeGzip(filename string) ([]byte, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 2 || data[0] != 0x1f || data[1] != 0x8b {
+		return data, nil
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "while decompressing %s", filename)
+	}
+	defer r.Close()
+
+	data, err = ioutil.ReadAll(r)
+	return data, errors.Wrapf(err, "while decompressing %s", filename)
+}
@@ -0,0 +1,90 @@
+package zoopla
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsCompressed(t *testing.T) {
+	tests := []struct {
+		args cliArgs
+		want bool
+	}{
+		{args: cliArgs{OutputFilename: "prices.json"}},
+		{args: cliArgs{OutputFilename: "prices.json.gz"}, want: true},
+		{args: cliArgs{OutputFilename: "PRICES.CSV.GZ"}, want: true},
+		{args: cliArgs{OutputFilename: "prices.json", Compress: true}, want: true},
+		{args: cliArgs{OutputFilename: "prices.gzip"}},
+	}
+
+	for _, tt := range tests {
+		if got := isCompressed(&tt.args); got != tt.want {
+			t.Errorf("isCompressed(%+v) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestReadFileMaybeGzip(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte(`[400000,450000]`)
+	compressed, err := gzipBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if compressed[0] != 0x1f || compressed[1] != 0x8b {
+		t.Fatalf("gzipBytes() doesn't start with the gzip magic number")
+	}
+
+	for name, contents := range map[string][]byte{"plain.json": data, "compressed.json.gz": compressed, "compressed-no-ext": compressed} {
+		filename := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(filename, contents, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := readFileMaybeGzip(filename)
+		if err != nil || string(got) != string(data) {
+			t.Errorf("readFileMaybeGzip(%s) = %q, %v, want %q", name, got, err, data)
+		}
+	}
+
+	if _, err := readFileMaybeGzip(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("read a missing file")
+	}
+
+	truncated := filepath.Join(dir, "truncated.json.gz")
+	if err := ioutil.WriteFile(truncated, compressed[:len(compressed)-4], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readFileMaybeGzip(truncated); err == nil {
+		t.Error("read a truncated gzip file without an error")
+	}
+}
+
+func TestWritePricesCompressed(t *testing.T) {
+	for _, args := range []cliArgs{
+		{OutputFilename: "prices.json.gz"},
+		{OutputFilename: "prices.json", Compress: true},
+	} {
+		args.OutputFilename = filepath.Join(t.TempDir(), args.OutputFilename)
+		if err := writePrices([]listing{{Price: 400000}, {Price: 450000}}, &args, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		raw, err := ioutil.ReadFile(args.OutputFilename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raw[0] != 0x1f || raw[1] != 0x8b {
+			t.Errorf("%s is not compressed", args.OutputFilename)
+		}
+
+		out, err := loadOutputFile(args.OutputFilename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalPrices(out.Prices, []uint64{400000, 450000}) {
+			t.Errorf("loaded prices %v", out.Prices)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package zoopla
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// tableColumn is a column of the CSV, TSV and XLSX listing outputs. value
+// gives the typed cell, which delimited outputs format as text.
+type tableColumn struct {
+	name  string
+	width float64
+	value func(l *listing) interface{}
+}
+
+// tableColumns are every listing column, in the default output order.
+var tableColumns = []tableColumn{
+	{"id", 12, func(l *listing) interface{} { return l.ID }},
+	{"price", 12, func(l *listing) interface{} { return l.Price }},
+	{"qualifier", 16, func(l *listing) interface{} { return l.Qualifier }},
+	{"beds", 6, func(l *listing) interface{} { return optionalCount(l.Beds) }},
+	{"type", 14, func(l *listing) interface{} { return l.PropertyType }},
+	{"address", 50, func(l *listing) interface{} { return l.Address }},
+	{"agent", 30, func(l *listing) interface{} { return l.Agent }},
+	{"url", 60, func(l *listing) interface{} { return l.URL }},
+}
+
+// optionalTableColumns are only output when chosen with --columns.
+var optionalTableColumns = []tableColumn{
+	{"price_per_bed", 12, pricePerBedValue},
+	{"stamp_duty", 12, func(l *listing) interface{} { return optionalAmount(l.StampDuty) }},
+	{"total_cost", 12, func(l *listing) interface{} { return optionalAmount(l.TotalCost) }},
+	{"value_score", 12, func(l *listing) interface{} { return optionalScore(l.ValueScore) }},
+}
+
+func allTableColumns() []tableColumn {
+	return append(append([]tableColumn(nil), tableColumns...), optionalTableColumns...)
+}
+
+func columnNames(columns []tableColumn) []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+
+	return names
+}
+
+// selectColumns returns the named columns in the order given, or all of them
+// when names is empty.
+func selectColumns(names []string) ([]tableColumn, error) {
+	if len(names) == 0 {
+		return tableColumns, nil
+	}
+
+	all := allTableColumns()
+	var columns []tableColumn
+	for _, name := range names {
+		i := indexOfColumn(all, name)
+		if i < 0 {
+			return nil, errors.Errorf("unknown column %q, must be one of %s", name, strings.Join(columnNames(all), ", "))
+		}
+		columns = append(columns, all[i])
+	}
+
+	return columns, nil
+}
+
+func indexOfColumn(columns []tableColumn, name string) int {
+	for i, c := range columns {
+		if c.name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// outputColumns returns the columns chosen with --columns, which parseArgs
+// has already validated. By default --stamp-duty and --regression add their
+// columns.
+func outputColumns(args *cliArgs) []tableColumn {
+	names := splitColumns(args.Columns)
+	if len(names) == 0 && (args.StampDuty != "" || args.Regression) {
+		names = columnNames(tableColumns)
+		if args.StampDuty != "" {
+			names = append(names, "stamp_duty", "total_cost")
+		}
+		if args.Regression {
+			names = append(names, "value_score")
+		}
+	}
+
+	columns, err := selectColumns(names)
+	if err != nil {
+		return tableColumns
+	}
+
+	return columns
+}
+
+// splitColumns splits the comma-separated --columns argument.
+func splitColumns(arg string) []string {
+	var names []string
+	for _, name := range strings.Split(arg, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func csvRow(l *listing, columns []tableColumn) []string {
+	row := make([]string, len(columns))
+	for i, c := range columns {
+		switch v := c.value(l).(type) {
+		case string:
+			row[i] = v
+		case uint64:
+			row[i] = strconv.FormatUint(v, 10)
+		case nil:
+		default:
+			row[i] = fmt.Sprint(v)
+		}
+	}
+
+	return row
+}
+
+// optionalCount returns a nil interface for a missing count so that it
+// leaves an empty cell.
+func optionalCount(n *uint32) interface{} {
+	if n == nil {
+		return nil
+	}
+
+	return *n
+}
+
+func formatOptionalCount(n *uint32) string {
+	if n == nil {
+		return ""
+	}
+
+	return strconv.FormatUint(uint64(*n), 10)
+}
+
+func listingRows(listings []listing, columns []tableColumn) [][]string {
+	rows := make([][]string, len(listings))
+	for i := range listings {
+		rows[i] = csvRow(&listings[i], columns)
+	}
+
+	return rows
+}
+
+func priceRows(prices []uint64) [][]string {
+	rows := make([][]string, len(prices))
+	for i, p := range prices {
+		rows[i] = []string{strconv.FormatUint(p, 10)}
+	}
+
+	return rows
+}
+
+func marshalCSV(listings []listing) ([]byte, error) {
+	return marshalDelimited(formatCSV, columnNames(tableColumns), listingRows(listings, tableColumns))
+}
+
+// marshalDelimited writes rows as CSV or TSV, with header first unless it's
+// nil.
+func marshalDelimited(format string, header []string, rows [][]string) ([]byte, error) {
+	if format == formatTSV {
+		return marshalTSV(header, rows), nil
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if header != nil {
+		if err := w.Write(header); err != nil {
+			return nil, errors.Wrap(err, "while writing CSV header")
+		}
+	}
+
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			return nil, errors.Wrap(err, "while writing CSV row")
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, errors.Wrap(err, "while writing CSV")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// tsvEscaper escapes the characters that would otherwise break a TSV row,
+// backslash first so escapes can be reversed unambiguously.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func marshalTSV(header []string, rows [][]string) []byte {
+	var buf bytes.Buffer
+	writeRow := func(row []string) {
+		for i, field := range row {
+			if i > 0 {
+				buf.WriteByte('\t')
+			}
+			buf.WriteString(tsvEscaper.Replace(field))
+		}
+		buf.WriteByte('\n')
+	}
+
+	if header != nil {
+		writeRow(header)
+	}
+	for _, row := range rows {
+		writeRow(row)
+	}
+
+	return buf.Bytes()
+}
+
+// optionalAmount is like optionalCount for amounts in pounds.
+func optionalAmount(n *uint64) interface{} {
+	if n == nil {
+		return nil
+	}
+
+	return *n
+}
+
+func optionalScore(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+
+	return *v
+}
@@ -0,0 +1,217 @@
+package zoopla
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMarshalCSV(t *testing.T) {
+	listings := []listing{
+		{
+			ID:           "58500000",
+			Price:        450000,
+			Qualifier:    qualifierOffersOver,
+			Beds:         uint32Ptr(2),
+			PropertyType: "flat",
+			Address:      "Acre Lane, Brixton SW2",
+			Agent:        `Foxtons "Brixton"`,
+			URL:          "https://www.zoopla.co.uk/for-sale/details/58500000/",
+		},
+		{ID: "58500001", Price: 1250000, Address: "Rue de l'Été, Crème Brûlée Mews"},
+	}
+
+	got, err := marshalCSV(listings)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "id,price,qualifier,beds,type,address,agent,url\n" +
+		`58500000,450000,offers_over,2,flat,"Acre Lane, Brixton SW2","Foxtons ""Brixton""",https://www.zoopla.co.uk/for-sale/details/58500000/` + "\n" +
+		"58500001,1250000,,,,\"Rue de l'Été, Crème Brûlée Mews\",,\n"
+	if string(got) != want {
+		t.Errorf("marshalCSV() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarshalOutputPricesCSV(t *testing.T) {
+	listings := []listing{{Price: 450000}, {Price: 400000}}
+
+	tests := []struct {
+		name string
+		args cliArgs
+		want string
+	}{
+		{name: "by format", args: cliArgs{Format: formatCSV}, want: "price\n450000\n400000\n"},
+		{name: "by extension", args: cliArgs{OutputFilename: "prices.CSV"}, want: "price\n450000\n400000\n"},
+		{name: "no header", args: cliArgs{Format: formatCSV, NoHeader: true}, want: "450000\n400000\n"},
+		{name: "tsv", args: cliArgs{Format: formatTSV}, want: "price\n450000\n400000\n"},
+		{name: "tsv no header", args: cliArgs{Format: formatTSV, NoHeader: true}, want: "450000\n400000\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := marshalOutput(listings, &tt.args, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalTSVEscaping(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		want  string
+	}{
+		{"plain", "Acre Lane, Brixton", "Acre Lane, Brixton"},
+		{"quotes", `Foxtons "Brixton"`, `Foxtons "Brixton"`},
+		{"tab", "Flat 1\tAcre Lane", `Flat 1\tAcre Lane`},
+		{"newline", "Flat 1\nAcre Lane", `Flat 1\nAcre Lane`},
+		{"carriage return", "Flat 1\r\nAcre Lane", `Flat 1\r\nAcre Lane`},
+		{"backslash", `Flat 1\2`, `Flat 1\\2`},
+		{"escaped backslash before n", `a\n`, `a\\n`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(marshalTSV(nil, [][]string{{tt.field, "x"}}))
+			if want := tt.want + "\tx\n"; got != want {
+				t.Errorf("marshalTSV() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+// tsvUnescaper reverses tsvEscaper.
+var tsvUnescaper = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n", `\r`, "\r")
+
+func TestMarshalTSVMatchesCSV(t *testing.T) {
+	listings := []listing{
+		fullListing(),
+		{ID: "58500001", Price: 400000, Address: "Flat 1\tAcre Lane\nBrixton", Agent: `Foxtons "Brixton"`},
+		{Price: 300000},
+	}
+	header := columnNames(allTableColumns())
+	rows := listingRows(listings, allTableColumns())
+
+	csvData, err := marshalDelimited(formatCSV, header, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := csv.NewReader(bytes.NewReader(csvData)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tsvData, err := marshalDelimited(formatTSV, header, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(tsvData), "\n"), "\n")
+	got := make([][]string, len(lines))
+	for i, line := range lines {
+		got[i] = strings.Split(line, "\t")
+		for j := range got[i] {
+			got[i][j] = tsvUnescaper.Replace(got[i][j])
+		}
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TSV rows =\n%q\nwant the CSV's\n%q", got, want)
+	}
+}
+
+func TestSplitColumns(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want []string
+	}{
+		{"", nil},
+		{"price", []string{"price"}},
+		{"price, beds ,url", []string{"price", "beds", "url"}},
+		{"price,,url,", []string{"price", "url"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitColumns(tt.arg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitColumns(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		want    []string
+		wantErr string
+	}{
+		{name: "default", want: columnNames(tableColumns)},
+		{name: "reordered", names: []string{"url", "price"}, want: []string{"url", "price"}},
+		{name: "optional", names: []string{"price", "price_per_bed", "value_score"}, want: []string{"price", "price_per_bed", "value_score"}},
+		{name: "repeated", names: []string{"price", "price"}, want: []string{"price", "price"}},
+		{name: "unknown", names: []string{"price", "garden"}, wantErr: `unknown column "garden", must be one of id, price,`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, err := selectColumns(tt.names)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := columnNames(columns); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputColumns(t *testing.T) {
+	defaults := columnNames(tableColumns)
+
+	tests := []struct {
+		name string
+		args cliArgs
+		want []string
+	}{
+		{name: "default", want: defaults},
+		{name: "chosen", args: cliArgs{Columns: "price,url"}, want: []string{"price", "url"}},
+		{name: "stamp duty", args: cliArgs{StampDuty: buyerHomeMover}, want: append(append([]string(nil), defaults...), "stamp_duty", "total_cost")},
+		{name: "regression", args: cliArgs{Regression: true}, want: append(append([]string(nil), defaults...), "value_score")},
+		{name: "chosen over stamp duty", args: cliArgs{Columns: "price", StampDuty: buyerHomeMover}, want: []string{"price"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := columnNames(outputColumns(&tt.args)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVRowColumns(t *testing.T) {
+	l := listing{ID: "1", Price: 450000, Beds: uint32Ptr(2), StampDuty: uint64Ptr(12500), ValueScore: float64Ptr(-1.5)}
+	columns, err := selectColumns([]string{"beds", "price", "id", "stamp_duty", "total_cost", "value_score"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"2", "450000", "1", "12500", "", "-1.5"}
+	if got := csvRow(&l, columns); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+package zoopla
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const dateExpr = `today|yesterday|\d+\s+(?:days?|weeks?)\s+ago|\d{1,2}(?:st|nd|rd|th)?\s+[a-z]{3,9}\s+\d{4}`
+
+var (
+	dateRegexp     = regexp.MustCompile(`(?i)\b\d{1,2}(?:st|nd|rd|th)?\s+[a-z]{3,9}\s+\d{4}\b`)
+	ordinalRegexp  = regexp.MustCompile(`(?i)^(\d{1,2})(?:st|nd|rd|th)\b`)
+	daysAgoRegexp  = regexp.MustCompile(`(?i)^(\d+)\s+(days?|weeks?)\s+ago$`)
+	listedOnRegexp = regexp.MustCompile(`(?i)\blisted\s+(?:on\s+)?(` + dateExpr + `)\b`)
+	reducedRegexp  = regexp.MustCompile(`(?i)\breduced\b(?:\s+(?:on\s+)?(` + dateExpr + `)\b)?`)
+)
+
+var dateLayouts = []string{
+	"2 Jan 2006",
+	"2 January 2006",
+}
+
+// timeNow is the clock used to resolve relative dates such as "yesterday".
+var timeNow = time.Now
+
+// parseListingDate parses the dates shown on Zoopla pages, which look like
+// "3rd Mar 2021" or "14th February 2021", or are relative to today such as
+// "yesterday" or "3 days ago".
+func parseListingDate(raw string) (time.Time, error) {
+	s := strings.ToLower(strings.Join(strings.Fields(raw), " "))
+
+	y, m, d := timeNow().Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	switch s {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+
+	if m := daysAgoRegexp.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, errors.Wrapf(err, "while parsing date %q", raw)
+		}
+		if strings.HasPrefix(m[2], "week") {
+			n *= 7
+		}
+		return today.AddDate(0, 0, -n), nil
+	}
+
+	s = ordinalRegexp.ReplaceAllString(s, "$1")
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, errors.Errorf("cannot parse date %q", raw)
+}
+
+// parseCardDates reads the "Listed on ..." and "Reduced ..." text of a card.
+// A reduced badge without a date still marks the listing as reduced.
+func parseCardDates(text string, l *listing) {
+	if m := listedOnRegexp.FindStringSubmatch(text); m != nil {
+		if t, err := parseListingDate(m[1]); err == nil {
+			l.ListedOn = &t
+		}
+	}
+
+	if m := reducedRegexp.FindStringSubmatch(text); m != nil {
+		l.IsReduced = true
+		if m[1] != "" {
+			if t, err := parseListingDate(m[1]); err == nil {
+				l.ReducedOn = &t
+			}
+		}
+	}
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
+
+type timeToReductionStats struct {
+	count      int
+	meanDays   float64
+	medianDays float64
+	minDays    uint64
+	maxDays    uint64
+}
+
+// calculateTimeToReductionStats summarises the days from listing to
+// reduction over listings with both dates known.
+func calculateTimeToReductionStats(listings []listing) timeToReductionStats {
+	var days []uint64
+	for i := range listings {
+		l := &listings[i]
+		if l.ListedOn == nil || l.ReducedOn == nil || l.ReducedOn.Before(*l.ListedOn) {
+			continue
+		}
+		days = append(days, uint64(daysBetween(*l.ListedOn, *l.ReducedOn)))
+	}
+
+	stats := timeToReductionStats{count: len(days)}
+	if len(days) == 0 {
+		return stats
+	}
+
+	stats.meanDays = calculateMean(days)
+	stats.medianDays = calculateMedian(days)
+	stats.minDays, stats.maxDays = days[0], days[0]
+	for _, d := range days[1:] {
+		if d < stats.minDays {
+			stats.minDays = d
+		}
+		if d > stats.maxDays {
+			stats.maxDays = d
+		}
+	}
+
+	return stats
+}
+
+func (s timeToReductionStats) String() string {
+	if s.count == 0 {
+		return "no listings with both listed and reduced dates"
+	}
+
+	return fmt.Sprintf(
+		"%d listings, mean = %.0f days, median = %.0f days, min = %d days, max = %d days",
+		s.count,
+		s.meanDays,
+		s.medianDays,
+		s.minDays,
+		s.maxDays,
+	)
+}
@@ -0,0 +1,132 @@
+package zoopla
+
+import (
+	"testing"
+	"time"
+)
+
+// setNow fixes the clock used for relative dates for the rest of the test.
+func setNow(t *testing.T, now time.Time) {
+	t.Helper()
+
+	saved := timeNow
+	timeNow = func() time.Time { return now }
+	t.Cleanup(func() { timeNow = saved })
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseListingDate(t *testing.T) {
+	setNow(t, time.Date(2021, time.March, 10, 15, 4, 5, 0, time.UTC))
+
+	tests := []struct {
+		raw     string
+		want    time.Time
+		wantErr bool
+	}{
+		{raw: "3rd Mar 2021", want: date(2021, time.March, 3)},
+		{raw: "14th February 2021", want: date(2021, time.February, 14)},
+		{raw: "1 Jan 2020", want: date(2020, time.January, 1)},
+		{raw: "22nd  Dec\n2020", want: date(2020, time.December, 22)},
+		{raw: "today", want: date(2021, time.March, 10)},
+		{raw: "Yesterday", want: date(2021, time.March, 9)},
+		{raw: "3 days ago", want: date(2021, time.March, 7)},
+		{raw: "1 day ago", want: date(2021, time.March, 9)},
+		{raw: "2 weeks ago", want: date(2021, time.February, 24)},
+		{raw: "last spring", wantErr: true},
+		{raw: "31st Feb 2021", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseListingDate(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseListingDate(%q) = %v, want an error", tt.raw, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseListingDate(%q): %v", tt.raw, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseListingDate(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseCardDates(t *testing.T) {
+	setNow(t, time.Date(2021, time.March, 10, 15, 4, 5, 0, time.UTC))
+
+	mar3 := date(2021, time.March, 3)
+	feb14 := date(2021, time.February, 14)
+	yesterday := date(2021, time.March, 9)
+
+	tests := []struct {
+		text          string
+		wantListed    *time.Time
+		wantReduced   bool
+		wantReducedOn *time.Time
+	}{
+		{text: "Listed on 14th Feb 2021 Reduced on 3rd Mar 2021", wantListed: &feb14, wantReduced: true, wantReducedOn: &mar3},
+		{text: "Listed 14th February 2021", wantListed: &feb14},
+		{text: "Reduced yesterday", wantReduced: true, wantReducedOn: &yesterday},
+		{text: "Reduced", wantReduced: true},
+		{text: "Listed on last spring"},
+		{text: "2 bed flat"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var l listing
+			parseCardDates(tt.text, &l)
+			if !equalTimePtr(l.ListedOn, tt.wantListed) {
+				t.Errorf("listed on = %v, want %v", l.ListedOn, tt.wantListed)
+			}
+			if l.IsReduced != tt.wantReduced || !equalTimePtr(l.ReducedOn, tt.wantReducedOn) {
+				t.Errorf("reduced = %v on %v, want %v on %v", l.IsReduced, l.ReducedOn, tt.wantReduced, tt.wantReducedOn)
+			}
+		})
+	}
+}
+
+func TestCalculateTimeToReductionStats(t *testing.T) {
+	at := func(m time.Month, d int) *time.Time {
+		t := date(2021, m, d)
+		return &t
+	}
+	listings := []listing{
+		{ListedOn: at(time.January, 1), ReducedOn: at(time.January, 11)},
+		{ListedOn: at(time.January, 1), ReducedOn: at(time.January, 31)},
+		{ListedOn: at(time.February, 1), ReducedOn: at(time.March, 3)},
+		{ListedOn: at(time.February, 1)},
+		{ReducedOn: at(time.February, 1)},
+		{ListedOn: at(time.March, 1), ReducedOn: at(time.February, 1)},
+	}
+
+	want := timeToReductionStats{count: 3, meanDays: 70.0 / 3, medianDays: 30, minDays: 10, maxDays: 30}
+	if got := calculateTimeToReductionStats(listings); got != want {
+		t.Errorf("calculateTimeToReductionStats() = %+v, want %+v", got, want)
+	}
+
+	if got := calculateTimeToReductionStats(nil).String(); got != "no listings with both listed and reduced dates" {
+		t.Errorf("String() without dates = %q", got)
+	}
+}
+
+func TestDaysBetween(t *testing.T) {
+	// Across the clocks going forward, which UTC dates ignore.
+	if got := daysBetween(date(2021, time.March, 27), date(2021, time.March, 29)); got != 2 {
+		t.Errorf("daysBetween() = %d, want 2", got)
+	}
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
@@ -0,0 +1,218 @@
+package zoopla
+
+import (
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxDuplicatePriceDiff  = 0.01
+	minDuplicateSimilarity = 0.8
+)
+
+var (
+	houseNumberRegexp    = regexp.MustCompile(`^\d+[a-z]?$`)
+	addressAbbreviations = map[string]string{
+		"rd":   "road",
+		"st":   "street",
+		"ave":  "avenue",
+		"ln":   "lane",
+		"sq":   "square",
+		"cl":   "close",
+		"ct":   "court",
+		"cres": "crescent",
+		"dr":   "drive",
+		"gdns": "gardens",
+		"pl":   "place",
+		"tce":  "terrace",
+	}
+)
+
+// normalisedAddress splits an address into its house/flat numbers and its
+// remaining words, after case folding, stripping punctuation and expanding
+// common abbreviations.
+type normalisedAddress struct {
+	numbers []string
+	words   map[string]bool
+}
+
+func normaliseAddress(raw string) normalisedAddress {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, raw)
+
+	addr := normalisedAddress{words: make(map[string]bool)}
+	for _, word := range strings.Fields(cleaned) {
+		if houseNumberRegexp.MatchString(word) {
+			addr.numbers = append(addr.numbers, word)
+			continue
+		}
+
+		if full, ok := addressAbbreviations[word]; ok {
+			word = full
+		}
+		addr.words[word] = true
+	}
+
+	return addr
+}
+
+// similarTo requires identical house numbers, so neighbouring properties on
+// the same street are never merged, and a high overlap of the other words.
+// Addresses without any numbers are never similar, since street-level
+// addresses such as "Acre Lane, London" can't tell properties apart.
+func (a normalisedAddress) similarTo(b normalisedAddress) bool {
+	if len(a.numbers) == 0 || len(a.numbers) != len(b.numbers) {
+		return false
+	}
+	for i := range a.numbers {
+		if a.numbers[i] != b.numbers[i] {
+			return false
+		}
+	}
+
+	if len(a.words) == 0 || len(b.words) == 0 {
+		return false
+	}
+
+	var common int
+	for w := range a.words {
+		if b.words[w] {
+			common++
+		}
+	}
+
+	smaller := len(a.words)
+	if len(b.words) < smaller {
+		smaller = len(b.words)
+	}
+
+	return float64(common)/float64(smaller) >= minDuplicateSimilarity
+}
+
+func pricesClose(a, b uint64) bool {
+	high, low := float64(a), float64(b)
+	if low > high {
+		high, low = low, high
+	}
+
+	return high-low <= maxDuplicatePriceDiff*high
+}
+
+// dedupeFuzzy merges listings that appear to be the same property marketed
+// more than once: both the address must be similar and the prices within
+// 1%. The first listing of each group is kept.
+func dedupeFuzzy(listings []listing) []listing {
+	addrs := make([]normalisedAddress, len(listings))
+	for i := range listings {
+		addrs[i] = normaliseAddress(listings[i].Address)
+	}
+
+	groupOf := make([]int, len(listings))
+	for i := range groupOf {
+		groupOf[i] = -1
+	}
+
+	var kept []listing
+	for i := range listings {
+		if groupOf[i] >= 0 {
+			continue
+		}
+		groupOf[i] = i
+		kept = append(kept, listings[i])
+
+		if listings[i].Address == "" {
+			continue
+		}
+
+		merged := []int{i}
+		for j := i + 1; j < len(listings); j++ {
+			if groupOf[j] >= 0 || listings[j].Address == "" {
+				continue
+			}
+
+			if pricesClose(listings[i].Price, listings[j].Price) && addrs[i].similarTo(addrs[j]) {
+				groupOf[j] = i
+				merged = append(merged, j)
+			}
+		}
+
+		if len(merged) > 1 {
+			logMergedGroup(listings, merged)
+		}
+	}
+
+	if removed := len(listings) - len(kept); removed > 0 {
+		log.Printf("fuzzy de-duplication removed %d listings", removed)
+	}
+
+	return kept
+}
+
+func logMergedGroup(listings []listing, group []int) {
+	descriptions := make([]string, len(group))
+	for i, idx := range group {
+		l := &listings[idx]
+		descriptions[i] = l.Address + " (" + formatPrice(l.Price) + ", " + l.Agent + ")"
+	}
+
+	log.Print("merged duplicate listings: ", strings.Join(descriptions, "; "))
+}
+
+const (
+	outputSetDeduped = "deduped"
+	outputSetRaw     = "raw"
+)
+
+var outputSets = []string{outputSetDeduped, outputSetRaw}
+
+// dedupeByID drops listings whose ID was already seen, such as promoted
+// listings shown on several pages or under several postcodes. Listings
+// without an ID are all kept.
+func dedupeByID(listings []listing) []listing {
+	seen := make(map[string]bool)
+	var kept []listing
+	for i := range listings {
+		id := listings[i].ID
+		if id != "" && seen[id] {
+			continue
+		}
+		seen[id] = true
+		kept = append(kept, listings[i])
+	}
+
+	if removed := len(listings) - len(kept); removed > 0 {
+		log.Printf("removed %d listings with repeated IDs", removed)
+	}
+
+	return kept
+}
+
+// rawStatsPrices are the prices the stats would have been calculated over
+// without de-duplication, with auctions, grossing up and outliers handled
+// as for the de-duplicated prices.
+func rawStatsPrices(raw []listing, args *cliArgs) []uint64 {
+	if !args.IncludeAuctionsInStats {
+		raw, _ = splitAuctions(raw)
+	}
+
+	prices := listingPrices(raw)
+	if args.GrossingUp {
+		prices = grossedUpPrices(raw)
+	}
+
+	if args.TrimOutliers != "" {
+		if rule, err := parseOutlierRule(args.TrimOutliers); err == nil {
+			if kept, _ := trimOutliers(prices, rule); len(kept) > 0 {
+				prices = kept
+			}
+		}
+	}
+
+	return prices
+}
@@ -0,0 +1,202 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ryanc414/zoopla-analyzer/internal/testserver"
+)
+
+func TestNormaliseAddress(t *testing.T) {
+	got := normaliseAddress("Flat 2, 14a Acre Rd., LONDON SW2")
+	want := normalisedAddress{
+		numbers: []string{"2", "14a"},
+		words:   map[string]bool{"flat": true, "acre": true, "road": true, "london": true, "sw2": true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("normaliseAddress() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSimilarTo(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{name: "abbreviated", a: "14 Acre Road, London SW2", b: "14 Acre Rd, London", want: true},
+		{name: "case and punctuation", a: "Flat 2, 14 Acre Road", b: "flat 2 14 acre road.", want: true},
+		{name: "neighbour", a: "14 Acre Road, London", b: "16 Acre Road, London"},
+		{name: "different flat", a: "Flat 2, 14 Acre Road", b: "Flat 3, 14 Acre Road"},
+		{name: "one numbered", a: "14 Acre Road, London", b: "Acre Road, London"},
+		{name: "no numbers", a: "Acre Road, London SW2", b: "Acre Road, London SW2"},
+		{name: "different street", a: "14 Acre Road, London", b: "14 Brixton Hill, London"},
+		{name: "numbers only", a: "14", b: "14"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := normaliseAddress(tt.a), normaliseAddress(tt.b)
+			if got := a.similarTo(b); got != tt.want {
+				t.Errorf("similarTo(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := b.similarTo(a); got != tt.want {
+				t.Errorf("similarTo(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPricesClose(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want bool
+	}{
+		{a: 450000, b: 450000, want: true},
+		{a: 450000, b: 454500, want: true},
+		{a: 454500, b: 450000, want: true},
+		{a: 450000, b: 460000},
+		{a: 0, b: 0, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := pricesClose(tt.a, tt.b); got != tt.want {
+			t.Errorf("pricesClose(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDedupeFuzzy(t *testing.T) {
+	listings := []listing{
+		{ID: "1", Address: "14 Acre Road, London SW2", Price: 450000, Agent: "Foxtons"},
+		{ID: "2", Address: "16 Acre Road, London SW2", Price: 450000, Agent: "Foxtons"},
+		{ID: "3", Address: "14 Acre Rd, London", Price: 452000, Agent: "Winkworth"},
+		{ID: "4", Address: "14 Acre Road, London SW2", Price: 500000, Agent: "Savills"},
+		{ID: "5", Address: "Acre Road, London SW2", Price: 300000, Agent: "Foxtons"},
+		{ID: "6", Address: "Acre Road, London SW2", Price: 300000, Agent: "Winkworth"},
+		{ID: "7", Price: 450000},
+		{ID: "8", Price: 450000},
+	}
+
+	var got []string
+	for _, l := range dedupeFuzzy(listings) {
+		got = append(got, l.ID)
+	}
+	want := []string{"1", "2", "4", "5", "6", "7", "8"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dedupeFuzzy() kept %v, want %v", got, want)
+	}
+}
+
+func TestDedupeByID(t *testing.T) {
+	tests := []struct {
+		name string
+		ids  []string
+		want []string
+	}{
+		{"none", nil, nil},
+		{"unique", []string{"1", "2", "3"}, []string{"1", "2", "3"}},
+		{"repeated", []string{"1", "2", "1", "3", "2"}, []string{"1", "2", "3"}},
+		{"without IDs", []string{"", "1", "", "1"}, []string{"", "1", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var listings []listing
+			for i, id := range tt.ids {
+				listings = append(listings, listing{ID: id, Price: uint64(i)})
+			}
+
+			got := dedupeByID(listings)
+			if !reflect.DeepEqual(ids(got), tt.want) {
+				t.Errorf("got %v, want %v", ids(got), tt.want)
+			}
+			// The first of each ID is the one kept.
+			for i := 1; i < len(got); i++ {
+				if got[i].Price < got[i-1].Price {
+					t.Errorf("got %+v out of order", got)
+				}
+			}
+		})
+	}
+}
+
+func TestRawStatsPrices(t *testing.T) {
+	raw := []listing{{Price: 400000}, {Price: 410000}, {Price: 420000}, {Price: 430000}, {Price: 440000}, {Price: 5000000}, {Price: 100000, IsAuction: true}}
+
+	tests := []struct {
+		name string
+		args cliArgs
+		want []uint64
+	}{
+		{"auctions left out", cliArgs{}, []uint64{400000, 410000, 420000, 430000, 440000, 5000000}},
+		{"auctions included", cliArgs{IncludeAuctionsInStats: true}, []uint64{400000, 410000, 420000, 430000, 440000, 5000000, 100000}},
+		{"outliers trimmed", cliArgs{TrimOutliers: trimIQR}, []uint64{400000, 410000, 420000, 430000, 440000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rawStatsPrices(raw, &tt.args); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunDedupesByID(t *testing.T) {
+	tests := []struct {
+		name       string
+		set        string
+		wantPrices []uint64
+	}{
+		{"deduped", outputSetDeduped, []uint64{400000, 450000}},
+		{"raw", outputSetRaw, []uint64{400000, 450000, 450000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testserver.New()
+			defer srv.Close()
+			// The promoted £450,000 listing is shown on both pages.
+			srv.SetPage(1, searchPage(400000, 450000))
+			srv.SetPage(2, searchPage(450000))
+
+			dir := t.TempDir()
+			output, statsFile := filepath.Join(dir, "prices.json"), filepath.Join(dir, "stats.json")
+			args := testSearchArgs(t, "--postcode", "sw2", "--base-url", srv.SearchURL(), "--delay", "0",
+				"--outputfilename", output, "--stats-file", statsFile, "--output-set", tt.set, "--quiet")
+			if err := runFetch(context.Background(), args); err != nil {
+				t.Fatal(err)
+			}
+
+			in, err := loadOutputFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(in.Prices, tt.wantPrices) {
+				t.Errorf("wrote %v, want %v", in.Prices, tt.wantPrices)
+			}
+
+			data, err := ioutil.ReadFile(statsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var stats struct {
+				Stats      struct{ Count int }  `json:"stats"`
+				RawStats   *struct{ Count int } `json:"raw_stats"`
+				Duplicates int                  `json:"duplicates"`
+			}
+			if err := json.Unmarshal(data, &stats); err != nil {
+				t.Fatal(err)
+			}
+			// The stats are always over the de-duplicated listings.
+			if stats.Stats.Count != 2 || stats.RawStats == nil || stats.RawStats.Count != 3 || stats.Duplicates != 1 {
+				t.Errorf("got stats %s", data)
+			}
+		})
+	}
+}
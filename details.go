@@ -0,0 +1,119 @@
+package zoopla
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultDetailWorkers = 4
+
+	// unknownTenure is the tenure of a listing whose detail page doesn't
+	// state one.
+	unknownTenure = "unknown"
+)
+
+var (
+	tenureRegexp = regexp.MustCompile(`(?i)\b(share of freehold|freehold|leasehold|commonhold)\b`)
+)
+
+// fetchAllDetails enriches listings in place from their detail pages. A
+// failure to fetch or parse a detail page leaves that listing un-enriched
+// rather than failing the run.
+func fetchAllDetails(ctx context.Context, f *fetcher, listings []listing, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures int
+	)
+
+	sem := make(chan struct{}, workers)
+	for i := range listings {
+		if listings[i].URL == "" {
+			mu.Lock()
+			failures++
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(l *listing) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := fetchDetails(ctx, f, l); err != nil {
+				log.Print(err)
+				mu.Lock()
+				failures++
+				mu.Unlock()
+			}
+		}(&listings[i])
+	}
+	wg.Wait()
+
+	log.Printf("fetched details for %d of %d listings", len(listings)-failures, len(listings))
+}
+
+func fetchDetails(ctx context.Context, f *fetcher, l *listing) error {
+	detailURL, err := url.Parse(l.URL)
+	if err != nil {
+		return errors.Wrapf(err, "while parsing detail URL %s", l.URL)
+	}
+
+	doc, err := f.getHTML(ctx, detailURL)
+	if err != nil {
+		return errors.Wrapf(err, "while getting detail page %s", l.URL)
+	}
+
+	parseDetailPage(doc, l)
+	return nil
+}
+
+func parseDetailPage(root *html.Node, l *listing) {
+	if n := findByTestID(root, "address-label"); n != nil {
+		l.Address = strings.Join(strings.Fields(textContent(n)), " ")
+	}
+
+	if n := findByTestID(root, "listing_description"); n != nil {
+		l.Description = strings.TrimSpace(textContent(n))
+	}
+
+	// The floor area and tenure are only looked for in the features list,
+	// since elsewhere the page has similar listings and embedded JSON that
+	// match the same patterns.
+	l.Tenure = unknownTenure
+	if n := findByTestID(root, "listing_features"); n != nil {
+		text := textContent(n)
+		applyDetailFloorArea(l, parseFloorArea(text))
+		if m := tenureRegexp.FindStringSubmatch(text); m != nil {
+			l.Tenure = strings.ToLower(m[1])
+		}
+	}
+	l.HasFloorplan = parseHasFloorplan(root)
+
+	l.PriceHistory = parsePriceHistory(root)
+	l.EPCBand = parseEPCBand(root)
+}
+
+func findByTestID(root *html.Node, testID string) *html.Node {
+	return findFirst(root, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && hasTestID(n, testID)
+	})
+}
+
+func hasTestID(n *html.Node, testID string) bool {
+	id, _ := getAttr(n, "data-testid")
+	return id == testID
+}
@@ -0,0 +1,173 @@
+package zoopla
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func mustReadDetailPage(t *testing.T, name string) []byte {
+	t.Helper()
+
+	b, err := os.ReadFile(filepath.Join("testdata", "details", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
+
+func TestParseDetailPage(t *testing.T) {
+	tests := []struct {
+		fixture      string
+		address      string
+		area         *uint32
+		tenure       string
+		hasFloorplan bool
+		epc          string
+		history      []uint64
+	}{
+		{
+			fixture:      "full.html",
+			address:      "Acre Lane, London SW2 5SG",
+			area:         uint32Ptr(812),
+			tenure:       "leasehold",
+			hasFloorplan: true,
+			epc:          "C",
+			history:      []uint64{550000, 525000},
+		},
+		{
+			fixture: "no-features.html",
+			address: "Effra Road, London SW2 1BZ",
+			tenure:  unknownTenure,
+		},
+		{
+			fixture: "older-template.html",
+			address: "Brixton Hill, London SW2 1AA",
+			area:    uint32Ptr(807),
+			tenure:  "share of freehold",
+			epc:     "D",
+			history: []uint64{700000, 650000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			var l listing
+			parseDetailPage(mustParseHTML(t, string(mustReadDetailPage(t, tt.fixture))), &l)
+
+			if l.Address != tt.address {
+				t.Errorf("address = %q, want %q", l.Address, tt.address)
+			}
+			if !reflect.DeepEqual(l.FloorArea, tt.area) {
+				t.Errorf("floor area = %v, want %v", derefUint32(l.FloorArea), derefUint32(tt.area))
+			}
+			if tt.area != nil && l.FloorAreaSource != areaSourceDetail {
+				t.Errorf("floor area source = %q, want %q", l.FloorAreaSource, areaSourceDetail)
+			}
+			if l.Tenure != tt.tenure {
+				t.Errorf("tenure = %q, want %q", l.Tenure, tt.tenure)
+			}
+			if l.HasFloorplan != tt.hasFloorplan {
+				t.Errorf("has floorplan = %v, want %v", l.HasFloorplan, tt.hasFloorplan)
+			}
+			if l.EPCBand != tt.epc {
+				t.Errorf("EPC band = %q, want %q", l.EPCBand, tt.epc)
+			}
+
+			var history []uint64
+			for _, p := range l.PriceHistory {
+				history = append(history, p.Price)
+			}
+			if !reflect.DeepEqual(history, tt.history) {
+				t.Errorf("price history = %v, want %v", history, tt.history)
+			}
+		})
+	}
+}
+
+func TestParseDetailPageKeepsCardAreaWithoutFeatures(t *testing.T) {
+	l := listing{FloorArea: uint32Ptr(700), FloorAreaSource: areaSourceCard}
+	parseDetailPage(mustParseHTML(t, string(mustReadDetailPage(t, "no-features.html"))), &l)
+
+	if l.FloorArea == nil || *l.FloorArea != 700 || l.FloorAreaSource != areaSourceCard {
+		t.Errorf("floor area = %v from %q, want 700 from the card", derefUint32(l.FloorArea), l.FloorAreaSource)
+	}
+}
+
+func TestFetchAllDetails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/details/1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(mustReadDetailPage(t, "full.html"))
+	}))
+	defer srv.Close()
+
+	listings := []listing{
+		{URL: srv.URL + "/details/1"},
+		{URL: srv.URL + "/details/2"},
+		{},
+	}
+	f := &fetcher{client: srv.Client()}
+	fetchAllDetails(context.Background(), f, listings, 2)
+
+	if listings[0].Tenure != "leasehold" {
+		t.Errorf("fetched listing tenure = %q, want leasehold", listings[0].Tenure)
+	}
+	for _, l := range listings[1:] {
+		if l.Tenure != "" || l.Address != "" {
+			t.Errorf("listing %q was enriched despite having no detail page", l.URL)
+		}
+	}
+}
+
+func TestFetcherSpacesRequests(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		w.Write(mustReadDetailPage(t, "full.html"))
+	}))
+	defer srv.Close()
+
+	const delay = 50 * time.Millisecond
+	listings := make([]listing, 3)
+	for i := range listings {
+		listings[i].URL = srv.URL + "/details/x"
+	}
+	fetchAllDetails(context.Background(), &fetcher{client: srv.Client(), delay: delay}, listings, 3)
+
+	if len(times) != len(listings) {
+		t.Fatalf("server saw %d requests, want %d", len(times), len(listings))
+	}
+	for i := 1; i < len(times); i++ {
+		// Allow for the server seeing the requests a little unevenly.
+		if gap := times[i].Sub(times[i-1]); gap < delay-10*time.Millisecond {
+			t.Errorf("requests %d and %d were %v apart, want at least %v", i-1, i, gap, delay)
+		}
+	}
+}
+
+func uint32Ptr(v uint32) *uint32 {
+	return &v
+}
+
+func derefUint32(p *uint32) interface{} {
+	if p == nil {
+		return nil
+	}
+
+	return *p
+}
@@ -0,0 +1,148 @@
+package zoopla
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+const maxSnippetBytes = 500
+
+type parseFailure struct {
+	Page    uint32 `json:"page"`
+	Card    int    `json:"card"`
+	Error   string `json:"error"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// diagnostics collects the cards that failed to parse during a run. With
+// --save-html the fetched pages and failing cards are also written to a
+// directory, and with --diagnostics all failures are written to one JSON
+// report at the end of the run.
+type diagnostics struct {
+	saveDir    string
+	reportFile string
+
+	mu       sync.Mutex
+	pages    int
+	failures []parseFailure
+}
+
+func newDiagnostics(args *cliArgs) *diagnostics {
+	return &diagnostics{saveDir: args.SaveHTML, reportFile: args.Diagnostics}
+}
+
+func (d *diagnostics) report(pageNum uint32, cardErrs []cardError) {
+	d.mu.Lock()
+	d.pages++
+	d.mu.Unlock()
+
+	for _, ce := range cardErrs {
+		failure := parseFailure{Page: pageNum, Card: ce.index, Error: ce.err.Error()}
+		if ce.node != nil {
+			failure.Snippet = renderSnippet(ce.node)
+		}
+
+		if failure.Snippet != "" {
+			log.Printf("page %d card %d: %v: %s", pageNum, ce.index, ce.err, failure.Snippet)
+		} else {
+			log.Printf("page %d card %d: %v", pageNum, ce.index, ce.err)
+		}
+
+		if d.saveDir != "" && ce.node != nil {
+			name := fmt.Sprintf("page-%d-card-%d.html", pageNum, ce.index)
+			if err := d.saveNode(name, ce.node); err != nil {
+				log.Print(err)
+			}
+		}
+
+		d.mu.Lock()
+		d.failures = append(d.failures, failure)
+		d.mu.Unlock()
+	}
+}
+
+// counts returns the number of pages reported and cards that failed.
+func (d *diagnostics) counts() (pages, failures int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.pages, len(d.failures)
+}
+
+func (d *diagnostics) savePage(pageNum uint32, doc *html.Node) {
+	if d.saveDir == "" {
+		return
+	}
+
+	if err := d.saveNode(fmt.Sprintf("page-%d.html", pageNum), doc); err != nil {
+		log.Print(err)
+	}
+}
+
+func (d *diagnostics) saveNode(name string, n *html.Node) error {
+	if err := os.MkdirAll(d.saveDir, 0755); err != nil {
+		return errors.Wrap(err, "while creating HTML directory")
+	}
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, n); err != nil {
+		return errors.Wrap(err, "while rendering HTML")
+	}
+
+	return ioutil.WriteFile(filepath.Join(d.saveDir, name), buf.Bytes(), 0644)
+}
+
+func (d *diagnostics) write() error {
+	if d.reportFile == "" {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	failures := d.failures
+	if failures == nil {
+		failures = []parseFailure{}
+	}
+
+	data, err := json.MarshalIndent(failures, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "while marshalling diagnostics")
+	}
+
+	if err := writeFileAtomic(d.reportFile, data); err != nil {
+		return errors.Wrap(err, "while writing diagnostics")
+	}
+	log.Printf("wrote %d parse failures to %s", len(failures), d.reportFile)
+
+	return nil
+}
+
+// renderSnippet renders n as HTML, truncated to maxSnippetBytes.
+func renderSnippet(n *html.Node) string {
+	var buf bytes.Buffer
+	if err := html.Render(&buf, n); err != nil {
+		return ""
+	}
+
+	if buf.Len() <= maxSnippetBytes {
+		return buf.String()
+	}
+
+	snippet := buf.Bytes()[:maxSnippetBytes]
+	for len(snippet) > 0 && !utf8.Valid(snippet) {
+		snippet = snippet[:len(snippet)-1]
+	}
+
+	return string(snippet) + "..."
+}
@@ -0,0 +1,106 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+func TestRenderSnippet(t *testing.T) {
+	short := mustParseCard(t, `<div data-testid="search-result"><p>POA</p></div>`)
+	if got, want := renderSnippet(short), `<div data-testid="search-result"><p>POA</p></div>`; got != want {
+		t.Errorf("renderSnippet() = %q, want %q", got, want)
+	}
+
+	long := mustParseCard(t, `<div data-testid="search-result"><p>`+strings.Repeat("£", maxSnippetBytes)+`</p></div>`)
+	got := renderSnippet(long)
+	if !strings.HasSuffix(got, "...") {
+		t.Errorf("long snippet %q isn't marked as truncated", got)
+	}
+	if body := strings.TrimSuffix(got, "..."); len(body) > maxSnippetBytes || !utf8.ValidString(body) {
+		t.Errorf("long snippet is %d bytes, valid UTF-8 = %v", len(body), utf8.ValidString(body))
+	}
+}
+
+func TestDiagnosticsReport(t *testing.T) {
+	dir := t.TempDir()
+	d := &diagnostics{saveDir: filepath.Join(dir, "html"), reportFile: filepath.Join(dir, "diagnostics.json")}
+
+	card := mustParseCard(t, `<div data-testid="search-result"><p>POA</p></div>`)
+	d.report(1, nil)
+	d.report(2, []cardError{
+		{index: 3, node: card, err: errors.New("no price")},
+		{index: 4, err: errors.New("bad price")},
+	})
+
+	if pages, failures := d.counts(); pages != 2 || failures != 2 {
+		t.Errorf("counts() = %d pages, %d failures, want 2 and 2", pages, failures)
+	}
+
+	if _, err := os.Stat(filepath.Join(d.saveDir, "page-2-card-3.html")); err != nil {
+		t.Errorf("failing card not saved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(d.saveDir, "page-2-card-4.html")); !os.IsNotExist(err) {
+		t.Errorf("card without a node was saved")
+	}
+
+	if err := d.write(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(d.reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var failures []parseFailure
+	if err := json.Unmarshal(b, &failures); err != nil {
+		t.Fatal(err)
+	}
+	want := []parseFailure{
+		{Page: 2, Card: 3, Error: "no price", Snippet: `<div data-testid="search-result"><p>POA</p></div>`},
+		{Page: 2, Card: 4, Error: "bad price"},
+	}
+	if len(failures) != len(want) {
+		t.Fatalf("got %d failures, want %d", len(failures), len(want))
+	}
+	for i := range want {
+		if failures[i] != want[i] {
+			t.Errorf("failure %d = %+v, want %+v", i, failures[i], want[i])
+		}
+	}
+}
+
+func TestDiagnosticsWriteEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "diagnostics.json")
+	if err := (&diagnostics{reportFile: path}).write(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(b)); got != "[]" {
+		t.Errorf("empty report = %s, want []", got)
+	}
+}
+
+func TestParseFailuresCarryCards(t *testing.T) {
+	page := `<div class="ListingsContainer">
+<div data-testid="search-result"><div class="PriceContainer"><p>POA</p></div></div>
+<div data-testid="search-result"><div class="PriceContainer"><p>£450,000</p></div></div>
+</div>`
+
+	listings, cardErrs := classParser{}.parse(mustParseHTML(t, page), saleSearch)
+	if len(listings) != 1 || len(cardErrs) != 1 {
+		t.Fatalf("got %d listings and %d errors, want 1 and 1", len(listings), len(cardErrs))
+	}
+	if ce := cardErrs[0]; ce.index != 0 || ce.node == nil {
+		t.Errorf("card error = %+v, want card 0 with its node", ce)
+	}
+}
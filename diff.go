@@ -0,0 +1,247 @@
+package zoopla
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+)
+
+const diffFormatTable = "table"
+
+var diffFormats = []string{diffFormatTable, formatJSON}
+
+type diffArgs struct {
+	Old     string `arg:"positional,required"`
+	New     string `arg:"positional,required"`
+	Format  string `arg:"--format"`
+	Force   bool   `arg:"--force"`
+	Pretty  bool   `arg:"--pretty"`
+	NoColor bool   `arg:"--no-color"`
+}
+
+type listingDiff struct {
+	Summary diffSummary     `json:"summary"`
+	Added   []listing       `json:"added"`
+	Removed []listing       `json:"removed"`
+	Changed []listingChange `json:"price_changed"`
+}
+
+type diffSummary struct {
+	Added     int     `json:"added"`
+	Removed   int     `json:"removed"`
+	Changed   int     `json:"price_changed"`
+	Unchanged int     `json:"unchanged"`
+	OldMean   float64 `json:"old_mean"`
+	NewMean   float64 `json:"new_mean"`
+	OldMedian float64 `json:"old_median"`
+	NewMedian float64 `json:"new_median"`
+}
+
+type listingChange struct {
+	ID            string  `json:"id"`
+	Address       string  `json:"address,omitempty"`
+	URL           string  `json:"url,omitempty"`
+	OldPrice      uint64  `json:"old_price"`
+	NewPrice      uint64  `json:"new_price"`
+	ChangePercent float64 `json:"change_percent"`
+}
+
+func runDiff(argv []string) error {
+	args := diffArgs{Format: diffFormatTable}
+	p, err := parseSubcommand("diff", &args, argv)
+	if err != nil {
+		return err
+	}
+	setupLogColor(args.NoColor)
+	if !containsString(diffFormats, args.Format) {
+		return newUsageError(p, fmt.Sprintf("unknown format %q, must be one of %s", args.Format, strings.Join(diffFormats, ", ")))
+	}
+
+	old, err := loadDiffInput(args.Old)
+	if err != nil {
+		return err
+	}
+	cur, err := loadDiffInput(args.New)
+	if err != nil {
+		return err
+	}
+
+	if err := checkDiffQueries(args.Old, old, args.New, cur); err != nil {
+		if !args.Force {
+			return errors.Errorf("%v, use --force to diff them anyway", err)
+		}
+		log.Print("warning: ", err)
+	}
+
+	d := diffListings(old.Listings, cur.Listings)
+	if args.Format == formatJSON {
+		data, err := marshalJSON(d, args.Pretty)
+		if err != nil {
+			return errors.Wrap(err, "while marshalling diff")
+		}
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return errors.Wrap(err, "while writing diff")
+	}
+
+	prices := append([]uint64(nil), cur.Prices...)
+	sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+	bands := priceQuartiles{}
+	if len(prices) > 0 {
+		bands = priceQuartiles{p25: quantile(prices, 0.25), p75: quantile(prices, 0.75)}
+	}
+
+	return errors.Wrap(writeDiffTable(os.Stdout, d, bands, newPainter(os.Stdout, args.NoColor)), "while writing diff")
+}
+
+func loadDiffInput(filename string) (*loadedOutput, error) {
+	in, err := loadOutputFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if in.Kind != outputKindListings {
+		return nil, errors.Errorf("%s holds %s, diff needs --output-mode listings output", filename, in.Kind)
+	}
+
+	return in, nil
+}
+
+// checkDiffQueries fails when the files were run with different queries, or
+// when either has no metadata so that its query can't be checked.
+func checkDiffQueries(oldName string, old *loadedOutput, newName string, cur *loadedOutput) error {
+	switch {
+	case old.Metadata == nil:
+		return errors.Errorf("%s has no query metadata to check against %s", oldName, newName)
+	case cur.Metadata == nil:
+		return errors.Errorf("%s has no query metadata to check against %s", newName, oldName)
+	case !sameQuery(old.Metadata.Query, cur.Metadata.Query):
+		return errors.Errorf("%s and %s were run with different queries", oldName, newName)
+	}
+
+	return nil
+}
+
+func sameQuery(a, b runQuery) bool {
+	aData, aErr := json.Marshal(a)
+	bData, bErr := json.Marshal(b)
+	return aErr == nil && bErr == nil && bytes.Equal(aData, bData)
+}
+
+// diffListings matches listings by ID. Listings without an ID can't be
+// matched and are left out.
+func diffListings(old, cur []listing) listingDiff {
+	d := listingDiff{Added: []listing{}, Removed: []listing{}, Changed: []listingChange{}}
+
+	oldByID := listingsByID(old)
+	curByID := listingsByID(cur)
+	if skipped := len(old) + len(cur) - len(oldByID) - len(curByID); skipped > 0 {
+		log.Printf("skipped %d listings without an ID", skipped)
+	}
+
+	for _, l := range sortedByID(cur) {
+		prev, ok := oldByID[l.ID]
+		switch {
+		case l.ID == "":
+		case !ok:
+			d.Added = append(d.Added, l)
+		case prev.Price != l.Price:
+			d.Changed = append(d.Changed, listingChange{
+				ID:            l.ID,
+				Address:       l.Address,
+				URL:           l.URL,
+				OldPrice:      prev.Price,
+				NewPrice:      l.Price,
+				ChangePercent: percentChange(float64(prev.Price), float64(l.Price)),
+			})
+		default:
+			d.Summary.Unchanged++
+		}
+	}
+	for _, l := range sortedByID(old) {
+		if _, ok := curByID[l.ID]; l.ID != "" && !ok {
+			d.Removed = append(d.Removed, l)
+		}
+	}
+
+	d.Summary.Added = len(d.Added)
+	d.Summary.Removed = len(d.Removed)
+	d.Summary.Changed = len(d.Changed)
+	if prices := listingPrices(old); len(prices) > 0 {
+		d.Summary.OldMean = calculateMean(prices)
+		d.Summary.OldMedian = calculateMedian(prices)
+	}
+	if prices := listingPrices(cur); len(prices) > 0 {
+		d.Summary.NewMean = calculateMean(prices)
+		d.Summary.NewMedian = calculateMedian(prices)
+	}
+
+	return d
+}
+
+func listingsByID(listings []listing) map[string]listing {
+	byID := make(map[string]listing)
+	for _, l := range listings {
+		if l.ID != "" {
+			byID[l.ID] = l
+		}
+	}
+
+	return byID
+}
+
+func percentChange(from, to float64) float64 {
+	if from == 0 {
+		return 0
+	}
+
+	return (to - from) / from * 100
+}
+
+// priceQuartiles are the bounds used to colour listing prices.
+type priceQuartiles struct {
+	p25, p75 float64
+}
+
+func (q priceQuartiles) paint(p painter, price uint64) string {
+	return p.paintPrice(float64(price), q.p25, q.p75, formatPrice(price))
+}
+
+func writeDiffTable(w io.Writer, d listingDiff, q priceQuartiles, p painter) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	s := d.Summary
+	fmt.Fprintf(tw, "added: %d, removed: %d, price changed: %d, unchanged: %d\n", s.Added, s.Removed, s.Changed, s.Unchanged)
+	fmt.Fprintf(tw, "mean:\t%s\t->\t%s\t(%+.1f%%)\n", formatMeanPrice(s.OldMean), formatMeanPrice(s.NewMean), percentChange(s.OldMean, s.NewMean))
+	fmt.Fprintf(tw, "median:\t%s\t->\t%s\t(%+.1f%%)\n", formatMeanPrice(s.OldMedian), formatMeanPrice(s.NewMedian), percentChange(s.OldMedian, s.NewMedian))
+
+	writeDiffListings(tw, "ADDED", d.Added, q, p)
+	writeDiffListings(tw, "REMOVED", d.Removed, q, p)
+
+	if len(d.Changed) > 0 {
+		fmt.Fprintln(tw, "\nPRICE CHANGED")
+		fmt.Fprintf(tw, "ID\t%s\t%s\tCHANGE\tADDRESS\n", p.paint(colorDefault, "OLD"), p.paint(colorDefault, "NEW"))
+		for _, c := range d.Changed {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%+.1f%%\t%s\n", c.ID, q.paint(p, c.OldPrice), q.paint(p, c.NewPrice), c.ChangePercent, c.Address)
+		}
+	}
+
+	return tw.Flush()
+}
+
+func writeDiffListings(w io.Writer, heading string, listings []listing, q priceQuartiles, p painter) {
+	if len(listings) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "\n%s\n", heading)
+	fmt.Fprintf(w, "ID\t%s\tADDRESS\n", p.paint(colorDefault, "PRICE"))
+	for _, l := range listings {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", l.ID, q.paint(p, l.Price), l.Address)
+	}
+}
@@ -0,0 +1,260 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func diffFixture(name string) string {
+	return filepath.Join("testdata", "diff", name)
+}
+
+func TestRunDiffGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		argv   []string
+		golden string
+	}{
+		{"table", []string{"--no-color"}, "table.golden.txt"},
+		{"json", []string{"--format", "json", "--pretty"}, "json.golden.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argv := append([]string{diffFixture("old.json"), diffFixture("new.json")}, tt.argv...)
+			var err error
+			got, _ := captureOutput(t, func() { err = runDiff(argv) })
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := diffFixture(tt.golden)
+			if *updateGoldens {
+				if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("diff differs from %s, rerun with -update if the change is deliberate:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestRunDiffQueryChecks(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, cur string
+		force    bool
+		wantErr  string
+		wantWarn string
+	}{
+		{name: "same query", old: "old.json", cur: "new.json"},
+		{
+			name:    "different query",
+			old:     "old.json",
+			cur:     "other-query.json",
+			wantErr: "were run with different queries, use --force to diff them anyway",
+		},
+		{
+			name:     "different query forced",
+			old:      "old.json",
+			cur:      "other-query.json",
+			force:    true,
+			wantWarn: "warning: testdata/diff/old.json and testdata/diff/other-query.json were run with different queries",
+		},
+		{
+			name:    "old without metadata",
+			old:     "no-metadata.json",
+			cur:     "new.json",
+			wantErr: "testdata/diff/no-metadata.json has no query metadata to check against testdata/diff/new.json, use --force",
+		},
+		{
+			name:    "new without metadata",
+			old:     "old.json",
+			cur:     "no-metadata.json",
+			wantErr: "testdata/diff/no-metadata.json has no query metadata to check against testdata/diff/old.json, use --force",
+		},
+		{
+			name:     "without metadata forced",
+			old:      "no-metadata.json",
+			cur:      "new.json",
+			force:    true,
+			wantWarn: "warning: testdata/diff/no-metadata.json has no query metadata",
+		},
+		{
+			name:    "prices output",
+			old:     "old.json",
+			cur:     "prices.json",
+			wantErr: "diff needs --output-mode listings output",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argv := []string{diffFixture(tt.old), diffFixture(tt.cur), "--no-color", "--format", "json"}
+			if tt.force {
+				argv = append(argv, "--force")
+			}
+
+			var err error
+			stdout, stderr := captureOutput(t, func() { err = runDiff(argv) })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				if stdout != "" {
+					t.Errorf("got output %q after the error", stdout)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if stdout == "" {
+				t.Error("got no diff")
+			}
+			if tt.wantWarn != "" && !strings.Contains(stderr, tt.wantWarn) {
+				t.Errorf("log %q has no %q", stderr, tt.wantWarn)
+			}
+			if tt.wantWarn == "" && strings.Contains(stderr, "warning:") {
+				t.Errorf("got a warning: %q", stderr)
+			}
+		})
+	}
+}
+
+func TestDiffListings(t *testing.T) {
+	l := func(id string, price uint64) listing { return listing{ID: id, Price: price} }
+
+	tests := []struct {
+		name                    string
+		old, cur                []listing
+		added, removed, changed []string
+		unchanged               int
+	}{
+		{name: "empty"},
+		{name: "all added", cur: []listing{l("2", 200), l("1", 100)}, added: []string{"1", "2"}},
+		{name: "all removed", old: []listing{l("1", 100)}, removed: []string{"1"}},
+		{
+			name:      "unchanged",
+			old:       []listing{l("1", 100), l("2", 200)},
+			cur:       []listing{l("2", 200), l("1", 100)},
+			unchanged: 2,
+		},
+		{
+			name:      "mixed",
+			old:       []listing{l("1", 100), l("2", 200), l("3", 300)},
+			cur:       []listing{l("1", 100), l("2", 180), l("4", 400)},
+			added:     []string{"4"},
+			removed:   []string{"3"},
+			changed:   []string{"2"},
+			unchanged: 1,
+		},
+		{
+			name:      "without IDs",
+			old:       []listing{l("", 100), l("1", 100)},
+			cur:       []listing{l("", 200), l("1", 100)},
+			unchanged: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := diffListings(tt.old, tt.cur)
+
+			if got := ids(d.Added); !equalStrings(got, tt.added) {
+				t.Errorf("got added %v, want %v", got, tt.added)
+			}
+			if got := ids(d.Removed); !equalStrings(got, tt.removed) {
+				t.Errorf("got removed %v, want %v", got, tt.removed)
+			}
+			var changed []string
+			for _, c := range d.Changed {
+				changed = append(changed, c.ID)
+			}
+			if !equalStrings(changed, tt.changed) {
+				t.Errorf("got changed %v, want %v", changed, tt.changed)
+			}
+
+			s := d.Summary
+			if s.Added != len(tt.added) || s.Removed != len(tt.removed) || s.Changed != len(tt.changed) || s.Unchanged != tt.unchanged {
+				t.Errorf("got summary %+v", s)
+			}
+		})
+	}
+}
+
+func TestDiffListingsChange(t *testing.T) {
+	d := diffListings(
+		[]listing{{ID: "1", Price: 400000}},
+		[]listing{{ID: "1", Price: 380000, Address: "Acre Lane", URL: "https://example.com/1"}},
+	)
+	if len(d.Changed) != 1 {
+		t.Fatalf("got %d changes, want 1", len(d.Changed))
+	}
+
+	want := listingChange{ID: "1", Address: "Acre Lane", URL: "https://example.com/1", OldPrice: 400000, NewPrice: 380000, ChangePercent: -5}
+	if got := d.Changed[0]; got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if d.Summary.OldMean != 400000 || d.Summary.NewMedian != 380000 {
+		t.Errorf("got summary %+v", d.Summary)
+	}
+}
+
+func TestDiffListingsEmptyJSON(t *testing.T) {
+	data, err := json.Marshal(diffListings(nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Empty sections are arrays rather than null, for consumers that
+	// iterate over them.
+	for _, key := range []string{`"added":[]`, `"removed":[]`, `"price_changed":[]`} {
+		if !strings.Contains(string(data), key) {
+			t.Errorf("%s has no %s", data, key)
+		}
+	}
+}
+
+func TestPercentChange(t *testing.T) {
+	tests := []struct {
+		from, to, want float64
+	}{
+		{100, 110, 10},
+		{100, 90, -10},
+		{100, 100, 0},
+		{0, 100, 0},
+		{400000, 450000, 12.5},
+	}
+
+	for _, tt := range tests {
+		if got := percentChange(tt.from, tt.to); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("percentChange(%v, %v) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,207 @@
+package zoopla
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	smtpTLSStartTLS = "starttls"
+	smtpTLSImplicit = "implicit"
+	smtpTLSNone     = "none"
+
+	// maxEmailAttachmentBytes keeps the CSV attachment under the limits of
+	// most mail servers. Larger outputs are left out of the email.
+	maxEmailAttachmentBytes = 5 << 20
+
+	smtpTimeout = 30 * time.Second
+)
+
+var smtpTLSModes = []string{smtpTLSStartTLS, smtpTLSImplicit, smtpTLSNone}
+
+// sendEmail emails the report to args.EmailTo as plain text and HTML, with
+// the listings attached as CSV if they're small enough.
+func sendEmail(args *cliArgs, report *reportData, listings []listing) error {
+	htmlBody, err := renderHTMLReport(report)
+	if err != nil {
+		return err
+	}
+
+	attachment, err := marshalCSV(listings)
+	if err != nil {
+		return err
+	}
+	if len(attachment) > maxEmailAttachmentBytes {
+		attachment = nil
+	}
+
+	from := args.EmailFrom
+	if from == "" {
+		from = args.SMTPUser
+	}
+	to := splitAddresses(args.EmailTo)
+
+	msg, err := buildEmail(from, to, "Zoopla prices for "+args.Postcode, renderMarkdown(report), htmlBody, attachment)
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(deliverEmail(args, from, to, msg), "while sending email")
+}
+
+func splitAddresses(s string) []string {
+	var addrs []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addrs = append(addrs, a)
+		}
+	}
+
+	return addrs
+}
+
+// buildEmail builds a multipart/mixed message holding a multipart/alternative
+// text and HTML body, followed by the CSV attachment if there is one.
+func buildEmail(from string, to []string, subject, text string, htmlBody, csvData []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	mixed := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", timeNow().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mixed.Boundary())
+
+	var alt bytes.Buffer
+	altWriter := multipart.NewWriter(&alt)
+	if err := writeEmailPart(altWriter, "text/plain; charset=utf-8", "", []byte(text)); err != nil {
+		return nil, err
+	}
+	if err := writeEmailPart(altWriter, "text/html; charset=utf-8", "", htmlBody); err != nil {
+		return nil, err
+	}
+	if err := altWriter.Close(); err != nil {
+		return nil, errors.Wrap(err, "while building email body")
+	}
+
+	altHeader := textproto.MIMEHeader{}
+	altHeader.Set("Content-Type", "multipart/alternative; boundary="+altWriter.Boundary())
+	part, err := mixed.CreatePart(altHeader)
+	if err != nil {
+		return nil, errors.Wrap(err, "while building email body")
+	}
+	if _, err := part.Write(alt.Bytes()); err != nil {
+		return nil, errors.Wrap(err, "while building email body")
+	}
+
+	if csvData != nil {
+		if err := writeEmailPart(mixed, "text/csv; charset=utf-8", "prices.csv", csvData); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mixed.Close(); err != nil {
+		return nil, errors.Wrap(err, "while building email")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeEmailPart writes a base64-encoded part, as an attachment if filename
+// is set.
+func writeEmailPart(w *multipart.Writer, contentType, filename string, data []byte) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "base64")
+	if filename != "" {
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return errors.Wrap(err, "while building email part")
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := fmt.Fprintf(part, "%s\r\n", encoded[:76]); err != nil {
+			return errors.Wrap(err, "while building email part")
+		}
+		encoded = encoded[76:]
+	}
+	_, err = fmt.Fprintf(part, "%s\r\n", encoded)
+	return errors.Wrap(err, "while building email part")
+}
+
+func deliverEmail(args *cliArgs, from string, to []string, msg []byte) error {
+	host, _, err := net.SplitHostPort(args.SMTPHost)
+	if err != nil {
+		return errors.Wrapf(err, "while parsing SMTP host %q, expected host:port", args.SMTPHost)
+	}
+	tlsConfig := &tls.Config{ServerName: host}
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	if args.SMTPTLS == smtpTLSImplicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", args.SMTPHost, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", args.SMTPHost)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "while connecting to %s", args.SMTPHost)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "while starting SMTP session")
+	}
+	defer c.Close()
+
+	if args.SMTPTLS == smtpTLSStartTLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return errors.Wrap(err, "while starting TLS")
+		}
+	}
+
+	if args.SMTPUser != "" {
+		if err := c.Auth(smtp.PlainAuth("", args.SMTPUser, args.SMTPPass, host)); err != nil {
+			return errors.Wrap(err, "while authenticating")
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return errors.Wrap(err, "while setting sender")
+	}
+	for _, addr := range to {
+		if err := c.Rcpt(addr); err != nil {
+			return errors.Wrapf(err, "while adding recipient %s", addr)
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return errors.Wrap(err, "while starting message")
+	}
+	if _, err := w.Write(msg); err != nil {
+		return errors.Wrap(err, "while writing message")
+	}
+	if err := w.Close(); err != nil {
+		return errors.Wrap(err, "while finishing message")
+	}
+
+	return c.Quit()
+}
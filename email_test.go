@@ -0,0 +1,313 @@
+package zoopla
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitAddresses(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"a@example.com", []string{"a@example.com"}},
+		{" a@example.com , b@example.com,, ", []string{"a@example.com", "b@example.com"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitAddresses(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitAddresses(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// emailPart is a decoded MIME part.
+type emailPart struct {
+	contentType string
+	filename    string
+	body        string
+}
+
+// readEmailParts parses msg, returning its headers and leaf parts in order.
+func readEmailParts(t *testing.T, msg []byte) (mail.Header, []emailPart) {
+	t.Helper()
+
+	m, err := mail.ReadMessage(bytes.NewReader(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parts []emailPart
+	var walk func(contentType string, r io.Reader)
+	walk = func(contentType string, r io.Reader) {
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(mediaType, "multipart/") {
+			t.Fatalf("unexpected %s part", mediaType)
+		}
+
+		mr := multipart.NewReader(r, params["boundary"])
+		for {
+			p, err := mr.NextPart()
+			if err == io.EOF {
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			ct := p.Header.Get("Content-Type")
+			if strings.HasPrefix(ct, "multipart/") {
+				walk(ct, p)
+				continue
+			}
+
+			var body []byte
+			if p.Header.Get("Content-Transfer-Encoding") == "base64" {
+				body, err = ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, p))
+			} else {
+				body, err = ioutil.ReadAll(p)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts = append(parts, emailPart{contentType: ct, filename: p.FileName(), body: string(body)})
+		}
+	}
+	walk(m.Header.Get("Content-Type"), m.Body)
+
+	return m.Header, parts
+}
+
+func TestBuildEmail(t *testing.T) {
+	setNow(t, date(2021, time.April, 1))
+
+	tests := []struct {
+		name      string
+		csvData   []byte
+		wantParts []emailPart
+	}{
+		{
+			name: "without attachment",
+			wantParts: []emailPart{
+				{contentType: "text/plain; charset=utf-8", body: "# Report"},
+				{contentType: "text/html; charset=utf-8", body: "<h1>Report</h1>"},
+			},
+		},
+		{
+			name:    "with attachment",
+			csvData: []byte("price\n450000\n"),
+			wantParts: []emailPart{
+				{contentType: "text/plain; charset=utf-8", body: "# Report"},
+				{contentType: "text/html; charset=utf-8", body: "<h1>Report</h1>"},
+				{contentType: "text/csv; charset=utf-8", filename: "prices.csv", body: "price\n450000\n"},
+			},
+		},
+		{
+			name:    "long attachment",
+			csvData: []byte(strings.Repeat("450000,", 100)),
+			wantParts: []emailPart{
+				{contentType: "text/plain; charset=utf-8", body: "# Report"},
+				{contentType: "text/html; charset=utf-8", body: "<h1>Report</h1>"},
+				{contentType: "text/csv; charset=utf-8", filename: "prices.csv", body: strings.Repeat("450000,", 100)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := buildEmail("me@example.com", []string{"a@example.com", "b@example.com"}, "Zoopla prices for SW2 – £", "# Report", []byte("<h1>Report</h1>"), tt.csvData)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, line := range strings.Split(string(msg), "\r\n") {
+				if len(line) > 998 {
+					t.Fatalf("line is %d long, over SMTP's limit", len(line))
+				}
+			}
+
+			header, parts := readEmailParts(t, msg)
+			if got := header.Get("From"); got != "me@example.com" {
+				t.Errorf("From = %q", got)
+			}
+			if got := header.Get("To"); got != "a@example.com, b@example.com" {
+				t.Errorf("To = %q", got)
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject"))
+			if err != nil || subject != "Zoopla prices for SW2 – £" {
+				t.Errorf("Subject = %q (%v)", subject, err)
+			}
+			if got := header.Get("MIME-Version"); got != "1.0" {
+				t.Errorf("MIME-Version = %q", got)
+			}
+			if d, err := header.Date(); err != nil || !d.Equal(date(2021, time.April, 1)) {
+				t.Errorf("Date = %v (%v)", d, err)
+			}
+
+			if !reflect.DeepEqual(parts, tt.wantParts) {
+				t.Errorf("parts = %+v, want %+v", parts, tt.wantParts)
+			}
+		})
+	}
+}
+
+// setEnv sets an environment variable for the rest of the test.
+func setEnv(t *testing.T, key, value string) {
+	t.Helper()
+
+	old, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// smtpDelivery is a message the fake SMTP server received.
+type smtpDelivery struct {
+	from string
+	to   []string
+	data []byte
+}
+
+// fakeSMTPServer accepts one message without TLS or auth, sending what it
+// received on the returned channel.
+func fakeSMTPServer(t *testing.T) (string, <-chan smtpDelivery) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	deliveries := make(chan smtpDelivery, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ESMTP")
+		var d smtpDelivery
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+
+			switch cmd := strings.ToUpper(strings.Fields(line)[0]); cmd {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 localhost")
+			case "MAIL":
+				d.from = strings.Trim(strings.TrimPrefix(line, "MAIL FROM:"), "<>")
+				tp.PrintfLine("250 OK")
+			case "RCPT":
+				d.to = append(d.to, strings.Trim(strings.TrimPrefix(line, "RCPT TO:"), "<>"))
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				d.data, err = ioutil.ReadAll(tp.DotReader())
+				if err != nil {
+					return
+				}
+				tp.PrintfLine("250 OK")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				deliveries <- d
+				return
+			default:
+				tp.PrintfLine("502 %s not implemented", cmd)
+			}
+		}
+	}()
+
+	return ln.Addr().String(), deliveries
+}
+
+func TestSendEmail(t *testing.T) {
+	setNow(t, date(2021, time.April, 1))
+	addr, deliveries := fakeSMTPServer(t)
+
+	listings := reportListings()
+	args := reportArgs()
+	args.SMTPHost = addr
+	args.SMTPTLS = smtpTLSNone
+	args.EmailFrom = "me@example.com"
+	args.EmailTo = "a@example.com,b@example.com"
+
+	if err := sendEmail(args, buildReport(listings, listingPrices(listings), args), listings); err != nil {
+		t.Fatal(err)
+	}
+
+	d := <-deliveries
+	if d.from != "me@example.com" {
+		t.Errorf("MAIL FROM = %q", d.from)
+	}
+	if !reflect.DeepEqual(d.to, []string{"a@example.com", "b@example.com"}) {
+		t.Errorf("RCPT TO = %q", d.to)
+	}
+
+	_, parts := readEmailParts(t, d.data)
+	if len(parts) != 3 {
+		t.Fatalf("got %d parts, want text, HTML and CSV", len(parts))
+	}
+	if !strings.HasPrefix(parts[0].body, "# Price report for SW2") {
+		t.Errorf("text part = %q", parts[0].body)
+	}
+	if !strings.Contains(parts[1].body, "<h1>Price report for SW2</h1>") {
+		t.Error("HTML part is missing the report")
+	}
+	if parts[2].filename != "prices.csv" || strings.Count(parts[2].body, "\n") != len(listings)+1 {
+		t.Errorf("attachment %q has %d lines, want a header and %d listings", parts[2].filename, strings.Count(parts[2].body, "\n"), len(listings))
+	}
+}
+
+func TestSendEmailStartTLSUnsupported(t *testing.T) {
+	addr, _ := fakeSMTPServer(t)
+
+	args := reportArgs()
+	args.SMTPHost = addr
+	args.SMTPTLS = smtpTLSStartTLS
+	args.EmailTo = "a@example.com"
+
+	// The fake server doesn't offer STARTTLS, which mustn't fall back to
+	// sending in the clear.
+	if err := sendEmail(args, buildReport(nil, nil, args), nil); err == nil {
+		t.Error("sendEmail() succeeded without STARTTLS")
+	}
+}
+
+func TestSMTPCredentialsFromEnv(t *testing.T) {
+	setEnv(t, "SMTP_HOST", "smtp.example.com:587")
+	setEnv(t, "SMTP_USER", "user")
+	setEnv(t, "SMTP_PASS", "pass")
+
+	args := testSearchArgs(t, "--postcode", "sw2", "--email-to", "a@example.com")
+	if args.SMTPHost != "smtp.example.com:587" || args.SMTPUser != "user" || args.SMTPPass != "pass" {
+		t.Errorf("SMTP settings = %q, %q, %q, want them from the environment", args.SMTPHost, args.SMTPUser, args.SMTPPass)
+	}
+	if args.SMTPTLS != smtpTLSStartTLS {
+		t.Errorf("SMTPTLS = %q, want %q by default", args.SMTPTLS, smtpTLSStartTLS)
+	}
+}
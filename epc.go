@@ -0,0 +1,78 @@
+package zoopla
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const unknownEPCBand = "unknown"
+
+var (
+	epcBands = []string{"A", "B", "C", "D", "E", "F", "G"}
+
+	epcRegexp = regexp.MustCompile(`(?i)\b(?:EPC|energy(?: efficiency)?)\s+(?:rating|band)\s*(?::|is|of)?\s*([A-G])\b`)
+)
+
+// parseEPCBand looks for a stated EPC rating such as "EPC Rating: C" in the
+// page text, falling back to the alt text of the EPC graph image.
+func parseEPCBand(root *html.Node) string {
+	if m := epcRegexp.FindStringSubmatch(joinedText(root)); m != nil {
+		return strings.ToUpper(m[1])
+	}
+
+	img := findFirst(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.Data != "img" {
+			return false
+		}
+		alt, _ := getAttr(n, "alt")
+		return epcRegexp.MatchString(alt)
+	})
+	if img != nil {
+		alt, _ := getAttr(img, "alt")
+		return strings.ToUpper(epcRegexp.FindStringSubmatch(alt)[1])
+	}
+
+	return ""
+}
+
+type epcBandStats struct {
+	band      string
+	count     int
+	meanPrice float64
+}
+
+// calculateEPCStats returns one entry per EPC band in order A to G, followed
+// by the listings with no known rating.
+func calculateEPCStats(listings []listing) []epcBandStats {
+	bandPrices := make(map[string][]uint64)
+	for i := range listings {
+		band := listings[i].EPCBand
+		if band == "" {
+			band = unknownEPCBand
+		}
+		bandPrices[band] = append(bandPrices[band], listings[i].Price)
+	}
+
+	var stats []epcBandStats
+	for _, band := range append(epcBands, unknownEPCBand) {
+		prices := bandPrices[band]
+		s := epcBandStats{band: band, count: len(prices)}
+		if len(prices) > 0 {
+			s.meanPrice = calculateMean(prices)
+		}
+		stats = append(stats, s)
+	}
+
+	return stats
+}
+
+func (s epcBandStats) String() string {
+	if s.count == 0 {
+		return fmt.Sprintf("%s: count = 0", s.band)
+	}
+
+	return fmt.Sprintf("%s: count = %d, mean price = %.0f", s.band, s.count, s.meanPrice)
+}
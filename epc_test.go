@@ -0,0 +1,56 @@
+package zoopla
+
+import "testing"
+
+func TestParseEPCBand(t *testing.T) {
+	tests := []struct {
+		page string
+		want string
+	}{
+		{`<p>EPC Rating: C</p>`, "C"},
+		{`<p>EPC rating is b</p>`, "B"},
+		{`<li>Energy efficiency rating of D</li>`, "D"},
+		{`<dl><dt>EPC Rating</dt><dd>E</dd></dl>`, "E"},
+		{`<img src="/epc.png" alt="Energy rating F">`, "F"},
+		{`<p>EPC rating: H</p>`, ""},
+		{`<p>Grade A listed building</p>`, ""},
+	}
+
+	for _, tt := range tests {
+		if got := parseEPCBand(mustParseHTML(t, tt.page)); got != tt.want {
+			t.Errorf("parseEPCBand(%s) = %q, want %q", tt.page, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateEPCStats(t *testing.T) {
+	listings := []listing{
+		{Price: 300000, EPCBand: "C"},
+		{Price: 500000, EPCBand: "C"},
+		{Price: 450000, EPCBand: "A"},
+		{Price: 200000},
+	}
+
+	stats := calculateEPCStats(listings)
+	if len(stats) != len(epcBands)+1 {
+		t.Fatalf("got %d bands, want %d", len(stats), len(epcBands)+1)
+	}
+
+	want := map[string]epcBandStats{
+		"A":            {band: "A", count: 1, meanPrice: 450000},
+		"B":            {band: "B"},
+		"C":            {band: "C", count: 2, meanPrice: 400000},
+		unknownEPCBand: {band: unknownEPCBand, count: 1, meanPrice: 200000},
+	}
+	for i, s := range stats {
+		if i < len(epcBands) && s.band != epcBands[i] {
+			t.Errorf("band %d = %s, want %s", i, s.band, epcBands[i])
+		}
+		if w, ok := want[s.band]; ok && s != w {
+			t.Errorf("band %s = %+v, want %+v", s.band, s, w)
+		}
+	}
+	if last := stats[len(stats)-1]; last.band != unknownEPCBand {
+		t.Errorf("last band = %s, want %s", last.band, unknownEPCBand)
+	}
+}
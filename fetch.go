@@ -0,0 +1,280 @@
+package zoopla
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultDelay   = time.Second
+	defaultRetries = 2
+	defaultBackoff = time.Second
+)
+
+// fetcher makes all HTTP requests for a run. Requests are spaced at least
+// delay apart, even when made from several goroutines, to stay polite to the
+// site.
+type fetcher struct {
+	client  *http.Client
+	delay   time.Duration
+	retries int
+	// backoff is the wait before the first retry, doubling after each,
+	// unless the server gives a Retry-After.
+	backoff time.Duration
+	// userAgent replaces Go's default User-Agent header if set.
+	userAgent string
+
+	mu        sync.Mutex
+	lastFetch time.Time
+
+	// cache holds the bodies of the pages fetched so far, so a page needed
+	// twice in a run, such as the detail page of a listing found under
+	// several postcodes, is only requested once. Caching is off while it's
+	// nil.
+	cacheMu sync.Mutex
+	cache   map[string][]byte
+
+	statsMu sync.Mutex
+	stats   fetchStats
+}
+
+// fetchStats counts the HTTP requests a fetcher made, for the run manifest.
+type fetchStats struct {
+	Requests int            `json:"requests"`
+	Retries  int            `json:"retries"`
+	Errors   int            `json:"errors"`
+	Statuses map[string]int `json:"statuses"`
+
+	// URLs are the final URLs requested, after any redirects.
+	URLs []string `json:"urls"`
+}
+
+func newFetcher(args *cliArgs) *fetcher {
+	return &fetcher{
+		client:  http.DefaultClient,
+		delay:   args.Delay,
+		retries: args.Retries,
+		backoff: defaultBackoff,
+		cache:   make(map[string][]byte),
+	}
+}
+
+type statusError struct {
+	code   int
+	status string
+
+	// retryAfter is how long the server asked us to wait, if it said.
+	retryAfter time.Duration
+}
+
+func newStatusError(rsp *http.Response) *statusError {
+	return &statusError{code: rsp.StatusCode, status: rsp.Status, retryAfter: retryAfter(rsp.Header)}
+}
+
+func (e *statusError) Error() string {
+	return "unexpected status " + e.status
+}
+
+// retryAfter returns the wait asked for by a Retry-After header, given in
+// seconds or as a date, or zero if there's none.
+func retryAfter(header http.Header) time.Duration {
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(time.Now()) {
+		return time.Until(t)
+	}
+
+	return 0
+}
+
+func isRetryable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code == http.StatusTooManyRequests || statusErr.code >= 500
+	}
+
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+func (f *fetcher) getHTML(ctx context.Context, pageUrl *url.URL) (*html.Node, error) {
+	var doc *html.Node
+	err := f.fetch(ctx, pageUrl, func(body io.Reader) error {
+		var err error
+		doc, err = html.Parse(body)
+		return errors.Wrap(err, "while parsing as HTML")
+	})
+
+	return doc, err
+}
+
+// fetch requests pageUrl, retrying transient failures with exponential
+// backoff or after the server's Retry-After, and passes the body of a
+// successful response to handle. A page already in the cache isn't
+// requested again.
+func (f *fetcher) fetch(ctx context.Context, pageUrl *url.URL, handle func(body io.Reader) error) error {
+	if body, ok := f.cached(pageUrl); ok {
+		return handle(bytes.NewReader(body))
+	}
+
+	backoff := f.backoff
+	var err error
+	for attempt := 0; attempt <= f.retries; attempt++ {
+		if attempt > 0 {
+			wait := backoff
+			var statusErr *statusError
+			if errors.As(err, &statusErr) && statusErr.retryAfter > 0 {
+				wait = statusErr.retryAfter
+			}
+			log.Printf("retrying %s in %v (attempt %d): %v", pageUrl, wait, attempt+1, err)
+			f.record(func(s *fetchStats) { s.Retries++ })
+
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return errors.Wrapf(ctx.Err(), "while waiting to retry %s", pageUrl)
+			}
+			backoff *= 2
+		}
+
+		err = f.tryFetch(ctx, pageUrl, handle)
+		if err == nil {
+			return nil
+		}
+
+		if !isRetryable(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (f *fetcher) tryFetch(ctx context.Context, pageUrl *url.URL, handle func(body io.Reader) error) error {
+	if err := f.wait(ctx); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageUrl.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, "while building HTTP request")
+	}
+	if f.userAgent != "" {
+		req.Header.Set("User-Agent", f.userAgent)
+	}
+
+	rsp, err := f.client.Do(req)
+	if err != nil {
+		f.record(func(s *fetchStats) {
+			s.Requests++
+			s.Errors++
+			s.URLs = append(s.URLs, pageUrl.String())
+		})
+		return errors.Wrapf(err, "while making HTTP request to %s", pageUrl)
+	}
+	defer rsp.Body.Close()
+
+	f.record(func(s *fetchStats) {
+		s.Requests++
+		if s.Statuses == nil {
+			s.Statuses = make(map[string]int)
+		}
+		s.Statuses[strconv.Itoa(rsp.StatusCode)]++
+		s.URLs = append(s.URLs, rsp.Request.URL.String())
+	})
+
+	if rsp.StatusCode != http.StatusOK {
+		return newStatusError(rsp)
+	}
+
+	if f.cache == nil {
+		return handle(rsp.Body)
+	}
+
+	body, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return errors.Wrapf(err, "while reading response from %s", pageUrl)
+	}
+	if err := handle(bytes.NewReader(body)); err != nil {
+		return err
+	}
+	f.store(pageUrl, body)
+
+	return nil
+}
+
+func (f *fetcher) cached(pageUrl *url.URL) ([]byte, bool) {
+	f.cacheMu.Lock()
+	defer f.cacheMu.Unlock()
+
+	body, ok := f.cache[pageUrl.String()]
+	return body, ok
+}
+
+func (f *fetcher) store(pageUrl *url.URL, body []byte) {
+	f.cacheMu.Lock()
+	defer f.cacheMu.Unlock()
+
+	if f.cache != nil {
+		f.cache[pageUrl.String()] = body
+	}
+}
+
+func (f *fetcher) record(update func(s *fetchStats)) {
+	f.statsMu.Lock()
+	defer f.statsMu.Unlock()
+
+	update(&f.stats)
+}
+
+// requestStats returns a copy of the stats for the requests made so far.
+func (f *fetcher) requestStats() fetchStats {
+	f.statsMu.Lock()
+	defer f.statsMu.Unlock()
+
+	s := f.stats
+	s.Statuses = make(map[string]int, len(f.stats.Statuses))
+	for k, v := range f.stats.Statuses {
+		s.Statuses[k] = v
+	}
+	s.URLs = append([]string(nil), f.stats.URLs...)
+
+	return s
+}
+
+func (f *fetcher) wait(ctx context.Context) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if next := f.lastFetch.Add(f.delay); time.Now().Before(next) {
+		timer := time.NewTimer(time.Until(next))
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	f.lastFetch = time.Now()
+	return nil
+}
@@ -0,0 +1,451 @@
+package zoopla
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ryanc414/zoopla-analyzer/internal/testserver"
+)
+
+// searchPage is a results page with a card for each price.
+func searchPage(prices ...uint64) string {
+	var sb strings.Builder
+	sb.WriteString(`<html><body><div class="ListingsContainer">`)
+	for _, p := range prices {
+		fmt.Fprintf(&sb, `<div data-testid="search-result"><h2>2 bed flat for sale</h2>`+
+			`<div class="PriceContainer"><p>%s</p></div><a href="/for-sale/details/%d/">Details</a></div>`, formatPrice(p), p)
+	}
+	sb.WriteString(`</div></body></html>`)
+
+	return sb.String()
+}
+
+func testServerArgs(srv *testserver.Server) *cliArgs {
+	return &cliArgs{BaseURL: srv.SearchURL(), Postcode: "sw2"}
+}
+
+func requestedPages(srv *testserver.Server) []int {
+	var pages []int
+	for _, r := range srv.Requests() {
+		pages = append(pages, r.Page)
+	}
+
+	return pages
+}
+
+func TestGetAllListingsPagination(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, searchPage(400000, 450000))
+	srv.SetPage(2, searchPage(500000))
+
+	args := testServerArgs(srv)
+	var pages int
+	listings, err := getAllListings(context.Background(), &fetcher{client: srv.Client()}, newDiagnostics(args), args, func([]listing) error {
+		pages++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := listingPrices(listings), []uint64{400000, 450000, 500000}; !equalPrices(got, want) {
+		t.Errorf("got prices %v, want %v", got, want)
+	}
+	if pages != 2 {
+		t.Errorf("onPage called %d times, want 2", pages)
+	}
+	if got, want := requestedPages(srv), []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("requested pages %v, want %v", got, want)
+	}
+	if got := listings[0].URL; !strings.HasPrefix(got, srv.URL+"/for-sale/details/") {
+		t.Errorf("listing URL %q not resolved against the page", got)
+	}
+}
+
+func TestGetAllListingsStopsOnPageError(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, searchPage(400000))
+	srv.SetPage(2, searchPage(450000))
+	srv.InjectStatus(2, http.StatusNotFound, 1)
+
+	args := testServerArgs(srv)
+	_, err := getAllListings(context.Background(), &fetcher{client: srv.Client()}, newDiagnostics(args), args, nil)
+
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) || statusErr.code != http.StatusNotFound {
+		t.Fatalf("got error %v, want a 404", err)
+	}
+	if got, want := requestedPages(srv), []int{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("requested pages %v, want %v", got, want)
+	}
+}
+
+func TestFetchRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		failures     int
+		wantErr      bool
+		wantRequests int
+	}{
+		{name: "recovers from server errors", status: http.StatusServiceUnavailable, failures: 2, wantRequests: 3},
+		{name: "recovers from rate limiting", status: http.StatusTooManyRequests, failures: 1, wantRequests: 2},
+		{name: "gives up after the retries", status: http.StatusBadGateway, failures: 3, wantErr: true, wantRequests: 3},
+		{name: "client errors not retried", status: http.StatusNotFound, failures: 1, wantErr: true, wantRequests: 1},
+		{name: "challenge not retried", status: http.StatusForbidden, failures: 1, wantErr: true, wantRequests: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testserver.New()
+			defer srv.Close()
+			srv.SetPage(1, searchPage(400000))
+			if tt.status == http.StatusForbidden {
+				srv.InjectChallenge(1, tt.failures)
+			} else {
+				srv.InjectStatus(1, tt.status, tt.failures)
+			}
+
+			f := &fetcher{client: srv.Client(), retries: 2}
+			args := testServerArgs(srv)
+			listings, err := getListingsPage(context.Background(), f, newDiagnostics(args), args, 1)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %d listings, want an error", len(listings))
+				}
+			} else if err != nil || len(listings) != 1 {
+				t.Errorf("got %d listings, %v, want 1", len(listings), err)
+			}
+
+			if got := len(srv.Requests()); got != tt.wantRequests {
+				t.Errorf("made %d requests, want %d", got, tt.wantRequests)
+			}
+			if s := f.requestStats(); s.Requests != tt.wantRequests || s.Retries != tt.wantRequests-1 {
+				t.Errorf("stats = %+v, want %d requests", s, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestFetchFollowsRedirects(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(2, searchPage(400000))
+	srv.InjectRedirect(1, srv.SearchURL()+"/sw2?pn=2", 1)
+
+	f := &fetcher{client: srv.Client()}
+	args := testServerArgs(srv)
+	listings, err := getListingsPage(context.Background(), f, newDiagnostics(args), args, 1)
+	if err != nil || len(listings) != 1 {
+		t.Fatalf("got %d listings, %v, want 1", len(listings), err)
+	}
+
+	if s := f.requestStats(); len(s.URLs) != 1 || !strings.HasSuffix(s.URLs[0], "pn=2") {
+		t.Errorf("recorded URLs %v, want the redirect's target", s.URLs)
+	}
+}
+
+func TestFetchRateLimit(t *testing.T) {
+	const delay = 50 * time.Millisecond
+
+	srv := testserver.New()
+	defer srv.Close()
+	for pn := 1; pn <= 3; pn++ {
+		srv.SetPage(pn, searchPage(400000))
+	}
+	srv.InjectStatus(2, http.StatusServiceUnavailable, 1)
+
+	args := testServerArgs(srv)
+	f := &fetcher{client: srv.Client(), delay: delay, retries: 1, userAgent: "zoopla-analyzer-test"}
+	if _, err := getAllListings(context.Background(), f, newDiagnostics(args), args, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pages 1, 2 twice, 3 and the empty page 4, with the retry kept to the
+	// delay like any other request.
+	requests := srv.Requests()
+	if len(requests) != 5 {
+		t.Fatalf("made %d requests, want 5", len(requests))
+	}
+	for i, r := range requests {
+		if got := r.Header.Get("User-Agent"); got != "zoopla-analyzer-test" {
+			t.Errorf("request %d sent User-Agent %q", i, got)
+		}
+		if i == 0 {
+			continue
+		}
+		// Allow for the time between the fetcher's clock and the server's.
+		if gap := r.Time.Sub(requests[i-1].Time); gap < delay-5*time.Millisecond {
+			t.Errorf("request %d came %v after the last, want at least %v", i, gap, delay)
+		}
+	}
+}
+
+func TestFetchRateLimitConcurrent(t *testing.T) {
+	const delay = 30 * time.Millisecond
+
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, searchPage(400000))
+	pageURL, err := url.Parse(srv.SearchURL() + "/sw2?pn=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fetcher{client: srv.Client(), delay: delay}
+	errs := make(chan error)
+	for i := 0; i < 4; i++ {
+		go func() {
+			_, err := f.getHTML(context.Background(), pageURL)
+			errs <- err
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	requests := srv.Requests()
+	if total := requests[len(requests)-1].Time.Sub(requests[0].Time); total < 3*delay-5*time.Millisecond {
+		t.Errorf("4 concurrent requests took %v, want at least %v", total, 3*delay)
+	}
+}
+
+func TestFetchCancellation(t *testing.T) {
+	t.Run("during a slow response", func(t *testing.T) {
+		srv := testserver.New()
+		defer srv.Close()
+		srv.SetPage(1, searchPage(400000))
+		srv.InjectDelay(1, 5*time.Second, 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		f := &fetcher{client: srv.Client(), retries: 2}
+		args := testServerArgs(srv)
+		start := time.Now()
+		_, err := getAllListings(ctx, f, newDiagnostics(args), args, nil)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got error %v, want the deadline", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("took %v to give up", elapsed)
+		}
+		if got := len(srv.Requests()); got != 1 {
+			t.Errorf("made %d requests, want no retries after cancelling", got)
+		}
+	})
+
+	t.Run("while waiting for the delay", func(t *testing.T) {
+		srv := testserver.New()
+		defer srv.Close()
+		srv.SetPage(1, searchPage(400000))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		args := testServerArgs(srv)
+		f := &fetcher{client: srv.Client(), delay: 5 * time.Second}
+		_, err := getAllListings(ctx, f, newDiagnostics(args), args, func([]listing) error {
+			cancel()
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got error %v, want cancelled", err)
+		}
+		if got, want := requestedPages(srv), []int{1}; !reflect.DeepEqual(got, want) {
+			t.Errorf("requested pages %v, want %v", got, want)
+		}
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"none", "", 0},
+		{"seconds", "3", 3 * time.Second},
+		{"past date", "Wed, 21 Oct 2015 07:28:00 GMT", 0},
+		{"junk", "soon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := make(http.Header)
+			if tt.value != "" {
+				header.Set("Retry-After", tt.value)
+			}
+			if got := retryAfter(header); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	header := http.Header{"Retry-After": {time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)}}
+	if got := retryAfter(header); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("got %v for a date an hour away", got)
+	}
+}
+
+// failingServer fails the first failures requests with status, setting the
+// given headers, and records when each request came.
+func failingServer(status, failures int, header http.Header) (*httptest.Server, func() []time.Time) {
+	var mu sync.Mutex
+	var times []time.Time
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		n := len(times)
+		mu.Unlock()
+
+		if n <= failures {
+			for k, vs := range header {
+				w.Header()[k] = vs
+			}
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	return srv, func() []time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return append([]time.Time(nil), times...)
+	}
+}
+
+func fetchBody(f *fetcher, u string) (string, error) {
+	pageURL, err := url.Parse(u)
+	if err != nil {
+		return "", err
+	}
+
+	var body string
+	err = f.fetch(context.Background(), pageURL, func(r io.Reader) error {
+		data, err := ioutil.ReadAll(r)
+		body = string(data)
+		return err
+	})
+
+	return body, err
+}
+
+func TestFetchBackoff(t *testing.T) {
+	const backoff = 30 * time.Millisecond
+
+	srv, requestTimes := failingServer(http.StatusServiceUnavailable, 2, nil)
+	defer srv.Close()
+
+	f := &fetcher{client: srv.Client(), retries: 2, backoff: backoff}
+	if body, err := fetchBody(f, srv.URL); err != nil || body != "ok" {
+		t.Fatalf("got %q, %v", body, err)
+	}
+
+	times := requestTimes()
+	if len(times) != 3 {
+		t.Fatalf("made %d requests, want 3", len(times))
+	}
+	// Allow for the time between the fetcher's clock and the server's.
+	for i, want := range []time.Duration{backoff, 2 * backoff} {
+		if gap := times[i+1].Sub(times[i]); gap < want-5*time.Millisecond {
+			t.Errorf("retry %d came %v after the last request, want at least %v", i+1, gap, want)
+		}
+	}
+}
+
+func TestFetchRetryAfter(t *testing.T) {
+	srv, requestTimes := failingServer(http.StatusTooManyRequests, 1, http.Header{"Retry-After": {"1"}})
+	defer srv.Close()
+
+	// The server's wait is used in place of the much shorter backoff.
+	f := &fetcher{client: srv.Client(), retries: 1, backoff: time.Millisecond}
+	if _, err := fetchBody(f, srv.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	times := requestTimes()
+	if len(times) != 2 {
+		t.Fatalf("made %d requests, want 2", len(times))
+	}
+	if gap := times[1].Sub(times[0]); gap < time.Second-5*time.Millisecond {
+		t.Errorf("retried after %v, want the 1s the server asked for", gap)
+	}
+}
+
+func TestFetchRetryCancelled(t *testing.T) {
+	srv, requestTimes := failingServer(http.StatusTooManyRequests, 1, http.Header{"Retry-After": {"60"}})
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	pageURL, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fetcher{client: srv.Client(), retries: 1}
+	err = f.fetch(ctx, pageURL, func(io.Reader) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want the deadline", err)
+	}
+	if times := requestTimes(); len(times) != 1 {
+		t.Errorf("made %d requests, want no retry after cancelling", len(times))
+	}
+}
+
+func TestFetchCache(t *testing.T) {
+	tests := []struct {
+		name         string
+		cache        map[string][]byte
+		wantRequests int
+	}{
+		{"cached", make(map[string][]byte), 2},
+		{"no cache", nil, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The failed first request isn't cached.
+			srv, requestTimes := failingServer(http.StatusNotFound, 1, nil)
+			defer srv.Close()
+
+			f := &fetcher{client: srv.Client(), cache: tt.cache}
+			if _, err := fetchBody(f, srv.URL); err == nil {
+				t.Fatal("got no error from a 404")
+			}
+			for i := 0; i < 2; i++ {
+				if body, err := fetchBody(f, srv.URL); err != nil || body != "ok" {
+					t.Fatalf("fetch %d: got %q, %v", i, body, err)
+				}
+			}
+
+			if times := requestTimes(); len(times) != tt.wantRequests {
+				t.Errorf("made %d requests, want %d", len(times), tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestNewFetcherCaches(t *testing.T) {
+	f := newFetcher(&cliArgs{Delay: defaultDelay, Retries: defaultRetries})
+	if f.cache == nil || f.backoff != defaultBackoff {
+		t.Errorf("got cache %v, backoff %v", f.cache, f.backoff)
+	}
+}
@@ -0,0 +1,76 @@
+package zoopla
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var filenamePlaceholderRegexp = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// filenamePlaceholders are the names that may appear in braces in output
+// filenames, such as "out/{postcode}-{date}.json".
+var filenamePlaceholders = []string{"postcode", "date", "time", "beds"}
+
+// validateFilenameTemplate checks that every placeholder in filename is known
+// and that its braces are balanced.
+func validateFilenameTemplate(filename string) error {
+	for _, m := range filenamePlaceholderRegexp.FindAllStringSubmatch(filename, -1) {
+		if !containsString(filenamePlaceholders, m[1]) {
+			return errors.Errorf("unknown placeholder {%s} in %q, must be one of %s", m[1], filename, strings.Join(filenamePlaceholders, ", "))
+		}
+	}
+
+	if rest := filenamePlaceholderRegexp.ReplaceAllString(filename, ""); strings.ContainsAny(rest, "{}") {
+		return errors.Errorf("unbalanced braces in %q", filename)
+	}
+
+	return nil
+}
+
+func hasPlaceholder(filename, name string) bool {
+	return strings.Contains(filename, "{"+name+"}")
+}
+
+// expandFilename replaces the placeholders in filename for a run of args
+// starting at now.
+func expandFilename(filename string, args *cliArgs, now time.Time) string {
+	if !strings.Contains(filename, "{") {
+		return filename
+	}
+
+	return strings.NewReplacer(
+		"{postcode}", postcodeKey(args.Postcode),
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("150405"),
+		"{beds}", bedsFilterLabel(args),
+	).Replace(filename)
+}
+
+// outputFilenames returns pointers to each of the filename arguments that
+// take placeholders.
+func outputFilenames(args *cliArgs) []*string {
+	return []*string{&args.OutputFilename, &args.StatsFile, &args.ReportMD, &args.ReportHTML, &args.Chart, &args.URLsFile, &args.Manifest}
+}
+
+// expandOutputFilenames returns a copy of args with its output filenames
+// expanded, creating any directories they name that don't exist yet.
+func expandOutputFilenames(args *cliArgs, now time.Time) (*cliArgs, error) {
+	expanded := *args
+	for _, f := range outputFilenames(&expanded) {
+		if *f == "" || *f == stdoutFilename {
+			continue
+		}
+
+		*f = expandFilename(*f, args, now)
+		if err := os.MkdirAll(filepath.Dir(*f), 0755); err != nil {
+			return nil, errors.Wrapf(err, "while creating directory for %s", *f)
+		}
+	}
+
+	return &expanded, nil
+}
@@ -0,0 +1,105 @@
+package zoopla
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValidateFilenameTemplate(t *testing.T) {
+	tests := []struct {
+		filename string
+		wantErr  bool
+	}{
+		{"prices.json", false},
+		{"out/{postcode}-{date}.json", false},
+		{"{postcode}/{beds}/{date}T{time}.csv", false},
+		{"{unknown}.json", true},
+		{"{Postcode}.json", true},
+		{"{}.json", true},
+		{"{postcode.json", true},
+		{"postcode}.json", true},
+		{"{{postcode}}.json", true},
+	}
+
+	for _, tt := range tests {
+		err := validateFilenameTemplate(tt.filename)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateFilenameTemplate(%q) error = %v, want error: %t", tt.filename, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExpandFilename(t *testing.T) {
+	now := time.Date(2021, time.April, 1, 9, 5, 30, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		filename string
+		args     cliArgs
+		want     string
+	}{
+		{"no placeholders", "prices.json", cliArgs{Postcode: "SW2"}, "prices.json"},
+		{"postcode and date", "out/{postcode}-{date}.json", cliArgs{Postcode: "sw2 1aa"}, "out/SW21AA-2021-04-01.json"},
+		{"time", "{date}T{time}.json", cliArgs{}, "2021-04-01T090530.json"},
+		{"beds", "{beds}.json", cliArgs{BedsMin: uint32Ptr(2), BedsMax: uint32Ptr(3)}, "2-3.json"},
+		{"any beds", "{beds}.json", cliArgs{}, "any.json"},
+		{"repeated", "{postcode}/{postcode}.json", cliArgs{Postcode: "SW2"}, "SW2/SW2.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandFilename(tt.filename, &tt.args, now); got != tt.want {
+				t.Errorf("expandFilename(%q) = %q, want %q", tt.filename, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandOutputFilenames(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2021, time.April, 1, 9, 0, 0, 0, time.UTC)
+
+	args := &cliArgs{
+		Postcode:       "SW2",
+		OutputFilename: filepath.Join(dir, "out", "{postcode}", "{date}.json"),
+		StatsFile:      filepath.Join(dir, "stats-{postcode}.json"),
+		ReportMD:       stdoutFilename,
+	}
+	expanded, err := expandOutputFilenames(args, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := filepath.Join(dir, "out", "SW2", "2021-04-01.json"); expanded.OutputFilename != want {
+		t.Errorf("OutputFilename = %q, want %q", expanded.OutputFilename, want)
+	}
+	if want := filepath.Join(dir, "stats-SW2.json"); expanded.StatsFile != want {
+		t.Errorf("StatsFile = %q, want %q", expanded.StatsFile, want)
+	}
+	if expanded.ReportMD != stdoutFilename {
+		t.Errorf("ReportMD = %q, want stdout left alone", expanded.ReportMD)
+	}
+	if fi, err := os.Stat(filepath.Join(dir, "out", "SW2")); err != nil || !fi.IsDir() {
+		t.Errorf("output directory wasn't created: %v", err)
+	}
+	// The templates are kept for later runs.
+	if args.OutputFilename != filepath.Join(dir, "out", "{postcode}", "{date}.json") {
+		t.Errorf("args were changed: OutputFilename = %q", args.OutputFilename)
+	}
+}
+
+func TestExpandOutputFilenamesDirectoryError(t *testing.T) {
+	dir := t.TempDir()
+	blocker := filepath.Join(dir, "file")
+	if err := ioutil.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := &cliArgs{OutputFilename: filepath.Join(blocker, "{postcode}.json"), Postcode: "SW2"}
+	if _, err := expandOutputFilenames(args, time.Now()); err == nil {
+		t.Error("expandOutputFilenames() succeeded under a file")
+	}
+}
@@ -0,0 +1,160 @@
+package zoopla
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+var (
+	timestampRegexp = regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
+	buildIDRegexp   = regexp.MustCompile(`"buildId"\s*:\s*"[^"]*"`)
+
+	volatileAttrs = map[string]bool{"nonce": true, "integrity": true, "data-timestamp": true}
+)
+
+// fixtureGolden is the expected parse of a recorded fixture page.
+// RecordedAt is when the page was fetched, which relative dates such as
+// "yesterday" are resolved against when it is replayed.
+type fixtureGolden struct {
+	Parser     string    `json:"parser"`
+	RecordedAt time.Time `json:"recorded_at"`
+	Listings   []listing `json:"listings"`
+}
+
+// recordFixture saves a sanitised copy of a fetched page to dir along with
+// the listings parsed from that copy, so the two can be replayed later to
+// catch parser regressions. The fixture is named after the postcode and
+// page number. doc itself is left as it was, so that recording doesn't
+// change what the run parses.
+func recordFixture(dir, postcode string, pageNum uint32, doc *html.Node, forcedParser string, mode searchMode) error {
+	var page bytes.Buffer
+	if err := html.Render(&page, doc); err != nil {
+		return errors.Wrap(err, "while rendering fixture")
+	}
+
+	fixture, err := html.Parse(&page)
+	if err != nil {
+		return errors.Wrap(err, "while copying fixture")
+	}
+	sanitiseFixture(fixture)
+
+	p := selectParser(fixture, forcedParser)
+	if p == nil {
+		return errors.Errorf("no parser recognises page %d, not recording fixture", pageNum)
+	}
+
+	listings, _ := p.parse(fixture, mode)
+	if listings == nil {
+		listings = []listing{}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "while creating fixture directory")
+	}
+
+	page.Reset()
+	if err := html.Render(&page, fixture); err != nil {
+		return errors.Wrap(err, "while rendering fixture")
+	}
+
+	base := filepath.Join(dir, fixtureName(postcode, pageNum))
+	if err := ioutil.WriteFile(base+".html", page.Bytes(), 0644); err != nil {
+		return errors.Wrap(err, "while writing fixture")
+	}
+
+	golden, err := marshalGolden(fixtureGolden{Parser: p.name(), RecordedAt: timeNow().UTC(), Listings: listings})
+	if err != nil {
+		return errors.Wrap(err, "while marshalling golden listings")
+	}
+
+	return ioutil.WriteFile(base+".golden.json", golden, 0644)
+}
+
+// fixtureName is like "sw2-1aa-page-3".
+func fixtureName(postcode string, pageNum uint32) string {
+	name := strings.ToLower(strings.Join(strings.Fields(postcode), "-"))
+	return fmt.Sprintf("%s-page-%d", name, pageNum)
+}
+
+func marshalGolden(g fixtureGolden) ([]byte, error) {
+	b, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(b, '\n'), nil
+}
+
+// sanitiseFixture strips content that changes between requests: CSRF tokens,
+// nonces, build IDs, timestamps and executable scripts. Data scripts used by
+// the parsers are kept.
+func sanitiseFixture(root *html.Node) {
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if isExecutableScript(c) {
+				n.RemoveChild(c)
+			} else {
+				walk(c)
+			}
+			c = next
+		}
+
+		if n.Type == html.TextNode {
+			n.Data = timestampRegexp.ReplaceAllString(n.Data, "1970-01-01T00:00:00Z")
+			n.Data = buildIDRegexp.ReplaceAllString(n.Data, `"buildId":"fixture"`)
+			return
+		}
+
+		attrs := n.Attr[:0]
+		for _, a := range n.Attr {
+			key := strings.ToLower(a.Key)
+			if volatileAttrs[key] {
+				continue
+			}
+			if strings.Contains(key, "csrf") || strings.Contains(key, "token") {
+				a.Val = ""
+			}
+			attrs = append(attrs, a)
+		}
+		n.Attr = attrs
+
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			if name, _ := getAttr(n, "name"); strings.Contains(strings.ToLower(name), "csrf") {
+				setAttr(n, "content", "")
+			}
+		}
+	}
+	walk(root)
+}
+
+func isExecutableScript(n *html.Node) bool {
+	if n.Type != html.ElementNode || n.Data != "script" {
+		return false
+	}
+
+	typ, _ := getAttr(n, "type")
+	return typ == "" || strings.Contains(typ, "javascript") || typ == "module"
+}
+
+func setAttr(n *html.Node, key, val string) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			n.Attr[i].Val = val
+			return
+		}
+	}
+
+	n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+}
@@ -0,0 +1,216 @@
+package zoopla
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// The pages in testdata/fixtures were saved by --record-fixture, each next
+// to the golden listings parsed from it. To add one when the layout changes,
+// record it with
+//
+//	go run ./cmd/zoopla-analyzer --postcode SW2 --record-fixture testdata/fixtures
+//
+// and check its golden file. After a deliberate change to what the parsers
+// extract, rewrite the goldens with
+//
+//	go test -run TestFixtures -update
+var updateGoldens = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+const fixtureDir = "testdata/fixtures"
+
+// fixturePages returns the paths of the recorded fixture pages.
+func fixturePages(t testing.TB) []string {
+	t.Helper()
+
+	pages, err := filepath.Glob(filepath.Join(fixtureDir, "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pages) == 0 {
+		t.Fatal("no fixtures in " + fixtureDir)
+	}
+
+	return pages
+}
+
+func goldenPath(page string) string {
+	return strings.TrimSuffix(page, ".html") + ".golden.json"
+}
+
+func readGolden(t testing.TB, page string) (fixtureGolden, bool) {
+	t.Helper()
+
+	b, err := os.ReadFile(goldenPath(page))
+	if os.IsNotExist(err) {
+		return fixtureGolden{}, false
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	var g fixtureGolden
+	if err := json.Unmarshal(b, &g); err != nil {
+		t.Fatalf("decoding %s: %v", goldenPath(page), err)
+	}
+
+	return g, true
+}
+
+func TestFixtures(t *testing.T) {
+	for _, page := range fixturePages(t) {
+		t.Run(filepath.Base(page), func(t *testing.T) {
+			golden, ok := readGolden(t, page)
+			if !ok && !*updateGoldens {
+				t.Fatalf("no golden file, run go test -run TestFixtures -update to create it")
+			}
+			if !ok {
+				golden.RecordedAt = time.Now().UTC()
+			}
+			setNow(t, golden.RecordedAt)
+
+			b, err := os.ReadFile(page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc := mustParseHTML(t, string(b))
+
+			// Pages are replayed through the parser they were recorded with,
+			// which is also the one chosen for them unless the recording
+			// forced another.
+			p := selectParser(doc, golden.Parser)
+			if p == nil {
+				t.Fatalf("no parser recognises the page")
+			}
+			listings, _ := parseHTML(doc, p.name(), saleSearch)
+			if listings == nil {
+				listings = []listing{}
+			}
+
+			got, err := marshalGolden(fixtureGolden{Parser: p.name(), RecordedAt: golden.RecordedAt, Listings: listings})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *updateGoldens {
+				if err := os.WriteFile(goldenPath(page), got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(goldenPath(page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				diffGoldenListings(t, golden.Listings, listings)
+			}
+		})
+	}
+}
+
+// diffGoldenListings reports each listing that differs from its golden, as
+// JSON since that is what the golden files hold.
+func diffGoldenListings(t *testing.T, want, got []listing) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Errorf("got %d listings, want %d", len(got), len(want))
+	}
+	for i := 0; i < len(got) && i < len(want); i++ {
+		g, _ := json.Marshal(got[i])
+		w, _ := json.Marshal(want[i])
+		if !bytes.Equal(g, w) {
+			t.Errorf("listing %d:\n got %s\nwant %s", i, g, w)
+		}
+	}
+}
+
+func TestRecordFixture(t *testing.T) {
+	const page = `<html><head>
+<meta name="csrf-token" content="secret-token">
+<script nonce="abc123">window.boot()</script>
+<script id="__NEXT_DATA__" type="application/json">{"buildId":"b-20210303","renderedAt":"2021-03-03T10:11:12Z"}</script>
+</head><body><div class="ListingsContainer">
+<div data-testid="search-result" data-timestamp="1614766272">
+  <a href="/for-sale/details/1/"><h2>2 bed flat for sale</h2></a>
+  <div class="PriceContainer"><p>£450,000</p></div>
+  <p>Listed yesterday</p>
+  <form><input type="hidden" name="csrf" data-csrf-token="secret-token"></form>
+</div>
+</div></body></html>`
+
+	doc := mustParseHTML(t, page)
+	var before bytes.Buffer
+	if err := html.Render(&before, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, time.March, 3, 10, 11, 12, 0, time.UTC)
+	setNow(t, now)
+	dir := t.TempDir()
+	if err := recordFixture(dir, "SW2 1AA", 1, doc, "", saleSearch); err != nil {
+		t.Fatal(err)
+	}
+
+	var after bytes.Buffer
+	if err := html.Render(&after, doc); err != nil {
+		t.Fatal(err)
+	}
+	if before.String() != after.String() {
+		t.Error("recording the fixture changed the page being parsed")
+	}
+
+	fixture, err := os.ReadFile(filepath.Join(dir, "sw2-1aa-page-1.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, volatile := range []string{"secret-token", "abc123", "window.boot", "b-20210303", "2021-03-03T10:11:12Z", "1614766272"} {
+		if bytes.Contains(fixture, []byte(volatile)) {
+			t.Errorf("fixture still contains %q", volatile)
+		}
+	}
+	if !bytes.Contains(fixture, []byte("__NEXT_DATA__")) {
+		t.Error("fixture lost its data script")
+	}
+
+	golden, ok := readGolden(t, filepath.Join(dir, "sw2-1aa-page-1.html"))
+	if !ok {
+		t.Fatal("no golden file written")
+	}
+	if golden.Parser != "class" || !golden.RecordedAt.Equal(now) {
+		t.Errorf("golden parser = %q recorded at %v, want class at %v", golden.Parser, golden.RecordedAt, now)
+	}
+	if len(golden.Listings) != 1 || golden.Listings[0].Price != 450000 {
+		t.Fatalf("golden listings = %+v, want one at 450000", golden.Listings)
+	}
+	if l := golden.Listings[0]; l.ListedOn == nil || !l.ListedOn.Equal(date(2021, time.March, 2)) {
+		t.Errorf("listed on = %v, want 2021-03-02", l.ListedOn)
+	}
+}
+
+func TestFixtureName(t *testing.T) {
+	tests := []struct {
+		postcode string
+		page     uint32
+		want     string
+	}{
+		{"SW2", 1, "sw2-page-1"},
+		{"SW2 1AA", 3, "sw2-1aa-page-3"},
+		{" e1  6an ", 2, "e1-6an-page-2"},
+	}
+
+	for _, tt := range tests {
+		if got := fixtureName(tt.postcode, tt.page); got != tt.want {
+			t.Errorf("fixtureName(%q, %d) = %q, want %q", tt.postcode, tt.page, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package zoopla
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type coordinates struct {
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// jsonCoordinates reads a latitude/longitude pair from a decoded JSON object,
+// either directly or nested under "coordinates" as in __NEXT_DATA__.
+func jsonCoordinates(v interface{}) *coordinates {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if nested, ok := obj["coordinates"]; ok {
+		return jsonCoordinates(nested)
+	}
+
+	lat, latOK := jsonFloat(obj["latitude"])
+	lon, lonOK := jsonFloat(obj["longitude"])
+	if !latOK || !lonOK || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return nil
+	}
+
+	return &coordinates{Latitude: lat, Longitude: lon}
+}
+
+func jsonFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// priceBands are the upper bounds of the bands used to style mapped
+// listings. Prices above the last bound fall into a final open band.
+var priceBands = []uint64{250000, 500000, 750000, 1000000}
+
+// priceBand returns the index of the band containing price.
+func priceBand(price uint64) int {
+	return bandIndex(priceBands, price)
+}
+
+// priceBandLabel returns a short label such as "250k-500k" for a band.
+func priceBandLabel(band int) string {
+	switch {
+	case band == 0:
+		return "<" + shortPrice(priceBands[0])
+	case band >= len(priceBands):
+		return shortPrice(priceBands[len(priceBands)-1]) + "+"
+	default:
+		return shortPrice(priceBands[band-1]) + "-" + shortPrice(priceBands[band])
+	}
+}
+
+func shortPrice(price uint64) string {
+	if price >= 1000000 && price%1000000 == 0 {
+		return fmt.Sprintf("%dm", price/1000000)
+	}
+
+	return fmt.Sprintf("%dk", price/1000)
+}
@@ -0,0 +1,76 @@
+package zoopla
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONCoordinates(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want *coordinates
+	}{
+		{"numbers", map[string]interface{}{"latitude": 51.46, "longitude": -0.11}, &coordinates{Latitude: 51.46, Longitude: -0.11}},
+		{"strings", map[string]interface{}{"latitude": "51.46", "longitude": "-0.11"}, &coordinates{Latitude: 51.46, Longitude: -0.11}},
+		{"nested", map[string]interface{}{"coordinates": map[string]interface{}{"latitude": 51.46, "longitude": -0.11}}, &coordinates{Latitude: 51.46, Longitude: -0.11}},
+		{"missing longitude", map[string]interface{}{"latitude": 51.46}, nil},
+		{"not a number", map[string]interface{}{"latitude": "north", "longitude": -0.11}, nil},
+		{"latitude out of range", map[string]interface{}{"latitude": 91.0, "longitude": -0.11}, nil},
+		{"longitude out of range", map[string]interface{}{"latitude": 51.46, "longitude": 181.0}, nil},
+		{"not an object", []interface{}{51.46, -0.11}, nil},
+		{"nil", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jsonCoordinates(tt.v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("jsonCoordinates() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceBand(t *testing.T) {
+	tests := []struct {
+		price uint64
+		band  int
+		label string
+	}{
+		{0, 0, "<250k"},
+		{249999, 0, "<250k"},
+		{250000, 1, "250k-500k"},
+		{600000, 2, "500k-750k"},
+		{999999, 3, "750k-1m"},
+		{1000000, 4, "1m+"},
+		{5000000, 4, "1m+"},
+	}
+
+	for _, tt := range tests {
+		band := priceBand(tt.price)
+		if band != tt.band {
+			t.Errorf("priceBand(%d) = %d, want %d", tt.price, band, tt.band)
+		}
+		if label := priceBandLabel(band); label != tt.label {
+			t.Errorf("priceBandLabel(%d) = %q, want %q", band, label, tt.label)
+		}
+	}
+}
+
+func TestShortPrice(t *testing.T) {
+	tests := []struct {
+		price uint64
+		want  string
+	}{
+		{250000, "250k"},
+		{1000000, "1m"},
+		{1500000, "1500k"},
+		{2000000, "2m"},
+	}
+
+	for _, tt := range tests {
+		if got := shortPrice(tt.price); got != tt.want {
+			t.Errorf("shortPrice(%d) = %q, want %q", tt.price, got, tt.want)
+		}
+	}
+}
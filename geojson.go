@@ -0,0 +1,70 @@
+package zoopla
+
+import (
+	"log"
+)
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string            `json:"type"`
+	Geometry   geoJSONPoint      `json:"geometry"`
+	Properties geoJSONProperties `json:"properties"`
+}
+
+// geoJSONPoint holds its position as [longitude, latitude], the order the
+// GeoJSON spec requires.
+type geoJSONPoint struct {
+	Type        string     `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+type geoJSONProperties struct {
+	ID           string  `json:"id,omitempty"`
+	Price        uint64  `json:"price"`
+	PriceBand    string  `json:"price_band"`
+	Beds         *uint32 `json:"beds,omitempty"`
+	PropertyType string  `json:"property_type,omitempty"`
+	Address      string  `json:"address,omitempty"`
+	URL          string  `json:"url,omitempty"`
+}
+
+// marshalGeoJSON writes a FeatureCollection with a Point per listing. Listings
+// without coordinates can't be placed and are skipped.
+func marshalGeoJSON(listings []listing, args *cliArgs) ([]byte, error) {
+	fc := geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+	skipped := 0
+	for i := range listings {
+		l := &listings[i]
+		if l.Location == nil {
+			skipped++
+			continue
+		}
+
+		fc.Features = append(fc.Features, geoJSONFeature{
+			Type: "Feature",
+			Geometry: geoJSONPoint{
+				Type:        "Point",
+				Coordinates: [2]float64{l.Location.Longitude, l.Location.Latitude},
+			},
+			Properties: geoJSONProperties{
+				ID:           l.ID,
+				Price:        l.Price,
+				PriceBand:    priceBandLabel(priceBand(l.Price)),
+				Beds:         l.Beds,
+				PropertyType: l.PropertyType,
+				Address:      l.Address,
+				URL:          l.URL,
+			},
+		})
+	}
+
+	if skipped > 0 {
+		log.Printf("skipped %d listings without coordinates", skipped)
+	}
+
+	return marshalJSON(fc, args.Pretty)
+}
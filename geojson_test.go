@@ -0,0 +1,83 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMarshalGeoJSON(t *testing.T) {
+	listings := []listing{
+		{ID: "1", Price: 450000, Beds: uint32Ptr(2), PropertyType: "flat", Address: "Acre Lane", URL: "https://example.com/1/", Location: &coordinates{Latitude: 51.4613, Longitude: -0.1156}},
+		{ID: "2", Price: 300000},
+		{ID: "3", Price: 1200000, Location: &coordinates{Latitude: 51.45, Longitude: -0.12}},
+	}
+
+	data, err := marshalGeoJSON(listings, &cliArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Decode generically so that the test checks what's written, not just
+	// that it round trips through the same structs.
+	var fc map[string]interface{}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		t.Fatal(err)
+	}
+	if fc["type"] != "FeatureCollection" {
+		t.Errorf("type = %v, want FeatureCollection", fc["type"])
+	}
+
+	features, ok := fc["features"].([]interface{})
+	if !ok || len(features) != 2 {
+		t.Fatalf("features = %v, want two, skipping the listing without coordinates", fc["features"])
+	}
+
+	tests := []struct {
+		lon, lat float64
+		id, band string
+	}{
+		{-0.1156, 51.4613, "1", "250k-500k"},
+		{-0.12, 51.45, "3", "1m+"},
+	}
+	for i, tt := range tests {
+		f := features[i].(map[string]interface{})
+		if f["type"] != "Feature" {
+			t.Errorf("feature %d type = %v", i, f["type"])
+		}
+
+		geom := f["geometry"].(map[string]interface{})
+		coords, ok := geom["coordinates"].([]interface{})
+		if geom["type"] != "Point" || !ok || len(coords) != 2 {
+			t.Fatalf("feature %d geometry = %v", i, geom)
+		}
+		// GeoJSON positions are longitude first.
+		if coords[0] != tt.lon || coords[1] != tt.lat {
+			t.Errorf("feature %d coordinates = %v, want [%v %v]", i, coords, tt.lon, tt.lat)
+		}
+
+		props := f["properties"].(map[string]interface{})
+		if props["id"] != tt.id || props["price_band"] != tt.band {
+			t.Errorf("feature %d properties = %v", i, props)
+		}
+		if _, ok := props["price"].(float64); !ok {
+			t.Errorf("feature %d price = %#v, want a number", i, props["price"])
+		}
+	}
+
+	first := features[0].(map[string]interface{})["properties"].(map[string]interface{})
+	if first["beds"] != 2.0 || first["property_type"] != "flat" || first["url"] != "https://example.com/1/" {
+		t.Errorf("first feature properties = %v", first)
+	}
+}
+
+func TestMarshalGeoJSONEmpty(t *testing.T) {
+	data, err := marshalGeoJSON([]listing{{Price: 300000}}, &cliArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An empty collection still has a features array, as the spec requires.
+	if want := `{"type":"FeatureCollection","features":[]}`; string(data) != want {
+		t.Errorf("marshalGeoJSON() = %s, want %s", data, want)
+	}
+}
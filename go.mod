@@ -3,7 +3,15 @@ module github.com/ryanc414/zoopla-analyzer
 go 1.16
 
 require (
-	github.com/alexflint/go-arg v1.3.0 // indirect
-	github.com/pkg/errors v0.9.1 // indirect
-	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110 // indirect
+	github.com/alexflint/go-arg v1.3.0
+	github.com/aws/aws-sdk-go v1.38.0
+	github.com/fxamacker/cbor/v2 v2.4.0
+	github.com/lib/pq v1.10.0
+	github.com/mattn/go-sqlite3 v1.14.6
+	github.com/pkg/errors v0.9.1
+	github.com/xitongsys/parquet-go v1.6.2
+	github.com/xuri/excelize/v2 v2.4.1
+	golang.org/x/net v0.0.0-20210726213435-c6fcb2dbf985
+	gonum.org/v1/plot v0.9.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,128 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// defaultMinGroupSize is the fewest listings a group needs for its stats to
+// be considered reliable.
+const defaultMinGroupSize = 5
+
+type groupStats struct {
+	key    string
+	count  int
+	mean   float64
+	median float64
+	p25    float64
+	p75    float64
+
+	// unreliable is set when the group has fewer listings than the minimum
+	// sample size.
+	unreliable bool
+}
+
+// calculateGroupStats groups listings by the key returned for each and
+// summarises the prices in each group, largest groups first.
+func calculateGroupStats(listings []listing, key func(l *listing) string) []groupStats {
+	var keys []string
+	groupPrices := make(map[string][]uint64)
+	for i := range listings {
+		k := key(&listings[i])
+		if _, ok := groupPrices[k]; !ok {
+			keys = append(keys, k)
+		}
+		groupPrices[k] = append(groupPrices[k], listings[i].Price)
+	}
+
+	groups := make([]groupStats, len(keys))
+	for i, k := range keys {
+		prices := groupPrices[k]
+		sorted := make([]uint64, len(prices))
+		copy(sorted, prices)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		groups[i] = groupStats{
+			key:    k,
+			count:  len(prices),
+			mean:   calculateMean(prices),
+			median: quantile(sorted, 0.5),
+			p25:    quantile(sorted, 0.25),
+			p75:    quantile(sorted, 0.75),
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].count != groups[j].count {
+			return groups[i].count > groups[j].count
+		}
+		return groups[i].key < groups[j].key
+	})
+
+	return groups
+}
+
+// orderedGroupStats is like calculateGroupStats but orders groups by key,
+// with numeric keys such as "2 bed" in numeric order and unknown last.
+// Groups with fewer than minSize listings are flagged as unreliable.
+func orderedGroupStats(listings []listing, key func(l *listing) string, minSize int) []groupStats {
+	groups := calculateGroupStats(listings, key)
+	sort.Slice(groups, func(i, j int) bool {
+		return lessGroupKey(groups[i].key, groups[j].key)
+	})
+
+	for i := range groups {
+		groups[i].unreliable = groups[i].count < minSize
+	}
+
+	return groups
+}
+
+// onlyUnknownGroup reports whether groups say nothing, because there are
+// none or every listing's key was unknown.
+func onlyUnknownGroup(groups []groupStats) bool {
+	return len(groups) == 0 || (len(groups) == 1 && groups[0].key == unknownGroup)
+}
+
+func lessGroupKey(a, b string) bool {
+	if a == unknownGroup || b == unknownGroup {
+		return b == unknownGroup && a != unknownGroup
+	}
+
+	return compareIDs(a, b) < 0
+}
+
+func (g groupStats) String() string {
+	s := fmt.Sprintf("%s: count = %d, mean = %.0f, median = %.0f, p25 = %.0f, p75 = %.0f",
+		g.key, g.count, g.mean, g.median, g.p25, g.p75)
+	if g.unreliable {
+		s += " (unreliable, too few listings)"
+	}
+
+	return s
+}
+
+func (g groupStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Key        string  `json:"key"`
+		Count      int     `json:"count"`
+		Mean       float64 `json:"mean"`
+		Median     float64 `json:"median"`
+		P25        float64 `json:"p25"`
+		P75        float64 `json:"p75"`
+		Unreliable bool    `json:"unreliable"`
+	}{g.key, g.count, g.mean, g.median, g.p25, g.p75, g.unreliable})
+}
+
+// reliableGroups leaves out the groups flagged as unreliable.
+func reliableGroups(groups []groupStats) []groupStats {
+	var reliable []groupStats
+	for _, g := range groups {
+		if !g.unreliable {
+			reliable = append(reliable, g)
+		}
+	}
+
+	return reliable
+}
@@ -0,0 +1,128 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCalculateGroupStats(t *testing.T) {
+	listings := []listing{
+		{Outcode: "SW9", Price: 500000},
+		{Outcode: "SW2", Price: 300000},
+		{Outcode: "SW2", Price: 400000},
+		{Price: 200000},
+		{Outcode: "SW2", Price: 500000},
+		{Outcode: "SW4", Price: 600000},
+		{Outcode: "SW2", Price: 200000},
+	}
+
+	want := []groupStats{
+		{key: "SW2", count: 4, mean: 350000, median: 350000, p25: 275000, p75: 425000},
+		{key: "SW4", count: 1, mean: 600000, median: 600000, p25: 600000, p75: 600000},
+		{key: "SW9", count: 1, mean: 500000, median: 500000, p25: 500000, p75: 500000},
+		{key: unknownGroup, count: 1, mean: 200000, median: 200000, p25: 200000, p75: 200000},
+	}
+	if got := calculateGroupStats(listings, outcodeKey); !reflect.DeepEqual(got, want) {
+		t.Errorf("calculateGroupStats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCalculateGroupStatsEmpty(t *testing.T) {
+	if got := calculateGroupStats(nil, outcodeKey); len(got) != 0 {
+		t.Errorf("calculateGroupStats(nil) = %+v, want none", got)
+	}
+}
+
+func TestOrderedGroupStats(t *testing.T) {
+	listings := []listing{
+		{Price: 100000},
+		{Beds: uint32Ptr(10), Price: 900000},
+		{Beds: uint32Ptr(2), Price: 400000},
+		{Beds: uint32Ptr(1), Price: 300000},
+		{Beds: uint32Ptr(2), Price: 500000},
+		{Beds: uint32Ptr(1), Price: 250000},
+		{Beds: uint32Ptr(2), Price: 450000},
+	}
+
+	tests := []struct {
+		name       string
+		minSize    int
+		keys       []string
+		unreliable []bool
+	}{
+		{"none unreliable", 0, []string{"1 bed", "2 bed", "10 bed", unknownGroup}, []bool{false, false, false, false}},
+		{"small groups", 2, []string{"1 bed", "2 bed", "10 bed", unknownGroup}, []bool{false, false, true, true}},
+		{"all unreliable", 4, []string{"1 bed", "2 bed", "10 bed", unknownGroup}, []bool{true, true, true, true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups := orderedGroupStats(listings, bedsKey, tt.minSize)
+			var keys []string
+			var unreliable []bool
+			for _, g := range groups {
+				keys = append(keys, g.key)
+				unreliable = append(unreliable, g.unreliable)
+			}
+			if !reflect.DeepEqual(keys, tt.keys) || !reflect.DeepEqual(unreliable, tt.unreliable) {
+				t.Errorf("got keys %v, unreliable %v, want %v, %v", keys, unreliable, tt.keys, tt.unreliable)
+			}
+		})
+	}
+
+	two := orderedGroupStats(listings, bedsKey, 0)[1]
+	want := groupStats{key: "2 bed", count: 3, mean: 450000, median: 450000, p25: 425000, p75: 475000}
+	if two != want {
+		t.Errorf("got %+v, want %+v", two, want)
+	}
+}
+
+func TestOnlyUnknownGroup(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups []groupStats
+		want   bool
+	}{
+		{"none", nil, true},
+		{"only unknown", []groupStats{{key: unknownGroup}}, true},
+		{"one known", []groupStats{{key: "2 bed"}}, false},
+		{"known and unknown", []groupStats{{key: "2 bed"}, {key: unknownGroup}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := onlyUnknownGroup(tt.groups); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestReliableGroups(t *testing.T) {
+	groups := []groupStats{{key: "1 bed"}, {key: "2 bed", unreliable: true}, {key: "3 bed"}}
+
+	got := reliableGroups(groups)
+	if len(got) != 2 || got[0].key != "1 bed" || got[1].key != "3 bed" {
+		t.Errorf("got %+v", got)
+	}
+	if got := reliableGroups([]groupStats{{unreliable: true}}); got != nil {
+		t.Errorf("got %+v, want none", got)
+	}
+}
+
+func TestGroupStatsOutput(t *testing.T) {
+	g := groupStats{key: "2 bed", count: 3, mean: 450000, median: 450000, p25: 425000, p75: 475000, unreliable: true}
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"key":"2 bed","count":3,"mean":450000,"median":450000,"p25":425000,"p75":475000,"unreliable":true}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	wantStr := "2 bed: count = 3, mean = 450000, median = 450000, p25 = 425000, p75 = 475000 (unreliable, too few listings)"
+	if got := g.String(); got != wantStr {
+		t.Errorf("got %q, want %q", got, wantStr)
+	}
+}
@@ -0,0 +1,100 @@
+package zoopla
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// maxHistogramBuckets caps automatic binning, which can otherwise produce a
+// bucket per few pounds for very skewed data.
+const maxHistogramBuckets = 50
+
+type histogramBucket struct {
+	Min   uint64 `json:"min"`
+	Max   uint64 `json:"max"`
+	Count int    `json:"count"`
+}
+
+func (b histogramBucket) String() string {
+	return fmt.Sprintf("%s - %s: %d", formatPrice(b.Min), formatPrice(b.Max), b.Count)
+}
+
+// calculateHistogram buckets prices into buckets of bucketSize, or if that's
+// zero a width chosen by the Freedman-Diaconis rule. Buckets cover [Min, Max)
+// apart from the last, which also includes Max, and empty buckets inside the
+// range are kept. Identical prices give a single zero-width bucket.
+func calculateHistogram(prices []uint64, bucketSize uint64) []histogramBucket {
+	if len(prices) == 0 {
+		return nil
+	}
+
+	sorted := make([]uint64, len(prices))
+	copy(sorted, prices)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	lo, hi := sorted[0], sorted[len(sorted)-1]
+	if lo == hi {
+		return []histogramBucket{{Min: lo, Max: hi, Count: len(sorted)}}
+	}
+
+	width := bucketSize
+	if width == 0 {
+		width = freedmanDiaconisWidth(sorted)
+		if width == 0 || (hi-lo)/width >= maxHistogramBuckets {
+			width = niceWidth(float64(hi-lo) / maxHistogramBuckets)
+		}
+	}
+
+	start := lo / width * width
+	n := int((hi-start)/width) + 1
+	buckets := make([]histogramBucket, n)
+	for i := range buckets {
+		buckets[i].Min = start + uint64(i)*width
+		buckets[i].Max = buckets[i].Min + width
+	}
+
+	for _, p := range sorted {
+		buckets[int((p-start)/width)].Count++
+	}
+
+	return buckets
+}
+
+func freedmanDiaconisWidth(sorted []uint64) uint64 {
+	iqr := quantile(sorted, 0.75) - quantile(sorted, 0.25)
+	if iqr <= 0 {
+		return 0
+	}
+
+	return niceWidth(2 * iqr / math.Cbrt(float64(len(sorted))))
+}
+
+// niceWidth rounds a bucket width up to 1, 2 or 5 times a power of ten so
+// that bucket boundaries are readable.
+func niceWidth(w float64) uint64 {
+	if w <= 1 {
+		return 1
+	}
+
+	pow := math.Pow(10, math.Floor(math.Log10(w)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if m*pow >= w {
+			return uint64(m * pow)
+		}
+	}
+
+	return uint64(10 * pow)
+}
+
+// quantile linearly interpolates the q'th quantile of sorted prices.
+func quantile(sorted []uint64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return float64(sorted[len(sorted)-1])
+	}
+
+	frac := pos - float64(i)
+	return float64(sorted[i]) + frac*(float64(sorted[i+1])-float64(sorted[i]))
+}
@@ -0,0 +1,130 @@
+package zoopla
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCalculateHistogram(t *testing.T) {
+	tests := []struct {
+		name       string
+		prices     []uint64
+		bucketSize uint64
+		want       []histogramBucket
+	}{
+		{name: "empty", want: nil},
+		{
+			name:   "identical prices",
+			prices: []uint64{450000, 450000},
+			want:   []histogramBucket{{Min: 450000, Max: 450000, Count: 2}},
+		},
+		{
+			name:       "fixed width",
+			prices:     []uint64{410000, 420000, 440000, 455000, 520000},
+			bucketSize: 25000,
+			want: []histogramBucket{
+				{Min: 400000, Max: 425000, Count: 2},
+				{Min: 425000, Max: 450000, Count: 1},
+				{Min: 450000, Max: 475000, Count: 1},
+				{Min: 475000, Max: 500000, Count: 0},
+				{Min: 500000, Max: 525000, Count: 1},
+			},
+		},
+		{
+			name:       "maximum on a boundary",
+			prices:     []uint64{400000, 450000},
+			bucketSize: 50000,
+			want: []histogramBucket{
+				{Min: 400000, Max: 450000, Count: 1},
+				{Min: 450000, Max: 500000, Count: 1},
+			},
+		},
+		{
+			// The IQR is 350000 so the Freedman-Diaconis width is
+			// 2 * 350000 / 2, rounded up to a nice 500000.
+			name:   "automatic width",
+			prices: []uint64{100000, 200000, 300000, 400000, 500000, 600000, 700000, 800000},
+			want: []histogramBucket{
+				{Min: 0, Max: 500000, Count: 4},
+				{Min: 500000, Max: 1000000, Count: 4},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateHistogram(tt.prices, tt.bucketSize); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("calculateHistogram() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateHistogramCapsBuckets(t *testing.T) {
+	// A tight cluster and one outlier would give thousands of buckets.
+	prices := []uint64{400000, 400100, 400200, 400300, 400400, 400500, 5000000}
+
+	buckets := calculateHistogram(prices, 0)
+	if len(buckets) > maxHistogramBuckets+1 {
+		t.Errorf("got %d buckets, want at most %d", len(buckets), maxHistogramBuckets+1)
+	}
+
+	var count int
+	for _, b := range buckets {
+		count += b.Count
+	}
+	if count != len(prices) {
+		t.Errorf("buckets count %d prices, want %d", count, len(prices))
+	}
+}
+
+func TestNiceWidth(t *testing.T) {
+	tests := []struct {
+		w    float64
+		want uint64
+	}{
+		{w: 0.5, want: 1},
+		{w: 1, want: 1},
+		{w: 3, want: 5},
+		{w: 12000, want: 20000},
+		{w: 25000, want: 50000},
+		{w: 50000, want: 50000},
+		{w: 60000, want: 100000},
+	}
+
+	for _, tt := range tests {
+		if got := niceWidth(tt.w); got != tt.want {
+			t.Errorf("niceWidth(%v) = %d, want %d", tt.w, got, tt.want)
+		}
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	sorted := []uint64{100, 200, 300, 400}
+
+	tests := []struct {
+		q    float64
+		want float64
+	}{
+		{q: 0, want: 100},
+		{q: 0.25, want: 175},
+		{q: 0.5, want: 250},
+		{q: 1, want: 400},
+	}
+
+	for _, tt := range tests {
+		if got := quantile(sorted, tt.q); got != tt.want {
+			t.Errorf("quantile(%v) = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+	if got := quantile([]uint64{100}, 0.5); got != 100 {
+		t.Errorf("quantile of one price = %v, want 100", got)
+	}
+}
+
+func TestHistogramBucketString(t *testing.T) {
+	b := histogramBucket{Min: 400000, Max: 425000, Count: 3}
+	if got, want := b.String(), "£400,000 - £425,000: 3"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
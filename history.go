@@ -0,0 +1,153 @@
+package zoopla
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	historyPriceRegexp  = regexp.MustCompile(`£\s*([\d,]+)`)
+	historyClauseRegexp = regexp.MustCompile(`,\s+`)
+)
+
+type pricePoint struct {
+	Date  *time.Time `json:"date,omitempty"`
+	Price uint64     `json:"price"`
+}
+
+// parsePriceHistory reads the price-history section of a detail page. Each
+// entry is either a list item or, for older templates, a comma-separated
+// clause such as "reduced to £525,000 on 3rd Mar 2021". A missing section
+// gives a nil history.
+func parsePriceHistory(root *html.Node) []pricePoint {
+	section := findByTestID(root, "price-history")
+	if section == nil {
+		return nil
+	}
+
+	var entries []string
+	for _, item := range findAll(section, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "li"
+	}) {
+		entries = append(entries, joinedText(item))
+	}
+	if len(entries) == 0 {
+		entries = historyClauseRegexp.Split(joinedText(section), -1)
+	}
+
+	var history []pricePoint
+	allDated := true
+	for _, entry := range entries {
+		m := historyPriceRegexp.FindStringSubmatch(entry)
+		if m == nil {
+			continue
+		}
+
+		price, err := strconv.ParseUint(strings.Replace(m[1], ",", "", -1), 10, 64)
+		if err != nil {
+			log.Printf("cannot parse price history entry %q: %v", entry, err)
+			continue
+		}
+
+		point := pricePoint{Price: price}
+		if d := dateRegexp.FindString(entry); d != "" {
+			if t, err := parseListingDate(d); err == nil {
+				point.Date = &t
+			}
+		}
+		if point.Date == nil {
+			allDated = false
+		}
+
+		history = append(history, point)
+	}
+
+	if allDated {
+		sort.SliceStable(history, func(i, j int) bool {
+			return history[i].Date.Before(*history[j].Date)
+		})
+	}
+
+	return history
+}
+
+// joinedText is like textContent but separates the text of sibling elements
+// with spaces, so "<span>£550,000</span><span>3rd Mar 2021</span>" does not
+// run together.
+func joinedText(n *html.Node) string {
+	var parts []string
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			if s := strings.TrimSpace(n.Data); s != "" {
+				parts = append(parts, s)
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return strings.Join(parts, " ")
+}
+
+type reductionStats struct {
+	withHistory      int
+	reduced          int
+	meanReduction    float64
+	meanReductionPct float64
+}
+
+func calculateReductionStats(listings []listing) reductionStats {
+	var stats reductionStats
+	var totalReduction, totalReductionPct float64
+	for i := range listings {
+		history := listings[i].PriceHistory
+		if len(history) == 0 {
+			continue
+		}
+		stats.withHistory++
+
+		first, last := history[0].Price, history[len(history)-1].Price
+		if last >= first {
+			continue
+		}
+
+		stats.reduced++
+		reduction := float64(first - last)
+		totalReduction += reduction
+		totalReductionPct += 100 * reduction / float64(first)
+	}
+
+	if stats.reduced > 0 {
+		stats.meanReduction = totalReduction / float64(stats.reduced)
+		stats.meanReductionPct = totalReductionPct / float64(stats.reduced)
+	}
+
+	return stats
+}
+
+func (s reductionStats) String() string {
+	if s.withHistory == 0 {
+		return "no price history available"
+	}
+
+	return fmt.Sprintf(
+		"%d of %d listings reduced (%.0f%%), mean reduction = %.0f (%.1f%%)",
+		s.reduced,
+		s.withHistory,
+		100*float64(s.reduced)/float64(s.withHistory),
+		s.meanReduction,
+		s.meanReductionPct,
+	)
+}
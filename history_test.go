@@ -0,0 +1,107 @@
+package zoopla
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParsePriceHistory(t *testing.T) {
+	tests := []struct {
+		name  string
+		page  string
+		want  []uint64
+		dated bool
+	}{
+		{
+			name: "list items sorted by date",
+			page: `<section data-testid="price-history"><ul>
+				<li><span>Reduced to £525,000</span><span>3rd Mar 2021</span></li>
+				<li><span>First listed</span><span>£550,000</span><span>14th Jan 2021</span></li>
+			</ul></section>`,
+			want:  []uint64{550000, 525000},
+			dated: true,
+		},
+		{
+			name:  "comma separated clauses",
+			page:  `<div data-testid="price-history">First listed at £700,000 on 2nd Feb 2021, reduced to £650,000 on 1st Apr 2021</div>`,
+			want:  []uint64{700000, 650000},
+			dated: true,
+		},
+		{
+			name: "undated entries keep page order",
+			page: `<section data-testid="price-history"><ul>
+				<li>Reduced to £480,000</li><li>Listed at £500,000 on 1st Jan 2021</li>
+			</ul></section>`,
+			want: []uint64{480000, 500000},
+		},
+		{
+			name:  "entries without prices are skipped",
+			page:  `<section data-testid="price-history"><ul><li>Listed</li><li>£400,000 on 5th May 2020</li></ul></section>`,
+			want:  []uint64{400000},
+			dated: true,
+		},
+		{
+			name: "no section",
+			page: `<p>£400,000</p>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := parsePriceHistory(mustParseHTML(t, tt.page))
+			if len(history) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d: %+v", len(history), len(tt.want), history)
+			}
+			for i, p := range history {
+				if p.Price != tt.want[i] {
+					t.Errorf("entry %d price = %d, want %d", i, p.Price, tt.want[i])
+				}
+				if tt.dated && p.Date == nil {
+					t.Errorf("entry %d has no date", i)
+				}
+			}
+		})
+	}
+}
+
+func TestJoinedText(t *testing.T) {
+	root := mustParseHTML(t, `<li><span>£550,000</span><span>3rd Mar 2021</span></li>`)
+	if got, want := joinedText(root), "£550,000 3rd Mar 2021"; got != want {
+		t.Errorf("joinedText() = %q, want %q", got, want)
+	}
+}
+
+func TestCalculateReductionStats(t *testing.T) {
+	d := date(2021, time.January, 1)
+	history := func(prices ...uint64) []pricePoint {
+		points := make([]pricePoint, len(prices))
+		for i, p := range prices {
+			points[i] = pricePoint{Date: &d, Price: p}
+		}
+		return points
+	}
+
+	listings := []listing{
+		{PriceHistory: history(500000, 450000)},
+		{PriceHistory: history(400000, 380000, 300000)},
+		{PriceHistory: history(300000, 320000)},
+		{PriceHistory: history(250000)},
+		{},
+	}
+
+	s := calculateReductionStats(listings)
+	if s.withHistory != 4 || s.reduced != 2 {
+		t.Fatalf("got %d reduced of %d, want 2 of 4", s.reduced, s.withHistory)
+	}
+	if s.meanReduction != 75000 {
+		t.Errorf("mean reduction = %v, want 75000", s.meanReduction)
+	}
+	if want := (10.0 + 25.0) / 2; math.Abs(s.meanReductionPct-want) > 1e-9 {
+		t.Errorf("mean reduction = %v%%, want %v%%", s.meanReductionPct, want)
+	}
+
+	if got := calculateReductionStats(nil).String(); got != "no price history available" {
+		t.Errorf("empty stats = %q", got)
+	}
+}
@@ -0,0 +1,271 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	hpiURL         = "https://landregistry.data.gov.uk/app/ukhpi/download/new.csv"
+	hpiRegionURI   = "http://landregistry.data.gov.uk/id/region/"
+	hpiMonthLayout = "2006-01"
+
+	// hpiCacheMaxAge is how long a cached index is used before it's
+	// fetched again. The index is only published monthly.
+	hpiCacheMaxAge = 7 * 24 * time.Hour
+)
+
+// hpiPoint is the UK House Price Index for all property types in a month.
+type hpiPoint struct {
+	Month string  `json:"month"`
+	Index float64 `json:"index"`
+}
+
+// hpiSource looks up a region's index for the months between two dates.
+type hpiSource interface {
+	indexSeries(ctx context.Context, region string, from, to time.Time) ([]hpiPoint, error)
+}
+
+// httpHPISource downloads the index as CSV from the UK HPI site.
+type httpHPISource struct {
+	f       *fetcher
+	baseURL string
+}
+
+func (s *httpHPISource) indexSeries(ctx context.Context, region string, from, to time.Time) ([]hpiPoint, error) {
+	u, err := url.Parse(s.baseURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "while parsing HPI URL")
+	}
+
+	u.RawQuery = url.Values{
+		"location": {hpiRegionURI + region},
+		"from":     {from.Format("2006-01-02")},
+		"to":       {to.Format("2006-01-02")},
+		"thm[]":    {"property_type"},
+		"in[]":     {"hpi"},
+	}.Encode()
+
+	var series []hpiPoint
+	err = s.f.fetch(ctx, u, func(body io.Reader) error {
+		var err error
+		series, err = parseHPICSV(body)
+		return err
+	})
+
+	return series, errors.Wrapf(err, "while fetching house price index for %s", region)
+}
+
+// parseHPICSV reads the "Period" column and the first "House price index"
+// column, which is for all property types, ordering the months.
+func parseHPICSV(r io.Reader) ([]hpiPoint, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(err, "while reading HPI CSV")
+	}
+	if len(records) == 0 {
+		return nil, errors.New("HPI CSV is empty")
+	}
+
+	periodCol, indexCol := -1, -1
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == "period" && periodCol < 0:
+			periodCol = i
+		case strings.HasPrefix(name, "house price index") && indexCol < 0:
+			indexCol = i
+		}
+	}
+	if periodCol < 0 || indexCol < 0 {
+		return nil, errors.New("HPI CSV has no period or index column")
+	}
+
+	var series []hpiPoint
+	for row, record := range records[1:] {
+		if len(record) <= periodCol || len(record) <= indexCol || record[indexCol] == "" {
+			continue
+		}
+
+		month, err := time.Parse(hpiMonthLayout, record[periodCol])
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing period on HPI row %d", row+2)
+		}
+		index, err := strconv.ParseFloat(record[indexCol], 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing index on HPI row %d", row+2)
+		}
+
+		series = append(series, hpiPoint{Month: month.Format(hpiMonthLayout), Index: index})
+	}
+
+	sort.Slice(series, func(i, j int) bool { return series[i].Month < series[j].Month })
+	return series, nil
+}
+
+type hpiCache struct {
+	Region  string     `json:"region"`
+	Fetched time.Time  `json:"fetched"`
+	From    string     `json:"from"`
+	Series  []hpiPoint `json:"series"`
+}
+
+// defaultHPICacheFile is in the user's cache directory, with a file per
+// region.
+func defaultHPICacheFile(region string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", errors.Wrap(err, "while finding cache directory")
+	}
+
+	return filepath.Join(dir, programName, "hpi-"+region+".json"), nil
+}
+
+// loadHPI returns the index for region back to from, using the cache file
+// if it's recent and goes back far enough.
+func loadHPI(ctx context.Context, src hpiSource, cacheFile, region string, from time.Time) ([]hpiPoint, error) {
+	first := from.Format(hpiMonthLayout)
+
+	if data, err := ioutil.ReadFile(cacheFile); err == nil {
+		var c hpiCache
+		if err := json.Unmarshal(data, &c); err != nil {
+			log.Printf("warning: ignoring unreadable HPI cache %s: %v", cacheFile, err)
+		} else if c.Region == region && c.From <= first && timeNow().Sub(c.Fetched) < hpiCacheMaxAge {
+			return c.Series, nil
+		}
+	}
+
+	series, err := src.indexSeries(ctx, region, from, timeNow())
+	if err != nil {
+		return nil, err
+	}
+	if len(series) == 0 {
+		return nil, errors.Errorf("no house price index found for %s", region)
+	}
+
+	data, err := json.Marshal(hpiCache{Region: region, Fetched: timeNow().UTC(), From: first, Series: series})
+	if err != nil {
+		return nil, errors.Wrap(err, "while marshalling HPI cache")
+	}
+	if err := os.MkdirAll(filepath.Dir(cacheFile), 0755); err != nil {
+		log.Print("warning: cannot cache house price index: ", err)
+	} else if err := writeFileAtomic(cacheFile, data); err != nil {
+		log.Print("warning: cannot cache house price index: ", err)
+	}
+
+	return series, nil
+}
+
+// indexAt returns the index for month. Months missing between two that are
+// known are interpolated, and months after the latest published carry its
+// value forward, with a note of either. Months before the series starts
+// can't be indexed.
+func indexAt(series []hpiPoint, month time.Time) (index float64, note string, err error) {
+	key := month.Format(hpiMonthLayout)
+	i := sort.Search(len(series), func(i int) bool { return series[i].Month >= key })
+	if i < len(series) && series[i].Month == key {
+		return series[i].Index, "", nil
+	}
+
+	if i == 0 {
+		return 0, "", errors.Errorf("no house price index for %s or earlier", key)
+	}
+
+	prev := series[i-1]
+	if i == len(series) {
+		return prev.Index, fmt.Sprintf("no index published for %s yet, used %s's", key, prev.Month), nil
+	}
+
+	next := series[i]
+	prevMonth, _ := time.Parse(hpiMonthLayout, prev.Month)
+	nextMonth, _ := time.Parse(hpiMonthLayout, next.Month)
+	frac := float64(monthsBetween(prevMonth, month)) / float64(monthsBetween(prevMonth, nextMonth))
+
+	return prev.Index + frac*(next.Index-prev.Index), fmt.Sprintf("interpolated the index for %s", key), nil
+}
+
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+// rebaseTrend adds the runs' mean and median in the latest run's money,
+// deflating each by the index for the month it was run, and fits the trend
+// of the adjusted medians.
+func rebaseTrend(r *trendReport, series []hpiPoint) error {
+	if len(r.Runs) == 0 {
+		return nil
+	}
+
+	latest, note, err := indexAt(series, r.Runs[len(r.Runs)-1].time)
+	if err != nil {
+		return err
+	}
+
+	var notes []string
+	if note != "" {
+		notes = append(notes, note)
+	}
+
+	adjusted := make([]trendPoint, len(r.Runs))
+	for i := range r.Runs {
+		p := &r.Runs[i]
+		index, note, err := indexAt(series, p.time)
+		if err != nil {
+			return err
+		}
+		if note != "" && !containsString(notes, note) {
+			notes = append(notes, note)
+		}
+
+		factor := latest / index
+		mean, median := p.Mean*factor, p.Median*factor
+		p.Index, p.RealMean, p.RealMedian = &index, &mean, &median
+
+		adjusted[i] = *p
+		adjusted[i].Median = median
+	}
+
+	r.RealTrend = fitTrend(adjusted)
+	r.HPINotes = notes
+	return nil
+}
+
+func runHPIAdjustment(ctx context.Context, r *trendReport, args *trendArgs) error {
+	if len(r.Runs) == 0 {
+		return nil
+	}
+
+	cacheFile := args.HPICache
+	if cacheFile == "" {
+		var err error
+		if cacheFile, err = defaultHPICacheFile(args.HPIRegion); err != nil {
+			return err
+		}
+	}
+
+	src := &httpHPISource{
+		f:       &fetcher{client: http.DefaultClient, delay: defaultDelay, retries: defaultRetries, backoff: defaultBackoff},
+		baseURL: hpiURL,
+	}
+	series, err := loadHPI(ctx, src, cacheFile, args.HPIRegion, r.Runs[0].time.AddDate(0, -1, 0))
+	if err != nil {
+		return err
+	}
+
+	return rebaseTrend(r, series)
+}
@@ -0,0 +1,351 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const hpiCSV = `Name,URI,Period,House price index All property types,House price index Detached
+London,http://landregistry.data.gov.uk/id/region/london,2021-03,110,120
+London,http://landregistry.data.gov.uk/id/region/london,2020-12,98,99
+London,http://landregistry.data.gov.uk/id/region/london,2021-01,100,101
+London,http://landregistry.data.gov.uk/id/region/london,2021-02,,
+`
+
+// hpiSeries has no index for February 2021.
+func hpiSeries() []hpiPoint {
+	return []hpiPoint{{"2020-12", 98}, {"2021-01", 100}, {"2021-03", 110}}
+}
+
+func TestParseHPICSV(t *testing.T) {
+	got, err := parseHPICSV(strings.NewReader(hpiCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := hpiSeries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseHPICSVErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr string
+	}{
+		{"empty", "", "HPI CSV is empty"},
+		{"no index column", "Name,Period\nLondon,2021-01\n", "HPI CSV has no period or index column"},
+		{"bad period", "Period,House price index\nJan 2021,100\n", "while parsing period on HPI row 2"},
+		{"bad index", "Period,House price index\n2021-01,100\n2021-02,high\n", "while parsing index on HPI row 3"},
+		{"bad CSV", "Period,House price index\n2021-01,\"100\n", "while reading HPI CSV"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseHPICSV(strings.NewReader(tt.csv))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTTPHPISource(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(hpiCSV))
+	}))
+	defer srv.Close()
+
+	src := &httpHPISource{f: &fetcher{client: srv.Client()}, baseURL: srv.URL}
+	series, err := src.indexSeries(context.Background(), "london", date(2020, time.December, 1), date(2021, time.May, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 3 {
+		t.Errorf("got %v, want 3 months", series)
+	}
+
+	want := url.Values{
+		"location": {"http://landregistry.data.gov.uk/id/region/london"},
+		"from":     {"2020-12-01"},
+		"to":       {"2021-05-10"},
+		"thm[]":    {"property_type"},
+		"in[]":     {"hpi"},
+	}
+	if !reflect.DeepEqual(query, want) {
+		t.Errorf("got query %v, want %v", query, want)
+	}
+}
+
+// fakeHPISource serves a fixed series, counting how often it's asked and
+// failing if the context it's given is done.
+type fakeHPISource struct {
+	series []hpiPoint
+	calls  int
+}
+
+func (s *fakeHPISource) indexSeries(ctx context.Context, _ string, _, _ time.Time) ([]hpiPoint, error) {
+	s.calls++
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return s.series, nil
+}
+
+func writeHPICache(t *testing.T, filename string, c hpiCache) {
+	t.Helper()
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadHPICache(t *testing.T) {
+	now := time.Date(2021, time.May, 10, 9, 0, 0, 0, time.UTC)
+	cached := []hpiPoint{{"2021-01", 1}}
+	fresh := hpiCache{Region: "london", Fetched: now.Add(-time.Hour), From: "2021-01", Series: cached}
+
+	stale := fresh
+	stale.Fetched = now.Add(-hpiCacheMaxAge)
+	otherRegion := fresh
+	otherRegion.Region = "wales"
+	tooRecent := fresh
+	tooRecent.From = "2021-02"
+
+	tests := []struct {
+		name      string
+		cache     *hpiCache
+		raw       string
+		wantCache bool
+	}{
+		{name: "fresh", cache: &fresh, wantCache: true},
+		{name: "stale", cache: &stale},
+		{name: "other region", cache: &otherRegion},
+		{name: "doesn't go back far enough", cache: &tooRecent},
+		{name: "unreadable", raw: "{"},
+		{name: "missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setNow(t, now)
+			// The cache's directory is made when it's first written.
+			cacheFile := filepath.Join(t.TempDir(), "cache", "hpi-london.json")
+			switch {
+			case tt.cache != nil:
+				cacheFile = filepath.Join(t.TempDir(), "hpi-london.json")
+				writeHPICache(t, cacheFile, *tt.cache)
+			case tt.raw != "":
+				cacheFile = filepath.Join(t.TempDir(), "hpi-london.json")
+				if err := ioutil.WriteFile(cacheFile, []byte(tt.raw), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			src := &fakeHPISource{series: hpiSeries()}
+			series, err := loadHPI(context.Background(), src, cacheFile, "london", date(2021, time.January, 1))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.wantCache {
+				if src.calls != 0 || !reflect.DeepEqual(series, cached) {
+					t.Errorf("got %v after %d fetches, want the cached series", series, src.calls)
+				}
+				return
+			}
+			if src.calls != 1 || !reflect.DeepEqual(series, hpiSeries()) {
+				t.Errorf("got %v after %d fetches, want a fetched series", series, src.calls)
+			}
+
+			// The fetched series is cached for next time.
+			again, err := loadHPI(context.Background(), src, cacheFile, "london", date(2021, time.January, 1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if src.calls != 1 || !reflect.DeepEqual(again, hpiSeries()) {
+				t.Errorf("got %v after %d fetches, want it from the cache", again, src.calls)
+			}
+		})
+	}
+}
+
+func TestLoadHPIErrors(t *testing.T) {
+	cacheFile := filepath.Join(t.TempDir(), "hpi-london.json")
+
+	_, err := loadHPI(context.Background(), &fakeHPISource{}, cacheFile, "london", date(2021, time.January, 1))
+	if err == nil || !strings.Contains(err.Error(), "no house price index found for london") {
+		t.Errorf("got %v, want an error for an empty series", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = loadHPI(ctx, &fakeHPISource{series: hpiSeries()}, cacheFile, "london", date(2021, time.January, 1))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want the context's error", err)
+	}
+}
+
+func TestIndexAt(t *testing.T) {
+	tests := []struct {
+		month   time.Time
+		index   float64
+		note    string
+		wantErr string
+	}{
+		{month: date(2021, time.January, 15), index: 100},
+		{month: date(2020, time.December, 1), index: 98},
+		{month: date(2021, time.February, 1), index: 105, note: "interpolated the index for 2021-02"},
+		{month: date(2021, time.March, 31), index: 110},
+		{month: date(2021, time.May, 1), index: 110, note: "no index published for 2021-05 yet, used 2021-03's"},
+		{month: date(2020, time.November, 30), wantErr: "no house price index for 2020-11 or earlier"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.month.Format("2006-01-02"), func(t *testing.T) {
+			index, note, err := indexAt(hpiSeries(), tt.month)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("got %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !closeTo(index, tt.index) || note != tt.note {
+				t.Errorf("got %v, %q, want %v, %q", index, note, tt.index, tt.note)
+			}
+		})
+	}
+}
+
+func TestIndexAtInterpolatesByMonth(t *testing.T) {
+	series := []hpiPoint{{"2021-01", 100}, {"2021-05", 120}}
+
+	for month, want := range map[time.Month]float64{time.February: 105, time.March: 110, time.April: 115} {
+		if got, _, _ := indexAt(series, date(2021, month, 1)); !closeTo(got, want) {
+			t.Errorf("got %v for %s, want %v", got, month, want)
+		}
+	}
+}
+
+func TestMonthsBetween(t *testing.T) {
+	tests := []struct {
+		from, to time.Time
+		want     int
+	}{
+		{date(2021, time.January, 31), date(2021, time.February, 1), 1},
+		{date(2020, time.November, 1), date(2021, time.February, 28), 3},
+		{date(2021, time.March, 1), date(2021, time.March, 31), 0},
+		{date(2021, time.March, 1), date(2020, time.March, 1), -12},
+	}
+
+	for _, tt := range tests {
+		if got := monthsBetween(tt.from, tt.to); got != tt.want {
+			t.Errorf("monthsBetween(%v, %v) = %d, want %d", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestRebaseTrend(t *testing.T) {
+	sw2 := runQuery{Postcode: "SW2"}
+	r := analyseTrend([]runEntry{
+		{Timestamp: "2021-01-01T00:00:00Z", Query: sw2, Prices: []uint64{400000}},
+		{Timestamp: "2021-02-01T00:00:00Z", Query: sw2, Prices: []uint64{420000}},
+		{Timestamp: "2021-05-01T00:00:00Z", Query: sw2, Prices: []uint64{450000}},
+	}, defaultTrendMoves)
+
+	if err := rebaseTrend(&r, hpiSeries()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each run is in May's money, whose index carries March's 110 forward.
+	want := []struct {
+		index, realMedian float64
+	}{
+		{100, 440000},
+		{105, 440000},
+		{110, 450000},
+	}
+	for i, w := range want {
+		p := r.Runs[i]
+		if p.Index == nil || p.RealMean == nil || p.RealMedian == nil {
+			t.Fatalf("run %d wasn't adjusted: %+v", i, p)
+		}
+		if !closeTo(*p.Index, w.index) || !closeTo(*p.RealMedian, w.realMedian) || !closeTo(*p.RealMean, w.realMedian) {
+			t.Errorf("run %d: got index %v, real median %v, mean %v, want %v, %v", i, *p.Index, *p.RealMedian, *p.RealMean, w.index, w.realMedian)
+		}
+	}
+
+	if r.RealTrend == nil || !closeTo(r.RealTrend.SlopePerDay, 89.76892019585947) || !closeTo(r.RealTrend.PercentPer30Days, 0.607458858468222) {
+		t.Errorf("got real trend %+v", r.RealTrend)
+	}
+	// The unadjusted medians and their trend are left alone.
+	if r.Runs[0].Median != 400000 || r.Trend == nil || !closeTo(r.Trend.SlopePerDay, 399.0207026887725) {
+		t.Errorf("got median %v and trend %+v", r.Runs[0].Median, r.Trend)
+	}
+
+	wantNotes := []string{"no index published for 2021-05 yet, used 2021-03's", "interpolated the index for 2021-02"}
+	if !reflect.DeepEqual(r.HPINotes, wantNotes) {
+		t.Errorf("got notes %q, want %q", r.HPINotes, wantNotes)
+	}
+}
+
+func TestRebaseTrendErrors(t *testing.T) {
+	r := analyseTrend([]runEntry{{Timestamp: "2020-06-01T00:00:00Z", Prices: []uint64{400000}}}, defaultTrendMoves)
+	if err := rebaseTrend(&r, hpiSeries()); err == nil {
+		t.Error("rebased a run from before the index starts")
+	}
+
+	empty := analyseTrend(nil, defaultTrendMoves)
+	if err := rebaseTrend(&empty, nil); err != nil || empty.RealTrend != nil {
+		t.Errorf("got %v, %+v for no runs", err, empty.RealTrend)
+	}
+}
+
+func TestRunHPIAdjustmentUsesContext(t *testing.T) {
+	r := analyseTrend([]runEntry{{Timestamp: "2021-01-01T00:00:00Z", Prices: []uint64{400000}}}, defaultTrendMoves)
+	args := &trendArgs{HPIRegion: "london", HPICache: filepath.Join(t.TempDir(), "hpi-london.json")}
+
+	// A cancelled run gives up rather than fetching the index.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := runHPIAdjustment(ctx, &r, args); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want the context's error", err)
+	}
+}
+
+func TestRunHPIAdjustmentFromCache(t *testing.T) {
+	setNow(t, time.Date(2021, time.May, 10, 9, 0, 0, 0, time.UTC))
+	args := &trendArgs{HPIRegion: "london", HPICache: filepath.Join(t.TempDir(), "hpi-london.json")}
+	writeHPICache(t, args.HPICache, hpiCache{Region: "london", Fetched: timeNow(), From: "2020-12", Series: hpiSeries()})
+
+	r := analyseTrend([]runEntry{
+		{Timestamp: "2021-01-01T00:00:00Z", Prices: []uint64{400000}},
+		{Timestamp: "2021-03-01T00:00:00Z", Prices: []uint64{450000}},
+	}, defaultTrendMoves)
+	if err := runHPIAdjustment(context.Background(), &r, args); err != nil {
+		t.Fatal(err)
+	}
+	if r.Runs[0].RealMedian == nil || !closeTo(*r.Runs[0].RealMedian, 440000) {
+		t.Errorf("got %+v", r.Runs[0])
+	}
+}
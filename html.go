@@ -0,0 +1,85 @@
+package zoopla
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func getAttr(n *html.Node, key string) (string, bool) {
+	for i := range n.Attr {
+		if n.Attr[i].Key == key {
+			return n.Attr[i].Val, true
+		}
+	}
+
+	return "", false
+}
+
+func hasClass(n *html.Node, substr string) bool {
+	class, ok := getAttr(n, "class")
+	return ok && strings.Contains(class, substr)
+}
+
+func findFirst(root *html.Node, match func(n *html.Node) bool) *html.Node {
+	if match(root) {
+		return root
+	}
+
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, match); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// findAll returns every node matching the predicate. Matching nodes are not
+// descended into, so nested matches are not returned.
+func findAll(root *html.Node, match func(n *html.Node) bool) []*html.Node {
+	var found []*html.Node
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if match(n) {
+			found = append(found, n)
+			return
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	return found
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return sb.String()
+}
@@ -0,0 +1,56 @@
+package zoopla
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// mustParseHTML parses a page or fragment for tests.
+func mustParseHTML(t testing.TB, s string) *html.Node {
+	t.Helper()
+
+	root, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		t.Fatalf("parsing HTML: %v", err)
+	}
+
+	return root
+}
+
+// mustParseCard parses a fragment holding one listing card and returns the
+// card's node.
+func mustParseCard(t testing.TB, s string) *html.Node {
+	t.Helper()
+
+	card := findFirst(mustParseHTML(t, s), isListingCard)
+	if card == nil {
+		t.Fatal("no listing card in HTML")
+	}
+
+	return card
+}
+
+func TestTextContent(t *testing.T) {
+	root := mustParseHTML(t, `<div id="a">one <b>two</b> <i>three</i></div>`)
+	n := findFirst(root, func(n *html.Node) bool {
+		id, _ := getAttr(n, "id")
+		return id == "a"
+	})
+	if n == nil {
+		t.Fatal("no node with id a")
+	}
+
+	if got, want := textContent(n), "one two three"; got != want {
+		t.Errorf("textContent() = %q, want %q", got, want)
+	}
+}
+
+func TestFindAllSkipsNested(t *testing.T) {
+	root := mustParseHTML(t, `<div class="x"><div class="x"></div></div><div class="x"></div>`)
+	found := findAll(root, func(n *html.Node) bool { return n.Type == html.ElementNode && hasClass(n, "x") })
+	if len(found) != 2 {
+		t.Errorf("findAll() found %d nodes, want 2", len(found))
+	}
+}
@@ -0,0 +1,240 @@
+package zoopla
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	svgWidth   = 720
+	svgHeight  = 300
+	svgPadding = 40
+)
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"price":     formatPrice,
+	"meanPrice": formatMeanPrice,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Price report for {{.Data.Query.Postcode}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+th { background: #ddebf7; }
+th.sortable { cursor: pointer; }
+td.num { text-align: right; }
+svg rect.bar { fill: #4a7ebb; }
+svg polyline.kde { fill: none; stroke: #d62728; stroke-width: 2; }
+svg text { font-size: 11px; }
+</style>
+</head>
+<body>
+<h1>Price report for {{.Data.Query.Postcode}}</h1>
+<p>Generated {{.Data.GeneratedAt}}.</p>
+{{with .Data.Stats}}
+<h2>Price stats</h2>
+<table>
+<tr><th>Count</th><th>Min</th><th>Max</th><th>Mean</th><th>Median</th><th>Std dev</th></tr>
+<tr><td class="num">{{.Count}}</td><td class="num">{{price .Min}}</td><td class="num">{{price .Max}}</td><td class="num">{{meanPrice .Mean}}</td><td class="num">{{meanPrice .Median}}</td><td class="num">{{meanPrice .Stddev}}</td></tr>
+</table>
+{{else}}
+<p>No prices were found.</p>
+{{end}}
+{{with .Data.Sold}}
+<p>Sold prices: {{.}}.</p>
+{{end}}
+{{with .Data.Yield}}
+<h2>Gross yield</h2>
+<p>{{.}}.</p>
+<table>
+<tr><th>Bedrooms</th><th>Sales</th><th>Rents</th><th>Median price</th><th>Median rent</th><th>Yield</th></tr>
+{{range $.Data.YieldGroups}}<tr><td>{{.Key}}</td><td class="num">{{.Sales}}</td><td class="num">{{.Rents}}</td><td class="num">{{if .Sales}}{{meanPrice .MedianPrice}}{{else}}-{{end}}</td><td class="num">{{if .Rents}}{{meanPrice .MedianRent}} pcm{{else}}-{{end}}</td><td class="num">{{if .Estimated}}{{printf "%.1f%%" .Yield}}{{else}}-{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+{{with .Data.Beds}}
+<h2>By bedrooms</h2>
+{{template "groups" .}}
+{{end}}
+{{with .Data.Types}}
+<h2>By property type</h2>
+{{template "groups" .}}
+{{end}}
+{{with .Data.FlatsVsHouses}}
+<p>Flats vs houses: {{.}}.</p>
+{{end}}
+{{with .Data.NewBuilds}}
+<p>New builds vs resales: {{.}}.</p>
+{{end}}
+{{with .Data.Softness}}
+<h2>Market softness</h2>
+<table>
+<tr><th>Measure</th><th>Value</th><th>Listings</th></tr>
+<tr><td>Ever reduced</td><td class="num">{{printf "%.0f%%" .ReducedShare}}</td><td class="num">{{.Reduced}} of {{.Listings}}</td></tr>
+{{with .Reduction}}<tr><td>Mean reduction</td><td class="num">{{meanPrice .Mean}} ({{printf "%.1f%%" .MeanPct}})</td><td class="num">{{.Count}}</td></tr>
+<tr><td>Median reduction</td><td class="num">{{meanPrice .Median}} ({{printf "%.1f%%" .MedianPct}})</td><td class="num">{{.Count}}</td></tr>
+{{end}}{{with .Days}}<tr><td>Mean days to first reduction</td><td class="num">{{printf "%.0f" .Mean}}</td><td class="num">{{.Count}}</td></tr>
+{{end}}</table>
+{{end}}
+{{with .Data.OnMarket}}
+<h2>Time on market</h2>
+<table>
+<tr><th>Measure</th><th>Value</th></tr>
+<tr><td>Median days listed</td><td class="num">{{printf "%.0f" .MedianDays}}</td></tr>
+<tr><td>P90 days listed</td><td class="num">{{printf "%.0f" .P90Days}}</td></tr>
+<tr><td>Listed over {{.StaleDays}} days</td><td class="num">{{printf "%.0f%%" .StaleShare}} ({{.Stale}} of {{.Dated}})</td></tr>
+</table>
+{{with .Stalest}}<table>
+<tr><th>Days</th><th>Price</th><th>Listing</th></tr>
+{{range .}}<tr><td class="num">{{.Days}}</td><td class="num">{{price .Price}}</td><td>{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}} {{.Address}}</td></tr>
+{{end}}</table>
+{{end}}<p>Listed dates for {{.Dated}} of {{.Listings}} listings.</p>
+{{end}}
+{{with .Data.PricePerSqft}}
+<h2>Price per sq ft</h2>
+<table>
+<tr><th>Type</th><th>Count</th><th>Min</th><th>P25</th><th>Median</th><th>Mean</th><th>P75</th><th>Max</th></tr>
+{{template "sqft" .All}}{{range .ByType}}{{template "sqft" .}}{{end}}</table>
+<p>Area data for {{.WithArea}} of {{.Total}} listings ({{printf "%.0f%%" .Coverage}}).{{if .LowCoverage}} Coverage is low, so these may be biased towards new builds.{{end}}</p>
+{{end}}
+{{with .Data.Agents}}
+<h2>Top agents</h2>
+<table>
+<tr><th>Agent</th><th>Count</th><th>Share</th><th>Mean</th><th>Median</th><th>Vs area</th></tr>
+{{range .}}<tr><td>{{.Name}}{{if .Flagged}} *{{end}}</td><td class="num">{{.Count}}</td><td class="num">{{printf "%.1f%%" .Share}}</td><td class="num">{{meanPrice .Mean}}</td><td class="num">{{meanPrice .Median}}</td><td class="num">{{printf "%+.1f%%" .Deviation}}</td></tr>
+{{end}}</table>
+{{with $.Data.AgentMargin}}<p>* Median is more than {{.}} from the area median.</p>
+{{end}}{{end}}
+{{with .Chart}}
+<h2>Distribution</h2>
+{{.}}
+{{end}}
+{{if .Data.Listings}}
+<h2>Listings</h2>
+<table id="listings">
+<thead><tr><th class="sortable" data-type="num">Price</th><th class="sortable" data-type="num">Beds</th><th class="sortable">Type</th><th class="sortable">Listing</th><th class="sortable">Agent</th></tr></thead>
+<tbody>
+{{range .Data.Listings}}<tr><td class="num" data-value="{{.Price}}">{{price .Price}}</td><td class="num" data-value="{{.Beds}}">{{.Beds}}</td><td>{{.Type}}</td><td>{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}} {{.Address}}</td><td>{{.Agent}}</td></tr>
+{{end}}</tbody>
+</table>
+<script>
+document.querySelectorAll("#listings th.sortable").forEach(function (th, col) {
+  var asc = true;
+  th.addEventListener("click", function () {
+    var body = document.querySelector("#listings tbody");
+    var rows = Array.prototype.slice.call(body.rows);
+    var num = th.dataset.type === "num";
+    rows.sort(function (a, b) {
+      var x = a.cells[col].dataset.value || a.cells[col].textContent;
+      var y = b.cells[col].dataset.value || b.cells[col].textContent;
+      var c = num ? (parseFloat(x) || 0) - (parseFloat(y) || 0) : x.localeCompare(y);
+      return asc ? c : -c;
+    });
+    asc = !asc;
+    rows.forEach(function (r) { body.appendChild(r); });
+  });
+});
+</script>
+{{end}}
+</body>
+</html>
+{{define "groups"}}<table>
+<tr><th>Group</th><th>Count</th><th>Mean</th><th>Median</th><th>P25</th><th>P75</th></tr>
+{{range .}}<tr><td>{{.Key}}</td><td class="num">{{.Count}}</td><td class="num">{{meanPrice .Mean}}</td><td class="num">{{meanPrice .Median}}</td><td class="num">{{meanPrice .P25}}</td><td class="num">{{meanPrice .P75}}</td></tr>
+{{end}}</table>{{end}}
+{{define "sqft"}}<tr><td>{{.Key}}</td><td class="num">{{.Count}}</td><td class="num">{{meanPrice .Min}}</td><td class="num">{{meanPrice .P25}}</td><td class="num">{{meanPrice .Median}}</td><td class="num">{{meanPrice .Mean}}</td><td class="num">{{meanPrice .P75}}</td><td class="num">{{meanPrice .Max}}</td></tr>
+{{end}}`))
+
+func renderHTMLReport(r *reportData) ([]byte, error) {
+	var buf bytes.Buffer
+	err := htmlReportTemplate.Execute(&buf, struct {
+		Data  *reportData
+		Chart template.HTML
+	}{r, histogramSVG(r.Histogram, r.KDE, svgWidth, svgHeight)})
+	if err != nil {
+		return nil, errors.Wrap(err, "while rendering HTML report")
+	}
+
+	return buf.Bytes(), nil
+}
+
+// histogramSVG draws buckets as a bar chart with the tallest bar filling the
+// plot height, overlaid with the density curve if there is one. It returns
+// an empty string when there's nothing to draw.
+func histogramSVG(buckets []histogramBucket, kde *kernelDensity, width, height int) template.HTML {
+	if len(buckets) == 0 {
+		return ""
+	}
+
+	maxCount := 0
+	for _, b := range buckets {
+		if b.Count > maxCount {
+			maxCount = b.Count
+		}
+	}
+
+	plotWidth := float64(width - 2*svgPadding)
+	plotHeight := float64(height - 2*svgPadding)
+	barWidth := plotWidth / float64(len(buckets))
+
+	var s strings.Builder
+	fmt.Fprintf(&s, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, height, width, height)
+	for i, b := range buckets {
+		barHeight := 0.0
+		if maxCount > 0 {
+			barHeight = plotHeight * float64(b.Count) / float64(maxCount)
+		}
+
+		x := float64(svgPadding) + float64(i)*barWidth
+		y := float64(svgPadding) + plotHeight - barHeight
+		fmt.Fprintf(&s, `<rect class="bar" x="%.1f" y="%.1f" width="%.1f" height="%.1f"><title>%s</title></rect>`,
+			x, y, barWidth*0.9, barHeight, template.HTMLEscapeString(b.String()))
+	}
+
+	if kde != nil && maxCount > 0 {
+		s.WriteString(kdePolyline(buckets, kde, plotWidth, plotHeight, maxCount))
+	}
+
+	baseline := float64(svgPadding) + plotHeight
+	fmt.Fprintf(&s, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#222"/>`, svgPadding, baseline, width-svgPadding, baseline)
+	fmt.Fprintf(&s, `<text x="%d" y="%.1f">%s</text>`, svgPadding, baseline+16, template.HTMLEscapeString(formatPrice(buckets[0].Min)))
+	fmt.Fprintf(&s, `<text x="%d" y="%.1f" text-anchor="end">%s</text>`, width-svgPadding, baseline+16, template.HTMLEscapeString(formatPrice(buckets[len(buckets)-1].Max)))
+	fmt.Fprintf(&s, `<text x="%d" y="%d" text-anchor="end">%d</text>`, svgPadding-4, svgPadding+4, maxCount)
+	s.WriteString(`</svg>`)
+
+	return template.HTML(s.String())
+}
+
+// kdePolyline scales the density to listings per bucket so that it lines up
+// with the bars, leaving out the parts of the curve beyond them.
+func kdePolyline(buckets []histogramBucket, kde *kernelDensity, plotWidth, plotHeight float64, maxCount int) string {
+	lo, hi := float64(buckets[0].Min), float64(buckets[len(buckets)-1].Max)
+	if hi <= lo {
+		return ""
+	}
+
+	total := 0
+	for _, b := range buckets {
+		total += b.Count
+	}
+	scale := float64(total) * float64(buckets[0].Max-buckets[0].Min)
+
+	var points []string
+	for _, p := range kde.Points {
+		if p.Price < lo || p.Price > hi {
+			continue
+		}
+		x := float64(svgPadding) + (p.Price-lo)/(hi-lo)*plotWidth
+		y := float64(svgPadding) + plotHeight - math.Min(p.Density*scale/float64(maxCount), 1)*plotHeight
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+
+	return `<polyline class="kde" points="` + strings.Join(points, " ") + `"/>`
+}
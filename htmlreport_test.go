@@ -0,0 +1,160 @@
+package zoopla
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var svgBarRE = regexp.MustCompile(`<rect class="bar" x="([0-9.]+)" y="([0-9.]+)" width="([0-9.]+)" height="([0-9.]+)">`)
+
+// svgBars returns the x, y, width and height of each bar in svg.
+func svgBars(t *testing.T, svg string) [][4]float64 {
+	t.Helper()
+
+	var bars [][4]float64
+	for _, m := range svgBarRE.FindAllStringSubmatch(svg, -1) {
+		var bar [4]float64
+		for i := range bar {
+			v, err := strconv.ParseFloat(m[i+1], 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bar[i] = v
+		}
+		bars = append(bars, bar)
+	}
+
+	return bars
+}
+
+func TestHistogramSVGScaling(t *testing.T) {
+	const width, height = 440, 280
+	plotHeight := float64(height - 2*svgPadding)
+
+	tests := []struct {
+		name    string
+		counts  []int
+		heights []float64
+	}{
+		{"tallest fills the plot", []int{2, 4, 1}, []float64{plotHeight / 2, plotHeight, plotHeight / 4}},
+		{"single bucket", []int{3}, []float64{plotHeight}},
+		{"empty bucket", []int{5, 0, 5}, []float64{plotHeight, 0, plotHeight}},
+		{"all empty", []int{0, 0}, []float64{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buckets []histogramBucket
+			for i, c := range tt.counts {
+				buckets = append(buckets, histogramBucket{Min: uint64(i) * 100000, Max: uint64(i+1) * 100000, Count: c})
+			}
+
+			bars := svgBars(t, string(histogramSVG(buckets, nil, width, height)))
+			if len(bars) != len(tt.heights) {
+				t.Fatalf("got %d bars, want %d", len(bars), len(tt.heights))
+			}
+
+			barWidth := float64(width-2*svgPadding) / float64(len(buckets))
+			for i, bar := range bars {
+				x, y, h := bar[0], bar[1], bar[3]
+				if diff := h - tt.heights[i]; diff < -0.1 || diff > 0.1 {
+					t.Errorf("bar %d height = %v, want %v", i, h, tt.heights[i])
+				}
+				// Bars stand on the baseline, side by side from the left.
+				if diff := y + h - (float64(svgPadding) + plotHeight); diff < -0.1 || diff > 0.1 {
+					t.Errorf("bar %d ends at %v, want the baseline %v", i, y+h, float64(svgPadding)+plotHeight)
+				}
+				if diff := x - (float64(svgPadding) + float64(i)*barWidth); diff < -0.1 || diff > 0.1 {
+					t.Errorf("bar %d x = %v", i, x)
+				}
+			}
+		})
+	}
+}
+
+func TestHistogramSVGEmpty(t *testing.T) {
+	if got := histogramSVG(nil, nil, svgWidth, svgHeight); got != "" {
+		t.Errorf("histogramSVG(nil) = %q, want empty", got)
+	}
+}
+
+func TestHistogramSVGLabels(t *testing.T) {
+	buckets := []histogramBucket{{Min: 200000, Max: 300000, Count: 1}, {Min: 300000, Max: 400000, Count: 7}}
+	got := string(histogramSVG(buckets, nil, svgWidth, svgHeight))
+
+	for _, want := range []string{"£200,000", "£400,000", ">7</text>", "<title>"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("SVG is missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "polyline") {
+		t.Error("SVG has a density curve without a KDE")
+	}
+}
+
+func TestHistogramSVGDensity(t *testing.T) {
+	buckets := []histogramBucket{{Min: 200000, Max: 300000, Count: 1}, {Min: 300000, Max: 400000, Count: 2}}
+	kde := &kernelDensity{Points: []kdePoint{{Price: 100000, Density: 1e-6}, {Price: 250000, Density: 1e-6}, {Price: 350000, Density: 2e-6}}}
+
+	got := string(histogramSVG(buckets, kde, svgWidth, svgHeight))
+	m := regexp.MustCompile(`<polyline class="kde" points="([^"]*)"`).FindStringSubmatch(got)
+	if m == nil {
+		t.Fatalf("SVG has no density curve:\n%s", got)
+	}
+	// The point below the first bucket is left out.
+	if n := len(strings.Fields(m[1])); n != 2 {
+		t.Errorf("density curve has %d points, want 2: %s", n, m[1])
+	}
+}
+
+func TestRenderHTMLReport(t *testing.T) {
+	setNow(t, date(2021, time.April, 1))
+
+	listings := reportListings()
+	listings[0].Title = `<script>alert("x")</script>`
+	data, err := renderHTMLReport(buildReport(listings, listingPrices(listings), reportArgs()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(data)
+	for _, want := range []string{
+		"<title>Price report for SW2</title>",
+		"<h2>Price stats</h2>",
+		"<h2>By bedrooms</h2>",
+		"<h2>By property type</h2>",
+		"<h2>Distribution</h2>",
+		`<table id="listings">`,
+		`&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	if strings.Contains(got, `<script>alert`) {
+		t.Error("listing title wasn't escaped")
+	}
+	if n := strings.Count(got, `<tr><td class="num" data-value=`); n != len(listings) {
+		t.Errorf("listings table has %d rows, want %d", n, len(listings))
+	}
+}
+
+func TestRenderHTMLReportNoPrices(t *testing.T) {
+	data, err := renderHTMLReport(buildReport(nil, nil, reportArgs()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(data)
+	if !strings.Contains(got, "No prices were found.") {
+		t.Error("report doesn't say no prices were found")
+	}
+	for _, s := range []string{"<svg", `<table id="listings">`, "<h2>Price stats</h2>"} {
+		if strings.Contains(got, s) {
+			t.Errorf("report has %q without prices", s)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package zoopla
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	influxRunMeasurement     = "zoopla_prices"
+	influxListingMeasurement = "zoopla_listing"
+	influxBatchSize          = 5000
+	allPropertyTypes         = "all"
+)
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
+
+// influxFields are a point's fields, written in key order so lines are
+// stable.
+type influxFields map[string]string
+
+func influxLine(measurement string, tags map[string]string, fields influxFields, timestamp int64) string {
+	var b strings.Builder
+	b.WriteString(influxMeasurementEscaper.Replace(measurement))
+
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&b, ",%s=%s", influxTagEscaper.Replace(k), influxTagEscaper.Replace(tags[k]))
+	}
+
+	keys = keys[:0]
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		sep := ","
+		if i == 0 {
+			sep = " "
+		}
+		fmt.Fprintf(&b, "%s%s=%s", sep, influxTagEscaper.Replace(k), fields[k])
+	}
+
+	fmt.Fprintf(&b, " %d", timestamp)
+	return b.String()
+}
+
+func influxFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func influxInt(v int64) string {
+	return strconv.FormatInt(v, 10) + "i"
+}
+
+func influxStatsFields(prices []uint64) influxFields {
+	sorted := make([]uint64, len(prices))
+	copy(sorted, prices)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	stats := calculatePriceStats(sorted)
+	return influxFields{
+		"count":  influxInt(int64(stats.count)),
+		"mean":   influxFloat(stats.mean),
+		"median": influxFloat(calculateMedian(sorted)),
+		"stddev": influxFloat(stats.stddev),
+		"p25":    influxFloat(quantile(sorted, 0.25)),
+		"p75":    influxFloat(quantile(sorted, 0.75)),
+	}
+}
+
+// influxLines builds a run point across all listings, one per property type
+// and, if perListing is set, one per listing.
+func influxLines(args *cliArgs, listings []listing, perListing bool) []string {
+	timestamp := timeNow().Unix()
+	runTags := func(propertyType string) map[string]string {
+		return map[string]string{
+			"postcode":      args.Postcode,
+			"beds":          bedsFilterLabel(args),
+			"property_type": propertyType,
+		}
+	}
+
+	var lines []string
+	if len(listings) > 0 {
+		lines = append(lines, influxLine(influxRunMeasurement, runTags(allPropertyTypes), influxStatsFields(listingPrices(listings)), timestamp))
+	}
+
+	var types []string
+	byType := make(map[string][]uint64)
+	for i := range listings {
+		t := propertyTypeKey(&listings[i])
+		if _, ok := byType[t]; !ok {
+			types = append(types, t)
+		}
+		byType[t] = append(byType[t], listings[i].Price)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		lines = append(lines, influxLine(influxRunMeasurement, runTags(t), influxStatsFields(byType[t]), timestamp))
+	}
+
+	if perListing {
+		for i := range listings {
+			l := &listings[i]
+			tags := map[string]string{
+				"postcode":      args.Postcode,
+				"listing_id":    l.ID,
+				"property_type": l.PropertyType,
+				"beds":          formatOptionalCount(l.Beds),
+			}
+			lines = append(lines, influxLine(influxListingMeasurement, tags, influxFields{"price": influxInt(int64(l.Price))}, timestamp))
+		}
+	}
+
+	return lines
+}
+
+// writeInflux writes points to an InfluxDB 2 bucket over the HTTP line
+// protocol API, gzipped and in batches.
+func writeInflux(ctx context.Context, args *cliArgs, listings []listing) error {
+	u, err := url.Parse(args.InfluxURL)
+	if err != nil {
+		return errors.Wrap(err, "while parsing InfluxDB URL")
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/v2/write"
+	q := u.Query()
+	q.Set("bucket", args.InfluxBucket)
+	if args.InfluxOrg != "" {
+		q.Set("org", args.InfluxOrg)
+	}
+	q.Set("precision", "s")
+	u.RawQuery = q.Encode()
+
+	header := make(http.Header)
+	header.Set("Content-Type", "text/plain; charset=utf-8")
+	header.Set("Content-Encoding", "gzip")
+	if args.InfluxToken != "" {
+		header.Set("Authorization", "Token "+args.InfluxToken)
+	}
+
+	lines := influxLines(args, listings, args.InfluxListings)
+	for start := 0; start < len(lines); start += influxBatchSize {
+		end := start + influxBatchSize
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		var body bytes.Buffer
+		for _, line := range lines[start:end] {
+			body.WriteString(line)
+			body.WriteByte('\n')
+		}
+
+		compressed, err := gzipBytes(body.Bytes())
+		if err != nil {
+			return err
+		}
+
+		if err := post(ctx, u.String(), compressed, header); err != nil {
+			return errors.Wrap(err, "while writing to InfluxDB")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package zoopla
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// influxPoint is a parsed line protocol line. Tag and field values are left
+// as written.
+type influxPoint struct {
+	measurement string
+	tags        map[string]string
+	fields      map[string]string
+	timestamp   string
+}
+
+// splitUnescaped splits s on sep where it isn't escaped by a backslash.
+func splitUnescaped(s string, sep byte) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+var influxUnescaper = strings.NewReplacer(`\,`, ",", `\ `, " ", `\=`, "=")
+
+func parseInfluxLine(t *testing.T, line string) influxPoint {
+	t.Helper()
+
+	sections := splitUnescaped(line, ' ')
+	if len(sections) != 3 {
+		t.Fatalf("line %q has %d sections, want 3", line, len(sections))
+	}
+
+	p := influxPoint{tags: map[string]string{}, fields: map[string]string{}, timestamp: sections[2]}
+	key := splitUnescaped(sections[0], ',')
+	p.measurement = influxUnescaper.Replace(key[0])
+	for _, kv := range key[1:] {
+		pair := splitUnescaped(kv, '=')
+		if len(pair) != 2 {
+			t.Fatalf("bad tag %q in %q", kv, line)
+		}
+		p.tags[influxUnescaper.Replace(pair[0])] = influxUnescaper.Replace(pair[1])
+	}
+	for _, kv := range splitUnescaped(sections[1], ',') {
+		pair := splitUnescaped(kv, '=')
+		if len(pair) != 2 {
+			t.Fatalf("bad field %q in %q", kv, line)
+		}
+		p.fields[influxUnescaper.Replace(pair[0])] = pair[1]
+	}
+
+	return p
+}
+
+func TestInfluxLine(t *testing.T) {
+	tests := []struct {
+		name        string
+		measurement string
+		tags        map[string]string
+		fields      influxFields
+		want        string
+	}{
+		{
+			name:        "sorted",
+			measurement: "zoopla_prices",
+			tags:        map[string]string{"postcode": "SW2", "beds": "any"},
+			fields:      influxFields{"mean": "1.5", "count": "2i"},
+			want:        "zoopla_prices,beds=any,postcode=SW2 count=2i,mean=1.5 1617235200",
+		},
+		{
+			name:        "escaped",
+			measurement: "a measurement,x",
+			tags:        map[string]string{"postcode": "SW2 1AA,SW3", "k=v": "a=b"},
+			fields:      influxFields{"price": "1i"},
+			want:        `a\ measurement\,x,k\=v=a\=b,postcode=SW2\ 1AA\,SW3 price=1i 1617235200`,
+		},
+		{
+			name:        "empty tags left out",
+			measurement: "zoopla_listing",
+			tags:        map[string]string{"listing_id": "", "postcode": "SW2"},
+			fields:      influxFields{"price": "1i"},
+			want:        "zoopla_listing,postcode=SW2 price=1i 1617235200",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := influxLine(tt.measurement, tt.tags, tt.fields, 1617235200)
+			if got != tt.want {
+				t.Errorf("influxLine() = %q, want %q", got, tt.want)
+			}
+
+			p := parseInfluxLine(t, got)
+			if p.measurement != tt.measurement {
+				t.Errorf("measurement parses as %q, want %q", p.measurement, tt.measurement)
+			}
+		})
+	}
+}
+
+func TestInfluxLines(t *testing.T) {
+	setNow(t, date(2021, time.April, 1))
+
+	args := &cliArgs{Postcode: "SW2", BedsMin: uint32Ptr(2)}
+	listings := []listing{
+		{ID: "1", Price: 300000, PropertyType: "flat", Beds: uint32Ptr(2)},
+		{ID: "2", Price: 500000, PropertyType: "flat", Beds: uint32Ptr(2)},
+		{ID: "3", Price: 700000, PropertyType: "terraced", Beds: uint32Ptr(3)},
+		{ID: "4", Price: 400000},
+	}
+
+	const timestamp = "1617235200"
+	want := []influxPoint{
+		{measurement: "zoopla_prices", tags: map[string]string{"postcode": "SW2", "beds": "2+", "property_type": "all"}, timestamp: timestamp,
+			fields: map[string]string{"count": "4i", "mean": "475000", "median": "450000", "stddev": "170782.5127659933", "p25": "375000", "p75": "550000"}},
+		{measurement: "zoopla_prices", tags: map[string]string{"postcode": "SW2", "beds": "2+", "property_type": "flat"}, timestamp: timestamp,
+			fields: map[string]string{"count": "2i", "mean": "400000", "median": "400000", "stddev": "141421.35623730952", "p25": "350000", "p75": "450000"}},
+		{measurement: "zoopla_prices", tags: map[string]string{"postcode": "SW2", "beds": "2+", "property_type": "terraced"}, timestamp: timestamp,
+			fields: map[string]string{"count": "1i", "mean": "700000", "median": "700000", "stddev": "0", "p25": "700000", "p75": "700000"}},
+		{measurement: "zoopla_prices", tags: map[string]string{"postcode": "SW2", "beds": "2+", "property_type": unknownGroup}, timestamp: timestamp,
+			fields: map[string]string{"count": "1i", "mean": "400000", "median": "400000", "stddev": "0", "p25": "400000", "p75": "400000"}},
+	}
+
+	runLines := influxLines(args, listings, false)
+	var got []influxPoint
+	for _, line := range runLines {
+		got = append(got, parseInfluxLine(t, line))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("influxLines() =\n%s\nwant %+v", strings.Join(runLines, "\n"), want)
+	}
+
+	lines := influxLines(args, listings, true)
+	if len(lines) != 8 {
+		t.Fatalf("got %d lines with listings, want 8", len(lines))
+	}
+	p := parseInfluxLine(t, lines[4])
+	if p.measurement != influxListingMeasurement || p.tags["listing_id"] != "1" || p.tags["beds"] != "2" || p.fields["price"] != "300000i" {
+		t.Errorf("listing point = %+v", p)
+	}
+
+	if lines := influxLines(args, nil, true); len(lines) != 0 {
+		t.Errorf("got %d lines without listings, want none", len(lines))
+	}
+}
+
+func TestWriteInflux(t *testing.T) {
+	setNow(t, date(2021, time.April, 1))
+
+	var mu sync.Mutex
+	var queries []string
+	var batches [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path != "/api/v2/write" || r.Header.Get("Authorization") != "Token secret" || r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("unexpected request %s with headers %v", r.URL, r.Header)
+		}
+		queries = append(queries, r.URL.RawQuery)
+
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var lines []string
+		sc := bufio.NewScanner(zr)
+		for sc.Scan() {
+			lines = append(lines, sc.Text())
+		}
+		batches = append(batches, lines)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	// One run point and one per listing overflow the first batch.
+	listings := make([]listing, influxBatchSize)
+	for i := range listings {
+		listings[i] = listing{ID: fmt.Sprint(i), Price: 400000, PropertyType: "flat"}
+	}
+	args := &cliArgs{Postcode: "SW2", InfluxURL: srv.URL + "/", InfluxToken: "secret", InfluxBucket: "prices", InfluxOrg: "home", InfluxListings: true}
+	if err := writeInflux(context.Background(), args, listings); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(batches) != 2 || len(batches[0]) != influxBatchSize || len(batches[1]) != 2 {
+		var sizes []int
+		for _, b := range batches {
+			sizes = append(sizes, len(b))
+		}
+		t.Fatalf("got batches of %v lines, want [%d 2]", sizes, influxBatchSize)
+	}
+	if want := "bucket=prices&org=home&precision=s"; queries[0] != want {
+		t.Errorf("query = %q, want %q", queries[0], want)
+	}
+	for _, b := range batches {
+		for _, line := range b {
+			parseInfluxLine(t, line)
+		}
+	}
+}
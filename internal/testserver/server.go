@@ -0,0 +1,193 @@
+// Package testserver provides a fake Zoopla search site for end-to-end
+// tests. It serves fixture pages keyed by the pn (page number) query
+// parameter, can inject failures on demand and records every request it
+// receives.
+package testserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EmptyPage is served for page numbers without a fixture. It has a listings
+// container but no cards, which ends pagination.
+const EmptyPage = `<html><body><div class="ListingsContainer"></div></body></html>`
+
+// ChallengePage imitates the bot-detection interstitial served instead of
+// search results.
+const ChallengePage = `<html><head><title>Just a moment...</title></head>` +
+	`<body><div id="challenge-form">Checking your browser before accessing zoopla.co.uk</div></body></html>`
+
+// Request is a request received by the server.
+type Request struct {
+	Method string
+	Path   string
+	Page   int
+	Header http.Header
+	Time   time.Time
+}
+
+// fault is a response injected in place of a page's fixture.
+type fault struct {
+	status    int
+	delay     time.Duration
+	location  string
+	challenge bool
+	remaining int
+}
+
+// Server is a fake Zoopla site. The zero value is not usable; create one
+// with New and Close it when done.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	pages    map[int]string
+	details  map[string]string
+	faults   map[int][]*fault
+	requests []Request
+}
+
+// New starts a server with no fixture pages.
+func New() *Server {
+	s := &Server{
+		pages:   make(map[int]string),
+		details: make(map[string]string),
+		faults:  make(map[int][]*fault),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+
+	return s
+}
+
+// SearchURL is the base URL to point the analyzer's --base-url at.
+func (s *Server) SearchURL() string {
+	return s.URL + "/for-sale/property"
+}
+
+// SetPage serves body for search results page pn.
+func (s *Server) SetPage(pn int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pages[pn] = body
+}
+
+// SetDetailPage serves body for requests to path, such as a listing's
+// detail page.
+func (s *Server) SetDetailPage(path, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.details[path] = body
+}
+
+// InjectStatus makes the next times requests for page pn fail with status.
+func (s *Server) InjectStatus(pn, status, times int) {
+	s.addFault(pn, &fault{status: status, remaining: times})
+}
+
+// InjectDelay delays the next times responses for page pn by d.
+func (s *Server) InjectDelay(pn int, d time.Duration, times int) {
+	s.addFault(pn, &fault{delay: d, remaining: times})
+}
+
+// InjectRedirect redirects the next times requests for page pn to location.
+func (s *Server) InjectRedirect(pn int, location string, times int) {
+	s.addFault(pn, &fault{status: http.StatusFound, location: location, remaining: times})
+}
+
+// InjectChallenge serves ChallengePage for the next times requests for page
+// pn.
+func (s *Server) InjectChallenge(pn, times int) {
+	s.addFault(pn, &fault{status: http.StatusForbidden, challenge: true, remaining: times})
+}
+
+// Requests returns the requests received so far, in arrival order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	requests := make([]Request, len(s.requests))
+	copy(requests, s.requests)
+
+	return requests
+}
+
+func (s *Server) addFault(pn int, f *fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults[pn] = append(s.faults[pn], f)
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	pn, _ := strconv.Atoi(r.URL.Query().Get("pn"))
+
+	s.mu.Lock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Page:   pn,
+		Header: r.Header.Clone(),
+		Time:   time.Now(),
+	})
+
+	f := s.takeFault(pn)
+	body, isDetail := s.details[r.URL.Path]
+	if !isDetail {
+		var ok bool
+		if body, ok = s.pages[pn]; !ok {
+			body = EmptyPage
+		}
+	}
+	s.mu.Unlock()
+
+	if f != nil {
+		if f.delay > 0 {
+			select {
+			case <-time.After(f.delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		switch {
+		case f.location != "":
+			http.Redirect(w, r, f.location, f.status)
+			return
+		case f.challenge:
+			body = ChallengePage
+		}
+
+		if f.status != 0 {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(f.status)
+			_, _ = w.Write([]byte(body))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write([]byte(body))
+}
+
+// takeFault pops the next pending fault for page pn. The caller must hold
+// s.mu.
+func (s *Server) takeFault(pn int) *fault {
+	pending := s.faults[pn]
+	if len(pending) == 0 {
+		return nil
+	}
+
+	f := pending[0]
+	f.remaining--
+	if f.remaining <= 0 {
+		s.faults[pn] = pending[1:]
+	}
+
+	return f
+}
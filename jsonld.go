@@ -0,0 +1,93 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// jsonLDParser reads schema.org structured data, where each listing is an
+// object carrying an "offers" price.
+type jsonLDParser struct{}
+
+func (jsonLDParser) name() string {
+	return "jsonld"
+}
+
+func (jsonLDParser) canParse(root *html.Node) bool {
+	for _, script := range findJSONLD(root) {
+		if strings.Contains(textContent(script), `"offers"`) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (jsonLDParser) parse(root *html.Node, _ searchMode) ([]listing, []cardError) {
+	var listings []listing
+	var cardErrs []cardError
+	index := 0
+	for _, script := range findJSONLD(root) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(textContent(script)), &data); err != nil {
+			log.Print("cannot decode JSON-LD: ", err)
+			continue
+		}
+
+		walkJSON(data, func(obj map[string]interface{}) bool {
+			offers, ok := obj["offers"].(map[string]interface{})
+			if !ok {
+				return false
+			}
+
+			defer func() { index++ }()
+
+			price, err := jsonPrice(offers["price"])
+			if err != nil {
+				cardErrs = append(cardErrs, cardError{index: index, err: err})
+				return true
+			}
+
+			l := listing{
+				Price:    price,
+				Status:   statusForSale,
+				Address:  jsonLDAddress(obj["address"]),
+				Title:    jsonString(obj["name"]),
+				Location: jsonCoordinates(obj["geo"]),
+			}
+			l.Category = classifyListing(l.Title, "")
+			if u := jsonString(obj["url"]); u != "" {
+				l.URL = u
+			}
+
+			listings = append(listings, l)
+			return true
+		})
+	}
+
+	return listings, cardErrs
+}
+
+func findJSONLD(root *html.Node) []*html.Node {
+	return findScripts(root, func(n *html.Node) bool {
+		typ, _ := getAttr(n, "type")
+		return typ == "application/ld+json"
+	})
+}
+
+func jsonLDAddress(v interface{}) string {
+	if addr, ok := v.(map[string]interface{}); ok {
+		var parts []string
+		for _, key := range []string{"streetAddress", "addressLocality", "postalCode"} {
+			if s := jsonString(addr[key]); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	return jsonString(v)
+}
@@ -0,0 +1,41 @@
+package zoopla
+
+import "testing"
+
+func TestJSONLDParser(t *testing.T) {
+	listings, cardErrs := jsonLDParser{}.parse(mustParseHTML(t, jsonLDLayoutPage), saleSearch)
+	if len(cardErrs) > 0 {
+		t.Fatalf("got card errors: %v", cardErrs)
+	}
+	if len(listings) != 2 {
+		t.Fatalf("got %d listings, want 2", len(listings))
+	}
+
+	l := listings[0]
+	if l.Address != "Acre Lane, London, SW2 5SG" {
+		t.Errorf("address = %q", l.Address)
+	}
+	if l.Location == nil || l.Location.Latitude != 51.46 || l.Location.Longitude != -0.12 {
+		t.Errorf("location = %+v", l.Location)
+	}
+	if l.URL != "/for-sale/details/58412345/" || l.Category != categoryResidential {
+		t.Errorf("listing = %+v", l)
+	}
+}
+
+func TestJSONLDAddress(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{map[string]interface{}{"streetAddress": "Acre Lane", "postalCode": "SW2 5SG"}, "Acre Lane, SW2 5SG"},
+		{"Acre Lane, London", "Acre Lane, London"},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := jsonLDAddress(tt.v); got != tt.want {
+			t.Errorf("jsonLDAddress(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
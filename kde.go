@@ -0,0 +1,96 @@
+package zoopla
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// kdeGridPoints is how many evenly spaced prices the density is evaluated
+// at, spanning three bandwidths either side of the prices.
+const kdeGridPoints = 100
+
+type kdePoint struct {
+	Price   float64 `json:"price"`
+	Density float64 `json:"density"`
+}
+
+// kernelDensity is a Gaussian kernel density estimate of the prices.
+type kernelDensity struct {
+	Bandwidth float64    `json:"bandwidth"`
+	Points    []kdePoint `json:"points"`
+}
+
+// silvermanBandwidth is Silverman's rule of thumb. It's zero when there are
+// fewer than two prices or they're all the same.
+func silvermanBandwidth(sorted []uint64) float64 {
+	n := len(sorted)
+	if n < 2 {
+		return 0
+	}
+
+	spread := calculateStddev(sorted, calculateMean(sorted))
+	if iqr := quantile(sorted, 0.75) - quantile(sorted, 0.25); iqr > 0 && iqr/1.34 < spread {
+		spread = iqr / 1.34
+	}
+
+	return 0.9 * spread * math.Pow(float64(n), -0.2)
+}
+
+// calculateKDE estimates the density with the given bandwidth, or Silverman's
+// if it's zero. It returns nil when there's no usable bandwidth, as for a
+// single price or identical prices without one given.
+func calculateKDE(prices []uint64, bandwidth float64) *kernelDensity {
+	if len(prices) == 0 {
+		return nil
+	}
+
+	sorted := make([]uint64, len(prices))
+	copy(sorted, prices)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	if bandwidth == 0 {
+		bandwidth = silvermanBandwidth(sorted)
+	}
+	if bandwidth <= 0 {
+		return nil
+	}
+
+	lo := float64(sorted[0]) - 3*bandwidth
+	hi := float64(sorted[len(sorted)-1]) + 3*bandwidth
+	step := (hi - lo) / (kdeGridPoints - 1)
+
+	k := &kernelDensity{Bandwidth: bandwidth, Points: make([]kdePoint, kdeGridPoints)}
+	for i := range k.Points {
+		x := lo + float64(i)*step
+		k.Points[i] = kdePoint{Price: x, Density: gaussianDensity(sorted, bandwidth, x)}
+	}
+
+	return k
+}
+
+func gaussianDensity(prices []uint64, bandwidth, x float64) float64 {
+	var sum float64
+	for _, p := range prices {
+		u := (x - float64(p)) / bandwidth
+		sum += math.Exp(-u * u / 2)
+	}
+
+	return sum / (float64(len(prices)) * bandwidth * math.Sqrt(2*math.Pi))
+}
+
+// mode is the price on the grid with the highest density.
+func (k kernelDensity) mode() float64 {
+	best := k.Points[0]
+	for _, p := range k.Points[1:] {
+		if p.Density > best.Density {
+			best = p
+		}
+	}
+
+	return best.Price
+}
+
+func (k kernelDensity) String() string {
+	return fmt.Sprintf("bandwidth = %.0f, mode = %.0f", k.Bandwidth, k.mode())
+}
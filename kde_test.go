@@ -0,0 +1,139 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSilvermanBandwidth(t *testing.T) {
+	tests := []struct {
+		name   string
+		sorted []uint64
+		want   float64
+	}{
+		{"empty", nil, 0},
+		{"single", []uint64{400000}, 0},
+		{"identical", []uint64{400000, 400000, 400000}, 0},
+		// The IQR, 200, over 1.34 is below the stddev of 158.
+		{"iqr", []uint64{100, 200, 300, 400, 500}, 0.9 * (200 / 1.34) * math.Pow(5, -0.2)},
+		// The stddev of 100 is below the IQR's 149.
+		{"stddev", []uint64{0, 0, 100, 200, 200}, 0.9 * 100 * math.Pow(5, -0.2)},
+		// With no IQR the stddev is used.
+		{"no iqr", []uint64{100, 100, 100, 100, 500}, 0.9 * math.Sqrt(32000) * math.Pow(5, -0.2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := silvermanBandwidth(tt.sorted); !closeTo(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateKDE(t *testing.T) {
+	prices := []uint64{500, 100, 300, 200, 400}
+	k := calculateKDE(prices, 0)
+	if k == nil {
+		t.Fatal("got nil")
+	}
+
+	bandwidth := 0.9 * (200 / 1.34) * math.Pow(5, -0.2)
+	if !closeTo(k.Bandwidth, bandwidth) {
+		t.Errorf("got bandwidth %v, want Silverman's %v", k.Bandwidth, bandwidth)
+	}
+	if len(k.Points) != kdeGridPoints {
+		t.Fatalf("got %d points, want %d", len(k.Points), kdeGridPoints)
+	}
+	if first, last := k.Points[0].Price, k.Points[len(k.Points)-1].Price; !closeTo(first, 100-3*bandwidth) || !closeTo(last, 500+3*bandwidth) {
+		t.Errorf("grid spans %v to %v, want three bandwidths beyond the prices", first, last)
+	}
+
+	// The density over the grid should hold nearly all the probability.
+	step := k.Points[1].Price - k.Points[0].Price
+	var area float64
+	for _, p := range k.Points {
+		area += p.Density * step
+	}
+	if math.Abs(area-1) > 0.01 {
+		t.Errorf("density integrates to %v, want about 1", area)
+	}
+
+	if !reflect.DeepEqual(prices, []uint64{500, 100, 300, 200, 400}) {
+		t.Errorf("prices were reordered to %v", prices)
+	}
+}
+
+func TestCalculateKDEWithBandwidth(t *testing.T) {
+	k := calculateKDE([]uint64{400000}, 10000)
+	if k == nil {
+		t.Fatal("got nil")
+	}
+
+	// The grid has no point at 400000 itself, so the mode is the first of
+	// the two either side of it.
+	mode := 370000 + 49*60000.0/99
+	if !closeTo(k.mode(), mode) {
+		t.Errorf("got mode %v, want %v", k.mode(), mode)
+	}
+	if got, want := k.String(), "bandwidth = 10000, mode = 399697"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCalculateKDEUnusable(t *testing.T) {
+	tests := []struct {
+		name      string
+		prices    []uint64
+		bandwidth float64
+	}{
+		{"no prices", nil, 0},
+		{"no prices with bandwidth", nil, 1000},
+		{"single", []uint64{400000}, 0},
+		{"identical", []uint64{400000, 400000}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if k := calculateKDE(tt.prices, tt.bandwidth); k != nil {
+				t.Errorf("got %v, want nil", *k)
+			}
+		})
+	}
+}
+
+func TestGaussianDensity(t *testing.T) {
+	peak := 1 / (1000 * math.Sqrt(2*math.Pi))
+
+	tests := []struct {
+		name   string
+		prices []uint64
+		x      float64
+		want   float64
+	}{
+		{"at the price", []uint64{5000}, 5000, peak},
+		{"one bandwidth away", []uint64{5000}, 6000, peak * math.Exp(-0.5)},
+		{"between two prices", []uint64{4000, 6000}, 5000, peak * math.Exp(-0.5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gaussianDensity(tt.prices, 1000, tt.x); math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKernelDensityJSON(t *testing.T) {
+	k := kernelDensity{Bandwidth: 1000, Points: []kdePoint{{Price: 400000, Density: 0.5}}}
+	data, err := json.Marshal(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"bandwidth":1000,"points":[{"price":400000,"density":0.5}]}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,128 @@
+package zoopla
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const kmlNamespace = "http://www.opengis.net/kml/2.2"
+
+// kmlBandColours are pin colours, in KML's aabbggrr order, for each price
+// band from cheapest (green) to most expensive (red).
+var kmlBandColours = []string{"ff00c800", "ff00e6b4", "ff00d7ff", "ff008cff", "ff0000e6"}
+
+type kmlFile struct {
+	XMLName  xml.Name    `xml:"kml"`
+	XMLNS    string      `xml:"xmlns,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string         `xml:"name"`
+	Styles     []kmlStyle     `xml:"Style"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlStyle struct {
+	ID        string       `xml:"id,attr"`
+	IconStyle kmlIconStyle `xml:"IconStyle"`
+}
+
+type kmlIconStyle struct {
+	Color string `xml:"color"`
+	Icon  string `xml:"Icon>href"`
+}
+
+type kmlPlacemark struct {
+	Name        string   `xml:"name"`
+	Description kmlCDATA `xml:"description"`
+	StyleURL    string   `xml:"styleUrl"`
+	Point       kmlPoint `xml:"Point"`
+}
+
+type kmlCDATA struct {
+	Text string `xml:",cdata"`
+}
+
+type kmlPoint struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// marshalKML writes a KML document with a Placemark per listing, its pin
+// coloured by price band. Listings without coordinates are skipped.
+func marshalKML(listings []listing, args *cliArgs) ([]byte, error) {
+	doc := kmlDocument{Name: "Listings for " + args.Postcode}
+	for band, colour := range kmlBandColours {
+		doc.Styles = append(doc.Styles, kmlStyle{
+			ID: kmlStyleID(band),
+			IconStyle: kmlIconStyle{
+				Color: colour,
+				Icon:  "http://maps.google.com/mapfiles/kml/pushpin/wht-pushpin.png",
+			},
+		})
+	}
+
+	skipped := 0
+	for i := range listings {
+		l := &listings[i]
+		if l.Location == nil {
+			skipped++
+			continue
+		}
+
+		name := formatPrice(l.Price)
+		if l.Address != "" {
+			name += " – " + l.Address
+		}
+
+		doc.Placemarks = append(doc.Placemarks, kmlPlacemark{
+			Name:        name,
+			Description: kmlCDATA{Text: kmlDescription(l)},
+			StyleURL:    "#" + kmlStyleID(priceBand(l.Price)),
+			Point: kmlPoint{
+				Coordinates: strconv.FormatFloat(l.Location.Longitude, 'f', -1, 64) + "," +
+					strconv.FormatFloat(l.Location.Latitude, 'f', -1, 64),
+			},
+		})
+	}
+
+	if skipped > 0 {
+		log.Printf("skipped %d listings without coordinates", skipped)
+	}
+
+	data, err := xml.MarshalIndent(kmlFile{XMLNS: kmlNamespace, Document: doc}, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "while marshalling KML")
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+func kmlStyleID(band int) string {
+	return "band-" + strconv.Itoa(band)
+}
+
+// kmlDescription renders the HTML shown in a placemark's balloon.
+func kmlDescription(l *listing) string {
+	var lines []string
+	if l.Beds != nil {
+		lines = append(lines, fmt.Sprintf("Beds: %d", *l.Beds))
+	}
+	if l.PropertyType != "" {
+		lines = append(lines, "Type: "+html.EscapeString(l.PropertyType))
+	}
+	if l.Agent != "" {
+		lines = append(lines, "Agent: "+html.EscapeString(l.Agent))
+	}
+	if l.URL != "" {
+		lines = append(lines, fmt.Sprintf(`<a href="%s">View listing</a>`, html.EscapeString(l.URL)))
+	}
+
+	return strings.Join(lines, "<br/>")
+}
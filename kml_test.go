@@ -0,0 +1,104 @@
+package zoopla
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestMarshalKML(t *testing.T) {
+	listings := []listing{
+		{
+			Price:        450000,
+			Beds:         uint32Ptr(2),
+			PropertyType: "flat",
+			Address:      "Acre Lane & Brixton Hill",
+			Agent:        "Foxtons <Brixton> ]]>",
+			URL:          "https://example.com/1/?a=1&b=2",
+			Location:     &coordinates{Latitude: 51.4613, Longitude: -0.1156},
+		},
+		{Price: 300000},
+		{Price: 1200000, Location: &coordinates{Latitude: 51.45, Longitude: -0.12}},
+	}
+
+	data, err := marshalKML(listings, &cliArgs{Postcode: "SW2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(data, []byte(xml.Header)) {
+		t.Error("KML has no XML declaration")
+	}
+	// Reading every token checks the document is well-formed.
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("KML isn't well-formed: %v\n%s", err, data)
+		}
+	}
+
+	var got kmlFile
+	if err := xml.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.XMLName.Space != kmlNamespace {
+		t.Errorf("namespace = %q, want %q", got.XMLName.Space, kmlNamespace)
+	}
+	if got.Document.Name != "Listings for SW2" {
+		t.Errorf("document name = %q", got.Document.Name)
+	}
+	if len(got.Document.Styles) != len(kmlBandColours) {
+		t.Errorf("got %d styles, want one per band", len(got.Document.Styles))
+	}
+
+	placemarks := got.Document.Placemarks
+	if len(placemarks) != 2 {
+		t.Fatalf("got %d placemarks, want 2 without the listing lacking coordinates", len(placemarks))
+	}
+
+	p := placemarks[0]
+	if p.Name != "£450,000 – Acre Lane & Brixton Hill" {
+		t.Errorf("name = %q", p.Name)
+	}
+	if p.StyleURL != "#band-1" {
+		t.Errorf("styleUrl = %q, want #band-1", p.StyleURL)
+	}
+	if p.Point.Coordinates != "-0.1156,51.4613" {
+		t.Errorf("coordinates = %q, want longitude first", p.Point.Coordinates)
+	}
+	for _, want := range []string{"Beds: 2", "Type: flat", "Agent: Foxtons &lt;Brixton&gt; ]]&gt;", `<a href="https://example.com/1/?a=1&amp;b=2">View listing</a>`} {
+		if !strings.Contains(p.Description.Text, want) {
+			t.Errorf("description %q is missing %q", p.Description.Text, want)
+		}
+	}
+
+	if p := placemarks[1]; p.Name != "£1,200,000" || p.StyleURL != "#band-4" || p.Description.Text != "" {
+		t.Errorf("second placemark = %+v", p)
+	}
+}
+
+func TestKMLDescription(t *testing.T) {
+	tests := []struct {
+		name string
+		l    listing
+		want string
+	}{
+		{"empty", listing{}, ""},
+		{"beds only", listing{Beds: uint32Ptr(0)}, "Beds: 0"},
+		{"all", listing{Beds: uint32Ptr(3), PropertyType: "terraced", Agent: "Foxtons", URL: "https://example.com/"},
+			`Beds: 3<br/>Type: terraced<br/>Agent: Foxtons<br/><a href="https://example.com/">View listing</a>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kmlDescription(&tt.l); got != tt.want {
+				t.Errorf("kmlDescription() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
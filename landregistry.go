@@ -0,0 +1,228 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	landRegistryURL     = "https://landregistry.data.gov.uk/app/ppd/ppd_data.csv"
+	landRegistryTimeout = 2 * time.Minute
+
+	defaultLandRegistryMonths = 12
+)
+
+// Columns of the price-paid CSV, which has no header row by default.
+const (
+	ppdPriceColumn    = 1
+	ppdDateColumn     = 2
+	ppdPostcodeColumn = 3
+	ppdTypeColumn     = 4
+	ppdCategoryColumn = 14
+)
+
+// ppdStandardCategory marks sales at full market value. Category B covers
+// repossessions, transfers to companies and the like.
+const ppdStandardCategory = "A"
+
+type soldPrice struct {
+	Price    uint64
+	Date     time.Time
+	Postcode string
+	Type     string
+}
+
+// soldPriceSource looks up the sales in a postcode district between two
+// dates.
+type soldPriceSource interface {
+	soldPrices(ctx context.Context, district string, from, to time.Time) ([]soldPrice, error)
+}
+
+// landRegistryClient downloads HM Land Registry price-paid data as CSV.
+type landRegistryClient struct {
+	f       *fetcher
+	baseURL string
+}
+
+func (c *landRegistryClient) soldPrices(ctx context.Context, district string, from, to time.Time) ([]soldPrice, error) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "while parsing Land Registry URL")
+	}
+
+	u.RawQuery = url.Values{
+		"postcode": {district},
+		"min_date": {from.Format("2006-01-02")},
+		"max_date": {to.Format("2006-01-02")},
+		"limit":    {"all"},
+	}.Encode()
+
+	var sold []soldPrice
+	err = c.f.fetch(ctx, u, func(body io.Reader) error {
+		var err error
+		sold, err = parsePricePaidCSV(body)
+		return err
+	})
+
+	return sold, errors.Wrapf(err, "while fetching sold prices for %s", district)
+}
+
+// parsePricePaidCSV reads standard-category sales from price-paid CSV. A
+// header row, if there is one, is skipped.
+func parsePricePaidCSV(r io.Reader) ([]soldPrice, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	var sold []soldPrice
+	for row := 1; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return sold, nil
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "while reading price-paid CSV")
+		}
+
+		if len(record) <= ppdTypeColumn {
+			return nil, errors.Errorf("price-paid row %d has %d columns", row, len(record))
+		}
+
+		price, err := strconv.ParseUint(record[ppdPriceColumn], 10, 64)
+		if err != nil {
+			if row == 1 {
+				continue
+			}
+			return nil, errors.Wrapf(err, "while parsing price on price-paid row %d", row)
+		}
+
+		if len(record) > ppdCategoryColumn && record[ppdCategoryColumn] != ppdStandardCategory {
+			continue
+		}
+
+		// Dates are followed by a midnight time, as in "2021-03-03 00:00".
+		date := record[ppdDateColumn]
+		if i := strings.IndexByte(date, ' '); i >= 0 {
+			date = date[:i]
+		}
+		transferred, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while parsing date on price-paid row %d", row)
+		}
+
+		sold = append(sold, soldPrice{
+			Price:    price,
+			Date:     transferred,
+			Postcode: record[ppdPostcodeColumn],
+			Type:     record[ppdTypeColumn],
+		})
+	}
+}
+
+// postcodeDistricts returns the distinct outcodes of the postcodes searched,
+// skipping any that aren't postcodes.
+func postcodeDistricts(postcodes []string) []string {
+	var districts []string
+	for _, pc := range postcodes {
+		outcode, _ := parsePostcode(pc)
+		if outcode == "" {
+			log.Printf("warning: %q is not a postcode, so has no sold prices", pc)
+			continue
+		}
+		if !containsString(districts, outcode) {
+			districts = append(districts, outcode)
+		}
+	}
+
+	return districts
+}
+
+type soldComparison struct {
+	districts []string
+	from, to  time.Time
+	stats     priceStats
+
+	// askingMedian is the median asking price of the scraped listings, and
+	// ratio it over the median sold price.
+	askingMedian float64
+	ratio        float64
+}
+
+// compareSoldPrices fetches the sales in the districts over the months up to
+// now and compares them with the asking prices.
+func compareSoldPrices(ctx context.Context, src soldPriceSource, districts []string, months int, asking []uint64) (*soldComparison, error) {
+	ctx, cancel := context.WithTimeout(ctx, landRegistryTimeout)
+	defer cancel()
+
+	y, m, d := timeNow().Date()
+	to := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, -months, 0)
+
+	var prices []uint64
+	for _, district := range districts {
+		sold, err := src.soldPrices(ctx, district, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range sold {
+			prices = append(prices, s.Price)
+		}
+	}
+
+	if len(prices) == 0 {
+		return nil, errors.Errorf("no sold prices found in %s", strings.Join(districts, ", "))
+	}
+
+	c := &soldComparison{
+		districts:    districts,
+		from:         from,
+		to:           to,
+		stats:        calculatePriceStats(prices),
+		askingMedian: calculateMedian(asking),
+	}
+	c.ratio = c.askingMedian / c.stats.median
+
+	return c, nil
+}
+
+func runSoldComparison(ctx context.Context, f *fetcher, args *cliArgs, postcodes []string, asking []uint64) *soldComparison {
+	districts := postcodeDistricts(postcodes)
+	if len(districts) == 0 || len(asking) == 0 {
+		return nil
+	}
+
+	src := &landRegistryClient{f: f, baseURL: landRegistryURL}
+	c, err := compareSoldPrices(ctx, src, districts, args.LandRegistryMonths, asking)
+	if err != nil {
+		log.Print("warning: ", err)
+		return nil
+	}
+
+	return c
+}
+
+func (c soldComparison) String() string {
+	return fmt.Sprintf("%d sales in %s from %s to %s, median = %.0f, mean = %.0f; asking median is %.2fx sold median",
+		c.stats.count, strings.Join(c.districts, ", "), c.from.Format("2006-01-02"), c.to.Format("2006-01-02"),
+		c.stats.median, c.stats.mean, c.ratio)
+}
+
+func (c soldComparison) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Districts    []string   `json:"districts"`
+		From         string     `json:"from"`
+		To           string     `json:"to"`
+		Stats        priceStats `json:"stats"`
+		AskingMedian float64    `json:"asking_median"`
+		Ratio        float64    `json:"asking_to_sold"`
+	}{c.districts, c.from.Format("2006-01-02"), c.to.Format("2006-01-02"), c.stats, c.askingMedian, c.ratio})
+}
@@ -0,0 +1,202 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const pricePaidCSV = `"{1}","450000","2021-03-03 00:00","SW2 1AA","F","N","L","1","","ACRE LANE","","LONDON","LAMBETH","GREATER LONDON","A","A"
+"{2}","1200000","2021-02-01 00:00","SW2 2BB","T","N","F","2","","BRIXTON HILL","","LONDON","LAMBETH","GREATER LONDON","A","A"
+"{3}","90000","2021-01-15 00:00","SW2 3CC","O","N","L","3","","ACRE LANE","","LONDON","LAMBETH","GREATER LONDON","B","A"
+`
+
+func TestParsePricePaidCSV(t *testing.T) {
+	want := []soldPrice{
+		{Price: 450000, Date: date(2021, time.March, 3), Postcode: "SW2 1AA", Type: "F"},
+		{Price: 1200000, Date: date(2021, time.February, 1), Postcode: "SW2 2BB", Type: "T"},
+	}
+
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{"no header", pricePaidCSV},
+		{"header", "id,price,date,postcode,type\n" + pricePaidCSV},
+		{"no category", "{1},450000,2021-03-03,SW2 1AA,F\n{2},1200000,2021-02-01 00:00,SW2 2BB,T\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePricePaidCSV(strings.NewReader(tt.csv))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestParsePricePaidCSVErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		wantErr string
+	}{
+		{"too few columns", "{1},450000,2021-03-03,SW2 1AA\n", "price-paid row 1 has 4 columns"},
+		{"bad price", "{1},450000,2021-03-03,SW2 1AA,F\n{2},lots,2021-03-03,SW2 1AA,F\n", "while parsing price on price-paid row 2"},
+		{"bad date", "{1},450000,3rd March,SW2 1AA,F\n", "while parsing date on price-paid row 1"},
+		{"bad CSV", "{1},\"450000,2021-03-03,SW2 1AA,F\n", "while reading price-paid CSV"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePricePaidCSV(strings.NewReader(tt.csv))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLandRegistryClient(t *testing.T) {
+	var query string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+		w.Write([]byte(pricePaidCSV))
+	}))
+	defer srv.Close()
+
+	c := &landRegistryClient{f: &fetcher{client: srv.Client()}, baseURL: srv.URL + "/app/ppd/ppd_data.csv"}
+	sold, err := c.soldPrices(context.Background(), "SW2", date(2020, time.May, 10), date(2021, time.May, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sold) != 2 {
+		t.Errorf("got %d sales, want 2", len(sold))
+	}
+
+	if want := "limit=all&max_date=2021-05-10&min_date=2020-05-10&postcode=SW2"; query != want {
+		t.Errorf("got query %q, want %q", query, want)
+	}
+}
+
+func TestLandRegistryClientError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c := &landRegistryClient{f: &fetcher{client: srv.Client()}, baseURL: srv.URL}
+	_, err := c.soldPrices(context.Background(), "SW2", date(2020, time.May, 10), date(2021, time.May, 10))
+	if err == nil || !strings.Contains(err.Error(), "while fetching sold prices for SW2") {
+		t.Errorf("got %v, want a fetch error", err)
+	}
+}
+
+func TestPostcodeDistricts(t *testing.T) {
+	tests := []struct {
+		postcodes []string
+		want      []string
+	}{
+		{[]string{"sw2"}, []string{"SW2"}},
+		{[]string{"SW2 1AA", "sw2", "SW9 8LA"}, []string{"SW2", "SW9"}},
+		{[]string{"Brixton", "SE1"}, []string{"SE1"}},
+		{[]string{"Brixton"}, nil},
+	}
+
+	for _, tt := range tests {
+		if got := postcodeDistricts(tt.postcodes); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("postcodeDistricts(%q) = %q, want %q", tt.postcodes, got, tt.want)
+		}
+	}
+}
+
+// fakeSoldPrices serves sold prices by district, recording the dates asked
+// for.
+type fakeSoldPrices struct {
+	sold     map[string][]soldPrice
+	err      error
+	from, to time.Time
+}
+
+func (f *fakeSoldPrices) soldPrices(_ context.Context, district string, from, to time.Time) ([]soldPrice, error) {
+	f.from, f.to = from, to
+	return f.sold[district], f.err
+}
+
+func TestCompareSoldPrices(t *testing.T) {
+	setNow(t, time.Date(2021, time.May, 10, 15, 30, 0, 0, time.UTC))
+	src := &fakeSoldPrices{sold: map[string][]soldPrice{
+		"SW2": {{Price: 400000}},
+		"SW9": {{Price: 500000}},
+	}}
+
+	c, err := compareSoldPrices(context.Background(), src, []string{"SW2", "SW9"}, 12, []uint64{450000, 540000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !src.from.Equal(date(2020, time.May, 10)) || !src.to.Equal(date(2021, time.May, 10)) {
+		t.Errorf("asked for sales from %v to %v", src.from, src.to)
+	}
+	if c.stats.count != 2 || c.stats.median != 450000 || c.askingMedian != 495000 || !closeTo(c.ratio, 1.1) {
+		t.Errorf("got %+v", *c)
+	}
+
+	want := "2 sales in SW2, SW9 from 2020-05-10 to 2021-05-10, median = 450000, mean = 450000; asking median is 1.10x sold median"
+	if got := c.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"districts":["SW2","SW9"]`, `"from":"2020-05-10"`, `"to":"2021-05-10"`, `"asking_median":495000`, `"asking_to_sold":1.1`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s has no %s", data, want)
+		}
+	}
+}
+
+func TestCompareSoldPricesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     *fakeSoldPrices
+		wantErr string
+	}{
+		{"no sales", &fakeSoldPrices{}, "no sold prices found in SW2, SW9"},
+		{"source error", &fakeSoldPrices{err: errors.New("boom")}, "boom"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compareSoldPrices(context.Background(), tt.src, []string{"SW2", "SW9"}, 12, []uint64{450000})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunSoldComparisonSkips(t *testing.T) {
+	args := &cliArgs{LandRegistryMonths: defaultLandRegistryMonths}
+
+	// Neither needs the Land Registry, so nothing is fetched.
+	if c := runSoldComparison(context.Background(), nil, args, []string{"Brixton"}, []uint64{450000}); c != nil {
+		t.Errorf("got %v without a postcode district", *c)
+	}
+	if c := runSoldComparison(context.Background(), nil, args, []string{"SW2"}, nil); c != nil {
+		t.Errorf("got %v without asking prices", *c)
+	}
+}
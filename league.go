@@ -0,0 +1,262 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alexflint/go-arg"
+	"github.com/pkg/errors"
+)
+
+type leagueArgs struct {
+	cliArgs
+	LeagueFormat string `arg:"--league-format"`
+	// Progress is a file the completed areas are saved to as they finish,
+	// so that an interrupted run can carry on where it stopped.
+	Progress string `arg:"--progress"`
+}
+
+var leagueFormats = []string{diffFormatTable, formatCSV, formatJSON}
+
+// leagueRow is an area's line in the league table. Areas whose search
+// failed only have the postcode and error.
+type leagueRow struct {
+	Postcode    string   `json:"postcode"`
+	Count       int      `json:"count"`
+	Median      float64  `json:"median"`
+	Mean        float64  `json:"mean"`
+	P25         float64  `json:"p25"`
+	P75         float64  `json:"p75"`
+	PricePerBed *float64 `json:"price_per_bed,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+type leagueProgress struct {
+	Query runQuery             `json:"query"`
+	Areas map[string]leagueRow `json:"areas"`
+}
+
+// runLeague searches each postcode in turn and ranks the areas by median
+// price. Areas that fail are listed at the bottom rather than stopping the
+// run.
+func runLeague(ctx context.Context, argv []string) error {
+	var league leagueArgs
+	args, err := parseSearchArgs(func(cli *cliArgs) (*arg.Parser, error) {
+		league.cliArgs = *cli
+		league.LeagueFormat = diffFormatTable
+		p, err := parseSubcommand("league", &league, argv)
+		if err != nil {
+			return p, err
+		}
+		if !containsString(leagueFormats, league.LeagueFormat) {
+			return p, newUsageError(p, fmt.Sprintf("unknown league format %q, must be one of %s", league.LeagueFormat, strings.Join(leagueFormats, ", ")))
+		}
+		*cli = league.cliArgs
+		return p, nil
+	})
+	if err != nil {
+		return err
+	}
+	postcodes := splitPostcodes(args.Postcode)
+
+	query := newRunQuery(&args)
+	query.Postcode = ""
+	progress, err := loadLeagueProgress(league.Progress, query)
+	if err != nil {
+		return err
+	}
+	if n := len(progress.Areas); n > 0 {
+		log.Printf("resuming with %d areas already done", n)
+	}
+
+	// One fetcher for every area keeps requests spaced by the delay.
+	f := newFetcher(&args)
+	for _, pc := range postcodes {
+		if row, ok := progress.Areas[pc]; ok && row.Error == "" {
+			continue
+		}
+
+		row := searchLeagueArea(ctx, f, &args, pc)
+		if row.Error != "" {
+			log.Printf("warning: postcode %s: %s", pc, row.Error)
+		} else {
+			log.Printf("postcode %s: %d listings, median %s", pc, row.Count, formatMeanPrice(row.Median))
+		}
+
+		progress.Areas[pc] = row
+		if err := saveLeagueProgress(league.Progress, progress, args.Pretty); err != nil {
+			return err
+		}
+	}
+
+	rows := make([]leagueRow, 0, len(postcodes))
+	for _, pc := range postcodes {
+		rows = append(rows, progress.Areas[pc])
+	}
+	sortLeague(rows)
+
+	out, err := renderLeague(rows, league.LeagueFormat, args.Pretty)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return errors.Wrap(err, "while writing league table")
+}
+
+func searchLeagueArea(ctx context.Context, f *fetcher, args *cliArgs, pc string) leagueRow {
+	q := *args
+	q.Postcode = pc
+
+	listings, err := getQueryListings(ctx, f, newDiagnostics(&q), &q, []string{pc}, nil)
+	if err != nil {
+		return leagueRow{Postcode: pc, Error: err.Error()}
+	}
+
+	listings = filterCategories(listings, q.IncludeCategory)
+	listings, _ = splitComingSoon(listings)
+	assignPostcodes(listings)
+	assignIdentity(listings)
+	listings = dedupeByID(listings)
+	if !q.IncludeAuctionsInStats {
+		listings, _ = splitAuctions(listings)
+	}
+
+	s := summariseArea(pc, listings)
+	return leagueRow{
+		Postcode:    pc,
+		Count:       s.count,
+		Median:      s.median,
+		Mean:        s.mean,
+		P25:         s.p25,
+		P75:         s.p75,
+		PricePerBed: s.pricePerBed,
+	}
+}
+
+// loadLeagueProgress reads the areas done so far, ignoring a progress file
+// for a different query.
+func loadLeagueProgress(filename string, query runQuery) (leagueProgress, error) {
+	progress := leagueProgress{Query: query, Areas: make(map[string]leagueRow)}
+	if filename == "" {
+		return progress, nil
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return progress, nil
+	}
+	if err != nil {
+		return progress, errors.Wrap(err, "while reading league progress")
+	}
+
+	var saved leagueProgress
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return progress, errors.Wrapf(err, "while decoding league progress %s", filename)
+	}
+	if !sameQuery(saved.Query, query) {
+		log.Printf("warning: %s is for a different query, starting again", filename)
+		return progress, nil
+	}
+	for pc, row := range saved.Areas {
+		progress.Areas[pc] = row
+	}
+
+	return progress, nil
+}
+
+func saveLeagueProgress(filename string, progress leagueProgress, pretty bool) error {
+	if filename == "" {
+		return nil
+	}
+
+	data, err := marshalJSON(progress, pretty)
+	if err != nil {
+		return errors.Wrap(err, "while marshalling league progress")
+	}
+
+	return writeFileAtomic(filename, data)
+}
+
+// sortLeague puts the most expensive areas first, then those with no
+// listings, then those that failed.
+func sortLeague(rows []leagueRow) {
+	rank := func(r leagueRow) int {
+		switch {
+		case r.Error != "":
+			return 2
+		case r.Count == 0:
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if ri, rj := rank(rows[i]), rank(rows[j]); ri != rj {
+			return ri < rj
+		}
+		return rows[i].Median > rows[j].Median
+	})
+}
+
+var leagueHeader = []string{"rank", "postcode", "count", "median", "mean", "p25", "p75", "price_per_bed", "error"}
+
+func renderLeague(rows []leagueRow, format string, pretty bool) ([]byte, error) {
+	switch format {
+	case formatJSON:
+		data, err := marshalJSON(rows, pretty)
+		return append(data, '\n'), errors.Wrap(err, "while marshalling league table")
+	case formatCSV:
+		records := make([][]string, len(rows))
+		for i, r := range rows {
+			records[i] = leagueRecord(i+1, r, func(v float64) string { return fmt.Sprintf("%.0f", v) })
+		}
+		return marshalDelimited(formatCSV, leagueHeader, records)
+	}
+
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(leagueHeader[:len(leagueHeader)-1], "\t"))+"\t")
+	for i, r := range rows {
+		if r.Error != "" {
+			fmt.Fprintf(tw, "-\t%s\t! %s\n", r.Postcode, r.Error)
+			continue
+		}
+		fmt.Fprintln(tw, strings.Join(leagueRecord(i+1, r, formatMeanPrice)[:len(leagueHeader)-1], "\t")+"\t")
+	}
+	tw.Flush()
+
+	return []byte(b.String()), nil
+}
+
+// leagueRecord has a cell per leagueHeader column, with prices formatted by
+// price. Failed areas have no rank.
+func leagueRecord(rank int, r leagueRow, price func(float64) string) []string {
+	if r.Error != "" {
+		return []string{"", r.Postcode, "", "", "", "", "", "", r.Error}
+	}
+
+	perBed := ""
+	if r.PricePerBed != nil {
+		perBed = price(*r.PricePerBed)
+	}
+
+	cell := func(v float64) string {
+		if r.Count == 0 {
+			return ""
+		}
+		return price(v)
+	}
+
+	return []string{
+		fmt.Sprint(rank), r.Postcode, fmt.Sprint(r.Count),
+		cell(r.Median), cell(r.Mean), cell(r.P25), cell(r.P75), perBed, "",
+	}
+}
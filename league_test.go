@@ -0,0 +1,203 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ryanc414/zoopla-analyzer/internal/testserver"
+)
+
+func leagueRows() []leagueRow {
+	perBed := 200000.0
+	return []leagueRow{
+		{Postcode: "SE1", Error: "status 404"},
+		{Postcode: "SW9", Count: 2, Median: 450000, Mean: 460000, P25: 420000, P75: 500000},
+		{Postcode: "E1"},
+		{Postcode: "SW2", Count: 3, Median: 500000, Mean: 510000, P25: 450000, P75: 550000, PricePerBed: &perBed},
+	}
+}
+
+func TestSortLeague(t *testing.T) {
+	rows := leagueRows()
+	sortLeague(rows)
+
+	var got []string
+	for _, r := range rows {
+		got = append(got, r.Postcode)
+	}
+	if want := []string{"SW2", "SW9", "E1", "SE1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestLeagueRecord(t *testing.T) {
+	rows := leagueRows()
+
+	tests := []struct {
+		name string
+		row  leagueRow
+		want []string
+	}{
+		{"failed", rows[0], []string{"", "SE1", "", "", "", "", "", "", "status 404"}},
+		{"no per bed", rows[1], []string{"1", "SW9", "2", "£450,000", "£460,000", "£420,000", "£500,000", "", ""}},
+		{"no listings", rows[2], []string{"1", "E1", "0", "", "", "", "", "", ""}},
+		{"per bed", rows[3], []string{"1", "SW2", "3", "£500,000", "£510,000", "£450,000", "£550,000", "£200,000", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := leagueRecord(1, tt.row, formatMeanPrice); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderLeague(t *testing.T) {
+	rows := leagueRows()
+	sortLeague(rows)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: diffFormatTable,
+			want: "RANK  POSTCODE  COUNT  MEDIAN    MEAN      P25       P75       PRICE_PER_BED  \n" +
+				"1     SW2       3      £500,000  £510,000  £450,000  £550,000  £200,000       \n" +
+				"2     SW9       2      £450,000  £460,000  £420,000  £500,000                 \n" +
+				"3     E1        0                                                             \n" +
+				"-     SE1       ! status 404\n",
+		},
+		{
+			format: formatCSV,
+			want: "rank,postcode,count,median,mean,p25,p75,price_per_bed,error\n" +
+				"1,SW2,3,500000,510000,450000,550000,200000,\n" +
+				"2,SW9,2,450000,460000,420000,500000,,\n" +
+				"3,E1,0,,,,,,\n" +
+				",SE1,,,,,,,status 404\n",
+		},
+		{
+			format: formatJSON,
+			want: `[{"postcode":"SW2","count":3,"median":500000,"mean":510000,"p25":450000,"p75":550000,"price_per_bed":200000},` +
+				`{"postcode":"SW9","count":2,"median":450000,"mean":460000,"p25":420000,"p75":500000},` +
+				`{"postcode":"E1","count":0,"median":0,"mean":0,"p25":0,"p75":0},` +
+				`{"postcode":"SE1","count":0,"median":0,"mean":0,"p25":0,"p75":0,"error":"status 404"}]` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := renderLeague(rows, tt.format, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeagueProgress(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "progress.json")
+	query := runQuery{Radius: 1}
+
+	progress, err := loadLeagueProgress(filename, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(progress.Areas) != 0 {
+		t.Fatalf("got %d areas before any were saved", len(progress.Areas))
+	}
+
+	progress.Areas["SW2"] = leagueRows()[3]
+	if err := saveLeagueProgress(filename, progress, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query runQuery
+		want  int
+	}{
+		{"same query", query, 1},
+		{"different query", runQuery{Radius: 3}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadLeagueProgress(filename, tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Areas) != tt.want || !sameQuery(got.Query, tt.query) {
+				t.Errorf("got %+v, want %d areas", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLeagueProgressErrors(t *testing.T) {
+	// Without a file nothing is read or written.
+	if _, err := loadLeagueProgress("", runQuery{}); err != nil {
+		t.Errorf("loading without a file: %v", err)
+	}
+	if err := saveLeagueProgress("", leagueProgress{}, false); err != nil {
+		t.Errorf("saving without a file: %v", err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "progress.json")
+	if err := ioutil.WriteFile(filename, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadLeagueProgress(filename, runQuery{}); err == nil || !strings.Contains(err.Error(), "while decoding league progress") {
+		t.Errorf("got %v, want a decoding error", err)
+	}
+}
+
+func TestRunLeagueResumes(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, searchPage(400000, 500000))
+	srv.InjectStatus(1, http.StatusNotFound, 1)
+
+	progressFile := filepath.Join(t.TempDir(), "progress.json")
+	argv := []string{"--postcode", "sw2,sw9", "--base-url", srv.SearchURL(), "--delay", "0",
+		"--progress", progressFile, "--league-format", "json", "--quiet"}
+
+	run := func() []leagueRow {
+		var err error
+		stdout, _ := captureOutput(t, func() { err = runLeague(context.Background(), argv) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rows []leagueRow
+		if err := json.Unmarshal([]byte(stdout), &rows); err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	// The first area fails but doesn't stop the second.
+	rows := run()
+	if len(rows) != 2 || rows[0].Postcode != "sw9" || rows[0].Count != 2 || rows[1].Postcode != "sw2" || rows[1].Error == "" {
+		t.Fatalf("got %+v", rows)
+	}
+	perArea := len(srv.Requests()) - 1
+
+	// Resuming only searches the area that failed.
+	rows = run()
+	if len(rows) != 2 || rows[0].Median != 450000 || rows[1].Median != 450000 || rows[1].Error != "" {
+		t.Errorf("got %+v", rows)
+	}
+	if got, want := len(srv.Requests()), 1+2*perArea; got != want {
+		t.Errorf("got %d requests, want %d", got, want)
+	}
+}
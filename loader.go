@@ -0,0 +1,156 @@
+package zoopla
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// pricesSchemaVersion is bumped whenever a field in the prices envelope is
+// renamed or removed. Envelopes written before the field existed are read as
+// version 1.
+const pricesSchemaVersion = 1
+
+const (
+	outputKindPrices   = "prices"
+	outputKindListings = "listings"
+	outputKindHistory  = "history"
+)
+
+// loadedOutput is any file the tool writes, as read back by loadOutput.
+// Prices is set for every kind except history.
+type loadedOutput struct {
+	Kind          string
+	SchemaVersion int
+	Metadata      *outputMetadata
+	Prices        []uint64
+	Listings      []listing
+	Runs          []runEntry
+}
+
+// loadOutputFile reads and validates an output file, which may be gzipped.
+func loadOutputFile(filename string) (*loadedOutput, error) {
+	data, err := readFileMaybeGzip(filename)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading %s", filename)
+	}
+
+	out, err := loadOutput(data)
+	return out, errors.Wrapf(err, "while loading %s", filename)
+}
+
+// loadOutput sniffs which shape data has: a bare price array, a prices
+// envelope, a listings file or an --append run history. CBOR output is
+// converted to JSON first.
+func loadOutput(data []byte) (*loadedOutput, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	switch trimmed[0] {
+	case '[':
+		return loadArray(trimmed)
+	case '{':
+		return loadObject(trimmed)
+	default:
+		if j, err := cborToJSON(data, false); err == nil && len(j) > 0 && (j[0] == '[' || j[0] == '{') {
+			return loadOutput(j)
+		}
+		return nil, errors.New(unrecognisedOutput)
+	}
+}
+
+var unrecognisedOutput = fmt.Sprintf(
+	"unrecognised output, expected a price array, a prices envelope (schema_version %d), a listings file (schema_version %d) or a run history",
+	pricesSchemaVersion, listingsSchemaVersion,
+)
+
+func loadArray(data []byte) (*loadedOutput, error) {
+	var elems []json.RawMessage
+	if err := json.Unmarshal(data, &elems); err != nil {
+		return nil, errors.Wrap(err, "while parsing array")
+	}
+
+	if len(elems) == 0 || !bytes.HasPrefix(bytes.TrimSpace(elems[0]), []byte("{")) {
+		var prices []uint64
+		if err := json.Unmarshal(data, &prices); err != nil {
+			return nil, errors.Wrap(err, "while parsing price array")
+		}
+		return &loadedOutput{Kind: outputKindPrices, Prices: prices}, nil
+	}
+
+	var runs []runEntry
+	if err := json.Unmarshal(data, &runs); err != nil {
+		return nil, errors.Wrap(err, "while parsing run history")
+	}
+	for i := range runs {
+		if runs[i].Timestamp == "" || runs[i].Prices == nil {
+			return nil, errors.Errorf("run %d in history has no timestamp or prices", i)
+		}
+	}
+
+	return &loadedOutput{Kind: outputKindHistory, Runs: runs}, nil
+}
+
+func loadObject(data []byte) (*loadedOutput, error) {
+	var shape struct {
+		SchemaVersion *int            `json:"schema_version"`
+		Prices        json.RawMessage `json:"prices"`
+		Listings      json.RawMessage `json:"listings"`
+	}
+	if err := json.Unmarshal(data, &shape); err != nil {
+		return nil, errors.Wrap(err, "while parsing object")
+	}
+
+	var meta outputMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, errors.Wrap(err, "while parsing metadata")
+	}
+	out := &loadedOutput{Metadata: &meta}
+
+	switch {
+	case shape.Listings != nil:
+		if shape.SchemaVersion == nil || *shape.SchemaVersion != listingsSchemaVersion {
+			return nil, errors.Errorf("unsupported listings schema_version %s, expected %d", formatSchemaVersion(shape.SchemaVersion), listingsSchemaVersion)
+		}
+		if err := json.Unmarshal(shape.Listings, &out.Listings); err != nil {
+			return nil, errors.Wrap(err, "while parsing listings")
+		}
+		out.Kind = outputKindListings
+		out.SchemaVersion = listingsSchemaVersion
+		// Coming soon listings are kept in the file, but their prices are
+		// placeholders.
+		priced, _ := splitComingSoon(out.Listings)
+		out.Prices = listingPrices(priced)
+
+	case shape.Prices != nil:
+		if shape.SchemaVersion != nil && *shape.SchemaVersion != pricesSchemaVersion {
+			return nil, errors.Errorf("unsupported prices schema_version %d, expected %d", *shape.SchemaVersion, pricesSchemaVersion)
+		}
+		if err := json.Unmarshal(shape.Prices, &out.Prices); err != nil {
+			return nil, errors.Wrap(err, "while parsing prices")
+		}
+		out.Kind = outputKindPrices
+		out.SchemaVersion = pricesSchemaVersion
+
+	default:
+		return nil, errors.New(unrecognisedOutput)
+	}
+
+	if out.Metadata.GeneratedAt == "" {
+		out.Metadata = nil
+	}
+
+	return out, nil
+}
+
+func formatSchemaVersion(v *int) string {
+	if v == nil {
+		return "missing"
+	}
+
+	return fmt.Sprint(*v)
+}
@@ -0,0 +1,125 @@
+package zoopla
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadOutput(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		wantKind    string
+		wantVersion int
+		wantPrices  []uint64
+		wantMeta    bool
+		wantRuns    int
+	}{
+		{name: "bare prices", data: "[450000, 400000]", wantKind: outputKindPrices, wantPrices: []uint64{450000, 400000}},
+		{name: "empty array", data: " [] \n", wantKind: outputKindPrices, wantPrices: []uint64{}},
+		{name: "legacy envelope", data: `{"generated_at": "2021-04-01T00:00:00Z", "prices": [450000]}`, wantKind: outputKindPrices, wantVersion: pricesSchemaVersion, wantPrices: []uint64{450000}, wantMeta: true},
+		{name: "prices envelope", data: `{"schema_version": 1, "generated_at": "2021-04-01T00:00:00Z", "prices": [450000]}`, wantKind: outputKindPrices, wantVersion: pricesSchemaVersion, wantPrices: []uint64{450000}, wantMeta: true},
+		{name: "prices without metadata", data: `{"schema_version": 1, "prices": [450000]}`, wantKind: outputKindPrices, wantVersion: pricesSchemaVersion, wantPrices: []uint64{450000}},
+		{name: "listings", data: `{"schema_version": 1, "generated_at": "2021-04-01T00:00:00Z", "listings": [{"id": "1", "price": 450000}, {"price": 400000}]}`, wantKind: outputKindListings, wantVersion: listingsSchemaVersion, wantPrices: []uint64{450000, 400000}, wantMeta: true},
+		{name: "listings coming soon", data: `{"schema_version": 1, "listings": [{"id": "1", "price": 450000}, {"id": "2", "price": 0, "status": "coming_soon"}]}`, wantKind: outputKindListings, wantVersion: listingsSchemaVersion, wantPrices: []uint64{450000}},
+		{name: "history", data: `[{"timestamp": "2021-04-01T00:00:00Z", "prices": [450000]}, {"timestamp": "2021-04-02T00:00:00Z", "prices": []}]`, wantKind: outputKindHistory, wantRuns: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadOutput([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Kind != tt.wantKind || got.SchemaVersion != tt.wantVersion {
+				t.Errorf("got %s version %d, want %s version %d", got.Kind, got.SchemaVersion, tt.wantKind, tt.wantVersion)
+			}
+			if !reflect.DeepEqual(got.Prices, tt.wantPrices) {
+				t.Errorf("Prices = %v, want %v", got.Prices, tt.wantPrices)
+			}
+			if (got.Metadata != nil) != tt.wantMeta {
+				t.Errorf("Metadata = %+v, want metadata: %t", got.Metadata, tt.wantMeta)
+			}
+			if len(got.Runs) != tt.wantRuns {
+				t.Errorf("got %d runs, want %d", len(got.Runs), tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestLoadOutputErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"empty", "  \n", "file is empty"},
+		{"not json", "prices", "unrecognised output"},
+		{"negative price", "[-1]", "while parsing price array"},
+		{"object without data", `{"generated_at": "2021-04-01T00:00:00Z"}`, "unrecognised output"},
+		{"future prices version", `{"schema_version": 99, "prices": []}`, "unsupported prices schema_version 99, expected 1"},
+		{"listings without version", `{"listings": []}`, "unsupported listings schema_version missing"},
+		{"future listings version", `{"schema_version": 99, "listings": []}`, "unsupported listings schema_version 99"},
+		{"history without timestamp", `[{"prices": [1]}]`, "run 0 in history has no timestamp or prices"},
+		{"history without prices", `[{"timestamp": "2021-04-01T00:00:00Z"}]`, "run 0 in history has no timestamp or prices"},
+		{"truncated", `{"prices": [1, 2`, "while parsing object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadOutput([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadOutput() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadOutputUnrecognisedNamesVersions(t *testing.T) {
+	_, err := loadOutput([]byte("nonsense"))
+	if err == nil {
+		t.Fatal("loadOutput() succeeded")
+	}
+	for _, want := range []string{"schema_version " + formatSchemaVersion(intPtr(pricesSchemaVersion)), "schema_version " + formatSchemaVersion(intPtr(listingsSchemaVersion))} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't name %q", err, want)
+		}
+	}
+}
+
+func TestLoadOutputFileGzip(t *testing.T) {
+	data, err := gzipBytes([]byte("[450000]"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filename := filepath.Join(t.TempDir(), "prices.json.gz")
+	if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadOutputFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalPrices(got.Prices, []uint64{450000}) {
+		t.Errorf("Prices = %v, want [450000]", got.Prices)
+	}
+}
+
+func TestLoadOutputFileNamesFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "prices.json")
+	if err := ioutil.WriteFile(filename, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadOutputFile(filename); err == nil || !strings.Contains(err.Error(), filename) {
+		t.Errorf("loadOutputFile() error = %v, want it to name the file", err)
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
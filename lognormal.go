@@ -0,0 +1,67 @@
+package zoopla
+
+import (
+	"fmt"
+	"math"
+)
+
+// logNormalFit is a log-normal distribution fitted to prices, which suits
+// them better than a normal distribution as they're skewed towards the
+// expensive end.
+type logNormalFit struct {
+	GeometricMean float64 `json:"geometric_mean"`
+	LogMean       float64 `json:"log_mean"`
+	LogStddev     float64 `json:"log_stddev"`
+	Median        float64 `json:"median"`
+	Mode          float64 `json:"mode"`
+
+	// Excluded counts the zero prices left out, as they have no logarithm.
+	Excluded int `json:"excluded"`
+}
+
+// fitLogNormal fits a log-normal distribution to the positive prices. It
+// returns nil if there are none.
+func fitLogNormal(prices []uint64) *logNormalFit {
+	var logs []float64
+	excluded := 0
+	for _, p := range prices {
+		if p == 0 {
+			excluded++
+			continue
+		}
+		logs = append(logs, math.Log(float64(p)))
+	}
+
+	if len(logs) == 0 {
+		return nil
+	}
+
+	var sum float64
+	for _, v := range logs {
+		sum += v
+	}
+	mu := sum / float64(len(logs))
+
+	var sigma float64
+	if len(logs) > 1 {
+		var sumSquares float64
+		for _, v := range logs {
+			sumSquares += (v - mu) * (v - mu)
+		}
+		sigma = math.Sqrt(sumSquares / float64(len(logs)-1))
+	}
+
+	return &logNormalFit{
+		GeometricMean: math.Exp(mu),
+		LogMean:       mu,
+		LogStddev:     sigma,
+		Median:        math.Exp(mu),
+		Mode:          math.Exp(mu - sigma*sigma),
+		Excluded:      excluded,
+	}
+}
+
+func (f logNormalFit) String() string {
+	return fmt.Sprintf("geometric mean = %.0f, log mean = %.4f, log stddev = %.4f, implied median = %.0f, implied mode = %.0f",
+		f.GeometricMean, f.LogMean, f.LogStddev, f.Median, f.Mode)
+}
@@ -0,0 +1,76 @@
+package zoopla
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFitLogNormal(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []uint64
+		want   *logNormalFit
+	}{
+		{name: "empty"},
+		{name: "only zeros", prices: []uint64{0, 0}},
+		{
+			name:   "single",
+			prices: []uint64{400000},
+			want:   &logNormalFit{GeometricMean: 400000, LogMean: math.Log(400000), Median: 400000, Mode: 400000},
+		},
+		{
+			// The logs are 2 ln 10 either side of ln 1000.
+			name:   "spread",
+			prices: []uint64{100, 10000},
+			want: &logNormalFit{
+				GeometricMean: 1000,
+				LogMean:       math.Log(1000),
+				LogStddev:     math.Sqrt2 * math.Ln10,
+				Median:        1000,
+				Mode:          1000 * math.Exp(-2*math.Ln10*math.Ln10),
+			},
+		},
+		{
+			name:   "zeros excluded",
+			prices: []uint64{0, 100, 10000},
+			want: &logNormalFit{
+				GeometricMean: 1000,
+				LogMean:       math.Log(1000),
+				LogStddev:     math.Sqrt2 * math.Ln10,
+				Median:        1000,
+				Mode:          1000 * math.Exp(-2*math.Ln10*math.Ln10),
+				Excluded:      1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fitLogNormal(tt.prices)
+			if tt.want == nil {
+				if got != nil {
+					t.Errorf("got %+v, want nil", *got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("got nil")
+			}
+
+			if got.Excluded != tt.want.Excluded ||
+				!closeTo(got.GeometricMean, tt.want.GeometricMean) || !closeTo(got.LogMean, tt.want.LogMean) ||
+				!closeTo(got.LogStddev, tt.want.LogStddev) || !closeTo(got.Median, tt.want.Median) || !closeTo(got.Mode, tt.want.Mode) {
+				t.Errorf("got %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestLogNormalFitString(t *testing.T) {
+	f := logNormalFit{GeometricMean: 450000, LogMean: 13.017, LogStddev: 0.25, Median: 450000, Mode: 422760}
+
+	want := "geometric mean = 450000, log mean = 13.0170, log stddev = 0.2500, implied median = 450000, implied mode = 422760"
+	if got := f.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
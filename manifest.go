@@ -0,0 +1,67 @@
+package zoopla
+
+import (
+	"log"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const redacted = "REDACTED"
+
+// runManifest records how a run's results were produced, so that it can be
+// reproduced.
+type runManifest struct {
+	Args         cliArgs      `json:"args"`
+	ToolVersion  string       `json:"tool_version"`
+	Commit       string       `json:"commit,omitempty"`
+	StartedAt    string       `json:"started_at"`
+	FinishedAt   string       `json:"finished_at"`
+	PagesFetched int          `json:"pages_fetched"`
+	HTTP         fetchStats   `json:"http"`
+	Counts       outputCounts `json:"counts"`
+}
+
+// redactedArgs returns args with its secrets blanked out.
+func redactedArgs(args cliArgs) cliArgs {
+	for _, secret := range []*string{&args.WebhookSecret, &args.SlackWebhook, &args.SMTPPass, &args.InfluxToken} {
+		if *secret != "" {
+			*secret = redacted
+		}
+	}
+
+	return args
+}
+
+func newRunManifest(args *cliArgs, started time.Time, f *fetcher, diag *diagnostics, counts outputCounts) runManifest {
+	meta := newOutputMetadata(args, diag, counts)
+
+	return runManifest{
+		Args:         redactedArgs(*args),
+		ToolVersion:  meta.ToolVersion,
+		Commit:       vcsRevision(),
+		StartedAt:    started.UTC().Format(time.RFC3339),
+		FinishedAt:   timeNow().UTC().Format(time.RFC3339),
+		PagesFetched: meta.PagesFetched,
+		HTTP:         f.requestStats(),
+		Counts:       meta.Counts,
+	}
+}
+
+func writeManifest(args *cliArgs, started time.Time, f *fetcher, diag *diagnostics, counts outputCounts) error {
+	if args.Manifest == "" {
+		return nil
+	}
+
+	data, err := marshalJSON(newRunManifest(args, started, f, diag, counts), true)
+	if err != nil {
+		return errors.Wrap(err, "while marshalling manifest")
+	}
+
+	if err := writeFileAtomic(args.Manifest, data); err != nil {
+		return err
+	}
+	log.Print("wrote manifest to ", args.Manifest)
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ryanc414/zoopla-analyzer/internal/testserver"
+)
+
+func TestRedactedArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		args cliArgs
+		want cliArgs
+	}{
+		{name: "no secrets"},
+		{
+			name: "secrets",
+			args: cliArgs{Postcode: "SW2", WebhookSecret: "s1", SlackWebhook: "https://hooks.slack.com/x", SMTPPass: "hunter2", InfluxToken: "t"},
+			want: cliArgs{Postcode: "SW2", WebhookSecret: redacted, SlackWebhook: redacted, SMTPPass: redacted, InfluxToken: redacted},
+		},
+		{
+			name: "some secrets",
+			args: cliArgs{SMTPUser: "me", SMTPPass: "hunter2"},
+			want: cliArgs{SMTPUser: "me", SMTPPass: redacted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := redactedArgs(tt.args)
+			if got.Postcode != tt.want.Postcode || got.SMTPUser != tt.want.SMTPUser ||
+				got.WebhookSecret != tt.want.WebhookSecret || got.SlackWebhook != tt.want.SlackWebhook ||
+				got.SMTPPass != tt.want.SMTPPass || got.InfluxToken != tt.want.InfluxToken {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactedArgsLeavesOriginal(t *testing.T) {
+	args := cliArgs{SMTPPass: "hunter2"}
+	redactedArgs(args)
+	if args.SMTPPass != "hunter2" {
+		t.Errorf("got %q, want the original args unchanged", args.SMTPPass)
+	}
+}
+
+func TestRunWithManifest(t *testing.T) {
+	setNow(t, time.Date(2021, time.May, 10, 9, 0, 0, 0, time.UTC))
+
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, searchPage(400000, 450000))
+
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.json")
+	args := testSearchArgs(t, "--postcode", "sw2", "--base-url", srv.SearchURL(), "--delay", "0",
+		"--outputfilename", filepath.Join(dir, "prices.json"), "--manifest", manifest, "--quiet")
+	args.SMTPPass = "hunter2"
+	if err := runFetch(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got runManifest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Args.Postcode != "sw2" || got.Args.Manifest != manifest {
+		t.Errorf("got args %+v", got.Args)
+	}
+	if got.Args.SMTPPass != redacted {
+		t.Errorf("got SMTP password %q, want it redacted", got.Args.SMTPPass)
+	}
+	if got.FinishedAt != "2021-05-10T09:00:00Z" || got.StartedAt == "" {
+		t.Errorf("got started %q, finished %q", got.StartedAt, got.FinishedAt)
+	}
+	// The empty second page ends the search.
+	if got.PagesFetched != 2 || got.HTTP.Requests != 2 || got.HTTP.Statuses["200"] != 2 || len(got.HTTP.URLs) != 2 {
+		t.Errorf("got %d pages and HTTP stats %+v", got.PagesFetched, got.HTTP)
+	}
+	if got.Counts.Parsed != 2 || got.Counts.Deduped != 0 {
+		t.Errorf("got counts %+v", got.Counts)
+	}
+}
+
+func TestWriteManifestWithoutFile(t *testing.T) {
+	if err := writeManifest(&cliArgs{}, time.Now(), &fetcher{}, newDiagnostics(&cliArgs{}), outputCounts{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,211 @@
+package zoopla
+
+import (
+	"fmt"
+	"strings"
+)
+
+var markdownEscaper = strings.NewReplacer(`|`, `\|`, `[`, `\[`, `]`, `\]`, "\n", " ")
+
+func renderMarkdown(r *reportData) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Price report for %s\n\n", markdownEscaper.Replace(r.Query.Postcode))
+	fmt.Fprintf(&b, "Generated %s.\n\n", r.GeneratedAt)
+
+	b.WriteString("## Query\n\n| Parameter | Value |\n| --- | --- |\n")
+	fmt.Fprintf(&b, "| Postcode | %s |\n", markdownEscaper.Replace(r.Query.Postcode))
+	if r.Query.PriceMin != nil {
+		fmt.Fprintf(&b, "| Min price | %s |\n", formatPrice(*r.Query.PriceMin))
+	}
+	if r.Query.PriceMax != nil {
+		fmt.Fprintf(&b, "| Max price | %s |\n", formatPrice(*r.Query.PriceMax))
+	}
+	if r.Query.BedsMin != nil {
+		fmt.Fprintf(&b, "| Min beds | %d |\n", *r.Query.BedsMin)
+	}
+	if r.Query.BedsMax != nil {
+		fmt.Fprintf(&b, "| Max beds | %d |\n", *r.Query.BedsMax)
+	}
+	fmt.Fprintf(&b, "| Radius | %d miles |\n\n", r.Query.Radius)
+
+	if r.Stats == nil {
+		b.WriteString("No prices were found.\n")
+		return b.String()
+	}
+
+	b.WriteString("## Price stats\n\n| Count | Min | Max | Mean | Median | Std dev |\n| ---: | ---: | ---: | ---: | ---: | ---: |\n")
+	fmt.Fprintf(&b, "| %d | %s | %s | %s | %s | %s |\n\n",
+		r.Stats.Count, formatPrice(r.Stats.Min), formatPrice(r.Stats.Max),
+		formatMeanPrice(r.Stats.Mean), formatMeanPrice(r.Stats.Median), formatMeanPrice(r.Stats.Stddev))
+
+	if r.Sold != "" {
+		fmt.Fprintf(&b, "Sold prices: %s.\n\n", markdownEscaper.Replace(r.Sold))
+	}
+
+	writeMarkdownYield(&b, r.Yield, r.YieldGroups)
+	writeMarkdownGroups(&b, "By bedrooms", "Bedrooms", r.Beds)
+	writeMarkdownGroups(&b, "By property type", "Type", r.Types)
+	if r.FlatsVsHouses != "" {
+		fmt.Fprintf(&b, "Flats vs houses: %s.\n\n", r.FlatsVsHouses)
+	}
+	if r.NewBuilds != "" {
+		fmt.Fprintf(&b, "New builds vs resales: %s.\n\n", r.NewBuilds)
+	}
+	writeMarkdownSoftness(&b, r.Softness)
+	writeMarkdownOnMarket(&b, r.OnMarket)
+	writeMarkdownSqft(&b, r.PricePerSqft)
+	writeMarkdownAgents(&b, r.Agents, r.AgentMargin)
+
+	if len(r.Histogram) > 0 {
+		b.WriteString("## Distribution\n\n```\n")
+		for _, bucket := range r.Histogram {
+			fmt.Fprintf(&b, "%-12s %-12s %5d\n", formatPrice(bucket.Min), formatPrice(bucket.Max), bucket.Count)
+		}
+		b.WriteString("```\n\n")
+	}
+
+	writeMarkdownListings(&b, "Most expensive", r.Top)
+	writeMarkdownListings(&b, "Least expensive", r.Bottom)
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func writeMarkdownGroups(b *strings.Builder, title, keyHeader string, groups []reportGroup) {
+	if len(groups) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "## %s\n\n| %s | Count | Mean | Median | P25 | P75 |\n| --- | ---: | ---: | ---: | ---: | ---: |\n", title, keyHeader)
+	for _, g := range groups {
+		fmt.Fprintf(b, "| %s | %d | %s | %s | %s | %s |\n", markdownEscaper.Replace(g.Key), g.Count,
+			formatMeanPrice(g.Mean), formatMeanPrice(g.Median), formatMeanPrice(g.P25), formatMeanPrice(g.P75))
+	}
+	b.WriteString("\n")
+}
+
+func writeMarkdownSoftness(b *strings.Builder, s *reportSoftness) {
+	if s == nil {
+		return
+	}
+
+	b.WriteString("## Market softness\n\n| Measure | Value | Listings |\n| --- | ---: | ---: |\n")
+	fmt.Fprintf(b, "| Ever reduced | %.0f%% | %d of %d |\n", s.ReducedShare, s.Reduced, s.Listings)
+	if r := s.Reduction; r != nil {
+		fmt.Fprintf(b, "| Mean reduction | %s (%.1f%%) | %d |\n", formatMeanPrice(r.Mean), r.MeanPct, r.Count)
+		fmt.Fprintf(b, "| Median reduction | %s (%.1f%%) | %d |\n", formatMeanPrice(r.Median), r.MedianPct, r.Count)
+	}
+	if d := s.Days; d != nil {
+		fmt.Fprintf(b, "| Mean days to first reduction | %.0f | %d |\n", d.Mean, d.Count)
+	}
+	b.WriteString("\n")
+}
+
+func writeMarkdownOnMarket(b *strings.Builder, s *reportOnMarket) {
+	if s == nil {
+		return
+	}
+
+	b.WriteString("## Time on market\n\n| Measure | Value |\n| --- | ---: |\n")
+	fmt.Fprintf(b, "| Median days listed | %.0f |\n| P90 days listed | %.0f |\n", s.MedianDays, s.P90Days)
+	fmt.Fprintf(b, "| Listed over %d days | %.0f%% (%d of %d) |\n", s.StaleDays, s.StaleShare, s.Stale, s.Dated)
+	if len(s.Stalest) > 0 {
+		b.WriteString("\n| Days | Price | Listing |\n| ---: | ---: | --- |\n")
+		for _, sl := range s.Stalest {
+			link := markdownListingLink(reportListing{Title: sl.Title, Address: sl.Address, URL: sl.URL})
+			fmt.Fprintf(b, "| %d | %s | %s |\n", sl.Days, formatPrice(sl.Price), link)
+		}
+	}
+	fmt.Fprintf(b, "\nListed dates for %d of %d listings.\n\n", s.Dated, s.Listings)
+}
+
+func writeMarkdownYield(b *strings.Builder, summary string, groups []reportYieldGroup) {
+	if summary == "" {
+		return
+	}
+
+	fmt.Fprintf(b, "## Gross yield\n\n%s.\n\n", markdownEscaper.Replace(summary))
+	b.WriteString("| Bedrooms | Sales | Rents | Median price | Median rent | Yield |\n| --- | ---: | ---: | ---: | ---: | ---: |\n")
+	for _, g := range groups {
+		price, rent, yield := "-", "-", "-"
+		if g.Sales > 0 {
+			price = formatMeanPrice(g.MedianPrice)
+		}
+		if g.Rents > 0 {
+			rent = formatMeanPrice(g.MedianRent) + " pcm"
+		}
+		if g.Estimated {
+			yield = fmt.Sprintf("%.1f%%", g.Yield)
+		}
+		fmt.Fprintf(b, "| %s | %d | %d | %s | %s | %s |\n", markdownEscaper.Replace(g.Key), g.Sales, g.Rents, price, rent, yield)
+	}
+	b.WriteString("\n")
+}
+
+func writeMarkdownSqft(b *strings.Builder, s *reportSqft) {
+	if s == nil {
+		return
+	}
+
+	b.WriteString("## Price per sq ft\n\n| Type | Count | Min | P25 | Median | Mean | P75 | Max |\n| --- | ---: | ---: | ---: | ---: | ---: | ---: | ---: |\n")
+	for _, g := range append([]pricePerSqftGroup{s.All}, s.ByType...) {
+		fmt.Fprintf(b, "| %s | %d | %s | %s | %s | %s | %s | %s |\n", markdownEscaper.Replace(g.Key), g.Count,
+			formatMeanPrice(g.Min), formatMeanPrice(g.P25), formatMeanPrice(g.Median),
+			formatMeanPrice(g.Mean), formatMeanPrice(g.P75), formatMeanPrice(g.Max))
+	}
+	fmt.Fprintf(b, "\nArea data for %d of %d listings (%.0f%%).", s.WithArea, s.Total, s.Coverage)
+	if s.LowCoverage {
+		b.WriteString(" Coverage is low, so these may be biased towards new builds.")
+	}
+	b.WriteString("\n\n")
+}
+
+func writeMarkdownAgents(b *strings.Builder, agents []reportAgent, margin string) {
+	if len(agents) == 0 {
+		return
+	}
+
+	b.WriteString("## Top agents\n\n| Agent | Count | Share | Mean | Median | Vs area |\n| --- | ---: | ---: | ---: | ---: | ---: |\n")
+	for _, a := range agents {
+		name := markdownEscaper.Replace(a.Name)
+		if a.Flagged {
+			name += " \\*"
+		}
+		fmt.Fprintf(b, "| %s | %d | %.1f%% | %s | %s | %+.1f%% |\n", name, a.Count, a.Share,
+			formatMeanPrice(a.Mean), formatMeanPrice(a.Median), a.Deviation)
+	}
+	if margin != "" {
+		fmt.Fprintf(b, "\n\\* Median is more than %s from the area median.\n", margin)
+	}
+	b.WriteString("\n")
+}
+
+func writeMarkdownListings(b *strings.Builder, title string, listings []reportListing) {
+	if len(listings) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "## %s\n\n| Price | Beds | Listing |\n| ---: | ---: | --- |\n", title)
+	for _, l := range listings {
+		fmt.Fprintf(b, "| %s | %s | %s |\n", formatPrice(l.Price), l.Beds, markdownListingLink(l))
+	}
+	b.WriteString("\n")
+}
+
+func markdownListingLink(l reportListing) string {
+	text := l.Title
+	if l.Address != "" {
+		text = strings.TrimSpace(text + ", " + l.Address)
+	}
+	text = strings.Trim(text, ", ")
+	if text == "" {
+		text = "listing"
+	}
+
+	text = markdownEscaper.Replace(text)
+	if l.URL == "" {
+		return text
+	}
+
+	return fmt.Sprintf("[%s](%s)", text, strings.ReplaceAll(l.URL, ")", "%29"))
+}
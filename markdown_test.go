@@ -0,0 +1,112 @@
+package zoopla
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMarkdownGolden(t *testing.T) {
+	const golden = "testdata/output/report.golden.md"
+
+	setNow(t, date(2021, time.April, 1))
+	listings := reportListings()
+	got := renderMarkdown(buildReport(listings, listingPrices(listings), reportArgs()))
+
+	if *updateGoldens {
+		if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("Markdown report differs from %s, rerun with -update if the change is deliberate:\n%s", golden, got)
+	}
+}
+
+func TestRenderMarkdownSections(t *testing.T) {
+	setNow(t, date(2021, time.April, 1))
+
+	tests := []struct {
+		name     string
+		listings []listing
+		want     []string
+		notWant  []string
+	}{
+		{
+			name:     "no prices",
+			listings: nil,
+			want:     []string{"# Price report for SW2", "No prices were found."},
+			notWant:  []string{"## Price stats", "## Most expensive"},
+		},
+		{
+			name:     "no beds or types",
+			listings: []listing{{Price: 300000}, {Price: 400000}},
+			want:     []string{"## Price stats", "## Distribution", "## Most expensive", "## Least expensive"},
+			notWant:  []string{"## By bedrooms", "## By property type", "## Time on market", "## Price per sq ft"},
+		},
+		{
+			name:     "breakdowns",
+			listings: reportListings(),
+			want:     []string{"## By bedrooms", "| 2 bed | 3 |", "## By property type", "| terraced | 2 |"},
+		},
+		{
+			name:     "price per sq ft",
+			listings: sqftListings(),
+			want: []string{
+				"## Price per sq ft", "| all | 3 | £500 | £550 | £600 | £600 | £650 | £700 |", "| flat | 2 |",
+				"Area data for 3 of 5 listings (60%).\n",
+			},
+			notWant: []string{"Coverage is low"},
+		},
+		{
+			name:     "low sq ft coverage",
+			listings: append(sqftListings(), make([]listing, 6)...),
+			want:     []string{"Area data for 3 of 11 listings (27%). Coverage is low"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := renderMarkdown(buildReport(tt.listings, listingPrices(tt.listings), reportArgs()))
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("report is missing %q:\n%s", s, got)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("report has %q:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMarkdownListingLink(t *testing.T) {
+	tests := []struct {
+		name string
+		l    reportListing
+		want string
+	}{
+		{"title and address", reportListing{Title: "2 bed flat", Address: "Acre Lane", URL: "https://example.com/1/"}, "[2 bed flat, Acre Lane](https://example.com/1/)"},
+		{"address only", reportListing{Address: "Acre Lane"}, "Acre Lane"},
+		{"nothing", reportListing{}, "listing"},
+		{"escaped text", reportListing{Title: "flat | [new]"}, `flat \| \[new\]`},
+		{"newline", reportListing{Title: "2 bed\nflat"}, "2 bed flat"},
+		{"paren in url", reportListing{Title: "flat", URL: "https://example.com/a)b"}, "[flat](https://example.com/a%29b)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markdownListingLink(tt.l); got != tt.want {
+				t.Errorf("markdownListingLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package zoopla
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type mergeArgs struct {
+	Output string   `arg:"positional,required"`
+	Inputs []string `arg:"positional,required"`
+	Dedupe bool     `arg:"--dedupe"`
+	Pretty bool     `arg:"--pretty"`
+}
+
+// mergeSource records what one input contributed to a merged file.
+type mergeSource struct {
+	File          string    `json:"file"`
+	Kind          string    `json:"kind"`
+	SchemaVersion int       `json:"schema_version,omitempty"`
+	GeneratedAt   string    `json:"generated_at,omitempty"`
+	Query         *runQuery `json:"query,omitempty"`
+	Count         int       `json:"count"`
+}
+
+type mergeProvenance struct {
+	MergedFrom []mergeSource `json:"merged_from"`
+	Stats      *priceStats   `json:"stats,omitempty"`
+}
+
+// mergedListings and mergedPrices keep the listings and prices envelope
+// shapes, so merged files load like any other output.
+type mergedListings struct {
+	SchemaVersion int    `json:"schema_version"`
+	GeneratedAt   string `json:"generated_at"`
+	ToolVersion   string `json:"tool_version"`
+	mergeProvenance
+	Listings []listing `json:"listings"`
+}
+
+type mergedPrices struct {
+	SchemaVersion int    `json:"schema_version"`
+	GeneratedAt   string `json:"generated_at"`
+	ToolVersion   string `json:"tool_version"`
+	mergeProvenance
+	Prices []uint64 `json:"prices"`
+}
+
+func runMerge(argv []string) error {
+	var args mergeArgs
+	p, err := parseSubcommand("merge", &args, argv)
+	if err != nil {
+		return err
+	}
+
+	inputs := make([]*loadedOutput, len(args.Inputs))
+	for i, filename := range args.Inputs {
+		in, err := loadOutputFile(filename)
+		if err != nil {
+			return err
+		}
+		if in.Kind == outputKindHistory {
+			return errors.Errorf("%s is a run history, which can't be merged with other outputs", filename)
+		}
+		if i > 0 && in.Kind != inputs[0].Kind {
+			return errors.Errorf("%s holds %s but %s holds %s, so they can't be merged", filename, in.Kind, args.Inputs[0], inputs[0].Kind)
+		}
+		inputs[i] = in
+	}
+
+	if args.Dedupe && inputs[0].Kind != outputKindListings {
+		return newUsageError(p, "--dedupe needs listings files, as price files have no listing IDs")
+	}
+
+	now := timeNow().UTC().Format(time.RFC3339)
+	var out interface{}
+	var sources []mergeSource
+	var prices []uint64
+	if inputs[0].Kind == outputKindListings {
+		var listings []listing
+		listings, sources = mergeListings(args.Inputs, inputs, args.Dedupe)
+		prices = listingPrices(listings)
+		out = &mergedListings{
+			SchemaVersion:   listingsSchemaVersion,
+			GeneratedAt:     now,
+			ToolVersion:     toolVersion(),
+			mergeProvenance: newMergeProvenance(sources, prices),
+			Listings:        listings,
+		}
+	} else {
+		for i, in := range inputs {
+			prices = append(prices, in.Prices...)
+			sources = append(sources, newMergeSource(args.Inputs[i], in, len(in.Prices)))
+		}
+		out = &mergedPrices{
+			SchemaVersion:   pricesSchemaVersion,
+			GeneratedAt:     now,
+			ToolVersion:     toolVersion(),
+			mergeProvenance: newMergeProvenance(sources, prices),
+			Prices:          prices,
+		}
+	}
+
+	data, err := marshalJSON(out, args.Pretty)
+	if err != nil {
+		return errors.Wrap(err, "while marshalling merged output")
+	}
+	if strings.HasSuffix(strings.ToLower(args.Output), gzipExt) {
+		if data, err = gzipBytes(data); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFileAtomic(args.Output, data); err != nil {
+		return err
+	}
+	log.Printf("merged %d prices from %d files into %s", len(prices), len(inputs), args.Output)
+	if len(prices) > 0 {
+		log.Print("price stats: ", calculatePriceStats(prices))
+	}
+
+	return nil
+}
+
+// mergeListings concatenates the inputs' listings. With dedupe, a listing ID
+// seen in several inputs keeps only the copy from the most recent run.
+func mergeListings(filenames []string, inputs []*loadedOutput, dedupe bool) ([]listing, []mergeSource) {
+	type seenListing struct {
+		index  int
+		source int
+		at     time.Time
+	}
+
+	var listings []listing
+	sources := make([]mergeSource, len(inputs))
+	seen := make(map[string]seenListing)
+	for i, in := range inputs {
+		sources[i] = newMergeSource(filenames[i], in, 0)
+		at := runTime(filenames[i], in)
+		for _, l := range in.Listings {
+			if !dedupe || l.ID == "" {
+				listings = append(listings, l)
+				sources[i].Count++
+				continue
+			}
+
+			prev, ok := seen[l.ID]
+			switch {
+			case !ok:
+				seen[l.ID] = seenListing{index: len(listings), source: i, at: at}
+				listings = append(listings, l)
+				sources[i].Count++
+			case !at.Before(prev.at):
+				listings[prev.index] = l
+				sources[prev.source].Count--
+				sources[i].Count++
+				seen[l.ID] = seenListing{index: prev.index, source: i, at: at}
+			}
+		}
+	}
+
+	return listings, sources
+}
+
+// runTime is when an input was generated, falling back to its modification
+// time for files written without metadata.
+func runTime(filename string, in *loadedOutput) time.Time {
+	if in.Metadata != nil {
+		if t, err := time.Parse(time.RFC3339, in.Metadata.GeneratedAt); err == nil {
+			return t
+		}
+	}
+	if info, err := os.Stat(filename); err == nil {
+		return info.ModTime()
+	}
+
+	return time.Time{}
+}
+
+func newMergeSource(filename string, in *loadedOutput, count int) mergeSource {
+	s := mergeSource{File: filename, Kind: in.Kind, SchemaVersion: in.SchemaVersion, Count: count}
+	if in.Metadata != nil {
+		s.GeneratedAt = in.Metadata.GeneratedAt
+		q := in.Metadata.Query
+		s.Query = &q
+	}
+
+	return s
+}
+
+func newMergeProvenance(sources []mergeSource, prices []uint64) mergeProvenance {
+	p := mergeProvenance{MergedFrom: sources}
+	if len(prices) > 0 {
+		s := calculatePriceStats(prices)
+		p.Stats = &s
+	}
+
+	return p
+}
@@ -0,0 +1,199 @@
+package zoopla
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeListings(t *testing.T) {
+	older := &loadedOutput{
+		Kind:     outputKindListings,
+		Metadata: &outputMetadata{GeneratedAt: "2021-04-01T00:00:00Z"},
+		Listings: []listing{{ID: "1", Price: 450000}, {ID: "2", Price: 400000}, {Price: 300000}},
+	}
+	newer := &loadedOutput{
+		Kind:     outputKindListings,
+		Metadata: &outputMetadata{GeneratedAt: "2021-04-08T00:00:00Z"},
+		Listings: []listing{{ID: "1", Price: 440000}, {ID: "3", Price: 500000}, {Price: 300000}},
+	}
+
+	tests := []struct {
+		name       string
+		inputs     []*loadedOutput
+		dedupe     bool
+		wantPrices []uint64
+		wantCounts []int
+	}{
+		{"concatenated", []*loadedOutput{older, newer}, false, []uint64{450000, 400000, 300000, 440000, 500000, 300000}, []int{3, 3}},
+		{"deduped", []*loadedOutput{older, newer}, true, []uint64{440000, 400000, 300000, 500000, 300000}, []int{2, 3}},
+		// The newest run wins whatever order the files are given in.
+		{"deduped newest first", []*loadedOutput{newer, older}, true, []uint64{440000, 500000, 300000, 400000, 300000}, []int{3, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filenames := []string{"a.json", "b.json"}
+			listings, sources := mergeListings(filenames, tt.inputs, tt.dedupe)
+			if got := listingPrices(listings); !equalPrices(got, tt.wantPrices) {
+				t.Errorf("prices = %v, want %v", got, tt.wantPrices)
+			}
+
+			var counts []int
+			for _, s := range sources {
+				counts = append(counts, s.Count)
+			}
+			if !reflect.DeepEqual(counts, tt.wantCounts) {
+				t.Errorf("source counts = %v, want %v", counts, tt.wantCounts)
+			}
+		})
+	}
+}
+
+func TestRunTime(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "prices.json")
+	if err := ioutil.WriteFile(filename, []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2021, time.March, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filename, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		in       *loadedOutput
+		want     time.Time
+	}{
+		{"metadata", filename, &loadedOutput{Metadata: &outputMetadata{GeneratedAt: "2021-04-01T00:00:00Z"}}, date(2021, time.April, 1)},
+		{"no metadata", filename, &loadedOutput{}, mtime},
+		{"bad timestamp", filename, &loadedOutput{Metadata: &outputMetadata{GeneratedAt: "yesterday"}}, mtime},
+		{"missing file", filepath.Join(filepath.Dir(filename), "missing.json"), &loadedOutput{}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := runTime(tt.filename, tt.in); !got.Equal(tt.want) {
+				t.Errorf("runTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeTestFiles writes each file's contents into dir, returning their
+// paths in the order given.
+func writeTestFiles(t *testing.T, dir string, files ...[2]string) []string {
+	t.Helper()
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = filepath.Join(dir, f[0])
+		if err := ioutil.WriteFile(paths[i], []byte(f[1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return paths
+}
+
+func TestRunMerge(t *testing.T) {
+	setNow(t, date(2021, time.April, 10))
+	dir := t.TempDir()
+
+	tests := []struct {
+		name       string
+		files      [][2]string
+		flags      []string
+		output     string
+		wantKind   string
+		wantPrices []uint64
+	}{
+		{
+			name:       "prices",
+			files:      [][2]string{{"a.json", "[450000]"}, {"b.json", `{"schema_version": 1, "generated_at": "2021-04-01T00:00:00Z", "query": {"postcode": "SW2"}, "prices": [400000, 300000]}`}},
+			output:     "merged.json",
+			wantKind:   outputKindPrices,
+			wantPrices: []uint64{450000, 400000, 300000},
+		},
+		{
+			name: "listings deduped",
+			files: [][2]string{
+				{"c.json", `{"schema_version": 1, "generated_at": "2021-04-01T00:00:00Z", "listings": [{"id": "1", "price": 450000}]}`},
+				{"d.json", `{"schema_version": 1, "generated_at": "2021-04-08T00:00:00Z", "listings": [{"id": "1", "price": 440000}, {"id": "2", "price": 500000}]}`},
+			},
+			flags:      []string{"--dedupe"},
+			output:     "merged.json.gz",
+			wantKind:   outputKindListings,
+			wantPrices: []uint64{440000, 500000},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inputs := writeTestFiles(t, dir, tt.files...)
+			output := filepath.Join(dir, tt.output)
+			if err := runMerge(append(append([]string{output}, inputs...), tt.flags...)); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadOutputFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Kind != tt.wantKind || !equalPrices(got.Prices, tt.wantPrices) {
+				t.Errorf("merged %s with prices %v, want %s with %v", got.Kind, got.Prices, tt.wantKind, tt.wantPrices)
+			}
+			if got.Metadata == nil || got.Metadata.GeneratedAt != "2021-04-10T00:00:00Z" {
+				t.Errorf("merged metadata = %+v", got.Metadata)
+			}
+
+			data, err := readFileMaybeGzip(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, in := range inputs {
+				if !strings.Contains(string(data), filepath.Base(in)) {
+					t.Errorf("merged file doesn't record it came from %s", in)
+				}
+			}
+		})
+	}
+}
+
+func TestRunMergeErrors(t *testing.T) {
+	dir := t.TempDir()
+	files := writeTestFiles(t, dir,
+		[2]string{"prices.json", "[450000]"},
+		[2]string{"listings.json", `{"schema_version": 1, "listings": [{"price": 1}]}`},
+		[2]string{"history.json", `[{"timestamp": "2021-04-01T00:00:00Z", "prices": [1]}]`},
+	)
+	prices, listings, history := files[0], files[1], files[2]
+
+	tests := []struct {
+		name    string
+		inputs  []string
+		wantErr string
+	}{
+		{"mixed kinds", []string{prices, listings}, "can't be merged"},
+		{"history", []string{prices, history}, "is a run history"},
+		{"missing", []string{prices, filepath.Join(dir, "missing.json")}, "missing.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(dir, "merged.json")
+			err := runMerge(append([]string{output}, tt.inputs...))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("runMerge() error = %v, want %q", err, tt.wantErr)
+			}
+			if _, err := os.Stat(output); !os.IsNotExist(err) {
+				t.Error("a failed merge wrote its output")
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package zoopla
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// outputMetadata describes how a run's output was produced. With
+// --with-metadata it's written alongside the prices or listings.
+type outputMetadata struct {
+	GeneratedAt  string       `json:"generated_at"`
+	Query        runQuery     `json:"query"`
+	ToolVersion  string       `json:"tool_version"`
+	PagesFetched int          `json:"pages_fetched"`
+	Counts       outputCounts `json:"counts"`
+
+	Histogram []histogramBucket `json:"histogram,omitempty"`
+}
+
+type outputCounts struct {
+	Parsed     int `json:"parsed"`
+	SkippedPOA int `json:"skipped_poa"`
+	Deduped    int `json:"deduped"`
+	ComingSoon int `json:"coming_soon"`
+}
+
+type pricesEnvelope struct {
+	SchemaVersion int    `json:"schema_version"`
+	Set           string `json:"set,omitempty"`
+	*outputMetadata
+	Prices []uint64 `json:"prices"`
+}
+
+func newOutputMetadata(args *cliArgs, diag *diagnostics, counts outputCounts) *outputMetadata {
+	pages, skipped := diag.counts()
+	counts.SkippedPOA = skipped
+
+	return &outputMetadata{
+		GeneratedAt:  timeNow().UTC().Format(time.RFC3339),
+		Query:        newRunQuery(args),
+		ToolVersion:  toolVersion(),
+		PagesFetched: pages,
+		Counts:       counts,
+	}
+}
+
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Version
+	}
+
+	return "unknown"
+}
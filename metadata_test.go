@@ -0,0 +1,135 @@
+package zoopla
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/ryanc414/zoopla-analyzer/internal/testserver"
+)
+
+func TestNewOutputMetadata(t *testing.T) {
+	setNow(t, time.Date(2021, time.May, 10, 9, 0, 0, 0, time.UTC))
+
+	diag := newDiagnostics(&cliArgs{})
+	diag.report(1, []cardError{{index: 2, err: errors.New("no price")}})
+	diag.report(2, nil)
+
+	meta := newOutputMetadata(&cliArgs{Postcode: "SW2", Radius: 1}, diag, outputCounts{Parsed: 10, Deduped: 1})
+	want := &outputMetadata{
+		GeneratedAt:  "2021-05-10T09:00:00Z",
+		Query:        runQuery{Postcode: "SW2", Radius: 1},
+		ToolVersion:  toolVersion(),
+		PagesFetched: 2,
+		Counts:       outputCounts{Parsed: 10, SkippedPOA: 1, Deduped: 1},
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("newOutputMetadata() = %+v, want %+v", meta, want)
+	}
+}
+
+func TestMarshalOutputWithMetadata(t *testing.T) {
+	meta := &outputMetadata{GeneratedAt: "2021-05-10T09:00:00Z", Query: runQuery{Postcode: "SW2"}, ToolVersion: "(devel)", PagesFetched: 1}
+	got, err := marshalOutput([]listing{{Price: 400000}}, &cliArgs{OutputSet: outputSetDeduped}, meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"schema_version":1,"set":"deduped","generated_at":"2021-05-10T09:00:00Z","query":{"postcode":"SW2"},` +
+		`"tool_version":"(devel)","pages_fetched":1,"counts":{"parsed":0,"skipped_poa":0,"deduped":0,"coming_soon":0},"prices":[400000]}`
+	if string(got) != want {
+		t.Errorf("got %s\nwant %s", got, want)
+	}
+}
+
+func TestRunWithMetadata(t *testing.T) {
+	setNow(t, time.Date(2021, time.May, 10, 9, 0, 0, 0, time.UTC))
+
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, searchPage(400000, 450000, 400000))
+	srv.SetPage(2, `<html><body><div class="ListingsContainer">`+
+		`<div data-testid="search-result"><div class="PriceContainer"><p>POA</p></div></div>`+
+		`<div data-testid="search-result"><div class="PriceContainer"><p>£500,000</p></div></div></div></body></html>`)
+
+	filename := filepath.Join(t.TempDir(), "prices.json")
+	args := testSearchArgs(t, "--postcode", "sw2", "--base-url", srv.SearchURL(), "--delay", "0",
+		"--outputfilename", filename, "--with-metadata", "--quiet")
+	if err := runFetch(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := loadOutputFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SchemaVersion != pricesSchemaVersion || got.Metadata == nil {
+		t.Fatalf("got %+v", got)
+	}
+
+	meta := got.Metadata
+	if want := (outputCounts{Parsed: 4, SkippedPOA: 1, Deduped: 1}); meta.Counts != want {
+		t.Errorf("counts = %+v, want %+v", meta.Counts, want)
+	}
+	if meta.PagesFetched != 3 || meta.GeneratedAt != "2021-05-10T09:00:00Z" || meta.Query.Postcode != "sw2" {
+		t.Errorf("metadata = %+v", *meta)
+	}
+	if !equalPrices(got.Prices, []uint64{400000, 450000, 500000}) {
+		t.Errorf("prices = %v", got.Prices)
+	}
+}
+
+func TestRunComingSoon(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, `<html><body><div class="ListingsContainer">`+
+		`<div data-testid="search-result"><div class="PriceContainer"><p>£400,000</p></div><a href="/for-sale/details/1/">Details</a></div>`+
+		`<div data-testid="search-result"><p class="Badge-r18">Coming soon</p><div class="PriceContainer"><p>£1</p></div><a href="/for-sale/details/2/">Details</a></div>`+
+		`<div data-testid="search-result"><div class="PriceContainer"><p>£500,000</p></div><a href="/for-sale/details/3/">Details</a></div>`+
+		`</div></body></html>`)
+
+	tests := []struct {
+		name      string
+		mode      string
+		wantIDs   []string
+		wantPrice []uint64
+	}{
+		{name: "listings", mode: outputModeListings, wantIDs: []string{"1", "3", "2"}, wantPrice: []uint64{400000, 500000}},
+		{name: "prices", mode: outputModePrices, wantPrice: []uint64{400000, 500000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "prices.json")
+			args := testSearchArgs(t, "--postcode", "sw2", "--base-url", srv.SearchURL(), "--delay", "0",
+				"--outputfilename", filename, "--output-mode", tt.mode, "--with-metadata", "--quiet")
+			if err := runFetch(context.Background(), args); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadOutputFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Metadata == nil || got.Metadata.Counts.ComingSoon != 1 {
+				t.Errorf("got metadata %+v, want 1 coming soon", got.Metadata)
+			}
+			if !equalPrices(got.Prices, tt.wantPrice) {
+				t.Errorf("prices = %v, want %v", got.Prices, tt.wantPrice)
+			}
+
+			if gotIDs := ids(got.Listings); !reflect.DeepEqual(gotIDs, tt.wantIDs) {
+				t.Fatalf("listings = %v, want %v", gotIDs, tt.wantIDs)
+			}
+			if len(got.Listings) > 0 {
+				if last := got.Listings[2]; last.Status != statusComingSoon || last.Price != 0 {
+					t.Errorf("got %+v, want a coming soon listing without a price", last)
+				}
+			}
+		})
+	}
+}
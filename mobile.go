@@ -0,0 +1,77 @@
+package zoopla
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+// mobileParser handles the older server-rendered layout still served to some
+// mobile clients, with "listing-results" list items.
+type mobileParser struct{}
+
+func (mobileParser) name() string {
+	return "mobile"
+}
+
+func (mobileParser) canParse(root *html.Node) bool {
+	return len(findMobileListings(root)) > 0
+}
+
+func (mobileParser) parse(root *html.Node, _ searchMode) ([]listing, []cardError) {
+	var listings []listing
+	var cardErrs []cardError
+	for i, item := range findMobileListings(root) {
+		priceNode := findFirst(item, func(n *html.Node) bool {
+			return n.Type == html.ElementNode && hasClass(n, "listing-results-price")
+		})
+		if priceNode == nil {
+			cardErrs = append(cardErrs, cardError{index: i, node: item, err: errors.New("no price in listing item")})
+			continue
+		}
+
+		price, err := parsePriceText(textContent(priceNode))
+		if err != nil {
+			cardErrs = append(cardErrs, cardError{index: i, node: item, err: err})
+			continue
+		}
+
+		id, _ := getAttr(item, "data-listing-id")
+		l := listing{ID: id, Price: price, Status: statusForSale}
+		if title := findFirst(item, func(n *html.Node) bool {
+			return n.Type == html.ElementNode && n.Data == "h2"
+		}); title != nil {
+			l.Title = strings.Join(strings.Fields(textContent(title)), " ")
+		}
+		l.Category = classifyListing(l.Title, "")
+		if href, ok := getAttr(priceNode, "href"); ok {
+			l.URL = href
+		}
+
+		marketed := findFirst(item, func(n *html.Node) bool {
+			return n.Type == html.ElementNode && hasClass(n, "listing-results-marketed")
+		})
+		if marketed != nil {
+			if agent := findFirst(marketed, func(n *html.Node) bool {
+				return n.Type == html.ElementNode && n.Data == "span"
+			}); agent != nil {
+				l.Agent = normaliseAgentName(textContent(agent))
+			}
+		}
+
+		listings = append(listings, l)
+	}
+
+	return listings, cardErrs
+}
+
+func findMobileListings(root *html.Node) []*html.Node {
+	return findAll(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.Data != "li" {
+			return false
+		}
+		_, ok := getAttr(n, "data-listing-id")
+		return ok && strings.Contains(joinedText(n), "£")
+	})
+}
@@ -0,0 +1,29 @@
+package zoopla
+
+import "testing"
+
+func TestMobileParser(t *testing.T) {
+	listings, cardErrs := mobileParser{}.parse(mustParseHTML(t, mobileLayoutPage), saleSearch)
+	if len(cardErrs) > 0 {
+		t.Fatalf("got card errors: %v", cardErrs)
+	}
+	if len(listings) != 2 {
+		t.Fatalf("got %d listings, want 2", len(listings))
+	}
+
+	l := listings[0]
+	if l.ID != "58412345" || l.Price != 450000 || l.Title != "2 bed flat for sale" || l.Agent != "Foxtons" {
+		t.Errorf("listing = %+v", l)
+	}
+	if l.URL != "/for-sale/details/58412345/" {
+		t.Errorf("URL = %q", l.URL)
+	}
+}
+
+func TestMobileParserMissingPrice(t *testing.T) {
+	page := `<ul><li data-listing-id="1"><h2>Flat</h2><p>£300,000 guide</p></li></ul>`
+	listings, cardErrs := mobileParser{}.parse(mustParseHTML(t, page), saleSearch)
+	if len(listings) != 0 || len(cardErrs) != 1 {
+		t.Errorf("got %d listings and %d errors, want 0 and 1", len(listings), len(cardErrs))
+	}
+}
@@ -0,0 +1,185 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const defaultMortgageTerm = 25
+
+// mortgageTerms describe a repayment mortgage. The deposit is a fraction of
+// the price if it's below one, or else an amount in pounds.
+type mortgageTerms struct {
+	ratePercent float64
+	deposit     float64
+	years       uint
+}
+
+func newMortgageTerms(args *cliArgs) mortgageTerms {
+	return mortgageTerms{ratePercent: *args.MortgageRate, deposit: args.Deposit, years: args.Term}
+}
+
+// depositAmount is the deposit put down on a property at price.
+func depositAmount(price uint64, deposit float64) float64 {
+	if deposit < 1 {
+		return float64(price) * deposit
+	}
+
+	return deposit
+}
+
+// monthlyRepayment is the monthly payment that repays principal over years
+// at an annual interest rate of ratePercent, compounded monthly.
+func monthlyRepayment(principal, ratePercent float64, years uint) float64 {
+	n := float64(years * 12)
+	if n == 0 {
+		return 0
+	}
+
+	r := ratePercent / 100 / 12
+	if r == 0 {
+		return principal / n
+	}
+
+	return principal * r / (1 - math.Pow(1+r, -n))
+}
+
+// repayment returns the monthly repayment for a listing, or false if
+// it has no price or the deposit covers all of it.
+func (t mortgageTerms) repayment(l *listing) (float64, bool) {
+	if l.Price == 0 {
+		return 0, false
+	}
+
+	principal := float64(l.Price) - depositAmount(l.Price, t.deposit)
+	if principal <= 0 {
+		return 0, false
+	}
+
+	return monthlyRepayment(principal, t.ratePercent, t.years), true
+}
+
+type mortgageStats struct {
+	terms   mortgageTerms
+	count   int
+	skipped int
+	mean    float64
+	median  float64
+	p75     float64
+}
+
+func calculateMortgageStats(listings []listing, terms mortgageTerms) mortgageStats {
+	s := mortgageStats{terms: terms}
+
+	var payments []float64
+	for i := range listings {
+		p, ok := terms.repayment(&listings[i])
+		if !ok {
+			s.skipped++
+			continue
+		}
+		payments = append(payments, p)
+	}
+
+	s.count = len(payments)
+	if s.count == 0 {
+		return s
+	}
+
+	sort.Float64s(payments)
+	var sum float64
+	for _, p := range payments {
+		sum += p
+	}
+	s.mean = sum / float64(len(payments))
+	s.median = floatQuantile(payments, 0.5)
+	s.p75 = floatQuantile(payments, 0.75)
+
+	return s
+}
+
+func (s mortgageStats) String() string {
+	terms := fmt.Sprintf("at %g%% over %d years", s.terms.ratePercent, s.terms.years)
+	if s.count == 0 {
+		return fmt.Sprintf("no listings to estimate repayments for %s, skipped %d", terms, s.skipped)
+	}
+
+	return fmt.Sprintf("monthly repayment %s: mean = %.0f, median = %.0f, p75 = %.0f, skipped %d listings",
+		terms, s.mean, s.median, s.p75, s.skipped)
+}
+
+func (s mortgageStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		RatePercent float64 `json:"rate_percent"`
+		Deposit     float64 `json:"deposit"`
+		TermYears   uint    `json:"term_years"`
+		Count       int     `json:"count"`
+		Skipped     int     `json:"skipped"`
+		Mean        float64 `json:"mean"`
+		Median      float64 `json:"median"`
+		P75         float64 `json:"p75"`
+	}{s.terms.ratePercent, s.terms.deposit, s.terms.years, s.count, s.skipped, s.mean, s.median, s.p75})
+}
+
+const defaultLTI = 4.5
+
+// affordability is the most a buyer can borrow, as a multiple of income, and
+// so the most they can pay with their deposit on top.
+type affordability struct {
+	income   uint64
+	lti      float64
+	deposit  float64
+	maxLoan  float64
+	maxPrice float64
+}
+
+func newAffordability(args *cliArgs) affordability {
+	a := affordability{income: *args.Income, lti: args.LTI, deposit: args.Deposit}
+	a.maxLoan = float64(a.income) * a.lti
+	if a.deposit < 1 {
+		a.maxPrice = a.maxLoan / (1 - a.deposit)
+	} else {
+		a.maxPrice = a.maxLoan + a.deposit
+	}
+
+	return a
+}
+
+// cost is what a listing costs the buyer up front: its total cost including
+// stamp duty if that's known, or else its price.
+func (a affordability) cost(l *listing) uint64 {
+	if l.TotalCost != nil {
+		return *l.TotalCost
+	}
+
+	return l.Price
+}
+
+func (a affordability) affordable(l *listing) bool {
+	return float64(a.cost(l)) <= a.maxPrice
+}
+
+// derivation explains how the maximum price was worked out.
+func (a affordability) derivation() string {
+	loan := fmt.Sprintf("income %s × LTI %g = loan %s", formatPrice(a.income), a.lti, formatMeanPrice(a.maxLoan))
+	if a.deposit < 1 {
+		return fmt.Sprintf("%s, which is %g%% of the price with a %g%% deposit, so the maximum price is %s",
+			loan, 100*(1-a.deposit), 100*a.deposit, formatMeanPrice(a.maxPrice))
+	}
+
+	return fmt.Sprintf("%s, plus deposit %s, so the maximum price is %s",
+		loan, formatMeanPrice(a.deposit), formatMeanPrice(a.maxPrice))
+}
+
+func filterAffordable(listings []listing, a affordability) []listing {
+	var affordable []listing
+	for i := range listings {
+		if a.affordable(&listings[i]) {
+			affordable = append(affordable, listings[i])
+		}
+	}
+
+	return affordable
+}
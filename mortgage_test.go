@@ -0,0 +1,205 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDepositAmount(t *testing.T) {
+	tests := []struct {
+		price   uint64
+		deposit float64
+		want    float64
+	}{
+		{400000, 0.1, 40000},
+		{400000, 0, 0},
+		{400000, 50000, 50000},
+		{400000, 1, 1},
+	}
+
+	for _, tt := range tests {
+		if got := depositAmount(tt.price, tt.deposit); !closeTo(got, tt.want) {
+			t.Errorf("depositAmount(%d, %v) = %v, want %v", tt.price, tt.deposit, got, tt.want)
+		}
+	}
+}
+
+func TestMonthlyRepayment(t *testing.T) {
+	tests := []struct {
+		principal, rate float64
+		years           uint
+		want            float64
+	}{
+		// Checked against a spreadsheet's PMT.
+		{200000, 5, 25, 1169.1800830159602},
+		{300000, 4.5, 25, 1667.4974338859863},
+		{120000, 0, 10, 1000},
+		{120000, 5, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := monthlyRepayment(tt.principal, tt.rate, tt.years); !closeTo(got, tt.want) {
+			t.Errorf("monthlyRepayment(%v, %v, %d) = %v, want %v", tt.principal, tt.rate, tt.years, got, tt.want)
+		}
+	}
+}
+
+func TestMortgageTermsRepayment(t *testing.T) {
+	tests := []struct {
+		name    string
+		deposit float64
+		price   uint64
+		want    float64
+		ok      bool
+	}{
+		{"fractional deposit", 0.1, 200000, 1052.2620747143642, true},
+		{"pounds deposit", 20000, 200000, 1052.2620747143642, true},
+		{"no price", 0.1, 0, 0, false},
+		{"deposit covers price", 200000, 200000, 0, false},
+		{"deposit over price", 250000, 200000, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			terms := mortgageTerms{ratePercent: 5, deposit: tt.deposit, years: 25}
+			got, ok := terms.repayment(&listing{Price: tt.price})
+			if ok != tt.ok || !closeTo(got, tt.want) {
+				t.Errorf("got %v, %v, want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNewMortgageTerms(t *testing.T) {
+	rate := 4.5
+	got := newMortgageTerms(&cliArgs{MortgageRate: &rate, Deposit: 0.15, Term: 30})
+	if want := (mortgageTerms{ratePercent: 4.5, deposit: 0.15, years: 30}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestCalculateMortgageStats(t *testing.T) {
+	const base = 1052.2620747143642
+	terms := mortgageTerms{ratePercent: 5, deposit: 0.1, years: 25}
+	listings := []listing{{ID: "1", Price: 600000}, {ID: "2"}, {ID: "3", Price: 200000}, {ID: "4", Price: 400000}}
+
+	s := calculateMortgageStats(listings, terms)
+	if s.count != 3 || s.skipped != 1 {
+		t.Errorf("got %d counted, %d skipped, want 3, 1", s.count, s.skipped)
+	}
+	if !closeTo(s.mean, 2*base) || !closeTo(s.median, 2*base) || !closeTo(s.p75, 2.5*base) {
+		t.Errorf("got mean %v, median %v, p75 %v", s.mean, s.median, s.p75)
+	}
+
+	want := "monthly repayment at 5% over 25 years: mean = 2105, median = 2105, p75 = 2631, skipped 1 listings"
+	if got := s.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCalculateMortgageStatsNone(t *testing.T) {
+	terms := mortgageTerms{ratePercent: 3.5, deposit: 0.1, years: 25}
+
+	s := calculateMortgageStats([]listing{{ID: "1"}}, terms)
+	if want := "no listings to estimate repayments for at 3.5% over 25 years, skipped 1"; s.String() != want {
+		t.Errorf("got %q, want %q", s.String(), want)
+	}
+}
+
+func TestMortgageStatsJSON(t *testing.T) {
+	terms := mortgageTerms{ratePercent: 0, deposit: 0.5, years: 10}
+
+	data, err := json.Marshal(calculateMortgageStats([]listing{{ID: "1", Price: 240000}}, terms))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"rate_percent":0,"deposit":0.5,"term_years":10,"count":1,"skipped":0,"mean":1000,"median":1000,"p75":1000}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestNewAffordability(t *testing.T) {
+	income := uint64(100000)
+
+	tests := []struct {
+		name    string
+		args    cliArgs
+		maxLoan float64
+		max     float64
+		want    string
+	}{
+		{
+			name:    "fractional deposit",
+			args:    cliArgs{Income: &income, LTI: defaultLTI, Deposit: 0.1},
+			maxLoan: 450000,
+			max:     500000,
+			want:    "income £100,000 × LTI 4.5 = loan £450,000, which is 90% of the price with a 10% deposit, so the maximum price is £500,000",
+		},
+		{
+			name:    "pounds deposit",
+			args:    cliArgs{Income: &income, LTI: 4, Deposit: 60000},
+			maxLoan: 400000,
+			max:     460000,
+			want:    "income £100,000 × LTI 4 = loan £400,000, plus deposit £60,000, so the maximum price is £460,000",
+		},
+		{
+			name:    "no deposit",
+			args:    cliArgs{Income: &income, LTI: 4},
+			maxLoan: 400000,
+			max:     400000,
+			want:    "income £100,000 × LTI 4 = loan £400,000, which is 100% of the price with a 0% deposit, so the maximum price is £400,000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAffordability(&tt.args)
+			if !closeTo(a.maxLoan, tt.maxLoan) || !closeTo(a.maxPrice, tt.max) {
+				t.Errorf("got loan %v, price %v, want %v, %v", a.maxLoan, a.maxPrice, tt.maxLoan, tt.max)
+			}
+			if got := a.derivation(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAffordabilityCost(t *testing.T) {
+	a := affordability{maxPrice: 500000}
+	total := uint64(520000)
+
+	tests := []struct {
+		name       string
+		l          listing
+		cost       uint64
+		affordable bool
+	}{
+		{"price", listing{Price: 480000}, 480000, true},
+		{"at the maximum", listing{Price: 500000}, 500000, true},
+		{"over", listing{Price: 500001}, 500001, false},
+		{"total cost", listing{Price: 480000, TotalCost: &total}, 520000, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.cost(&tt.l); got != tt.cost {
+				t.Errorf("got cost %d, want %d", got, tt.cost)
+			}
+			if got := a.affordable(&tt.l); got != tt.affordable {
+				t.Errorf("got affordable %v, want %v", got, tt.affordable)
+			}
+		})
+	}
+}
+
+func TestFilterAffordable(t *testing.T) {
+	a := affordability{maxPrice: 480000}
+
+	if got, want := ids(filterAffordable(reportListings(), a)), []string{"1", "2", "3", "4", "5"}; !equalStrings(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := filterAffordable(reportListings(), affordability{maxPrice: 1}); got != nil {
+		t.Errorf("got %v, want none", ids(got))
+	}
+}
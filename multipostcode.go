@@ -0,0 +1,163 @@
+package zoopla
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const postcodeIndexFilename = "index.json"
+
+type postcodeIndexEntry struct {
+	File  string      `json:"file"`
+	Count int         `json:"count"`
+	Stats *priceStats `json:"stats,omitempty"`
+}
+
+// splitPostcodes splits the comma-separated --postcode argument.
+func splitPostcodes(arg string) []string {
+	var postcodes []string
+	for _, pc := range strings.Split(arg, ",") {
+		if pc = strings.TrimSpace(pc); pc != "" {
+			postcodes = append(postcodes, pc)
+		}
+	}
+
+	return postcodes
+}
+
+// readPostcodeFile reads a postcode per line, skipping blank lines and
+// comments starting with "#".
+func readPostcodeFile(filename string) ([]string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Wrap(err, "while reading postcode file")
+	}
+
+	var postcodes []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			postcodes = append(postcodes, line)
+		}
+	}
+
+	return postcodes, nil
+}
+
+func postcodeKey(pc string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(pc), ""))
+}
+
+// duplicatePostcode returns the first postcode given more than once, which
+// would otherwise have both runs write to the same file.
+func duplicatePostcode(postcodes []string) string {
+	seen := make(map[string]bool)
+	for _, pc := range postcodes {
+		key := postcodeKey(pc)
+		if seen[key] {
+			return pc
+		}
+		seen[key] = true
+	}
+
+	return ""
+}
+
+// postcodeFilename inserts the postcode before the extension, so
+// "prices.json.gz" becomes "prices-SW1A1AA.json.gz". Filenames with a
+// {postcode} placeholder are left for expandFilename.
+func postcodeFilename(filename, pc string) string {
+	if hasPlaceholder(filename, "postcode") {
+		return filename
+	}
+
+	base := filename
+	var gz string
+	if strings.HasSuffix(strings.ToLower(base), gzipExt) {
+		gz = base[len(base)-len(gzipExt):]
+		base = base[:len(base)-len(gzipExt)]
+	}
+
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "-" + postcodeKey(pc) + ext + gz
+}
+
+// runPerPostcode runs each postcode as its own query with its own output
+// file, then writes an index of the files alongside them.
+func runPerPostcode(ctx context.Context, args *cliArgs, postcodes []string) error {
+	now := timeNow()
+	indexDir := filepath.Dir(expandFilename(args.OutputFilename, args, now))
+	index := make(map[string]postcodeIndexEntry)
+	var anomaly error
+	for _, pc := range postcodes {
+		q := *args
+		q.Postcode = pc
+		q.OutputFilename = postcodeFilename(args.OutputFilename, pc)
+		if args.StatsFile != "" {
+			q.StatsFile = postcodeFilename(args.StatsFile, pc)
+		}
+		if args.ReportMD != "" {
+			q.ReportMD = postcodeFilename(args.ReportMD, pc)
+		}
+		if args.Chart != "" {
+			q.Chart = postcodeFilename(args.Chart, pc)
+		}
+		if args.ReportHTML != "" {
+			q.ReportHTML = postcodeFilename(args.ReportHTML, pc)
+		}
+		if args.URLsFile != "" {
+			q.URLsFile = postcodeFilename(args.URLsFile, pc)
+		}
+		if args.Manifest != "" {
+			q.Manifest = postcodeFilename(args.Manifest, pc)
+		}
+
+		count, stats, err := runSearch(ctx, &q, []string{pc}, now)
+		var exitErr *exitCodeError
+		if errors.As(err, &exitErr) {
+			// Carry on with the other postcodes, which may be fine, and
+			// report the first anomaly once they're done.
+			log.Printf("postcode %s: %v", pc, err)
+			if anomaly == nil {
+				anomaly = &exitCodeError{code: exitErr.code, err: errors.Wrapf(exitErr.err, "while running postcode %s", pc)}
+			}
+		} else if err != nil {
+			return errors.Wrapf(err, "while running postcode %s", pc)
+		}
+
+		output := expandFilename(q.OutputFilename, &q, now)
+		file, err := filepath.Rel(indexDir, output)
+		if err != nil {
+			file = output
+		}
+		index[pc] = postcodeIndexEntry{
+			File:  file,
+			Count: count,
+			Stats: stats,
+		}
+	}
+
+	if err := os.MkdirAll(indexDir, 0755); err != nil {
+		return errors.Wrap(err, "while creating postcode index directory")
+	}
+	indexFilename := filepath.Join(indexDir, postcodeIndexFilename)
+	data, err := marshalJSON(index, args.Pretty)
+	if err != nil {
+		return errors.Wrap(err, "while marshalling postcode index")
+	}
+
+	if err := writeFileAtomic(indexFilename, data); err != nil {
+		return err
+	}
+	log.Print("wrote postcode index to ", indexFilename)
+
+	return anomaly
+}
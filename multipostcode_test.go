@@ -0,0 +1,130 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ryanc414/zoopla-analyzer/internal/testserver"
+)
+
+func TestSplitPostcodes(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want []string
+	}{
+		{arg: "SW2", want: []string{"SW2"}},
+		{arg: "SW2, SW9 ,,SE24", want: []string{"SW2", "SW9", "SE24"}},
+		{arg: " , ", want: nil},
+		{arg: "", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := splitPostcodes(tt.arg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitPostcodes(%q) = %q, want %q", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestReadPostcodeFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "postcodes.txt")
+	if err := ioutil.WriteFile(filename, []byte("# Lambeth\nSW2\n\n  SW9  # Stockwell\r\nSE24\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readPostcodeFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"SW2", "SW9", "SE24"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("readPostcodeFile() = %q, want %q", got, want)
+	}
+
+	if _, err := readPostcodeFile(filename + ".missing"); err == nil {
+		t.Error("read a missing postcode file")
+	}
+}
+
+func TestDuplicatePostcode(t *testing.T) {
+	tests := []struct {
+		postcodes []string
+		want      string
+	}{
+		{postcodes: []string{"SW2", "SW9"}, want: ""},
+		{postcodes: []string{"SW2 1AA", "SW9", "sw21aa"}, want: "sw21aa"},
+	}
+
+	for _, tt := range tests {
+		if got := duplicatePostcode(tt.postcodes); got != tt.want {
+			t.Errorf("duplicatePostcode(%q) = %q, want %q", tt.postcodes, got, tt.want)
+		}
+	}
+}
+
+func TestPostcodeFilename(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{filename: "prices.json", want: "prices-SW1A1AA.json"},
+		{filename: "out/prices.json.gz", want: "out/prices-SW1A1AA.json.gz"},
+		{filename: "prices", want: "prices-SW1A1AA"},
+		{filename: "{postcode}/prices.json", want: "{postcode}/prices.json"},
+	}
+
+	for _, tt := range tests {
+		if got := postcodeFilename(tt.filename, "sw1a 1aa"); got != tt.want {
+			t.Errorf("postcodeFilename(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestRunPerPostcode(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, searchPage(400000, 500000))
+
+	dir := t.TempDir()
+	args := testSearchArgs(t, "--postcode", "sw2,sw9", "--base-url", srv.SearchURL(), "--delay", "0",
+		"--outputfilename", filepath.Join(dir, "prices.json"), "--quiet")
+	if err := runFetch(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"prices-SW2.json", "prices-SW9.json"} {
+		out, err := loadOutputFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equalPrices(out.Prices, []uint64{400000, 500000}) {
+			t.Errorf("%s has prices %v", name, out.Prices)
+		}
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, postcodeIndexFilename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var index map[string]struct {
+		File  string `json:"file"`
+		Count int    `json:"count"`
+		Stats struct {
+			Median float64 `json:"median"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		t.Fatal(err)
+	}
+	for pc, file := range map[string]string{"sw2": "prices-SW2.json", "sw9": "prices-SW9.json"} {
+		if e := index[pc]; e.File != file || e.Count != 2 || e.Stats.Median != 450000 {
+			t.Errorf("index entry for %s = %+v", pc, e)
+		}
+	}
+
+	if got := requestedPages(srv); !reflect.DeepEqual(got, []int{1, 2, 1, 2}) {
+		t.Errorf("requested pages %v, want both postcodes' pages", got)
+	}
+}
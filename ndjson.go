@@ -0,0 +1,106 @@
+package zoopla
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ndjsonWriter writes one JSON line per listing (or per price) as each page
+// is parsed, so a run that dies part way still leaves usable output. Since
+// lines are written before filtering and deduplication, the summary line at
+// the end is the authoritative record of a completed run.
+type ndjsonWriter struct {
+	file     io.WriteCloser
+	gz       *gzip.Writer
+	buf      *bufio.Writer
+	enc      *json.Encoder
+	listings bool
+}
+
+type ndjsonSummary struct {
+	Summary priceStats `json:"summary"`
+}
+
+func newNDJSONWriter(args *cliArgs) (*ndjsonWriter, error) {
+	var file io.WriteCloser = os.Stdout
+	if args.OutputFilename != stdoutFilename {
+		var err error
+		file, err = os.Create(args.OutputFilename)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while creating %s", args.OutputFilename)
+		}
+	}
+
+	w := &ndjsonWriter{
+		file:     file,
+		listings: args.OutputMode == outputModeListings,
+	}
+
+	var out io.Writer = file
+	if isCompressed(args) {
+		w.gz = gzip.NewWriter(file)
+		out = w.gz
+	}
+
+	w.buf = bufio.NewWriter(out)
+	w.enc = json.NewEncoder(w.buf)
+
+	return w, nil
+}
+
+func (w *ndjsonWriter) flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return errors.Wrap(err, "while flushing NDJSON output")
+	}
+
+	if w.gz != nil {
+		return errors.Wrap(w.gz.Flush(), "while flushing NDJSON output")
+	}
+
+	return nil
+}
+
+func (w *ndjsonWriter) writePage(listings []listing) error {
+	for i := range listings {
+		var v interface{} = listings[i].Price
+		if w.listings {
+			v = &listings[i]
+		} else if listings[i].Status == statusComingSoon {
+			// A coming soon listing's price is only a placeholder.
+			continue
+		}
+
+		if err := w.enc.Encode(v); err != nil {
+			return errors.Wrap(err, "while writing NDJSON line")
+		}
+	}
+
+	return w.flush()
+}
+
+func (w *ndjsonWriter) writeSummary(stats priceStats) error {
+	if err := w.enc.Encode(ndjsonSummary{Summary: stats}); err != nil {
+		return errors.Wrap(err, "while writing NDJSON summary")
+	}
+
+	return w.flush()
+}
+
+func (w *ndjsonWriter) Close() error {
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			return errors.Wrap(err, "while closing NDJSON output")
+		}
+	}
+
+	if w.file == os.Stdout {
+		return nil
+	}
+
+	return w.file.Close()
+}
@@ -0,0 +1,175 @@
+package zoopla
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"github.com/ryanc414/zoopla-analyzer/internal/testserver"
+)
+
+// readNDJSON decodes each line of an NDJSON file.
+func readNDJSON(t *testing.T, filename string) []json.RawMessage {
+	t.Helper()
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(filename, gzipExt) {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	}
+
+	var lines []json.RawMessage
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var line json.RawMessage
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("line %d is not JSON: %v: %s", len(lines)+1, err, scanner.Bytes())
+		}
+		lines = append(lines, line)
+	}
+	// A gzip stream cut short by a crash ends without its trailer.
+	if err := scanner.Err(); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatal(err)
+	}
+
+	return lines
+}
+
+func TestNDJSONOutput(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, searchPage(400000, 450000))
+	srv.SetPage(2, searchPage(500000))
+
+	filename := filepath.Join(t.TempDir(), "prices.ndjson")
+	args := testSearchArgs(t, "--postcode", "sw2", "--base-url", srv.SearchURL(), "--delay", "0", "--outputfilename", filename, "--quiet")
+	if err := runFetch(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := readNDJSON(t, filename)
+	if len(lines) != 4 {
+		t.Fatalf("got %d lines, want 3 prices and a summary", len(lines))
+	}
+	for i, want := range []string{"400000", "450000", "500000"} {
+		if string(lines[i]) != want {
+			t.Errorf("line %d = %s, want %s", i+1, lines[i], want)
+		}
+	}
+
+	var summary struct {
+		Summary struct {
+			Count int `json:"count"`
+		} `json:"summary"`
+	}
+	if err := json.Unmarshal(lines[3], &summary); err != nil || summary.Summary.Count != 3 {
+		t.Errorf("summary line = %s", lines[3])
+	}
+}
+
+func TestNDJSONOutputSurvivesFailedRun(t *testing.T) {
+	for _, name := range []string{"prices.ndjson", "prices.ndjson.gz"} {
+		t.Run(name, func(t *testing.T) {
+			srv := testserver.New()
+			defer srv.Close()
+			srv.SetPage(1, searchPage(400000, 450000))
+			srv.SetPage(2, searchPage(500000))
+			srv.SetPage(3, searchPage(550000))
+			srv.InjectStatus(3, http.StatusNotFound, 1)
+
+			filename := filepath.Join(t.TempDir(), name)
+			args := testSearchArgs(t, "--postcode", "sw2", "--base-url", srv.SearchURL(), "--delay", "0", "--outputfilename", filename,
+				"--output-mode", outputModeListings, "--quiet")
+			if err := runFetch(context.Background(), args); err == nil {
+				t.Fatal("run succeeded, want the page error")
+			}
+
+			// The pages before the failure are all there, without a summary
+			// to say the run completed.
+			lines := readNDJSON(t, filename)
+			if len(lines) != 3 {
+				t.Fatalf("got %d lines, want the first 2 pages' 3 listings", len(lines))
+			}
+			for i, want := range []uint64{400000, 450000, 500000} {
+				var l listing
+				if err := json.Unmarshal(lines[i], &l); err != nil || l.Price != want {
+					t.Errorf("line %d = %s, want a listing priced %d", i+1, lines[i], want)
+				}
+			}
+		})
+	}
+}
+
+type failingCloser struct {
+	bytes.Buffer
+}
+
+func (*failingCloser) Close() error {
+	return errors.New("disk full")
+}
+
+func TestNDJSONWriterCloseError(t *testing.T) {
+	file := &failingCloser{}
+	w := &ndjsonWriter{file: file}
+	w.buf = bufio.NewWriter(file)
+	w.enc = json.NewEncoder(w.buf)
+
+	if err := w.writePage([]listing{{Price: 400000}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := file.String(); got != "400000\n" {
+		t.Errorf("wrote %q before closing", got)
+	}
+	if err := w.Close(); err == nil {
+		t.Error("Close() succeeded, want the file's error")
+	}
+}
+
+func TestNDJSONSkipsComingSoonPrices(t *testing.T) {
+	listings := []listing{{ID: "1", Price: 400000}, {ID: "2", Status: statusComingSoon}}
+
+	for _, mode := range []string{outputModePrices, outputModeListings} {
+		t.Run(mode, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "prices.ndjson")
+			w, err := newNDJSONWriter(&cliArgs{OutputFilename: filename, OutputMode: mode})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.writePage(listings); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// Only the listings output has a line for the coming soon
+			// listing.
+			want := 1
+			if mode == outputModeListings {
+				want = 2
+			}
+			if lines := readNDJSON(t, filename); len(lines) != want {
+				t.Errorf("got %d lines, want %d", len(lines), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+var newBuildRegexp = regexp.MustCompile(`(?i)\bnew[ -](?:homes?|builds?)\b`)
+
+// buildGroup summarises the new builds or the resales. PricePerSqft is the
+// median over the listings with a floor area, and nil if none had one.
+type buildGroup struct {
+	Count        int      `json:"count"`
+	Median       float64  `json:"median"`
+	WithArea     int      `json:"with_area"`
+	PricePerSqft *float64 `json:"price_per_sqft,omitempty"`
+}
+
+// newBuildComparison compares new builds with resales in the same search,
+// when there are enough of both.
+type newBuildComparison struct {
+	newBuild buildGroup
+	resale   buildGroup
+	// premium is how much higher the new builds' median is than the
+	// resales', as a percentage.
+	premium float64
+}
+
+func compareNewBuilds(listings []listing, minGroupSize int) *newBuildComparison {
+	var newBuilds, resales []listing
+	for i := range listings {
+		if listings[i].IsNewBuild {
+			newBuilds = append(newBuilds, listings[i])
+		} else {
+			resales = append(resales, listings[i])
+		}
+	}
+
+	if len(newBuilds) == 0 || len(resales) == 0 || len(newBuilds) < minGroupSize || len(resales) < minGroupSize {
+		return nil
+	}
+
+	c := newBuildComparison{newBuild: summariseBuildGroup(newBuilds), resale: summariseBuildGroup(resales)}
+	c.premium = percentChange(c.resale.Median, c.newBuild.Median)
+	return &c
+}
+
+func summariseBuildGroup(listings []listing) buildGroup {
+	g := buildGroup{Count: len(listings), Median: calculateMedian(listingPrices(listings))}
+
+	var perSqft []float64
+	for i := range listings {
+		if area := listings[i].FloorArea; area != nil && *area > 0 {
+			perSqft = append(perSqft, float64(listings[i].Price)/float64(*area))
+		}
+	}
+	if g.WithArea = len(perSqft); g.WithArea > 0 {
+		sort.Float64s(perSqft)
+		median := floatQuantile(perSqft, 0.5)
+		g.PricePerSqft = &median
+	}
+
+	return g
+}
+
+func (c newBuildComparison) String() string {
+	s := fmt.Sprintf("new builds' median %s is %+.0f%% on resales' %s (%d new builds, %d resales)",
+		formatMeanPrice(c.newBuild.Median), c.premium, formatMeanPrice(c.resale.Median), c.newBuild.Count, c.resale.Count)
+	if c.newBuild.PricePerSqft != nil && c.resale.PricePerSqft != nil {
+		s += fmt.Sprintf(", %s vs %s per sq ft", formatMeanPrice(*c.newBuild.PricePerSqft), formatMeanPrice(*c.resale.PricePerSqft))
+	}
+
+	return s
+}
+
+func (c newBuildComparison) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		NewBuild             buildGroup `json:"new_build"`
+		Resale               buildGroup `json:"resale"`
+		MedianPremiumPercent float64    `json:"median_premium_percent"`
+	}{c.newBuild, c.resale, c.premium})
+}
@@ -0,0 +1,136 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewBuildRegexp(t *testing.T) {
+	tests := map[string]bool{
+		"New home":                    true,
+		"2 bed flat - new build":      true,
+		"NEW-BUILDS":                  true,
+		"new homes for sale":          true,
+		"Newly refurbished":           false,
+		"brand new kitchen":           false,
+		"renew homeowner's insurance": false,
+		"":                            false,
+	}
+
+	for text, want := range tests {
+		var l listing
+		parseCardText(text, &l)
+		if l.IsNewBuild != want {
+			t.Errorf("new build in %q = %v, want %v", text, l.IsNewBuild, want)
+		}
+	}
+}
+
+func newBuildListings() []listing {
+	return []listing{
+		{Price: 600000, IsNewBuild: true, FloorArea: uint32Ptr(600)},
+		{Price: 700000, IsNewBuild: true, FloorArea: uint32Ptr(500)},
+		{Price: 800000, IsNewBuild: true},
+		{Price: 400000, FloorArea: uint32Ptr(800)},
+		{Price: 500000, FloorArea: uint32Ptr(1000)},
+	}
+}
+
+func TestCompareNewBuilds(t *testing.T) {
+	c := compareNewBuilds(newBuildListings(), 2)
+	if c == nil {
+		t.Fatal("got nil")
+	}
+
+	if nb := c.newBuild; nb.Count != 3 || nb.Median != 700000 || nb.WithArea != 2 || nb.PricePerSqft == nil || !closeTo(*nb.PricePerSqft, 1200) {
+		t.Errorf("got new builds %+v", nb)
+	}
+	if r := c.resale; r.Count != 2 || r.Median != 450000 || r.WithArea != 2 || r.PricePerSqft == nil || !closeTo(*r.PricePerSqft, 500) {
+		t.Errorf("got resales %+v", r)
+	}
+	if !closeTo(c.premium, 100*250000.0/450000) {
+		t.Errorf("got premium %v", c.premium)
+	}
+
+	want := "new builds' median £700,000 is +56% on resales' £450,000 (3 new builds, 2 resales), £1,200 vs £500 per sq ft"
+	if got := c.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCompareNewBuildsWithoutArea(t *testing.T) {
+	listings := newBuildListings()
+	for i := range listings {
+		if !listings[i].IsNewBuild {
+			listings[i].FloorArea = uint32Ptr(0)
+		}
+	}
+
+	c := compareNewBuilds(listings, 2)
+	if c == nil {
+		t.Fatal("got nil")
+	}
+	if c.resale.WithArea != 0 || c.resale.PricePerSqft != nil {
+		t.Errorf("got resales %+v, want no price per sq ft from zero areas", c.resale)
+	}
+
+	want := "new builds' median £700,000 is +56% on resales' £450,000 (3 new builds, 2 resales)"
+	if got := c.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCompareNewBuildsMissing(t *testing.T) {
+	tests := []struct {
+		name         string
+		listings     []listing
+		minGroupSize int
+	}{
+		{"resales too few", newBuildListings(), 3},
+		{"only new builds", newBuildListings()[:3], 1},
+		{"only resales", newBuildListings()[3:], 1},
+		{"none", nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c := compareNewBuilds(tt.listings, tt.minGroupSize); c != nil {
+				t.Errorf("got %v, want nil", *c)
+			}
+		})
+	}
+}
+
+func TestNewBuildComparisonJSON(t *testing.T) {
+	listings := newBuildListings()
+	listings[3].FloorArea, listings[4].FloorArea = nil, nil
+
+	data, err := json.Marshal(compareNewBuilds(listings, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`"new_build":{"count":3,"median":700000,"with_area":2,"price_per_sqft":1200}`,
+		`"resale":{"count":2,"median":450000,"with_area":0}`,
+		`"median_premium_percent":55.5`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s has no %s", data, want)
+		}
+	}
+}
+
+func TestBuildReportNewBuilds(t *testing.T) {
+	args := reportArgs()
+	args.MinGroupSize = 2
+	r := buildReport(newBuildListings(), listingPrices(newBuildListings()), args)
+
+	if !strings.HasPrefix(r.NewBuilds, "new builds' median £700,000") {
+		t.Errorf("got %q", r.NewBuilds)
+	}
+	if md := renderMarkdown(r); !strings.Contains(md, "New builds vs resales: new builds' median") {
+		t.Errorf("markdown has no new builds line:\n%s", md)
+	}
+}
@@ -0,0 +1,122 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// nextDataParser reads the listings embedded as JSON in the __NEXT_DATA__
+// script of pages rendered by the Next.js search frontend.
+type nextDataParser struct{}
+
+func (nextDataParser) name() string {
+	return "nextdata"
+}
+
+func (nextDataParser) canParse(root *html.Node) bool {
+	return findNextData(root) != nil
+}
+
+func (nextDataParser) parse(root *html.Node, _ searchMode) ([]listing, []cardError) {
+	script := findNextData(root)
+	if script == nil {
+		log.Print("no __NEXT_DATA__ script in response")
+		return nil, nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(textContent(script)), &data); err != nil {
+		log.Print("cannot decode __NEXT_DATA__: ", err)
+		return nil, nil
+	}
+
+	var listings []listing
+	var cardErrs []cardError
+	index := 0
+	walkJSON(data, func(obj map[string]interface{}) bool {
+		id, ok := obj["listingId"]
+		if !ok {
+			return false
+		}
+
+		rawPrice, ok := obj["price"]
+		if !ok {
+			return false
+		}
+
+		defer func() { index++ }()
+
+		price, err := jsonPrice(rawPrice)
+		if err != nil {
+			cardErrs = append(cardErrs, cardError{index: index, err: err})
+			return true
+		}
+
+		l := listing{
+			ID:      jsonString(id),
+			Price:   price,
+			Status:  statusForSale,
+			Address: jsonString(obj["address"]),
+			Title:   jsonString(obj["title"]),
+		}
+		l.Category = classifyListing(l.Title, jsonString(obj["category"]))
+		l.Beds = jsonRoomCount(obj["numBedrooms"])
+		l.Baths = jsonRoomCount(obj["numBathrooms"])
+		l.Receptions = jsonRoomCount(obj["numLivingRooms"])
+		l.Location = jsonCoordinates(obj["location"])
+		if branch, ok := obj["branch"].(map[string]interface{}); ok {
+			l.Agent = normaliseAgentName(jsonString(branch["name"]))
+		}
+		if uris, ok := obj["listingUris"].(map[string]interface{}); ok {
+			if detail := jsonString(uris["detail"]); detail != "" {
+				l.URL = detail
+			}
+		}
+
+		listings = append(listings, l)
+		return true
+	})
+
+	return listings, cardErrs
+}
+
+func findNextData(root *html.Node) *html.Node {
+	scripts := findScripts(root, func(n *html.Node) bool {
+		id, _ := getAttr(n, "id")
+		return id == "__NEXT_DATA__"
+	})
+	if len(scripts) == 0 || !strings.Contains(textContent(scripts[0]), "listingId") {
+		return nil
+	}
+
+	return scripts[0]
+}
+
+// walkJSON calls visit on every object in a decoded JSON document. When visit
+// returns true the object's children are not walked. Object members are
+// walked in key order, so that listings are found in the same order on
+// every run.
+func walkJSON(v interface{}, visit func(obj map[string]interface{}) bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if visit(v) {
+			return
+		}
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walkJSON(v[k], visit)
+		}
+	case []interface{}:
+		for _, child := range v {
+			walkJSON(child, visit)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package zoopla
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNextDataParser(t *testing.T) {
+	listings, cardErrs := nextDataParser{}.parse(mustParseHTML(t, nextDataLayoutPage), saleSearch)
+	if len(cardErrs) > 0 {
+		t.Fatalf("got card errors: %v", cardErrs)
+	}
+
+	// Members are walked in key order, so featuredListings come first.
+	var ids []string
+	for _, l := range listings {
+		ids = append(ids, l.ID)
+	}
+	if want := []string{"58400001", "58412345", "58412346"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("IDs = %v, want %v", ids, want)
+	}
+
+	l := listings[1]
+	if l.Price != 450000 || l.Agent != "Foxtons" || l.URL != "/for-sale/details/58412345/" {
+		t.Errorf("listing = %+v", l)
+	}
+	if derefUint32(l.Beds) != uint32(2) || derefUint32(l.Baths) != uint32(1) {
+		t.Errorf("beds = %v, baths = %v, want 2 and 1", derefUint32(l.Beds), derefUint32(l.Baths))
+	}
+}
+
+func TestNextDataParserOrderIsStable(t *testing.T) {
+	first, _ := nextDataParser{}.parse(mustParseHTML(t, nextDataLayoutPage), saleSearch)
+	for i := 0; i < 20; i++ {
+		again, _ := nextDataParser{}.parse(mustParseHTML(t, nextDataLayoutPage), saleSearch)
+		if !reflect.DeepEqual(first, again) {
+			t.Fatalf("parse %d gave listings in a different order", i+2)
+		}
+	}
+}
+
+func TestNextDataParserBadPrice(t *testing.T) {
+	page := `<script id="__NEXT_DATA__" type="application/json">{"a":{"listingId":"1","price":"POA"},"b":{"listingId":"2","price":300000}}</script>`
+	listings, cardErrs := nextDataParser{}.parse(mustParseHTML(t, page), saleSearch)
+	if len(listings) != 1 || listings[0].ID != "2" {
+		t.Errorf("listings = %+v, want only listing 2", listings)
+	}
+	if len(cardErrs) != 1 || cardErrs[0].index != 0 {
+		t.Errorf("card errors = %+v, want one for the first listing", cardErrs)
+	}
+}
+
+func TestAssignIdentityKeepsParsedID(t *testing.T) {
+	listings := []listing{
+		{ID: "58412345"},
+		{URL: "https://www.zoopla.co.uk/for-sale/details/58412346/"},
+		{ID: "1", URL: "/for-sale/details/2/"},
+	}
+	assignIdentity(listings)
+
+	for i, want := range []string{"58412345", "58412346", "1"} {
+		if listings[i].ID != want {
+			t.Errorf("listing %d ID = %q, want %q", i, listings[i].ID, want)
+		}
+	}
+}
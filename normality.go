@@ -0,0 +1,65 @@
+package zoopla
+
+import (
+	"fmt"
+	"math"
+)
+
+// normalityAlpha is the significance level below which prices are taken
+// not to be normally distributed.
+const normalityAlpha = 0.05
+
+const (
+	summaryMean   = "mean"
+	summaryMedian = "median"
+)
+
+// normalityTest is a Jarque-Bera test of whether prices could be normally
+// distributed, along with which of the mean or median better summarises
+// them as a result.
+type normalityTest struct {
+	Test        string  `json:"test"`
+	Statistic   float64 `json:"statistic"`
+	PValue      float64 `json:"p_value"`
+	Alpha       float64 `json:"alpha"`
+	Normal      bool    `json:"normal"`
+	Recommended string  `json:"recommended"`
+}
+
+// testNormality runs the Jarque-Bera test on prices. It returns nil for
+// fewer than minShapeSample prices or if they're all the same.
+func testNormality(prices []uint64, mean float64) *normalityTest {
+	if len(prices) < minShapeSample {
+		return nil
+	}
+
+	g1, g2, ok := sampleMoments(prices, mean)
+	if !ok {
+		return nil
+	}
+
+	jb := float64(len(prices)) / 6 * (g1*g1 + g2*g2/4)
+	t := &normalityTest{
+		Test:      "jarque_bera",
+		Statistic: jb,
+		// The statistic is asymptotically chi-squared with two degrees of
+		// freedom, whose survival function is exp(-x/2).
+		PValue: math.Exp(-jb / 2),
+		Alpha:  normalityAlpha,
+	}
+	t.Normal = t.PValue >= t.Alpha
+	t.Recommended = summaryMedian
+	if t.Normal {
+		t.Recommended = summaryMean
+	}
+
+	return t
+}
+
+func (t normalityTest) String() string {
+	if t.Normal {
+		return fmt.Sprintf("Jarque-Bera = %.2f, p = %.3f, consistent with a normal distribution so the mean is a fair summary", t.Statistic, t.PValue)
+	}
+
+	return fmt.Sprintf("Jarque-Bera = %.2f, p = %.3f, not normally distributed so the median is the better summary", t.Statistic, t.PValue)
+}
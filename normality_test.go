@@ -0,0 +1,72 @@
+package zoopla
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTestNormality(t *testing.T) {
+	skewed := make([]uint64, 20)
+	for i := range skewed {
+		skewed[i] = 1
+	}
+	skewed[19] = 100
+
+	tests := []struct {
+		name        string
+		prices      []uint64
+		statistic   float64
+		normal      bool
+		recommended string
+	}{
+		{"even spread", []uint64{1, 2, 3, 4, 5}, 0.35208333, true, summaryMean},
+		{"one outlier in five", []uint64{1, 1, 1, 1, 10}, 1.88802083, true, summaryMean},
+		{"one outlier in twenty", skewed, 245.66020314, false, summaryMedian},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testNormality(tt.prices, calculateMean(tt.prices))
+			if got == nil {
+				t.Fatal("got nil")
+			}
+			if got.Test != "jarque_bera" || got.Alpha != normalityAlpha {
+				t.Errorf("got %+v", *got)
+			}
+			if math.Abs(got.Statistic-tt.statistic) > 1e-6 {
+				t.Errorf("got statistic %v, want %v", got.Statistic, tt.statistic)
+			}
+			if !closeTo(got.PValue, math.Exp(-tt.statistic/2)) {
+				t.Errorf("got p-value %v, want %v", got.PValue, math.Exp(-tt.statistic/2))
+			}
+			if got.Normal != tt.normal || got.Recommended != tt.recommended {
+				t.Errorf("got normal %v, recommended %s, want %v, %s", got.Normal, got.Recommended, tt.normal, tt.recommended)
+			}
+		})
+	}
+}
+
+func TestTestNormalityUndefined(t *testing.T) {
+	for _, prices := range [][]uint64{nil, {1, 2, 3}, {7, 7, 7, 7}} {
+		if got := testNormality(prices, calculateMean(prices)); got != nil {
+			t.Errorf("testNormality(%v) = %+v, want nil", prices, *got)
+		}
+	}
+}
+
+func TestNormalityTestString(t *testing.T) {
+	tests := []struct {
+		test normalityTest
+		want string
+	}{
+		{normalityTest{Statistic: 1.888, PValue: 0.389, Normal: true}, "Jarque-Bera = 1.89, p = 0.389, consistent with a normal distribution"},
+		{normalityTest{Statistic: 245.66, PValue: 0}, "Jarque-Bera = 245.66, p = 0.000, not normally distributed so the median"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.test.String(); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package zoopla
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	notifyTimeout  = 30 * time.Second
+	notifyAttempts = 3
+)
+
+// notifyBackoff is the wait before the first retry, doubling after each.
+var notifyBackoff = time.Second
+
+func postJSON(ctx context.Context, u string, body []byte, header http.Header) error {
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set("Content-Type", "application/json")
+
+	return post(ctx, u, body, header)
+}
+
+func post(ctx context.Context, u string, body []byte, header http.Header) error {
+	return doJSON(ctx, http.MethodPost, u, body, header, nil)
+}
+
+// doJSON makes a request, decoding a JSON response into out if it's non-nil.
+// 429 and 5xx responses are retried with exponential backoff, or after the
+// Retry-After delay if the server gave one. The whole call, retries
+// included, is bounded by notifyTimeout.
+func doJSON(ctx context.Context, method, u string, body []byte, header http.Header, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+
+	backoff := notifyBackoff
+	var err error
+	for attempt := 0; attempt < notifyAttempts; attempt++ {
+		if attempt > 0 {
+			wait := backoff
+			var statusErr *statusError
+			if errors.As(err, &statusErr) && statusErr.retryAfter > 0 {
+				wait = statusErr.retryAfter
+			}
+			log.Printf("retrying %s in %v (attempt %d): %v", method, wait, attempt+1, err)
+
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return errors.Wrapf(ctx.Err(), "while waiting to retry %s", method)
+			}
+			backoff *= 2
+		}
+
+		err = tryDoJSON(ctx, method, u, body, header, out)
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+func tryDoJSON(ctx context.Context, method, u string, body []byte, header http.Header, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "while building %s request", method)
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "while making %s request", method)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return newStatusError(resp)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return errors.Wrap(json.NewDecoder(resp.Body).Decode(out), "while decoding response")
+}
@@ -0,0 +1,123 @@
+package zoopla
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// shortenNotifyBackoff makes retries immediate for the rest of the test.
+func shortenNotifyBackoff(t *testing.T) {
+	t.Helper()
+
+	old := notifyBackoff
+	notifyBackoff = time.Millisecond
+	t.Cleanup(func() { notifyBackoff = old })
+}
+
+func TestDoJSONRetries(t *testing.T) {
+	shortenNotifyBackoff(t)
+
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int32
+		wantErr      bool
+	}{
+		{"success", []int{http.StatusOK}, 1, false},
+		{"server error then success", []int{http.StatusBadGateway, http.StatusOK}, 2, false},
+		{"rate limited then success", []int{http.StatusTooManyRequests, http.StatusOK}, 2, false},
+		{"always failing", []int{http.StatusServiceUnavailable}, notifyAttempts, true},
+		{"client error", []int{http.StatusBadRequest}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(atomic.AddInt32(&attempts, 1))
+				if n > len(tt.statuses) {
+					n = len(tt.statuses)
+				}
+				w.WriteHeader(tt.statuses[n-1])
+			}))
+			defer srv.Close()
+
+			err := doJSON(context.Background(), http.MethodPost, srv.URL, nil, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("doJSON() error = %v, want error: %t", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("made %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestDoJSONRetryAfter(t *testing.T) {
+	shortenNotifyBackoff(t)
+
+	var attempts int32
+	var first time.Time
+	var waited time.Duration
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		waited = time.Since(first)
+	}))
+	defer srv.Close()
+
+	if err := doJSON(context.Background(), http.MethodPost, srv.URL, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if waited < time.Second {
+		t.Errorf("retried after %v, want the 1s Retry-After", waited)
+	}
+}
+
+func TestDoJSONCancelledWhileWaiting(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The default backoff of a second outlasts the context.
+	err := doJSON(ctx, http.MethodPost, srv.URL, nil, nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("doJSON() error = %v, want the deadline", err)
+	}
+}
+
+func TestDoJSONDecodesResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer srv.Close()
+
+	var out struct {
+		OK bool `json:"ok"`
+	}
+	header := http.Header{"Authorization": {"Bearer token"}}
+	if err := doJSON(context.Background(), http.MethodGet, srv.URL, nil, header, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.OK {
+		t.Error("response wasn't decoded")
+	}
+}
@@ -0,0 +1,119 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	defaultStaleDays  = 90
+	defaultStalestTop = 5
+)
+
+// staleListing is one of the listings that have been on the market longest.
+type staleListing struct {
+	Days    int    `json:"days"`
+	Price   uint64 `json:"price"`
+	Title   string `json:"title,omitempty"`
+	Address string `json:"address,omitempty"`
+	URL     string `json:"url,omitempty"`
+}
+
+// timeOnMarket summarises how long listings with a listed-on date have been
+// on the market. A large share of old stock is a sign of a cooling area.
+type timeOnMarket struct {
+	listings   int
+	dated      int
+	medianDays float64
+	p90Days    float64
+	// stale counts the listings on the market for more than staleDays.
+	staleDays int
+	stale     int
+	stalest   []staleListing
+}
+
+// calculateTimeOnMarket measures the days listed up to now, counted from
+// midnight like the relative dates are. Listings dated later than now count
+// as listed today.
+func calculateTimeOnMarket(listings []listing, now time.Time, staleDays, topN int) timeOnMarket {
+	s := timeOnMarket{listings: len(listings), staleDays: staleDays}
+
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	var dated []staleListing
+	for i := range listings {
+		l := &listings[i]
+		if l.ListedOn == nil {
+			continue
+		}
+
+		days := daysBetween(*l.ListedOn, today)
+		if days < 0 {
+			days = 0
+		}
+		dated = append(dated, staleListing{Days: days, Price: l.Price, Title: l.Title, Address: l.Address, URL: l.URL})
+	}
+
+	s.dated = len(dated)
+	if s.dated == 0 {
+		return s
+	}
+
+	sort.SliceStable(dated, func(i, j int) bool { return dated[i].Days > dated[j].Days })
+
+	days := make([]uint64, len(dated))
+	for i, sl := range dated {
+		// Sorted ascending for the quantiles.
+		days[len(dated)-1-i] = uint64(sl.Days)
+		if sl.Days > staleDays {
+			s.stale++
+		}
+	}
+	s.medianDays = quantile(days, 0.5)
+	s.p90Days = quantile(days, 0.9)
+
+	if topN > len(dated) {
+		topN = len(dated)
+	}
+	s.stalest = dated[:topN]
+
+	return s
+}
+
+// staleShare is the percentage of dated listings that are stale.
+func (s timeOnMarket) staleShare() float64 {
+	if s.dated == 0 {
+		return 0
+	}
+
+	return 100 * float64(s.stale) / float64(s.dated)
+}
+
+func (s timeOnMarket) String() string {
+	if s.dated == 0 {
+		return fmt.Sprintf("no listed dates for any of %d listings", s.listings)
+	}
+
+	return fmt.Sprintf("median = %.0f days, p90 = %.0f days, %.0f%% listed over %d days, dates for %d of %d listings",
+		s.medianDays, s.p90Days, s.staleShare(), s.staleDays, s.dated, s.listings)
+}
+
+func (sl staleListing) String() string {
+	return fmt.Sprintf("%d days, %s %s", sl.Days, formatPrice(sl.Price), sl.URL)
+}
+
+func (s timeOnMarket) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Listings   int            `json:"listings"`
+		Dated      int            `json:"dated"`
+		MedianDays float64        `json:"median_days"`
+		P90Days    float64        `json:"p90_days"`
+		StaleDays  int            `json:"stale_days"`
+		Stale      int            `json:"stale"`
+		StaleShare float64        `json:"stale_share"`
+		Stalest    []staleListing `json:"stalest,omitempty"`
+	}{s.listings, s.dated, s.medianDays, s.p90Days, s.staleDays, s.stale, s.staleShare(), s.stalest})
+}
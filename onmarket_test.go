@@ -0,0 +1,165 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func onMarketListings() []listing {
+	return []listing{
+		{Price: 400000, ListedOn: datePtr(2021, time.May, 10), URL: "/today/"},
+		{Price: 410000, ListedOn: datePtr(2021, time.April, 30), URL: "/ten/"},
+		{Price: 420000, ListedOn: datePtr(2021, time.March, 11), URL: "/sixty/"},
+		{Price: 430000, ListedOn: datePtr(2021, time.January, 10), Title: "2 bed flat", Address: "Acre Lane", URL: "/old/"},
+		{Price: 440000, ListedOn: datePtr(2020, time.November, 11), URL: "/oldest/"},
+		{Price: 450000, ListedOn: datePtr(2021, time.May, 20), URL: "/future/"},
+		{Price: 460000, URL: "/undated/"},
+	}
+}
+
+var onMarketNow = time.Date(2021, time.May, 10, 15, 30, 0, 0, time.UTC)
+
+func TestCalculateTimeOnMarket(t *testing.T) {
+	s := calculateTimeOnMarket(onMarketListings(), onMarketNow, 90, 3)
+
+	if s.listings != 7 || s.dated != 6 || s.stale != 2 || s.staleDays != 90 {
+		t.Errorf("got %+v", s)
+	}
+	if !closeTo(s.medianDays, 35) || !closeTo(s.p90Days, 150) {
+		t.Errorf("got median %v, p90 %v days, want 35, 150", s.medianDays, s.p90Days)
+	}
+	if !closeTo(s.staleShare(), 100.0/3) {
+		t.Errorf("got stale share %v", s.staleShare())
+	}
+
+	want := []staleListing{
+		{Days: 180, Price: 440000, URL: "/oldest/"},
+		{Days: 120, Price: 430000, Title: "2 bed flat", Address: "Acre Lane", URL: "/old/"},
+		{Days: 60, Price: 420000, URL: "/sixty/"},
+	}
+	if len(s.stalest) != len(want) {
+		t.Fatalf("got stalest %+v, want %+v", s.stalest, want)
+	}
+	for i, w := range want {
+		if s.stalest[i] != w {
+			t.Errorf("stalest %d: got %+v, want %+v", i, s.stalest[i], w)
+		}
+	}
+}
+
+func TestCalculateTimeOnMarketStalest(t *testing.T) {
+	tests := []struct {
+		name  string
+		topN  int
+		want  int
+		first string
+	}{
+		{"none", 0, 0, ""},
+		{"one", 1, 1, "/oldest/"},
+		{"more than dated", 10, 6, "/oldest/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := calculateTimeOnMarket(onMarketListings(), onMarketNow, 90, tt.topN)
+			if len(s.stalest) != tt.want || (tt.want > 0 && s.stalest[0].URL != tt.first) {
+				t.Errorf("got %+v, want %d starting with %s", s.stalest, tt.want, tt.first)
+			}
+		})
+	}
+
+	// Listings dated after now count as listed today, like those listed
+	// today, and keep their order.
+	s := calculateTimeOnMarket(onMarketListings(), onMarketNow, 90, 10)
+	if last := s.stalest[4:]; last[0].URL != "/today/" || last[0].Days != 0 || last[1].URL != "/future/" || last[1].Days != 0 {
+		t.Errorf("got %+v, want today's then the future listing on 0 days", last)
+	}
+}
+
+func TestTimeOnMarketString(t *testing.T) {
+	tests := []struct {
+		name     string
+		listings []listing
+		want     string
+	}{
+		{"dated", onMarketListings(), "median = 35 days, p90 = 150 days, 33% listed over 90 days, dates for 6 of 7 listings"},
+		{"undated", onMarketListings()[6:], "no listed dates for any of 1 listings"},
+		{"none", nil, "no listed dates for any of 0 listings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := calculateTimeOnMarket(tt.listings, onMarketNow, 90, 3)
+			if got := s.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if s.dated == 0 && s.staleShare() != 0 {
+				t.Errorf("got stale share %v without dates", s.staleShare())
+			}
+		})
+	}
+}
+
+func TestStaleListingString(t *testing.T) {
+	sl := staleListing{Days: 120, Price: 430000, URL: "https://www.zoopla.co.uk/for-sale/details/1/"}
+	if got, want := sl.String(), "120 days, £430,000 https://www.zoopla.co.uk/for-sale/details/1/"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestTimeOnMarketJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		topN int
+		want string
+	}{
+		{
+			name: "stalest",
+			topN: 1,
+			want: `{"listings":7,"dated":6,"median_days":35,"p90_days":150,"stale_days":90,"stale":2,"stale_share":33.333333333333336,` +
+				`"stalest":[{"days":180,"price":440000,"url":"/oldest/"}]}`,
+		},
+		{
+			name: "no stalest",
+			topN: 0,
+			want: `{"listings":7,"dated":6,"median_days":35,"p90_days":150,"stale_days":90,"stale":2,"stale_share":33.333333333333336}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(calculateTimeOnMarket(onMarketListings(), onMarketNow, 90, tt.topN))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownOnMarket(t *testing.T) {
+	setNow(t, onMarketNow)
+	listings := onMarketListings()
+	args := reportArgs()
+	args.Stalest = 2
+
+	got := renderMarkdown(buildReport(listings, listingPrices(listings), args))
+	for _, want := range []string{
+		"## Time on market",
+		"| Median days listed | 35 |\n| P90 days listed | 150 |\n| Listed over 90 days | 33% (2 of 6) |\n",
+		"| 180 | £440,000 |",
+		"| 120 | £430,000 |",
+		"Listed dates for 6 of 7 listings.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report is missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "| 60 | £420,000 |") {
+		t.Errorf("report lists more than --stalest listings:\n%s", got)
+	}
+}
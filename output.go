@@ -0,0 +1,268 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// stdoutFilename as the output filename writes the data to stdout. All
+// logging goes to stderr so the two streams never mix.
+const stdoutFilename = "-"
+
+const (
+	formatJSON    = "json"
+	formatCSV     = "csv"
+	formatNDJSON  = "ndjson"
+	formatXLSX    = "xlsx"
+	formatTSV     = "tsv"
+	formatYAML    = "yaml"
+	formatParquet = "parquet"
+	formatGeoJSON = "geojson"
+	formatKML     = "kml"
+	formatCBOR    = "cbor"
+	formatSQL     = "sql"
+)
+
+var outputFormats = []string{formatJSON, formatCSV, formatTSV, formatNDJSON, formatXLSX, formatYAML, formatParquet, formatGeoJSON, formatKML, formatCBOR, formatSQL}
+
+const (
+	outputModePrices   = "prices"
+	outputModeListings = "listings"
+)
+
+var outputModes = []string{outputModePrices, outputModeListings}
+
+// listingsSchemaVersion is bumped whenever a field in the listings output is
+// renamed or removed.
+const listingsSchemaVersion = 1
+
+type listingsOutput struct {
+	SchemaVersion int `json:"schema_version"`
+	// Set is whether the listings were de-duplicated.
+	Set string `json:"set,omitempty"`
+	*outputMetadata
+	Listings []listing `json:"listings"`
+}
+
+// outputFormat returns the --format given, or else guesses it from the
+// output filename's extension.
+func outputFormat(args *cliArgs) string {
+	if args.Format != "" {
+		return args.Format
+	}
+
+	filename := strings.ToLower(args.OutputFilename)
+	switch path.Ext(strings.TrimSuffix(filename, gzipExt)) {
+	case ".csv":
+		return formatCSV
+	case ".tsv", ".tab":
+		return formatTSV
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".parquet":
+		return formatParquet
+	case ".geojson":
+		return formatGeoJSON
+	case ".kml":
+		return formatKML
+	case ".cbor":
+		return formatCBOR
+	case ".sql":
+		return formatSQL
+	case ".ndjson", ".jsonl":
+		return formatNDJSON
+	case ".xlsx":
+		return formatXLSX
+	default:
+		return formatJSON
+	}
+}
+
+func marshalJSON(v interface{}, pretty bool) ([]byte, error) {
+	if pretty {
+		return json.MarshalIndent(v, "", "  ")
+	}
+
+	return json.Marshal(v)
+}
+
+// sortedByID returns a copy of listings ordered by listing ID, so that output
+// doesn't depend on the order results were paged in.
+func sortedByID(listings []listing) []listing {
+	sorted := make([]listing, len(listings))
+	copy(sorted, listings)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareIDs(sorted[i].ID, sorted[j].ID) < 0
+	})
+
+	return sorted
+}
+
+// compareIDs orders numeric IDs by value rather than lexically.
+func compareIDs(a, b string) int {
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+
+	return strings.Compare(a, b)
+}
+
+func outputPrices(listings []listing, args *cliArgs) []uint64 {
+	prices := listingPrices(listings)
+	if args.SortPrices {
+		sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+	}
+
+	return prices
+}
+
+func marshalOutput(listings []listing, args *cliArgs, meta *outputMetadata) ([]byte, error) {
+	// Workbooks and maps always have an entry per listing, whatever the
+	// output mode.
+	format := outputFormat(args)
+	switch format {
+	case formatXLSX:
+		return marshalXLSX(listings, args)
+	case formatGeoJSON:
+		return marshalGeoJSON(listings, args)
+	case formatKML:
+		return marshalKML(listings, args)
+	case formatSQL:
+		return marshalSQLDump(listings, args)
+	}
+
+	if args.OutputMode == outputModeListings {
+		switch format {
+		case formatCSV, formatTSV:
+			columns := outputColumns(args)
+			return marshalDelimited(format, delimitedHeader(args, columnNames(columns)), listingRows(listings, columns))
+		default:
+			return marshalStructured(format, listingsOutput{
+				SchemaVersion:  listingsSchemaVersion,
+				Set:            args.OutputSet,
+				outputMetadata: meta,
+				Listings:       listings,
+			}, args.Pretty)
+		}
+	}
+
+	prices := outputPrices(listings, args)
+	switch format {
+	case formatCSV, formatTSV:
+		return marshalDelimited(format, delimitedHeader(args, []string{"price"}), priceRows(prices))
+	default:
+		// CBOR output always carries a schema version, so it's always an
+		// envelope.
+		if meta != nil || format == formatCBOR {
+			return marshalStructured(format, pricesEnvelope{SchemaVersion: pricesSchemaVersion, Set: args.OutputSet, outputMetadata: meta, Prices: prices}, args.Pretty)
+		}
+		return marshalStructured(format, prices, args.Pretty)
+	}
+}
+
+func marshalStructured(format string, v interface{}, pretty bool) ([]byte, error) {
+	switch format {
+	case formatYAML:
+		return marshalYAML(v)
+	case formatCBOR:
+		return marshalCBOR(v)
+	}
+
+	return marshalJSON(v, pretty)
+}
+
+func delimitedHeader(args *cliArgs, header []string) []string {
+	if args.NoHeader {
+		return nil
+	}
+
+	return header
+}
+
+type statsOutput struct {
+	Timestamp string     `json:"timestamp"`
+	Query     runQuery   `json:"query"`
+	Stats     priceStats `json:"stats"`
+	// RawStats are calculated before de-duplication, and only given if it
+	// removed any listings.
+	RawStats   *priceStats       `json:"raw_stats,omitempty"`
+	Duplicates int               `json:"duplicates"`
+	Histogram  []histogramBucket `json:"histogram,omitempty"`
+	KDE        *kernelDensity    `json:"kde,omitempty"`
+	Beds       []groupStats      `json:"beds,omitempty"`
+	// Types leaves out property types with too few listings.
+	Types []groupStats `json:"types,omitempty"`
+	// PricePerBed is only set when some listings' bedrooms are known.
+	PricePerBed *pricePerBedStats `json:"price_per_bed,omitempty"`
+	// PricePerSqft is only set when some listings have a floor area.
+	PricePerSqft *pricePerSqftStats `json:"price_per_sq_ft,omitempty"`
+	Bands        []bandCount        `json:"bands,omitempty"`
+	Budget       *budgetCoverage    `json:"budget,omitempty"`
+	StampDuty    *stampDutyStats    `json:"stamp_duty,omitempty"`
+	Mortgage     *mortgageStats     `json:"mortgage,omitempty"`
+	// Affordability covers every listing, even with --only-affordable.
+	Affordability *budgetCoverage  `json:"affordability,omitempty"`
+	Regression    *priceRegression `json:"regression,omitempty"`
+	FlatsVsHouses *flatsVsHouses   `json:"flats_vs_houses,omitempty"`
+	// NewBuilds is left out unless there are enough new builds and
+	// resales.
+	NewBuilds *newBuildComparison `json:"new_builds,omitempty"`
+	Agents    []agentStats        `json:"agents,omitempty"`
+	Softness  *marketSoftness     `json:"softness,omitempty"`
+	OnMarket  *timeOnMarket       `json:"time_on_market,omitempty"`
+	Sold      *soldComparison     `json:"sold,omitempty"`
+	Yield     *yieldEstimate      `json:"yield,omitempty"`
+}
+
+// writeStatsFile writes out with the timestamp and query filled in.
+func writeStatsFile(filename string, args *cliArgs, out statsOutput) error {
+	out.Timestamp = timeNow().UTC().Format(time.RFC3339)
+	out.Query = newRunQuery(args)
+
+	data, err := marshalJSON(out, args.Pretty)
+	if err != nil {
+		return errors.Wrap(err, "while marshalling stats")
+	}
+
+	return writeFileAtomic(filename, data)
+}
+
+// writePrices writes the run's output. meta is only included by the JSON
+// formats, and only if it's non-nil.
+func writePrices(listings []listing, args *cliArgs, meta *outputMetadata) error {
+	if args.SortPrices {
+		listings = sortedByID(listings)
+	}
+
+	if args.Append {
+		return appendRun(args.OutputFilename, args, outputPrices(listings, args))
+	}
+
+	if outputFormat(args) == formatParquet {
+		return writeParquetFile(args.OutputFilename, listings, meta)
+	}
+
+	priceData, err := marshalOutput(listings, args, meta)
+	if err != nil {
+		return errors.Wrap(err, "while marshalling price data")
+	}
+
+	if isCompressed(args) {
+		if priceData, err = gzipBytes(priceData); err != nil {
+			return err
+		}
+	}
+
+	if args.OutputFilename == stdoutFilename {
+		_, err = os.Stdout.Write(priceData)
+		return errors.Wrap(err, "while writing price data to stdout")
+	}
+
+	return writeFileAtomic(args.OutputFilename, priceData)
+}
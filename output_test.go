@@ -0,0 +1,279 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ryanc414/zoopla-analyzer/internal/testserver"
+)
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		format   string
+		filename string
+		want     string
+	}{
+		{filename: "output.json", want: formatJSON},
+		{filename: "output", want: formatJSON},
+		{filename: "output.csv", want: formatCSV},
+		{filename: "output.csv.gz", want: formatCSV},
+		{filename: "OUTPUT.TSV", want: formatTSV},
+		{filename: "output.tab", want: formatTSV},
+		{filename: "output.jsonl", want: formatNDJSON},
+		{filename: "output.yml", want: formatYAML},
+		{filename: "output.xlsx", want: formatXLSX},
+		{filename: stdoutFilename, want: formatJSON},
+		{format: formatJSON, filename: "output.csv", want: formatJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			args := cliArgs{Format: tt.format, OutputFilename: tt.filename}
+			if got := outputFormat(&args); got != tt.want {
+				t.Errorf("outputFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWritePricesToStdout(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, searchPage(450000, 400000))
+
+	args := testSearchArgs(t, "--postcode", "sw2", "--base-url", srv.SearchURL(), "--delay", "0", "--outputfilename", stdoutFilename)
+	var err error
+	stdout, stderr := captureOutput(t, func() {
+		err = runFetch(context.Background(), args)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var prices []uint64
+	if err := json.Unmarshal([]byte(stdout), &prices); err != nil {
+		t.Fatalf("stdout is not only the price data: %v\n%s", err, stdout)
+	}
+	if want := []uint64{450000, 400000}; !equalPrices(prices, want) {
+		t.Errorf("got prices %v, want %v", prices, want)
+	}
+
+	if !strings.Contains(stderr, "wrote price data to stdout") {
+		t.Errorf("stderr is missing the output message:\n%s", stderr)
+	}
+	if !strings.Contains(stderr, "£425,000") {
+		t.Errorf("stderr is missing the summary:\n%s", stderr)
+	}
+}
+
+func TestWritePricesStableOrder(t *testing.T) {
+	listings := []listing{
+		{ID: "100", Price: 450000},
+		{ID: "9", Price: 400000},
+		{ID: "20", Price: 500000},
+	}
+	shuffled := []listing{listings[2], listings[0], listings[1]}
+
+	tests := []struct {
+		name string
+		args cliArgs
+		want string
+	}{
+		{
+			name: "prices",
+			args: cliArgs{SortPrices: true},
+			want: `[400000,450000,500000]`,
+		},
+		{
+			name: "pretty prices",
+			args: cliArgs{SortPrices: true, Pretty: true},
+			want: "[\n  400000,\n  450000,\n  500000\n]",
+		},
+		{
+			name: "listings by ID",
+			args: cliArgs{SortPrices: true, OutputMode: outputModeListings, Format: formatCSV, Columns: "id,price"},
+			want: "id,price\n9,400000\n20,500000\n100,450000\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var outputs []string
+			for _, in := range [][]listing{listings, shuffled} {
+				tt.args.OutputFilename = filepath.Join(t.TempDir(), "prices.out")
+				if err := writePrices(append([]listing(nil), in...), &tt.args, nil); err != nil {
+					t.Fatal(err)
+				}
+				data, err := ioutil.ReadFile(tt.args.OutputFilename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				outputs = append(outputs, string(data))
+			}
+
+			if outputs[0] != tt.want || outputs[1] != tt.want {
+				t.Errorf("got %q and %q, want %q", outputs[0], outputs[1], tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalOutputUnsorted(t *testing.T) {
+	listings := []listing{{ID: "2", Price: 450000}, {ID: "1", Price: 400000}}
+	got, err := marshalOutput(listings, &cliArgs{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[450000,400000]`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestCompareIDs(t *testing.T) {
+	ids := []string{"100", "9", "20", "b", "a", "10"}
+	sort.Slice(ids, func(i, j int) bool { return compareIDs(ids[i], ids[j]) < 0 })
+	if want := []string{"9", "a", "b", "10", "20", "100"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("sorted IDs = %v, want %v", ids, want)
+	}
+}
+
+// fullListing has every field of the listings output set.
+func fullListing() listing {
+	listed := date(2021, time.February, 14)
+	reduced := date(2021, time.March, 3)
+
+	return listing{
+		ID:              "58500000",
+		Price:           450000,
+		Status:          statusForSale,
+		Agent:           "Foxtons",
+		URL:             "https://www.zoopla.co.uk/for-sale/details/58500000/",
+		Title:           "2 bed flat for sale",
+		Category:        categoryResidential,
+		SearchPostcode:  "SW2",
+		PropertyType:    "flat",
+		Address:         "Acre Lane, Brixton SW2 5SG",
+		Location:        &coordinates{Latitude: 51.4613, Longitude: -0.1156},
+		Outcode:         "SW2",
+		Sector:          "SW2 5",
+		Qualifier:       qualifierOffersOver,
+		IsAuction:       false,
+		IsReduced:       true,
+		IsNewBuild:      true,
+		ListedOn:        &listed,
+		ReducedOn:       &reduced,
+		Stations:        []station{{Name: "Brixton", DistanceMiles: 0.3}},
+		Beds:            uint32Ptr(2),
+		Baths:           uint32Ptr(1),
+		Receptions:      uint32Ptr(1),
+		FloorArea:       uint32Ptr(700),
+		FloorAreaSource: "detail",
+		StampDuty:       uint64Ptr(10000),
+		TotalCost:       uint64Ptr(460000),
+		ValueScore:      float64Ptr(4.5),
+		HasFloorplan:    true,
+		Tenure:          "leasehold",
+		EPCBand:         "C",
+		Description:     "A bright two bedroom flat.",
+		PriceHistory:    []pricePoint{{Date: &listed, Price: 475000}, {Date: &reduced, Price: 450000}},
+		SharedOwnership: true,
+		SharePercent:    float64Ptr(100),
+		Flags:           []string{flagShareUnknown},
+	}
+}
+
+func TestListingsOutputGolden(t *testing.T) {
+	const golden = "testdata/output/listings.golden.json"
+
+	args := cliArgs{OutputMode: outputModeListings, OutputSet: outputSetDeduped, Pretty: true}
+	got, err := marshalOutput([]listing{fullListing(), {ID: "58500001", Price: 400000, Status: statusForSale, Category: categoryResidential}}, &args, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	if *updateGoldens {
+		if err := ioutil.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("listings output differs from %s, rerun with -update if the change is deliberate:\n%s", golden, got)
+	}
+}
+
+func TestListingsOutputSnakeCase(t *testing.T) {
+	data, err := json.Marshal(fullListing())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	for name := range fields {
+		if name != strings.ToLower(name) || strings.ContainsAny(name, " -") {
+			t.Errorf("field %q is not snake_case", name)
+		}
+	}
+}
+
+func uint64Ptr(v uint64) *uint64 {
+	return &v
+}
+
+func TestWriteStatsFile(t *testing.T) {
+	setNow(t, time.Date(2021, time.May, 10, 9, 0, 0, 0, time.UTC))
+	filename := filepath.Join(t.TempDir(), "stats.json")
+	priceMax := uint64(600000)
+	args := cliArgs{Postcode: "SW2", PriceMax: &priceMax, Radius: 1}
+
+	out := statsOutput{Stats: calculatePriceStats([]uint64{400000, 450000, 500000})}
+	if err := writeStatsFile(filename, &args, out); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Timestamp string                 `json:"timestamp"`
+		Query     map[string]interface{} `json:"query"`
+		Stats     map[string]interface{} `json:"stats"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Timestamp != "2021-05-10T09:00:00Z" {
+		t.Errorf("timestamp = %q", got.Timestamp)
+	}
+	wantQuery := map[string]interface{}{"postcode": "SW2", "price_max": float64(600000), "radius": float64(1)}
+	if !reflect.DeepEqual(got.Query, wantQuery) {
+		t.Errorf("query = %v, want %v", got.Query, wantQuery)
+	}
+	for key, want := range map[string]float64{"count": 3, "min": 400000, "max": 500000, "range": 100000, "mean": 450000, "median": 450000} {
+		if v, ok := got.Stats[key].(float64); !ok || v != want {
+			t.Errorf("stats %s = %v, want %v", key, got.Stats[key], want)
+		}
+	}
+	for _, key := range []string{"stddev", "stderr", "cv", "flags"} {
+		if _, ok := got.Stats[key]; !ok {
+			t.Errorf("stats are missing %s", key)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package zoopla
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+const (
+	// parquetRowGroupSize bounds how much the writer buffers before
+	// flushing a row group to the file.
+	parquetRowGroupSize = 8 * 1024 * 1024
+
+	parquetMetadataKey      = "zoopla_analyzer.metadata"
+	parquetSchemaVersionKey = "zoopla_analyzer.schema_version"
+)
+
+// parquetListing is the Parquet schema for a listing. Optional listing
+// fields are nullable columns rather than zero values.
+type parquetListing struct {
+	ID              *string  `parquet:"name=id, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Price           int64    `parquet:"name=price, type=INT64"`
+	Status          string   `parquet:"name=status, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Category        string   `parquet:"name=category, type=BYTE_ARRAY, convertedtype=UTF8, encoding=PLAIN_DICTIONARY"`
+	Qualifier       *string  `parquet:"name=qualifier, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	PropertyType    *string  `parquet:"name=property_type, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Beds            *int32   `parquet:"name=beds, type=INT32, repetitiontype=OPTIONAL"`
+	Baths           *int32   `parquet:"name=baths, type=INT32, repetitiontype=OPTIONAL"`
+	Receptions      *int32   `parquet:"name=receptions, type=INT32, repetitiontype=OPTIONAL"`
+	FloorArea       *int32   `parquet:"name=floor_area_sqft, type=INT32, repetitiontype=OPTIONAL"`
+	Title           *string  `parquet:"name=title, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Address         *string  `parquet:"name=address, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Outcode         *string  `parquet:"name=outcode, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Sector          *string  `parquet:"name=sector, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	SearchPostcode  *string  `parquet:"name=search_postcode, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Agent           *string  `parquet:"name=agent, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	URL             *string  `parquet:"name=url, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	IsAuction       bool     `parquet:"name=is_auction, type=BOOLEAN"`
+	IsReduced       bool     `parquet:"name=is_reduced, type=BOOLEAN"`
+	ListedOn        *int64   `parquet:"name=listed_on, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL"`
+	ReducedOn       *int64   `parquet:"name=reduced_on, type=INT64, convertedtype=TIMESTAMP_MILLIS, repetitiontype=OPTIONAL"`
+	HasFloorplan    bool     `parquet:"name=has_floorplan, type=BOOLEAN"`
+	Tenure          *string  `parquet:"name=tenure, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	EPCBand         *string  `parquet:"name=epc_band, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	SharedOwnership bool     `parquet:"name=shared_ownership, type=BOOLEAN"`
+	SharePercent    *float64 `parquet:"name=share_percent, type=DOUBLE, repetitiontype=OPTIONAL"`
+	Flags           *string  `parquet:"name=flags, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+}
+
+func newParquetListing(l *listing) parquetListing {
+	row := parquetListing{
+		ID:              optionalString(l.ID),
+		Price:           int64(l.Price),
+		Status:          l.Status,
+		Category:        l.Category,
+		Qualifier:       optionalString(l.Qualifier),
+		PropertyType:    optionalString(l.PropertyType),
+		Beds:            optionalInt32(l.Beds),
+		Baths:           optionalInt32(l.Baths),
+		Receptions:      optionalInt32(l.Receptions),
+		FloorArea:       optionalInt32(l.FloorArea),
+		Title:           optionalString(l.Title),
+		Address:         optionalString(l.Address),
+		Outcode:         optionalString(l.Outcode),
+		Sector:          optionalString(l.Sector),
+		SearchPostcode:  optionalString(l.SearchPostcode),
+		Agent:           optionalString(l.Agent),
+		URL:             optionalString(l.URL),
+		IsAuction:       l.IsAuction,
+		IsReduced:       l.IsReduced,
+		ListedOn:        optionalMillis(l.ListedOn),
+		ReducedOn:       optionalMillis(l.ReducedOn),
+		HasFloorplan:    l.HasFloorplan,
+		Tenure:          optionalString(l.Tenure),
+		EPCBand:         optionalString(l.EPCBand),
+		SharedOwnership: l.SharedOwnership,
+		SharePercent:    l.SharePercent,
+		Flags:           optionalString(strings.Join(l.Flags, ",")),
+	}
+
+	return row
+}
+
+// writeParquet writes listings to w as a Parquet file, flushing row groups as
+// they fill. The run metadata, if any, is stored as JSON in the file's
+// key/value metadata.
+func writeParquet(w io.Writer, listings []listing, meta *outputMetadata) error {
+	pw, err := writer.NewParquetWriterFromWriter(w, new(parquetListing), 1)
+	if err != nil {
+		return errors.Wrap(err, "while creating Parquet writer")
+	}
+	pw.RowGroupSize = parquetRowGroupSize
+
+	for i := range listings {
+		if err := pw.Write(newParquetListing(&listings[i])); err != nil {
+			return errors.Wrap(err, "while writing Parquet row")
+		}
+	}
+
+	pw.Footer.KeyValueMetadata = []*parquet.KeyValue{
+		parquetKeyValue(parquetSchemaVersionKey, strconv.Itoa(listingsSchemaVersion)),
+	}
+	if meta != nil {
+		data, err := marshalJSON(meta, false)
+		if err != nil {
+			return err
+		}
+		pw.Footer.KeyValueMetadata = append(pw.Footer.KeyValueMetadata, parquetKeyValue(parquetMetadataKey, string(data)))
+	}
+
+	return errors.Wrap(pw.WriteStop(), "while finishing Parquet file")
+}
+
+// writeParquetFile streams listings to filename, or stdout for "-".
+func writeParquetFile(filename string, listings []listing, meta *outputMetadata) error {
+	if filename == stdoutFilename {
+		return writeParquet(os.Stdout, listings, meta)
+	}
+
+	return writeFileAtomicFunc(filename, func(w io.Writer) error {
+		return writeParquet(w, listings, meta)
+	})
+}
+
+func parquetKeyValue(key, value string) *parquet.KeyValue {
+	kv := parquet.NewKeyValue()
+	kv.Key, kv.Value = key, &value
+	return kv
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func optionalInt32(n *uint32) *int32 {
+	if n == nil {
+		return nil
+	}
+	v := int32(*n)
+	return &v
+}
+
+func optionalMillis(t *time.Time) *int64 {
+	if t == nil {
+		return nil
+	}
+	v := t.UnixNano() / int64(time.Millisecond)
+	return &v
+}
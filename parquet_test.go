@@ -0,0 +1,122 @@
+package zoopla
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// bytesParquetFile reads a Parquet file held in memory. Each Open gets its
+// own offset, as the reader opens a handle per column.
+type bytesParquetFile struct {
+	*bytes.Reader
+	data []byte
+}
+
+func newBytesParquetFile(data []byte) *bytesParquetFile {
+	return &bytesParquetFile{Reader: bytes.NewReader(data), data: data}
+}
+
+func (f *bytesParquetFile) Open(string) (source.ParquetFile, error) {
+	return newBytesParquetFile(f.data), nil
+}
+
+func (f *bytesParquetFile) Create(string) (source.ParquetFile, error) {
+	panic("read only")
+}
+
+func (f *bytesParquetFile) Write([]byte) (int, error) {
+	panic("read only")
+}
+
+func (f *bytesParquetFile) Close() error {
+	return nil
+}
+
+// readParquet reads back every row of a file written by writeParquet, with
+// its key/value metadata.
+func readParquet(t *testing.T, data []byte) ([]parquetListing, map[string]string) {
+	t.Helper()
+
+	pr, err := reader.NewParquetReader(newBytesParquetFile(data), new(parquetListing), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.ReadStop()
+
+	rows := make([]parquetListing, pr.GetNumRows())
+	if err := pr.Read(&rows); err != nil {
+		t.Fatal(err)
+	}
+
+	kv := make(map[string]string)
+	for _, m := range pr.Footer.KeyValueMetadata {
+		kv[m.Key] = *m.Value
+	}
+
+	return rows, kv
+}
+
+func TestWriteParquet(t *testing.T) {
+	listings := []listing{fullListing(), {Price: 400000, Status: statusForSale, Category: categoryResidential}}
+	meta := &outputMetadata{GeneratedAt: "2021-04-01T00:00:00Z", Query: runQuery{Postcode: "SW2"}, ToolVersion: "dev"}
+
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, listings, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, kv := readParquet(t, buf.Bytes())
+	if len(rows) != len(listings) {
+		t.Fatalf("read %d rows, want %d", len(rows), len(listings))
+	}
+	for i := range listings {
+		if want := newParquetListing(&listings[i]); !reflect.DeepEqual(rows[i], want) {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want)
+		}
+	}
+
+	full := rows[0]
+	if full.Price != 450000 || full.Beds == nil || *full.Beds != 2 || full.ListedOn == nil || *full.ListedOn != date(2021, 2, 14).Unix()*1000 {
+		t.Errorf("full row = %+v", full)
+	}
+	// Missing optional fields are nulls rather than zero values.
+	empty := rows[1]
+	if empty.ID != nil || empty.Beds != nil || empty.Address != nil || empty.ListedOn != nil || empty.SharePercent != nil || empty.Flags != nil {
+		t.Errorf("empty row has non-null optional fields: %+v", empty)
+	}
+
+	if kv[parquetSchemaVersionKey] != strconv.Itoa(listingsSchemaVersion) {
+		t.Errorf("schema version = %q", kv[parquetSchemaVersionKey])
+	}
+	var gotMeta outputMetadata
+	if err := json.Unmarshal([]byte(kv[parquetMetadataKey]), &gotMeta); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&gotMeta, meta) {
+		t.Errorf("metadata = %+v, want %+v", gotMeta, *meta)
+	}
+}
+
+func TestWriteParquetWithoutMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, kv := readParquet(t, buf.Bytes())
+	if len(rows) != 0 {
+		t.Errorf("read %d rows, want none", len(rows))
+	}
+	if _, ok := kv[parquetMetadataKey]; ok {
+		t.Error("file has run metadata")
+	}
+	if _, ok := kv[parquetSchemaVersionKey]; !ok {
+		t.Error("file has no schema version")
+	}
+}
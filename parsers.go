@@ -0,0 +1,146 @@
+package zoopla
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+// pageParser extracts listings from one layout of the search results page.
+// Zoopla serves several layouts at once, so each parser sniffs the document
+// to say whether it recognises it.
+type pageParser interface {
+	name() string
+	canParse(root *html.Node) bool
+	parse(root *html.Node, mode searchMode) ([]listing, []cardError)
+}
+
+// cardError records a listing that could not be parsed. node is the card's
+// subtree when the parser has one.
+type cardError struct {
+	index int
+	node  *html.Node
+	err   error
+}
+
+// pageParsers is ordered by preference: the first parser able to handle a
+// page is used.
+var pageParsers = []pageParser{
+	classParser{},
+	nextDataParser{},
+	jsonLDParser{},
+	mobileParser{},
+}
+
+func lookupParser(name string) pageParser {
+	for _, p := range pageParsers {
+		if p.name() == name {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func parserNames() []string {
+	names := make([]string, len(pageParsers))
+	for i, p := range pageParsers {
+		names[i] = p.name()
+	}
+
+	return append(names, streamingParserName)
+}
+
+func selectParser(root *html.Node, forced string) pageParser {
+	if forced != "" {
+		return lookupParser(forced)
+	}
+
+	for _, p := range pageParsers {
+		if p.canParse(root) {
+			return p
+		}
+	}
+
+	return nil
+}
+
+func parseHTML(root *html.Node, forcedParser string, mode searchMode) ([]listing, []cardError) {
+	p := selectParser(root, forcedParser)
+	if p == nil {
+		log.Print("no parser recognises the page layout")
+		return nil, nil
+	}
+	log.Printf("using %s parser", p.name())
+
+	return p.parse(root, mode)
+}
+
+// classParser handles the current layout, identified by the styled component
+// names embedded in class attributes.
+type classParser struct{}
+
+func (classParser) name() string {
+	return "class"
+}
+
+func (classParser) canParse(root *html.Node) bool {
+	return findListingsContainer(root) != nil
+}
+
+func (classParser) parse(root *html.Node, mode searchMode) ([]listing, []cardError) {
+	container := findListingsContainer(root)
+	if container == nil {
+		log.Print("no listings container in response")
+		return nil, nil
+	}
+
+	return getListingsFromContainer(container, mode)
+}
+
+// parsePriceText finds the first "£123,456" amount within free text.
+func parsePriceText(text string) (uint64, error) {
+	m := historyPriceRegexp.FindString(text)
+	if m == "" {
+		return 0, errors.Errorf("no price in %q", text)
+	}
+
+	return parsePrice(m)
+}
+
+func jsonString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+func jsonPrice(v interface{}) (uint64, error) {
+	switch v := v.(type) {
+	case float64:
+		if v <= 0 {
+			return 0, errors.Errorf("invalid price %v", v)
+		}
+		return uint64(v), nil
+	case string:
+		if price, err := parsePrice(v); err == nil {
+			return price, nil
+		}
+		return parsePriceText(v)
+	default:
+		return 0, errors.Errorf("unexpected price value %v", v)
+	}
+}
+
+func findScripts(root *html.Node, match func(n *html.Node) bool) []*html.Node {
+	return findAll(root, func(n *html.Node) bool {
+		return n.Type == html.ElementNode && n.Data == "script" && match(n)
+	})
+}
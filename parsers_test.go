@@ -0,0 +1,163 @@
+package zoopla
+
+import (
+	"strings"
+	"testing"
+)
+
+// Minimal pages in each of the layouts the parsers recognise.
+const (
+	classLayoutPage = `<html><body><div class="css-1 ListingsContainer-a1">
+<div data-testid="search-result">
+  <a href="/for-sale/details/58412345/"><h2 data-testid="listing-title">2 bed flat for sale</h2></a>
+  <div class="PriceContainer-b2"><p>£450,000</p></div>
+  <address>Acre Lane, London SW2 5SG</address>
+  <p class="AgentName-c3">Foxtons - Brixton</p>
+</div>
+<div data-testid="search-result">
+  <a href="/for-sale/details/58412346/"><h2 data-testid="listing-title">3 bed terraced house for sale</h2></a>
+  <div class="PriceContainer-b2"><p>£725,000</p></div>
+  <address>Brixton Hill, London SW2 1AA</address>
+</div>
+</div></body></html>`
+
+	nextDataLayoutPage = `<html><body><div id="__next"></div>
+<script id="__NEXT_DATA__" type="application/json">{"props":{"pageProps":{
+  "regularListings":[
+    {"listingId":"58412345","price":"£450,000","title":"2 bed flat for sale","address":"Acre Lane, London SW2 5SG",
+     "numBedrooms":2,"numBathrooms":1,"branch":{"name":"Foxtons - Brixton"},"listingUris":{"detail":"/for-sale/details/58412345/"}},
+    {"listingId":58412346,"price":725000,"title":"3 bed terraced house for sale","address":"Brixton Hill, London SW2 1AA"}
+  ],
+  "featuredListings":[
+    {"listingId":"58400001","price":"£395,000","title":"1 bed flat for sale"}
+  ]
+}}}</script></body></html>`
+
+	jsonLDLayoutPage = `<html><head>
+<script type="application/ld+json">{"@graph":[
+  {"@type":"Residence","name":"2 bed flat for sale","url":"/for-sale/details/58412345/",
+   "address":{"streetAddress":"Acre Lane","addressLocality":"London","postalCode":"SW2 5SG"},
+   "geo":{"latitude":51.46,"longitude":-0.12},"offers":{"price":450000}},
+  {"@type":"Residence","name":"3 bed terraced house for sale","offers":{"price":"£725,000"}}
+]}</script></head><body></body></html>`
+
+	mobileLayoutPage = `<html><body><ul class="listing-results">
+<li data-listing-id="58412345">
+  <h2>2 bed flat for sale</h2>
+  <a class="listing-results-price" href="/for-sale/details/58412345/">£450,000</a>
+  <p class="listing-results-marketed">Marketed by <span>Foxtons - Brixton</span></p>
+</li>
+<li data-listing-id="58412346">
+  <h2>3 bed terraced house for sale</h2>
+  <a class="listing-results-price" href="/for-sale/details/58412346/">£725,000</a>
+</li>
+</ul></body></html>`
+)
+
+func TestSelectParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		page   string
+		forced string
+		want   string
+	}{
+		{name: "class layout", page: classLayoutPage, want: "class"},
+		{name: "next data layout", page: nextDataLayoutPage, want: "nextdata"},
+		{name: "JSON-LD layout", page: jsonLDLayoutPage, want: "jsonld"},
+		{name: "mobile layout", page: mobileLayoutPage, want: "mobile"},
+		{
+			name: "class preferred over embedded data",
+			page: strings.Replace(classLayoutPage, "</body>", `<script id="__NEXT_DATA__" type="application/json">{"listingId":"1","price":1}</script></body>`, 1),
+			want: "class",
+		},
+		{name: "forced parser", page: classLayoutPage, forced: "mobile", want: "mobile"},
+		{name: "unrecognised page", page: `<html><body><p>Just a moment...</p></body></html>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := selectParser(mustParseHTML(t, tt.page), tt.forced)
+			var got string
+			if p != nil {
+				got = p.name()
+			}
+			if got != tt.want {
+				t.Errorf("selectParser() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHTMLLayouts(t *testing.T) {
+	tests := []struct {
+		name   string
+		page   string
+		prices []uint64
+	}{
+		{"class", classLayoutPage, []uint64{450000, 725000}},
+		{"nextdata", nextDataLayoutPage, []uint64{395000, 450000, 725000}},
+		{"jsonld", jsonLDLayoutPage, []uint64{450000, 725000}},
+		{"mobile", mobileLayoutPage, []uint64{450000, 725000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listings, cardErrs := parseHTML(mustParseHTML(t, tt.page), "", saleSearch)
+			if len(cardErrs) > 0 {
+				t.Errorf("got card errors: %v", cardErrs)
+			}
+			if got := listingPrices(listings); !equalPrices(got, tt.prices) {
+				t.Errorf("prices = %v, want %v", got, tt.prices)
+			}
+		})
+	}
+}
+
+func TestLookupParser(t *testing.T) {
+	for _, name := range parserNames() {
+		if name == streamingParserName {
+			continue
+		}
+		if p := lookupParser(name); p == nil || p.name() != name {
+			t.Errorf("lookupParser(%q) = %v", name, p)
+		}
+	}
+	if p := lookupParser("nope"); p != nil {
+		t.Errorf("lookupParser(\"nope\") = %v, want nil", p)
+	}
+}
+
+func TestJSONPrice(t *testing.T) {
+	tests := []struct {
+		v       interface{}
+		want    uint64
+		wantErr bool
+	}{
+		{v: 450000.0, want: 450000},
+		{v: "£450,000", want: 450000},
+		{v: "Offers over £450,000", want: 450000},
+		{v: 0.0, wantErr: true},
+		{v: "POA", wantErr: true},
+		{v: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := jsonPrice(tt.v)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("jsonPrice(%v) = %d, %v, want %d (error %v)", tt.v, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func equalPrices(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
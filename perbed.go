@@ -0,0 +1,134 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// pricePerBed returns a listing's price divided by its bedrooms. Listings
+// with unknown beds, or none as for studios, have no price per bedroom.
+func pricePerBed(l *listing) (float64, bool) {
+	if l.Beds == nil || *l.Beds == 0 {
+		return 0, false
+	}
+
+	return float64(l.Price) / float64(*l.Beds), true
+}
+
+// pricePerBedValue is the price per bedroom column, which is empty when
+// there's no price per bedroom.
+func pricePerBedValue(l *listing) interface{} {
+	v, ok := pricePerBed(l)
+	if !ok {
+		return nil
+	}
+
+	return uint64(math.Round(v))
+}
+
+type pricePerBedGroup struct {
+	Key    string  `json:"key"`
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+}
+
+type pricePerBedStats struct {
+	total int
+	// studios and unknownBeds count the listings excluded from the metric.
+	studios     int
+	unknownBeds int
+	all         pricePerBedGroup
+	byType      []pricePerBedGroup
+}
+
+func calculatePricePerBedStats(listings []listing) pricePerBedStats {
+	stats := pricePerBedStats{total: len(listings)}
+
+	var values []float64
+	typeValues := make(map[string][]float64)
+	for i := range listings {
+		l := &listings[i]
+		switch {
+		case l.Beds == nil:
+			stats.unknownBeds++
+			continue
+		case *l.Beds == 0:
+			stats.studios++
+			continue
+		}
+
+		v, _ := pricePerBed(l)
+		values = append(values, v)
+		k := propertyTypeKey(l)
+		typeValues[k] = append(typeValues[k], v)
+	}
+
+	stats.all = newPricePerBedGroup("all", values)
+
+	for k, vs := range typeValues {
+		stats.byType = append(stats.byType, newPricePerBedGroup(k, vs))
+	}
+	sort.Slice(stats.byType, func(i, j int) bool {
+		return lessGroupKey(stats.byType[i].Key, stats.byType[j].Key)
+	})
+
+	return stats
+}
+
+func newPricePerBedGroup(key string, values []float64) pricePerBedGroup {
+	g := pricePerBedGroup{Key: key, Count: len(values)}
+	if len(values) == 0 {
+		return g
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	g.Mean = sum / float64(len(sorted))
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		g.Median = sorted[mid]
+	} else {
+		g.Median = (sorted[mid-1] + sorted[mid]) / 2
+	}
+
+	return g
+}
+
+func (s pricePerBedStats) excluded() int {
+	return s.studios + s.unknownBeds
+}
+
+func (s pricePerBedStats) String() string {
+	excluded := fmt.Sprintf("excluded %d of %d listings (%d studios, %d with unknown beds)",
+		s.excluded(), s.total, s.studios, s.unknownBeds)
+	if s.all.Count == 0 {
+		return "no listings with bedrooms, " + excluded
+	}
+
+	return fmt.Sprintf("mean = %.0f, median = %.0f, %s", s.all.Mean, s.all.Median, excluded)
+}
+
+func (g pricePerBedGroup) String() string {
+	return fmt.Sprintf("%s: count = %d, mean = %.0f, median = %.0f", g.Key, g.Count, g.Mean, g.Median)
+}
+
+func (s pricePerBedStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Count       int                `json:"count"`
+		Mean        float64            `json:"mean"`
+		Median      float64            `json:"median"`
+		Studios     int                `json:"excluded_studios"`
+		UnknownBeds int                `json:"excluded_unknown_beds"`
+		ByType      []pricePerBedGroup `json:"by_type"`
+	}{s.all.Count, s.all.Mean, s.all.Median, s.studios, s.unknownBeds, s.byType})
+}
@@ -0,0 +1,123 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPricePerBed(t *testing.T) {
+	tests := []struct {
+		name   string
+		l      listing
+		want   float64
+		wantOK bool
+		column interface{}
+	}{
+		{name: "two beds", l: listing{Price: 450000, Beds: uint32Ptr(2)}, want: 225000, wantOK: true, column: uint64(225000)},
+		{name: "rounded column", l: listing{Price: 100000, Beds: uint32Ptr(3)}, want: 100000.0 / 3, wantOK: true, column: uint64(33333)},
+		{name: "studio", l: listing{Price: 250000, Beds: uint32Ptr(0)}},
+		{name: "unknown beds", l: listing{Price: 250000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pricePerBed(&tt.l)
+			if ok != tt.wantOK || !closeTo(got, tt.want) {
+				t.Errorf("got %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+			if got := pricePerBedValue(&tt.l); got != tt.column {
+				t.Errorf("got column %#v, want %#v", got, tt.column)
+			}
+		})
+	}
+}
+
+func TestCalculatePricePerBedStats(t *testing.T) {
+	listings := []listing{
+		{Price: 400000, Beds: uint32Ptr(2), PropertyType: "flat"},
+		{Price: 300000, Beds: uint32Ptr(1), PropertyType: "flat"},
+		{Price: 900000, Beds: uint32Ptr(3), PropertyType: "terraced"},
+		{Price: 600000, Beds: uint32Ptr(3)},
+		{Price: 250000, Beds: uint32Ptr(0), PropertyType: "flat"},
+		{Price: 500000, PropertyType: "flat"},
+	}
+
+	got := calculatePricePerBedStats(listings)
+	want := pricePerBedStats{
+		total:       6,
+		studios:     1,
+		unknownBeds: 1,
+		all:         pricePerBedGroup{Key: "all", Count: 4, Mean: 250000, Median: 250000},
+		byType: []pricePerBedGroup{
+			{Key: "flat", Count: 2, Mean: 250000, Median: 250000},
+			{Key: "terraced", Count: 1, Mean: 300000, Median: 300000},
+			{Key: unknownGroup, Count: 1, Mean: 200000, Median: 200000},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got.excluded() != 2 {
+		t.Errorf("got %d excluded, want 2", got.excluded())
+	}
+}
+
+func TestNewPricePerBedGroup(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   pricePerBedGroup
+	}{
+		{name: "empty", want: pricePerBedGroup{Key: "all"}},
+		{name: "odd", values: []float64{300, 100, 200}, want: pricePerBedGroup{Key: "all", Count: 3, Mean: 200, Median: 200}},
+		{name: "even", values: []float64{400, 100, 200, 1000}, want: pricePerBedGroup{Key: "all", Count: 4, Mean: 425, Median: 300}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newPricePerBedGroup("all", tt.values); got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPricePerBedStatsOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		listings []listing
+		str      string
+		json     string
+	}{
+		{
+			name:     "with bedrooms",
+			listings: []listing{{Price: 400000, Beds: uint32Ptr(2), PropertyType: "flat"}, {Price: 200000, Beds: uint32Ptr(0)}},
+			str:      "mean = 200000, median = 200000, excluded 1 of 2 listings (1 studios, 0 with unknown beds)",
+			json:     `{"count":1,"mean":200000,"median":200000,"excluded_studios":1,"excluded_unknown_beds":0,"by_type":[{"key":"flat","count":1,"mean":200000,"median":200000}]}`,
+		},
+		{
+			name:     "without bedrooms",
+			listings: []listing{{Price: 200000}},
+			str:      "no listings with bedrooms, excluded 1 of 1 listings (0 studios, 1 with unknown beds)",
+			json:     `{"count":0,"mean":0,"median":0,"excluded_studios":0,"excluded_unknown_beds":1,"by_type":null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := calculatePricePerBedStats(tt.listings)
+			if got := s.String(); got != tt.str {
+				t.Errorf("got %q, want %q", got, tt.str)
+			}
+
+			data, err := json.Marshal(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.json {
+				t.Errorf("got %s, want %s", data, tt.json)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package zoopla
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var defaultPercentiles = []float64{10, 25, 75, 90}
+
+// parsePercentiles parses the comma-separated --percentiles argument, such
+// as "5,50,95". An empty argument gives the default percentiles.
+func parsePercentiles(arg string) ([]float64, error) {
+	if strings.TrimSpace(arg) == "" {
+		return defaultPercentiles, nil
+	}
+
+	var percentiles []float64
+	for _, field := range strings.Split(arg, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || p < 0 || p > 100 {
+			return nil, errors.Errorf("invalid percentile %q, must be a number from 0 to 100", field)
+		}
+		percentiles = append(percentiles, p)
+	}
+
+	return percentiles, nil
+}
+
+// statsPercentiles returns the percentiles chosen with --percentiles, which
+// parseArgs has already validated.
+func statsPercentiles(args *cliArgs) []float64 {
+	percentiles, err := parsePercentiles(args.Percentiles)
+	if err != nil {
+		return defaultPercentiles
+	}
+
+	return percentiles
+}
+
+// percentileRank is where a price sits among some prices.
+type percentileRank struct {
+	price uint64
+	// rank counts the prices below price plus half of those equal to it,
+	// as a percentage of all prices.
+	rank    float64
+	cheaper int
+	dearer  int
+	total   int
+}
+
+// calculatePercentileRank ranks price among sorted prices, which mustn't be
+// empty.
+func calculatePercentileRank(sorted []uint64, price uint64) percentileRank {
+	cheaper := sort.Search(len(sorted), func(i int) bool { return sorted[i] >= price })
+	notDearer := sort.Search(len(sorted), func(i int) bool { return sorted[i] > price })
+	equal := notDearer - cheaper
+
+	return percentileRank{
+		price:   price,
+		rank:    100 * (float64(cheaper) + float64(equal)/2) / float64(len(sorted)),
+		cheaper: cheaper,
+		dearer:  len(sorted) - notDearer,
+		total:   len(sorted),
+	}
+}
+
+func (r percentileRank) String() string {
+	price := formatPrice(r.price)
+	switch {
+	case r.cheaper == r.total:
+		return fmt.Sprintf("%s is dearer than all %d current listings", price, r.total)
+	case r.dearer == r.total:
+		return fmt.Sprintf("%s is cheaper than all %d current listings", price, r.total)
+	}
+
+	dearerPct := 100 * float64(r.dearer) / float64(r.total)
+	return fmt.Sprintf("%s is at the %s percentile; %.0f%% of current listings are dearer", price, ordinal(int(math.Round(r.rank))), dearerPct)
+}
+
+// ordinal formats n like "1st" or "38th".
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 100 {
+	case 11, 12, 13:
+	default:
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+
+	return strconv.Itoa(n) + suffix
+}
+
+// parseRankPrices parses the comma-separated --rank argument.
+func parseRankPrices(arg string) ([]uint64, error) {
+	var prices []uint64
+	for _, field := range strings.Split(arg, ",") {
+		price, err := strconv.ParseUint(strings.TrimSpace(field), 10, 64)
+		if err != nil {
+			return nil, errors.Errorf("invalid price %q to rank, must be in whole pounds", field)
+		}
+		prices = append(prices, price)
+	}
+
+	return prices, nil
+}
+
+// logPercentileRanks logs where each price given with --rank, which
+// parseArgs has already validated, sits among prices.
+func logPercentileRanks(prices []uint64, arg string) {
+	ranked, err := parseRankPrices(arg)
+	if err != nil || len(prices) == 0 {
+		return
+	}
+
+	sorted := make([]uint64, len(prices))
+	copy(sorted, prices)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for _, p := range ranked {
+		log.Print("rank: ", calculatePercentileRank(sorted, p))
+	}
+}
@@ -0,0 +1,266 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePercentiles(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    []float64
+		wantErr bool
+	}{
+		{arg: "", want: defaultPercentiles},
+		{arg: "  ", want: defaultPercentiles},
+		{arg: "50", want: []float64{50}},
+		{arg: "5, 50 ,95", want: []float64{5, 50, 95}},
+		{arg: "0,100", want: []float64{0, 100}},
+		{arg: "2.5,97.5", want: []float64{2.5, 97.5}},
+		{arg: "101", wantErr: true},
+		{arg: "-1", wantErr: true},
+		{arg: "p90", wantErr: true},
+		{arg: "10,,90", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := parsePercentiles(tt.arg)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "must be a number from 0 to 100") {
+					t.Errorf("got %v, %v, want an error", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsPercentiles(t *testing.T) {
+	if got := statsPercentiles(&cliArgs{Percentiles: "5,95"}); !reflect.DeepEqual(got, []float64{5, 95}) {
+		t.Errorf("got %v, want [5 95]", got)
+	}
+	if got := statsPercentiles(&cliArgs{}); !reflect.DeepEqual(got, defaultPercentiles) {
+		t.Errorf("got %v, want the defaults", got)
+	}
+}
+
+func TestPercentileName(t *testing.T) {
+	tests := []struct {
+		p    float64
+		want string
+	}{
+		{10, "p10"},
+		{50, "p50"},
+		{2.5, "p2.5"},
+		{0, "p0"},
+		{100, "p100"},
+	}
+
+	for _, tt := range tests {
+		if got := (percentile{p: tt.p}).name(); got != tt.want {
+			t.Errorf("percentile %v name = %q, want %q", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestPriceStatsPercentiles(t *testing.T) {
+	// Eleven prices put each tenth percentile exactly on a price.
+	prices := []uint64{1000, 100, 900, 200, 800, 300, 700, 400, 600, 500, 0}
+
+	tests := []struct {
+		name        string
+		percentiles []float64
+		want        map[string]float64
+	}{
+		{name: "none"},
+		{name: "median", percentiles: []float64{50}, want: map[string]float64{"p50": 500}},
+		{name: "extremes", percentiles: []float64{0, 100}, want: map[string]float64{"p0": 0, "p100": 1000}},
+		{name: "interpolated", percentiles: []float64{5, 95}, want: map[string]float64{"p5": 50, "p95": 950}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := calculatePriceStatsWith(prices, statsOptions{percentiles: tt.percentiles})
+			if s.median != 500 {
+				t.Errorf("got median %v, want 500", s.median)
+			}
+
+			got := make(map[string]float64)
+			for _, p := range s.percentiles {
+				got[p.name()] = p.value
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got percentiles %v, want %v", got, tt.want)
+			}
+			for name, want := range tt.want {
+				if v, ok := got[name]; !ok || !closeTo(v, want) {
+					t.Errorf("got %s = %v, want %v", name, v, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPriceStatsPercentilesJSON(t *testing.T) {
+	s := calculatePriceStatsWith([]uint64{100, 200, 300}, statsOptions{percentiles: []float64{50, 2.5}})
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Median      float64            `json:"median"`
+		Percentiles map[string]float64 `json:"percentiles"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Median != 200 || !closeTo(got.Percentiles["p50"], 200) || !closeTo(got.Percentiles["p2.5"], 105) {
+		t.Errorf("got %s", data)
+	}
+
+	if str := s.String(); !strings.Contains(str, "median = 200") || !strings.HasSuffix(str, ", p50 = 200, p2.5 = 105") {
+		t.Errorf("got %q", str)
+	}
+}
+
+func closeTo(got, want float64) bool {
+	return math.Abs(got-want) < 1e-6
+}
+
+func TestCalculatePercentileRank(t *testing.T) {
+	sorted := []uint64{100, 200, 200, 300}
+
+	tests := []struct {
+		price   uint64
+		rank    float64
+		cheaper int
+		dearer  int
+	}{
+		{50, 0, 0, 4},
+		{100, 12.5, 0, 3},
+		{150, 25, 1, 3},
+		{200, 50, 1, 1},
+		{300, 87.5, 3, 0},
+		{400, 100, 4, 0},
+	}
+
+	for _, tt := range tests {
+		got := calculatePercentileRank(sorted, tt.price)
+		want := percentileRank{price: tt.price, rank: tt.rank, cheaper: tt.cheaper, dearer: tt.dearer, total: len(sorted)}
+		if got != want {
+			t.Errorf("calculatePercentileRank(%d) = %+v, want %+v", tt.price, got, want)
+		}
+	}
+}
+
+func TestPercentileRankString(t *testing.T) {
+	sorted := []uint64{100000, 200000, 200000, 300000}
+
+	tests := []struct {
+		price uint64
+		want  string
+	}{
+		{50000, "£50,000 is cheaper than all 4 current listings"},
+		{400000, "£400,000 is dearer than all 4 current listings"},
+		{200000, "£200,000 is at the 50th percentile; 25% of current listings are dearer"},
+		{300000, "£300,000 is at the 88th percentile; 0% of current listings are dearer"},
+	}
+
+	for _, tt := range tests {
+		if got := calculatePercentileRank(sorted, tt.price).String(); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestOrdinal(t *testing.T) {
+	tests := map[int]string{
+		0: "0th", 1: "1st", 2: "2nd", 3: "3rd", 4: "4th",
+		11: "11th", 12: "12th", 13: "13th", 21: "21st", 22: "22nd",
+		38: "38th", 101: "101st", 111: "111th", 112: "112th",
+	}
+
+	for n, want := range tests {
+		if got := ordinal(n); got != want {
+			t.Errorf("ordinal(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestParseRankPrices(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    []uint64
+		wantErr bool
+	}{
+		{arg: "450000", want: []uint64{450000}},
+		{arg: "300000, 450000", want: []uint64{300000, 450000}},
+		{arg: "", wantErr: true},
+		{arg: "450k", wantErr: true},
+		{arg: "450000.50", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseRankPrices(tt.arg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseRankPrices(%q) error = %v, want error %v", tt.arg, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseRankPrices(%q) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestLogPercentileRanks(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []uint64
+		arg    string
+		want   []string
+	}{
+		{
+			name:   "ranked",
+			prices: []uint64{300000, 100000, 200000, 200000},
+			arg:    "200000,50000",
+			want: []string{
+				"rank: £200,000 is at the 50th percentile",
+				"rank: £50,000 is cheaper than all 4 current listings",
+			},
+		},
+		{name: "no prices", arg: "200000"},
+		{name: "invalid", prices: []uint64{100000}, arg: "abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr := captureOutput(t, func() { logPercentileRanks(tt.prices, tt.arg) })
+			lines := strings.Split(strings.TrimSpace(stderr), "\n")
+			if len(tt.want) == 0 {
+				if strings.TrimSpace(stderr) != "" {
+					t.Errorf("logged %q, want nothing", stderr)
+				}
+				return
+			}
+			if len(lines) != len(tt.want) {
+				t.Fatalf("logged %q, want %d lines", stderr, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(lines[i], want) {
+					t.Errorf("line %d: got %q, want %q", i, lines[i], want)
+				}
+			}
+		})
+	}
+}
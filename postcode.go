@@ -0,0 +1,47 @@
+package zoopla
+
+import (
+	"regexp"
+	"strings"
+)
+
+const unknownGroup = "unknown"
+
+// A full postcode is an outcode like "SW2" followed by an inward code like
+// "1AB", whose digit gives the sector. Card addresses often only carry the
+// outcode.
+var (
+	fullPostcodeRegexp = regexp.MustCompile(`\b([A-Z]{1,2}\d[A-Z\d]?)\s*(\d)[A-Z]{2}\b`)
+	outcodeRegexp      = regexp.MustCompile(`\b([A-Z]{1,2}\d[A-Z\d]?)\b`)
+)
+
+// parsePostcode finds the last postcode in an address, returning its outcode
+// and, when the full postcode is present, its sector.
+func parsePostcode(address string) (outcode, sector string) {
+	address = strings.ToUpper(address)
+
+	if m := fullPostcodeRegexp.FindAllStringSubmatch(address, -1); m != nil {
+		last := m[len(m)-1]
+		return last[1], last[1] + " " + last[2]
+	}
+
+	if m := outcodeRegexp.FindAllStringSubmatch(address, -1); m != nil {
+		return m[len(m)-1][1], ""
+	}
+
+	return "", ""
+}
+
+func assignPostcodes(listings []listing) {
+	for i := range listings {
+		listings[i].Outcode, listings[i].Sector = parsePostcode(listings[i].Address)
+	}
+}
+
+func outcodeKey(l *listing) string {
+	if l.Outcode == "" {
+		return unknownGroup
+	}
+
+	return l.Outcode
+}
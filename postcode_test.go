@@ -0,0 +1,41 @@
+package zoopla
+
+import "testing"
+
+func TestParsePostcode(t *testing.T) {
+	tests := []struct {
+		address     string
+		wantOutcode string
+		wantSector  string
+	}{
+		{address: "Acre Lane, Brixton SW2", wantOutcode: "SW2"},
+		{address: "Acre Lane, London SW2 5SG", wantOutcode: "SW2", wantSector: "SW2 5"},
+		{address: "acre lane, london sw2 5sg", wantOutcode: "SW2", wantSector: "SW2 5"},
+		{address: "Brixton Hill, London SW2 1AA, near SW9", wantOutcode: "SW2", wantSector: "SW2 1"},
+		{address: "High Street, London W1A", wantOutcode: "W1A"},
+		{address: "Clapham Road, SW9 and SE11", wantOutcode: "SE11"},
+		{address: "Old Kent Road, London", wantOutcode: ""},
+		{address: "", wantOutcode: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			outcode, sector := parsePostcode(tt.address)
+			if outcode != tt.wantOutcode || sector != tt.wantSector {
+				t.Errorf("parsePostcode(%q) = %q, %q, want %q, %q", tt.address, outcode, sector, tt.wantOutcode, tt.wantSector)
+			}
+		})
+	}
+}
+
+func TestOutcodeKey(t *testing.T) {
+	listings := []listing{{Address: "Acre Lane, Brixton SW2 5SG"}, {Address: "Acre Lane"}}
+	assignPostcodes(listings)
+
+	if got := outcodeKey(&listings[0]); got != "SW2" || listings[0].Sector != "SW2 5" {
+		t.Errorf("got outcode %q, sector %q", got, listings[0].Sector)
+	}
+	if got := outcodeKey(&listings[1]); got != unknownGroup {
+		t.Errorf("got outcode %q, want %q", got, unknownGroup)
+	}
+}
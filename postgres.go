@@ -0,0 +1,74 @@
+package zoopla
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strconv"
+
+	_ "github.com/lib/pq"
+)
+
+const postgresConnectTimeoutSecs = 10
+
+var postgresDialect = &sqlDialect{
+	name: "PostgreSQL",
+	migrations: []string{
+		`CREATE TABLE IF NOT EXISTS runs (
+			id BIGSERIAL PRIMARY KEY,
+			timestamp TIMESTAMPTZ NOT NULL,
+			postcode TEXT NOT NULL,
+			params_json JSONB NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS listings (
+			run_id BIGINT NOT NULL REFERENCES runs(id),
+			listing_id TEXT,
+			price BIGINT NOT NULL,
+			qualifier TEXT,
+			beds INTEGER,
+			baths INTEGER,
+			property_type TEXT,
+			address TEXT,
+			outcode TEXT,
+			agent TEXT,
+			url TEXT
+		);
+		CREATE INDEX IF NOT EXISTS listings_run_id ON listings(run_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS listings_run_listing ON listings(run_id, listing_id)`,
+	},
+	placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+	insertRun: func(ctx context.Context, tx *sql.Tx, r *runRecord) (int64, error) {
+		var id int64
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO runs (timestamp, postcode, params_json) VALUES ($1, $2, $3) RETURNING id`,
+			r.Timestamp, r.Postcode, r.ParamsJSON,
+		).Scan(&id)
+
+		return id, err
+	},
+	// PostgreSQL allows 65535 bind parameters, but smaller batches keep each
+	// statement a manageable size.
+	batchSize: 500,
+}
+
+// openPostgresStore connects using a postgres:// DSN.
+func openPostgresStore(ctx context.Context, dsn string) (*sqlStore, error) {
+	return openSQLStore(ctx, "postgres", postgresDSN(dsn), postgresDialect)
+}
+
+// postgresDSN adds a connect timeout to dsn if it doesn't set one. DSNs
+// that don't parse as URLs are left for the driver to reject.
+func postgresDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return dsn
+	}
+
+	q := u.Query()
+	if q.Get("connect_timeout") == "" {
+		q.Set("connect_timeout", strconv.Itoa(postgresConnectTimeoutSecs))
+		u.RawQuery = q.Encode()
+	}
+
+	return u.String()
+}
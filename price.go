@@ -0,0 +1,175 @@
+package zoopla
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+var (
+	priceAmountRegexp   = regexp.MustCompile(`£\s*(?:\d{1,3}(?:,\d{3})+|\d+)`)
+	perPeriodRegexp     = regexp.MustCompile(`(?i)^\s*(?:pcm|pm|pw|p/m|/\s*m(?:on)?th|per\s+(?:calendar\s+)?(?:month|week)|a\s+month)\b`)
+	perWeekRegexp       = regexp.MustCompile(`(?i)^\s*(?:pw|per\s+week)\b`)
+	blockElements       = map[string]bool{"div": true, "p": true, "li": true, "ul": true, "section": true, "br": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true}
+	excludedPriceTokens = []string{"PriceTitleText", "Monthly", "PerMonth", "Mortgage"}
+)
+
+// isPriceExcluded reports whether n holds text near the price which is not
+// the asking price itself, such as the "Guide price" label or monthly
+// payment estimates.
+func isPriceExcluded(n *html.Node) bool {
+	for _, token := range excludedPriceTokens {
+		if hasClass(n, token) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// segmenter accumulates text into segments split at block boundaries.
+type segmenter struct {
+	segments []string
+	current  strings.Builder
+}
+
+func (s *segmenter) text(t string) {
+	s.current.WriteString(t)
+}
+
+func (s *segmenter) flush() {
+	if t := strings.TrimSpace(s.current.String()); t != "" {
+		s.segments = append(s.segments, t)
+	}
+	s.current.Reset()
+}
+
+// priceSegments returns the text of n split at block element boundaries, so
+// that text split across inline elements like "<span>£</span>435,000" is
+// joined back up.
+func priceSegments(n *html.Node) []string {
+	var seg segmenter
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			seg.text(n.Data)
+			return
+		case html.ElementNode:
+			if isPriceExcluded(n) {
+				seg.flush()
+				return
+			}
+			if blockElements[n.Data] {
+				seg.flush()
+				defer seg.flush()
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	seg.flush()
+
+	return seg.segments
+}
+
+// poundReplacer maps the other ways a pound sign arrives to "£": the
+// fullwidth form, text mis-decoded as Latin-1, and an explicit currency code.
+var poundReplacer = strings.NewReplacer("\uffe1", "£", "Â£", "£", "GBP", "£")
+
+// normalisePriceText decodes any entities left in the text, turns every kind
+// of Unicode space into a plain space and drops invisible formatting
+// characters such as zero-width spaces.
+func normalisePriceText(s string) string {
+	if strings.ContainsRune(s, '&') {
+		s = html.UnescapeString(s)
+	}
+
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.In(r, unicode.Cf):
+			return -1
+		case unicode.IsSpace(r):
+			return ' '
+		default:
+			return r
+		}
+	}, s)
+
+	return poundReplacer.Replace(s)
+}
+
+// formatPrice formats a price in pounds with thousands separators, like
+// "£435,000".
+func formatPrice(price uint64) string {
+	digits := strconv.FormatUint(price, 10)
+
+	var sb strings.Builder
+	sb.WriteString("£")
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteRune(d)
+	}
+
+	return sb.String()
+}
+
+// searchMode says whether a search is for properties for sale or to rent,
+// which decides whether a card's rent counts as its price.
+type searchMode int
+
+const (
+	saleSearch searchMode = iota
+	rentSearch
+)
+
+// findPriceInSegments returns the first currency amount in segments that is
+// not a per-month or per-week figure, which on a sale card are mortgage
+// estimates and never the price. In a rental search it's the other way
+// round: the first amount must be a rent, returned per month, and a card led
+// by any other figure, such as a sale price, isn't a rental.
+func findPriceInSegments(segments []string, mode searchMode) (uint64, error) {
+	for _, segment := range segments {
+		segment = normalisePriceText(segment)
+		for _, loc := range priceAmountRegexp.FindAllStringIndex(segment, -1) {
+			isRent := perPeriodRegexp.MatchString(segment[loc[1]:])
+			switch {
+			case mode == rentSearch && isRent:
+				return parseMonthlyRent(segment[loc[0]:])
+			case mode == rentSearch:
+				return 0, errors.Errorf("%s is not a rent", segment[loc[0]:loc[1]])
+			case !isRent:
+				return parsePrice(segment[loc[0]:loc[1]])
+			}
+		}
+	}
+
+	return 0, errors.New("cannot find price data to parse")
+}
+
+// parseMonthlyRent parses text starting with a rent like "£1,500 pcm" or
+// "£346 pw", returning it per calendar month.
+func parseMonthlyRent(text string) (uint64, error) {
+	amount := priceAmountRegexp.FindString(text)
+	price, err := parsePrice(amount)
+	if err != nil {
+		return 0, err
+	}
+
+	if perWeekRegexp.MatchString(text[len(amount):]) {
+		price = uint64(math.Round(float64(price) * 52 / 12))
+	}
+
+	return price, nil
+}
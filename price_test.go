@@ -0,0 +1,259 @@
+package zoopla
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPriceSegments(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{
+			name: "split across inline elements",
+			html: `<div data-testid="search-result"><p><span>£</span><span>435,000</span></p></div>`,
+			want: []string{"£435,000"},
+		},
+		{
+			name: "blocks kept apart",
+			html: `<div data-testid="search-result"><p>£435,000</p><p>2 beds</p></div>`,
+			want: []string{"£435,000", "2 beds"},
+		},
+		{
+			name: "qualifier and monthly estimate excluded",
+			html: `<div data-testid="search-result"><p class="PriceTitleText">Offers over £400,000</p><p>£435,000</p><p class="MonthlyPayment">£1,900 pcm</p></div>`,
+			want: []string{"£435,000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := priceSegments(mustParseCard(t, tt.html)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("priceSegments() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindPriceInSegments(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []string
+		mode     searchMode
+		want     uint64
+		wantErr  bool
+	}{
+		{name: "plain", segments: []string{"£450,000"}, want: 450000},
+		{name: "first amount", segments: []string{"2 beds", "£450,000 £475,000"}, want: 450000},
+		{name: "without separators", segments: []string{"£450000"}, want: 450000},
+		{name: "space after pound sign", segments: []string{"£ 450,000"}, want: 450000},
+		{name: "monthly figure skipped", segments: []string{"Mortgage from £1,950 pcm", "£450,000"}, want: 450000},
+		{name: "per month skipped", segments: []string{"£2,000 per month", "£450,000"}, want: 450000},
+		{name: "sale with only a monthly figure", segments: []string{"Mortgage from £1,950 pcm"}, wantErr: true},
+		{name: "rent", segments: []string{"£1,500 pcm"}, mode: rentSearch, want: 1500},
+		{name: "weekly rent", segments: []string{"£346 pw", "£1,499 pcm"}, mode: rentSearch, want: 1499},
+		{name: "rent with a deposit", segments: []string{"£1,500 pcm", "Deposit £1,730"}, mode: rentSearch, want: 1500},
+		{name: "sale price in rental search", segments: []string{"£450,000", "£1,950 pcm"}, mode: rentSearch, wantErr: true},
+		{name: "guide price in rental search", segments: []string{"Guide price £2,000"}, mode: rentSearch, wantErr: true},
+		{name: "POA", segments: []string{"POA"}, wantErr: true},
+		{name: "rent POA", segments: []string{"POA"}, mode: rentSearch, wantErr: true},
+		{name: "nothing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findPriceInSegments(tt.segments, tt.mode)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %d, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListingCardPrice(t *testing.T) {
+	tests := []struct {
+		name    string
+		card    string
+		want    uint64
+		wantErr bool
+	}{
+		{
+			name: "price container",
+			card: `<div data-testid="search-result"><p>Save £20,000</p><div class="PriceContainer"><p>£450,000</p></div></div>`,
+			want: 450000,
+		},
+		{
+			name: "no price container",
+			card: `<div data-testid="search-result"><h2>2 bed flat</h2><p><span>£</span>450,000</p><p>£1,950 pcm</p></div>`,
+			want: 450000,
+		},
+		{
+			name: "coming soon placeholder",
+			card: `<div data-testid="search-result"><p class="Badge">Coming soon</p><p>£1</p></div>`,
+			want: 0,
+		},
+		{
+			name:    "no price",
+			card:    `<div data-testid="search-result"><div class="PriceContainer"><p>POA</p></div></div>`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := parseListingCard(mustParseCard(t, tt.card), saleSearch)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %d, want an error", l.Price)
+				}
+				return
+			}
+			if err != nil || l.Price != tt.want {
+				t.Errorf("got %d, %v, want %d", l.Price, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalisePriceText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "named entity", in: "&pound;435,000", want: "£435,000"},
+		{name: "numeric entity", in: "&#163;435,000", want: "£435,000"},
+		{name: "non-breaking space", in: "£\u00a0435,000", want: "£ 435,000"},
+		{name: "narrow no-break space", in: "£435,000\u202fpcm", want: "£435,000 pcm"},
+		{name: "zero-width space", in: "£435\u200b,000", want: "£435,000"},
+		{name: "byte order mark", in: "\ufeff£435,000", want: "£435,000"},
+		{name: "fullwidth pound", in: "\uffe1435,000", want: "£435,000"},
+		{name: "mis-decoded pound", in: "Â£435,000", want: "£435,000"},
+		{name: "currency code", in: "GBP435,000", want: "£435,000"},
+		{name: "plain", in: "£435,000", want: "£435,000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalisePriceText(tt.in); got != tt.want {
+				t.Errorf("normalisePriceText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    uint64
+		wantErr bool
+	}{
+		{raw: "£435,000", want: 435000},
+		{raw: " £435,000 ", want: 435000},
+		{raw: "&pound;435,000", want: 435000},
+		{raw: "£\u00a0435,000", want: 435000},
+		{raw: "£435\u200b,000", want: 435000},
+		{raw: "435000", want: 435000},
+		{raw: "POA", wantErr: true},
+		{raw: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := parsePrice(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %d, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPrice(t *testing.T) {
+	tests := []struct {
+		price uint64
+		want  string
+	}{
+		{price: 0, want: "£0"},
+		{price: 950, want: "£950"},
+		{price: 1000, want: "£1,000"},
+		{price: 435000, want: "£435,000"},
+		{price: 1250000, want: "£1,250,000"},
+	}
+
+	for _, tt := range tests {
+		if got := formatPrice(tt.price); got != tt.want {
+			t.Errorf("formatPrice(%d) = %q, want %q", tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestParseListingCardPriceEntities(t *testing.T) {
+	// The entities are escaped twice, as templates that left them in the
+	// text do, so that they survive the HTML parser's own decoding.
+	card := `<div data-testid="search-result"><div class="PriceContainer"><p>&amp;pound;&nbsp;435,000</p></div></div>`
+	l, err := parseListingCard(mustParseCard(t, card), saleSearch)
+	if err != nil || l.Price != 435000 {
+		t.Errorf("got %d, %v, want 435000", l.Price, err)
+	}
+}
+
+func TestParseCardPriceBySearchMode(t *testing.T) {
+	const (
+		saleCard   = `<div data-testid="search-result"><div class="PriceContainer"><p>£450,000</p><p>£1,950 pcm</p></div></div>`
+		rentCard   = `<div data-testid="search-result"><div class="PriceContainer"><p>£1,500 pcm</p><p>£346 pw</p></div></div>`
+		weeklyCard = `<div data-testid="search-result"><div class="PriceContainer"><p>£346 pw</p></div></div>`
+	)
+
+	tests := []struct {
+		name    string
+		card    string
+		mode    searchMode
+		want    uint64
+		wantErr bool
+	}{
+		{name: "sale card in sale search", card: saleCard, mode: saleSearch, want: 450000},
+		{name: "sale card in rental search", card: saleCard, mode: rentSearch, wantErr: true},
+		{name: "rental card in sale search", card: rentCard, mode: saleSearch, wantErr: true},
+		{name: "rental card in rental search", card: rentCard, mode: rentSearch, want: 1500},
+		{name: "weekly rent in rental search", card: weeklyCard, mode: rentSearch, want: 1499},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := `<html><body><div class="ListingsContainer">` + tt.card + `</div></body></html>`
+			l, err := parseListingCard(mustParseCard(t, tt.card), tt.mode)
+			streamed, streamedErrs, streamErr := parseStreaming(strings.NewReader(page), tt.mode)
+			if streamErr != nil {
+				t.Fatal(streamErr)
+			}
+
+			if tt.wantErr {
+				if err == nil || len(streamedErrs) != 1 {
+					t.Errorf("got %d and %d streamed listings, want errors", l.Price, len(streamed))
+				}
+				return
+			}
+			if err != nil || l.Price != tt.want {
+				t.Errorf("got %d, %v, want %d", l.Price, err, tt.want)
+			}
+			if len(streamed) != 1 || streamed[0].Price != tt.want {
+				t.Errorf("streaming parser got %+v, %v, want %d", streamed, streamedErrs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package zoopla
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	listingIDRegexp = regexp.MustCompile(`/details/(\d+)`)
+
+	// propertyTypeRules are checked in order against the lower-cased card
+	// title, so more specific phrases like "end terrace" come first.
+	propertyTypeRules = []struct {
+		propertyType string
+		pattern      *regexp.Regexp
+	}{
+		{"studio", regexp.MustCompile(`\bstudio\b`)},
+		{"maisonette", regexp.MustCompile(`\bmaisonette\b`)},
+		{"flat", regexp.MustCompile(`\b(?:flat|apartment|penthouse|duplex)\b`)},
+		{"end_terrace", regexp.MustCompile(`\bend[ -]of[ -]terrace\b|\bend[ -]terrace\b`)},
+		{"semi_detached", regexp.MustCompile(`\bsemi[ -]detached\b`)},
+		{"detached", regexp.MustCompile(`\bdetached\b`)},
+		{"terraced", regexp.MustCompile(`\bterrace[d]?\b`)},
+		{"town_house", regexp.MustCompile(`\btown ?house\b`)},
+		{"bungalow", regexp.MustCompile(`\bbungalow\b`)},
+		{"cottage", regexp.MustCompile(`\bcottage\b`)},
+		{"house", regexp.MustCompile(`\bhouse\b`)},
+	}
+)
+
+// parseListingID extracts the numeric listing ID from a detail page link
+// such as "/for-sale/details/58412345/".
+func parseListingID(href string) string {
+	if m := listingIDRegexp.FindStringSubmatch(href); m != nil {
+		return m[1]
+	}
+
+	return ""
+}
+
+func parsePropertyType(title string) string {
+	title = strings.ToLower(title)
+	for _, rule := range propertyTypeRules {
+		if rule.pattern.MatchString(title) {
+			return rule.propertyType
+		}
+	}
+
+	return ""
+}
+
+// assignIdentity sets the fields derived from a listing's link and title.
+// An ID the parser already found in the page's data is kept.
+func assignIdentity(listings []listing) {
+	for i := range listings {
+		if listings[i].ID == "" {
+			listings[i].ID = parseListingID(listings[i].URL)
+		}
+		listings[i].PropertyType = parsePropertyType(listings[i].Title)
+	}
+}
@@ -0,0 +1,79 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	propertyClassFlat  = "flat"
+	propertyClassHouse = "house"
+)
+
+// propertyClasses sorts property types into flats and houses for the
+// headline comparison between them.
+var propertyClasses = map[string]string{
+	"studio":        propertyClassFlat,
+	"maisonette":    propertyClassFlat,
+	"flat":          propertyClassFlat,
+	"end_terrace":   propertyClassHouse,
+	"semi_detached": propertyClassHouse,
+	"detached":      propertyClassHouse,
+	"terraced":      propertyClassHouse,
+	"town_house":    propertyClassHouse,
+	"bungalow":      propertyClassHouse,
+	"cottage":       propertyClassHouse,
+	"house":         propertyClassHouse,
+}
+
+func propertyClassKey(l *listing) string {
+	if class, ok := propertyClasses[l.PropertyType]; ok {
+		return class
+	}
+
+	return unknownGroup
+}
+
+// flatsVsHouses compares the prices of flats and houses, when there are
+// enough of both.
+type flatsVsHouses struct {
+	flats  groupStats
+	houses groupStats
+	// premium is how much higher the houses' median is than the flats', as
+	// a percentage.
+	premium float64
+}
+
+func compareFlatsAndHouses(listings []listing, minGroupSize int) *flatsVsHouses {
+	var c flatsVsHouses
+	var haveFlats, haveHouses bool
+	for _, g := range orderedGroupStats(listings, propertyClassKey, minGroupSize) {
+		switch {
+		case g.unreliable:
+		case g.key == propertyClassFlat:
+			c.flats, haveFlats = g, true
+		case g.key == propertyClassHouse:
+			c.houses, haveHouses = g, true
+		}
+	}
+
+	if !haveFlats || !haveHouses {
+		return nil
+	}
+
+	c.premium = percentChange(c.flats.median, c.houses.median)
+	return &c
+}
+
+func (c flatsVsHouses) String() string {
+	return fmt.Sprintf("houses' median %s is %+.0f%% on flats' %s (%d houses, %d flats)",
+		formatMeanPrice(c.houses.median), c.premium, formatMeanPrice(c.flats.median), c.houses.count, c.flats.count)
+}
+
+func (c flatsVsHouses) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Flats                groupStats `json:"flats"`
+		Houses               groupStats `json:"houses"`
+		MedianPremiumPercent float64    `json:"median_premium_percent"`
+	}{c.flats, c.houses, c.premium})
+}
@@ -0,0 +1,87 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestPropertyClassKey(t *testing.T) {
+	tests := map[string]string{
+		"flat":          propertyClassFlat,
+		"studio":        propertyClassFlat,
+		"maisonette":    propertyClassFlat,
+		"terraced":      propertyClassHouse,
+		"semi_detached": propertyClassHouse,
+		"bungalow":      propertyClassHouse,
+		"":              unknownGroup,
+		"land":          unknownGroup,
+		"Flat":          unknownGroup,
+	}
+
+	for typ, want := range tests {
+		if got := propertyClassKey(&listing{PropertyType: typ}); got != want {
+			t.Errorf("propertyClassKey(%q) = %q, want %q", typ, got, want)
+		}
+	}
+}
+
+func TestCompareFlatsAndHouses(t *testing.T) {
+	c := compareFlatsAndHouses(reportListings(), 2)
+	if c == nil {
+		t.Fatal("got nil")
+	}
+
+	if c.flats.count != 6 || c.flats.median != 395000 || c.houses.count != 2 || c.houses.median != 725000 {
+		t.Errorf("got flats %+v, houses %+v", c.flats, c.houses)
+	}
+	if !closeTo(c.premium, 100*330000.0/395000) {
+		t.Errorf("got premium %v", c.premium)
+	}
+
+	want := "houses' median £725,000 is +84% on flats' £395,000 (2 houses, 6 flats)"
+	if got := c.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCompareFlatsAndHousesMissing(t *testing.T) {
+	onlyFlats := reportListings()[:6]
+	unknown := reportListings()
+	for i := range unknown {
+		unknown[i].PropertyType = ""
+	}
+
+	tests := []struct {
+		name         string
+		listings     []listing
+		minGroupSize int
+	}{
+		{"houses too few", reportListings(), 3},
+		{"only flats", onlyFlats, 1},
+		{"unknown types", unknown, 1},
+		{"none", nil, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c := compareFlatsAndHouses(tt.listings, tt.minGroupSize); c != nil {
+				t.Errorf("got %v, want nil", *c)
+			}
+		})
+	}
+}
+
+func TestFlatsVsHousesJSON(t *testing.T) {
+	c := compareFlatsAndHouses(reportListings(), 2)
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{`"flats":{"key":"flat","count":6`, `"houses":{"key":"house","count":2`, `"median_premium_percent":83.5`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s has no %s", data, want)
+		}
+	}
+}
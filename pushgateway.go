@@ -0,0 +1,119 @@
+package zoopla
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultPushJob = "zoopla"
+	pushTimeout    = 10 * time.Second
+)
+
+// pushMetrics replaces the run's group on a Prometheus Pushgateway with
+// gauges summarising prices. The group is keyed by job, postcode and bedroom
+// filter so that each search keeps its own series.
+func pushMetrics(ctx context.Context, args *cliArgs, prices []uint64) error {
+	u, err := pushURL(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pushTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, bytes.NewReader(formatMetrics(prices)))
+	if err != nil {
+		return errors.Wrap(err, "while building push request")
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "while pushing metrics")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return errors.Wrap(&statusError{code: resp.StatusCode, status: resp.Status}, "while pushing metrics")
+	}
+
+	return nil
+}
+
+func pushURL(args *cliArgs) (string, error) {
+	u, err := url.Parse(args.PushGateway)
+	if err != nil {
+		return "", errors.Wrap(err, "while parsing push gateway URL")
+	}
+
+	job := args.PushJob
+	if job == "" {
+		job = defaultPushJob
+	}
+
+	// Label values go in the path, so RawPath keeps any slashes in them
+	// escaped rather than splitting the path.
+	elems := []string{"metrics", "job", job, "postcode", args.Postcode, "beds", bedsFilterLabel(args)}
+	escaped := make([]string, len(elems))
+	for i, e := range elems {
+		escaped[i] = url.PathEscape(e)
+	}
+	u.RawPath = path.Join(append([]string{u.EscapedPath()}, escaped...)...)
+	u.Path = path.Join(append([]string{u.Path}, elems...)...)
+
+	return u.String(), nil
+}
+
+func bedsFilterLabel(args *cliArgs) string {
+	switch {
+	case args.BedsMin == nil && args.BedsMax == nil:
+		return "any"
+	case args.BedsMax == nil:
+		return fmt.Sprintf("%d+", *args.BedsMin)
+	case args.BedsMin == nil:
+		return fmt.Sprintf("0-%d", *args.BedsMax)
+	default:
+		return fmt.Sprintf("%d-%d", *args.BedsMin, *args.BedsMax)
+	}
+}
+
+// formatMetrics writes gauges in the Prometheus text exposition format.
+// Labels come from the push URL's grouping key, so none are written here.
+func formatMetrics(prices []uint64) []byte {
+	metrics := map[string]float64{"listing_count": float64(len(prices))}
+	if len(prices) > 0 {
+		sorted := make([]uint64, len(prices))
+		copy(sorted, prices)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		stats := calculatePriceStats(prices)
+		metrics["price_mean"] = stats.mean
+		metrics["price_median"] = calculateMedian(prices)
+		metrics["price_stddev"] = stats.stddev
+		metrics["price_p10"] = quantile(sorted, 0.1)
+		metrics["price_p90"] = quantile(sorted, 0.9)
+	}
+
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n%s %s\n", name, name, strconv.FormatFloat(metrics[name], 'g', -1, 64))
+	}
+
+	return buf.Bytes()
+}
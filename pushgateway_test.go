@@ -0,0 +1,138 @@
+package zoopla
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPushURL(t *testing.T) {
+	tests := []struct {
+		name string
+		args cliArgs
+		want string
+	}{
+		{
+			name: "defaults",
+			args: cliArgs{PushGateway: "http://localhost:9091", Postcode: "SW2"},
+			want: "http://localhost:9091/metrics/job/zoopla/postcode/SW2/beds/any",
+		},
+		{
+			name: "job and beds",
+			args: cliArgs{PushGateway: "http://localhost:9091", PushJob: "london", Postcode: "SW2", BedsMin: uint32Ptr(2), BedsMax: uint32Ptr(3)},
+			want: "http://localhost:9091/metrics/job/london/postcode/SW2/beds/2-3",
+		},
+		{
+			name: "gateway path",
+			args: cliArgs{PushGateway: "http://localhost:9091/prometheus/", Postcode: "SW2"},
+			want: "http://localhost:9091/prometheus/metrics/job/zoopla/postcode/SW2/beds/any",
+		},
+		{
+			name: "escaped labels",
+			args: cliArgs{PushGateway: "http://localhost:9091", PushJob: "a/b", Postcode: "SW2 1AA,SW3"},
+			want: "http://localhost:9091/metrics/job/a%2Fb/postcode/SW2%201AA%2CSW3/beds/any",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pushURL(&tt.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("pushURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBedsFilterLabel(t *testing.T) {
+	tests := []struct {
+		min, max *uint32
+		want     string
+	}{
+		{nil, nil, "any"},
+		{uint32Ptr(2), nil, "2+"},
+		{nil, uint32Ptr(3), "0-3"},
+		{uint32Ptr(1), uint32Ptr(1), "1-1"},
+	}
+
+	for _, tt := range tests {
+		if got := bedsFilterLabel(&cliArgs{BedsMin: tt.min, BedsMax: tt.max}); got != tt.want {
+			t.Errorf("bedsFilterLabel(%v, %v) = %q, want %q", derefUint32(tt.min), derefUint32(tt.max), got, tt.want)
+		}
+	}
+}
+
+func TestFormatMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []uint64
+		want   string
+	}{
+		{
+			name: "no prices",
+			want: "# TYPE listing_count gauge\nlisting_count 0\n",
+		},
+		{
+			name:   "prices",
+			prices: []uint64{400000, 200000, 300000},
+			want: "# TYPE listing_count gauge\nlisting_count 3\n" +
+				"# TYPE price_mean gauge\nprice_mean 300000\n" +
+				"# TYPE price_median gauge\nprice_median 300000\n" +
+				"# TYPE price_p10 gauge\nprice_p10 220000\n" +
+				"# TYPE price_p90 gauge\nprice_p90 380000\n" +
+				"# TYPE price_stddev gauge\nprice_stddev 100000\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(formatMetrics(tt.prices)); got != tt.want {
+				t.Errorf("formatMetrics() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPushMetrics(t *testing.T) {
+	var method, path, contentType, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		method, path, contentType, body = r.Method, r.URL.EscapedPath(), r.Header.Get("Content-Type"), string(b)
+	}))
+	defer srv.Close()
+
+	args := cliArgs{PushGateway: srv.URL, Postcode: "SW2/3"}
+	if err := pushMetrics(context.Background(), &args, []uint64{300000}); err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodPut {
+		t.Errorf("method = %s, want PUT", method)
+	}
+	if want := "/metrics/job/zoopla/postcode/SW2%2F3/beds/any"; path != want {
+		t.Errorf("path = %s, want %s", path, want)
+	}
+	if contentType != "text/plain; version=0.0.4" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	if body != string(formatMetrics([]uint64{300000})) {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestPushMetricsError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad metrics", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	args := cliArgs{PushGateway: srv.URL, Postcode: "SW2"}
+	if err := pushMetrics(context.Background(), &args, []uint64{300000}); err == nil {
+		t.Error("pushMetrics() succeeded against a failing gateway")
+	}
+}
@@ -0,0 +1,84 @@
+package zoopla
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	qualifierGuide      = "guide"
+	qualifierOffersOver = "offers_over"
+	qualifierOIRO       = "offers_in_region"
+	qualifierFrom       = "from"
+	qualifierFixed      = "fixed"
+)
+
+var (
+	auctionRegexp = regexp.MustCompile(`(?i)\bauction\b`)
+
+	qualifierPhrases = []struct {
+		phrase    string
+		qualifier string
+	}{
+		{"guide price", qualifierGuide},
+		{"offers over", qualifierOffersOver},
+		{"offers in excess of", qualifierOffersOver},
+		{"offers in the region of", qualifierOIRO},
+		{"offers in region of", qualifierOIRO},
+		{"oiro", qualifierOIRO},
+		{"from", qualifierFrom},
+		{"fixed price", qualifierFixed},
+	}
+)
+
+func isQualifierNode(n *html.Node) bool {
+	return n.Type == html.ElementNode && hasClass(n, "PriceTitleText")
+}
+
+func parseQualifier(card *html.Node) string {
+	n := findFirst(card, isQualifierNode)
+	if n == nil {
+		return ""
+	}
+
+	return normaliseQualifier(textContent(n))
+}
+
+func normaliseQualifier(raw string) string {
+	raw = strings.ToLower(strings.Join(strings.Fields(raw), " "))
+	for _, p := range qualifierPhrases {
+		if strings.Contains(raw, p.phrase) {
+			return p.qualifier
+		}
+	}
+
+	return ""
+}
+
+func splitAuctions(listings []listing) (others, auctions []listing) {
+	for i := range listings {
+		if listings[i].IsAuction {
+			auctions = append(auctions, listings[i])
+		} else {
+			others = append(others, listings[i])
+		}
+	}
+
+	return others, auctions
+}
+
+type auctionStats struct {
+	count     int
+	meanGuide float64
+}
+
+func calculateAuctionStats(auctions []listing) auctionStats {
+	return auctionStats{count: len(auctions), meanGuide: calculateMean(listingPrices(auctions))}
+}
+
+func (s auctionStats) String() string {
+	return fmt.Sprintf("count = %d, mean guide = %.0f", s.count, s.meanGuide)
+}
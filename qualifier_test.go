@@ -0,0 +1,107 @@
+package zoopla
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormaliseQualifier(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "Guide price", want: qualifierGuide},
+		{raw: "  OFFERS \n OVER ", want: qualifierOffersOver},
+		{raw: "Offers in excess of", want: qualifierOffersOver},
+		{raw: "Offers in the region of", want: qualifierOIRO},
+		{raw: "OIRO", want: qualifierOIRO},
+		{raw: "Shared ownership from", want: qualifierFrom},
+		{raw: "Fixed price", want: qualifierFixed},
+		{raw: "Asking price", want: ""},
+		{raw: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			if got := normaliseQualifier(tt.raw); got != tt.want {
+				t.Errorf("normaliseQualifier(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListingCardAuction(t *testing.T) {
+	tests := []struct {
+		name          string
+		card          string
+		wantQualifier string
+		wantAuction   bool
+	}{
+		{
+			name:          "auction badge forces guide",
+			card:          `<div data-testid="search-result"><span>Auction</span><div class="PriceContainer"><p class="PriceTitleText">Offers over</p><p>£250,000</p></div></div>`,
+			wantQualifier: qualifierGuide,
+			wantAuction:   true,
+		},
+		{
+			name:          "guide price without auction",
+			card:          `<div data-testid="search-result"><div class="PriceContainer"><p class="PriceTitleText">Guide price</p><p>£250,000</p></div></div>`,
+			wantQualifier: qualifierGuide,
+		},
+		{
+			name:          "offers over",
+			card:          `<div data-testid="search-result"><div class="PriceContainer"><p class="PriceTitleText">Offers over</p><p>£250,000</p></div></div>`,
+			wantQualifier: qualifierOffersOver,
+		},
+		{
+			name: "auctioneer is not an auction",
+			card: `<div data-testid="search-result"><p>Marketed by Auctioneers &amp; Co</p><p>£250,000</p></div>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := parseListingCard(mustParseCard(t, tt.card), saleSearch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Qualifier != tt.wantQualifier || l.IsAuction != tt.wantAuction {
+				t.Errorf("got qualifier %q, auction %v, want %q, %v", l.Qualifier, l.IsAuction, tt.wantQualifier, tt.wantAuction)
+			}
+			if l.Price != 250000 {
+				t.Errorf("got price %d, want 250000", l.Price)
+			}
+		})
+	}
+}
+
+func TestSplitAuctions(t *testing.T) {
+	listings := []listing{
+		{ID: "1"},
+		{ID: "2", IsAuction: true, Price: 200000},
+		{ID: "3"},
+		{ID: "4", IsAuction: true, Price: 300000},
+	}
+
+	others, auctions := splitAuctions(listings)
+	if got := ids(others); !reflect.DeepEqual(got, []string{"1", "3"}) {
+		t.Errorf("others = %v", got)
+	}
+	if got := ids(auctions); !reflect.DeepEqual(got, []string{"2", "4"}) {
+		t.Errorf("auctions = %v", got)
+	}
+
+	s := calculateAuctionStats(auctions)
+	if s.count != 2 || s.meanGuide != 250000 {
+		t.Errorf("calculateAuctionStats() = %+v", s)
+	}
+}
+
+func ids(listings []listing) []string {
+	var ids []string
+	for _, l := range listings {
+		ids = append(ids, l.ID)
+	}
+
+	return ids
+}
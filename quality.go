@@ -0,0 +1,69 @@
+package zoopla
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	defaultMinSample = 10
+	defaultMaxCV     = 0.5
+)
+
+// Stats flags warn that a sample's stats shouldn't be taken at face value.
+const (
+	flagSmallSample   = "small_sample"
+	flagHighVariation = "high_variation"
+)
+
+// sampleThresholds decide when stats are flagged: below minSample prices,
+// or with a coefficient of variation above maxCV.
+type sampleThresholds struct {
+	minSample int
+	maxCV     float64
+}
+
+func calculateStandardError(stddev float64, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+
+	return stddev / math.Sqrt(float64(n))
+}
+
+// coefficientOfVariation is stddev relative to the mean, which is zero if
+// the mean is.
+func coefficientOfVariation(stddev, mean float64) float64 {
+	if mean == 0 {
+		return 0
+	}
+
+	return stddev / mean
+}
+
+func (t sampleThresholds) flags(s priceStats) []string {
+	var flags []string
+	if s.count < t.minSample {
+		flags = append(flags, flagSmallSample)
+	}
+	if t.maxCV > 0 && s.cv > t.maxCV {
+		flags = append(flags, flagHighVariation)
+	}
+
+	return flags
+}
+
+// sampleWarnings explains each of the stats' flags.
+func sampleWarnings(s priceStats, t sampleThresholds) []string {
+	var warnings []string
+	for _, f := range s.flags {
+		switch f {
+		case flagSmallSample:
+			warnings = append(warnings, fmt.Sprintf("only %d prices, fewer than %d, so the stats may not be representative", s.count, t.minSample))
+		case flagHighVariation:
+			warnings = append(warnings, fmt.Sprintf("coefficient of variation %.2f is above %.2f, so prices vary too widely for the mean to be typical", s.cv, t.maxCV))
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,102 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCalculateStandardError(t *testing.T) {
+	tests := []struct {
+		stddev float64
+		n      int
+		want   float64
+	}{
+		{100, 4, 50},
+		{100, 1, 100},
+		{0, 10, 0},
+		{100, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := calculateStandardError(tt.stddev, tt.n); !closeTo(got, tt.want) {
+			t.Errorf("calculateStandardError(%v, %d) = %v, want %v", tt.stddev, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestCoefficientOfVariation(t *testing.T) {
+	if got := coefficientOfVariation(50, 200); !closeTo(got, 0.25) {
+		t.Errorf("got %v, want 0.25", got)
+	}
+	if got := coefficientOfVariation(50, 0); got != 0 {
+		t.Errorf("got %v for a zero mean, want 0", got)
+	}
+}
+
+func TestSampleThresholdsFlags(t *testing.T) {
+	th := sampleThresholds{minSample: 10, maxCV: 0.5}
+
+	tests := []struct {
+		name string
+		th   sampleThresholds
+		s    priceStats
+		want []string
+	}{
+		{"fine", th, priceStats{count: 10, cv: 0.5}, nil},
+		{"small", th, priceStats{count: 9, cv: 0.1}, []string{flagSmallSample}},
+		{"varied", th, priceStats{count: 20, cv: 0.51}, []string{flagHighVariation}},
+		{"both", th, priceStats{count: 3, cv: 2}, []string{flagSmallSample, flagHighVariation}},
+		{"no cv limit", sampleThresholds{minSample: 1}, priceStats{count: 3, cv: 2}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.th.flags(tt.s); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSampleWarnings(t *testing.T) {
+	th := sampleThresholds{minSample: 10, maxCV: 0.5}
+	s := priceStats{count: 3, cv: 0.75, flags: []string{flagSmallSample, flagHighVariation}}
+
+	want := []string{
+		"only 3 prices, fewer than 10, so the stats may not be representative",
+		"coefficient of variation 0.75 is above 0.50, so prices vary too widely for the mean to be typical",
+	}
+	if got := sampleWarnings(s, th); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := sampleWarnings(priceStats{count: 30}, th); got != nil {
+		t.Errorf("got %q for no flags", got)
+	}
+}
+
+func TestPriceStatsFlags(t *testing.T) {
+	opts := statsOptions{thresholds: &sampleThresholds{minSample: 5, maxCV: 0.5}}
+
+	tests := []struct {
+		name   string
+		prices []uint64
+		want   string
+	}{
+		{"flagged", []uint64{100000, 900000}, `"flags":["small_sample","high_variation"]`},
+		{"unflagged", []uint64{400000, 410000, 420000, 430000, 440000}, `"flags":[]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(calculatePriceStatsWith(tt.prices, opts))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("%s has no %s", data, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// minRegressionSample is the fewest listings with bedrooms that a
+// regression is fitted to.
+const minRegressionSample = 10
+
+// priceRegression is an ordinary least squares fit of price on bedrooms,
+// optionally with a dummy variable per property type.
+type priceRegression struct {
+	n         int
+	intercept float64
+	perBed    float64
+	rSquared  float64
+
+	// typeEffects are each property type's difference in price from
+	// baselineType, the most common type, for the same bedrooms.
+	baselineType string
+	typeEffects  map[string]float64
+}
+
+// fitPriceRegression fits price against bedrooms for the listings whose
+// bedrooms are known. If property type dummies can't be fitted it falls
+// back to bedrooms alone.
+func fitPriceRegression(listings []listing, withTypes bool) (*priceRegression, error) {
+	var sample []*listing
+	for i := range listings {
+		if listings[i].Beds != nil {
+			sample = append(sample, &listings[i])
+		}
+	}
+
+	if len(sample) < minRegressionSample {
+		return nil, errors.Errorf("only %d listings with bedrooms, need at least %d", len(sample), minRegressionSample)
+	}
+
+	if withTypes {
+		if r, err := fitRegression(sample, regressionTypes(sample)); err == nil {
+			return r, nil
+		}
+	}
+
+	return fitRegression(sample, nil)
+}
+
+// regressionTypes returns the property types in sample, most common first,
+// leaving out any seen only once as they would be fitted exactly.
+func regressionTypes(sample []*listing) []string {
+	counts := make(map[string]int)
+	for _, l := range sample {
+		counts[propertyTypeKey(l)]++
+	}
+
+	var types []string
+	for t, n := range counts {
+		if n > 1 {
+			types = append(types, t)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if counts[types[i]] != counts[types[j]] {
+			return counts[types[i]] > counts[types[j]]
+		}
+		return types[i] < types[j]
+	})
+
+	return types
+}
+
+// fitRegression fits price = intercept + perBed*beds plus a dummy for each
+// of types after the first, which is the baseline.
+func fitRegression(sample []*listing, types []string) (*priceRegression, error) {
+	var dummies []string
+	if len(types) > 1 {
+		dummies = types[1:]
+	}
+
+	k := 2 + len(dummies)
+	xtx := make([][]float64, k)
+	for i := range xtx {
+		xtx[i] = make([]float64, k)
+	}
+	xty := make([]float64, k)
+
+	row := make([]float64, k)
+	for _, l := range sample {
+		regressionRow(row, l, dummies)
+		for i := range row {
+			for j := range row {
+				xtx[i][j] += row[i] * row[j]
+			}
+			xty[i] += row[i] * float64(l.Price)
+		}
+	}
+
+	coef, err := solveLinear(xtx, xty)
+	if err != nil {
+		return nil, errors.Wrap(err, "while fitting regression")
+	}
+
+	r := &priceRegression{n: len(sample), intercept: coef[0], perBed: coef[1]}
+	if len(dummies) > 0 {
+		r.baselineType = types[0]
+		r.typeEffects = make(map[string]float64, len(dummies))
+		for i, t := range dummies {
+			r.typeEffects[t] = coef[2+i]
+		}
+	}
+
+	var mean float64
+	for _, l := range sample {
+		mean += float64(l.Price)
+	}
+	mean /= float64(len(sample))
+
+	var ssRes, ssTot float64
+	for _, l := range sample {
+		pred, _ := r.predict(l)
+		ssRes += (float64(l.Price) - pred) * (float64(l.Price) - pred)
+		ssTot += (float64(l.Price) - mean) * (float64(l.Price) - mean)
+	}
+	if ssTot > 0 {
+		r.rSquared = 1 - ssRes/ssTot
+	}
+
+	return r, nil
+}
+
+func regressionRow(row []float64, l *listing, dummies []string) {
+	row[0] = 1
+	row[1] = float64(*l.Beds)
+	t := propertyTypeKey(l)
+	for i, d := range dummies {
+		row[2+i] = 0
+		if t == d {
+			row[2+i] = 1
+		}
+	}
+}
+
+// solveLinear solves a x = b by Gaussian elimination with partial pivoting,
+// failing if a is singular, as it is when a regressor doesn't vary.
+func solveLinear(a [][]float64, b []float64) ([]float64, error) {
+	n := len(b)
+	m := make([][]float64, n)
+	for i := range a {
+		m[i] = append(append([]float64(nil), a[i]...), b[i])
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(m[pivot][col]) < 1e-9 {
+			return nil, errors.New("regressors are collinear or don't vary")
+		}
+		m[col], m[pivot] = m[pivot], m[col]
+
+		for r := 0; r < n; r++ {
+			if r == col {
+				continue
+			}
+			f := m[r][col] / m[col][col]
+			for c := col; c <= n; c++ {
+				m[r][c] -= f * m[col][c]
+			}
+		}
+	}
+
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = m[i][n] / m[i][i]
+	}
+
+	return x, nil
+}
+
+// predict returns the fitted price for a listing, or false if its bedrooms
+// aren't known.
+func (r *priceRegression) predict(l *listing) (float64, bool) {
+	if l.Beds == nil {
+		return 0, false
+	}
+
+	pred := r.intercept + r.perBed*float64(*l.Beds)
+	if effect, ok := r.typeEffects[propertyTypeKey(l)]; ok {
+		pred += effect
+	}
+
+	return pred, true
+}
+
+// assignValueScores sets each listing's value score: how far below its
+// fitted price it is, as a percentage, so cheaper looking listings score
+// higher.
+func assignValueScores(listings []listing, r *priceRegression) {
+	for i := range listings {
+		pred, ok := r.predict(&listings[i])
+		if !ok || pred <= 0 {
+			continue
+		}
+
+		score := math.Round(1000*(pred-float64(listings[i].Price))/pred) / 10
+		listings[i].ValueScore = &score
+	}
+}
+
+// sortByValue orders listings by descending value score, with those without
+// a score last.
+func sortByValue(listings []listing) {
+	sort.SliceStable(listings, func(i, j int) bool {
+		a, b := listings[i].ValueScore, listings[j].ValueScore
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a > *b
+	})
+}
+
+func (r *priceRegression) String() string {
+	s := fmt.Sprintf("each extra bedroom ≈ %s in this area (n = %d, R² = %.2f)", signedPrice(r.perBed), r.n, r.rSquared)
+
+	types := make([]string, 0, len(r.typeEffects))
+	for t := range r.typeEffects {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	var effects []string
+	for _, t := range types {
+		effects = append(effects, fmt.Sprintf("%s ≈ %s", t, signedPrice(r.typeEffects[t])))
+	}
+	if len(effects) > 0 {
+		s += fmt.Sprintf(", compared with %s: %s", r.baselineType, strings.Join(effects, ", "))
+	}
+
+	return s
+}
+
+func signedPrice(v float64) string {
+	if v < 0 {
+		return "-" + roundedPrice(-v)
+	}
+
+	return roundedPrice(v)
+}
+
+func (r *priceRegression) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		N            int                `json:"n"`
+		Intercept    float64            `json:"intercept"`
+		PerBed       float64            `json:"per_bed"`
+		RSquared     float64            `json:"r_squared"`
+		BaselineType string             `json:"baseline_type,omitempty"`
+		TypeEffects  map[string]float64 `json:"type_effects,omitempty"`
+	}{r.n, r.intercept, r.perBed, r.rSquared, r.baselineType, r.typeEffects})
+}
@@ -0,0 +1,211 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// regressionListings are priced exactly £200k plus £100k a bedroom, with
+// terraced houses £50k more than flats.
+func regressionListings() []listing {
+	var listings []listing
+	for i := 0; i < 12; i++ {
+		beds := uint32(1 + i%4)
+		l := listing{ID: strconv.Itoa(i + 1), Beds: uint32Ptr(beds), PropertyType: "flat", Price: 200000 + 100000*uint64(beds)}
+		if i%3 == 0 {
+			l.PropertyType = "terraced"
+			l.Price += 50000
+		}
+		listings = append(listings, l)
+	}
+
+	return listings
+}
+
+func closeToPrice(got, want float64) bool {
+	return math.Abs(got-want) < 1e-3
+}
+
+func TestFitPriceRegression(t *testing.T) {
+	r, err := fitPriceRegression(regressionListings(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.n != 12 || !closeToPrice(r.intercept, 200000) || !closeToPrice(r.perBed, 100000) || !closeTo(r.rSquared, 1) {
+		t.Errorf("got %+v, want an exact fit", *r)
+	}
+	if r.baselineType != "flat" || len(r.typeEffects) != 1 || !closeToPrice(r.typeEffects["terraced"], 50000) {
+		t.Errorf("got baseline %q, effects %v, want terraced £50k over flat", r.baselineType, r.typeEffects)
+	}
+}
+
+func TestFitPriceRegressionWithoutTypes(t *testing.T) {
+	r, err := fitPriceRegression(regressionListings(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the dummy the terraced premium is left in the residuals.
+	if r.n != 12 || r.rSquared <= 0.9 || r.rSquared >= 1 || r.typeEffects != nil {
+		t.Errorf("got %+v, want a close but inexact fit without types", *r)
+	}
+}
+
+func TestFitPriceRegressionErrors(t *testing.T) {
+	sameBeds := regressionListings()
+	for i := range sameBeds {
+		sameBeds[i].Beds = uint32Ptr(2)
+	}
+
+	tests := []struct {
+		name     string
+		listings []listing
+		wantErr  string
+	}{
+		{"too few", regressionListings()[:minRegressionSample-1], "only 9 listings with bedrooms, need at least 10"},
+		{"no bedrooms", make([]listing, 20), "only 0 listings with bedrooms"},
+		{"bedrooms don't vary", sameBeds, "regressors are collinear or don't vary"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := fitPriceRegression(tt.listings, true)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFitPriceRegressionSkipsRareTypes(t *testing.T) {
+	// A type seen only once would be fitted exactly, so it gets no dummy.
+	listings := regressionListings()
+	for i := range listings {
+		listings[i].PropertyType = "flat"
+	}
+	listings[0].PropertyType = "terraced"
+
+	r, err := fitPriceRegression(listings, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.typeEffects != nil {
+		t.Errorf("got type effects %v for a type seen once", r.typeEffects)
+	}
+}
+
+func TestRegressionTypes(t *testing.T) {
+	var sample []*listing
+	for _, typ := range []string{"flat", "terraced", "flat", "detached", "terraced", "flat", "", "", "semi-detached"} {
+		sample = append(sample, &listing{PropertyType: typ})
+	}
+
+	want := []string{"flat", "terraced", unknownGroup}
+	if got := regressionTypes(sample); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSolveLinear(t *testing.T) {
+	// x + 2y = 5 and 3x + 4y = 11.
+	got, err := solveLinear([][]float64{{1, 2}, {3, 4}}, []float64{5, 11})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !closeTo(got[0], 1) || !closeTo(got[1], 2) {
+		t.Errorf("got %v, want [1 2]", got)
+	}
+
+	// A zero leading coefficient needs the rows swapping.
+	got, err = solveLinear([][]float64{{0, 1}, {1, 0}}, []float64{3, 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !closeTo(got[0], 4) || !closeTo(got[1], 3) {
+		t.Errorf("got %v, want [4 3]", got)
+	}
+
+	if _, err := solveLinear([][]float64{{1, 2}, {2, 4}}, []float64{1, 2}); err == nil {
+		t.Error("solved a singular system")
+	}
+}
+
+func TestAssignValueScores(t *testing.T) {
+	r := &priceRegression{intercept: 200000, perBed: 100000, baselineType: "flat", typeEffects: map[string]float64{"terraced": 50000}}
+
+	listings := []listing{
+		{ID: "cheap", Beds: uint32Ptr(2), Price: 360000, PropertyType: "flat"},
+		{ID: "dear", Beds: uint32Ptr(1), Price: 385000, PropertyType: "terraced"},
+		{ID: "fair", Beds: uint32Ptr(3), Price: 500000},
+		{ID: "unknown beds", Price: 100000},
+		{ID: "negative fit", Beds: uint32Ptr(0), Price: 100000},
+	}
+	r2 := *r
+	r2.intercept = -1
+	assignValueScores(listings[:4], r)
+	assignValueScores(listings[4:], &r2)
+
+	want := []*float64{float64Ptr(10), float64Ptr(-10), float64Ptr(0), nil, nil}
+	for i, l := range listings {
+		if (l.ValueScore == nil) != (want[i] == nil) || (l.ValueScore != nil && *l.ValueScore != *want[i]) {
+			t.Errorf("%s: got value score %v, want %v", l.ID, derefFloat64(l.ValueScore), derefFloat64(want[i]))
+		}
+	}
+
+	sortByValue(listings)
+	if got, want := ids(listings), []string{"cheap", "fair", "dear", "unknown beds", "negative fit"}; !equalStrings(got, want) {
+		t.Errorf("sorted by value got %v, want %v", got, want)
+	}
+}
+
+func TestPriceRegressionString(t *testing.T) {
+	tests := []struct {
+		name string
+		r    priceRegression
+		want string
+	}{
+		{
+			name: "bedrooms only",
+			r:    priceRegression{n: 12, perBed: 98765, rSquared: 0.876},
+			want: "each extra bedroom ≈ £99k in this area (n = 12, R² = 0.88)",
+		},
+		{
+			name: "with types",
+			r: priceRegression{n: 20, perBed: 100000, rSquared: 1, baselineType: "flat",
+				typeEffects: map[string]float64{"terraced": 50000, "detached": -1250000}},
+			want: "each extra bedroom ≈ £100k in this area (n = 20, R² = 1.00), compared with flat: detached ≈ -£1.25m, terraced ≈ £50k",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceRegressionJSON(t *testing.T) {
+	r := &priceRegression{n: 12, intercept: 200000, perBed: 100000, rSquared: 1}
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"n":12,"intercept":200000,"per_bed":100000,"r_squared":1}`; string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+
+	r.baselineType, r.typeEffects = "flat", map[string]float64{"terraced": 50000}
+	if data, err = json.Marshal(r); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), `"baseline_type":"flat","type_effects":{"terraced":50000}}`) {
+		t.Errorf("got %s", data)
+	}
+}
@@ -0,0 +1,298 @@
+package zoopla
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// reportData is everything the Markdown and HTML reports show. It's built
+// once from a run's results so that rendering is a pure function of it.
+type reportData struct {
+	GeneratedAt string
+	Query       runQuery
+	Stats       *reportStats
+	Beds        []reportGroup
+	Types       []reportGroup
+	// FlatsVsHouses is a headline comparing them, if there are enough of
+	// both.
+	FlatsVsHouses string
+	NewBuilds     string
+	// Sold compares the asking prices with Land Registry sold prices, when
+	// they were looked up.
+	Sold string
+	// Yield is the gross rental yield, when --yield searched for rentals.
+	Yield       string
+	YieldGroups []reportYieldGroup
+	Softness    *reportSoftness
+	OnMarket    *reportOnMarket
+	// PricePerSqft is only set when some listings have a floor area.
+	PricePerSqft *reportSqft
+	Agents       []reportAgent
+	// AgentMargin is the margin agents are flagged beyond, as a percentage.
+	// It's only set if some agents were flagged.
+	AgentMargin string
+	Histogram   []histogramBucket
+	// KDE is drawn over the histogram when --kde is given.
+	KDE      *kernelDensity
+	Top      []reportListing
+	Bottom   []reportListing
+	Listings []reportListing
+}
+
+type reportStats struct {
+	Count  int
+	Min    uint64
+	Max    uint64
+	Mean   float64
+	Median float64
+	Stddev float64
+}
+
+type reportGroup struct {
+	Key    string
+	Count  int
+	Mean   float64
+	Median float64
+	P25    float64
+	P75    float64
+}
+
+type reportSoftness struct {
+	Listings     int
+	Reduced      int
+	ReducedShare float64
+	Reduction    *softnessReduction
+	Days         *softnessDays
+}
+
+type reportSqft struct {
+	WithArea    int
+	Total       int
+	Coverage    float64
+	LowCoverage bool
+	All         pricePerSqftGroup
+	ByType      []pricePerSqftGroup
+}
+
+// reportYieldGroup has Estimated set when there were enough sales and rents
+// for Yield.
+type reportYieldGroup struct {
+	Key         string
+	Sales       int
+	Rents       int
+	MedianPrice float64
+	MedianRent  float64
+	Yield       float64
+	Estimated   bool
+}
+
+type reportOnMarket struct {
+	Listings   int
+	Dated      int
+	MedianDays float64
+	P90Days    float64
+	StaleDays  int
+	Stale      int
+	StaleShare float64
+	Stalest    []staleListing
+}
+
+type reportAgent struct {
+	Name      string
+	Count     int
+	Share     float64
+	Mean      float64
+	Median    float64
+	Deviation float64
+	Flagged   bool
+}
+
+type reportListing struct {
+	Price   uint64
+	Beds    string
+	Type    string
+	Title   string
+	Address string
+	Agent   string
+	URL     string
+}
+
+const reportTopN = 5
+
+// buildReport summarises listings, with headline stats taken from
+// statsPrices so they match those logged. Breakdowns are left out when none
+// of the listings had the field they group by.
+func buildReport(listings []listing, statsPrices []uint64, args *cliArgs) *reportData {
+	r := &reportData{
+		GeneratedAt: timeNow().UTC().Format(time.RFC3339),
+		Query:       newRunQuery(args),
+	}
+
+	if len(statsPrices) > 0 {
+		s := calculatePriceStats(statsPrices)
+		r.Stats = &reportStats{
+			Count:  s.count,
+			Min:    s.min,
+			Max:    s.max,
+			Mean:   s.mean,
+			Median: calculateMedian(statsPrices),
+			Stddev: s.stddev,
+		}
+		r.Histogram = calculateHistogram(statsPrices, args.BucketSize)
+		if args.KDE {
+			r.KDE = calculateKDE(statsPrices, args.KDEBandwidth)
+		}
+	}
+
+	if len(listings) > 0 {
+		s := calculateMarketSoftness(listings)
+		r.Softness = &reportSoftness{
+			Listings:     s.listings,
+			Reduced:      s.reduced,
+			ReducedShare: s.reducedShare(),
+			Reduction:    s.reductionSummary(),
+			Days:         s.daysSummary(),
+		}
+	}
+
+	if s := calculateTimeOnMarket(listings, timeNow(), args.StaleDays, args.Stalest); s.dated > 0 {
+		r.OnMarket = &reportOnMarket{
+			Listings:   s.listings,
+			Dated:      s.dated,
+			MedianDays: s.medianDays,
+			P90Days:    s.p90Days,
+			StaleDays:  s.staleDays,
+			Stale:      s.stale,
+			StaleShare: s.staleShare(),
+			Stalest:    s.stalest,
+		}
+	}
+
+	if s := calculatePricePerSqftStats(listings); s.all.Count > 0 {
+		r.PricePerSqft = &reportSqft{
+			WithArea:    s.all.Count,
+			Total:       s.total,
+			Coverage:    100 * s.coverage(),
+			LowCoverage: s.lowCoverage(),
+			All:         s.all,
+			ByType:      s.byType,
+		}
+	}
+
+	r.Beds = reportGroups(listings, calculateGroupStats(listings, bedsKey))
+	r.Types = reportGroups(listings, reliableGroups(orderedGroupStats(listings, propertyTypeKey, args.MinGroupSize)))
+	if c := compareFlatsAndHouses(listings, args.MinGroupSize); c != nil {
+		r.FlatsVsHouses = c.String()
+	}
+	if c := compareNewBuilds(listings, args.MinGroupSize); c != nil {
+		r.NewBuilds = c.String()
+	}
+	if args.TopAgents > 0 {
+		for _, a := range calculateAgentStats(listings, args.TopAgents, args.AgentMargin) {
+			r.Agents = append(r.Agents, reportAgent{
+				Name:      a.name,
+				Count:     a.count,
+				Share:     a.share,
+				Mean:      a.meanPrice,
+				Median:    a.medianPrice,
+				Deviation: a.deviation,
+				Flagged:   a.flagged,
+			})
+		}
+		for _, a := range r.Agents {
+			if a.Flagged {
+				r.AgentMargin = fractionPercent(args.AgentMargin)
+			}
+		}
+	}
+
+	sorted := make([]listing, len(listings))
+	copy(sorted, listings)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Price > sorted[j].Price })
+
+	r.Listings = make([]reportListing, len(sorted))
+	for i := range sorted {
+		r.Listings[i] = newReportListing(&sorted[i])
+	}
+
+	n := reportTopN
+	if n > len(r.Listings) {
+		n = len(r.Listings)
+	}
+	r.Top = r.Listings[:n]
+	for i := len(r.Listings) - 1; i >= len(r.Listings)-n; i-- {
+		r.Bottom = append(r.Bottom, r.Listings[i])
+	}
+
+	return r
+}
+
+func writeReports(r *reportData, args *cliArgs) error {
+	if args.ReportMD != "" {
+		if err := writeFileAtomic(args.ReportMD, []byte(renderMarkdown(r))); err != nil {
+			return err
+		}
+		log.Print("wrote Markdown report to ", args.ReportMD)
+	}
+
+	if args.ReportHTML != "" {
+		data, err := renderHTMLReport(r)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(args.ReportHTML, data); err != nil {
+			return err
+		}
+		log.Print("wrote HTML report to ", args.ReportHTML)
+	}
+
+	return nil
+}
+
+func reportGroups(listings []listing, groups []groupStats) []reportGroup {
+	if onlyUnknownGroup(groups) {
+		return nil
+	}
+
+	rgs := make([]reportGroup, len(groups))
+	for i, g := range groups {
+		rgs[i] = reportGroup{Key: g.key, Count: g.count, Mean: g.mean, Median: g.median, P25: g.p25, P75: g.p75}
+	}
+
+	return rgs
+}
+
+func newReportListing(l *listing) reportListing {
+	return reportListing{
+		Price:   l.Price,
+		Beds:    formatOptionalCount(l.Beds),
+		Type:    l.PropertyType,
+		Title:   l.Title,
+		Address: l.Address,
+		Agent:   l.Agent,
+		URL:     l.URL,
+	}
+}
+
+func bedsKey(l *listing) string {
+	if l.Beds == nil {
+		return unknownGroup
+	}
+
+	return strconv.FormatUint(uint64(*l.Beds), 10) + " bed"
+}
+
+func propertyTypeKey(l *listing) string {
+	if l.PropertyType == "" {
+		return unknownGroup
+	}
+
+	return l.PropertyType
+}
+
+func formatMeanPrice(v float64) string {
+	return formatPrice(uint64(math.Round(v)))
+}
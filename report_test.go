@@ -0,0 +1,126 @@
+package zoopla
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// reportListings are the listings the report tests summarise, with enough
+// of each bedroom count and type to make the breakdowns.
+func reportListings() []listing {
+	var listings []listing
+	for i, p := range []uint64{300000, 320000, 340000, 450000, 480000, 510000, 700000, 750000} {
+		l := listing{
+			ID:           strconv.Itoa(i + 1),
+			Price:        p,
+			Title:        "flat for sale",
+			Address:      "Acre Lane, SW2",
+			URL:          "https://www.zoopla.co.uk/for-sale/details/" + strconv.Itoa(i+1) + "/",
+			PropertyType: "flat",
+			Beds:         uint32Ptr(1),
+		}
+		if i >= 3 {
+			l.Beds = uint32Ptr(2)
+		}
+		if i%2 == 0 {
+			listed := date(2021, time.January, 1+i)
+			l.ListedOn = &listed
+		}
+		if i >= 6 {
+			l.PropertyType = "terraced"
+			l.Beds = uint32Ptr(3)
+		}
+		listings = append(listings, l)
+	}
+
+	return listings
+}
+
+func reportArgs() *cliArgs {
+	return &cliArgs{Postcode: "SW2", Radius: 1, MinGroupSize: 2, StaleDays: defaultStaleDays, Stalest: defaultStalestTop}
+}
+
+func TestBuildReport(t *testing.T) {
+	setNow(t, date(2021, time.April, 1))
+
+	listings := reportListings()
+	r := buildReport(listings, listingPrices(listings), reportArgs())
+
+	if r.GeneratedAt != "2021-04-01T00:00:00Z" {
+		t.Errorf("GeneratedAt = %q", r.GeneratedAt)
+	}
+	if r.Stats == nil || r.Stats.Count != 8 || r.Stats.Min != 300000 || r.Stats.Max != 750000 {
+		t.Fatalf("Stats = %+v", r.Stats)
+	}
+	if r.Stats.Median != 465000 {
+		t.Errorf("Stats.Median = %v, want 465000", r.Stats.Median)
+	}
+
+	var beds []string
+	for _, g := range r.Beds {
+		beds = append(beds, g.Key)
+	}
+	if len(beds) != 3 || beds[0] != "1 bed" || beds[2] != "3 bed" {
+		t.Errorf("Beds keys = %v", beds)
+	}
+	if len(r.Types) != 2 {
+		t.Errorf("got %d type groups, want 2", len(r.Types))
+	}
+
+	if len(r.Top) != reportTopN || r.Top[0].Price != 750000 {
+		t.Errorf("Top = %+v", r.Top)
+	}
+	if len(r.Bottom) != reportTopN || r.Bottom[0].Price != 300000 {
+		t.Errorf("Bottom = %+v", r.Bottom)
+	}
+	if len(r.Listings) != len(listings) {
+		t.Errorf("got %d listings, want %d", len(r.Listings), len(listings))
+	}
+}
+
+func TestBuildReportWithoutFields(t *testing.T) {
+	setNow(t, date(2021, time.April, 1))
+
+	// Nothing to group by, so the breakdowns should be left out rather
+	// than shown with a single unknown group.
+	listings := []listing{{Price: 300000}, {Price: 400000}, {Price: 500000}}
+	r := buildReport(listings, listingPrices(listings), reportArgs())
+
+	if r.Beds != nil || r.Types != nil {
+		t.Errorf("Beds = %+v, Types = %+v, want none", r.Beds, r.Types)
+	}
+	if r.OnMarket != nil {
+		t.Errorf("OnMarket = %+v, want nil without listed dates", r.OnMarket)
+	}
+	if r.PricePerSqft != nil {
+		t.Errorf("PricePerSqft = %+v, want nil without floor areas", r.PricePerSqft)
+	}
+	if len(r.Top) != 3 || len(r.Bottom) != 3 {
+		t.Errorf("got %d top and %d bottom, want 3 of each", len(r.Top), len(r.Bottom))
+	}
+}
+
+func TestBuildReportNoPrices(t *testing.T) {
+	r := buildReport(nil, nil, reportArgs())
+	if r.Stats != nil || len(r.Histogram) > 0 || len(r.Top) > 0 || len(r.Bottom) > 0 {
+		t.Errorf("got %+v, want an empty report", r)
+	}
+}
+
+func TestBedsKey(t *testing.T) {
+	tests := []struct {
+		beds *uint32
+		want string
+	}{
+		{nil, unknownGroup},
+		{uint32Ptr(0), "0 bed"},
+		{uint32Ptr(3), "3 bed"},
+	}
+
+	for _, tt := range tests {
+		if got := bedsKey(&listing{Beds: tt.beds}); got != tt.want {
+			t.Errorf("bedsKey(%v) = %q, want %q", derefUint32(tt.beds), got, tt.want)
+		}
+	}
+}
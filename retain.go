@@ -0,0 +1,105 @@
+package zoopla
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// filenamePattern turns a filename template into a glob to list candidates
+// and a regexp they must fully match. Only the date and time vary between
+// runs; the postcode and beds are fixed to this run's values, so retention
+// never reaches another query's files.
+func filenamePattern(template string, args *cliArgs) (string, *regexp.Regexp) {
+	var glob, re strings.Builder
+	re.WriteString("^")
+
+	last := 0
+	for _, loc := range filenamePlaceholderRegexp.FindAllStringSubmatchIndex(template, -1) {
+		literal := template[last:loc[0]]
+		glob.WriteString(escapeGlob(literal))
+		re.WriteString(regexp.QuoteMeta(literal))
+
+		switch name := template[loc[2]:loc[3]]; name {
+		case "date":
+			glob.WriteString("*")
+			re.WriteString(`\d{4}-\d{2}-\d{2}`)
+		case "time":
+			glob.WriteString("*")
+			re.WriteString(`\d{6}`)
+		default:
+			value := expandFilename("{"+name+"}", args, timeNow())
+			glob.WriteString(escapeGlob(value))
+			re.WriteString(regexp.QuoteMeta(value))
+		}
+		last = loc[1]
+	}
+
+	glob.WriteString(escapeGlob(template[last:]))
+	re.WriteString(regexp.QuoteMeta(template[last:]))
+	re.WriteString("$")
+
+	return glob.String(), regexp.MustCompile(re.String())
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`)
+
+func escapeGlob(s string) string {
+	return globEscaper.Replace(s)
+}
+
+// pruneOutputs deletes all but the newest keep files matching template.
+// It does nothing when keep is 0.
+func pruneOutputs(template string, args *cliArgs, keep uint) error {
+	if keep == 0 || template == "" || template == stdoutFilename {
+		return nil
+	}
+
+	glob, re := filenamePattern(template, args)
+	candidates, err := filepath.Glob(glob)
+	if err != nil {
+		return errors.Wrapf(err, "while listing files matching %s", template)
+	}
+
+	type match struct {
+		name  string
+		mtime int64
+	}
+	var matches []match
+	for _, name := range candidates {
+		if !re.MatchString(name) {
+			continue
+		}
+
+		info, err := os.Stat(name)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		matches = append(matches, match{name: name, mtime: info.ModTime().UnixNano()})
+	}
+
+	if uint(len(matches)) <= keep {
+		return nil
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].mtime != matches[j].mtime {
+			return matches[i].mtime > matches[j].mtime
+		}
+		return matches[i].name > matches[j].name
+	})
+
+	for _, m := range matches[keep:] {
+		if err := os.Remove(m.name); err != nil {
+			return errors.Wrapf(err, "while removing %s", m.name)
+		}
+		log.Print("removed old output ", m.name)
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package zoopla
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilenamePattern(t *testing.T) {
+	args := &cliArgs{Postcode: "SW2", BedsMin: uint32Ptr(2)}
+
+	tests := []struct {
+		template string
+		wantGlob string
+		match    []string
+		noMatch  []string
+	}{
+		{
+			template: "out/{postcode}-{date}.json",
+			wantGlob: "out/SW2-*.json",
+			match:    []string{"out/SW2-2021-04-01.json"},
+			noMatch:  []string{"out/SW2-latest.json", "out/SW9-2021-04-01.json", "out/SW2-2021-04-01.json.bak"},
+		},
+		{
+			template: "{date}T{time}-{beds}.csv",
+			wantGlob: "*T*-2+.csv",
+			match:    []string{"2021-04-01T093000-2+.csv"},
+			noMatch:  []string{"2021-04-01T0930-2+.csv", "2021-04-01T093000-any.csv"},
+		},
+		{
+			template: "runs [old]/*{date}?.json",
+			wantGlob: `runs \[old]/\**\?.json`,
+			match:    []string{"runs [old]/*2021-04-01?.json"},
+			noMatch:  []string{"runs o/x2021-04-01y.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			glob, re := filenamePattern(tt.template, args)
+			if glob != tt.wantGlob {
+				t.Errorf("glob = %q, want %q", glob, tt.wantGlob)
+			}
+			for _, name := range tt.match {
+				if !re.MatchString(name) {
+					t.Errorf("%q doesn't match %s", name, re)
+				}
+				if ok, err := filepath.Match(glob, name); err != nil || !ok {
+					t.Errorf("%q doesn't match the glob %q (%v)", name, glob, err)
+				}
+			}
+			for _, name := range tt.noMatch {
+				if re.MatchString(name) {
+					t.Errorf("%q matches %s", name, re)
+				}
+			}
+		})
+	}
+}
+
+func TestPruneOutputs(t *testing.T) {
+	base := time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		keep uint
+		want []string
+	}{
+		{"keep two", 2, []string{"SW2-2021-04-02.json", "SW2-2021-04-03.json", "SW2-latest.json", "SW9-2021-04-01.json", "notes.txt"}},
+		{"keep all", 10, []string{"SW2-2021-04-01.json", "SW2-2021-04-02.json", "SW2-2021-04-03.json", "SW2-2021-04-04.json", "SW2-latest.json", "SW9-2021-04-01.json", "notes.txt"}},
+		{"zero is a no-op", 0, []string{"SW2-2021-04-01.json", "SW2-2021-04-02.json", "SW2-2021-04-03.json", "SW2-2021-04-04.json", "SW2-latest.json", "SW9-2021-04-01.json", "notes.txt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			// Modification times run in date order, except that the last
+			// date is the oldest file, so pruning has to go by mtime.
+			files := map[string]int{
+				"SW2-2021-04-01.json": 1,
+				"SW2-2021-04-02.json": 2,
+				"SW2-2021-04-03.json": 3,
+				"SW2-2021-04-04.json": -1,
+				"SW2-latest.json":     0,
+				"SW9-2021-04-01.json": 0,
+				"notes.txt":           0,
+			}
+			for name, day := range files {
+				path := filepath.Join(dir, name)
+				if err := ioutil.WriteFile(path, []byte("[]"), 0644); err != nil {
+					t.Fatal(err)
+				}
+				mtime := base.AddDate(0, 0, day)
+				if err := os.Chtimes(path, mtime, mtime); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			template := filepath.Join(dir, "{postcode}-{date}.json")
+			if err := pruneOutputs(template, &cliArgs{Postcode: "SW2"}, tt.keep); err != nil {
+				t.Fatal(err)
+			}
+			assertOnlyFiles(t, dir, tt.want...)
+		})
+	}
+}
+
+func TestPruneOutputsIgnoresDirectories(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "SW2-2021-04-01.json"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "SW2-2021-04-02.json"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := pruneOutputs(filepath.Join(dir, "{postcode}-{date}.json"), &cliArgs{Postcode: "SW2"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	assertOnlyFiles(t, dir, "SW2-2021-04-01.json", "SW2-2021-04-02.json")
+}
+
+func TestPruneOutputsStdout(t *testing.T) {
+	if err := pruneOutputs(stdoutFilename, &cliArgs{}, 1); err != nil {
+		t.Error(err)
+	}
+}
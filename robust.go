@@ -0,0 +1,88 @@
+package zoopla
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// statsOptions chooses the optional stats calculatePriceStatsWith adds to
+// the usual ones.
+type statsOptions struct {
+	percentiles []float64
+
+	// trimFraction and winsorizeFraction are the fraction of prices at each
+	// end dropped for the trimmed mean, or clamped for the winsorized mean.
+	// Zero leaves that mean out.
+	trimFraction      float64
+	winsorizeFraction float64
+
+	// bootstrap, if set, adds confidence intervals for the mean and median.
+	bootstrap *bootstrapOptions
+
+	// thresholds, if set, flag small or widely varying samples.
+	thresholds *sampleThresholds
+}
+
+func newStatsOptions(args *cliArgs) statsOptions {
+	return statsOptions{
+		percentiles:       statsPercentiles(args),
+		trimFraction:      args.TrimmedMean,
+		winsorizeFraction: args.Winsorize,
+		bootstrap:         newBootstrapOptions(args),
+		thresholds:        &sampleThresholds{minSample: args.MinSample, maxCV: args.MaxCV},
+	}
+}
+
+func validateMeanFraction(name string, fraction float64) error {
+	if fraction < 0 || fraction >= 0.5 {
+		return errors.Errorf("%s must be at least 0 and less than 0.5", name)
+	}
+
+	return nil
+}
+
+// fractionPercent formats a fraction such as 0.05 as "5%".
+func fractionPercent(fraction float64) string {
+	return strconv.FormatFloat(math.Round(fraction*1e4)/100, 'f', -1, 64) + "%"
+}
+
+// robustMean is a mean calculated with a fraction of prices at each end
+// dropped or clamped.
+type robustMean struct {
+	Fraction float64 `json:"fraction"`
+	Value    float64 `json:"value"`
+}
+
+// trimmedCount is how many prices at each end of n a fraction covers.
+func trimmedCount(n int, fraction float64) int {
+	return int(fraction * float64(n))
+}
+
+// calculateTrimmedMean is the mean of sorted without the lowest and highest
+// fraction of prices.
+func calculateTrimmedMean(sorted []uint64, fraction float64) robustMean {
+	k := trimmedCount(len(sorted), fraction)
+	return robustMean{Fraction: fraction, Value: calculateMean(sorted[k : len(sorted)-k])}
+}
+
+// calculateWinsorizedMean is the mean of sorted with the lowest and highest
+// fraction of prices replaced by the nearest price that's kept.
+func calculateWinsorizedMean(sorted []uint64, fraction float64) robustMean {
+	k := trimmedCount(len(sorted), fraction)
+	lo, hi := sorted[k], sorted[len(sorted)-1-k]
+
+	var sum float64
+	for _, p := range sorted {
+		switch {
+		case p < lo:
+			p = lo
+		case p > hi:
+			p = hi
+		}
+		sum += float64(p)
+	}
+
+	return robustMean{Fraction: fraction, Value: sum / float64(len(sorted))}
+}
@@ -0,0 +1,115 @@
+package zoopla
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateMeanFraction(t *testing.T) {
+	tests := []struct {
+		fraction float64
+		valid    bool
+	}{
+		{0, true},
+		{0.05, true},
+		{0.49, true},
+		{0.5, false},
+		{1, false},
+		{-0.1, false},
+	}
+
+	for _, tt := range tests {
+		err := validateMeanFraction("--trimmed-mean", tt.fraction)
+		if tt.valid && err != nil {
+			t.Errorf("validateMeanFraction(%v) = %v, want valid", tt.fraction, err)
+		}
+		if !tt.valid && (err == nil || !strings.HasPrefix(err.Error(), "--trimmed-mean must be")) {
+			t.Errorf("validateMeanFraction(%v) = %v, want an error naming the flag", tt.fraction, err)
+		}
+	}
+}
+
+func TestFractionPercent(t *testing.T) {
+	tests := []struct {
+		fraction float64
+		want     string
+	}{
+		{0.05, "5%"},
+		{0.1, "10%"},
+		{0.125, "12.5%"},
+		{0.0001, "0.01%"},
+		{0, "0%"},
+	}
+
+	for _, tt := range tests {
+		if got := fractionPercent(tt.fraction); got != tt.want {
+			t.Errorf("fractionPercent(%v) = %q, want %q", tt.fraction, got, tt.want)
+		}
+	}
+}
+
+func TestRobustMeans(t *testing.T) {
+	sorted := []uint64{100, 200, 300, 400, 500, 600, 700, 800, 900, 10000}
+
+	tests := []struct {
+		name                string
+		sorted              []uint64
+		fraction            float64
+		trimmed, winsorized float64
+	}{
+		{name: "no fraction", sorted: sorted, fraction: 0, trimmed: 1450, winsorized: 1450},
+		{name: "one at each end", sorted: sorted, fraction: 0.1, trimmed: 550, winsorized: 550},
+		{name: "two at each end", sorted: sorted, fraction: 0.2, trimmed: 550, winsorized: 550},
+		// A fraction covering less than one price drops nothing.
+		{name: "too small to drop", sorted: sorted, fraction: 0.05, trimmed: 1450, winsorized: 1450},
+		{name: "single", sorted: []uint64{400}, fraction: 0.4, trimmed: 400, winsorized: 400},
+		{name: "skewed", sorted: []uint64{100, 100, 100, 1000}, fraction: 0.25, trimmed: 100, winsorized: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trimmed := calculateTrimmedMean(tt.sorted, tt.fraction)
+			if trimmed.Fraction != tt.fraction || !closeTo(trimmed.Value, tt.trimmed) {
+				t.Errorf("got trimmed mean %+v, want %v", trimmed, tt.trimmed)
+			}
+
+			winsorized := calculateWinsorizedMean(tt.sorted, tt.fraction)
+			if winsorized.Fraction != tt.fraction || !closeTo(winsorized.Value, tt.winsorized) {
+				t.Errorf("got winsorized mean %+v, want %v", winsorized, tt.winsorized)
+			}
+		})
+	}
+}
+
+func TestWinsorizedMeanClamps(t *testing.T) {
+	// Clamping 1 up to 10 and 1000 down to 40 gives (10+10+20+30+40+40)/6.
+	got := calculateWinsorizedMean([]uint64{1, 10, 20, 30, 40, 1000}, 0.2)
+	if !closeTo(got.Value, 25) {
+		t.Errorf("got %v, want 25", got.Value)
+	}
+
+	// Trimming drops them instead, giving (10+20+30+40)/4.
+	if got := calculateTrimmedMean([]uint64{1, 10, 20, 30, 40, 1000}, 0.2); !closeTo(got.Value, 25) {
+		t.Errorf("got trimmed mean %v, want 25", got.Value)
+	}
+}
+
+func TestPriceStatsRobustMeans(t *testing.T) {
+	prices := []uint64{10000, 100, 900, 200, 800, 300, 700, 400, 600, 500}
+
+	s := calculatePriceStatsWith(prices, statsOptions{trimFraction: 0.1, winsorizeFraction: 0.1})
+	if s.trimmedMean == nil || !closeTo(s.trimmedMean.Value, 550) {
+		t.Errorf("got trimmed mean %+v, want 550", s.trimmedMean)
+	}
+	if s.winsorizedMean == nil || !closeTo(s.winsorizedMean.Value, 550) {
+		t.Errorf("got winsorized mean %+v, want 550", s.winsorizedMean)
+	}
+	if str := s.String(); !strings.Contains(str, "10% trimmed mean = 550, 10% winsorized mean = 550") {
+		t.Errorf("got %q", str)
+	}
+
+	s = calculatePriceStatsWith(prices, statsOptions{})
+	if s.trimmedMean != nil || s.winsorizedMean != nil {
+		t.Errorf("got robust means %+v, %+v without asking", s.trimmedMean, s.winsorizedMean)
+	}
+}
@@ -0,0 +1,51 @@
+package zoopla
+
+import (
+	"regexp"
+	"strconv"
+)
+
+var (
+	bedsRegexp       = regexp.MustCompile(`(?i)\b(\d{1,2})\s*(?:beds?|bedrooms?)\b`)
+	bathsRegexp      = regexp.MustCompile(`(?i)\b(\d{1,2})\s*(?:baths?|bathrooms?)\b`)
+	receptionsRegexp = regexp.MustCompile(`(?i)\b(\d{1,2})\s*(?:receptions?|reception rooms?|living rooms?)\b`)
+	studioRegexp     = regexp.MustCompile(`(?i)\bstudio\b`)
+)
+
+// parseRooms reads the bed, bath and reception chips from a card's text,
+// leaving counts nil when their chip is missing. Studios count as 0 beds.
+func parseRooms(text string, l *listing) {
+	l.Beds = parseRoomCount(bedsRegexp, text)
+	if l.Beds == nil && studioRegexp.MatchString(text) {
+		zero := uint32(0)
+		l.Beds = &zero
+	}
+
+	l.Baths = parseRoomCount(bathsRegexp, text)
+	l.Receptions = parseRoomCount(receptionsRegexp, text)
+}
+
+func parseRoomCount(re *regexp.Regexp, text string) *uint32 {
+	m := re.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+
+	n, err := strconv.ParseUint(m[1], 10, 32)
+	if err != nil {
+		return nil
+	}
+
+	count := uint32(n)
+	return &count
+}
+
+func jsonRoomCount(v interface{}) *uint32 {
+	n, ok := v.(float64)
+	if !ok || n < 0 {
+		return nil
+	}
+
+	count := uint32(n)
+	return &count
+}
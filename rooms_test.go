@@ -0,0 +1,59 @@
+package zoopla
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRooms(t *testing.T) {
+	tests := []struct {
+		text           string
+		wantBeds       *uint32
+		wantBaths      *uint32
+		wantReceptions *uint32
+	}{
+		{text: "2 beds 1 bath 1 reception", wantBeds: uint32Ptr(2), wantBaths: uint32Ptr(1), wantReceptions: uint32Ptr(1)},
+		{text: "3 bedrooms 2 bathrooms 2 reception rooms", wantBeds: uint32Ptr(3), wantBaths: uint32Ptr(2), wantReceptions: uint32Ptr(2)},
+		{text: "4bed 2baths", wantBeds: uint32Ptr(4), wantBaths: uint32Ptr(2)},
+		{text: "1 Bed 1 Living room", wantBeds: uint32Ptr(1), wantReceptions: uint32Ptr(1)},
+		{text: "Studio flat 1 bath", wantBeds: uint32Ptr(0), wantBaths: uint32Ptr(1)},
+		{text: "Studio 1 bed", wantBeds: uint32Ptr(1)},
+		{text: "Flat for sale £450,000"},
+		{text: "2 bedside tables"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var l listing
+			parseRooms(tt.text, &l)
+			if !reflect.DeepEqual(l.Beds, tt.wantBeds) {
+				t.Errorf("beds = %v, want %v", derefUint32(l.Beds), derefUint32(tt.wantBeds))
+			}
+			if !reflect.DeepEqual(l.Baths, tt.wantBaths) {
+				t.Errorf("baths = %v, want %v", derefUint32(l.Baths), derefUint32(tt.wantBaths))
+			}
+			if !reflect.DeepEqual(l.Receptions, tt.wantReceptions) {
+				t.Errorf("receptions = %v, want %v", derefUint32(l.Receptions), derefUint32(tt.wantReceptions))
+			}
+		})
+	}
+}
+
+func TestJSONRoomCount(t *testing.T) {
+	tests := []struct {
+		v    interface{}
+		want *uint32
+	}{
+		{v: float64(3), want: uint32Ptr(3)},
+		{v: float64(0), want: uint32Ptr(0)},
+		{v: float64(-1)},
+		{v: "3"},
+		{v: nil},
+	}
+
+	for _, tt := range tests {
+		if got := jsonRoomCount(tt.v); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("jsonRoomCount(%v) = %v, want %v", tt.v, derefUint32(got), derefUint32(tt.want))
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package zoopla
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// runEntry is one run in an --append history file.
+type runEntry struct {
+	Timestamp string   `json:"timestamp"`
+	Query     runQuery `json:"query"`
+	Prices    []uint64 `json:"prices"`
+	Stats     runStats `json:"stats"`
+}
+
+type runQuery struct {
+	Postcode string  `json:"postcode,omitempty"`
+	PriceMin *uint64 `json:"price_min,omitempty"`
+	PriceMax *uint64 `json:"price_max,omitempty"`
+	BedsMin  *uint32 `json:"beds_min,omitempty"`
+	BedsMax  *uint32 `json:"beds_max,omitempty"`
+	Radius   uint32  `json:"radius,omitempty"`
+}
+
+func newRunQuery(args *cliArgs) runQuery {
+	return runQuery{
+		Postcode: args.Postcode,
+		PriceMin: args.PriceMin,
+		PriceMax: args.PriceMax,
+		BedsMin:  args.BedsMin,
+		BedsMax:  args.BedsMax,
+		Radius:   args.Radius,
+	}
+}
+
+type runStats struct {
+	Count  int     `json:"count"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	Stddev float64 `json:"stddev"`
+}
+
+func newRunEntry(args *cliArgs, prices []uint64) runEntry {
+	return runEntry{
+		Timestamp: timeNow().UTC().Format(time.RFC3339),
+		Query:     newRunQuery(args),
+		Prices:    prices,
+		Stats:     calculateRunStats(prices),
+	}
+}
+
+func calculateRunStats(prices []uint64) runStats {
+	s := runStats{Count: len(prices)}
+	if len(prices) == 0 {
+		return s
+	}
+
+	ps := calculatePriceStats(prices)
+	s.Mean = ps.mean
+	s.Stddev = ps.stddev
+	s.Median = calculateMedian(prices)
+
+	return s
+}
+
+// readRuns loads an existing history file. A missing file is an empty
+// history. A plain price or listings file is wrapped as a single run dated by
+// the file's modification time, and a file that can't be parsed at all is
+// moved aside so it isn't lost.
+func readRuns(filename string) ([]runEntry, error) {
+	data, err := readFileMaybeGzip(filename)
+	if os.IsNotExist(errors.Cause(err)) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading %s", filename)
+	}
+
+	out, err := loadOutput(data)
+	if err == nil {
+		if out.Kind == outputKindHistory {
+			return out.Runs, nil
+		}
+
+		log.Printf("warning: %s is a plain %s file, migrating it to a single historical run", filename, out.Kind)
+		return []runEntry{legacyRun(filename, out.Prices)}, nil
+	}
+
+	backup := fmt.Sprintf("%s.corrupt-%d", filename, timeNow().Unix())
+	log.Printf("warning: could not parse %s as run history (%v), moving it to %s", filename, err, backup)
+	if err := os.Rename(filename, backup); err != nil {
+		return nil, errors.Wrapf(err, "while moving aside %s", filename)
+	}
+
+	return nil, nil
+}
+
+// legacyRun wraps the prices of a plain output file as a run. It's dated now
+// if the file's modification time can't be read, and always has a timestamp
+// and prices so the history it's written to can be read back.
+func legacyRun(filename string, prices []uint64) runEntry {
+	if prices == nil {
+		prices = []uint64{}
+	}
+
+	modified := timeNow()
+	if info, err := os.Stat(filename); err == nil {
+		modified = info.ModTime()
+	}
+
+	return runEntry{
+		Timestamp: modified.UTC().Format(time.RFC3339),
+		Prices:    prices,
+		Stats:     calculateRunStats(prices),
+	}
+}
+
+func appendRun(filename string, args *cliArgs, prices []uint64) error {
+	runs, err := readRuns(filename)
+	if err != nil {
+		return err
+	}
+
+	runs = append(runs, newRunEntry(args, prices))
+
+	data, err := marshalJSON(runs, args.Pretty)
+	if err != nil {
+		return errors.Wrap(err, "while marshalling run history")
+	}
+
+	if isCompressed(args) {
+		if data, err = gzipBytes(data); err != nil {
+			return err
+		}
+	}
+
+	return writeFileAtomic(filename, data)
+}
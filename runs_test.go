@@ -0,0 +1,142 @@
+package zoopla
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAppendRunRoundTrip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "history.json")
+	priceMin := uint64(300000)
+	args := cliArgs{Postcode: "SW2", PriceMin: &priceMin, Radius: 1}
+
+	setNow(t, time.Date(2021, time.May, 3, 9, 0, 0, 0, time.UTC))
+	if err := appendRun(filename, &args, []uint64{400000, 500000}); err != nil {
+		t.Fatal(err)
+	}
+	setNow(t, time.Date(2021, time.May, 10, 9, 0, 0, 0, time.UTC))
+	if err := appendRun(filename, &args, []uint64{450000}); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := readRuns(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := runQuery{Postcode: "SW2", PriceMin: &priceMin, Radius: 1}
+	want := []runEntry{
+		{Timestamp: "2021-05-03T09:00:00Z", Query: query, Prices: []uint64{400000, 500000}, Stats: runStats{Count: 2, Mean: 450000, Median: 450000, Stddev: calculatePriceStats([]uint64{400000, 500000}).stddev}},
+		{Timestamp: "2021-05-10T09:00:00Z", Query: query, Prices: []uint64{450000}, Stats: runStats{Count: 1, Mean: 450000, Median: 450000}},
+	}
+	if !reflect.DeepEqual(runs, want) {
+		t.Errorf("readRuns() = %+v, want %+v", runs, want)
+	}
+}
+
+func TestReadRunsMissing(t *testing.T) {
+	runs, err := readRuns(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil || runs != nil {
+		t.Errorf("readRuns() = %v, %v, want no runs", runs, err)
+	}
+}
+
+func TestAppendRunMigratesPriceFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "prices.json")
+	if err := ioutil.WriteFile(filename, []byte(`[400000,500000]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Date(2021, time.April, 26, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filename, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	setNow(t, time.Date(2021, time.May, 3, 9, 0, 0, 0, time.UTC))
+	if err := appendRun(filename, &cliArgs{Postcode: "SW2"}, []uint64{450000}); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := readRuns(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("got %d runs, want 2", len(runs))
+	}
+	if r := runs[0]; r.Timestamp != "2021-04-26T12:00:00Z" || !equalPrices(r.Prices, []uint64{400000, 500000}) || r.Stats.Count != 2 {
+		t.Errorf("migrated run = %+v", r)
+	}
+	if r := runs[1]; r.Timestamp != "2021-05-03T09:00:00Z" || !equalPrices(r.Prices, []uint64{450000}) {
+		t.Errorf("new run = %+v", r)
+	}
+}
+
+func TestAppendRunMovesCorruptFileAside(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "history.json")
+	if err := ioutil.WriteFile(filename, []byte(`[{"timestamp": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, time.May, 3, 9, 0, 0, 0, time.UTC)
+	setNow(t, now)
+	if err := appendRun(filename, &cliArgs{Postcode: "SW2"}, []uint64{450000}); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := ioutil.ReadFile(filepath.Join(dir, "history.json.corrupt-1620032400"))
+	if err != nil || string(backup) != `[{"timestamp": ` {
+		t.Errorf("backup = %q, %v", backup, err)
+	}
+
+	runs, err := readRuns(filename)
+	if err != nil || len(runs) != 1 {
+		t.Errorf("readRuns() = %+v, %v, want the new run only", runs, err)
+	}
+}
+
+func TestCalculateRunStats(t *testing.T) {
+	if got := calculateRunStats(nil); got != (runStats{}) {
+		t.Errorf("calculateRunStats(nil) = %+v", got)
+	}
+
+	got := calculateRunStats([]uint64{500000, 300000, 400000})
+	if got.Count != 3 || got.Mean != 400000 || got.Median != 400000 || got.Stddev <= 0 {
+		t.Errorf("calculateRunStats() = %+v", got)
+	}
+}
+
+func TestLegacyRunWithoutModTime(t *testing.T) {
+	setNow(t, time.Date(2021, time.May, 3, 9, 0, 0, 0, time.UTC))
+
+	r := legacyRun(filepath.Join(t.TempDir(), "gone.json"), nil)
+	if r.Timestamp != "2021-05-03T09:00:00Z" || r.Prices == nil {
+		t.Errorf("got %+v, want a run dated now with empty prices", r)
+	}
+}
+
+func TestAppendRunMigratesEmptyFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "prices.json")
+	if err := ioutil.WriteFile(filename, []byte(`{"schema_version": 1, "listings": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	setNow(t, time.Date(2021, time.May, 3, 9, 0, 0, 0, time.UTC))
+	if err := appendRun(filename, &cliArgs{Postcode: "SW2"}, []uint64{450000}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The migrated run is still valid history when it's read back, rather
+	// than the whole file being moved aside as corrupt.
+	runs, err := readRuns(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || len(runs[0].Prices) != 0 || !equalPrices(runs[1].Prices, []uint64{450000}) {
+		t.Errorf("readRuns() = %+v, want the empty migrated run and the new one", runs)
+	}
+}
@@ -0,0 +1,79 @@
+package zoopla
+
+import (
+	"fmt"
+	"math"
+)
+
+// minShapeSample is the fewest prices the corrected skewness and kurtosis
+// can be calculated for.
+const minShapeSample = 4
+
+// symmetricSkewness is the largest skewness either way still considered
+// roughly symmetric.
+const symmetricSkewness = 0.5
+
+type distributionShape struct {
+	Skewness       float64 `json:"skewness"`
+	ExcessKurtosis float64 `json:"excess_kurtosis"`
+	Interpretation string  `json:"interpretation"`
+}
+
+// calculateShape calculates the sample skewness and excess kurtosis of
+// prices with the usual small-sample corrections, as used by spreadsheets.
+// It returns nil for fewer than minShapeSample prices or if they're all the
+// same.
+func calculateShape(prices []uint64, mean float64) *distributionShape {
+	if len(prices) < minShapeSample {
+		return nil
+	}
+
+	g1, g2, ok := sampleMoments(prices, mean)
+	if !ok {
+		return nil
+	}
+
+	n := float64(len(prices))
+	shape := &distributionShape{
+		Skewness:       g1 * math.Sqrt(n*(n-1)) / (n - 2),
+		ExcessKurtosis: (n - 1) / ((n - 2) * (n - 3)) * ((n+1)*g2 + 6),
+	}
+	shape.Interpretation = interpretSkewness(shape.Skewness)
+
+	return shape
+}
+
+// sampleMoments returns the uncorrected skewness g1 and excess kurtosis g2
+// of prices, or false if they're all the same.
+func sampleMoments(prices []uint64, mean float64) (g1, g2 float64, ok bool) {
+	n := float64(len(prices))
+
+	var m2, m3, m4 float64
+	for _, p := range prices {
+		d := float64(p) - mean
+		m2 += d * d
+		m3 += d * d * d
+		m4 += d * d * d * d
+	}
+	m2, m3, m4 = m2/n, m3/n, m4/n
+	if m2 == 0 {
+		return 0, 0, false
+	}
+
+	return m3 / math.Pow(m2, 1.5), m4/(m2*m2) - 3, true
+}
+
+func interpretSkewness(skewness float64) string {
+	switch {
+	case skewness > symmetricSkewness:
+		return "right-skewed: a few expensive listings pull the mean above the median"
+	case skewness < -symmetricSkewness:
+		return "left-skewed: a few cheap listings pull the mean below the median"
+	default:
+		return "roughly symmetric: the mean and median are close"
+	}
+}
+
+func (s distributionShape) String() string {
+	return fmt.Sprintf("skewness = %.2f, excess kurtosis = %.2f, %s", s.Skewness, s.ExcessKurtosis, s.Interpretation)
+}
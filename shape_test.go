@@ -0,0 +1,79 @@
+package zoopla
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCalculateShape(t *testing.T) {
+	tests := []struct {
+		name               string
+		prices             []uint64
+		skewness, kurtosis float64
+		interpretation     string
+	}{
+		// The expected values match a spreadsheet's SKEW and KURT.
+		{"symmetric", []uint64{1, 2, 3, 4, 5}, 0, -1.2, "roughly symmetric"},
+		{"right-skewed", []uint64{1, 1, 1, 1, 10}, math.Sqrt(5), 5, "right-skewed"},
+		{"left-skewed", []uint64{10, 10, 10, 10, 1}, -math.Sqrt(5), 5, "left-skewed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calculateShape(tt.prices, calculateMean(tt.prices))
+			if got == nil {
+				t.Fatal("got nil")
+			}
+			if !closeTo(got.Skewness, tt.skewness) || !closeTo(got.ExcessKurtosis, tt.kurtosis) {
+				t.Errorf("got skewness %v, kurtosis %v, want %v, %v", got.Skewness, got.ExcessKurtosis, tt.skewness, tt.kurtosis)
+			}
+			if !strings.HasPrefix(got.Interpretation, tt.interpretation+":") {
+				t.Errorf("got interpretation %q, want %q", got.Interpretation, tt.interpretation)
+			}
+		})
+	}
+}
+
+func TestCalculateShapeUndefined(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []uint64
+	}{
+		{"empty", nil},
+		{"too few", []uint64{1, 2, 10}},
+		{"identical", []uint64{5, 5, 5, 5, 5}},
+	}
+
+	for _, tt := range tests {
+		if got := calculateShape(tt.prices, calculateMean(tt.prices)); got != nil {
+			t.Errorf("%s: got %+v, want nil", tt.name, *got)
+		}
+	}
+}
+
+func TestInterpretSkewness(t *testing.T) {
+	tests := []struct {
+		skewness float64
+		want     string
+	}{
+		{0, "roughly symmetric"},
+		{symmetricSkewness, "roughly symmetric"},
+		{-symmetricSkewness, "roughly symmetric"},
+		{0.51, "right-skewed"},
+		{-0.51, "left-skewed"},
+	}
+
+	for _, tt := range tests {
+		if got := interpretSkewness(tt.skewness); !strings.HasPrefix(got, tt.want) {
+			t.Errorf("interpretSkewness(%v) = %q, want %q", tt.skewness, got, tt.want)
+		}
+	}
+}
+
+func TestDistributionShapeString(t *testing.T) {
+	s := distributionShape{Skewness: 2.236, ExcessKurtosis: 5, Interpretation: "right-skewed"}
+	if got, want := s.String(), "skewness = 2.24, excess kurtosis = 5.00, right-skewed"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
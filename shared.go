@@ -0,0 +1,64 @@
+package zoopla
+
+import (
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+const flagShareUnknown = "share_unknown"
+
+var (
+	sharePercentRegexp    = regexp.MustCompile(`(?i)(\d{1,3}(?:\.\d+)?)\s*%\s*shares?\b`)
+	sharedOwnershipRegexp = regexp.MustCompile(`(?i)\bshared[ -]ownership\b`)
+)
+
+func parseSharePercent(text string) *float64 {
+	m := sharePercentRegexp.FindStringSubmatch(text)
+	if m == nil {
+		return nil
+	}
+
+	share, err := strconv.ParseFloat(m[1], 64)
+	if err != nil || share <= 0 || share > 100 {
+		return nil
+	}
+
+	return &share
+}
+
+// grossedUpPrice is the implied value of the whole property for a
+// shared-ownership listing, or the listed price for any other listing.
+func grossedUpPrice(l *listing) uint64 {
+	if l.SharePercent == nil {
+		return l.Price
+	}
+
+	return uint64(math.Round(float64(l.Price) * 100 / *l.SharePercent))
+}
+
+func grossedUpPrices(listings []listing) []uint64 {
+	prices := make([]uint64, len(listings))
+	for i := range listings {
+		prices[i] = grossedUpPrice(&listings[i])
+	}
+
+	return prices
+}
+
+// flagMissingShares flags shared-ownership listings whose share could not be
+// found, since their price may or may not be for a share.
+func flagMissingShares(listings []listing) {
+	var missing int
+	for i := range listings {
+		if listings[i].SharedOwnership && listings[i].SharePercent == nil {
+			listings[i].Flags = append(listings[i].Flags, flagShareUnknown)
+			missing++
+		}
+	}
+
+	if missing > 0 {
+		log.Printf("no share percentage found for %d shared-ownership listings", missing)
+	}
+}
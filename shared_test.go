@@ -0,0 +1,95 @@
+package zoopla
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSharePercent(t *testing.T) {
+	tests := []struct {
+		text string
+		want *float64
+	}{
+		{text: "25% shares available", want: float64Ptr(25)},
+		{text: "Shared ownership, 37.5 % share", want: float64Ptr(37.5)},
+		{text: "100% share", want: float64Ptr(100)},
+		{text: "0% share", want: nil},
+		{text: "150% share", want: nil},
+		{text: "Shared ownership available", want: nil},
+		{text: "5% deposit", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := parseSharePercent(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSharePercent(%q) = %v, want %v", tt.text, derefFloat64(got), derefFloat64(tt.want))
+			}
+		})
+	}
+}
+
+func TestGrossedUpPrices(t *testing.T) {
+	listings := []listing{
+		{Price: 450000},
+		{Price: 100000, SharePercent: float64Ptr(25)},
+		{Price: 150000, SharePercent: float64Ptr(37.5)},
+	}
+
+	want := []uint64{450000, 400000, 400000}
+	if got := grossedUpPrices(listings); !reflect.DeepEqual(got, want) {
+		t.Errorf("grossedUpPrices() = %v, want %v", got, want)
+	}
+}
+
+func TestFlagMissingShares(t *testing.T) {
+	listings := []listing{
+		{ID: "1", SharedOwnership: true, SharePercent: float64Ptr(25)},
+		{ID: "2", SharedOwnership: true},
+		{ID: "3"},
+	}
+
+	flagMissingShares(listings)
+	for _, l := range listings {
+		want := l.ID == "2"
+		got := len(l.Flags) == 1 && l.Flags[0] == flagShareUnknown
+		if got != want || (!want && len(l.Flags) > 0) {
+			t.Errorf("listing %s flags = %v", l.ID, l.Flags)
+		}
+	}
+}
+
+func TestParseCardTextSharedOwnership(t *testing.T) {
+	tests := []struct {
+		text      string
+		wantShare *float64
+		wantSO    bool
+	}{
+		{text: "2 bed flat £100,000 25% shares available", wantShare: float64Ptr(25), wantSO: true},
+		{text: "2 bed flat £100,000 Shared ownership", wantSO: true},
+		{text: "2 bed flat £400,000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var l listing
+			parseCardText(tt.text, &l)
+			if !reflect.DeepEqual(l.SharePercent, tt.wantShare) || l.SharedOwnership != tt.wantSO {
+				t.Errorf("got share %v, shared ownership %v, want %v, %v",
+					derefFloat64(l.SharePercent), l.SharedOwnership, derefFloat64(tt.wantShare), tt.wantSO)
+			}
+		})
+	}
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func derefFloat64(p *float64) interface{} {
+	if p == nil {
+		return nil
+	}
+
+	return *p
+}
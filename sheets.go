@@ -0,0 +1,246 @@
+package zoopla
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultSheetsTab = "Listings"
+	sheetsAPI        = "https://sheets.googleapis.com/v4/spreadsheets/"
+	sheetsScope      = "https://www.googleapis.com/auth/spreadsheets"
+	sheetsSummaryID  = "summary"
+)
+
+var sheetsHeader = []interface{}{"date", "postcode", "id", "price", "qualifier", "beds", "type", "address", "agent", "url"}
+
+// sheetsClient is the part of the Sheets API the export needs.
+type sheetsClient interface {
+	sheetTitles(ctx context.Context) ([]string, error)
+	addSheet(ctx context.Context, title string) error
+	appendRows(ctx context.Context, tab string, rows [][]interface{}) error
+}
+
+// exportToSheets appends a row per listing and a summary row to tab, first
+// creating the tab with a header row if it doesn't exist.
+func exportToSheets(ctx context.Context, c sheetsClient, tab string, args *cliArgs, listings []listing) error {
+	titles, err := c.sheetTitles(ctx)
+	if err != nil {
+		return err
+	}
+
+	var rows [][]interface{}
+	if !containsString(titles, tab) {
+		if err := c.addSheet(ctx, tab); err != nil {
+			return err
+		}
+		rows = append(rows, sheetsHeader)
+	}
+
+	date := timeNow().Format("2006-01-02")
+	rows = append(rows, sheetsRows(date, args.Postcode, listings)...)
+	if len(listings) > 0 {
+		rows = append(rows, sheetsSummaryRow(date, args.Postcode, listingPrices(listings)))
+	}
+
+	return c.appendRows(ctx, tab, rows)
+}
+
+func sheetsRows(date, postcode string, listings []listing) [][]interface{} {
+	rows := make([][]interface{}, len(listings))
+	for i := range listings {
+		l := &listings[i]
+
+		var beds interface{} = ""
+		if l.Beds != nil {
+			beds = *l.Beds
+		}
+
+		rows[i] = []interface{}{date, postcode, l.ID, l.Price, l.Qualifier, beds, l.PropertyType, l.Address, l.Agent, l.URL}
+	}
+
+	return rows
+}
+
+// sheetsSummaryRow puts the count, mean, median and standard deviation after
+// an ID of "summary", so the row is easy to filter out.
+func sheetsSummaryRow(date, postcode string, prices []uint64) []interface{} {
+	stats := calculatePriceStats(prices)
+	return []interface{}{
+		date, postcode, sheetsSummaryID, stats.count,
+		math.Round(stats.mean), math.Round(calculateMedian(prices)), math.Round(stats.stddev),
+	}
+}
+
+func sendToSheets(ctx context.Context, args *cliArgs, listings []listing) error {
+	c, err := newHTTPSheetsClient(ctx, args.SheetsID, args.SheetsCredentials)
+	if err != nil {
+		return err
+	}
+
+	tab := args.SheetsTab
+	if tab == "" {
+		tab = defaultSheetsTab
+	}
+
+	return errors.Wrap(exportToSheets(ctx, c, tab, args, listings), "while exporting to Google Sheets")
+}
+
+type serviceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// httpSheetsClient calls the Sheets REST API with a service account token.
+type httpSheetsClient struct {
+	spreadsheetID string
+	token         string
+}
+
+func newHTTPSheetsClient(ctx context.Context, spreadsheetID, credentialsFile string) (*httpSheetsClient, error) {
+	data, err := ioutil.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "while reading Sheets credentials")
+	}
+
+	var sa serviceAccount
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return nil, errors.Wrap(err, "while parsing Sheets credentials")
+	}
+
+	token, err := serviceAccountToken(ctx, &sa)
+	if err != nil {
+		return nil, err
+	}
+
+	return &httpSheetsClient{spreadsheetID: spreadsheetID, token: token}, nil
+}
+
+// serviceAccountToken exchanges a signed JWT for an OAuth access token.
+func serviceAccountToken(ctx context.Context, sa *serviceAccount) (string, error) {
+	block, _ := pem.Decode([]byte(sa.PrivateKey))
+	if block == nil {
+		return "", errors.New("no PEM private key in Sheets credentials")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", errors.Wrap(err, "while parsing Sheets private key")
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("Sheets private key is not an RSA key")
+	}
+
+	now := timeNow()
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   sa.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   sa.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "while building token claims")
+	}
+
+	enc := base64.RawURLEncoding
+	unsigned := enc.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." + enc.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", errors.Wrap(err, "while signing token request")
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {unsigned + "." + enc.EncodeToString(sig)},
+	}
+	header := http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}
+
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := doJSON(ctx, http.MethodPost, sa.TokenURI, []byte(form.Encode()), header, &resp); err != nil {
+		return "", errors.Wrap(err, "while getting Sheets access token")
+	}
+
+	return resp.AccessToken, nil
+}
+
+func (c *httpSheetsClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return errors.Wrap(err, "while marshalling Sheets request")
+		}
+	}
+
+	header := http.Header{
+		"Authorization": {"Bearer " + c.token},
+		"Content-Type":  {"application/json"},
+	}
+
+	return doJSON(ctx, method, sheetsAPI+url.PathEscape(c.spreadsheetID)+path, data, header, out)
+}
+
+func (c *httpSheetsClient) sheetTitles(ctx context.Context) ([]string, error) {
+	var resp struct {
+		Sheets []struct {
+			Properties struct {
+				Title string `json:"title"`
+			} `json:"properties"`
+		} `json:"sheets"`
+	}
+	if err := c.do(ctx, http.MethodGet, "?fields=sheets.properties.title", nil, &resp); err != nil {
+		return nil, errors.Wrap(err, "while listing sheets")
+	}
+
+	titles := make([]string, len(resp.Sheets))
+	for i, s := range resp.Sheets {
+		titles[i] = s.Properties.Title
+	}
+
+	return titles, nil
+}
+
+func (c *httpSheetsClient) addSheet(ctx context.Context, title string) error {
+	req := map[string]interface{}{
+		"requests": []interface{}{
+			map[string]interface{}{
+				"addSheet": map[string]interface{}{
+					"properties": map[string]string{"title": title},
+				},
+			},
+		},
+	}
+
+	return errors.Wrapf(c.do(ctx, http.MethodPost, ":batchUpdate", req, nil), "while adding sheet %s", title)
+}
+
+func (c *httpSheetsClient) appendRows(ctx context.Context, tab string, rows [][]interface{}) error {
+	path := "/values/" + url.PathEscape(quoteSheetName(tab)) + ":append?valueInputOption=RAW&insertDataOption=INSERT_ROWS"
+	req := map[string]interface{}{"values": rows}
+
+	return errors.Wrapf(c.do(ctx, http.MethodPost, path, req, nil), "while appending rows to %s", tab)
+}
+
+func quoteSheetName(name string) string {
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
@@ -0,0 +1,205 @@
+package zoopla
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// fakeSheetsClient keeps its tabs in memory.
+type fakeSheetsClient struct {
+	tabs      map[string][][]interface{}
+	appendErr error
+}
+
+func (c *fakeSheetsClient) sheetTitles(ctx context.Context) ([]string, error) {
+	var titles []string
+	for title := range c.tabs {
+		titles = append(titles, title)
+	}
+	return titles, nil
+}
+
+func (c *fakeSheetsClient) addSheet(ctx context.Context, title string) error {
+	c.tabs[title] = nil
+	return nil
+}
+
+func (c *fakeSheetsClient) appendRows(ctx context.Context, tab string, rows [][]interface{}) error {
+	if c.appendErr != nil {
+		return c.appendErr
+	}
+	c.tabs[tab] = append(c.tabs[tab], rows...)
+	return nil
+}
+
+func TestExportToSheets(t *testing.T) {
+	setNow(t, date(2021, time.April, 1))
+
+	args := &cliArgs{Postcode: "SW2"}
+	listings := []listing{
+		{ID: "1", Price: 300000, Qualifier: qualifierOffersOver, Beds: uint32Ptr(2), PropertyType: "flat", Address: "Acre Lane", Agent: "Foxtons", URL: "https://example.com/1/"},
+		{ID: "2", Price: 500000},
+	}
+	listingRows := [][]interface{}{
+		{"2021-04-01", "SW2", "1", uint64(300000), qualifierOffersOver, uint32(2), "flat", "Acre Lane", "Foxtons", "https://example.com/1/"},
+		{"2021-04-01", "SW2", "2", uint64(500000), "", "", "", "", "", ""},
+	}
+	summaryRow := []interface{}{"2021-04-01", "SW2", sheetsSummaryID, 2, 400000.0, 400000.0, 141421.0}
+
+	tests := []struct {
+		name     string
+		tabs     map[string][][]interface{}
+		listings []listing
+		want     [][]interface{}
+	}{
+		{
+			name:     "new tab",
+			tabs:     map[string][][]interface{}{"Sheet1": nil},
+			listings: listings,
+			want:     append(append([][]interface{}{sheetsHeader}, listingRows...), summaryRow),
+		},
+		{
+			name:     "existing tab",
+			tabs:     map[string][][]interface{}{"Listings": {sheetsHeader}},
+			listings: listings,
+			want:     append(append([][]interface{}{sheetsHeader}, listingRows...), summaryRow),
+		},
+		{
+			name: "no listings",
+			tabs: map[string][][]interface{}{"Listings": {sheetsHeader}},
+			want: [][]interface{}{sheetsHeader},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &fakeSheetsClient{tabs: tt.tabs}
+			if err := exportToSheets(context.Background(), c, defaultSheetsTab, args, tt.listings); err != nil {
+				t.Fatal(err)
+			}
+			if got := c.tabs[defaultSheetsTab]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tab rows =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportToSheetsError(t *testing.T) {
+	c := &fakeSheetsClient{tabs: map[string][][]interface{}{}, appendErr: errors.New("quota exceeded")}
+	err := exportToSheets(context.Background(), c, defaultSheetsTab, &cliArgs{}, []listing{{Price: 1}})
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Errorf("exportToSheets() error = %v, want the append error", err)
+	}
+}
+
+func TestQuoteSheetName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Listings", "'Listings'"},
+		{"Tom's sheet", "'Tom''s sheet'"},
+	}
+
+	for _, tt := range tests {
+		if got := quoteSheetName(tt.name); got != tt.want {
+			t.Errorf("quoteSheetName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestServiceAccountToken(t *testing.T) {
+	setNow(t, date(2021, time.April, 1))
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var claims map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if grant := r.PostForm.Get("grant_type"); grant != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			t.Errorf("grant_type = %q", grant)
+		}
+
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		if len(parts) != 3 {
+			t.Errorf("assertion has %d parts, want 3", len(parts))
+			return
+		}
+		sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			t.Error(err)
+		}
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+			t.Errorf("bad JWT signature: %v", err)
+		}
+		payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			t.Error(err)
+		}
+
+		w.Write([]byte(`{"access_token": "token", "token_type": "Bearer"}`))
+	}))
+	defer srv.Close()
+
+	sa := &serviceAccount{
+		ClientEmail: "exporter@project.iam.gserviceaccount.com",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		TokenURI:    srv.URL,
+	}
+	token, err := serviceAccountToken(context.Background(), sa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "token" {
+		t.Errorf("token = %q, want %q", token, "token")
+	}
+
+	now := float64(date(2021, time.April, 1).Unix())
+	want := map[string]interface{}{"iss": sa.ClientEmail, "scope": sheetsScope, "aud": srv.URL, "iat": now, "exp": now + 3600}
+	if !reflect.DeepEqual(claims, want) {
+		t.Errorf("claims = %v, want %v", claims, want)
+	}
+}
+
+func TestServiceAccountTokenBadKey(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"not PEM", "not a key"},
+		{"not PKCS8", string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("junk")}))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := serviceAccountToken(context.Background(), &serviceAccount{PrivateKey: tt.key}); err == nil {
+				t.Error("serviceAccountToken() succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// maxSlackTextLen is Slack's limit on a section block's text.
+const maxSlackTextLen = 3000
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackSummary is what the Slack message reports. Previous is the last run's
+// stats from the --append history, if there was one.
+type slackSummary struct {
+	Postcode string
+	Filters  string
+	Stats    *reportStats
+	Previous *runStats
+	Artifact string
+	Listings []reportListing
+}
+
+func newSlackText(format, text string) *slackText {
+	return &slackText{Type: format, Text: text}
+}
+
+func buildSlackMessage(s *slackSummary) slackMessage {
+	title := "Zoopla prices for " + s.Postcode
+	msg := slackMessage{Text: title}
+	msg.Blocks = append(msg.Blocks, slackBlock{Type: "header", Text: newSlackText("plain_text", title)})
+
+	filters := s.Filters
+	if filters == "" {
+		filters = "no filters"
+	}
+	msg.Blocks = append(msg.Blocks, slackBlock{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: filters}}})
+
+	if s.Stats == nil {
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "section", Text: newSlackText("mrkdwn", "No prices were found.")})
+		return msg
+	}
+
+	fields := []slackText{
+		{Type: "mrkdwn", Text: fmt.Sprintf("*Listings*\n%d", s.Stats.Count)},
+		{Type: "mrkdwn", Text: "*Mean*\n" + formatMeanPrice(s.Stats.Mean) + slackChange(s.Stats.Mean, s.Previous, func(p *runStats) float64 { return p.Mean })},
+		{Type: "mrkdwn", Text: "*Median*\n" + formatMeanPrice(s.Stats.Median) + slackChange(s.Stats.Median, s.Previous, func(p *runStats) float64 { return p.Median })},
+	}
+	if s.Previous != nil {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Previous run*\n%d listings", s.Previous.Count)})
+	}
+	msg.Blocks = append(msg.Blocks, slackBlock{Type: "section", Fields: fields})
+
+	if len(s.Listings) > 0 {
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "section", Text: newSlackText("mrkdwn", slackListingsText(s.Listings))})
+	}
+
+	if s.Artifact != "" {
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: "Output: `" + s.Artifact + "`"}}})
+	}
+
+	return msg
+}
+
+func slackChange(current float64, previous *runStats, value func(*runStats) float64) string {
+	if previous == nil || value(previous) == 0 {
+		return ""
+	}
+
+	change := (current - value(previous)) / value(previous) * 100
+	return fmt.Sprintf(" (%+.1f%%)", change)
+}
+
+// slackListingsText lists the most expensive listings, stopping early to stay
+// within Slack's text limit.
+func slackListingsText(listings []reportListing) string {
+	var b strings.Builder
+	b.WriteString("*Top listings*\n")
+	for i, l := range listings {
+		line := fmt.Sprintf("• %s %s\n", formatPrice(l.Price), slackLink(l))
+		more := fmt.Sprintf("…and %d more", len(listings)-i)
+		if b.Len()+len(line)+len(more) > maxSlackTextLen {
+			b.WriteString(more)
+			return b.String()
+		}
+		b.WriteString(line)
+	}
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func slackLink(l reportListing) string {
+	text := strings.Trim(l.Title+", "+l.Address, ", ")
+	if text == "" {
+		text = "listing"
+	}
+	text = slackEscaper.Replace(text)
+
+	if l.URL == "" {
+		return text
+	}
+
+	return "<" + l.URL + "|" + strings.ReplaceAll(text, "|", "/") + ">"
+}
+
+func sendSlack(ctx context.Context, args *cliArgs, report *reportData) error {
+	s := &slackSummary{
+		Postcode: args.Postcode,
+		Filters:  filterSummary(args),
+		Stats:    report.Stats,
+		Artifact: args.OutputFilename,
+		Listings: report.Listings,
+	}
+	if args.OutputFilename == stdoutFilename {
+		s.Artifact = ""
+	}
+
+	if args.Append {
+		runs, err := readRuns(args.OutputFilename)
+		if err != nil {
+			return err
+		}
+		// The current run has already been appended, so the previous one
+		// is second from last.
+		if len(runs) >= 2 {
+			s.Previous = &runs[len(runs)-2].Stats
+		}
+	}
+
+	body, err := json.Marshal(buildSlackMessage(s))
+	if err != nil {
+		return errors.Wrap(err, "while marshalling Slack message")
+	}
+
+	return errors.Wrap(postJSON(ctx, args.SlackWebhook, body, nil), "while posting to Slack")
+}
@@ -0,0 +1,161 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBuildSlackMessage(t *testing.T) {
+	stats := &reportStats{Count: 3, Mean: 440000, Median: 420000}
+
+	tests := []struct {
+		name    string
+		summary slackSummary
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "no prices",
+			summary: slackSummary{Postcode: "SW2"},
+			want:    []string{"Zoopla prices for SW2", "no filters", "No prices were found."},
+			notWant: []string{"*Listings*"},
+		},
+		{
+			name:    "stats",
+			summary: slackSummary{Postcode: "SW2", Filters: "2+ beds", Stats: stats, Artifact: "prices.json"},
+			want:    []string{"2+ beds", "*Listings*\n3", "*Mean*\n£440,000", "*Median*\n£420,000", "Output: `prices.json`"},
+			notWant: []string{"*Previous run*", "%)"},
+		},
+		{
+			name:    "previous run",
+			summary: slackSummary{Postcode: "SW2", Stats: stats, Previous: &runStats{Count: 4, Mean: 400000, Median: 420000}},
+			want:    []string{"*Mean*\n£440,000 (+10.0%)", "*Median*\n£420,000 (+0.0%)", "*Previous run*\n4 listings"},
+		},
+		{
+			name:    "listings",
+			summary: slackSummary{Postcode: "SW2", Stats: stats, Listings: []reportListing{{Price: 500000, Title: "flat", URL: "https://example.com/1/"}}},
+			want:    []string{"*Top listings*", "• £500,000 <https://example.com/1/|flat>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := buildSlackMessage(&tt.summary)
+			data, err := json.Marshal(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var texts []string
+			for _, b := range msg.Blocks {
+				if b.Text != nil {
+					texts = append(texts, b.Text.Text)
+				}
+				for _, f := range append(b.Fields, b.Elements...) {
+					texts = append(texts, f.Text)
+				}
+			}
+			got := strings.Join(texts, "\n")
+
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("message is missing %q:\n%s", s, data)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("message has %q:\n%s", s, data)
+				}
+			}
+			if msg.Blocks[0].Type != "header" {
+				t.Errorf("first block is %q, want a header", msg.Blocks[0].Type)
+			}
+		})
+	}
+}
+
+func TestSlackListingsTextTruncated(t *testing.T) {
+	listings := make([]reportListing, 200)
+	for i := range listings {
+		listings[i] = reportListing{Price: 500000, Title: strings.Repeat("x", 50), URL: "https://www.zoopla.co.uk/for-sale/details/58500000/"}
+	}
+
+	got := slackListingsText(listings)
+	if n := len(got); n > maxSlackTextLen {
+		t.Errorf("text is %d bytes, over Slack's %d", n, maxSlackTextLen)
+	}
+	if !utf8.ValidString(got) {
+		t.Error("text isn't valid UTF-8")
+	}
+	if !strings.Contains(got, "more") {
+		t.Errorf("truncated text doesn't say how many were left out:\n%s", got)
+	}
+}
+
+func TestSlackLink(t *testing.T) {
+	tests := []struct {
+		name string
+		l    reportListing
+		want string
+	}{
+		{"title and address", reportListing{Title: "flat", Address: "Acre Lane", URL: "https://example.com/"}, "<https://example.com/|flat, Acre Lane>"},
+		{"no url", reportListing{Address: "Acre Lane"}, "Acre Lane"},
+		{"nothing", reportListing{}, "listing"},
+		{"escaped", reportListing{Title: "<b> & co"}, "&lt;b&gt; &amp; co"},
+		{"pipe in link text", reportListing{Title: "a | b", URL: "https://example.com/"}, "<https://example.com/|a / b>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slackLink(tt.l); got != tt.want {
+				t.Errorf("slackLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendSlackRetriesRateLimit(t *testing.T) {
+	shortenNotifyBackoff(t)
+
+	var attempts int
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	args := cliArgs{Postcode: "SW2", SlackWebhook: srv.URL, OutputFilename: stdoutFilename}
+	if err := sendSlack(context.Background(), &args, &reportData{Stats: &reportStats{Count: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("made %d attempts, want 2", attempts)
+	}
+
+	var msg slackMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Text != "Zoopla prices for SW2" {
+		t.Errorf("message text = %q", msg.Text)
+	}
+	for _, b := range msg.Blocks {
+		for _, e := range b.Elements {
+			if strings.HasPrefix(e.Text, "Output:") {
+				t.Errorf("message links stdout as the output: %q", e.Text)
+			}
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// marketSoftness summarises how often and how far asking prices have been
+// cut. Each figure has its own denominator, since the data it needs is only
+// known for some listings.
+type marketSoftness struct {
+	// listings is the number of listings checked for a reduction, and
+	// reduced the number either badged as reduced or whose price history
+	// shows a cut.
+	listings int
+	reduced  int
+
+	// reductions are the total cuts, in pounds and as a percentage of the
+	// first asking price, of the reduced listings with a price history.
+	reductions   []uint64
+	reductionPct []float64
+
+	// daysToReduction is the number of days from listing to the first
+	// reduction, for the reduced listings with both dates known.
+	daysToReduction []uint64
+}
+
+func calculateMarketSoftness(listings []listing) marketSoftness {
+	s := marketSoftness{listings: len(listings)}
+	for i := range listings {
+		l := &listings[i]
+
+		reduction, pct, cut := historyReduction(l.PriceHistory)
+		if !cut && !l.IsReduced {
+			continue
+		}
+		s.reduced++
+
+		if cut {
+			s.reductions = append(s.reductions, reduction)
+			s.reductionPct = append(s.reductionPct, pct)
+		}
+
+		if listed, reduced := firstReductionDates(l); listed != nil && reduced != nil && !reduced.Before(*listed) {
+			s.daysToReduction = append(s.daysToReduction, uint64(daysBetween(*listed, *reduced)))
+		}
+	}
+
+	sort.Float64s(s.reductionPct)
+
+	return s
+}
+
+// historyReduction returns the total cut in a price history, from the first
+// asking price to the last.
+func historyReduction(history []pricePoint) (reduction uint64, pct float64, ok bool) {
+	if len(history) < 2 {
+		return 0, 0, false
+	}
+
+	first, last := history[0].Price, history[len(history)-1].Price
+	if last >= first || first == 0 {
+		return 0, 0, false
+	}
+
+	reduction = first - last
+	return reduction, 100 * float64(reduction) / float64(first), true
+}
+
+// firstReductionDates returns when a listing was listed and first reduced.
+// The price history is preferred, falling back to the card's dates, which
+// only show the latest reduction.
+func firstReductionDates(l *listing) (listed, reduced *time.Time) {
+	listed = l.ListedOn
+	if len(l.PriceHistory) > 0 && l.PriceHistory[0].Date != nil {
+		listed = l.PriceHistory[0].Date
+	}
+
+	for i := 1; i < len(l.PriceHistory); i++ {
+		p := l.PriceHistory[i]
+		if p.Price < l.PriceHistory[i-1].Price && p.Date != nil {
+			return listed, p.Date
+		}
+	}
+
+	return listed, l.ReducedOn
+}
+
+func (s marketSoftness) reducedShare() float64 {
+	if s.listings == 0 {
+		return 0
+	}
+
+	return 100 * float64(s.reduced) / float64(s.listings)
+}
+
+func meanFloat(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / float64(len(values))
+}
+
+func (s marketSoftness) String() string {
+	if s.listings == 0 {
+		return "no listings"
+	}
+
+	str := fmt.Sprintf("%d of %d listings reduced (%.0f%%)", s.reduced, s.listings, s.reducedShare())
+	if n := len(s.reductions); n > 0 {
+		str += fmt.Sprintf(", reduction over %d with price history: mean = %.0f (%.1f%%), median = %.0f (%.1f%%)",
+			n, calculateMean(s.reductions), meanFloat(s.reductionPct),
+			calculateMedian(s.reductions), floatQuantile(s.reductionPct, 0.5))
+	}
+	if n := len(s.daysToReduction); n > 0 {
+		str += fmt.Sprintf(", mean %.0f days to first reduction over %d with dates", calculateMean(s.daysToReduction), n)
+	}
+
+	return str
+}
+
+type softnessReduction struct {
+	Count     int     `json:"count"`
+	Mean      float64 `json:"mean"`
+	Median    float64 `json:"median"`
+	MeanPct   float64 `json:"mean_pct"`
+	MedianPct float64 `json:"median_pct"`
+}
+
+type softnessDays struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+}
+
+func (s marketSoftness) reductionSummary() *softnessReduction {
+	if len(s.reductions) == 0 {
+		return nil
+	}
+
+	return &softnessReduction{
+		Count:     len(s.reductions),
+		Mean:      calculateMean(s.reductions),
+		Median:    calculateMedian(s.reductions),
+		MeanPct:   meanFloat(s.reductionPct),
+		MedianPct: floatQuantile(s.reductionPct, 0.5),
+	}
+}
+
+func (s marketSoftness) daysSummary() *softnessDays {
+	if len(s.daysToReduction) == 0 {
+		return nil
+	}
+
+	return &softnessDays{Count: len(s.daysToReduction), Mean: calculateMean(s.daysToReduction)}
+}
+
+// MarshalJSON gives each figure its count, which is the denominator it was
+// calculated over.
+func (s marketSoftness) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Listings             int                `json:"listings"`
+		Reduced              int                `json:"reduced"`
+		ReducedShare         float64            `json:"reduced_share"`
+		Reduction            *softnessReduction `json:"reduction,omitempty"`
+		DaysToFirstReduction *softnessDays      `json:"days_to_first_reduction,omitempty"`
+	}{s.listings, s.reduced, s.reducedShare(), s.reductionSummary(), s.daysSummary()})
+}
@@ -0,0 +1,187 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func datePtr(y int, m time.Month, d int) *time.Time {
+	t := date(y, m, d)
+	return &t
+}
+
+func softnessListings() []listing {
+	return []listing{
+		{ID: "cut", PriceHistory: []pricePoint{
+			{Date: datePtr(2021, time.January, 1), Price: 500000},
+			{Date: datePtr(2021, time.February, 1), Price: 450000},
+		}},
+		{ID: "badged", IsReduced: true, ListedOn: datePtr(2021, time.January, 1), ReducedOn: datePtr(2021, time.January, 11)},
+		{ID: "raised then cut", PriceHistory: []pricePoint{
+			{Date: datePtr(2021, time.March, 1), Price: 400000},
+			{Price: 420000},
+			{Date: datePtr(2021, time.April, 1), Price: 380000},
+		}},
+		{ID: "raised", PriceHistory: []pricePoint{{Price: 300000}, {Price: 310000}}},
+		{ID: "badged without dates", IsReduced: true},
+	}
+}
+
+func TestCalculateMarketSoftness(t *testing.T) {
+	s := calculateMarketSoftness(softnessListings())
+
+	if s.listings != 5 || s.reduced != 4 || !closeTo(s.reducedShare(), 80) {
+		t.Errorf("got %d of %d reduced", s.reduced, s.listings)
+	}
+	if !equalUint64s(s.reductions, []uint64{50000, 20000}) || !equalFloats(s.reductionPct, []float64{5, 10}) {
+		t.Errorf("got reductions %v, %v", s.reductions, s.reductionPct)
+	}
+	if !equalUint64s(s.daysToReduction, []uint64{31, 10, 31}) {
+		t.Errorf("got days to reduction %v", s.daysToReduction)
+	}
+
+	want := "4 of 5 listings reduced (80%), reduction over 2 with price history: mean = 35000 (7.5%), median = 35000 (7.5%)" +
+		", mean 24 days to first reduction over 3 with dates"
+	if got := s.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestHistoryReduction(t *testing.T) {
+	tests := []struct {
+		name      string
+		history   []pricePoint
+		reduction uint64
+		pct       float64
+		ok        bool
+	}{
+		{"none", nil, 0, 0, false},
+		{"single", []pricePoint{{Price: 400000}}, 0, 0, false},
+		{"cut", []pricePoint{{Price: 400000}, {Price: 300000}}, 100000, 25, true},
+		{"raised", []pricePoint{{Price: 400000}, {Price: 450000}}, 0, 0, false},
+		{"unchanged", []pricePoint{{Price: 400000}, {Price: 350000}, {Price: 400000}}, 0, 0, false},
+		{"no first price", []pricePoint{{}, {Price: 0}}, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reduction, pct, ok := historyReduction(tt.history)
+			if reduction != tt.reduction || !closeTo(pct, tt.pct) || ok != tt.ok {
+				t.Errorf("got %d, %v, %v, want %d, %v, %v", reduction, pct, ok, tt.reduction, tt.pct, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFirstReductionDates(t *testing.T) {
+	listings := softnessListings()
+	tests := []struct {
+		l       listing
+		listed  *time.Time
+		reduced *time.Time
+	}{
+		{listings[0], datePtr(2021, time.January, 1), datePtr(2021, time.February, 1)},
+		{listings[1], datePtr(2021, time.January, 1), datePtr(2021, time.January, 11)},
+		{listings[2], datePtr(2021, time.March, 1), datePtr(2021, time.April, 1)},
+		{listings[3], nil, nil},
+		// The history has no dates, so the card's are used.
+		{listing{ListedOn: datePtr(2021, time.May, 1), ReducedOn: datePtr(2021, time.May, 5),
+			PriceHistory: []pricePoint{{Price: 2}, {Price: 1}}}, datePtr(2021, time.May, 1), datePtr(2021, time.May, 5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.l.ID, func(t *testing.T) {
+			listed, reduced := firstReductionDates(&tt.l)
+			if !equalTimePtr(listed, tt.listed) || !equalTimePtr(reduced, tt.reduced) {
+				t.Errorf("got %v, %v, want %v, %v", listed, reduced, tt.listed, tt.reduced)
+			}
+		})
+	}
+}
+
+func TestMarketSoftnessReducedBeforeListed(t *testing.T) {
+	l := listing{IsReduced: true, ListedOn: datePtr(2021, time.May, 5), ReducedOn: datePtr(2021, time.May, 1)}
+	if s := calculateMarketSoftness([]listing{l}); s.daysToReduction != nil {
+		t.Errorf("got days to reduction %v for a reduction before listing", s.daysToReduction)
+	}
+}
+
+func TestMarketSoftnessString(t *testing.T) {
+	tests := []struct {
+		name     string
+		listings []listing
+		want     string
+	}{
+		{"none", nil, "no listings"},
+		{"none reduced", softnessListings()[3:4], "0 of 1 listings reduced (0%)"},
+		{"badged only", softnessListings()[4:], "1 of 1 listings reduced (100%)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calculateMarketSoftness(tt.listings).String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarketSoftnessJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		listings []listing
+		want     string
+	}{
+		{
+			name:     "full",
+			listings: softnessListings(),
+			want: `{"listings":5,"reduced":4,"reduced_share":80,` +
+				`"reduction":{"count":2,"mean":35000,"median":35000,"mean_pct":7.5,"median_pct":7.5},` +
+				`"days_to_first_reduction":{"count":3,"mean":24}}`,
+		},
+		{
+			name:     "badged only",
+			listings: softnessListings()[4:],
+			want:     `{"listings":1,"reduced":1,"reduced_share":100}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(calculateMarketSoftness(tt.listings))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("got %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func equalUint64s(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !closeTo(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,78 @@
+package zoopla
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var sqlDialects = map[string]*sqlDialect{
+	"postgres": postgresDialect,
+	"sqlite":   sqliteDialect,
+}
+
+const defaultSQLDialect = "postgres"
+
+// marshalSQLDump writes a script that creates the store's tables if needed
+// and inserts the run and its listings, for loading with psql -f or sqlite3.
+// Values are inlined as literals rather than bound, so the script stands
+// alone.
+func marshalSQLDump(listings []listing, args *cliArgs) ([]byte, error) {
+	dialect := sqlDialects[args.SQLDialect]
+	if dialect == nil {
+		dialect = sqlDialects[defaultSQLDialect]
+	}
+
+	redacted := redactedArgs(*args)
+	r, err := newRunRecord(&redacted)
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- %s dump of %d listings for %s\n", dialect.name, len(listings), args.Postcode)
+	b.WriteString("BEGIN;\n\n")
+	for _, m := range dialect.migrations {
+		b.WriteString(strings.TrimSpace(m))
+		b.WriteString(";\n\n")
+	}
+
+	fmt.Fprintf(&b, "INSERT INTO runs (timestamp, postcode, params_json) VALUES (%s, %s, %s);\n\n",
+		sqlLiteral(r.Timestamp.Format(time.RFC3339)), sqlLiteral(r.Postcode), sqlLiteral(r.ParamsJSON))
+
+	// Both backends see the run just inserted as the newest, and neither
+	// offers a portable way to capture its ID in a variable.
+	columns := strings.Join(listingColumns, ", ")
+	for _, l := range lastByID(listings) {
+		values := listingValues(&l)
+		literals := make([]string, len(values))
+		for i, v := range values {
+			literals[i] = sqlLiteral(v)
+		}
+		fmt.Fprintf(&b, "INSERT INTO listings (%s) VALUES ((SELECT MAX(id) FROM runs), %s);\n", columns, strings.Join(literals, ", "))
+	}
+
+	b.WriteString("\nCOMMIT;\n")
+
+	return []byte(b.String()), nil
+}
+
+// sqlLiteral renders v as a standard SQL literal. Strings double their
+// single quotes, which both PostgreSQL and SQLite accept, and NUL bytes,
+// which neither can store in text, are dropped.
+func sqlLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		v = strings.ReplaceAll(v, "\x00", "")
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return sqlLiteral(fmt.Sprint(v))
+	}
+}
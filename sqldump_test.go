@@ -0,0 +1,132 @@
+package zoopla
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLLiteral(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+		want string
+	}{
+		{"nil", nil, "NULL"},
+		{"string", "Acre Lane", "'Acre Lane'"},
+		{"empty string", "", "''"},
+		{"quote", "King's Avenue", "'King''s Avenue'"},
+		{"injection", "'); DROP TABLE runs; --", "'''); DROP TABLE runs; --'"},
+		{"NUL", "a\x00b", "'ab'"},
+		{"uint64", uint64(450000), "450000"},
+		{"int64", int64(-2), "-2"},
+		{"other", 1.5, "'1.5'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sqlLiteral(tt.v); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalSQLDump(t *testing.T) {
+	setNow(t, time.Date(2021, time.May, 10, 9, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		dialect string
+		header  string
+		schema  string
+	}{
+		{"", "-- PostgreSQL dump of 2 listings for SW2\n", "BIGSERIAL"},
+		{"postgres", "-- PostgreSQL dump of 2 listings for SW2\n", "BIGSERIAL"},
+		{"sqlite", "-- SQLite dump of 2 listings for SW2\n", "AUTOINCREMENT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect, func(t *testing.T) {
+			args := cliArgs{Postcode: "SW2", SQLDialect: tt.dialect, SMTPPass: "smtp-secret"}
+			data, err := marshalSQLDump([]listing{{ID: "1", Price: 450000}, {ID: "2", Price: 400000}}, &args)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := string(data)
+			if !strings.HasPrefix(got, tt.header+"BEGIN;\n") || !strings.HasSuffix(got, "\nCOMMIT;\n") {
+				t.Errorf("got a dump not wrapped in a transaction:\n%s", got)
+			}
+			if !strings.Contains(got, tt.schema) {
+				t.Errorf("dump has no %q:\n%s", tt.schema, got)
+			}
+			if n := strings.Count(got, "INSERT INTO listings "); n != 2 {
+				t.Errorf("got %d listing inserts, want 2", n)
+			}
+			if strings.Contains(got, "smtp-secret") {
+				t.Error("dump has a secret")
+			}
+		})
+	}
+}
+
+func TestSQLDumpLoadsIntoSQLite(t *testing.T) {
+	args := cliArgs{Postcode: "SW2", SQLDialect: "sqlite"}
+	listings := []listing{
+		{ID: "1", Price: 450000, Beds: uint32Ptr(2), Address: "King's Avenue, SW4", Qualifier: qualifierOffersOver},
+		{ID: "2", Price: 400000},
+		{ID: "1", Price: 440000, Beds: uint32Ptr(2), Address: "King's Avenue, SW4"},
+		{Price: 300000},
+	}
+	dump, err := marshalSQLDump(listings, &args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "dump.db")+"?_foreign_keys=on")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Loading the dump twice appends a second run, as the store would.
+	for i := 0; i < 2; i++ {
+		if _, err := db.Exec(string(dump)); err != nil {
+			t.Fatalf("loading dump %d: %v", i+1, err)
+		}
+	}
+
+	count := func(query string) int {
+		t.Helper()
+
+		var n int
+		if err := db.QueryRow(query).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if n := count(`SELECT COUNT(*) FROM runs WHERE postcode = 'SW2'`); n != 2 {
+		t.Errorf("got %d runs, want 2", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM listings WHERE run_id = 2`); n != 3 {
+		t.Errorf("got %d listings in the second run, want 3", n)
+	}
+	// The repeated listing keeps its last price, as it does in the store.
+	if n := count(`SELECT price FROM listings WHERE run_id = 1 AND listing_id = '1'`); n != 440000 {
+		t.Errorf("got price %d for the repeated listing, want 440000", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM listings WHERE listing_id IS NULL AND beds IS NULL`); n != 2 {
+		t.Errorf("got %d listings without an ID or beds, want 2", n)
+	}
+
+	var address string
+	if err := db.QueryRow(`SELECT address FROM listings WHERE listing_id = '1' LIMIT 1`).Scan(&address); err != nil {
+		t.Fatal(err)
+	}
+	if address != "King's Avenue, SW4" {
+		t.Errorf("got address %q", address)
+	}
+}
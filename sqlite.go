@@ -0,0 +1,55 @@
+package zoopla
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var sqliteDialect = &sqlDialect{
+	name: "SQLite",
+	migrations: []string{
+		`CREATE TABLE IF NOT EXISTS runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp TEXT NOT NULL,
+			postcode TEXT NOT NULL,
+			params_json TEXT NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS listings (
+			run_id INTEGER NOT NULL REFERENCES runs(id),
+			listing_id TEXT,
+			price INTEGER NOT NULL,
+			qualifier TEXT,
+			beds INTEGER,
+			baths INTEGER,
+			property_type TEXT,
+			address TEXT,
+			outcode TEXT,
+			agent TEXT,
+			url TEXT
+		);
+		CREATE INDEX IF NOT EXISTS listings_run_id ON listings(run_id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS listings_run_listing ON listings(run_id, listing_id)`,
+	},
+	placeholder: func(n int) string { return "?" + strconv.Itoa(n) },
+	insertRun: func(ctx context.Context, tx *sql.Tx, r *runRecord) (int64, error) {
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO runs (timestamp, postcode, params_json) VALUES (?, ?, ?)`,
+			r.Timestamp.Format(time.RFC3339), r.Postcode, r.ParamsJSON,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		return res.LastInsertId()
+	},
+	// Older SQLite builds allow at most 999 bind parameters per statement.
+	batchSize: 999 / len(listingColumns),
+}
+
+func openSQLiteStore(ctx context.Context, filename string) (*sqlStore, error) {
+	return openSQLStore(ctx, "sqlite3", "file:"+filename+"?_foreign_keys=on", sqliteDialect)
+}
@@ -0,0 +1,88 @@
+package zoopla
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "prices.db")
+	spec := "sqlite:" + filename
+	ctx := context.Background()
+
+	first := []listing{
+		{ID: "1", Price: 450000, Beds: uint32Ptr(2), Address: "Acre Lane, Brixton SW2"},
+		{ID: "2", Price: 400000},
+		{ID: "1", Price: 440000, Beds: uint32Ptr(2)},
+		{Price: 300000},
+	}
+	second := []listing{{ID: "1", Price: 430000}, {ID: "3", Price: 500000}}
+
+	if err := saveToStore(ctx, spec, &cliArgs{Postcode: "SW2", SMTPPass: "smtp-secret"}, first); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveToStore(ctx, spec, &cliArgs{Postcode: "SW9"}, second); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	count := func(query string, args ...interface{}) int {
+		t.Helper()
+
+		var n int
+		if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if n := count(`SELECT COUNT(*) FROM runs`); n != 2 {
+		t.Errorf("got %d runs, want 2", n)
+	}
+	// The repeated listing 1 keeps its last price.
+	if n := count(`SELECT COUNT(*) FROM listings WHERE run_id = 1`); n != 3 {
+		t.Errorf("got %d listings in the first run, want 3", n)
+	}
+	if n := count(`SELECT price FROM listings WHERE run_id = 1 AND listing_id = '1'`); n != 440000 {
+		t.Errorf("got price %d for the repeated listing, want 440000", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM listings WHERE run_id = 2`); n != 2 {
+		t.Errorf("got %d listings in the second run, want 2", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM listings WHERE listing_id IS NULL`); n != 1 {
+		t.Errorf("got %d listings without an ID, want 1", n)
+	}
+	if n := count(`SELECT COUNT(*) FROM runs WHERE params_json LIKE '%smtp-secret%'`); n != 0 {
+		t.Error("run parameters stored a secret")
+	}
+
+	// Every listing belongs to a run, and the store enforces it.
+	if n := count(`SELECT COUNT(*) FROM listings l LEFT JOIN runs r ON l.run_id = r.id WHERE r.id IS NULL`); n != 0 {
+		t.Errorf("got %d listings without a run", n)
+	}
+	s, err := openSQLiteStore(ctx, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, err := s.db.Exec(`INSERT INTO listings (run_id, price) VALUES (99, 1)`); err == nil {
+		t.Error("inserted a listing for a run that doesn't exist")
+	}
+	if n := count(`SELECT version FROM schema_version`); n != len(sqliteDialect.migrations) {
+		t.Errorf("got schema version %d, want %d", n, len(sqliteDialect.migrations))
+	}
+}
+
+func TestLastByID(t *testing.T) {
+	listings := []listing{{ID: "1", Price: 1}, {Price: 2}, {ID: "1", Price: 3}, {Price: 4}, {ID: "2", Price: 5}}
+	if got := listingPrices(lastByID(listings)); !equalPrices(got, []uint64{3, 2, 4, 5}) {
+		t.Errorf("lastByID() prices = %v, want [3 2 4 5]", got)
+	}
+}
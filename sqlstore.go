@@ -0,0 +1,213 @@
+package zoopla
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const storeTimeout = time.Minute
+
+// sqlDialect holds what differs between the SQL backends. Everything else
+// about mapping runs and listings to rows lives in sqlStore.
+type sqlDialect struct {
+	name string
+
+	// migrations are applied in order, each one once, and the number
+	// applied is recorded in the schema_version table.
+	migrations []string
+
+	// placeholder returns the bind parameter for the 1-based argument n.
+	placeholder func(n int) string
+
+	// insertRun inserts a run and returns its ID.
+	insertRun func(ctx context.Context, tx *sql.Tx, r *runRecord) (int64, error)
+
+	// batchSize is how many listings go in each INSERT, kept under the
+	// backend's limit on bind parameters.
+	batchSize int
+}
+
+var listingColumns = []string{
+	"run_id", "listing_id", "price", "qualifier", "beds", "baths",
+	"property_type", "address", "outcode", "agent", "url",
+}
+
+type sqlStore struct {
+	db      *sql.DB
+	dialect *sqlDialect
+}
+
+func openSQLStore(ctx context.Context, driver, dsn string, dialect *sqlDialect) (*sqlStore, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while opening %s database", dialect.name)
+	}
+
+	s := &sqlStore{db: db, dialect: dialect}
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *sqlStore) migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return errors.Wrap(err, "while creating schema_version table")
+	}
+
+	var version int
+	err := s.db.QueryRowContext(ctx, `SELECT version FROM schema_version`).Scan(&version)
+	if err == sql.ErrNoRows {
+		if _, err := s.db.ExecContext(ctx, `INSERT INTO schema_version (version) VALUES (0)`); err != nil {
+			return errors.Wrap(err, "while initialising schema_version")
+		}
+	} else if err != nil {
+		return errors.Wrap(err, "while reading schema version")
+	}
+
+	for i := version; i < len(s.dialect.migrations); i++ {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return errors.Wrap(err, "while starting migration")
+		}
+
+		if _, err := tx.ExecContext(ctx, s.dialect.migrations[i]); err != nil {
+			tx.Rollback()
+			return errors.Wrapf(err, "while applying schema migration %d", i+1)
+		}
+
+		stmt := fmt.Sprintf(`UPDATE schema_version SET version = %s`, s.dialect.placeholder(1))
+		if _, err := tx.ExecContext(ctx, stmt, i+1); err != nil {
+			tx.Rollback()
+			return errors.Wrap(err, "while updating schema version")
+		}
+
+		if err := tx.Commit(); err != nil {
+			return errors.Wrapf(err, "while committing schema migration %d", i+1)
+		}
+	}
+
+	return nil
+}
+
+func (s *sqlStore) saveRun(ctx context.Context, r *runRecord, listings []listing) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "while starting transaction")
+	}
+	defer tx.Rollback()
+
+	runID, err := s.dialect.insertRun(ctx, tx, r)
+	if err != nil {
+		return errors.Wrap(err, "while inserting run")
+	}
+
+	listings = lastByID(listings)
+
+	for start := 0; start < len(listings); start += s.dialect.batchSize {
+		end := start + s.dialect.batchSize
+		if end > len(listings) {
+			end = len(listings)
+		}
+
+		stmt, args := s.listingsInsert(runID, listings[start:end])
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return errors.Wrap(err, "while inserting listings")
+		}
+	}
+
+	return errors.Wrap(tx.Commit(), "while committing run")
+}
+
+// lastByID drops all but the last of any listings sharing an ID, such as a
+// featured listing repeated on a later page. PostgreSQL refuses to upsert the
+// same row twice in one statement, so they can't be left to ON CONFLICT.
+func lastByID(listings []listing) []listing {
+	index := make(map[string]int)
+	var unique []listing
+	for _, l := range listings {
+		if i, ok := index[l.ID]; ok && l.ID != "" {
+			unique[i] = l
+			continue
+		}
+
+		index[l.ID] = len(unique)
+		unique = append(unique, l)
+	}
+
+	return unique
+}
+
+// listingsInsert builds a multi-row upsert for listings, so that saving a run
+// again updates its rows rather than failing.
+func (s *sqlStore) listingsInsert(runID int64, listings []listing) (string, []interface{}) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO listings (%s) VALUES ", strings.Join(listingColumns, ", "))
+
+	args := make([]interface{}, 0, len(listings)*len(listingColumns))
+	for i := range listings {
+		l := &listings[i]
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteString("(")
+		for j := range listingColumns {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(s.dialect.placeholder(len(args) + j + 1))
+		}
+		b.WriteString(")")
+
+		args = append(args, runID)
+		args = append(args, listingValues(l)...)
+	}
+
+	b.WriteString(" ON CONFLICT (run_id, listing_id) DO UPDATE SET ")
+	for i, c := range listingColumns[2:] {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s = excluded.%s", c, c)
+	}
+
+	return b.String(), args
+}
+
+// listingValues returns the values for every listing column after run_id.
+func listingValues(l *listing) []interface{} {
+	return []interface{}{
+		nullableString(l.ID), l.Price, l.Qualifier, nullableCount(l.Beds), nullableCount(l.Baths),
+		l.PropertyType, l.Address, l.Outcode, l.Agent, l.URL,
+	}
+}
+
+func (s *sqlStore) Close() error {
+	return s.db.Close()
+}
+
+func nullableCount(n *uint32) interface{} {
+	if n == nil {
+		return nil
+	}
+
+	return int64(*n)
+}
+
+// nullableString maps an empty string to NULL, so that listings without an ID
+// never conflict with each other.
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
@@ -0,0 +1,166 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	buyerFirstTime  = "first-time"
+	buyerHomeMover  = "home-mover"
+	buyerAdditional = "additional"
+)
+
+var buyerTypes = []string{buyerFirstTime, buyerHomeMover, buyerAdditional}
+
+// sdltBand charges percent of the part of the price above the previous
+// band's upper limit up to this one's. The last band has no upper limit.
+type sdltBand struct {
+	upTo    uint64
+	percent uint64
+}
+
+// sdltRates are the England and Northern Ireland stamp duty land tax rates
+// for residential property from 1 April 2025.
+var sdltRates = struct {
+	standard []sdltBand
+	// firstTime applies to first-time buyers paying no more than
+	// firstTimeCap, who otherwise pay the standard rates.
+	firstTime    []sdltBand
+	firstTimeCap uint64
+	// additionalSurcharge is added to every standard band for additional
+	// properties costing at least additionalMin.
+	additionalSurcharge uint64
+	additionalMin       uint64
+}{
+	standard: []sdltBand{
+		{125000, 0},
+		{250000, 2},
+		{925000, 5},
+		{1500000, 10},
+		{0, 12},
+	},
+	firstTime: []sdltBand{
+		{300000, 0},
+		{500000, 5},
+	},
+	firstTimeCap:        500000,
+	additionalSurcharge: 5,
+	additionalMin:       40000,
+}
+
+// stampDuty calculates the SDLT a buyer would pay for a residential property
+// at price, rounded down to the pound.
+func stampDuty(price uint64, buyer string) uint64 {
+	switch buyer {
+	case buyerFirstTime:
+		if price <= sdltRates.firstTimeCap {
+			return bandedTax(price, sdltRates.firstTime, 0)
+		}
+	case buyerAdditional:
+		if price >= sdltRates.additionalMin {
+			return bandedTax(price, sdltRates.standard, sdltRates.additionalSurcharge)
+		}
+	}
+
+	return bandedTax(price, sdltRates.standard, 0)
+}
+
+func bandedTax(price uint64, bands []sdltBand, surcharge uint64) uint64 {
+	// Sum the tax in hundredths of a pound so that only the total is
+	// rounded.
+	var hundredths, lower uint64
+	for _, b := range bands {
+		if price <= lower {
+			break
+		}
+
+		upper := price
+		if b.upTo != 0 && b.upTo < price {
+			upper = b.upTo
+		}
+		hundredths += (upper - lower) * (b.percent + surcharge)
+		lower = b.upTo
+		if b.upTo == 0 {
+			break
+		}
+	}
+
+	return hundredths / 100
+}
+
+// assignStampDuty sets each listing's stamp duty and total cost for buyer.
+func assignStampDuty(listings []listing, buyer string) {
+	for i := range listings {
+		duty := stampDuty(listings[i].Price, buyer)
+		total := listings[i].Price + duty
+		listings[i].StampDuty = &duty
+		listings[i].TotalCost = &total
+	}
+}
+
+func validBuyerType(buyer string) bool {
+	for _, b := range buyerTypes {
+		if b == buyer {
+			return true
+		}
+	}
+
+	return false
+}
+
+func buyerTypeList() string {
+	return strings.Join(buyerTypes, ", ")
+}
+
+type stampDutyStats struct {
+	buyer           string
+	count           int
+	meanDuty        float64
+	meanTotalCost   float64
+	medianTotalCost float64
+}
+
+// calculateStampDutyStats summarises the stamp duty and total costs that
+// assignStampDuty set on listings.
+func calculateStampDutyStats(listings []listing, buyer string) stampDutyStats {
+	s := stampDutyStats{buyer: buyer}
+
+	var duties, totals []uint64
+	for i := range listings {
+		if listings[i].StampDuty == nil || listings[i].TotalCost == nil {
+			continue
+		}
+		duties = append(duties, *listings[i].StampDuty)
+		totals = append(totals, *listings[i].TotalCost)
+	}
+
+	s.count = len(totals)
+	if s.count == 0 {
+		return s
+	}
+
+	sort.Slice(totals, func(i, j int) bool { return totals[i] < totals[j] })
+	s.meanDuty = calculateMean(duties)
+	s.meanTotalCost = calculateMean(totals)
+	s.medianTotalCost = quantile(totals, 0.5)
+
+	return s
+}
+
+func (s stampDutyStats) String() string {
+	return fmt.Sprintf("%s buyer: mean stamp duty = %.0f, mean total cost = %.0f, median total cost = %.0f",
+		s.buyer, s.meanDuty, s.meanTotalCost, s.medianTotalCost)
+}
+
+func (s stampDutyStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Buyer           string  `json:"buyer"`
+		Count           int     `json:"count"`
+		MeanStampDuty   float64 `json:"mean_stamp_duty"`
+		MeanTotalCost   float64 `json:"mean_total_cost"`
+		MedianTotalCost float64 `json:"median_total_cost"`
+	}{s.buyer, s.count, s.meanDuty, s.meanTotalCost, s.medianTotalCost})
+}
@@ -0,0 +1,111 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestStampDuty(t *testing.T) {
+	tests := []struct {
+		price uint64
+		buyer string
+		want  uint64
+	}{
+		{0, buyerHomeMover, 0},
+		{125000, buyerHomeMover, 0},
+		{125049, buyerHomeMover, 0},
+		{125050, buyerHomeMover, 1},
+		{250000, buyerHomeMover, 2500},
+		{300000, buyerHomeMover, 5000},
+		{925000, buyerHomeMover, 36250},
+		{1500000, buyerHomeMover, 93750},
+		{2000000, buyerHomeMover, 153750},
+
+		{300000, buyerFirstTime, 0},
+		{400000, buyerFirstTime, 5000},
+		{500000, buyerFirstTime, 10000},
+		// Over the cap first-time buyers lose the relief entirely.
+		{500001, buyerFirstTime, 15000},
+
+		{39999, buyerAdditional, 0},
+		{40000, buyerAdditional, 2000},
+		{300000, buyerAdditional, 20000},
+		{2000000, buyerAdditional, 253750},
+
+		{300000, "", 5000},
+	}
+
+	for _, tt := range tests {
+		if got := stampDuty(tt.price, tt.buyer); got != tt.want {
+			t.Errorf("stampDuty(%d, %q) = %d, want %d", tt.price, tt.buyer, got, tt.want)
+		}
+	}
+}
+
+func TestAssignStampDuty(t *testing.T) {
+	listings := []listing{{ID: "1", Price: 300000}, {ID: "2", Price: 100000}}
+	assignStampDuty(listings, buyerHomeMover)
+
+	tests := []struct {
+		duty, total uint64
+	}{
+		{5000, 305000},
+		{0, 100000},
+	}
+	for i, tt := range tests {
+		l := listings[i]
+		if l.StampDuty == nil || l.TotalCost == nil || *l.StampDuty != tt.duty || *l.TotalCost != tt.total {
+			t.Errorf("listing %s: got stamp duty %v, total %v, want %d, %d", l.ID, l.StampDuty, l.TotalCost, tt.duty, tt.total)
+		}
+	}
+}
+
+func TestValidBuyerType(t *testing.T) {
+	for _, b := range buyerTypes {
+		if !validBuyerType(b) {
+			t.Errorf("%q is invalid", b)
+		}
+	}
+	for _, b := range []string{"", "first time", "investor"} {
+		if validBuyerType(b) {
+			t.Errorf("%q is valid", b)
+		}
+	}
+	if got, want := buyerTypeList(), "first-time, home-mover, additional"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCalculateStampDutyStats(t *testing.T) {
+	listings := []listing{{ID: "1", Price: 300000}, {ID: "2", Price: 250000}, {ID: "3", Price: 100000}, {ID: "4", Price: 1}}
+	assignStampDuty(listings[:3], buyerHomeMover)
+
+	s := calculateStampDutyStats(listings, buyerHomeMover)
+	if s.count != 3 || !closeTo(s.meanDuty, 2500) || !closeTo(s.meanTotalCost, 219166.66666667) || !closeTo(s.medianTotalCost, 252500) {
+		t.Errorf("got %+v", s)
+	}
+
+	want := "home-mover buyer: mean stamp duty = 2500, mean total cost = 219167, median total cost = 252500"
+	if got := s.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	listings = listings[3:]
+	if s := calculateStampDutyStats(listings, buyerFirstTime); s.count != 0 || s.meanDuty != 0 {
+		t.Errorf("got %+v without stamp duty", s)
+	}
+}
+
+func TestStampDutyStatsJSON(t *testing.T) {
+	listings := []listing{{ID: "1", Price: 300000}}
+	assignStampDuty(listings, buyerAdditional)
+
+	data, err := json.Marshal(calculateStampDutyStats(listings, buyerAdditional))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"buyer":"additional","count":1,"mean_stamp_duty":20000,"mean_total_cost":320000,"median_total_cost":320000}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
@@ -0,0 +1,197 @@
+package zoopla
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const milesPerKm = 0.621371
+
+var (
+	stationRegexp = regexp.MustCompile(`(?i)^(.*?)\s*((?:\d+\s+)?\d+/\d+|(?:\d+\s*)?[¼½¾]|\d*\.?\d+)\s*(miles?|mi|km|kilometres?|m|metres?)\.?$`)
+
+	unicodeFractions = map[string]float64{"¼": 0.25, "½": 0.5, "¾": 0.75}
+)
+
+type station struct {
+	Name          string  `json:"name"`
+	DistanceMiles float64 `json:"distance_miles"`
+}
+
+func isTransportNode(n *html.Node) bool {
+	return n.Type == html.ElementNode && (hasTestID(n, "transport") || hasClass(n, "Transport"))
+}
+
+func isStationNode(n *html.Node) bool {
+	return n.Type == html.ElementNode && n.Data == "li"
+}
+
+func parseStations(card *html.Node) []station {
+	transport := findFirst(card, isTransportNode)
+	if transport == nil {
+		return nil
+	}
+
+	var texts []string
+	for _, item := range findAll(transport, isStationNode) {
+		texts = append(texts, joinedText(item))
+	}
+
+	return parseStationTexts(texts)
+}
+
+func parseStationTexts(texts []string) []station {
+	var stations []station
+	for _, text := range texts {
+		if s, ok := parseStation(text); ok {
+			stations = append(stations, s)
+		}
+	}
+
+	return stations
+}
+
+// parseStation parses chip text like "Clapham North 0.3 miles" or
+// "Brixton ½ mile".
+func parseStation(text string) (station, bool) {
+	m := stationRegexp.FindStringSubmatch(strings.Join(strings.Fields(text), " "))
+	if m == nil || m[1] == "" {
+		return station{}, false
+	}
+
+	distance, ok := parseDistance(m[2])
+	if !ok {
+		return station{}, false
+	}
+
+	switch unit := strings.ToLower(m[3]); {
+	case strings.HasPrefix(unit, "k"):
+		distance *= milesPerKm
+	case unit == "m" || strings.HasPrefix(unit, "metre"):
+		distance = distance / 1000 * milesPerKm
+	}
+
+	return station{Name: m[1], DistanceMiles: distance}, true
+}
+
+// parseDistance parses decimals ("0.3"), fractions ("1/2", "1 1/2") and
+// Unicode fractions ("½", "1½").
+func parseDistance(raw string) (float64, bool) {
+	raw = strings.TrimSpace(raw)
+
+	for symbol, value := range unicodeFractions {
+		if strings.HasSuffix(raw, symbol) {
+			whole := strings.TrimSpace(strings.TrimSuffix(raw, symbol))
+			if whole == "" {
+				return value, true
+			}
+			n, err := strconv.ParseFloat(whole, 64)
+			return n + value, err == nil
+		}
+	}
+
+	if i := strings.Index(raw, "/"); i >= 0 {
+		var whole float64
+		numerator := raw[:i]
+		if j := strings.LastIndex(numerator, " "); j >= 0 {
+			w, err := strconv.ParseFloat(numerator[:j], 64)
+			if err != nil {
+				return 0, false
+			}
+			whole, numerator = w, numerator[j+1:]
+		}
+
+		num, err1 := strconv.ParseFloat(numerator, 64)
+		den, err2 := strconv.ParseFloat(raw[i+1:], 64)
+		if err1 != nil || err2 != nil || den == 0 {
+			return 0, false
+		}
+		return whole + num/den, true
+	}
+
+	n, err := strconv.ParseFloat(raw, 64)
+	return n, err == nil
+}
+
+func nearestStation(l *listing) (station, bool) {
+	if len(l.Stations) == 0 {
+		return station{}, false
+	}
+
+	nearest := l.Stations[0]
+	for _, s := range l.Stations[1:] {
+		if s.DistanceMiles < nearest.DistanceMiles {
+			nearest = s
+		}
+	}
+
+	return nearest, true
+}
+
+// filterStationDistance keeps listings whose nearest station is within
+// maxMiles. Listings without any station information are dropped, since
+// they cannot be shown to be close enough.
+func filterStationDistance(listings []listing, maxMiles float64) []listing {
+	kept := listings[:0]
+	var tooFar, unknown int
+	for i := range listings {
+		nearest, ok := nearestStation(&listings[i])
+		switch {
+		case !ok:
+			unknown++
+		case nearest.DistanceMiles > maxMiles:
+			tooFar++
+		default:
+			kept = append(kept, listings[i])
+		}
+	}
+
+	log.Printf(
+		"excluded %d listings over %.2f miles from a station and %d without station data",
+		tooFar,
+		maxMiles,
+		unknown,
+	)
+
+	return kept
+}
+
+type stationStats struct {
+	withStations int
+	meanNearest  float64
+}
+
+func calculateStationStats(listings []listing) stationStats {
+	var stats stationStats
+	var sum float64
+	for i := range listings {
+		if nearest, ok := nearestStation(&listings[i]); ok {
+			stats.withStations++
+			sum += nearest.DistanceMiles
+		}
+	}
+
+	if stats.withStations > 0 {
+		stats.meanNearest = sum / float64(stats.withStations)
+	}
+
+	return stats
+}
+
+func (s stationStats) String() string {
+	if s.withStations == 0 {
+		return "no station data"
+	}
+
+	return fmt.Sprintf(
+		"mean distance to nearest station = %.2f miles over %d listings",
+		math.Round(s.meanNearest*100)/100,
+		s.withStations,
+	)
+}
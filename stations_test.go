@@ -0,0 +1,88 @@
+package zoopla
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseStation(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   station
+		wantOK bool
+	}{
+		{text: "Clapham North 0.3 miles", want: station{"Clapham North", 0.3}, wantOK: true},
+		{text: "Brixton ½ mile", want: station{"Brixton", 0.5}, wantOK: true},
+		{text: "Brixton 1½ miles", want: station{"Brixton", 1.5}, wantOK: true},
+		{text: "Herne Hill 1/4 mile", want: station{"Herne Hill", 0.25}, wantOK: true},
+		{text: "Herne Hill 1 1/2 miles", want: station{"Herne Hill", 1.5}, wantOK: true},
+		{text: "  Stockwell\n .5 mi. ", want: station{"Stockwell", 0.5}, wantOK: true},
+		{text: "Oval 1 km", want: station{"Oval", milesPerKm}, wantOK: true},
+		{text: "Oval 500 metres", want: station{"Oval", 0.5 * milesPerKm}, wantOK: true},
+		{text: "Oval 500m", want: station{"Oval", 0.5 * milesPerKm}, wantOK: true},
+		{text: "0.3 miles"},
+		{text: "Clapham North"},
+		{text: "Clapham North 1/0 miles"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, ok := parseStation(tt.text)
+			if ok != tt.wantOK || got.Name != tt.want.Name || math.Abs(got.DistanceMiles-tt.want.DistanceMiles) > 1e-9 {
+				t.Errorf("parseStation(%q) = %+v, %v, want %+v, %v", tt.text, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseStations(t *testing.T) {
+	card := `<div data-testid="search-result">
+		<ul data-testid="transport">
+			<li><span>Clapham North</span> <span>0.3 miles</span></li>
+			<li>Brixton ½ mile</li>
+			<li>Bus stop nearby</li>
+		</ul>
+	</div>`
+
+	want := []station{{"Clapham North", 0.3}, {"Brixton", 0.5}}
+	if got := parseStations(mustParseCard(t, card)); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseStations() = %+v, want %+v", got, want)
+	}
+
+	if got := parseStations(mustParseCard(t, `<div data-testid="search-result"><ul><li>Brixton ½ mile</li></ul></div>`)); got != nil {
+		t.Errorf("parseStations() without transport = %+v, want none", got)
+	}
+}
+
+func TestFilterStationDistance(t *testing.T) {
+	listings := []listing{
+		{ID: "near", Stations: []station{{"A", 1.2}, {"B", 0.4}}},
+		{ID: "far", Stations: []station{{"A", 1.2}}},
+		{ID: "unknown"},
+		{ID: "edge", Stations: []station{{"A", 0.5}}},
+	}
+
+	if got := ids(filterStationDistance(listings, 0.5)); !reflect.DeepEqual(got, []string{"near", "edge"}) {
+		t.Errorf("filterStationDistance() kept %v", got)
+	}
+}
+
+func TestCalculateStationStats(t *testing.T) {
+	listings := []listing{
+		{Stations: []station{{"A", 1.2}, {"B", 0.4}}},
+		{Stations: []station{{"A", 0.6}}},
+		{},
+	}
+
+	got := calculateStationStats(listings)
+	if got.withStations != 2 || math.Abs(got.meanNearest-0.5) > 1e-9 {
+		t.Errorf("calculateStationStats() = %+v", got)
+	}
+	if s := got.String(); s != "mean distance to nearest station = 0.50 miles over 2 listings" {
+		t.Errorf("String() = %q", s)
+	}
+	if s := calculateStationStats(nil).String(); s != "no station data" {
+		t.Errorf("String() without stations = %q", s)
+	}
+}
@@ -0,0 +1,213 @@
+package zoopla
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// statsArgs are the flags of the stats subcommand, which has none of the
+// search's since it makes no requests.
+type statsArgs struct {
+	Input                  string  `arg:"positional,required"`
+	StatsFile              string  `arg:"--stats-file"`
+	Pretty                 bool    `arg:"--pretty"`
+	Percentiles            string  `arg:"--percentiles"`
+	TrimmedMean            float64 `arg:"--trimmed-mean"`
+	Winsorize              float64 `arg:"--winsorize"`
+	MinGroupSize           int     `arg:"--min-group-size"`
+	ByBeds                 bool    `arg:"--by-beds"`
+	ByType                 bool    `arg:"--by-type"`
+	Histogram              bool    `arg:"--histogram"`
+	BucketSize             uint64  `arg:"--bucket-size"`
+	IncludeAuctionsInStats bool    `arg:"--include-auctions-in-stats"`
+	TopAgents              int     `arg:"--top-agents"`
+	StaleDays              int     `arg:"--stale-days"`
+	Stalest                int     `arg:"--stalest"`
+	ReportMD               string  `arg:"--report-md"`
+	ReportHTML             string  `arg:"--report-html"`
+	Quiet                  bool    `arg:"--quiet"`
+	NoColor                bool    `arg:"--no-color"`
+}
+
+// runFileStats recalculates the stats of a file written by an earlier run.
+// Price files only have the price stats, while listings files also get the
+// breakdowns that the listings' fields allow.
+func runFileStats(argv []string) error {
+	args := statsArgs{
+		MinGroupSize: defaultMinGroupSize,
+		TopAgents:    defaultTopAgents,
+		StaleDays:    defaultStaleDays,
+		Stalest:      defaultStalestTop,
+	}
+	p, err := parseSubcommand("stats", &args, argv)
+	if err != nil {
+		return err
+	}
+	setupLogColor(args.NoColor)
+	if err := validateMeanFraction("--trimmed-mean", args.TrimmedMean); err != nil {
+		return newUsageError(p, err.Error())
+	}
+	if err := validateMeanFraction("--winsorize", args.Winsorize); err != nil {
+		return newUsageError(p, err.Error())
+	}
+	if _, err := parsePercentiles(args.Percentiles); err != nil {
+		return newUsageError(p, err.Error())
+	}
+	if args.MinGroupSize < 0 {
+		return newUsageError(p, "--min-group-size must not be negative")
+	}
+	if args.StaleDays <= 0 {
+		return newUsageError(p, "--stale-days must be positive")
+	}
+	if args.BucketSize > 0 && !args.Histogram {
+		return newUsageError(p, "--bucket-size needs --histogram")
+	}
+
+	in, err := loadOutputFile(args.Input)
+	if err != nil {
+		return err
+	}
+	if in.Kind == outputKindHistory {
+		return errors.Errorf("%s is a run history, use trend for its stats", args.Input)
+	}
+
+	cli := args.cliArgs()
+	if in.Metadata != nil {
+		q := in.Metadata.Query
+		cli.Postcode, cli.PriceMin, cli.PriceMax, cli.BedsMin, cli.BedsMax, cli.Radius = q.Postcode, q.PriceMin, q.PriceMax, q.BedsMin, q.BedsMax, q.Radius
+	}
+
+	statsListings, _ := splitComingSoon(in.Listings)
+	statsPrices := in.Prices
+	if in.Kind == outputKindListings && !args.IncludeAuctionsInStats {
+		statsListings, _ = splitAuctions(statsListings)
+		statsPrices = listingPrices(statsListings)
+	}
+	log.Printf("read %d prices from %s", len(statsPrices), args.Input)
+	if len(statsPrices) == 0 {
+		return errors.Errorf("no prices in %s", args.Input)
+	}
+
+	out := statsOutput{Stats: calculatePriceStatsWith(statsPrices, newStatsOptions(&cli))}
+	log.Print("price stats: ", out.Stats)
+	if args.Histogram {
+		out.Histogram = calculateHistogram(statsPrices, args.BucketSize)
+		for _, b := range out.Histogram {
+			log.Print("histogram ", b)
+		}
+	}
+
+	if in.Kind == outputKindListings {
+		logListingStats(&out, statsListings, &cli)
+	}
+
+	if !args.Quiet {
+		rows := []summaryRow{newSummaryRow("all", statsPrices)}
+		if in.Kind == outputKindListings {
+			rows = summaryRows(statsListings, args.ByBeds, args.ByType, args.MinGroupSize, listingPrices)
+		}
+		fmt.Fprint(os.Stderr, "\n", renderSummaryTable(rows, logPainter))
+		if out.PricePerSqft != nil {
+			fmt.Fprint(os.Stderr, renderSqftSummary(*out.PricePerSqft))
+		}
+		if out.OnMarket != nil && out.OnMarket.dated > 0 {
+			fmt.Fprint(os.Stderr, renderOnMarketSummary(*out.OnMarket))
+		}
+	}
+
+	if args.StatsFile != "" {
+		if err := writeStatsFile(args.StatsFile, &cli, out); err != nil {
+			return err
+		}
+		log.Print("wrote stats to ", args.StatsFile)
+	}
+
+	if args.ReportMD != "" || args.ReportHTML != "" {
+		return writeReports(buildReport(statsListings, statsPrices, &cli), &cli)
+	}
+
+	return nil
+}
+
+// logListingStats adds the breakdowns of listings to out, logging each.
+func logListingStats(out *statsOutput, listings []listing, args *cliArgs) {
+	if groups := orderedGroupStats(listings, bedsKey, args.MinGroupSize); !onlyUnknownGroup(groups) {
+		out.Beds = groups
+		for _, g := range groups {
+			log.Print("beds ", g)
+		}
+	}
+
+	out.Types = reliableGroups(orderedGroupStats(listings, propertyTypeKey, args.MinGroupSize))
+	for _, g := range out.Types {
+		log.Print("type ", g)
+	}
+	if out.FlatsVsHouses = compareFlatsAndHouses(listings, args.MinGroupSize); out.FlatsVsHouses != nil {
+		log.Print("flats vs houses: ", *out.FlatsVsHouses)
+	}
+	if out.NewBuilds = compareNewBuilds(listings, args.MinGroupSize); out.NewBuilds != nil {
+		log.Print("new builds vs resales: ", *out.NewBuilds)
+	}
+
+	if args.TopAgents > 0 {
+		out.Agents = calculateAgentStats(listings, args.TopAgents, args.AgentMargin)
+		for _, a := range out.Agents {
+			log.Print("agent: ", a)
+		}
+	}
+
+	softness := calculateMarketSoftness(listings)
+	log.Print("market softness: ", softness)
+	out.Softness = &softness
+
+	onMarket := calculateTimeOnMarket(listings, timeNow(), args.StaleDays, args.Stalest)
+	log.Print("time on market: ", onMarket)
+	out.OnMarket = &onMarket
+
+	if perBed := calculatePricePerBedStats(listings); perBed.all.Count > 0 {
+		log.Print("price per bedroom: ", perBed)
+		out.PricePerBed = &perBed
+	}
+
+	if perSqft := calculatePricePerSqftStats(listings); perSqft.all.Count > 0 {
+		log.Print("price per sq ft: ", perSqft)
+		if perSqft.lowCoverage() {
+			log.Printf("warning: only %.0f%% of listings have a floor area, so price per sq ft may be biased towards new builds", 100*perSqft.coverage())
+		}
+		out.PricePerSqft = &perSqft
+	}
+}
+
+// cliArgs fills in the search flags the stats share, with the search's
+// defaults for the rest.
+func (a statsArgs) cliArgs() cliArgs {
+	return cliArgs{
+		StatsFile:              a.StatsFile,
+		Pretty:                 a.Pretty,
+		Percentiles:            a.Percentiles,
+		TrimmedMean:            a.TrimmedMean,
+		Winsorize:              a.Winsorize,
+		MinGroupSize:           a.MinGroupSize,
+		ByBeds:                 a.ByBeds,
+		ByType:                 a.ByType,
+		Histogram:              a.Histogram,
+		BucketSize:             a.BucketSize,
+		IncludeAuctionsInStats: a.IncludeAuctionsInStats,
+		TopAgents:              a.TopAgents,
+		StaleDays:              a.StaleDays,
+		Stalest:                a.Stalest,
+		ReportMD:               a.ReportMD,
+		ReportHTML:             a.ReportHTML,
+		Quiet:                  a.Quiet,
+		NoColor:                a.NoColor,
+
+		AgentMargin:        defaultAgentMargin,
+		CILevel:            defaultCILevel,
+		BootstrapResamples: defaultBootstrapResamples,
+		MinSample:          defaultMinSample,
+		MaxCV:              defaultMaxCV,
+	}
+}
@@ -0,0 +1,57 @@
+package zoopla
+
+import (
+	"regexp"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	statusForSale    = "for_sale"
+	statusComingSoon = "coming_soon"
+)
+
+var comingSoonRegexp = regexp.MustCompile(`(?i)\bcoming soon\b`)
+
+// isBadgeNode matches the badges and tags on a card, such as "Coming soon"
+// or "Reduced". Only these mark a card's status, since a description can
+// mention anything.
+func isBadgeNode(n *html.Node) bool {
+	return n.Type == html.ElementNode &&
+		(hasClass(n, "Badge") || hasClass(n, "Tag") || hasTestID(n, "listing-tag"))
+}
+
+// parseCardStatus returns the status shown by a card's badges.
+func parseCardStatus(card *html.Node) string {
+	var badges []string
+	for _, n := range findAll(card, isBadgeNode) {
+		badges = append(badges, textContent(n))
+	}
+
+	return parseStatus(badges)
+}
+
+// parseStatus returns the status given by the text of a card's badges.
+func parseStatus(badges []string) string {
+	for _, b := range badges {
+		if comingSoonRegexp.MatchString(b) {
+			return statusComingSoon
+		}
+	}
+
+	return statusForSale
+}
+
+// splitComingSoon separates pre-launch teaser listings, which have no real
+// price, from the rest.
+func splitComingSoon(listings []listing) (priced, comingSoon []listing) {
+	for i := range listings {
+		if listings[i].Status == statusComingSoon {
+			comingSoon = append(comingSoon, listings[i])
+		} else {
+			priced = append(priced, listings[i])
+		}
+	}
+
+	return priced, comingSoon
+}
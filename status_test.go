@@ -0,0 +1,90 @@
+package zoopla
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		badges []string
+		want   string
+	}{
+		{name: "no badges", want: statusForSale},
+		{name: "other badges", badges: []string{"Reduced", "New home"}, want: statusForSale},
+		{name: "coming soon", badges: []string{"Coming soon"}, want: statusComingSoon},
+		{name: "among others", badges: []string{"New home", "COMING SOON"}, want: statusComingSoon},
+		{name: "not a word", badges: []string{"Becoming soonest"}, want: statusForSale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseStatus(tt.badges); got != tt.want {
+				t.Errorf("parseStatus(%q) = %q, want %q", tt.badges, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListingCardComingSoon(t *testing.T) {
+	tests := []struct {
+		name string
+		card string
+	}{
+		{name: "placeholder price", card: `<div data-testid="search-result"><span class="Badge-r18">Coming soon</span><div class="PriceContainer"><p>£1</p></div></div>`},
+		{name: "no price", card: `<div data-testid="search-result"><span class="Badge-r18">Coming soon</span><div class="PriceContainer"><p>Price on application</p></div></div>`},
+		{name: "tag", card: `<div data-testid="search-result"><ul><li data-testid="listing-tag">Coming soon</li></ul></div>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := parseListingCard(mustParseCard(t, tt.card), saleSearch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l.Status != statusComingSoon || l.Price != 0 {
+				t.Errorf("got status %q, price %d, want %q, 0", l.Status, l.Price, statusComingSoon)
+			}
+		})
+	}
+}
+
+// TestParseCardStatusDescription checks that only a card's badges mark it
+// coming soon, so a priced card mentioning it elsewhere keeps its price
+// with both parsers.
+func TestParseCardStatusDescription(t *testing.T) {
+	card := `<div data-testid="search-result"><span class="Badge-r18">Reduced</span><h2>2 bed flat for sale</h2>` +
+		`<div class="PriceContainer"><p>£450,000</p></div><p>Stunning flat with a new kitchen coming soon</p></div>`
+
+	l, err := parseListingCard(mustParseCard(t, card), saleSearch)
+	if err != nil || l.Status != statusForSale || l.Price != 450000 {
+		t.Errorf("got status %q, price %d, %v, want a £450,000 listing for sale", l.Status, l.Price, err)
+	}
+
+	page := `<html><body><div class="ListingsContainer">` + card + `</div></body></html>`
+	streamed, _, err := parseStreaming(strings.NewReader(page), saleSearch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streamed) != 1 || streamed[0].Status != statusForSale || streamed[0].Price != 450000 {
+		t.Errorf("streamed %+v, want a £450,000 listing for sale", streamed)
+	}
+}
+
+func TestSplitComingSoon(t *testing.T) {
+	listings := []listing{
+		{ID: "1", Status: statusForSale},
+		{ID: "2", Status: statusComingSoon},
+		{ID: "3"},
+	}
+
+	priced, comingSoon := splitComingSoon(listings)
+	if got := ids(priced); !reflect.DeepEqual(got, []string{"1", "3"}) {
+		t.Errorf("priced = %v", got)
+	}
+	if got := ids(comingSoon); !reflect.DeepEqual(got, []string{"2"}) {
+		t.Errorf("coming soon = %v", got)
+	}
+}
@@ -0,0 +1,78 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// store is a sink that keeps the listings from every run, so history
+// accumulates across runs rather than being overwritten.
+type store interface {
+	saveRun(ctx context.Context, r *runRecord, listings []listing) error
+	Close() error
+}
+
+type runRecord struct {
+	Timestamp  time.Time
+	Postcode   string
+	ParamsJSON string
+}
+
+// newRunRecord records the run's parameters with their secrets redacted, as
+// the database may be shared more widely than the credentials.
+func newRunRecord(args *cliArgs) (*runRecord, error) {
+	params, err := json.Marshal(redactedArgs(*args))
+	if err != nil {
+		return nil, errors.Wrap(err, "while marshalling run parameters")
+	}
+
+	return &runRecord{
+		Timestamp:  timeNow().UTC(),
+		Postcode:   args.Postcode,
+		ParamsJSON: string(params),
+	}, nil
+}
+
+// openStore opens the store described by spec, which takes the form
+// "<backend>:<location>", e.g. "sqlite:prices.db", or is a PostgreSQL DSN
+// such as "postgres://user@host/db".
+func openStore(ctx context.Context, spec string) (store, error) {
+	if strings.HasPrefix(spec, "postgres://") || strings.HasPrefix(spec, "postgresql://") {
+		return openPostgresStore(ctx, spec)
+	}
+
+	i := strings.Index(spec, ":")
+	if i < 0 {
+		return nil, errors.Errorf("invalid output %q, expected <backend>:<location>", spec)
+	}
+	backend, location := spec[:i], spec[i+1:]
+
+	switch backend {
+	case "sqlite":
+		return openSQLiteStore(ctx, location)
+	default:
+		return nil, errors.Errorf("unknown output backend %q", backend)
+	}
+}
+
+func saveToStore(ctx context.Context, spec string, args *cliArgs, listings []listing) error {
+	r, err := newRunRecord(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, storeTimeout)
+	defer cancel()
+
+	s, err := openStore(ctx, spec)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	return s.saveRun(ctx, r, listings)
+}
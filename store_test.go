@@ -0,0 +1,53 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRunRecordRedactsSecrets(t *testing.T) {
+	setNow(t, time.Date(2021, time.May, 10, 9, 0, 0, 0, time.FixedZone("BST", 3600)))
+
+	args := cliArgs{
+		Postcode:      "SW2",
+		WebhookSecret: "webhook-secret",
+		SlackWebhook:  "https://hooks.slack.com/services/T000/B000/slack-secret",
+		SMTPPass:      "smtp-secret",
+		InfluxToken:   "influx-secret",
+		SMTPUser:      "someone",
+	}
+	r, err := newRunRecord(&args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Postcode != "SW2" || !r.Timestamp.Equal(timeNow()) || r.Timestamp.Location() != time.UTC {
+		t.Errorf("got run %+v", r)
+	}
+	if strings.Contains(r.ParamsJSON, "secret") {
+		t.Errorf("params leak a secret: %s", r.ParamsJSON)
+	}
+
+	var params cliArgs
+	if err := json.Unmarshal([]byte(r.ParamsJSON), &params); err != nil {
+		t.Fatal(err)
+	}
+	if params.SMTPPass != redacted || params.InfluxToken != redacted || params.SMTPUser != "someone" {
+		t.Errorf("got params %+v", params)
+	}
+	if args.SMTPPass != "smtp-secret" {
+		t.Error("redacting changed the run's own arguments")
+	}
+}
+
+func TestOpenStoreUnknownBackend(t *testing.T) {
+	for _, spec := range []string{"prices.db", "mysql:prices"} {
+		if s, err := openStore(context.Background(), spec); err == nil {
+			s.Close()
+			t.Errorf("openStore(%q) succeeded, want an error", spec)
+		}
+	}
+}
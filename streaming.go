@@ -0,0 +1,409 @@
+package zoopla
+
+import (
+	"io"
+	"log"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/html"
+)
+
+// streamingParserName selects parseStreaming, which reads the class-based
+// layout straight from the response body instead of building a DOM.
+const streamingParserName = "streaming"
+
+var (
+	voidElements = map[string]bool{
+		"area": true, "base": true, "br": true, "col": true, "embed": true,
+		"hr": true, "img": true, "input": true, "link": true, "meta": true,
+		"source": true, "track": true, "wbr": true,
+	}
+
+	// closesParagraph are the start tags that end an open p, as a DOM
+	// parser would.
+	closesParagraph = map[string]bool{
+		"address": true, "article": true, "aside": true, "blockquote": true,
+		"details": true, "div": true, "dl": true, "fieldset": true,
+		"figcaption": true, "figure": true, "footer": true, "form": true,
+		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+		"header": true, "hr": true, "li": true, "main": true, "nav": true,
+		"ol": true, "p": true, "pre": true, "section": true, "table": true,
+		"ul": true, "dd": true, "dt": true,
+	}
+
+	// paragraphScope are the elements an open p is not looked for beyond.
+	paragraphScope = map[string]bool{
+		"button": true, "table": true, "td": true, "th": true, "caption": true,
+		"object": true, "template": true, "html": true,
+	}
+)
+
+// openElements is the stack of elements the tokenizer is inside.
+type openElements []string
+
+// impliedEnds returns how many of the innermost open elements a start tag
+// closes implicitly. Only the optional end tags that cards use are handled:
+// p, li, dt and dd, and option.
+func (s openElements) impliedEnds(tag string) int {
+	closed := 0
+	switch tag {
+	case "li":
+		closed = s.innermost(closed, []string{"li"}, map[string]bool{"ul": true, "ol": true})
+	case "dt", "dd":
+		closed = s.innermost(closed, []string{"dt", "dd"}, map[string]bool{"dl": true})
+	case "option":
+		if s.top(closed) == "option" {
+			closed++
+		}
+	}
+
+	if closesParagraph[tag] {
+		closed = s.innermost(closed, []string{"p"}, paragraphScope)
+	}
+
+	return closed
+}
+
+// innermost returns closed plus the elements down to and including the
+// innermost one named in tags, looking below the first closed elements but
+// not beyond a scope element. closed is returned as is if there's none.
+func (s openElements) innermost(closed int, tags []string, scope map[string]bool) int {
+	for i := len(s) - 1 - closed; i >= 0; i-- {
+		if containsString(tags, s[i]) {
+			return len(s) - i
+		}
+		if scope[s[i]] {
+			break
+		}
+	}
+
+	return closed
+}
+
+func (s openElements) top(closed int) string {
+	if i := len(s) - 1 - closed; i >= 0 {
+		return s[i]
+	}
+
+	return ""
+}
+
+// matching returns how many of the innermost open elements an end tag
+// closes, which is none for a stray end tag.
+func (s openElements) matching(tag string) int {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] == tag {
+			return len(s) - i
+		}
+	}
+
+	return 0
+}
+
+// streamingCard accumulates the parts of a listing card seen so far.
+type streamingCard struct {
+	depth         int
+	priceDepth    int
+	excludedDepth int
+	hasPrice      bool
+
+	priceText segmenter
+	cardText  segmenter
+	allText   []string
+	agentName *textCapture
+	title     *textCapture
+	address   *textCapture
+	qualifier *textCapture
+	transport *textCapture
+	station   *textCapture
+	stations  []string
+	badge     *textCapture
+	badges    []string
+	agentLogo string
+	url       string
+}
+
+func newStreamingCard(depth int) *streamingCard {
+	return &streamingCard{
+		depth:         depth,
+		priceDepth:    -1,
+		excludedDepth: -1,
+		agentName:     newTextCapture(isAgentNameNode),
+		title:         newTextCapture(isTitleNode),
+		address:       newTextCapture(isAddressNode),
+		qualifier:     newTextCapture(isQualifierNode),
+		transport:     newTextCapture(isTransportNode),
+	}
+}
+
+// textCapture collects the text content of the first element in a card
+// matching a predicate, mirroring findFirst followed by textContent.
+type textCapture struct {
+	match func(n *html.Node) bool
+	depth int
+	done  bool
+	text  strings.Builder
+}
+
+func newTextCapture(match func(n *html.Node) bool) *textCapture {
+	return &textCapture{match: match, depth: -1}
+}
+
+func (tc *textCapture) startTag(n *html.Node, depth int) {
+	if !tc.done && tc.depth < 0 && tc.match(n) {
+		tc.depth = depth
+	}
+}
+
+func (tc *textCapture) endTag(depth int) {
+	if tc.depth == depth {
+		tc.depth = -1
+		tc.done = true
+	}
+}
+
+func (tc *textCapture) addText(t string) {
+	if tc.depth >= 0 {
+		tc.text.WriteString(t)
+	}
+}
+
+func (tc *textCapture) String() string {
+	return tc.text.String()
+}
+
+func (c *streamingCard) captures() []*textCapture {
+	return []*textCapture{c.agentName, c.title, c.address, c.qualifier, c.transport}
+}
+
+// parseStreaming extracts the same listings as the class parser using only a
+// tokenizer. It tracks the open elements to know when the listings container,
+// a card and the elements of interest within it are closed, including by the
+// start or end of another element as a DOM parser would close them.
+func parseStreaming(r io.Reader, mode searchMode) ([]listing, []cardError, error) {
+	var (
+		listings  []listing
+		cardErrs  []cardError
+		card      *streamingCard
+		cardIndex int
+
+		open           openElements
+		containerDepth = -1
+	)
+
+	// closeElements pops the n innermost open elements, returning true once
+	// the listings container is closed.
+	closeElements := func(n int) bool {
+		for ; n > 0; n-- {
+			tag := open[len(open)-1]
+			open = open[:len(open)-1]
+			depth := len(open)
+
+			switch {
+			case card != nil && depth == card.depth:
+				if l, err := card.listing(mode); err != nil {
+					cardErrs = append(cardErrs, cardError{index: cardIndex, err: err})
+				} else {
+					listings = append(listings, l)
+				}
+				card = nil
+				cardIndex++
+			case card != nil:
+				card.endTag(tag, depth)
+			case depth == containerDepth:
+				return true
+			}
+		}
+
+		return false
+	}
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				if containerDepth < 0 {
+					log.Print("no listings container in response")
+				}
+				return listings, cardErrs, nil
+			}
+			return nil, nil, errors.Wrap(z.Err(), "while tokenizing HTML")
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tok := z.Token()
+			if closeElements(open.impliedEnds(tok.Data)) {
+				return listings, cardErrs, nil
+			}
+
+			node := &html.Node{Type: html.ElementNode, Data: tok.Data, Attr: tok.Attr}
+			depth := len(open)
+			switch {
+			case containerDepth < 0:
+				if tok.Data == "div" && hasClass(node, "ListingsContainer") {
+					containerDepth = depth
+				}
+			case card == nil:
+				if isListingCard(node) {
+					card = newStreamingCard(depth)
+				}
+			default:
+				card.startTag(node, depth)
+			}
+
+			if tt == html.StartTagToken && !voidElements[tok.Data] {
+				open = append(open, tok.Data)
+			}
+
+		case html.EndTagToken:
+			if closeElements(open.matching(z.Token().Data)) {
+				return listings, cardErrs, nil
+			}
+
+		case html.TextToken:
+			if card != nil {
+				card.text(string(z.Text()))
+			}
+		}
+	}
+}
+
+func (c *streamingCard) startTag(n *html.Node, depth int) {
+	for _, tc := range c.captures() {
+		tc.startTag(n, depth)
+	}
+
+	// Each station chip within the first transport element is captured in
+	// turn, matching findAll over the transport subtree.
+	if c.transport.depth >= 0 && c.station == nil && isStationNode(n) {
+		c.station = newTextCapture(isStationNode)
+		c.station.startTag(n, depth)
+	}
+	// Every badge on the card is captured, matching findAll over the card.
+	if c.badge == nil && isBadgeNode(n) {
+		c.badge = newTextCapture(isBadgeNode)
+		c.badge.startTag(n, depth)
+	}
+
+	if c.excludedDepth < 0 && isPriceExcluded(n) {
+		c.excludedDepth = depth
+		c.flush()
+	} else if blockElements[n.Data] {
+		c.flush()
+	}
+
+	switch {
+	case n.Data == "div" && hasClass(n, "PriceContainer"):
+		if !c.hasPrice {
+			c.hasPrice = true
+			c.priceDepth = depth
+		}
+	case n.Data == "img" && hasClass(n, "AgentLogo"):
+		if c.agentLogo == "" {
+			c.agentLogo, _ = getAttr(n, "alt")
+		}
+	case n.Data == "a":
+		if href, _ := getAttr(n, "href"); c.url == "" && strings.Contains(href, "/details/") {
+			c.url = href
+		}
+	}
+}
+
+func (c *streamingCard) endTag(tag string, depth int) {
+	if blockElements[tag] {
+		c.flush()
+	}
+
+	if c.station != nil && c.station.depth == depth {
+		c.stations = append(c.stations, strings.Join(strings.Fields(c.station.String()), " "))
+		c.station = nil
+	}
+	if c.badge != nil && c.badge.depth == depth {
+		c.badges = append(c.badges, c.badge.String())
+		c.badge = nil
+	}
+
+	for _, tc := range c.captures() {
+		tc.endTag(depth)
+	}
+
+	switch depth {
+	case c.excludedDepth:
+		c.excludedDepth = -1
+	case c.priceDepth:
+		c.priceText.flush()
+		c.priceDepth = -2
+	}
+}
+
+func (c *streamingCard) text(t string) {
+	if s := strings.TrimSpace(t); s != "" {
+		c.allText = append(c.allText, s)
+	}
+
+	for _, tc := range c.captures() {
+		tc.addText(t)
+	}
+
+	if c.station != nil {
+		c.station.addText(t + " ")
+	}
+	if c.badge != nil {
+		c.badge.addText(t)
+	}
+
+	if c.excludedDepth >= 0 {
+		return
+	}
+
+	c.cardText.text(t)
+	if c.priceDepth >= 0 {
+		c.priceText.text(t)
+	}
+}
+
+func (c *streamingCard) flush() {
+	c.cardText.flush()
+	if c.priceDepth >= 0 {
+		c.priceText.flush()
+	}
+}
+
+func (c *streamingCard) listing(mode searchMode) (listing, error) {
+	c.cardText.flush()
+
+	segments := c.cardText.segments
+	if c.hasPrice {
+		c.priceText.flush()
+		segments = c.priceText.segments
+	}
+
+	text := strings.Join(c.allText, " ")
+	status := parseStatus(c.badges)
+
+	price, err := findPriceInSegments(segments, mode)
+	if status == statusComingSoon {
+		price = 0
+	} else if err != nil {
+		return listing{}, err
+	}
+
+	l := listing{
+		Price:     price,
+		Status:    status,
+		URL:       c.url,
+		Title:     strings.Join(strings.Fields(c.title.String()), " "),
+		Address:   strings.Join(strings.Fields(c.address.String()), " "),
+		Qualifier: normaliseQualifier(c.qualifier.String()),
+		Stations:  parseStationTexts(c.stations),
+	}
+	l.Category = classifyListing(l.Title, "")
+	if l.Agent = normaliseAgentName(c.agentName.String()); l.Agent == "" {
+		l.Agent = normaliseAgentName(c.agentLogo)
+	}
+	parseCardText(text, &l)
+
+	return l, nil
+}
@@ -0,0 +1,175 @@
+package zoopla
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// classFixtures returns the fixture pages in the class layout, which is the
+// one the streaming parser reads, by name.
+func classFixtures(t testing.TB) map[string][]byte {
+	t.Helper()
+
+	pages := make(map[string][]byte)
+	for _, page := range fixturePages(t) {
+		if golden, ok := readGolden(t, page); !ok || golden.Parser != "class" {
+			continue
+		}
+
+		b, err := os.ReadFile(page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages[filepath.Base(page)] = b
+	}
+	if len(pages) == 0 {
+		t.Fatal("no class layout fixtures")
+	}
+
+	return pages
+}
+
+func TestStreamingParity(t *testing.T) {
+	setNow(t, date(2021, 5, 10))
+
+	pages := classFixtures(t)
+	pages["inline"] = []byte(classLayoutPage)
+
+	for name, page := range pages {
+		t.Run(name, func(t *testing.T) {
+			want, wantErrs := classParser{}.parse(mustParseHTML(t, string(page)), saleSearch)
+			got, gotErrs, err := parseStreaming(bytes.NewReader(page), saleSearch)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(gotErrs) != len(wantErrs) {
+				t.Errorf("got %d card errors, want %d", len(gotErrs), len(wantErrs))
+			}
+			diffGoldenListings(t, want, got)
+		})
+	}
+}
+
+func TestStreamingImplicitlyClosedTags(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+	}{
+		{
+			name: "unclosed paragraphs",
+			page: `<div class="ListingsContainer">
+<div data-testid="search-result"><div class="PriceContainer"><p>£450,000</div><p>2 beds<p class="AgentName">Foxtons</div>
+<div data-testid="search-result"><div class="PriceContainer"><p>£500,000</p></div><p>3 beds</div>
+</div><div data-testid="search-result"><p>£1</p></div>`,
+		},
+		{
+			name: "unclosed list items",
+			page: `<div class="ListingsContainer">
+<div data-testid="search-result"><div class="PriceContainer"><p>£450,000</p></div>
+<div data-testid="transport"><ul><li>Brixton 0.3 miles<li>Herne Hill 1 mile</ul></div></div>
+<div data-testid="search-result"><div class="PriceContainer"><p>£500,000</p></div></div>
+</div>`,
+		},
+		{
+			name: "stray end tags",
+			page: `<div class="ListingsContainer">
+<div data-testid="search-result"></p></span><div class="PriceContainer"><p>£450,000</p></div></li></div>
+<div data-testid="search-result"><div class="PriceContainer"><p>£500,000</p></div></div>
+</div>`,
+		},
+		{
+			name: "paragraph closed by a block",
+			page: `<div class="ListingsContainer">
+<div data-testid="search-result"><p>Listed on 3rd Mar 2021<ul><li>2 beds</ul><div class="PriceContainer"><p>£450,000</div></div>
+<div data-testid="search-result"><div class="PriceContainer"><p>£500,000</p></div></div>
+</div>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, _ := classParser{}.parse(mustParseHTML(t, tt.page), saleSearch)
+			got, _, err := parseStreaming(strings.NewReader(tt.page), saleSearch)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			diffGoldenListings(t, want, got)
+		})
+	}
+}
+
+func TestStreamingNoContainer(t *testing.T) {
+	listings, cardErrs, err := parseStreaming(strings.NewReader(`<html><body><p>Just a moment...</p></body></html>`), saleSearch)
+	if err != nil || len(listings) != 0 || len(cardErrs) != 0 {
+		t.Errorf("got %d listings, %d card errors, error %v, want nothing", len(listings), len(cardErrs), err)
+	}
+}
+
+// The benchmarks compare the DOM and streaming parsers over the class layout
+// fixtures. Besides allocations they report live-B/op, the heap still
+// reachable after a collection once a page is parsed, which for the DOM path
+// includes the tree held until the page is done with. That is what adds up
+// to the peak RSS when several pages are in flight.
+func BenchmarkParseDOM(b *testing.B) {
+	benchmarkParse(b, func(page []byte) interface{} {
+		doc, err := html.Parse(bytes.NewReader(page))
+		if err != nil {
+			b.Fatal(err)
+		}
+		listings, _ := parseHTML(doc, "class", saleSearch)
+		return []interface{}{doc, listings}
+	})
+}
+
+func BenchmarkParseStreaming(b *testing.B) {
+	benchmarkParse(b, func(page []byte) interface{} {
+		listings, _, err := parseStreaming(bytes.NewReader(page), saleSearch)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return listings
+	})
+}
+
+func benchmarkParse(b *testing.B, parse func(page []byte) interface{}) {
+	pages := classFixtures(b)
+
+	var size int64
+	for _, page := range pages {
+		size += int64(len(page))
+	}
+	b.SetBytes(size)
+	b.ReportAllocs()
+
+	var live uint64
+	var before, after runtime.MemStats
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, page := range pages {
+			b.StopTimer()
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			b.StartTimer()
+
+			result := parse(page)
+
+			b.StopTimer()
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			if after.HeapAlloc > before.HeapAlloc {
+				live += after.HeapAlloc - before.HeapAlloc
+			}
+			runtime.KeepAlive(result)
+			b.StartTimer()
+		}
+	}
+	b.ReportMetric(float64(live)/float64(b.N), "live-B/op")
+}
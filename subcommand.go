@@ -0,0 +1,74 @@
+package zoopla
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alexflint/go-arg"
+)
+
+const programName = "zoopla-analyzer"
+
+type subcommand struct {
+	name        string
+	description string
+	run         func(ctx context.Context, argv []string) error
+}
+
+// subcommands are listed by help in this order. help itself isn't one, as
+// it lists them.
+var subcommands = []subcommand{
+	{"fetch", "search for listings and write their prices, the default", func(ctx context.Context, argv []string) error {
+		args, err := parseSearchArgs(func(cli *cliArgs) (*arg.Parser, error) {
+			return parseSubcommand("fetch", cli, argv)
+		})
+		if err != nil {
+			return err
+		}
+		return runFetch(ctx, args)
+	}},
+	{"stats", "recalculate the stats of an output file without fetching", withoutContext(runFileStats)},
+	{"compare", "compare several postcodes side by side", runCompare},
+	{"league", "rank the areas in a postcode file by median price", runLeague},
+	{"merge", "merge output files into one", withoutContext(runMerge)},
+	{"diff", "show the listings added, removed and changed between two runs", withoutContext(runDiff)},
+	{"trend", "show the trend of a run history", runTrend},
+	{"decode", "convert a CBOR output file to JSON", withoutContext(runDecode)},
+}
+
+func withoutContext(run func(argv []string) error) func(context.Context, []string) error {
+	return func(_ context.Context, argv []string) error { return run(argv) }
+}
+
+func lookupSubcommand(name string) *subcommand {
+	for i := range subcommands {
+		if subcommands[i].name == name {
+			return &subcommands[i]
+		}
+	}
+
+	return nil
+}
+
+func runHelp() error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage: %s [SUBCOMMAND] [ARGS]\n\nRun a subcommand with --help for its arguments. Without a subcommand,\nthe arguments are fetch's.\n\nSubcommands:\n", programName)
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	for _, cmd := range subcommands {
+		fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintf(tw, "  help\tlist the subcommands\n")
+	tw.Flush()
+
+	_, err := os.Stdout.WriteString(b.String())
+	return err
+}
+
+// parseSubcommand parses the arguments following a subcommand name into
+// dest, the same way as the arguments without one.
+func parseSubcommand(name string, dest interface{}, args []string) (*arg.Parser, error) {
+	return parseCommandLine(programName+" "+name, dest, args)
+}
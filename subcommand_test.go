@@ -0,0 +1,241 @@
+package zoopla
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alexflint/go-arg"
+	"github.com/pkg/errors"
+)
+
+func TestLookupSubcommand(t *testing.T) {
+	for _, name := range []string{"fetch", "stats", "compare", "league", "merge", "diff", "trend", "decode"} {
+		if cmd := lookupSubcommand(name); cmd == nil || cmd.name != name {
+			t.Errorf("lookupSubcommand(%q) = %v", name, cmd)
+		}
+	}
+
+	for _, name := range []string{"help", "", "--postcode", "Fetch"} {
+		if cmd := lookupSubcommand(name); cmd != nil {
+			t.Errorf("lookupSubcommand(%q) = %v, want nil", name, cmd.name)
+		}
+	}
+}
+
+func TestRunHelp(t *testing.T) {
+	var err error
+	stdout, _ := captureOutput(t, func() { err = runHelp() })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(stdout, "Usage: zoopla-analyzer [SUBCOMMAND] [ARGS]\n") {
+		t.Errorf("got usage %q", stdout)
+	}
+	listed := make(map[string]string)
+	for _, line := range strings.Split(stdout, "\n") {
+		if fields := strings.Fields(line); strings.HasPrefix(line, "  ") && len(fields) > 1 {
+			listed[fields[0]] = strings.Join(fields[1:], " ")
+		}
+	}
+	for _, cmd := range subcommands {
+		if listed[cmd.name] != cmd.description {
+			t.Errorf("help has no line for %s:\n%s", cmd.name, stdout)
+		}
+	}
+	if listed["help"] != "list the subcommands" {
+		t.Errorf("help doesn't list itself:\n%s", stdout)
+	}
+}
+
+// TestSubcommandHelp checks each subcommand prints the help for its own
+// flags and stops without failing, even when its positionals are missing.
+func TestSubcommandHelp(t *testing.T) {
+	flags := map[string]string{
+		"fetch":   "--outputfilename",
+		"stats":   "--min-group-size",
+		"compare": "--postcode",
+		"league":  "--league-format",
+		"merge":   "--dedupe",
+		"diff":    "--force",
+		"trend":   "--hpi-region",
+		"decode":  "INPUT",
+	}
+
+	for _, cmd := range subcommands {
+		t.Run(cmd.name, func(t *testing.T) {
+			var err error
+			stdout, _ := captureOutput(t, func() { err = cmd.run(context.Background(), []string{"--help"}) })
+			if !errors.Is(err, errHelp) {
+				t.Fatalf("got %v, want errHelp", err)
+			}
+
+			if want := "Usage: zoopla-analyzer " + cmd.name; !strings.HasPrefix(stdout, want) {
+				t.Errorf("help doesn't start with %q:\n%s", want, stdout)
+			}
+			if flag, ok := flags[cmd.name]; !ok || !strings.Contains(stdout, flag) {
+				t.Errorf("help has no %q:\n%s", flag, stdout)
+			}
+		})
+	}
+}
+
+func TestParseSubcommand(t *testing.T) {
+	tests := []struct {
+		name string
+		dest interface{}
+		argv []string
+		want interface{}
+	}{
+		{
+			name: "stats",
+			dest: &statsArgs{},
+			argv: []string{"prices.json", "--min-group-size", "3", "--by-beds", "--stats-file", "stats.json"},
+			want: &statsArgs{Input: "prices.json", MinGroupSize: 3, ByBeds: true, StatsFile: "stats.json"},
+		},
+		{
+			name: "merge",
+			dest: &mergeArgs{},
+			argv: []string{"all.json", "a.json", "b.json", "--dedupe"},
+			want: &mergeArgs{Output: "all.json", Inputs: []string{"a.json", "b.json"}, Dedupe: true},
+		},
+		{
+			name: "diff",
+			dest: &diffArgs{},
+			argv: []string{"old.json", "new.json", "--format", "json", "--force", "--no-color"},
+			want: &diffArgs{Old: "old.json", New: "new.json", Format: "json", Force: true, NoColor: true},
+		},
+		{
+			name: "trend",
+			dest: &trendArgs{},
+			argv: []string{"history.json", "--moves", "2", "--hpi-region", "london", "--hpi-cache", "hpi.csv"},
+			want: &trendArgs{History: "history.json", Moves: 2, HPIRegion: "london", HPICache: "hpi.csv"},
+		},
+		{
+			name: "decode",
+			dest: &decodeArgs{},
+			argv: []string{"prices.cbor"},
+			want: &decodeArgs{Input: "prices.cbor"},
+		},
+		{
+			name: "decode",
+			dest: &decodeArgs{},
+			argv: []string{"prices.cbor", "prices.json"},
+			want: &decodeArgs{Input: "prices.cbor", Output: "prices.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parseSubcommand(tt.name, tt.dest, tt.argv)
+			if err != nil || p == nil {
+				t.Fatalf("got %v, %v", p, err)
+			}
+			if !reflect.DeepEqual(tt.dest, tt.want) {
+				t.Errorf("got %+v, want %+v", tt.dest, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSearchSubcommands(t *testing.T) {
+	parse := func(name string, argv ...string) cliArgs {
+		args, err := parseSearchArgs(func(cli *cliArgs) (*arg.Parser, error) {
+			return parseSubcommand(name, cli, argv)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return args
+	}
+
+	fetch := parse("fetch", "--postcode", "sw2", "--radius", "1", "--bedsmin", "2")
+	if fetch.Postcode != "sw2" || fetch.Radius != 1 || derefUint32(fetch.BedsMin) != uint32(2) {
+		t.Errorf("got fetch args %+v", fetch)
+	}
+	// The defaults are filled in before parsing.
+	if fetch.BaseURL != baseURL || fetch.Delay != defaultDelay || fetch.OutputFilename != defaultOutputFilename || fetch.Mode != saleSearch {
+		t.Errorf("got fetch defaults %+v", fetch)
+	}
+
+	compare := parse("compare", joinRepeatedFlag([]string{"--postcode", "sw2", "--postcode", "sw9", "--combined"}, "--postcode")...)
+	if got := splitPostcodes(compare.Postcode); !equalStrings(got, []string{"sw2", "sw9"}) || !compare.Combined {
+		t.Errorf("got compare args %+v", compare)
+	}
+}
+
+func TestParseSearchArgsHelp(t *testing.T) {
+	called := false
+	_, err := parseSearchArgs(func(cli *cliArgs) (*arg.Parser, error) {
+		called = true
+		return nil, errHelp
+	})
+	if !errors.Is(err, errHelp) {
+		t.Errorf("got %v, want errHelp", err)
+	}
+	if !called {
+		t.Error("parse wasn't called")
+	}
+}
+
+func TestParseLeagueFlags(t *testing.T) {
+	var league leagueArgs
+	league.LeagueFormat = diffFormatTable
+
+	argv := []string{"--postcode", "sw2,sw9", "--league-format", "csv", "--progress", "progress.json", "--radius", "3"}
+	if _, err := parseSubcommand("league", &league, argv); err != nil {
+		t.Fatal(err)
+	}
+	if league.LeagueFormat != formatCSV || league.Progress != "progress.json" || league.Postcode != "sw2,sw9" || league.Radius != 3 {
+		t.Errorf("got %+v", league)
+	}
+}
+
+func TestSubcommandUsageErrors(t *testing.T) {
+	dir := t.TempDir()
+	prices := filepath.Join(dir, "prices.json")
+	if err := ioutil.WriteFile(prices, []byte(`[400000]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		argv    []string
+		wantErr string
+	}{
+		{"fetch", []string{"--bogus"}, "unknown argument --bogus"},
+		{"stats", nil, "input is required"},
+		{"stats", []string{prices, "--min-group-size", "-1"}, "--min-group-size must not be negative"},
+		{"stats", []string{prices, "--bucket-size", "1000"}, "--bucket-size needs --histogram"},
+		{"compare", nil, "no postcode given"},
+		{"league", []string{"--postcode", "sw2", "--league-format", "xml"}, `unknown league format "xml"`},
+		{"merge", []string{filepath.Join(dir, "out.json"), prices, prices, "--dedupe"}, "--dedupe needs listings files"},
+		{"diff", []string{prices, prices, "--format", "xml"}, `unknown format "xml"`},
+		{"trend", []string{prices, "--moves", "-1"}, "--moves must not be negative"},
+		{"decode", nil, "input is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.wantErr, func(t *testing.T) {
+			err := lookupSubcommand(tt.name).run(context.Background(), tt.argv)
+
+			var usageErr *usageError
+			if !errors.As(err, &usageErr) {
+				t.Fatalf("got %v, want a usage error", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %q, want %q", err, tt.wantErr)
+			}
+
+			var usage strings.Builder
+			usageErr.write(&usage)
+			if want := "Usage: zoopla-analyzer " + tt.name; !strings.HasPrefix(usage.String(), want) {
+				t.Errorf("got usage %q, want it to start with %q", usage.String(), want)
+			}
+		})
+	}
+}
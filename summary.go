@@ -0,0 +1,151 @@
+package zoopla
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type summaryRow struct {
+	label  string
+	count  int
+	min    uint64
+	p25    float64
+	median float64
+	mean   float64
+	p75    float64
+	p90    float64
+	max    uint64
+	stddev float64
+	// outliers counts the prices beyond the box plot's whiskers.
+	outliers int
+	// unreliable is set for groups with too few listings.
+	unreliable bool
+}
+
+func newSummaryRow(label string, prices []uint64) summaryRow {
+	s := calculatePriceStatsWith(prices, statsOptions{percentiles: []float64{90}})
+	row := summaryRow{
+		label:  label,
+		count:  s.count,
+		min:    s.min,
+		mean:   s.mean,
+		max:    s.max,
+		stddev: s.stddev,
+	}
+	if s.box != nil {
+		row.p25, row.median, row.p75 = s.box.Q1, s.box.Q2, s.box.Q3
+		row.p90 = s.percentiles[0].value
+		row.outliers = len(s.box.Outliers)
+	}
+
+	return row
+}
+
+// summaryRows returns a row for all of listings and, with byBeds, a row per
+// bedroom count in ascending order and, with byType, a row per property type.
+// prices gives the prices to summarise for a set of listings. Bedroom rows
+// with fewer than minGroupSize listings are flagged as unreliable, while such
+// property types are left out.
+func summaryRows(listings []listing, byBeds, byType bool, minGroupSize int, prices func([]listing) []uint64) []summaryRow {
+	rows := []summaryRow{newSummaryRow("all", prices(listings))}
+	if byBeds {
+		rows = append(rows, groupSummaryRows(listings, bedsKey, minGroupSize, false, prices)...)
+	}
+	if byType {
+		rows = append(rows, groupSummaryRows(listings, propertyTypeKey, minGroupSize, true, prices)...)
+	}
+
+	return rows
+}
+
+func groupSummaryRows(listings []listing, key func(*listing) string, minGroupSize int, suppress bool, prices func([]listing) []uint64) []summaryRow {
+	var rows []summaryRow
+	groups := make(map[string][]listing)
+	for i := range listings {
+		k := key(&listings[i])
+		groups[k] = append(groups[k], listings[i])
+	}
+
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return lessGroupKey(keys[i], keys[j]) })
+
+	for _, k := range keys {
+		row := newSummaryRow(k, prices(groups[k]))
+		row.unreliable = row.count < minGroupSize
+		if row.unreliable && suppress {
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	return rows
+}
+
+// renderSummaryTable lays rows out as an aligned table with a header. With
+// colour, prices below the first row's p25 are green and those above its p75
+// red. Unreliable rows are marked with an asterisk and explained below the
+// table.
+func renderSummaryTable(rows []summaryRow, p painter) string {
+	var buf bytes.Buffer
+	tw := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+
+	header := []string{"min", "p25", "median", "mean", "p75", "p90", "max"}
+	for i, h := range header {
+		header[i] = p.paint(colorDefault, h)
+	}
+	fmt.Fprintf(tw, "\tcount\t%s\tstddev\toutliers\t\n", strings.Join(header, "\t"))
+
+	if len(rows) == 0 {
+		tw.Flush()
+		return buf.String()
+	}
+
+	p25, p75 := rows[0].p25, rows[0].p75
+	anyUnreliable := false
+	for _, r := range rows {
+		label := r.label
+		if r.unreliable {
+			label += "*"
+			anyUnreliable = true
+		}
+
+		prices := []float64{float64(r.min), r.p25, r.median, r.mean, r.p75, r.p90, float64(r.max)}
+		cells := make([]string, len(prices))
+		for i, v := range prices {
+			cells[i] = p.paintPrice(v, p25, p75, formatMeanPrice(v))
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%d\t\n", label, r.count, strings.Join(cells, "\t"), formatMeanPrice(r.stddev), r.outliers)
+	}
+	tw.Flush()
+
+	if anyUnreliable {
+		buf.WriteString("* too few listings for reliable stats\n")
+	}
+
+	return buf.String()
+}
+
+// renderOnMarketSummary is a line under the summary table for how long
+// listings have been on the market.
+func renderOnMarketSummary(s timeOnMarket) string {
+	return fmt.Sprintf("on market: median %.0f days, p90 %.0f days, %.0f%% over %d days, dates for %d of %d listings\n",
+		s.medianDays, s.p90Days, s.staleShare(), s.staleDays, s.dated, s.listings)
+}
+
+// renderSqftSummary is a line under the summary table for the price per sq
+// ft, noting when too few listings had a floor area to rely on it.
+func renderSqftSummary(s pricePerSqftStats) string {
+	line := fmt.Sprintf("per sq ft: median %s (p25 %s, p75 %s), area data for %d of %d listings\n",
+		formatMeanPrice(s.all.Median), formatMeanPrice(s.all.P25), formatMeanPrice(s.all.P75), s.all.Count, s.total)
+	if s.lowCoverage() {
+		line += "  low area coverage, may be biased towards new builds\n"
+	}
+
+	return line
+}
@@ -0,0 +1,198 @@
+package zoopla
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNewSummaryRow(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []uint64
+		want   summaryRow
+	}{
+		{
+			name:   "single",
+			prices: []uint64{250000},
+			want:   summaryRow{label: "all", count: 1, min: 250000, p25: 250000, median: 250000, mean: 250000, p75: 250000, p90: 250000, max: 250000},
+		},
+		{
+			name:   "outlier",
+			prices: []uint64{400, 100, 5000, 300, 200},
+			want:   summaryRow{label: "all", count: 5, min: 100, p25: 200, median: 300, mean: 1200, p75: 400, p90: 3160, max: 5000, stddev: 2127.2047, outliers: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newSummaryRow("all", tt.prices)
+
+			gotFloats := []float64{got.p25, got.median, got.mean, got.p75, got.p90, got.stddev}
+			wantFloats := []float64{tt.want.p25, tt.want.median, tt.want.mean, tt.want.p75, tt.want.p90, tt.want.stddev}
+			for i := range gotFloats {
+				if math.Abs(gotFloats[i]-wantFloats[i]) > 1e-3 {
+					t.Errorf("got %+v, want %+v", got, tt.want)
+					break
+				}
+			}
+			if got.label != tt.want.label || got.count != tt.want.count || got.min != tt.want.min || got.max != tt.want.max || got.outliers != tt.want.outliers {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSummaryRows(t *testing.T) {
+	type row struct {
+		label      string
+		count      int
+		unreliable bool
+	}
+
+	tests := []struct {
+		name           string
+		byBeds, byType bool
+		minGroupSize   int
+		want           []row
+	}{
+		{name: "all only", minGroupSize: 3, want: []row{{"all", 8, false}}},
+		{
+			name:         "by beds",
+			byBeds:       true,
+			minGroupSize: 3,
+			want:         []row{{"all", 8, false}, {"1 bed", 3, false}, {"2 bed", 3, false}, {"3 bed", 2, true}},
+		},
+		{
+			name:         "by type drops small types",
+			byType:       true,
+			minGroupSize: 3,
+			want:         []row{{"all", 8, false}, {"flat", 6, false}},
+		},
+		{
+			name:         "by beds and type",
+			byBeds:       true,
+			byType:       true,
+			minGroupSize: 2,
+			want:         []row{{"all", 8, false}, {"1 bed", 3, false}, {"2 bed", 3, false}, {"3 bed", 2, false}, {"flat", 6, false}, {"terraced", 2, false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows := summaryRows(reportListings(), tt.byBeds, tt.byType, tt.minGroupSize, listingPrices)
+			if len(rows) != len(tt.want) {
+				t.Fatalf("got %d rows, want %d: %+v", len(rows), len(tt.want), rows)
+			}
+			for i, r := range rows {
+				if got := (row{r.label, r.count, r.unreliable}); got != tt.want[i] {
+					t.Errorf("row %d: got %+v, want %+v", i, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSummaryRowsUnknownBedsLast(t *testing.T) {
+	listings := []listing{{Price: 1}, {Price: 2, Beds: uint32Ptr(10)}, {Price: 3, Beds: uint32Ptr(2)}}
+
+	rows := summaryRows(listings, true, false, 0, listingPrices)
+	var labels []string
+	for _, r := range rows {
+		labels = append(labels, r.label)
+	}
+	if want := []string{"all", "2 bed", "10 bed", unknownGroup}; !equalStrings(labels, want) {
+		t.Errorf("got labels %v, want %v", labels, want)
+	}
+}
+
+func TestRenderSummaryTable(t *testing.T) {
+	rows := summaryRows(reportListings(), true, false, 3, listingPrices)
+
+	got := renderSummaryTable(rows, false)
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("got %d lines, want a header, 4 rows and a footnote:\n%s", len(lines), got)
+	}
+	for _, want := range []string{"count", "median", "p90", "stddev", "outliers"} {
+		if !strings.Contains(lines[0], want) {
+			t.Errorf("header %q has no %q", lines[0], want)
+		}
+	}
+	if !strings.HasPrefix(lines[1], "all ") || !strings.Contains(lines[1], "£300,000") || !strings.Contains(lines[1], "£750,000") {
+		t.Errorf("got all row %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[4], "3 bed* ") {
+		t.Errorf("got 3 bed row %q, want it marked unreliable", lines[4])
+	}
+	if lines[5] != "* too few listings for reliable stats" {
+		t.Errorf("got footnote %q", lines[5])
+	}
+
+	// The columns line up, so each row's count starts where the header's
+	// does.
+	col := strings.Index(lines[0], "count")
+	for _, l := range lines[1:5] {
+		if l[col-1] != ' ' || l[col] == ' ' {
+			t.Errorf("row %q is not aligned with the header %q", l, lines[0])
+		}
+	}
+	if strings.Contains(got, "\x1b[") {
+		t.Error("got colour codes without colour")
+	}
+}
+
+func TestRenderSummaryTableEmpty(t *testing.T) {
+	got := renderSummaryTable(nil, false)
+	if strings.Count(got, "\n") != 1 || !strings.Contains(got, "count") {
+		t.Errorf("got %q, want only the header", got)
+	}
+}
+
+func TestRenderSummaryTableColor(t *testing.T) {
+	rows := []summaryRow{newSummaryRow("all", []uint64{100, 200, 300, 400, 500})}
+
+	got := renderSummaryTable(rows, true)
+	for _, want := range []string{
+		"\x1b[" + colorDefault + "mmin\x1b[0m",
+		"\x1b[" + colorGreen + "m£100\x1b[0m",
+		"\x1b[" + colorDefault + "m£300\x1b[0m",
+		"\x1b[" + colorRed + "m£500\x1b[0m",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("%q has no %q", got, want)
+		}
+	}
+}
+
+func TestRenderOnMarketSummary(t *testing.T) {
+	s := timeOnMarket{listings: 10, dated: 8, medianDays: 30, p90Days: 120, staleDays: 90, stale: 2}
+
+	want := "on market: median 30 days, p90 120 days, 25% over 90 days, dates for 8 of 10 listings\n"
+	if got := renderOnMarketSummary(s); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRenderSqftSummary(t *testing.T) {
+	all := pricePerSqftGroup{Key: "all", Count: 2, Median: 650, P25: 600, P75: 700}
+	line := "per sq ft: median £650 (p25 £600, p75 £700), area data for 2 of %d listings\n"
+
+	tests := []struct {
+		name  string
+		total int
+		want  string
+	}{
+		{"good coverage", 4, fmt.Sprintf(line, 4)},
+		{"low coverage", 10, fmt.Sprintf(line, 10) + "  low area coverage, may be biased towards new builds\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderSqftSummary(pricePerSqftStats{total: tt.total, all: all}); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
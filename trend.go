@@ -0,0 +1,243 @@
+package zoopla
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultTrendMoves = 3
+
+type trendArgs struct {
+	History string `arg:"positional,required"`
+	Format  string `arg:"--format"`
+	Moves   int    `arg:"--moves"`
+	Pretty  bool   `arg:"--pretty"`
+	// HPIRegion is a UK HPI region such as "london" or "united-kingdom"
+	// to adjust the prices for.
+	HPIRegion string `arg:"--hpi-region"`
+	HPICache  string `arg:"--hpi-cache"`
+}
+
+type trendReport struct {
+	Runs []trendPoint `json:"runs"`
+	// Excluded counts the runs left out for a different query or a missing
+	// timestamp.
+	Excluded     int          `json:"excluded"`
+	Trend        *linearTrend `json:"trend,omitempty"`
+	LargestMoves []trendMove  `json:"largest_moves"`
+
+	// RealTrend is the trend of the medians adjusted by the house price
+	// index, with notes of any months whose index was estimated.
+	RealTrend *linearTrend `json:"real_trend,omitempty"`
+	HPINotes  []string     `json:"hpi_notes,omitempty"`
+}
+
+type trendPoint struct {
+	Timestamp string  `json:"timestamp"`
+	Count     int     `json:"count"`
+	Mean      float64 `json:"mean"`
+	Median    float64 `json:"median"`
+	// The changes are from the previous run, so are missing for the first.
+	MeanChange   *float64 `json:"mean_change_percent,omitempty"`
+	MedianChange *float64 `json:"median_change_percent,omitempty"`
+	// RealMean and RealMedian are in the latest run's money, deflated by
+	// the house price index.
+	Index      *float64 `json:"hpi,omitempty"`
+	RealMean   *float64 `json:"real_mean,omitempty"`
+	RealMedian *float64 `json:"real_median,omitempty"`
+
+	time time.Time
+}
+
+// linearTrend is a least squares fit of the median against time.
+type linearTrend struct {
+	SlopePerDay float64 `json:"median_slope_per_day"`
+	// PercentPer30Days is the slope over 30 days relative to the mean of
+	// the medians.
+	PercentPer30Days float64 `json:"median_percent_per_30_days"`
+}
+
+type trendMove struct {
+	From          string  `json:"from"`
+	To            string  `json:"to"`
+	ChangePercent float64 `json:"median_change_percent"`
+}
+
+func runTrend(ctx context.Context, argv []string) error {
+	args := trendArgs{Format: diffFormatTable, Moves: defaultTrendMoves}
+	p, err := parseSubcommand("trend", &args, argv)
+	if err != nil {
+		return err
+	}
+	if !containsString(diffFormats, args.Format) {
+		return newUsageError(p, fmt.Sprintf("unknown format %q, must be one of %s", args.Format, strings.Join(diffFormats, ", ")))
+	}
+	if args.Moves < 0 {
+		return newUsageError(p, "--moves must not be negative")
+	}
+
+	in, err := loadOutputFile(args.History)
+	if err != nil {
+		return err
+	}
+	if in.Kind != outputKindHistory {
+		return errors.Errorf("%s holds %s, trend needs an --append history file", args.History, in.Kind)
+	}
+
+	r := analyseTrend(in.Runs, args.Moves)
+	if r.Excluded > 0 {
+		log.Printf("warning: excluded %d runs with a different query from the latest or no timestamp", r.Excluded)
+	}
+
+	if args.HPIRegion != "" {
+		if err := runHPIAdjustment(ctx, &r, &args); err != nil {
+			return errors.Wrap(err, "while adjusting for house prices")
+		}
+		for _, note := range r.HPINotes {
+			log.Print("warning: ", note)
+		}
+	}
+
+	if args.Format == formatJSON {
+		data, err := marshalJSON(r, args.Pretty)
+		if err != nil {
+			return errors.Wrap(err, "while marshalling trend")
+		}
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return errors.Wrap(err, "while writing trend")
+	}
+
+	return errors.Wrap(writeTrendTable(os.Stdout, r), "while writing trend")
+}
+
+// analyseTrend follows the runs with the same query as the latest one in
+// time order, keeping the given number of largest moves in the median.
+func analyseTrend(runs []runEntry, moves int) trendReport {
+	r := trendReport{Runs: []trendPoint{}, LargestMoves: []trendMove{}}
+	if len(runs) == 0 {
+		return r
+	}
+
+	latest := runs[len(runs)-1].Query
+	for _, run := range runs {
+		t, err := time.Parse(time.RFC3339, run.Timestamp)
+		if err != nil || !sameQuery(run.Query, latest) {
+			r.Excluded++
+			continue
+		}
+
+		s := calculateRunStats(run.Prices)
+		r.Runs = append(r.Runs, trendPoint{
+			Timestamp: run.Timestamp,
+			Count:     s.Count,
+			Mean:      s.Mean,
+			Median:    s.Median,
+			time:      t,
+		})
+	}
+	sort.SliceStable(r.Runs, func(i, j int) bool { return r.Runs[i].time.Before(r.Runs[j].time) })
+
+	for i := 1; i < len(r.Runs); i++ {
+		prev, cur := &r.Runs[i-1], &r.Runs[i]
+		mean, median := percentChange(prev.Mean, cur.Mean), percentChange(prev.Median, cur.Median)
+		cur.MeanChange, cur.MedianChange = &mean, &median
+		r.LargestMoves = append(r.LargestMoves, trendMove{From: prev.Timestamp, To: cur.Timestamp, ChangePercent: median})
+	}
+
+	sort.SliceStable(r.LargestMoves, func(i, j int) bool {
+		return math.Abs(r.LargestMoves[i].ChangePercent) > math.Abs(r.LargestMoves[j].ChangePercent)
+	})
+	if len(r.LargestMoves) > moves {
+		r.LargestMoves = r.LargestMoves[:moves]
+	}
+
+	r.Trend = fitTrend(r.Runs)
+	return r
+}
+
+// fitTrend returns nil unless there are at least two points at different
+// times.
+func fitTrend(points []trendPoint) *linearTrend {
+	if len(points) < 2 {
+		return nil
+	}
+
+	start := points[0].time
+	var sumX, sumY float64
+	for _, p := range points {
+		sumX += p.time.Sub(start).Hours() / 24
+		sumY += p.Median
+	}
+	n := float64(len(points))
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxx, sxy float64
+	for _, p := range points {
+		dx := p.time.Sub(start).Hours()/24 - meanX
+		sxx += dx * dx
+		sxy += dx * (p.Median - meanY)
+	}
+	if sxx == 0 {
+		return nil
+	}
+
+	t := &linearTrend{SlopePerDay: sxy / sxx}
+	if meanY != 0 {
+		t.PercentPer30Days = 30 * t.SlopePerDay / meanY * 100
+	}
+
+	return t
+}
+
+func writeTrendTable(w io.Writer, r trendReport) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	adjusted := len(r.Runs) > 0 && r.Runs[0].RealMedian != nil
+	header := "RUN\tCOUNT\tMEAN\tCHANGE\tMEDIAN\tCHANGE\t"
+	if adjusted {
+		header += "REAL MEAN\tREAL MEDIAN\t"
+	}
+	fmt.Fprintln(tw, header)
+	for _, p := range r.Runs {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t", p.Timestamp, p.Count,
+			formatMeanPrice(p.Mean), formatChange(p.MeanChange),
+			formatMeanPrice(p.Median), formatChange(p.MedianChange))
+		if adjusted {
+			fmt.Fprintf(tw, "%s\t%s\t", formatMeanPrice(*p.RealMean), formatMeanPrice(*p.RealMedian))
+		}
+		fmt.Fprintln(tw)
+	}
+
+	if r.Trend != nil {
+		fmt.Fprintf(tw, "\nmedian trend: %+.0f per day (%+.1f%% per 30 days)\n", r.Trend.SlopePerDay, r.Trend.PercentPer30Days)
+	}
+	if r.RealTrend != nil {
+		fmt.Fprintf(tw, "real median trend: %+.0f per day (%+.1f%% per 30 days)\n", r.RealTrend.SlopePerDay, r.RealTrend.PercentPer30Days)
+	}
+
+	if len(r.LargestMoves) > 0 {
+		fmt.Fprintln(tw, "\nLARGEST MOVES")
+		for _, m := range r.LargestMoves {
+			fmt.Fprintf(tw, "%s\t->\t%s\t%+.1f%%\t\n", m.From, m.To, m.ChangePercent)
+		}
+	}
+
+	return tw.Flush()
+}
+
+func formatChange(change *float64) string {
+	if change == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%+.1f%%", *change)
+}
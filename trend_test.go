@@ -0,0 +1,169 @@
+package zoopla
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func trendFixture(name string) string {
+	return filepath.Join("testdata", "trend", name)
+}
+
+func trendRuns() []runEntry {
+	sw2 := runQuery{Postcode: "SW2", Radius: 1}
+	return []runEntry{
+		{Timestamp: "2021-04-11T00:00:00Z", Query: sw2, Prices: []uint64{420000, 480000, 540000}},
+		{Timestamp: "2021-04-01T00:00:00Z", Query: sw2, Prices: []uint64{400000, 500000}},
+		{Timestamp: "2021-04-05T00:00:00Z", Query: runQuery{Postcode: "SE1", Radius: 1}, Prices: []uint64{900000}},
+		{Query: sw2, Prices: []uint64{100000}},
+		{Timestamp: "2021-04-21T00:00:00Z", Query: sw2, Prices: []uint64{500000, 520000}},
+	}
+}
+
+func TestAnalyseTrend(t *testing.T) {
+	r := analyseTrend(trendRuns(), defaultTrendMoves)
+
+	if r.Excluded != 2 {
+		t.Errorf("excluded %d runs, want 2", r.Excluded)
+	}
+
+	wantRuns := []struct {
+		timestamp     string
+		count         int
+		median        float64
+		medianChange  float64
+		hasPrevChange bool
+	}{
+		{"2021-04-01T00:00:00Z", 2, 450000, 0, false},
+		{"2021-04-11T00:00:00Z", 3, 480000, 100.0 / 15, true},
+		{"2021-04-21T00:00:00Z", 2, 510000, 6.25, true},
+	}
+	if len(r.Runs) != len(wantRuns) {
+		t.Fatalf("got %d runs, want %d", len(r.Runs), len(wantRuns))
+	}
+	for i, want := range wantRuns {
+		p := r.Runs[i]
+		if p.Timestamp != want.timestamp || p.Count != want.count || !closeTo(p.Median, want.median) {
+			t.Errorf("run %d: got %+v", i, p)
+		}
+		if (p.MedianChange != nil) != want.hasPrevChange || (p.MedianChange != nil && !closeTo(*p.MedianChange, want.medianChange)) {
+			t.Errorf("run %d: got median change %v, want %v", i, p.MedianChange, want.medianChange)
+		}
+	}
+
+	if len(r.LargestMoves) != 2 || r.LargestMoves[0].To != "2021-04-11T00:00:00Z" || r.LargestMoves[1].To != "2021-04-21T00:00:00Z" {
+		t.Errorf("got largest moves %+v", r.LargestMoves)
+	}
+
+	if r.Trend == nil || !closeTo(r.Trend.SlopePerDay, 3000) || !closeTo(r.Trend.PercentPer30Days, 18.75) {
+		t.Errorf("got trend %+v, want 3000 per day, 18.75%% per 30 days", r.Trend)
+	}
+}
+
+func TestAnalyseTrendMoves(t *testing.T) {
+	tests := []struct {
+		moves int
+		want  int
+	}{
+		{0, 0},
+		{1, 1},
+		{5, 2},
+	}
+
+	for _, tt := range tests {
+		if got := analyseTrend(trendRuns(), tt.moves).LargestMoves; len(got) != tt.want {
+			t.Errorf("with %d moves got %+v, want %d", tt.moves, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyseTrendTooFewRuns(t *testing.T) {
+	tests := []struct {
+		name string
+		runs []runEntry
+	}{
+		{"none", nil},
+		{"one", trendRuns()[4:]},
+		{"same time", []runEntry{trendRuns()[4], trendRuns()[4]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := analyseTrend(tt.runs, defaultTrendMoves)
+			if r.Trend != nil {
+				t.Errorf("got trend %+v", *r.Trend)
+			}
+			if r.Runs == nil || r.LargestMoves == nil {
+				t.Error("got nil slices, which marshal as null")
+			}
+		})
+	}
+}
+
+func TestFormatChange(t *testing.T) {
+	up, down := 6.25, -1.04
+	tests := []struct {
+		change *float64
+		want   string
+	}{
+		{nil, ""},
+		{&up, "+6.2%"},
+		{&down, "-1.0%"},
+	}
+
+	for _, tt := range tests {
+		if got := formatChange(tt.change); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestRunTrendGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		argv   []string
+		golden string
+	}{
+		{"table", nil, "table.golden.txt"},
+		{"json", []string{"--format", "json", "--pretty", "--moves", "1"}, "json.golden.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argv := append([]string{trendFixture("history.json")}, tt.argv...)
+			var err error
+			got, stderr := captureOutput(t, func() { err = runTrend(context.Background(), argv) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(stderr, "warning: excluded 2 runs") {
+				t.Errorf("log %q has no warning about the excluded runs", stderr)
+			}
+
+			golden := trendFixture(tt.golden)
+			if *updateGoldens {
+				if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("trend differs from %s, rerun with -update if the change is deliberate:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestRunTrendNotHistory(t *testing.T) {
+	err := runTrend(context.Background(), []string{diffFixture("old.json")})
+	if err == nil || !strings.Contains(err.Error(), "trend needs an --append history file") {
+		t.Errorf("got %v, want an error about the file kind", err)
+	}
+}
@@ -0,0 +1,111 @@
+package zoopla
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	trimIQR    = "iqr"
+	trimZScore = "zscore"
+)
+
+// outlierRule decides which prices to trim before calculating stats: those
+// beyond the box plot whiskers, or those more than z standard deviations
+// from the mean.
+type outlierRule struct {
+	kind string
+	z    float64
+}
+
+// parseOutlierRule parses --trim-outliers, which is "iqr" or "zscore:N".
+func parseOutlierRule(arg string) (outlierRule, error) {
+	if arg == trimIQR {
+		return outlierRule{kind: trimIQR}, nil
+	}
+
+	if strings.HasPrefix(arg, trimZScore+":") {
+		z, err := strconv.ParseFloat(strings.TrimPrefix(arg, trimZScore+":"), 64)
+		if err != nil || z <= 0 {
+			return outlierRule{}, errors.Errorf("invalid z-score threshold in %q, must be a positive number", arg)
+		}
+		return outlierRule{kind: trimZScore, z: z}, nil
+	}
+
+	return outlierRule{}, errors.Errorf("unknown outlier rule %q, must be %s or %s:N", arg, trimIQR, trimZScore)
+}
+
+// trimStatsPrices trims the outliers from prices by the rule given with
+// --trim-outliers, which parseArgs has already validated. If every price is
+// an outlier nothing is trimmed.
+func trimStatsPrices(prices []uint64, arg string) []uint64 {
+	rule, err := parseOutlierRule(arg)
+	if err != nil {
+		return prices
+	}
+
+	kept, trimmed := trimOutliers(prices, rule)
+	if len(trimmed) == 0 {
+		log.Print("trimmed no outliers")
+		return prices
+	}
+	if len(kept) == 0 {
+		log.Printf("warning: every price is an outlier by %s, not trimming", arg)
+		return prices
+	}
+
+	values := make([]string, len(trimmed))
+	for i, p := range trimmed {
+		values[i] = formatPrice(p)
+	}
+	log.Printf("trimmed %d outliers: %s", len(trimmed), strings.Join(values, ", "))
+
+	return kept
+}
+
+// trimOutliers splits prices into those kept and the outliers trimmed by
+// rule, both in their original order.
+func trimOutliers(prices []uint64, rule outlierRule) (kept, trimmed []uint64) {
+	if len(prices) == 0 {
+		return prices, nil
+	}
+
+	isOutlier := rule.iqrOutlier(prices)
+	if rule.kind == trimZScore {
+		isOutlier = rule.zScoreOutlier(prices)
+	}
+
+	for _, p := range prices {
+		if isOutlier(p) {
+			trimmed = append(trimmed, p)
+		} else {
+			kept = append(kept, p)
+		}
+	}
+
+	return kept, trimmed
+}
+
+func (r outlierRule) iqrOutlier(prices []uint64) func(uint64) bool {
+	sorted := make([]uint64, len(prices))
+	copy(sorted, prices)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return calculateBoxPlot(sorted).isOutlier
+}
+
+// zScoreOutlier never trims identical prices, which have no spread to
+// measure against.
+func (r outlierRule) zScoreOutlier(prices []uint64) func(uint64) bool {
+	mean := calculateMean(prices)
+	stddev := calculateStddev(prices, mean)
+
+	return func(p uint64) bool {
+		return stddev > 0 && math.Abs(float64(p)-mean)/stddev > r.z
+	}
+}
@@ -0,0 +1,147 @@
+package zoopla
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOutlierRule(t *testing.T) {
+	tests := []struct {
+		arg     string
+		want    outlierRule
+		wantErr string
+	}{
+		{arg: "iqr", want: outlierRule{kind: trimIQR}},
+		{arg: "zscore:3", want: outlierRule{kind: trimZScore, z: 3}},
+		{arg: "zscore:2.5", want: outlierRule{kind: trimZScore, z: 2.5}},
+		{arg: "zscore:0", wantErr: "must be a positive number"},
+		{arg: "zscore:-1", wantErr: "must be a positive number"},
+		{arg: "zscore:", wantErr: "must be a positive number"},
+		{arg: "zscore", wantErr: `unknown outlier rule "zscore", must be iqr or zscore:N`},
+		{arg: "IQR", wantErr: "unknown outlier rule"},
+		{arg: "", wantErr: "unknown outlier rule"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.arg, func(t *testing.T) {
+			got, err := parseOutlierRule(tt.arg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got %+v, %v, want an error containing %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimOutliers(t *testing.T) {
+	tests := []struct {
+		name          string
+		prices        []uint64
+		rule          outlierRule
+		kept, trimmed []uint64
+	}{
+		{name: "empty", rule: outlierRule{kind: trimIQR}},
+		{
+			name:   "iqr keeps order",
+			prices: []uint64{300, 5000, 100, 400, 200},
+			rule:   outlierRule{kind: trimIQR},
+			kept:   []uint64{300, 100, 400, 200}, trimmed: []uint64{5000},
+		},
+		{
+			name:   "iqr none",
+			prices: []uint64{100, 200, 300, 400, 500},
+			rule:   outlierRule{kind: trimIQR},
+			kept:   []uint64{100, 200, 300, 400, 500},
+		},
+		{
+			// The outlier is 1.79 standard deviations above the mean.
+			name:   "zscore within",
+			prices: []uint64{100, 100, 1000, 100, 100},
+			rule:   outlierRule{kind: trimZScore, z: 2},
+			kept:   []uint64{100, 100, 1000, 100, 100},
+		},
+		{
+			name:   "zscore beyond",
+			prices: []uint64{100, 100, 1000, 100, 100},
+			rule:   outlierRule{kind: trimZScore, z: 1.5},
+			kept:   []uint64{100, 100, 100, 100}, trimmed: []uint64{1000},
+		},
+		{
+			name:   "zscore identical prices",
+			prices: []uint64{100, 100, 100},
+			rule:   outlierRule{kind: trimZScore, z: 0.1},
+			kept:   []uint64{100, 100, 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, trimmed := trimOutliers(tt.prices, tt.rule)
+			if !equalPrices(kept, tt.kept) {
+				t.Errorf("got kept %v, want %v", kept, tt.kept)
+			}
+			if !equalPrices(trimmed, tt.trimmed) {
+				t.Errorf("got trimmed %v, want %v", trimmed, tt.trimmed)
+			}
+		})
+	}
+}
+
+func TestTrimStatsPrices(t *testing.T) {
+	tests := []struct {
+		name    string
+		prices  []uint64
+		arg     string
+		want    []uint64
+		wantLog string
+	}{
+		{
+			name:    "trimmed",
+			prices:  []uint64{300000, 5000000, 100000, 400000, 200000},
+			arg:     "iqr",
+			want:    []uint64{300000, 100000, 400000, 200000},
+			wantLog: "trimmed 1 outliers: £5,000,000",
+		},
+		{
+			name:    "nothing to trim",
+			prices:  []uint64{100, 200, 300},
+			arg:     "iqr",
+			want:    []uint64{100, 200, 300},
+			wantLog: "trimmed no outliers",
+		},
+		{
+			name:    "every price an outlier",
+			prices:  []uint64{100, 200},
+			arg:     "zscore:0.5",
+			want:    []uint64{100, 200},
+			wantLog: "warning: every price is an outlier by zscore:0.5, not trimming",
+		},
+		{
+			name:   "invalid rule",
+			prices: []uint64{100, 200, 300},
+			arg:    "bogus",
+			want:   []uint64{100, 200, 300},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []uint64
+			_, stderr := captureOutput(t, func() { got = trimStatsPrices(tt.prices, tt.arg) })
+			if !equalPrices(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if !strings.Contains(stderr, tt.wantLog) {
+				t.Errorf("log %q has no %q", stderr, tt.wantLog)
+			}
+		})
+	}
+}
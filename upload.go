@@ -0,0 +1,132 @@
+package zoopla
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultUploadKeyTemplate = "{timestamp}/{postcode}/{file}"
+	defaultUploadRegion      = "us-east-1"
+
+	// uploadPartSize is the part size for multipart uploads, which the
+	// uploader switches to for files larger than one part.
+	uploadPartSize = 16 << 20
+)
+
+// uploadKey expands the key template for a file. The URL's path is used as
+// a prefix.
+func uploadKey(prefix, template string, args *cliArgs, timestamp time.Time, filename string) string {
+	if template == "" {
+		template = defaultUploadKeyTemplate
+	}
+
+	key := strings.NewReplacer(
+		"{timestamp}", timestamp.UTC().Format("20060102T150405Z"),
+		"{postcode}", postcodeKey(args.Postcode),
+		"{file}", filepath.Base(filename),
+	).Replace(template)
+
+	return path.Join(strings.Trim(prefix, "/"), key)
+}
+
+// uploadedFiles returns the files a run wrote that should be uploaded.
+func uploadedFiles(args *cliArgs) []string {
+	var files []string
+	for _, f := range []string{args.OutputFilename, args.StatsFile, args.ReportMD, args.ReportHTML, args.Chart, args.URLsFile} {
+		if f == "" || f == stdoutFilename {
+			continue
+		}
+		if _, err := os.Stat(f); err == nil {
+			files = append(files, f)
+		}
+	}
+
+	return files
+}
+
+// uploadArtifacts uploads the run's files to an s3://bucket/prefix URL,
+// using the standard AWS credential chain of environment variables, shared
+// config and instance roles.
+func uploadArtifacts(ctx context.Context, args *cliArgs) error {
+	u, err := url.Parse(args.UploadURL)
+	if err != nil {
+		return errors.Wrap(err, "while parsing upload URL")
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return errors.Errorf("invalid upload URL %q, expected s3://bucket/prefix/", args.UploadURL)
+	}
+
+	cfg := aws.NewConfig()
+	if args.UploadEndpoint != "" {
+		cfg = cfg.WithEndpoint(args.UploadEndpoint).WithS3ForcePathStyle(true)
+	}
+	if args.UploadRegion != "" {
+		cfg = cfg.WithRegion(args.UploadRegion)
+	} else if os.Getenv("AWS_REGION") == "" && os.Getenv("AWS_DEFAULT_REGION") == "" {
+		cfg = cfg.WithRegion(defaultUploadRegion)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *cfg,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return errors.Wrap(err, "while creating AWS session")
+	}
+
+	uploader := s3manager.NewUploader(sess, func(u *s3manager.Uploader) {
+		u.PartSize = uploadPartSize
+	})
+
+	// Every file from a run shares a timestamp so they land together.
+	timestamp := timeNow()
+	for _, filename := range uploadedFiles(args) {
+		key := uploadKey(u.Path, args.UploadKeyTemplate, args, timestamp, filename)
+		if err := uploadFile(ctx, uploader, u.Host, key, filename); err != nil {
+			return err
+		}
+		log.Printf("uploaded %s to s3://%s/%s", filename, u.Host, key)
+	}
+
+	return nil
+}
+
+func uploadFile(ctx context.Context, uploader *s3manager.Uploader, bucket, key, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return errors.Wrapf(err, "while opening %s for upload", filename)
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	_, err = uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   f,
+	})
+	if err == nil {
+		return nil
+	}
+
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) && (awsErr.Code() == "AccessDenied" || awsErr.Code() == "Forbidden") {
+		return errors.Errorf("permission denied uploading %s to s3://%s/%s: check the credentials allow s3:PutObject on the bucket", filename, bucket, key)
+	}
+
+	return errors.Wrapf(err, "while uploading %s to s3://%s/%s", filename, bucket, key)
+}
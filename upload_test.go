@@ -0,0 +1,173 @@
+package zoopla
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUploadKey(t *testing.T) {
+	timestamp := time.Date(2021, time.April, 1, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		prefix   string
+		template string
+		postcode string
+		filename string
+		want     string
+	}{
+		{"default", "/", "", "SW2", "out/prices.json", "20210401T093000Z/SW2/prices.json"},
+		{"prefix", "/runs/", "", "SW2", "prices.json", "runs/20210401T093000Z/SW2/prices.json"},
+		{"template", "/runs", "{postcode}/{file}", "SW2 1AA", "report.md", "runs/SW21AA/report.md"},
+		{"no placeholders", "", "latest.json", "SW2", "prices.json", "latest.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := uploadKey(tt.prefix, tt.template, &cliArgs{Postcode: tt.postcode}, timestamp, tt.filename)
+			if got != tt.want {
+				t.Errorf("uploadKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadedFiles(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "prices.json")
+	report := filepath.Join(dir, "report.md")
+	for _, f := range []string{output, report} {
+		if err := ioutil.WriteFile(f, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The stats file wasn't written, so there's nothing to upload.
+	args := &cliArgs{OutputFilename: output, StatsFile: filepath.Join(dir, "stats.json"), ReportMD: report}
+	if got := uploadedFiles(args); !reflect.DeepEqual(got, []string{output, report}) {
+		t.Errorf("uploadedFiles() = %q", got)
+	}
+
+	if got := uploadedFiles(&cliArgs{OutputFilename: stdoutFilename}); len(got) != 0 {
+		t.Errorf("uploadedFiles() = %q for stdout, want none", got)
+	}
+}
+
+// fakeS3 accepts path-style PUTs, keeping the objects by path, or denies
+// them all.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]string
+	auth    []string
+	deny    bool
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.deny {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`))
+		return
+	}
+	if r.Method != http.MethodPut {
+		http.Error(w, "unexpected "+r.Method, http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	s.objects[r.URL.Path] = string(body)
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+	w.Header().Set("ETag", `"etag"`)
+}
+
+// setTestAWSCredentials gives the AWS SDK static credentials, keeping it
+// away from any real config.
+func setTestAWSCredentials(t *testing.T) {
+	t.Helper()
+
+	setEnv(t, "AWS_ACCESS_KEY_ID", "AKIDTEST")
+	setEnv(t, "AWS_SECRET_ACCESS_KEY", "secret")
+	setEnv(t, "AWS_SESSION_TOKEN", "")
+	setEnv(t, "AWS_PROFILE", "")
+	setEnv(t, "AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	setEnv(t, "AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	setEnv(t, "AWS_EC2_METADATA_DISABLED", "true")
+}
+
+func TestUploadArtifacts(t *testing.T) {
+	setTestAWSCredentials(t)
+	setNow(t, time.Date(2021, time.April, 1, 9, 30, 0, 0, time.UTC))
+
+	s3 := &fakeS3{objects: map[string]string{}}
+	srv := httptest.NewServer(s3)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "prices.json")
+	stats := filepath.Join(dir, "stats.json")
+	for f, data := range map[string]string{output: "[450000]", stats: `{"count":1}`} {
+		if err := ioutil.WriteFile(f, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	args := &cliArgs{Postcode: "SW2", OutputFilename: output, StatsFile: stats, UploadURL: "s3://prices/runs/", UploadEndpoint: srv.URL}
+	if err := uploadArtifacts(context.Background(), args); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"/prices/runs/20210401T093000Z/SW2/prices.json": "[450000]",
+		"/prices/runs/20210401T093000Z/SW2/stats.json":  `{"count":1}`,
+	}
+	if !reflect.DeepEqual(s3.objects, want) {
+		var keys []string
+		for k := range s3.objects {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		t.Errorf("uploaded %q, want %v", keys, want)
+	}
+	for _, auth := range s3.auth {
+		if !strings.Contains(auth, "Credential=AKIDTEST/") {
+			t.Errorf("request wasn't signed with the environment's credentials: %q", auth)
+		}
+	}
+}
+
+func TestUploadArtifactsPermissionDenied(t *testing.T) {
+	setTestAWSCredentials(t)
+
+	srv := httptest.NewServer(&fakeS3{deny: true})
+	defer srv.Close()
+
+	output := filepath.Join(t.TempDir(), "prices.json")
+	if err := ioutil.WriteFile(output, []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := &cliArgs{Postcode: "SW2", OutputFilename: output, UploadURL: "s3://prices/", UploadEndpoint: srv.URL}
+	err := uploadArtifacts(context.Background(), args)
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("uploadArtifacts() error = %v, want permission denied", err)
+	}
+}
+
+func TestUploadArtifactsInvalidURL(t *testing.T) {
+	for _, u := range []string{"https://bucket/prefix", "s3:///prefix", "://"} {
+		if err := uploadArtifacts(context.Background(), &cliArgs{UploadURL: u}); err == nil {
+			t.Errorf("uploadArtifacts(%q) succeeded", u)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package zoopla
+
+import (
+	"sort"
+	"strings"
+)
+
+// renderURLs lists each listing's URL on its own line, cheapest first, with
+// its price and address as a comment above it when the address is known.
+func renderURLs(listings []listing) []byte {
+	sorted := make([]listing, 0, len(listings))
+	for _, l := range listings {
+		if l.URL != "" {
+			sorted = append(sorted, l)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Price < sorted[j].Price })
+
+	var sb strings.Builder
+	for _, l := range sorted {
+		if l.Address != "" {
+			sb.WriteString("# " + formatPrice(l.Price) + " – " + strings.ReplaceAll(l.Address, "\n", " ") + "\n")
+		}
+		sb.WriteString(l.URL + "\n")
+	}
+
+	return []byte(sb.String())
+}
@@ -0,0 +1,59 @@
+package zoopla
+
+import "testing"
+
+func TestRenderURLs(t *testing.T) {
+	tests := []struct {
+		name     string
+		listings []listing
+		want     string
+	}{
+		{name: "empty"},
+		{
+			name: "cheapest first",
+			listings: []listing{
+				{Price: 500000, URL: "https://example.com/2", Address: "Brixton Hill"},
+				{Price: 400000, URL: "https://example.com/1", Address: "Acre Lane"},
+			},
+			want: "# £400,000 – Acre Lane\nhttps://example.com/1\n# £500,000 – Brixton Hill\nhttps://example.com/2\n",
+		},
+		{
+			name: "without address",
+			listings: []listing{
+				{Price: 400000, URL: "https://example.com/1"},
+			},
+			want: "https://example.com/1\n",
+		},
+		{
+			name: "without URL",
+			listings: []listing{
+				{Price: 400000, Address: "Acre Lane"},
+				{Price: 500000, URL: "https://example.com/2"},
+			},
+			want: "https://example.com/2\n",
+		},
+		{
+			name: "multi-line address",
+			listings: []listing{
+				{Price: 400000, URL: "https://example.com/1", Address: "Flat 1\nAcre Lane"},
+			},
+			want: "# £400,000 – Flat 1 Acre Lane\nhttps://example.com/1\n",
+		},
+		{
+			name: "ties keep their order",
+			listings: []listing{
+				{Price: 400000, URL: "https://example.com/b"},
+				{Price: 400000, URL: "https://example.com/a"},
+			},
+			want: "https://example.com/b\nhttps://example.com/a\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(renderURLs(tt.listings)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
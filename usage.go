@@ -0,0 +1,58 @@
+package zoopla
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/alexflint/go-arg"
+	"github.com/pkg/errors"
+)
+
+// exitCodeUsage is the exit code for invalid arguments.
+const exitCodeUsage = 2
+
+// errHelp is returned once the help has been printed, so that the run stops
+// there without failing.
+var errHelp = errors.New("help requested")
+
+// usageError is an invalid command line. Main prints it under the usage of
+// the parser that rejected it, as arg.MustParse would, and exits with
+// exitCodeUsage.
+type usageError struct {
+	p   *arg.Parser
+	msg string
+}
+
+func newUsageError(p *arg.Parser, msg string) error {
+	return &usageError{p: p, msg: msg}
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func (e *usageError) write(w io.Writer) {
+	e.p.WriteUsage(w)
+	fmt.Fprintln(w, "error:", e.msg)
+}
+
+// parseCommandLine parses args into dest for program. It prints the help and
+// returns errHelp if asked for it, and returns a usageError for invalid
+// arguments.
+func parseCommandLine(program string, dest interface{}, args []string) (*arg.Parser, error) {
+	p, err := arg.NewParser(arg.Config{Program: program}, dest)
+	if err != nil {
+		return nil, errors.Wrap(err, "while building the argument parser")
+	}
+
+	switch err := p.Parse(args); {
+	case err == arg.ErrHelp:
+		p.WriteHelp(os.Stdout)
+		return p, errHelp
+	case err != nil:
+		return p, newUsageError(p, err.Error())
+	}
+
+	return p, nil
+}
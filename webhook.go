@@ -0,0 +1,51 @@
+package zoopla
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+const defaultWebhookSignatureHeader = "X-Signature-256"
+
+type webhookPayload struct {
+	*outputMetadata
+	Stats  *priceStats `json:"stats,omitempty"`
+	Prices []uint64    `json:"prices,omitempty"`
+}
+
+// sendWebhook posts a run's results to args.Webhook. With a secret the body
+// is signed with HMAC-SHA256, sent as "sha256=<hex>" in the signature header.
+func sendWebhook(ctx context.Context, args *cliArgs, meta *outputMetadata, stats *priceStats, prices []uint64) error {
+	payload := webhookPayload{outputMetadata: meta, Stats: stats}
+	if args.WebhookIncludePrices {
+		payload.Prices = prices
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(err, "while marshalling webhook payload")
+	}
+
+	header := make(http.Header)
+	if args.WebhookSecret != "" {
+		headerName := args.WebhookSignatureHeader
+		if headerName == "" {
+			headerName = defaultWebhookSignatureHeader
+		}
+		header.Set(headerName, signPayload(body, args.WebhookSecret))
+	}
+
+	return errors.Wrap(postJSON(ctx, args.Webhook, body, header), "while sending webhook")
+}
+
+func signPayload(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
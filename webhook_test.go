@@ -0,0 +1,107 @@
+package zoopla
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// webhookRequest is what a test receiver got.
+type webhookRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookReceiver(t *testing.T) (*httptest.Server, <-chan webhookRequest) {
+	t.Helper()
+
+	reqs := make(chan webhookRequest, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		reqs <- webhookRequest{header: r.Header, body: body}
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, reqs
+}
+
+func TestSignPayload(t *testing.T) {
+	// From GitHub's webhook documentation.
+	got := signPayload([]byte("Hello, World!"), "It's a Secret to Everybody")
+	want := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	if got != want {
+		t.Errorf("signPayload() = %q, want %q", got, want)
+	}
+}
+
+func TestSendWebhook(t *testing.T) {
+	stats := calculatePriceStats([]uint64{300000, 400000})
+
+	tests := []struct {
+		name       string
+		args       cliArgs
+		wantHeader string
+		wantPrices bool
+	}{
+		{name: "unsigned"},
+		{name: "signed", args: cliArgs{WebhookSecret: "secret"}, wantHeader: defaultWebhookSignatureHeader},
+		{name: "signature header", args: cliArgs{WebhookSecret: "secret", WebhookSignatureHeader: "X-Hub-Signature-256"}, wantHeader: "X-Hub-Signature-256"},
+		{name: "prices", args: cliArgs{WebhookIncludePrices: true}, wantPrices: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, reqs := newWebhookReceiver(t)
+			tt.args.Webhook = srv.URL
+			meta := &outputMetadata{Query: runQuery{Postcode: "SW2"}}
+
+			if err := sendWebhook(context.Background(), &tt.args, meta, &stats, []uint64{300000, 400000}); err != nil {
+				t.Fatal(err)
+			}
+			req := <-reqs
+
+			if ct := req.header.Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			if tt.wantHeader == "" {
+				if sig := req.header.Get(defaultWebhookSignatureHeader); sig != "" {
+					t.Errorf("unsigned webhook has signature %q", sig)
+				}
+			} else {
+				mac := hmac.New(sha256.New, []byte(tt.args.WebhookSecret))
+				mac.Write(req.body)
+				want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+				if got := req.header.Get(tt.wantHeader); !hmac.Equal([]byte(got), []byte(want)) {
+					t.Errorf("%s = %q, want %q", tt.wantHeader, got, want)
+				}
+			}
+
+			var payload struct {
+				Query  runQuery                   `json:"query"`
+				Stats  map[string]json.RawMessage `json:"stats"`
+				Prices []uint64                   `json:"prices"`
+			}
+			if err := json.Unmarshal(req.body, &payload); err != nil {
+				t.Fatal(err)
+			}
+			if payload.Query.Postcode != "SW2" {
+				t.Errorf("payload query = %+v", payload.Query)
+			}
+			if string(payload.Stats["count"]) != "2" {
+				t.Errorf("payload stats count = %s, want 2", payload.Stats["count"])
+			}
+			if got := len(payload.Prices) > 0; got != tt.wantPrices {
+				t.Errorf("payload prices = %v, want them included: %t", payload.Prices, tt.wantPrices)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package zoopla
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	xlsxListingsSheet = "Listings"
+	xlsxSummarySheet  = "Summary"
+)
+
+func marshalXLSX(listings []listing, args *cliArgs) ([]byte, error) {
+	f := excelize.NewFile()
+	f.SetSheetName("Sheet1", xlsxListingsSheet)
+	f.NewSheet(xlsxSummarySheet)
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#DDEBF7"}},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "while creating header style")
+	}
+
+	if err := writeXLSXListings(f, listings, outputColumns(args), headerStyle); err != nil {
+		return nil, err
+	}
+
+	if err := writeXLSXSummary(f, listings, args, headerStyle); err != nil {
+		return nil, err
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, errors.Wrap(err, "while writing workbook")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeXLSXListings(f *excelize.File, listings []listing, columns []tableColumn, headerStyle int) error {
+	header := make([]interface{}, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	if err := f.SetSheetRow(xlsxListingsSheet, "A1", &header); err != nil {
+		return errors.Wrap(err, "while writing listings header")
+	}
+
+	lastCol, _ := excelize.ColumnNumberToName(len(columns))
+	if err := f.SetCellStyle(xlsxListingsSheet, "A1", lastCol+"1", headerStyle); err != nil {
+		return errors.Wrap(err, "while styling listings header")
+	}
+
+	for i, c := range columns {
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		if err := f.SetColWidth(xlsxListingsSheet, col, col, c.width); err != nil {
+			return errors.Wrap(err, "while setting column width")
+		}
+	}
+
+	for i := range listings {
+		row := make([]interface{}, len(columns))
+		for j, c := range columns {
+			row[j] = c.value(&listings[i])
+		}
+		if err := f.SetSheetRow(xlsxListingsSheet, fmt.Sprintf("A%d", i+2), &row); err != nil {
+			return errors.Wrap(err, "while writing listing row")
+		}
+	}
+
+	return nil
+}
+
+func writeXLSXSummary(f *excelize.File, listings []listing, args *cliArgs, headerStyle int) error {
+	prices := listingPrices(listings)
+	stats := calculatePriceStats(prices)
+
+	rows := [][]interface{}{
+		{"query"},
+		{"postcode", args.Postcode},
+		{"price min", optionalUint64(args.PriceMin)},
+		{"price max", optionalUint64(args.PriceMax)},
+		{"beds min", optionalUint32(args.BedsMin)},
+		{"beds max", optionalUint32(args.BedsMax)},
+		{"radius", args.Radius},
+		{},
+		{"stats"},
+		{"count", stats.count},
+		{"min", stats.min},
+		{"max", stats.max},
+		{"mean", stats.mean},
+		{"median", calculateMedian(prices)},
+		{"stddev", stats.stddev},
+	}
+
+	for i := range rows {
+		if err := f.SetSheetRow(xlsxSummarySheet, fmt.Sprintf("A%d", i+1), &rows[i]); err != nil {
+			return errors.Wrap(err, "while writing summary row")
+		}
+	}
+
+	for _, cell := range []string{"A1", "A9"} {
+		if err := f.SetCellStyle(xlsxSummarySheet, cell, cell, headerStyle); err != nil {
+			return errors.Wrap(err, "while styling summary")
+		}
+	}
+
+	return errors.Wrap(f.SetColWidth(xlsxSummarySheet, "A", "B", 16), "while setting column width")
+}
+
+func optionalUint64(n *uint64) interface{} {
+	if n == nil {
+		return nil
+	}
+
+	return *n
+}
+
+func optionalUint32(n *uint32) interface{} {
+	if n == nil {
+		return nil
+	}
+
+	return *n
+}
@@ -0,0 +1,109 @@
+package zoopla
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestMarshalXLSX(t *testing.T) {
+	priceMin := uint64(300000)
+	args := cliArgs{Postcode: "SW2", PriceMin: &priceMin, Radius: 1}
+	listings := []listing{
+		{ID: "1", Price: 450000, Beds: uint32Ptr(2), Address: "Acre Lane, Brixton SW2"},
+		{ID: "2", Price: 350000},
+	}
+
+	data, err := marshalXLSX(listings, &args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.GetSheetList(), []string{xlsxListingsSheet, xlsxSummarySheet}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sheets = %v, want %v", got, want)
+	}
+
+	rows, err := f.GetRows(xlsxListingsSheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{
+		columnNames(tableColumns),
+		{"1", "450000", "", "2", "", "Acre Lane, Brixton SW2"},
+		{"2", "350000"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("listings rows = %q, want %q", rows, want)
+	}
+
+	summary := make(map[string]string)
+	rows, err = f.GetRows(xlsxSummarySheet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if len(row) == 2 {
+			summary[row[0]] = row[1]
+		}
+	}
+	for key, want := range map[string]string{"postcode": "SW2", "price min": "300000", "price max": "", "radius": "1", "count": "2", "mean": "400000", "median": "400000"} {
+		if summary[key] != want {
+			t.Errorf("summary %s = %q, want %q", key, summary[key], want)
+		}
+	}
+
+	// Prices are numeric cells, so spreadsheets can sum and sort them.
+	if typ := xlsxCellType(t, data, "B2"); typ != "" && typ != "n" {
+		t.Errorf("price cell has type %q, want a number", typ)
+	}
+	if typ := xlsxCellType(t, data, "F2"); typ == "" || typ == "n" {
+		t.Errorf("address cell has type %q, want a string", typ)
+	}
+}
+
+// xlsxCellType returns the t attribute of a cell on the first sheet, which
+// is empty for numbers.
+func xlsxCellType(t *testing.T, workbook []byte, cell string) string {
+	t.Helper()
+
+	r, err := zip.NewReader(bytes.NewReader(workbook), int64(len(workbook)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range r.File {
+		if file.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		sheet, err := ioutil.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		m := regexp.MustCompile(`<c r="` + cell + `"([^>]*)>`).FindSubmatch(sheet)
+		if m == nil {
+			t.Fatalf("no cell %s in the sheet", cell)
+		}
+		if typ := regexp.MustCompile(` t="(\w+)"`).FindSubmatch(m[1]); typ != nil {
+			return string(typ[1])
+		}
+		return ""
+	}
+
+	t.Fatal("workbook has no first sheet")
+	return ""
+}
@@ -0,0 +1,33 @@
+package zoopla
+
+import (
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// marshalYAML writes v as block-style YAML with the same keys and key order
+// as its JSON encoding, so both formats share the struct tags. Going through
+// a YAML node tree also keeps large integers such as prices exact.
+func marshalYAML(v interface{}) ([]byte, error) {
+	data, err := marshalJSON(v, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "while converting to YAML")
+	}
+	clearYAMLStyle(&doc)
+
+	out, err := yaml.Marshal(&doc)
+	return out, errors.Wrap(err, "while marshalling YAML")
+}
+
+// clearYAMLStyle drops the flow and quoting styles carried over from JSON.
+func clearYAMLStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearYAMLStyle(c)
+	}
+}
@@ -0,0 +1,126 @@
+package zoopla
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlToJSON converts YAML back to JSON so it can be decoded into the
+// output structs, which only have JSON tags.
+func yamlToJSON(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	j, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return j
+}
+
+func TestMarshalYAMLPrices(t *testing.T) {
+	// Prices beyond float64's 53 bits of precision must survive.
+	prices := []uint64{450000, 1<<53 + 1, math.MaxUint64}
+
+	data, err := marshalYAML(prices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "- 450000\n- 9007199254740993\n- 18446744073709551615\n"; string(data) != want {
+		t.Errorf("marshalYAML() = %q, want %q", data, want)
+	}
+
+	var got []uint64
+	if err := yaml.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !equalPrices(got, prices) {
+		t.Errorf("round trip = %v, want %v", got, prices)
+	}
+}
+
+func TestMarshalYAMLKeyOrder(t *testing.T) {
+	data, err := marshalYAML(histogramBucket{Min: 100000, Max: 200000, Count: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The JSON field order, rather than sorted.
+	if want := "min: 100000\nmax: 200000\ncount: 3\n"; string(data) != want {
+		t.Errorf("marshalYAML() = %q, want %q", data, want)
+	}
+}
+
+func TestMarshalYAMLBlockStyle(t *testing.T) {
+	data, err := marshalYAML(map[string]interface{}{"list": []int{1, 2}, "text": "a: b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := string(data)
+	if strings.Contains(got, "[") || strings.Contains(got, "{") {
+		t.Errorf("marshalYAML() used flow style:\n%s", got)
+	}
+	var back map[string]interface{}
+	if err := yaml.Unmarshal(data, &back); err != nil || back["text"] != "a: b" {
+		t.Errorf("text round trips as %v (%v)", back["text"], err)
+	}
+}
+
+func TestMarshalOutputYAMLRoundTrip(t *testing.T) {
+	meta := &outputMetadata{GeneratedAt: "2021-04-01T00:00:00Z", Query: runQuery{Postcode: "SW2", PriceMax: uint64Ptr(500000)}, ToolVersion: "dev", PagesFetched: 2}
+	listings := []listing{fullListing(), {ID: "58500001", Price: 1<<53 + 1}}
+
+	tests := []struct {
+		name string
+		args cliArgs
+	}{
+		{"prices", cliArgs{Format: formatYAML}},
+		{"listings", cliArgs{Format: formatYAML, OutputMode: outputModeListings, OutputSet: outputSetDeduped}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := marshalOutput(listings, &tt.args, meta)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			jsonArgs := tt.args
+			jsonArgs.Format = formatJSON
+			want, err := marshalOutput(listings, &jsonArgs, meta)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := loadOutput(yamlToJSON(t, data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantOut, err := loadOutput(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, wantOut) {
+				t.Errorf("YAML loads as\n%+v\nwant the JSON's\n%+v", got, wantOut)
+			}
+
+			again, err := marshalOutput(listings, &tt.args, meta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(data) {
+				t.Error("YAML output isn't stable")
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const rentBaseURL = "https://www.zoopla.co.uk/to-rent/property"
+
+// yieldGroup is the gross yield for one bedroom count, matching the median
+// sale price with the median rent of listings with as many bedrooms. Yield
+// is nil when either side has too few listings.
+type yieldGroup struct {
+	Key         string   `json:"key"`
+	Sales       int      `json:"sales"`
+	Rents       int      `json:"rents"`
+	MedianPrice float64  `json:"median_price,omitempty"`
+	MedianRent  float64  `json:"median_monthly_rent,omitempty"`
+	Yield       *float64 `json:"gross_yield_percent,omitempty"`
+}
+
+// yieldEstimate compares the sale and rental searches. The overall yield is
+// the mean of the groups' yields weighted by their sales, so that a different
+// mix of bedrooms on either side doesn't skew it.
+type yieldEstimate struct {
+	sales   int
+	rents   int
+	groups  []yieldGroup
+	overall *float64
+}
+
+// grossYield is a year's rent as a percentage of the price.
+func grossYield(price, monthlyRent float64) float64 {
+	return 100 * 12 * monthlyRent / price
+}
+
+func calculateYield(sales, rents []listing, minGroupSize int) yieldEstimate {
+	e := yieldEstimate{sales: len(sales), rents: len(rents)}
+
+	salePrices := bedPrices(sales)
+	rentPrices := bedPrices(rents)
+
+	var keys []string
+	for k := range salePrices {
+		keys = append(keys, k)
+	}
+	for k := range rentPrices {
+		if _, ok := salePrices[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return lessGroupKey(keys[i], keys[j]) })
+
+	var weighted float64
+	var weights int
+	for _, k := range keys {
+		g := yieldGroup{Key: k, Sales: len(salePrices[k]), Rents: len(rentPrices[k])}
+		if g.Sales > 0 {
+			g.MedianPrice = calculateMedian(salePrices[k])
+		}
+		if g.Rents > 0 {
+			g.MedianRent = calculateMedian(rentPrices[k])
+		}
+
+		if g.Sales > 0 && g.Rents > 0 && g.Sales >= minGroupSize && g.Rents >= minGroupSize && g.MedianPrice > 0 {
+			y := grossYield(g.MedianPrice, g.MedianRent)
+			g.Yield = &y
+			weighted += y * float64(g.Sales)
+			weights += g.Sales
+		}
+
+		e.groups = append(e.groups, g)
+	}
+
+	if weights > 0 {
+		overall := weighted / float64(weights)
+		e.overall = &overall
+	}
+
+	return e
+}
+
+// bedPrices groups the non-zero prices by bedroom count, leaving out
+// listings whose bedrooms aren't known since they can't be matched.
+func bedPrices(listings []listing) map[string][]uint64 {
+	prices := make(map[string][]uint64)
+	for i := range listings {
+		l := &listings[i]
+		if l.Beds == nil || l.Price == 0 {
+			continue
+		}
+
+		k := bedsKey(l)
+		prices[k] = append(prices[k], l.Price)
+	}
+
+	return prices
+}
+
+// runYieldEstimate searches for rentals with the same postcodes and bedroom
+// filters as the sale search, using the same fetcher so that both keep to
+// its delay. Failures are warnings, as the sale results still stand.
+func runYieldEstimate(ctx context.Context, f *fetcher, args *cliArgs, postcodes []string, sales []listing) *yieldEstimate {
+	q := *args
+	q.BaseURL = args.RentBaseURL
+	q.Mode = rentSearch
+	q.PriceMin, q.PriceMax = nil, nil
+
+	rents, err := getQueryListings(ctx, f, newDiagnostics(&q), &q, postcodes, nil)
+	if err != nil {
+		log.Print("warning: ", errors.Wrap(err, "while searching rentals"))
+		return nil
+	}
+
+	rents = filterCategories(rents, q.IncludeCategory)
+	rents, _ = splitComingSoon(rents)
+	assignIdentity(rents)
+	rents = dedupeByID(rents)
+	log.Printf("got %d rents", len(rents))
+	if len(rents) == 0 {
+		log.Print("warning: no rentals found, cannot estimate yield")
+		return nil
+	}
+
+	e := calculateYield(sales, rents, args.MinGroupSize)
+	return &e
+}
+
+func reportYieldGroups(groups []yieldGroup) []reportYieldGroup {
+	rows := make([]reportYieldGroup, len(groups))
+	for i, g := range groups {
+		rows[i] = reportYieldGroup{
+			Key:         g.Key,
+			Sales:       g.Sales,
+			Rents:       g.Rents,
+			MedianPrice: g.MedianPrice,
+			MedianRent:  g.MedianRent,
+			Estimated:   g.Yield != nil,
+		}
+		if g.Yield != nil {
+			rows[i].Yield = *g.Yield
+		}
+	}
+
+	return rows
+}
+
+func (g yieldGroup) String() string {
+	s := fmt.Sprintf("%s: %d sales, %d rents", g.Key, g.Sales, g.Rents)
+	if g.Yield == nil {
+		return s + ", too few to estimate"
+	}
+
+	return fmt.Sprintf("%s, median price = %.0f, median rent = %.0f pcm, gross yield = %.1f%%",
+		s, g.MedianPrice, g.MedianRent, *g.Yield)
+}
+
+func (e yieldEstimate) String() string {
+	s := fmt.Sprintf("%d sales, %d rents", e.sales, e.rents)
+	if e.overall == nil {
+		return s + ", no bedroom count with enough of both"
+	}
+
+	var matched []string
+	for _, g := range e.groups {
+		if g.Yield != nil {
+			matched = append(matched, g.Key)
+		}
+	}
+
+	return fmt.Sprintf("%s, gross yield = %.1f%% over %s", s, *e.overall, strings.Join(matched, ", "))
+}
+
+func (e yieldEstimate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Sales   int          `json:"sales"`
+		Rents   int          `json:"rents"`
+		Overall *float64     `json:"gross_yield_percent,omitempty"`
+		ByBeds  []yieldGroup `json:"by_beds"`
+	}{e.sales, e.rents, e.overall, e.groups})
+}
@@ -0,0 +1,228 @@
+package zoopla
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/ryanc414/zoopla-analyzer/internal/testserver"
+)
+
+// rentPage is a search results page of rental cards with the given beds
+// and monthly rents.
+func rentPage(beds uint32, rents ...uint64) string {
+	var sb strings.Builder
+	sb.WriteString(`<html><body><div class="ListingsContainer">`)
+	for _, r := range rents {
+		fmt.Fprintf(&sb, `<div data-testid="search-result"><h2>%d bed flat to rent</h2>`+
+			`<div class="PriceContainer"><p>%s pcm</p></div><a href="/to-rent/details/%d/">Details</a></div>`, beds, formatPrice(r), r)
+	}
+	sb.WriteString(`</div></body></html>`)
+
+	return sb.String()
+}
+
+// yieldRents has three 1 bed and two 2 bed rents to match reportListings,
+// along with two that can't be matched.
+func yieldRents() []listing {
+	return []listing{
+		{Price: 1400, Beds: uint32Ptr(1)},
+		{Price: 1500, Beds: uint32Ptr(1)},
+		{Price: 1600, Beds: uint32Ptr(1)},
+		{Price: 0, Beds: uint32Ptr(1)},
+		{Price: 2000, Beds: uint32Ptr(2)},
+		{Price: 2200, Beds: uint32Ptr(2)},
+		{Price: 3000},
+	}
+}
+
+func TestGrossYield(t *testing.T) {
+	tests := []struct {
+		price, rent float64
+		want        float64
+	}{
+		{400000, 2000, 6},
+		{320000, 1500, 5.625},
+		{500000, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := grossYield(tt.price, tt.rent); !closeTo(got, tt.want) {
+			t.Errorf("grossYield(%v, %v) = %v, want %v", tt.price, tt.rent, got, tt.want)
+		}
+	}
+}
+
+func TestBedPrices(t *testing.T) {
+	got := bedPrices(yieldRents())
+
+	want := map[string][]uint64{"1 bed": {1400, 1500, 1600}, "2 bed": {2000, 2200}}
+	if len(got) != len(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for k, w := range want {
+		if !equalUint64s(got[k], w) {
+			t.Errorf("%s: got %v, want %v", k, got[k], w)
+		}
+	}
+}
+
+func TestCalculateYield(t *testing.T) {
+	tests := []struct {
+		name         string
+		minGroupSize int
+		wantYields   []float64
+		wantOverall  float64
+	}{
+		// 1 and 2 bed are weighted equally as both have three sales.
+		{"every group", 2, []float64{5.625, 5.25, 0}, (5.625 + 5.25) / 2},
+		{"2 bed too few rents", 3, []float64{5.625, 0, 0}, 5.625},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := calculateYield(reportListings(), yieldRents(), tt.minGroupSize)
+			if e.sales != 8 || e.rents != 7 || len(e.groups) != 3 {
+				t.Fatalf("got %+v", e)
+			}
+
+			for i, want := range tt.wantYields {
+				g := e.groups[i]
+				if want == 0 {
+					if g.Yield != nil {
+						t.Errorf("%s: got yield %v, want none", g.Key, *g.Yield)
+					}
+					continue
+				}
+				if g.Yield == nil || !closeTo(*g.Yield, want) {
+					t.Errorf("%s: got yield %v, want %v", g.Key, derefFloat64(g.Yield), want)
+				}
+			}
+			if e.overall == nil || !closeTo(*e.overall, tt.wantOverall) {
+				t.Errorf("got overall %v, want %v", derefFloat64(e.overall), tt.wantOverall)
+			}
+		})
+	}
+}
+
+func TestCalculateYieldUnmatched(t *testing.T) {
+	sales := []listing{{Price: 400000, Beds: uint32Ptr(1)}, {Price: 400000}}
+	rents := []listing{{Price: 1500, Beds: uint32Ptr(2)}}
+
+	e := calculateYield(sales, rents, 1)
+	if e.overall != nil {
+		t.Errorf("got overall %v, want none with no bedroom count in both", *e.overall)
+	}
+
+	keys := []string{e.groups[0].Key, e.groups[1].Key}
+	if !equalStrings(keys, []string{"1 bed", "2 bed"}) || e.groups[0].Rents != 0 || e.groups[1].Sales != 0 {
+		t.Errorf("got groups %+v", e.groups)
+	}
+	if want := "2 sales, 1 rents, no bedroom count with enough of both"; e.String() != want {
+		t.Errorf("got %q, want %q", e.String(), want)
+	}
+}
+
+func TestYieldStrings(t *testing.T) {
+	e := calculateYield(reportListings(), yieldRents(), 2)
+
+	if want := "8 sales, 7 rents, gross yield = 5.4% over 1 bed, 2 bed"; e.String() != want {
+		t.Errorf("got %q, want %q", e.String(), want)
+	}
+
+	want := []string{
+		"1 bed: 3 sales, 3 rents, median price = 320000, median rent = 1500 pcm, gross yield = 5.6%",
+		"2 bed: 3 sales, 2 rents, median price = 480000, median rent = 2100 pcm, gross yield = 5.2%",
+		"3 bed: 2 sales, 0 rents, too few to estimate",
+	}
+	for i, w := range want {
+		if got := e.groups[i].String(); got != w {
+			t.Errorf("got %q, want %q", got, w)
+		}
+	}
+}
+
+func TestYieldEstimateJSON(t *testing.T) {
+	data, err := json.Marshal(calculateYield(reportListings(), yieldRents(), 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"sales":8,"rents":7,"gross_yield_percent":5.625,"by_beds":[` +
+		`{"key":"1 bed","sales":3,"rents":3,"median_price":320000,"median_monthly_rent":1500,"gross_yield_percent":5.625},` +
+		`{"key":"2 bed","sales":3,"rents":2,"median_price":480000,"median_monthly_rent":2100},` +
+		`{"key":"3 bed","sales":2,"rents":0,"median_price":725000}]}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestReportYieldGroups(t *testing.T) {
+	rows := reportYieldGroups(calculateYield(reportListings(), yieldRents(), 2).groups)
+
+	want := []reportYieldGroup{
+		{Key: "1 bed", Sales: 3, Rents: 3, MedianPrice: 320000, MedianRent: 1500, Yield: 5.625, Estimated: true},
+		{Key: "2 bed", Sales: 3, Rents: 2, MedianPrice: 480000, MedianRent: 2100, Yield: 5.25, Estimated: true},
+		{Key: "3 bed", Sales: 2, MedianPrice: 725000},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %+v, want %+v", rows, want)
+	}
+	for i, w := range want {
+		if r := rows[i]; r.Key != w.Key || r.Sales != w.Sales || r.Rents != w.Rents || r.MedianPrice != w.MedianPrice ||
+			r.MedianRent != w.MedianRent || !closeTo(r.Yield, w.Yield) || r.Estimated != w.Estimated {
+			t.Errorf("row %d: got %+v, want %+v", i, r, w)
+		}
+	}
+}
+
+func TestRunYieldEstimate(t *testing.T) {
+	srv := testserver.New()
+	defer srv.Close()
+	srv.SetPage(1, rentPage(1, 1400, 1500, 1600))
+
+	// The rental search keeps the sale search's other filters, but its
+	// cards only have rents.
+	args := &cliArgs{BaseURL: "http://sales.invalid/for-sale", RentBaseURL: srv.SearchURL(), Postcode: "sw2", PriceMin: uint64Ptr(250000), MinGroupSize: 2}
+	e := runYieldEstimate(context.Background(), &fetcher{client: srv.Client()}, args, []string{"sw2"}, reportListings())
+	if e == nil {
+		t.Fatal("got nil")
+	}
+
+	if e.rents != 3 || e.overall == nil || !closeTo(*e.overall, 5.625) {
+		t.Errorf("got %v", *e)
+	}
+	if args.Mode != saleSearch || args.PriceMin == nil {
+		t.Error("the sale search's args were changed")
+	}
+}
+
+func TestRunYieldEstimateFails(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		fail bool
+	}{
+		{"search fails", rentPage(1, 1500), true},
+		{"no rentals", testserver.EmptyPage, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := testserver.New()
+			defer srv.Close()
+			srv.SetPage(1, tt.page)
+			if tt.fail {
+				srv.InjectStatus(1, http.StatusNotFound, 1)
+			}
+
+			args := &cliArgs{RentBaseURL: srv.SearchURL(), Postcode: "sw2", MinGroupSize: 1}
+			if e := runYieldEstimate(context.Background(), &fetcher{client: srv.Client()}, args, []string{"sw2"}, reportListings()); e != nil {
+				t.Errorf("got %v, want nil", *e)
+			}
+		})
+	}
+}